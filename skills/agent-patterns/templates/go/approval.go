@@ -0,0 +1,32 @@
+/*
+ * Approval Points for Go Agent Patterns
+ * Human sign-off on agent tools, chain steps, orchestrator plans, and pipeline stages
+ */
+
+package agentpatterns
+
+import (
+	"context"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/approvals"
+)
+
+// requestApproval asks approver to allow an action of the current run. It
+// returns nil if approved, or an error wrapping approvals.ErrRejected.
+func (c *patternConfig) requestApproval(ctx context.Context, approver approvals.Approver, action, summary string, details map[string]interface{}) error {
+	ctx, span := StartSpan(ctx, "approval")
+	span.SetAttribute("action", action)
+	err := approvals.Require(ctx, approver, approvals.Request{
+		RunID:   RunIDFromContext(ctx),
+		Pattern: c.pattern,
+		Action:  action,
+		Summary: summary,
+		Details: details,
+	})
+	span.Finish(err)
+	c.publish(ctx, PhaseApproval, map[string]interface{}{"action": action, "approved": err == nil})
+	if err != nil {
+		c.logger.Warn("approval not granted", "action", action, "error", err)
+	}
+	return err
+}
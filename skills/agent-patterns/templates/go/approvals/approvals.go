@@ -0,0 +1,299 @@
+/*
+ * Human-in-the-Loop Approvals for Go Agent Patterns
+ * Approver interface, timeout and escalation policies, and decision audit records
+ */
+
+// Package approvals lets patterns pause for a human decision: before an
+// agent runs a sensitive tool, before a chain continues past a step, or
+// before an orchestrator executes its plan.
+//
+// An Approver asks one person or channel. A Gate wraps approvers with a
+// timeout, an escalation chain, and an audit log.
+//
+// Example:
+//
+//	gate := approvals.NewGate(approvals.NewCLIApprover(),
+//	    approvals.WithTimeout(10*time.Minute),
+//	    approvals.WithEscalation(approvals.NewSlackApprover(token, "#oncall")),
+//	    approvals.WithAudit(approvals.NewStoreAudit(st)),
+//	)
+//	agent.RequireApproval(gate, "deploy", "delete_records")
+package approvals
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/store"
+)
+
+// ErrRejected is wrapped by Require when a request is not approved
+var ErrRejected = errors.New("approval rejected")
+
+// Outcome is the result of an approval request
+type Outcome string
+
+const (
+	Approved Outcome = "approved"
+	Rejected Outcome = "rejected"
+	// TimedOut is recorded when an approver did not answer in time and the
+	// request was escalated or decided by policy
+	TimedOut Outcome = "timed_out"
+)
+
+// Request describes the action awaiting approval
+type Request struct {
+	ID      string `json:"id"`
+	RunID   string `json:"run_id,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	// Action identifies what is being approved, e.g. "tool:deploy" or
+	// "chain_step:publish"
+	Action  string                 `json:"action"`
+	Summary string                 `json:"summary"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Created time.Time              `json:"created"`
+}
+
+// Decision is an approver's answer
+type Decision struct {
+	Outcome  Outcome   `json:"outcome"`
+	Approver string    `json:"approver"`
+	Reason   string    `json:"reason,omitempty"`
+	Decided  time.Time `json:"decided"`
+}
+
+// Approved reports whether the decision allows the action
+func (d Decision) Approved() bool {
+	return d.Outcome == Approved
+}
+
+// Approver asks for a decision on a request. It blocks until a decision is
+// made or ctx is done.
+type Approver interface {
+	RequestApproval(ctx context.Context, req Request) (Decision, error)
+}
+
+// ApproverFunc adapts a function to Approver
+type ApproverFunc func(ctx context.Context, req Request) (Decision, error)
+
+// RequestApproval calls f
+func (f ApproverFunc) RequestApproval(ctx context.Context, req Request) (Decision, error) {
+	return f(ctx, req)
+}
+
+// AutoApprove approves every request, e.g. in tests or dry runs
+func AutoApprove() Approver {
+	return ApproverFunc(func(ctx context.Context, req Request) (Decision, error) {
+		return Decision{Outcome: Approved, Approver: "auto", Decided: time.Now()}, nil
+	})
+}
+
+// Require asks approver about req and returns nil if it was approved, or an
+// error wrapping ErrRejected with the reason
+func Require(ctx context.Context, approver Approver, req Request) error {
+	decision, err := approver.RequestApproval(ctx, prepare(req))
+	if err != nil {
+		return fmt.Errorf("approval request failed: %w", err)
+	}
+	if !decision.Approved() {
+		reason := decision.Reason
+		if reason == "" {
+			reason = string(decision.Outcome)
+		}
+		return fmt.Errorf("%w by %s: %s", ErrRejected, decision.Approver, reason)
+	}
+	return nil
+}
+
+// prepare fills in the ID and creation time if they are missing
+func prepare(req Request) Request {
+	if req.ID == "" {
+		var b [8]byte
+		rand.Read(b[:])
+		req.ID = "apr_" + hex.EncodeToString(b[:])
+	}
+	if req.Created.IsZero() {
+		req.Created = time.Now()
+	}
+	return req
+}
+
+// Option configures a Gate
+type Option func(*Gate)
+
+// WithTimeout limits how long each approver has to decide before the
+// request escalates
+func WithTimeout(d time.Duration) Option {
+	return func(g *Gate) { g.timeout = d }
+}
+
+// WithEscalation adds approvers tried in order when the previous one times
+// out
+func WithEscalation(approvers ...Approver) Option {
+	return func(g *Gate) { g.approvers = append(g.approvers, approvers...) }
+}
+
+// WithTimeoutOutcome sets the decision when every approver times out. The
+// default is Rejected.
+func WithTimeoutOutcome(outcome Outcome) Option {
+	return func(g *Gate) { g.onTimeout = outcome }
+}
+
+// WithAudit records every decision, including timeouts, to log
+func WithAudit(log AuditLog) Option {
+	return func(g *Gate) { g.audit = log }
+}
+
+// Gate applies timeout, escalation, and audit policies around approvers.
+// It is itself an Approver.
+type Gate struct {
+	approvers []Approver
+	timeout   time.Duration
+	onTimeout Outcome
+	audit     AuditLog
+}
+
+// NewGate creates a gate that asks primary first
+func NewGate(primary Approver, opts ...Option) *Gate {
+	g := &Gate{approvers: []Approver{primary}, onTimeout: Rejected}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// RequestApproval implements Approver
+func (g *Gate) RequestApproval(ctx context.Context, req Request) (Decision, error) {
+	req = prepare(req)
+	for level, approver := range g.approvers {
+		askCtx, cancel := ctx, context.CancelFunc(func() {})
+		if g.timeout > 0 {
+			askCtx, cancel = context.WithTimeout(ctx, g.timeout)
+		}
+		decision, err := approver.RequestApproval(askCtx, req)
+		cancel()
+
+		if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			timedOut := Decision{
+				Outcome:  TimedOut,
+				Approver: fmt.Sprintf("level %d", level),
+				Reason:   fmt.Sprintf("no decision within %s", g.timeout),
+				Decided:  time.Now(),
+			}
+			if err := g.record(ctx, req, timedOut, level); err != nil {
+				return Decision{}, err
+			}
+			continue
+		}
+		if err != nil {
+			return Decision{}, err
+		}
+		if decision.Decided.IsZero() {
+			decision.Decided = time.Now()
+		}
+		return decision, g.record(ctx, req, decision, level)
+	}
+
+	decision := Decision{
+		Outcome:  g.onTimeout,
+		Approver: "policy",
+		Reason:   "every approver timed out",
+		Decided:  time.Now(),
+	}
+	return decision, g.record(ctx, req, decision, len(g.approvers))
+}
+
+func (g *Gate) record(ctx context.Context, req Request, decision Decision, level int) error {
+	if g.audit == nil {
+		return nil
+	}
+	if err := g.audit.Record(ctx, AuditRecord{Request: req, Decision: decision, Level: level}); err != nil {
+		return fmt.Errorf("failed to record approval audit: %w", err)
+	}
+	return nil
+}
+
+// AuditRecord is one decision on a request. Level is the position of the
+// deciding approver in the escalation chain, starting at 0.
+type AuditRecord struct {
+	Request  Request  `json:"request"`
+	Decision Decision `json:"decision"`
+	Level    int      `json:"level"`
+}
+
+// AuditLog stores decision records
+type AuditLog interface {
+	Record(ctx context.Context, rec AuditRecord) error
+}
+
+// MemoryAudit keeps records in memory
+type MemoryAudit struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+// NewMemoryAudit creates an empty in-memory audit log
+func NewMemoryAudit() *MemoryAudit {
+	return &MemoryAudit{}
+}
+
+// Record implements AuditLog
+func (m *MemoryAudit) Record(ctx context.Context, rec AuditRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, rec)
+	return nil
+}
+
+// Records returns a copy of the recorded decisions
+func (m *MemoryAudit) Records() []AuditRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]AuditRecord(nil), m.records...)
+}
+
+// StoreAudit writes each record as JSON to a store.Store under
+// "approvals/<request id>/<level>-<outcome>"
+type StoreAudit struct {
+	st store.Store
+}
+
+// NewStoreAudit creates an audit log backed by st
+func NewStoreAudit(st store.Store) *StoreAudit {
+	return &StoreAudit{st: st}
+}
+
+// Record implements AuditLog
+func (s *StoreAudit) Record(ctx context.Context, rec AuditRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("approvals/%s/%d-%s", rec.Request.ID, rec.Level, rec.Decision.Outcome)
+	return s.st.Put(ctx, key, data)
+}
+
+// JSONLAudit appends each record as a line of JSON to a writer
+type JSONLAudit struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLAudit creates an audit log writing to w
+func NewJSONLAudit(w io.Writer) *JSONLAudit {
+	return &JSONLAudit{w: w}
+}
+
+// Record implements AuditLog
+func (j *JSONLAudit) Record(ctx context.Context, rec AuditRecord) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return json.NewEncoder(j.w).Encode(rec)
+}
@@ -0,0 +1,98 @@
+package approvals
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CLIApprover asks on a terminal. Answering "y" or "yes" approves; any
+// other answer rejects, and is recorded as the reason unless it is just "n".
+type CLIApprover struct {
+	Name string
+	in   io.Reader
+	out  io.Writer
+
+	mu    sync.Mutex
+	once  sync.Once
+	lines chan string
+}
+
+// NewCLIApprover asks on stdin and stdout, recording the approver as $USER
+func NewCLIApprover() *CLIApprover {
+	return NewCLIApproverWith(os.Stdin, os.Stdout, os.Getenv("USER"))
+}
+
+// NewCLIApproverWith asks on the given reader and writer
+func NewCLIApproverWith(in io.Reader, out io.Writer, name string) *CLIApprover {
+	if name == "" {
+		name = "cli"
+	}
+	return &CLIApprover{Name: name, in: in, out: out}
+}
+
+// RequestApproval implements Approver
+func (c *CLIApprover) RequestApproval(ctx context.Context, req Request) (Decision, error) {
+	// One prompt at a time, so concurrent requests do not interleave
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Lines are read in the background so a timed-out prompt does not block
+	// the next one; discard answers typed after a previous prompt expired
+	c.once.Do(c.startReading)
+	for drained := false; !drained; {
+		select {
+		case _, ok := <-c.lines:
+			drained = !ok
+		default:
+			drained = true
+		}
+	}
+
+	fmt.Fprintf(c.out, "\nApproval requested: %s\n", req.Summary)
+	if req.Action != "" {
+		fmt.Fprintf(c.out, "Action: %s\n", req.Action)
+	}
+	if len(req.Details) > 0 {
+		details, _ := json.MarshalIndent(req.Details, "", "  ")
+		fmt.Fprintf(c.out, "Details: %s\n", details)
+	}
+	fmt.Fprint(c.out, "Approve? [y/N, or a reason to reject]: ")
+
+	select {
+	case <-ctx.Done():
+		fmt.Fprintln(c.out)
+		return Decision{}, ctx.Err()
+	case line, ok := <-c.lines:
+		if !ok {
+			return Decision{}, io.ErrUnexpectedEOF
+		}
+		answer := strings.TrimSpace(line)
+		decision := Decision{Outcome: Rejected, Approver: c.Name, Decided: time.Now()}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			decision.Outcome = Approved
+		case "", "n", "no":
+		default:
+			decision.Reason = answer
+		}
+		return decision, nil
+	}
+}
+
+func (c *CLIApprover) startReading() {
+	c.lines = make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(c.in)
+		for scanner.Scan() {
+			c.lines <- scanner.Text()
+		}
+		close(c.lines)
+	}()
+}
@@ -0,0 +1,138 @@
+package approvals
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SlackApprover posts requests to a Slack channel and waits for a reviewer
+// to react with :white_check_mark: to approve or :x: to reject. It needs a
+// bot token with the chat:write and reactions:read scopes.
+type SlackApprover struct {
+	Token        string
+	Channel      string
+	PollInterval time.Duration
+	BaseURL      string
+	HTTPClient   *http.Client
+}
+
+// NewSlackApprover creates a Slack approver polling every five seconds
+func NewSlackApprover(token, channel string) *SlackApprover {
+	return &SlackApprover{
+		Token:        token,
+		Channel:      channel,
+		PollInterval: 5 * time.Second,
+		BaseURL:      "https://slack.com/api",
+		HTTPClient:   &http.Client{},
+	}
+}
+
+// RequestApproval implements Approver
+func (s *SlackApprover) RequestApproval(ctx context.Context, req Request) (Decision, error) {
+	text := fmt.Sprintf("*Approval requested:* %s", req.Summary)
+	if req.Action != "" {
+		text += fmt.Sprintf("\n*Action:* `%s`", req.Action)
+	}
+	if len(req.Details) > 0 {
+		details, _ := json.MarshalIndent(req.Details, "", "  ")
+		text += fmt.Sprintf("\n```%s```", details)
+	}
+	text += "\nReact with :white_check_mark: to approve or :x: to reject."
+
+	var posted struct {
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	err := s.call(ctx, "POST", "chat.postMessage", map[string]string{"channel": s.Channel, "text": text}, &posted)
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to post approval request: %w", err)
+	}
+
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return Decision{}, ctx.Err()
+		case <-ticker.C:
+		}
+
+		var reactions struct {
+			Message struct {
+				Reactions []struct {
+					Name  string   `json:"name"`
+					Users []string `json:"users"`
+				} `json:"reactions"`
+			} `json:"message"`
+		}
+		query := url.Values{"channel": {posted.Channel}, "timestamp": {posted.TS}}
+		if err := s.call(ctx, "GET", "reactions.get?"+query.Encode(), nil, &reactions); err != nil {
+			return Decision{}, fmt.Errorf("failed to read reactions: %w", err)
+		}
+		for _, r := range reactions.Message.Reactions {
+			if len(r.Users) == 0 {
+				continue
+			}
+			switch r.Name {
+			case "white_check_mark", "heavy_check_mark":
+				return Decision{Outcome: Approved, Approver: "slack:" + r.Users[0], Decided: time.Now()}, nil
+			case "x":
+				return Decision{Outcome: Rejected, Approver: "slack:" + r.Users[0], Decided: time.Now()}, nil
+			}
+		}
+	}
+}
+
+// call invokes a Slack Web API method and decodes its response into out
+func (s *SlackApprover) call(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader = http.NoBody
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(s.BaseURL, "/")+"/"+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.Token)
+	if in != nil {
+		req.Header.Set("content-type", "application/json; charset=utf-8")
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack API error (status %d)", resp.StatusCode)
+	}
+
+	// Slack reports failures in the body with ok=false
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return err
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return err
+	}
+	if !status.OK {
+		return fmt.Errorf("slack API error: %s", status.Error)
+	}
+	return json.Unmarshal(raw, out)
+}
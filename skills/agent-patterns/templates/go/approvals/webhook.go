@@ -0,0 +1,106 @@
+package approvals
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebhookApprover posts requests to an approval service. The service
+// either answers 200 with a Decision, or 202 Accepted, after which
+// GET <URL>/<request id> is polled until it answers 200 with a Decision.
+// If Secret is set, each POST carries an X-Signature-256 header with the
+// hex HMAC-SHA256 of the body, so the service can verify the sender.
+type WebhookApprover struct {
+	URL          string
+	Secret       string
+	PollInterval time.Duration
+	HTTPClient   *http.Client
+}
+
+// NewWebhookApprover creates a webhook approver polling every five seconds
+func NewWebhookApprover(url string) *WebhookApprover {
+	return &WebhookApprover{
+		URL:          strings.TrimRight(url, "/"),
+		PollInterval: 5 * time.Second,
+		HTTPClient:   &http.Client{},
+	}
+}
+
+// RequestApproval implements Approver
+func (w *WebhookApprover) RequestApproval(ctx context.Context, req Request) (Decision, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return Decision{}, err
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	if w.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.Secret))
+		mac.Write(body)
+		httpReq.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	decision, pending, err := w.do(httpReq)
+	if err != nil || !pending {
+		return decision, err
+	}
+
+	pollURL := w.URL + "/" + url.PathEscape(req.ID)
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return Decision{}, ctx.Err()
+		case <-ticker.C:
+		}
+		pollReq, err := http.NewRequestWithContext(ctx, "GET", pollURL, nil)
+		if err != nil {
+			return Decision{}, err
+		}
+		decision, pending, err := w.do(pollReq)
+		if err != nil || !pending {
+			return decision, err
+		}
+	}
+}
+
+// do sends req and reports whether the decision is still pending
+func (w *WebhookApprover) do(req *http.Request) (Decision, bool, error) {
+	resp, err := w.HTTPClient.Do(req)
+	if err != nil {
+		return Decision{}, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var decision Decision
+		if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+			return Decision{}, false, fmt.Errorf("invalid decision: %w", err)
+		}
+		if decision.Outcome != Approved && decision.Outcome != Rejected {
+			return Decision{}, false, fmt.Errorf("invalid decision outcome %q", decision.Outcome)
+		}
+		return decision, false, nil
+	case http.StatusAccepted, http.StatusNoContent:
+		return Decision{}, true, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return Decision{}, false, fmt.Errorf("approval webhook error (status %d): %s", resp.StatusCode, string(body))
+	}
+}
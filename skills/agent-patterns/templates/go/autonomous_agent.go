@@ -8,8 +8,18 @@ package agentpatterns
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"runtime/debug"
 	"strings"
+	"time"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/approvals"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/conversation"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/jsonx"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/memory"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/schema"
 )
 
 // ParameterDef defines a tool parameter
@@ -19,12 +29,73 @@ type ParameterDef struct {
 	Required    bool
 }
 
-// AgentTool represents a tool for the agent
+// AgentTool represents a tool for the agent. A handler that panics, or
+// does not return within Timeout, fails the call with an error the model
+// sees as the tool result; the agent keeps running.
 type AgentTool struct {
 	Name        string
 	Description string
 	Parameters  map[string]ParameterDef
 	Handler     func(ctx context.Context, args map[string]interface{}) (string, error)
+	// Timeout limits each attempt (0 = no limit). The handler's context is
+	// canceled when it expires; a handler that ignores it is abandoned.
+	Timeout time.Duration
+	// MaxRetries is the number of further attempts after a failed call.
+	// Retries are made at once; panics are not retried.
+	MaxRetries int
+}
+
+// ErrToolTimeout is returned when a tool call exceeds its Timeout
+var ErrToolTimeout = errors.New("tool timed out")
+
+// ErrToolPanicked is returned when a tool handler panics
+var ErrToolPanicked = errors.New("tool panicked")
+
+// ParametersFromSchema converts the properties of an object schema to
+// tool parameter definitions
+func ParametersFromSchema(s *schema.Schema) map[string]ParameterDef {
+	params := make(map[string]ParameterDef, len(s.Properties))
+	for name, prop := range s.Properties {
+		params[name] = ParameterDef{Type: prop.Type, Description: prop.Description}
+	}
+	for _, name := range s.Required {
+		if param, ok := params[name]; ok {
+			param.Required = true
+			params[name] = param
+		}
+	}
+	return params
+}
+
+// NewTool creates a tool whose parameters are generated from the struct T,
+// and whose handler receives the model's arguments decoded into T.
+//
+// Example:
+//
+//	type SearchArgs struct {
+//	    Query string `json:"query" description:"Search query"`
+//	}
+//	agent.RegisterTool(NewTool("search", "Search for information",
+//	    func(ctx context.Context, args SearchArgs) (string, error) {
+//	        return search(ctx, args.Query)
+//	    }))
+func NewTool[T any](name, description string, handler func(ctx context.Context, args T) (string, error)) AgentTool {
+	return AgentTool{
+		Name:        name,
+		Description: description,
+		Parameters:  ParametersFromSchema(schema.MustFor[T]()),
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			data, err := json.Marshal(args)
+			if err != nil {
+				return "", err
+			}
+			var typed T
+			if err := json.Unmarshal(data, &typed); err != nil {
+				return "", fmt.Errorf("invalid arguments for tool '%s': %w", name, err)
+			}
+			return handler(ctx, typed)
+		},
+	}
 }
 
 // ActionRecord represents an action in the history
@@ -35,6 +106,8 @@ type ActionRecord struct {
 	ToolArgs   map[string]interface{}
 	ToolResult string
 	Thought    string
+	// Thinking is the model's extended thinking on the step, if enabled
+	Thinking string
 }
 
 // AgentState tracks the agent's state
@@ -58,7 +131,7 @@ type AgentAction struct {
 //
 // Example:
 //
-//	agent := NewAutonomousAgent(client, "claude-sonnet-4-20250514")
+//	agent := NewAutonomousAgent(client, WithModel("claude-sonnet-4-20250514"))
 //	agent.RegisterTool(AgentTool{
 //	    Name: "search",
 //	    Description: "Search for information",
@@ -66,35 +139,366 @@ type AgentAction struct {
 //	})
 //	result, err := agent.Run(ctx, "Research AI safety", 10)
 type AutonomousAgent struct {
-	client              *AnthropicClient
-	model               string
-	tools               map[string]*AgentTool
-	state               AgentState
-	conversationHistory []MessageItem
+	client         *AnthropicClient
+	cfg            patternConfig
+	tools          map[string]*AgentTool
+	state          AgentState
+	conv           *conversation.Conversation
+	historyOpts    []conversation.Option
+	compaction     []conversation.Option
+	toolGuardrails []Guardrail
+	approval       ApprovalFunc
+	approvalTools  map[string]bool
+	memory         memory.Memory
+	memoryLimit    int
+	recalled       string
+	task           string
+	subAgentSteps  int
+	subAgentDepth  int
+	react          bool
+	maxObservation int
+	// busy holds a token while the agent runs a task delegated through
+	// AsTool, since a run keeps its state on the agent
+	busy chan struct{}
 }
 
 // NewAutonomousAgent creates a new AutonomousAgent
-func NewAutonomousAgent(client *AnthropicClient, model string) *AutonomousAgent {
+func NewAutonomousAgent(client *AnthropicClient, opts ...Option) *AutonomousAgent {
 	return &AutonomousAgent{
-		client:              client,
-		model:               model,
-		tools:               make(map[string]*AgentTool),
-		state:               AgentState{},
-		conversationHistory: []MessageItem{},
+		client: client,
+		cfg:    newPatternConfig("autonomous_agent", opts),
+		tools:  make(map[string]*AgentTool),
+		state:  AgentState{},
+		conv:   conversation.New(""),
+		busy:   make(chan struct{}, 1),
 	}
 }
 
+// SetThinkingBudget lets the model think for up to budget tokens before
+// choosing each step's action; the thinking is kept on the step's action
+// records. Zero turns thinking off.
+func (a *AutonomousAgent) SetThinkingBudget(budget int) *AutonomousAgent {
+	a.cfg.request.ThinkingBudget = budget
+	return a
+}
+
+// SetHistoryWindow limits how much of the conversation is sent on each
+// step, e.g. conversation.WithMaxMessages(20) with a summarizer for long runs
+func (a *AutonomousAgent) SetHistoryWindow(opts ...conversation.Option) *AutonomousAgent {
+	a.historyOpts = opts
+	return a
+}
+
+// CompactHistory keeps long runs within the model's context. Once the
+// history, estimated at four characters per token, exceeds maxTokens, older
+// turns are folded into a running summary by summarizer, or by a Haiku
+// summarizer if nil. Tool results are labelled like [r1], and any result
+// whose label the agent cites in a later response is kept verbatim.
+//
+// Example:
+//
+//	agent := NewAutonomousAgent(client).CompactHistory(50000, nil)
+func (a *AutonomousAgent) CompactHistory(maxTokens int, summarizer conversation.Summarizer) *AutonomousAgent {
+	if summarizer == nil {
+		opts := append(a.cfg.childOptions(), WithModel("claude-3-haiku-20240307"))
+		summarizer = NewSummarizer(a.client, opts...)
+	}
+	a.compaction = []conversation.Option{
+		conversation.WithMaxTokens(maxTokens),
+		conversation.WithSummarizer(summarizer),
+		conversation.WithPreserve(citedToolResult),
+	}
+	return a
+}
+
+// toolResultLabel extracts the label of a tool result message, e.g. "[r1]"
+var toolResultLabel = regexp.MustCompile(`^(?:Tool result|Observation) (\[r\d+\]):`)
+
+// citedToolResult preserves tool results whose label a later assistant
+// message mentions
+func citedToolResult(m conversation.Message, later []conversation.Message) bool {
+	if m.Role != conversation.RoleUser {
+		return false
+	}
+	match := toolResultLabel.FindStringSubmatch(m.Content)
+	if match == nil {
+		return false
+	}
+	for _, l := range later {
+		if l.Role == conversation.RoleAssistant && strings.Contains(l.Content, match[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Conversation returns the conversation of the current or last run
+func (a *AutonomousAgent) Conversation() *conversation.Conversation {
+	return a.conv
+}
+
+// UseMemory makes the agent remember across runs. Tool calls, facts the
+// agent chooses to remember, and the results of completed tasks are written
+// to m, and the newest limit entries (all if zero) are included in the
+// system prompt of each run.
+func (a *AutonomousAgent) UseMemory(m memory.Memory, limit int) *AutonomousAgent {
+	a.memory = m
+	a.memoryLimit = limit
+	return a
+}
+
 // RegisterTool registers a tool for the agent
 func (a *AutonomousAgent) RegisterTool(tool AgentTool) *AutonomousAgent {
 	a.tools[tool.Name] = &tool
 	return a
 }
 
+// AddToolGuardrail adds guardrails that screen every tool result before it
+// is shown to the model
+func (a *AutonomousAgent) AddToolGuardrail(guardrails ...Guardrail) *AutonomousAgent {
+	a.toolGuardrails = append(a.toolGuardrails, guardrails...)
+	return a
+}
+
+// RegisterToolGuardrail adds a guardrail definition that screens every tool
+// result, with LLM checks sent through the agent's client
+func (a *AutonomousAgent) RegisterToolGuardrail(def GuardrailDef) *AutonomousAgent {
+	return a.AddToolGuardrail(def.Bind(a.client))
+}
+
+// UseToolGuardrails registers built-in guardrails by name for tool results,
+// e.g. GuardrailPromptInjection for content fetched from the web
+func (a *AutonomousAgent) UseToolGuardrails(names ...string) error {
+	defs, err := resolveGuardrails(names)
+	if err != nil {
+		return err
+	}
+	for _, def := range defs {
+		a.RegisterToolGuardrail(def)
+	}
+	return nil
+}
+
+// screenToolResult runs the tool guardrails and returns the names of any
+// that failed
+func (a *AutonomousAgent) screenToolResult(ctx context.Context, result string) []string {
+	var failed []string
+	for _, g := range a.toolGuardrails {
+		verdict, err := checkGuardrail(ctx, g, result)
+		if err != nil || !verdict.Passed {
+			failed = append(failed, g.Name())
+		}
+	}
+	return failed
+}
+
+// Verdicts of a Decision on a tool call
+const (
+	DecisionApprove = "approve"
+	DecisionDeny    = "deny"
+	DecisionModify  = "modify"
+)
+
+// Decision is the answer to a tool call awaiting approval
+type Decision struct {
+	// Verdict is DecisionApprove, DecisionDeny, or DecisionModify
+	Verdict string
+	// Args replaces the call's arguments when the verdict is DecisionModify
+	Args map[string]interface{}
+	// Reason is shown to the model when the call is denied or modified
+	Reason string
+}
+
+// ApprovalFunc decides whether the agent may make a tool call. It may block
+// while a person decides; an error fails the run.
+type ApprovalFunc func(ctx context.Context, action AgentAction) (Decision, error)
+
+// SetApprovalFunc pauses each call to the named tools, or to every tool if
+// none are named, until fn decides on it. A denied call is reported to the
+// model as the tool result so it can choose another approach; a modified
+// call runs with the new arguments. Every decision is recorded in the
+// action history.
+//
+// Example:
+//
+//	agent.SetApprovalFunc(func(ctx context.Context, action AgentAction) (Decision, error) {
+//	    if path, _ := action.Args["path"].(string); !strings.HasPrefix(path, "/tmp/") {
+//	        args := map[string]interface{}{"path": "/tmp/" + filepath.Base(path), "content": action.Args["content"]}
+//	        return Decision{Verdict: DecisionModify, Args: args, Reason: "writes are limited to /tmp"}, nil
+//	    }
+//	    return Decision{Verdict: DecisionApprove}, nil
+//	}, "write_file")
+func (a *AutonomousAgent) SetApprovalFunc(fn ApprovalFunc, tools ...string) *AutonomousAgent {
+	a.approval = fn
+	a.approvalTools = make(map[string]bool, len(tools))
+	for _, name := range tools {
+		a.approvalTools[name] = true
+	}
+	return a
+}
+
+// RequireApproval asks approver before each call to the named tools, or to
+// every tool if none are named. A rejected call is reported to the model as
+// the tool result so it can choose another approach.
+func (a *AutonomousAgent) RequireApproval(approver approvals.Approver, tools ...string) *AutonomousAgent {
+	return a.SetApprovalFunc(func(ctx context.Context, action AgentAction) (Decision, error) {
+		err := approvals.Require(ctx, approver, approvals.Request{
+			RunID:   RunIDFromContext(ctx),
+			Pattern: a.cfg.pattern,
+			Action:  "tool:" + action.Action,
+			Summary: fmt.Sprintf("Agent wants to call %s", action.Action),
+			Details: map[string]interface{}{"args": action.Args, "thought": action.Thought},
+		})
+		if errors.Is(err, approvals.ErrRejected) {
+			return Decision{Verdict: DecisionDeny, Reason: err.Error()}, nil
+		}
+		if err != nil {
+			return Decision{}, err
+		}
+		return Decision{Verdict: DecisionApprove}, nil
+	}, tools...)
+}
+
+// needsApproval reports whether calls to tool must be approved
+func (a *AutonomousAgent) needsApproval(tool string) bool {
+	return a.approval != nil && (len(a.approvalTools) == 0 || a.approvalTools[tool])
+}
+
+// approveToolCall asks the approval hook about a tool call
+func (a *AutonomousAgent) approveToolCall(ctx context.Context, action AgentAction) (Decision, error) {
+	ctx, span := StartSpan(ctx, "approval")
+	span.SetAttribute("action", "tool:"+action.Action)
+	decision, err := a.approval(ctx, action)
+	if err == nil {
+		switch decision.Verdict {
+		case DecisionApprove, DecisionDeny, DecisionModify:
+		default:
+			err = fmt.Errorf("unknown approval verdict %q", decision.Verdict)
+		}
+	}
+	span.Finish(err)
+	if err != nil {
+		return decision, fmt.Errorf("approval of %s failed: %w", action.Action, err)
+	}
+	a.cfg.publish(ctx, PhaseApproval, map[string]interface{}{
+		"action":   "tool:" + action.Action,
+		"approved": decision.Verdict != DecisionDeny,
+		"verdict":  decision.Verdict,
+	})
+	if decision.Verdict != DecisionApprove {
+		a.cfg.logger.Warn("tool call not approved as is", "tool", action.Action, "verdict", decision.Verdict, "reason", decision.Reason)
+	}
+	return decision, nil
+}
+
 // State returns the current agent state
 func (a *AutonomousAgent) State() *AgentState {
 	return &a.state
 }
 
+// AgentSnapshot is the serializable state of an agent between runs: its
+// task, progress, and conversation, and the tools it was using. Store it
+// anywhere JSON goes, and resume it later or on another machine with
+// RestoreAgent.
+type AgentSnapshot struct {
+	Task         string          `json:"task"`
+	State        AgentState      `json:"state"`
+	Conversation json.RawMessage `json:"conversation"`
+	Tools        []string        `json:"tools"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// Snapshot captures the agent's state, e.g. after a run stopped by its
+// step limit or stopping condition. Later changes to the agent do not
+// affect the snapshot.
+func (a *AutonomousAgent) Snapshot() (*AgentSnapshot, error) {
+	conv, err := json.Marshal(a.conv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode conversation: %w", err)
+	}
+	state := a.state
+	state.ActionHistory = append([]ActionRecord(nil), a.state.ActionHistory...)
+	return &AgentSnapshot{
+		Task:         a.task,
+		State:        state,
+		Conversation: conv,
+		Tools:        sortedKeys(a.tools),
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+// RestoreAgent creates an agent from a snapshot, registering tools, which
+// must include every tool the snapshot was using. Continue its task with
+// Resume.
+//
+// Example:
+//
+//	snapshot, err := agent.Snapshot()
+//	data, err := json.Marshal(snapshot) // store it, and later...
+//	var saved AgentSnapshot
+//	err = json.Unmarshal(data, &saved)
+//	agent, err := RestoreAgent(client, &saved, tools, WithModel(model))
+//	result, err := agent.Resume(ctx, 50)
+func RestoreAgent(client *AnthropicClient, snapshot *AgentSnapshot, tools []AgentTool, opts ...Option) (*AutonomousAgent, error) {
+	a := NewAutonomousAgent(client, opts...)
+	for _, tool := range tools {
+		a.RegisterTool(tool)
+	}
+	if err := a.Restore(snapshot); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Restore replaces the agent's state with a snapshot's, for agents set up
+// with memory, approvals, or history options before restoring. The agent's
+// tools must include every tool the snapshot was using.
+func (a *AutonomousAgent) Restore(snapshot *AgentSnapshot) error {
+	for _, name := range snapshot.Tools {
+		if _, ok := a.tools[name]; !ok {
+			return fmt.Errorf("snapshot uses tool %s, which is not registered", name)
+		}
+	}
+	historyOpts := append(append([]conversation.Option(nil), a.historyOpts...), a.compaction...)
+	conv := conversation.New("", historyOpts...)
+	if len(snapshot.Conversation) > 0 {
+		if err := json.Unmarshal(snapshot.Conversation, conv); err != nil {
+			return fmt.Errorf("failed to decode conversation: %w", err)
+		}
+	}
+	a.task = snapshot.Task
+	a.state = snapshot.State
+	a.state.ActionHistory = append([]ActionRecord(nil), snapshot.State.ActionHistory...)
+	a.conv = conv
+	return nil
+}
+
+// Resume continues the agent's task from its current state, such as a
+// restored snapshot, until it completes or has taken maxSteps steps in
+// all
+func (a *AutonomousAgent) Resume(ctx context.Context, maxSteps int) (*AgentResult, error) {
+	if a.task == "" {
+		return nil, fmt.Errorf("agent has no task to resume")
+	}
+	return a.run(ctx, a.task, maxSteps, nil, true)
+}
+
+// revise reopens a completed task with feedback on its result and works
+// on for up to maxSteps more steps
+func (a *AutonomousAgent) revise(ctx context.Context, feedback string, maxSteps int) (*AgentResult, error) {
+	a.state.IsComplete = false
+	a.state.FinalResult = ""
+	a.conv.AddUser(feedback)
+	return a.run(ctx, a.task, a.state.TotalSteps+maxSteps, nil, true)
+}
+
+// agentCheckpoint is the state persisted after each step when a store is
+// configured
+type agentCheckpoint struct {
+	State        AgentState                 `json:"state"`
+	Conversation *conversation.Conversation `json:"conversation"`
+}
+
 // AgentResult represents the result of running the agent
 type AgentResult struct {
 	Success       bool
@@ -104,25 +508,63 @@ type AgentResult struct {
 	ActionHistory []ActionRecord
 }
 
-// Run runs the agent on a task
+// Run runs the agent on a task. A run that exceeds its budget returns a
+// *BudgetExceededError whose Partial is an *AgentResult of the steps taken.
 func (a *AutonomousAgent) Run(ctx context.Context, task string, maxSteps int) (*AgentResult, error) {
 	return a.RunWithStop(ctx, task, maxSteps, nil)
 }
 
 // RunWithStop runs the agent with a custom stopping condition
 func (a *AutonomousAgent) RunWithStop(ctx context.Context, task string, maxSteps int, shouldStop func(*AgentState) bool) (*AgentResult, error) {
-	// Reset state
-	a.state = AgentState{}
-	a.conversationHistory = []MessageItem{}
+	return a.run(ctx, task, maxSteps, shouldStop, false)
+}
 
-	// Build system prompt
-	systemPrompt := a.buildSystemPrompt()
+// run works on task, starting afresh or, if resume is set, from the
+// agent's current state and conversation
+func (a *AutonomousAgent) run(ctx context.Context, task string, maxSteps int, shouldStop func(*AgentState) bool, resume bool) (result *AgentResult, err error) {
+	ctx, cancel := a.cfg.startRun(ctx)
+	defer cancel()
+	ctx = withRunningAgent(ctx, a)
+	defer func() {
+		if err != nil {
+			err = budgetStop(ctx, err, &AgentResult{
+				FinalResult:   a.state.FinalResult,
+				TotalSteps:    a.state.TotalSteps,
+				ToolCalls:     a.state.ToolCalls,
+				ActionHistory: a.state.ActionHistory,
+			})
+		}
+	}()
 
-	// Add initial user message
-	a.conversationHistory = append(a.conversationHistory, MessageItem{
-		Role:    "user",
-		Content: fmt.Sprintf("Task: %s", task),
-	})
+	a.task = task
+	if err := a.recall(ctx); err != nil {
+		return nil, err
+	}
+	system, err := a.buildSystemPrompt()
+	if err != nil {
+		return nil, err
+	}
+	if resume {
+		// Tools and memories may have changed since the snapshot
+		a.conv.SetSystem(system)
+	} else {
+		// Reset state
+		a.state = AgentState{}
+		historyOpts := append(append([]conversation.Option(nil), a.historyOpts...), a.compaction...)
+		a.conv = conversation.New(system, historyOpts...)
+
+		// Resume from a checkpoint of the same run, or start with the task
+		checkpoint := agentCheckpoint{Conversation: a.conv}
+		if found, err := a.cfg.loadCheckpoint(ctx, &checkpoint); err != nil {
+			return nil, err
+		} else if found {
+			a.state = checkpoint.State
+			// Tools may have changed since the checkpoint was written
+			a.conv.SetSystem(system)
+		} else {
+			a.conv.AddUser(fmt.Sprintf("Task: %s", task))
+		}
+	}
 
 	for a.state.TotalSteps < maxSteps && !a.state.IsComplete {
 		a.state.TotalSteps++
@@ -132,14 +574,43 @@ func (a *AutonomousAgent) RunWithStop(ctx context.Context, task string, maxSteps
 			break
 		}
 
+		stepCtx, span := StartSpan(ctx, "agent.step")
+		span.SetAttribute("step", a.state.TotalSteps)
+		a.cfg.publish(stepCtx, PhaseStepStarted, map[string]interface{}{"step": a.state.TotalSteps})
+
 		// Get next action from LLM
-		response, err := a.getNextAction(ctx, systemPrompt)
+		var thinking string
+		callCtx := onThinking(stepCtx, func(t string) { thinking += t })
+		response, err := a.cfg.callConversation(callCtx, a.client, a.conv, a.cfg.model, a.cfg.tokens(2048))
 		if err != nil {
+			span.Finish(err)
 			return nil, fmt.Errorf("failed to get next action: %w", err)
 		}
 
 		// Process the response
-		if err := a.processResponse(ctx, response); err != nil {
+		recorded := len(a.state.ActionHistory)
+		err = a.processResponse(stepCtx, response)
+		span.Finish(err)
+		if err != nil {
+			return nil, err
+		}
+		for i := recorded; i < len(a.state.ActionHistory); i++ {
+			a.state.ActionHistory[i].Thinking = thinking
+		}
+		a.cfg.publish(stepCtx, PhaseStepFinished, map[string]interface{}{
+			"step":     a.state.TotalSteps,
+			"complete": a.state.IsComplete,
+		})
+
+		err = a.cfg.saveCheckpoint(ctx, agentCheckpoint{State: a.state, Conversation: a.conv})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if a.state.IsComplete {
+		err := a.remember(ctx, memory.KindFact, fmt.Sprintf("Completed task: %s\nResult: %s", task, a.state.FinalResult), nil)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -158,70 +629,83 @@ func (a *AutonomousAgent) RunWithStop(ctx context.Context, task string, maxSteps
 	}, nil
 }
 
-func (a *AutonomousAgent) buildSystemPrompt() string {
+// recall loads the entries shown to the agent from its memory
+func (a *AutonomousAgent) recall(ctx context.Context) error {
+	a.recalled = ""
+	if a.memory == nil {
+		return nil
+	}
+	entries, err := a.memory.Recent(ctx, a.memoryLimit)
+	if err != nil {
+		return fmt.Errorf("failed to load memory: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = fmt.Sprintf("- [%s] %s", entry.Kind, entry.Content)
+	}
+	// Memories include tool results, so they are quoted like them
+	a.recalled, err = a.cfg.quote(ctx, "memory", strings.Join(lines, "\n"))
+	return err
+}
+
+// remember writes an entry to the agent's memory, if it has one
+func (a *AutonomousAgent) remember(ctx context.Context, kind, content string, record *ActionRecord) error {
+	if a.memory == nil {
+		return nil
+	}
+	entry := memory.Entry{
+		Kind:    kind,
+		Content: content,
+		RunID:   RunIDFromContext(ctx),
+		Time:    time.Now(),
+	}
+	if record != nil {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		entry.Data = data
+	}
+	if err := a.memory.Add(ctx, entry); err != nil {
+		return fmt.Errorf("failed to write memory: %w", err)
+	}
+	return nil
+}
+
+func (a *AutonomousAgent) buildSystemPrompt() (string, error) {
 	var toolDescriptions []string
-	for _, tool := range a.tools {
+	for _, toolName := range sortedKeys(a.tools) {
+		tool := a.tools[toolName]
 		var params []string
-		for name, param := range tool.Parameters {
+		for _, name := range sortedKeys(tool.Parameters) {
+			param := tool.Parameters[name]
 			params = append(params, fmt.Sprintf("%s: %s (%s)", name, param.Type, param.Description))
 		}
 		toolDescriptions = append(toolDescriptions,
 			fmt.Sprintf("- %s(%s): %s", tool.Name, strings.Join(params, ", "), tool.Description))
 	}
 
-	return fmt.Sprintf(`You are an autonomous agent that can use tools to complete tasks.
-
-Available tools:
-%s
-
-To use a tool, respond with JSON in this format:
-{
-    "thought": "Your reasoning about what to do next",
-    "action": "tool_name",
-    "args": { "param": "value" }
-}
-
-When you have completed the task, respond with:
-{
-    "thought": "Task is complete because...",
-    "action": "complete",
-    "result": "Your final answer"
-}
-
-Always think step by step and use tools to gather information before providing a final answer.`,
-		strings.Join(toolDescriptions, "\n"))
-}
-
-func (a *AutonomousAgent) getNextAction(ctx context.Context, systemPrompt string) (string, error) {
-	// Build request with system prompt
-	reqBody := struct {
-		Model     string        `json:"model"`
-		MaxTokens int           `json:"max_tokens"`
-		Messages  []MessageItem `json:"messages"`
-		System    string        `json:"system,omitempty"`
-	}{
-		Model:     a.model,
-		MaxTokens: 2048,
-		Messages:  a.conversationHistory,
-		System:    systemPrompt,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", err
-	}
-
-	// This would use the actual HTTP client in production
-	_ = jsonData
-	return a.client.CreateMessage(ctx, a.conversationHistory[len(a.conversationHistory)-1].Content, a.model, 2048)
+	return a.cfg.prompt("agent.system", map[string]interface{}{
+		"tools":     toolDescriptions,
+		"react":     a.react,
+		"memory":    a.memory != nil,
+		"recalled":  a.recalled,
+		"compacted": len(a.compaction) > 0,
+	})
 }
 
 func (a *AutonomousAgent) processResponse(ctx context.Context, response string) error {
-	// Try to parse as JSON action
-	jsonStr := a.cleanJSON(response)
-
 	var action AgentAction
-	if err := json.Unmarshal([]byte(jsonStr), &action); err != nil {
+	if a.react {
+		parsed, err := parseReAct(response)
+		if err != nil {
+			return a.handleFormatError(response, err)
+		}
+		action = *parsed
+	} else if err := jsonx.Unmarshal(response, &action); err != nil || action.Action == "" {
 		// Non-JSON response
 		return a.handleTextResponse(response)
 	}
@@ -237,6 +721,7 @@ func (a *AutonomousAgent) processResponse(ctx context.Context, response string)
 
 	// Check if task is complete
 	if strings.ToLower(action.Action) == "complete" {
+		a.conv.AddAssistant(response)
 		a.state.IsComplete = true
 		a.state.FinalResult = action.Result
 		if a.state.FinalResult == "" {
@@ -245,6 +730,22 @@ func (a *AutonomousAgent) processResponse(ctx context.Context, response string)
 		return nil
 	}
 
+	// Remember a fact for future runs
+	if a.memory != nil && strings.ToLower(action.Action) == "remember" {
+		record := ActionRecord{
+			Step:       a.state.TotalSteps,
+			ActionType: "remember",
+			Thought:    action.Result,
+		}
+		a.state.ActionHistory = append(a.state.ActionHistory, record)
+		if err := a.remember(ctx, memory.KindFact, action.Result, &record); err != nil {
+			return err
+		}
+		a.conv.AddAssistant(response)
+		a.conv.AddUser("Remembered.")
+		return nil
+	}
+
 	// Execute tool
 	if tool, exists := a.tools[action.Action]; exists {
 		a.state.ToolCalls++
@@ -254,46 +755,171 @@ func (a *AutonomousAgent) processResponse(ctx context.Context, response string)
 			args = make(map[string]interface{})
 		}
 
-		toolResult, err := tool.Handler(ctx, args)
+		var reviewNote string
+		if a.needsApproval(action.Action) {
+			action.Args = args
+			decision, err := a.approveToolCall(ctx, action)
+			if err != nil {
+				return err
+			}
+			record := ActionRecord{
+				Step:     a.state.TotalSteps,
+				ToolName: action.Action,
+				ToolArgs: args,
+				Thought:  decision.Reason,
+			}
+			switch decision.Verdict {
+			case DecisionDeny:
+				record.ActionType = "approval_rejected"
+				a.state.ActionHistory = append(a.state.ActionHistory, record)
+				a.conv.AddAssistant(response)
+				a.conv.AddUser(fmt.Sprintf("Tool call not approved: %s", decision.Reason))
+				return nil
+			case DecisionModify:
+				// The record keeps the arguments the model asked for
+				record.ActionType = "approval_modified"
+				args = decision.Args
+				if args == nil {
+					args = make(map[string]interface{})
+				}
+				modified, _ := json.Marshal(args)
+				reviewNote = fmt.Sprintf("\n\nThe call ran with arguments changed on review to %s", modified)
+				if decision.Reason != "" {
+					reviewNote += ": " + decision.Reason
+				}
+			default:
+				record.ActionType = "approval_granted"
+			}
+			a.state.ActionHistory = append(a.state.ActionHistory, record)
+		}
+
+		toolCtx, span := StartSpan(ctx, "agent.tool")
+		span.SetAttribute("tool", action.Action)
+		toolInput, _ := json.Marshal(args)
+		toolResult, err := journaled(toolCtx, "tool:"+action.Action, string(toolInput), func(ctx context.Context) (string, error) {
+			return a.cfg.runTool(ctx, tool, args)
+		})
+		span.Finish(err)
+		a.cfg.publish(toolCtx, PhaseToolCalled, map[string]interface{}{
+			"tool":  action.Action,
+			"args":  args,
+			"error": err,
+		})
 		if err != nil {
 			toolResult = fmt.Sprintf("Error: %s", err.Error())
+		} else if failed := a.screenToolResult(ctx, toolResult); len(failed) > 0 {
+			a.state.ActionHistory = append(a.state.ActionHistory, ActionRecord{
+				Step:       a.state.TotalSteps,
+				ActionType: "guardrail_blocked",
+				ToolName:   action.Action,
+				ToolResult: toolResult,
+				Thought:    strings.Join(failed, ", "),
+			})
+			toolResult = fmt.Sprintf("Tool result withheld: failed guardrails %s", strings.Join(failed, ", "))
 		}
 
 		// Record tool call
-		a.state.ActionHistory = append(a.state.ActionHistory, ActionRecord{
+		record := ActionRecord{
 			Step:       a.state.TotalSteps,
 			ActionType: "tool_call",
 			ToolName:   action.Action,
 			ToolArgs:   args,
 			ToolResult: toolResult,
-		})
+		}
+		a.state.ActionHistory = append(a.state.ActionHistory, record)
+		summary := toolResult
+		if len(summary) > 200 {
+			summary = summary[:200] + "..."
+		}
+		err = a.remember(ctx, memory.KindAction, fmt.Sprintf("%s(%s) -> %s", action.Action, toolInput, summary), &record)
+		if err != nil {
+			return err
+		}
 
-		// Add to conversation history
-		a.conversationHistory = append(a.conversationHistory,
-			MessageItem{Role: "assistant", Content: response},
-			MessageItem{Role: "user", Content: fmt.Sprintf("Tool result: %s", toolResult)},
-		)
+		// Add to conversation history, quoted so instructions in the result
+		// are treated as data
+		label := "Tool result"
+		if a.react {
+			label = "Observation"
+			toolResult = truncateObservation(toolResult, a.maxObservation)
+		}
+		quoted, err := a.cfg.quote(ctx, "tool:"+action.Action, toolResult)
+		if err != nil {
+			return err
+		}
+		a.conv.AddAssistant(response)
+		if len(a.compaction) > 0 {
+			a.conv.AddUser(fmt.Sprintf("%s [r%d]: %s%s", label, a.state.ToolCalls, quoted, reviewNote))
+		} else {
+			a.conv.AddUser(fmt.Sprintf("%s: %s%s", label, quoted, reviewNote))
+		}
 	} else {
 		// Unknown action
-		var toolNames []string
-		for name := range a.tools {
-			toolNames = append(toolNames, name)
-		}
+		toolNames := sortedKeys(a.tools)
 
-		a.conversationHistory = append(a.conversationHistory,
-			MessageItem{Role: "assistant", Content: response},
-			MessageItem{Role: "user", Content: fmt.Sprintf("Unknown action: %s. Available tools: %s", action.Action, strings.Join(toolNames, ", "))},
-		)
+		a.conv.AddAssistant(response)
+		a.conv.AddUser(fmt.Sprintf("Unknown action: %s. Available tools: %s", action.Action, strings.Join(toolNames, ", ")))
 	}
 
 	return nil
 }
 
+// runTool calls a tool, retrying failed calls up to its MaxRetries
+func (c *patternConfig) runTool(ctx context.Context, tool *AgentTool, args map[string]interface{}) (string, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := c.callTool(ctx, tool, args)
+		if err == nil || attempt >= tool.MaxRetries || errors.Is(err, ErrToolPanicked) || ctx.Err() != nil {
+			return result, err
+		}
+		c.logger.Warn("retrying tool call", "tool", tool.Name, "attempt", attempt+1, "error", err)
+	}
+}
+
+// callTool makes one attempt at a tool call under its timeout, converting a
+// panic in the handler into an error
+func (c *patternConfig) callTool(ctx context.Context, tool *AgentTool, args map[string]interface{}) (string, error) {
+	callCtx := ctx
+	if tool.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, tool.Timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	// Buffered, so an abandoned handler can still finish
+	done := make(chan outcome, 1)
+	go func() {
+		var out outcome
+		defer func() {
+			if r := recover(); r != nil {
+				c.logger.Error("tool panicked", "tool", tool.Name, "panic", r, "stack", string(debug.Stack()))
+				out = outcome{err: fmt.Errorf("%w: %v", ErrToolPanicked, r)}
+			}
+			done <- out
+		}()
+		out.result, out.err = tool.Handler(callCtx, args)
+	}()
+
+	select {
+	case out := <-done:
+		if out.err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+			return out.result, fmt.Errorf("%w after %v: %v", ErrToolTimeout, tool.Timeout, out.err)
+		}
+		return out.result, out.err
+	case <-callCtx.Done():
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("%w after %v", ErrToolTimeout, tool.Timeout)
+	}
+}
+
 func (a *AutonomousAgent) handleTextResponse(response string) error {
-	a.conversationHistory = append(a.conversationHistory,
-		MessageItem{Role: "assistant", Content: response},
-		MessageItem{Role: "user", Content: "Please respond with a JSON action or mark the task as complete."},
-	)
+	a.conv.AddAssistant(response)
+	a.conv.AddUser("Please respond with a JSON action or mark the task as complete.")
 
 	thought := response
 	if len(thought) > 200 {
@@ -309,17 +935,6 @@ func (a *AutonomousAgent) handleTextResponse(response string) error {
 	return nil
 }
 
-func (a *AutonomousAgent) cleanJSON(text string) string {
-	if strings.Contains(text, "```") {
-		start := strings.Index(text, "{")
-		end := strings.LastIndex(text, "}")
-		if start >= 0 && end > start {
-			return text[start : end+1]
-		}
-	}
-	return text
-}
-
 // ExampleResearchAgent demonstrates the autonomous agent pattern
 func ExampleResearchAgent() error {
 	apiKey := getEnv("ANTHROPIC_API_KEY", "")
@@ -332,46 +947,40 @@ func ExampleResearchAgent() error {
 		HTTPClient: nil, // Would use http.Client in production
 	}
 
-	agent := NewAutonomousAgent(client, "claude-sonnet-4-20250514")
-
-	// Register tools
-	agent.RegisterTool(AgentTool{
-		Name:        "search",
-		Description: "Search for information on a topic",
-		Parameters: map[string]ParameterDef{
-			"query": {Type: "string", Description: "Search query", Required: true},
-		},
-		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
-			query, _ := args["query"].(string)
-			// Mock search - use actual search API in production
-			return fmt.Sprintf("Search results for '%s':\n1. Result about %s\n2. More info on %s", query, query, query), nil
-		},
-	})
+	agent := NewAutonomousAgent(client, WithModel("claude-sonnet-4-20250514"))
+
+	// Search with whichever provider has an API key
+	var search SearchProvider
+	switch {
+	case getEnv("TAVILY_API_KEY", "") != "":
+		search = NewTavilySearch(getEnv("TAVILY_API_KEY", ""))
+	case getEnv("BRAVE_API_KEY", "") != "":
+		search = NewBraveSearch(getEnv("BRAVE_API_KEY", ""))
+	case getEnv("SERPAPI_API_KEY", "") != "":
+		search = NewSerpAPISearch(getEnv("SERPAPI_API_KEY", ""))
+	default:
+		return fmt.Errorf("set TAVILY_API_KEY, BRAVE_API_KEY, or SERPAPI_API_KEY for web search")
+	}
+	agent.RegisterTool(SearchTool(search, 5))
 
-	agent.RegisterTool(AgentTool{
-		Name:        "read_url",
-		Description: "Read content from a URL",
-		Parameters: map[string]ParameterDef{
-			"url": {Type: "string", Description: "URL to read", Required: true},
-		},
-		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
-			url, _ := args["url"].(string)
-			return fmt.Sprintf("Content from %s: [Mock content about the topic]", url), nil
-		},
-	})
+	// Register tools; parameters are generated from the argument structs
 
-	agent.RegisterTool(AgentTool{
-		Name:        "write_note",
-		Description: "Save a note for later reference",
-		Parameters: map[string]ParameterDef{
-			"title":   {Type: "string", Description: "Note title", Required: true},
-			"content": {Type: "string", Description: "Note content", Required: true},
-		},
-		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
-			title, _ := args["title"].(string)
-			return fmt.Sprintf("Note saved: %s", title), nil
-		},
-	})
+	type readURLArgs struct {
+		URL string `json:"url" description:"URL to read"`
+	}
+	agent.RegisterTool(NewTool("read_url", "Read content from a URL",
+		func(ctx context.Context, args readURLArgs) (string, error) {
+			return fmt.Sprintf("Content from %s: [Mock content about the topic]", args.URL), nil
+		}))
+
+	type writeNoteArgs struct {
+		Title   string `json:"title" description:"Note title"`
+		Content string `json:"content" description:"Note content"`
+	}
+	agent.RegisterTool(NewTool("write_note", "Save a note for later reference",
+		func(ctx context.Context, args writeNoteArgs) (string, error) {
+			return fmt.Sprintf("Note saved: %s", args.Title), nil
+		}))
 
 	ctx := context.Background()
 	result, err := agent.Run(ctx, "Research the current state of quantum computing and summarize key developments", 8)
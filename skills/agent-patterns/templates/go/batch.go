@@ -0,0 +1,410 @@
+/*
+ * Batch Execution for Go Agent Patterns
+ * Offline subtasks through the Message Batches API at half the price of live calls
+ */
+
+package agentpatterns
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BatchRate is the fraction of the list price billed for batch requests
+const BatchRate = 0.5
+
+// MaxBatchRequests is the most requests the API accepts in one batch
+const MaxBatchRequests = 100000
+
+// BatchStatus is the state of a message batch
+type BatchStatus struct {
+	ID string `json:"id"`
+	// ProcessingStatus is in_progress, canceling, or ended
+	ProcessingStatus string             `json:"processing_status"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	ResultsURL       string             `json:"results_url"`
+	CreatedAt        time.Time          `json:"created_at"`
+	EndedAt          *time.Time         `json:"ended_at"`
+}
+
+// BatchRequestCounts counts the requests of a batch by state
+type BatchRequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// Ended reports whether every request of the batch has finished
+func (s *BatchStatus) Ended() bool {
+	return s.ProcessingStatus == "ended"
+}
+
+// BatchExecutor runs subtasks through the Message Batches API. Batches
+// finish within 24 hours, usually much sooner, and cost half as much as
+// live calls, which suits large jobs nobody is waiting on, such as scoring
+// ten thousand documents. Usage is recorded at BatchRate.
+//
+// Inside a durable workflow the IDs of submitted batches are journaled, so
+// a resumed run picks up its batches instead of submitting them again.
+// Clients with a Provider, such as a MockClient, run the subtasks live
+// instead, since only the Anthropic API takes batches.
+//
+// Example:
+//
+//	subtasks := make([]Subtask, len(reviews))
+//	for i, review := range reviews {
+//	    subtasks[i] = Subtask{Name: review.ID, Prompt: "Rate this review's sentiment from 1 to 5:\n\n" + review.Text}
+//	}
+//	results, err := NewBatchExecutor(client, WithModel("claude-3-5-haiku-20241022")).Execute(ctx, subtasks)
+type BatchExecutor struct {
+	client       *AnthropicClient
+	cfg          patternConfig
+	pollInterval time.Duration
+	maxBatchSize int
+	onPoll       func(status BatchStatus)
+}
+
+// NewBatchExecutor creates a BatchExecutor
+func NewBatchExecutor(client *AnthropicClient, opts ...Option) *BatchExecutor {
+	return &BatchExecutor{
+		client:       client,
+		cfg:          newPatternConfig("batch", opts),
+		pollInterval: 30 * time.Second,
+		maxBatchSize: MaxBatchRequests,
+	}
+}
+
+// WithPollInterval sets how often Wait checks on a batch
+func (b *BatchExecutor) WithPollInterval(d time.Duration) *BatchExecutor {
+	b.pollInterval = d
+	return b
+}
+
+// WithMaxBatchSize splits the subtasks given to Execute into batches of at
+// most n requests
+func (b *BatchExecutor) WithMaxBatchSize(n int) *BatchExecutor {
+	if n > 0 && n <= MaxBatchRequests {
+		b.maxBatchSize = n
+	}
+	return b
+}
+
+// OnPoll sets a callback that receives each status Wait polls
+func (b *BatchExecutor) OnPoll(fn func(status BatchStatus)) *BatchExecutor {
+	b.onPoll = fn
+	return b
+}
+
+// Execute submits the subtasks, waits for their batches to end, and returns
+// their results in the order of subtasks. Requests that fail, or that
+// expire before their batch ends, are unsuccessful results rather than an
+// error. If ctx is done while waiting, the batches keep running.
+func (b *BatchExecutor) Execute(ctx context.Context, subtasks []Subtask) ([]SubtaskResult, error) {
+	ctx, cancel := b.cfg.startRun(ctx)
+	defer cancel()
+
+	if b.client.Provider != nil {
+		b.cfg.logger.Info("provider has no batch API, running subtasks live", "provider", b.client.Provider.Name(), "subtasks", len(subtasks))
+		return NewSectioningParallelizer(b.client, b.cfg.childOptions()...).ExecuteParallel(ctx, subtasks), nil
+	}
+
+	// Submit every batch before waiting, so they are processed together
+	var batchIDs []string
+	for start := 0; start < len(subtasks); start += b.maxBatchSize {
+		end := start + b.maxBatchSize
+		if end > len(subtasks) {
+			end = len(subtasks)
+		}
+		batchID, err := journaled(ctx, "batch:"+b.cfg.pattern, batchInput(b.cfg.model, subtasks[start:end]), func(ctx context.Context) (string, error) {
+			status, err := b.submit(ctx, subtasks[start:end], start)
+			if err != nil {
+				return "", err
+			}
+			return status.ID, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		b.cfg.logger.Info("submitted batch", "batch", batchID, "requests", end-start)
+		batchIDs = append(batchIDs, batchID)
+	}
+
+	results := pendingResults(subtasks)
+	for _, batchID := range batchIDs {
+		status, err := b.Wait(ctx, batchID)
+		if err != nil {
+			return nil, fmt.Errorf("waiting for batch %s: %w", batchID, err)
+		}
+		if err := b.collect(ctx, status, subtasks, results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// Submit submits the subtasks as a single batch, identifying each request
+// by its index in subtasks, and returns the batch's initial status
+func (b *BatchExecutor) Submit(ctx context.Context, subtasks []Subtask) (*BatchStatus, error) {
+	if len(subtasks) > MaxBatchRequests {
+		return nil, fmt.Errorf("%d subtasks exceed the batch limit of %d", len(subtasks), MaxBatchRequests)
+	}
+	return b.submit(ctx, subtasks, 0)
+}
+
+// submit submits subtasks, numbering their requests from offset
+func (b *BatchExecutor) submit(ctx context.Context, subtasks []Subtask, offset int) (*BatchStatus, error) {
+	type batchRequest struct {
+		CustomID string         `json:"custom_id"`
+		Params   MessageRequest `json:"params"`
+	}
+	requests := make([]batchRequest, len(subtasks))
+	files := false
+	for i, st := range subtasks {
+		req := messageRequest(st.message(), b.cfg.model, b.cfg.tokens(2048))
+		b.cfg.request.apply(&req)
+		files = files || usesFiles(req)
+		requests[i] = batchRequest{CustomID: batchCustomID(offset + i), Params: req}
+	}
+
+	var status BatchStatus
+	err := b.client.batchAPI(ctx, http.MethodPost, b.client.apiURL("/v1/messages/batches"), files, map[string]interface{}{"requests": requests}, &status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit batch: %w", err)
+	}
+	return &status, nil
+}
+
+// Status returns the current status of a batch
+func (b *BatchExecutor) Status(ctx context.Context, batchID string) (*BatchStatus, error) {
+	var status BatchStatus
+	if err := b.client.batchAPI(ctx, http.MethodGet, b.client.apiURL("/v1/messages/batches/"+batchID), false, nil, &status); err != nil {
+		return nil, fmt.Errorf("failed to get batch %s: %w", batchID, err)
+	}
+	return &status, nil
+}
+
+// Wait polls a batch until it ends
+func (b *BatchExecutor) Wait(ctx context.Context, batchID string) (*BatchStatus, error) {
+	for {
+		status, err := b.Status(ctx, batchID)
+		if err != nil {
+			return nil, err
+		}
+		b.cfg.publish(ctx, PhaseBatchPolled, map[string]interface{}{
+			"batch":      status.ID,
+			"status":     status.ProcessingStatus,
+			"processing": status.RequestCounts.Processing,
+			"succeeded":  status.RequestCounts.Succeeded,
+			"errored":    status.RequestCounts.Errored,
+		})
+		if b.onPoll != nil {
+			b.onPoll(*status)
+		}
+		if status.Ended() {
+			return status, nil
+		}
+		if err := sleepContext(ctx, b.pollInterval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Cancel asks the API to stop a batch. Requests already processed keep
+// their results.
+func (b *BatchExecutor) Cancel(ctx context.Context, batchID string) (*BatchStatus, error) {
+	var status BatchStatus
+	if err := b.client.batchAPI(ctx, http.MethodPost, b.client.apiURL("/v1/messages/batches/"+batchID+"/cancel"), false, nil, &status); err != nil {
+		return nil, fmt.Errorf("failed to cancel batch %s: %w", batchID, err)
+	}
+	return &status, nil
+}
+
+// Results returns the results of an ended batch that was submitted with
+// Submit for subtasks, in the order of subtasks
+func (b *BatchExecutor) Results(ctx context.Context, status *BatchStatus, subtasks []Subtask) ([]SubtaskResult, error) {
+	results := pendingResults(subtasks)
+	if err := b.collect(ctx, status, subtasks, results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// collect reads an ended batch's results into results, by the index each
+// request was submitted with
+func (b *BatchExecutor) collect(ctx context.Context, status *BatchStatus, subtasks []Subtask, results []SubtaskResult) error {
+	if !status.Ended() || status.ResultsURL == "" {
+		return fmt.Errorf("batch %s has not ended", status.ID)
+	}
+	var duration time.Duration
+	if status.EndedAt != nil {
+		duration = status.EndedAt.Sub(status.CreatedAt)
+	}
+
+	resp, err := b.client.batchGet(ctx, status.ResultsURL)
+	if err != nil {
+		return fmt.Errorf("failed to get results of batch %s: %w", status.ID, err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var line struct {
+			CustomID string `json:"custom_id"`
+			Result   struct {
+				Type    string          `json:"type"`
+				Message json.RawMessage `json:"message"`
+				Error   struct {
+					Error struct {
+						Type    string `json:"type"`
+						Message string `json:"message"`
+					} `json:"error"`
+				} `json:"error"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return fmt.Errorf("invalid result in batch %s: %w", status.ID, err)
+		}
+		i, ok := batchIndex(line.CustomID)
+		if !ok || i >= len(subtasks) {
+			continue
+		}
+
+		result := SubtaskResult{Name: subtasks[i].Name, Duration: duration}
+		switch line.Result.Type {
+		case "succeeded":
+			text, usage, err := decodeMessage(ctx, bytes.NewReader(line.Result.Message))
+			b.cfg.recordUsageAt(ctx, b.cfg.model, usage, BatchRate)
+			b.cfg.chargeUsageAt(ctx, b.cfg.model, usage, BatchRate)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Result = text
+				result.Success = true
+			}
+		case "errored":
+			result.Error = fmt.Sprintf("%s: %s", line.Result.Error.Error.Type, line.Result.Error.Error.Message)
+		default:
+			result.Error = "request " + line.Result.Type
+		}
+		results[i] = result
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read results of batch %s: %w", status.ID, err)
+	}
+	return nil
+}
+
+// pendingResults are the results of subtasks before their batch has
+// reported them
+func pendingResults(subtasks []Subtask) []SubtaskResult {
+	results := make([]SubtaskResult, len(subtasks))
+	for i, st := range subtasks {
+		results[i] = SubtaskResult{Name: st.Name, Error: "no result in batch"}
+	}
+	return results
+}
+
+// batchCustomID identifies the request for the subtask at index i
+func batchCustomID(i int) string {
+	return "subtask-" + strconv.Itoa(i)
+}
+
+func batchIndex(customID string) (int, bool) {
+	i, err := strconv.Atoi(strings.TrimPrefix(customID, "subtask-"))
+	return i, err == nil && strings.HasPrefix(customID, "subtask-")
+}
+
+// batchInput identifies a batch's requests for journaling
+func batchInput(model string, subtasks []Subtask) string {
+	h := sha256.New()
+	for _, st := range subtasks {
+		fmt.Fprintf(h, "%s\x00", messageInput(st.message()))
+	}
+	return fmt.Sprintf("%s\x00%d\x00%x", model, len(subtasks), h.Sum(nil))
+}
+
+// batchAPI sends a request to the Message Batches API, retrying it under
+// the client's policy, and decodes the response into out
+func (c *AnthropicClient) batchAPI(ctx context.Context, method, url string, files bool, body, out interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+	return c.retry(ctx, "", func() error {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		c.setBatchHeaders(req)
+		if body != nil {
+			req.Header.Set("content-type", "application/json")
+		}
+		if files {
+			req.Header.Set("anthropic-beta", filesBeta)
+		}
+		resp, err := c.batchDo(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil
+	})
+}
+
+// batchGet fetches a batch's results. The caller must close the response
+// body.
+func (c *AnthropicClient) batchGet(ctx context.Context, url string) (*http.Response, error) {
+	var resp *http.Response
+	err := c.retry(ctx, "", func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		c.setBatchHeaders(req)
+		resp, err = c.batchDo(req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *AnthropicClient) setBatchHeaders(req *http.Request) {
+	req.Header.Set("x-api-key", c.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+}
+
+// batchDo sends req and returns the response once its status is 200, or
+// an *APIError
+func (c *AnthropicClient) batchDo(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,273 @@
+/*
+ * Best-of-N Pattern Implementation for Go
+ * Sample several candidates concurrently and keep the one a judge or scorer rates best
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/jsonx"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/schema"
+)
+
+// ScoreFunc scores a candidate completion of prompt; higher is better
+type ScoreFunc func(ctx context.Context, prompt, candidate string) (float64, error)
+
+// BestOfNCandidate is one sampled completion and its score
+type BestOfNCandidate struct {
+	Index       int
+	Text        string
+	Temperature float64
+	Score       float64
+	// Reasoning is the judge's explanation of the score
+	Reasoning string
+	// Error holds why the candidate could not be generated or scored; such
+	// candidates are never selected
+	Error string
+}
+
+// BestOfNResult is the selected completion with every candidate
+type BestOfNResult struct {
+	Best       string
+	BestIndex  int
+	Candidates []BestOfNCandidate
+}
+
+// bestOfNJudgement is the structured output of the judge
+type bestOfNJudgement struct {
+	Scores []bestOfNScore `json:"scores"`
+}
+
+type bestOfNScore struct {
+	Candidate int     `json:"candidate" description:"Candidate number as listed"`
+	Score     float64 `json:"score" description:"Quality from 0 (unusable) to 10 (excellent)"`
+	Reasoning string  `json:"reasoning" description:"One or two sentences justifying the score"`
+}
+
+// BestOfN generates N candidate completions concurrently, each at a
+// different temperature, and returns the one rated best. Candidates are
+// rated by a judge model that sees them side by side, or by a ScoreFunc,
+// e.g. running generated code against tests.
+//
+// Example:
+//
+//	best := NewBestOfN(client, 5).
+//	    SetCriteria("Correct, idiomatic Go with error handling").
+//	    WithJudgeModel("claude-opus-4-20250514")
+//	result, err := best.Run(ctx, "Write a function that parses ISO 8601 durations")
+//	fmt.Println(result.Best)
+type BestOfN struct {
+	client         *AnthropicClient
+	cfg            patternConfig
+	n              int
+	minTemperature float64
+	maxTemperature float64
+	judgeModel     string
+	criteria       string
+	scorer         ScoreFunc
+}
+
+// NewBestOfN creates a BestOfN sampling n candidates at temperatures spread
+// from 0.3 to 1.0
+func NewBestOfN(client *AnthropicClient, n int, opts ...Option) *BestOfN {
+	cfg := newPatternConfig("best_of_n", opts)
+	return &BestOfN{
+		client:         client,
+		cfg:            cfg,
+		n:              n,
+		minTemperature: 0.3,
+		maxTemperature: 1.0,
+		judgeModel:     cfg.model,
+	}
+}
+
+// SetTemperatures spreads candidate temperatures evenly from min to max
+func (b *BestOfN) SetTemperatures(min, max float64) *BestOfN {
+	b.minTemperature = min
+	b.maxTemperature = max
+	return b
+}
+
+// WithJudgeModel sets a different model for the judge
+func (b *BestOfN) WithJudgeModel(model string) *BestOfN {
+	b.judgeModel = model
+	return b
+}
+
+// SetCriteria tells the judge what makes a candidate good
+func (b *BestOfN) SetCriteria(criteria string) *BestOfN {
+	b.criteria = criteria
+	return b
+}
+
+// SetScorer rates candidates with fn instead of a judge model. Candidates
+// are scored concurrently.
+func (b *BestOfN) SetScorer(fn ScoreFunc) *BestOfN {
+	b.scorer = fn
+	return b
+}
+
+// Run samples candidates for prompt and returns the best. The earliest
+// candidate wins a tie.
+func (b *BestOfN) Run(ctx context.Context, prompt string) (*BestOfNResult, error) {
+	if b.n < 1 {
+		return nil, fmt.Errorf("best-of-n needs at least one candidate, got %d", b.n)
+	}
+
+	ctx, cancel := b.cfg.startRun(ctx)
+	defer cancel()
+
+	genCtx, span := StartSpan(ctx, "best_of_n.generate")
+	candidates := b.generate(genCtx, prompt)
+	span.Finish(nil)
+
+	generated := 0
+	for _, c := range candidates {
+		if c.Error == "" {
+			generated++
+		}
+	}
+	if generated == 0 {
+		return nil, fmt.Errorf("all %d candidates failed: %s", b.n, candidates[0].Error)
+	}
+
+	scoreCtx, span := StartSpan(ctx, "best_of_n.score")
+	var err error
+	if b.scorer != nil {
+		b.score(scoreCtx, prompt, candidates)
+	} else {
+		err = b.judge(scoreCtx, prompt, candidates)
+	}
+	span.Finish(err)
+	if err != nil {
+		return nil, fmt.Errorf("judging failed: %w", err)
+	}
+
+	bestIndex := -1
+	for i, c := range candidates {
+		if c.Error == "" && (bestIndex < 0 || c.Score > candidates[bestIndex].Score) {
+			bestIndex = i
+		}
+	}
+	if bestIndex < 0 {
+		return nil, fmt.Errorf("no candidate could be scored: %s", candidates[0].Error)
+	}
+
+	return &BestOfNResult{
+		Best:       candidates[bestIndex].Text,
+		BestIndex:  bestIndex,
+		Candidates: candidates,
+	}, nil
+}
+
+// temperature returns the sampling temperature of candidate i
+func (b *BestOfN) temperature(i int) float64 {
+	if b.n == 1 {
+		return b.minTemperature
+	}
+	return b.minTemperature + (b.maxTemperature-b.minTemperature)*float64(i)/float64(b.n-1)
+}
+
+// generate samples every candidate concurrently. A failed candidate is
+// recorded rather than failing the run.
+func (b *BestOfN) generate(ctx context.Context, prompt string) []BestOfNCandidate {
+	candidates := make([]BestOfNCandidate, b.n)
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			temperature := b.temperature(idx)
+			// The index keeps each candidate's journal entry distinct
+			input := fmt.Sprintf("%s\x00%d", prompt, idx)
+			text, err := b.cfg.callWith(ctx, b.cfg.model, input, func(ctx context.Context) (string, Usage, error) {
+				req := promptRequest(prompt, b.cfg.model, b.cfg.tokens(2048))
+				req.Temperature = &temperature
+				b.cfg.request.apply(&req)
+				return b.client.Send(ctx, req)
+			})
+			candidates[idx] = BestOfNCandidate{Index: idx, Text: text, Temperature: temperature}
+			if err != nil {
+				candidates[idx].Error = err.Error()
+				b.cfg.logger.Warn("candidate failed", "candidate", idx, "error", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return candidates
+}
+
+// score rates the generated candidates with the ScoreFunc
+func (b *BestOfN) score(ctx context.Context, prompt string, candidates []BestOfNCandidate) {
+	var wg sync.WaitGroup
+	for i := range candidates {
+		if candidates[i].Error != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(c *BestOfNCandidate) {
+			defer wg.Done()
+			score, err := b.scorer(ctx, prompt, c.Text)
+			c.Score = score
+			if err != nil {
+				c.Error = fmt.Sprintf("scoring failed: %v", err)
+			}
+		}(&candidates[i])
+	}
+	wg.Wait()
+}
+
+// judge asks the judge model to score the generated candidates side by side
+func (b *BestOfN) judge(ctx context.Context, prompt string, candidates []BestOfNCandidate) error {
+	var listing []map[string]interface{}
+	for i, c := range candidates {
+		if c.Error == "" {
+			listing = append(listing, map[string]interface{}{"number": i + 1, "text": c.Text})
+		}
+	}
+	criteria := b.criteria
+	if criteria == "" {
+		criteria = "Correctness, completeness, and clarity"
+	}
+
+	judgePrompt, err := b.cfg.prompt("best_of_n.judge", map[string]interface{}{
+		"task":       prompt,
+		"criteria":   criteria,
+		"candidates": listing,
+		"schema":     schema.MustFor[bestOfNJudgement]().String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	response, err := b.cfg.call(ctx, b.client, judgePrompt, b.judgeModel, 1024)
+	if err != nil {
+		return err
+	}
+
+	var judgement bestOfNJudgement
+	if err := jsonx.Unmarshal(response, &judgement); err != nil {
+		return fmt.Errorf("failed to parse judgement: %w", err)
+	}
+
+	scored := make([]bool, len(candidates))
+	for _, s := range judgement.Scores {
+		idx := s.Candidate - 1
+		if idx < 0 || idx >= len(candidates) || candidates[idx].Error != "" {
+			continue
+		}
+		candidates[idx].Score = s.Score
+		candidates[idx].Reasoning = s.Reasoning
+		scored[idx] = true
+	}
+	for i := range candidates {
+		if candidates[i].Error == "" && !scored[i] {
+			candidates[i].Error = "not scored by the judge"
+		}
+	}
+	return nil
+}
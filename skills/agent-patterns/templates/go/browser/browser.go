@@ -0,0 +1,345 @@
+/*
+ * Browser Automation for Go Agent Patterns
+ * A headless Chrome tool, over chromedp, confined to allowed domains and a step budget
+ */
+
+// Package browser gives agents a real browser, so they can read pages
+// that need JavaScript, follow links, and fill in forms. Pages are only
+// visited on allowed hosts, and each browser stops after a number of
+// steps, so an agent cannot wander the web indefinitely.
+//
+// Example:
+//
+//	b := browser.New("*.wikipedia.org", "arxiv.org")
+//	defer b.Close()
+//	agent := agentpatterns.NewAutonomousAgent(client).RegisterTool(b.Tool())
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/tools"
+)
+
+// ErrStepBudget is returned once a browser has taken MaxSteps steps
+var ErrStepBudget = errors.New("browser step budget exhausted")
+
+// Browser drives one headless Chrome tab, started on first use. Navigation
+// is limited to the allowed hosts: a link or redirect that leaves them is
+// undone and reported as an error. Resources a page loads itself, such as
+// images and scripts, are not restricted.
+type Browser struct {
+	// AllowedHosts lists the hosts pages may be on; "*.example.com" allows
+	// the subdomains of example.com
+	AllowedHosts []string
+	// MaxSteps caps the actions taken until Reset
+	MaxSteps int
+	// MaxText caps the characters of text returned per action
+	MaxText int
+	// Timeout limits each action
+	Timeout time.Duration
+	// ScreenshotDir, if set, adds the screenshot action, which saves PNG
+	// files there
+	ScreenshotDir string
+	// ExecPath is the Chrome binary; empty finds it on the system
+	ExecPath string
+
+	mu     sync.Mutex
+	steps  int
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New creates a browser for the allowed hosts with a budget of 20 steps,
+// 30 seconds per action, and 20,000 characters of text per action
+func New(allowedHosts ...string) *Browser {
+	return &Browser{
+		AllowedHosts: allowedHosts,
+		MaxSteps:     20,
+		MaxText:      20000,
+		Timeout:      30 * time.Second,
+	}
+}
+
+// Close shuts Chrome down
+func (b *Browser) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cancel != nil {
+		b.cancel()
+		b.ctx, b.cancel = nil, nil
+	}
+}
+
+// Reset restores the step budget, e.g. between agent runs
+func (b *Browser) Reset() {
+	b.mu.Lock()
+	b.steps = 0
+	b.mu.Unlock()
+}
+
+// Tool returns the browser as an agent tool named "browser"
+func (b *Browser) Tool() agentpatterns.AgentTool {
+	actions := "navigate (url), text (optional selector), click (selector), fill (selector, value)"
+	if b.ScreenshotDir != "" {
+		actions += ", screenshot"
+	}
+	return agentpatterns.AgentTool{
+		Name: "browser",
+		Description: fmt.Sprintf("Use a web browser. Actions: %s. Selectors are CSS selectors. "+
+			"navigate and click return the page's title, URL, and text. Allowed hosts: %s. At most %d actions.",
+			actions, strings.Join(b.AllowedHosts, ", "), b.MaxSteps),
+		Parameters: map[string]agentpatterns.ParameterDef{
+			"action":   {Type: "string", Description: "navigate, text, click, fill, or screenshot", Required: true},
+			"url":      {Type: "string", Description: "URL to navigate to"},
+			"selector": {Type: "string", Description: "CSS selector of the element to read, click, or fill"},
+			"value":    {Type: "string", Description: "Text to fill in"},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			action, _ := args["action"].(string)
+			rawURL, _ := args["url"].(string)
+			selector, _ := args["selector"].(string)
+			value, _ := args["value"].(string)
+			switch action {
+			case "navigate":
+				return b.Navigate(ctx, rawURL)
+			case "text":
+				return b.Text(ctx, selector)
+			case "click":
+				return b.Click(ctx, selector)
+			case "fill":
+				if err := b.Fill(ctx, selector, value); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Filled %s", selector), nil
+			case "screenshot":
+				if b.ScreenshotDir == "" {
+					return "", fmt.Errorf("screenshots are not enabled")
+				}
+				return b.saveScreenshot(ctx)
+			default:
+				return "", fmt.Errorf("unknown action %q", action)
+			}
+		},
+	}
+}
+
+// Navigate opens a URL on an allowed host and returns the page
+func (b *Browser) Navigate(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("only http and https URLs can be opened")
+	}
+	if !tools.HostAllowed(b.AllowedHosts, u.Hostname()) {
+		return "", fmt.Errorf("%w: %s", tools.ErrHostNotAllowed, u.Hostname())
+	}
+	if err := b.run(ctx, chromedp.Navigate(u.String())); err != nil {
+		return "", err
+	}
+	return b.page(ctx)
+}
+
+// Text returns the visible text of the first element matching selector,
+// or of the whole page if selector is empty
+func (b *Browser) Text(ctx context.Context, selector string) (string, error) {
+	if selector == "" {
+		selector = "body"
+	}
+	var text interface{}
+	quoted, _ := json.Marshal(selector)
+	script := fmt.Sprintf(`(() => { const el = document.querySelector(%s); return el ? el.innerText : null })()`, quoted)
+	if err := b.run(ctx, chromedp.Evaluate(script, &text)); err != nil {
+		return "", err
+	}
+	if err := b.guard(ctx); err != nil {
+		return "", err
+	}
+	s, ok := text.(string)
+	if !ok {
+		return "", fmt.Errorf("no element matches %s", selector)
+	}
+	return b.truncate(s), nil
+}
+
+// Click clicks the first visible element matching selector and returns
+// the page it leads to
+func (b *Browser) Click(ctx context.Context, selector string) (string, error) {
+	if selector == "" {
+		return "", fmt.Errorf("selector is required")
+	}
+	// Give a navigation the click starts a moment to begin
+	if err := b.run(ctx, chromedp.Click(selector, chromedp.ByQuery, chromedp.NodeVisible), chromedp.Sleep(500*time.Millisecond)); err != nil {
+		return "", err
+	}
+	return b.page(ctx)
+}
+
+// Fill replaces the value of the input matching selector
+func (b *Browser) Fill(ctx context.Context, selector, value string) error {
+	if selector == "" {
+		return fmt.Errorf("selector is required")
+	}
+	err := b.run(ctx,
+		chromedp.Clear(selector, chromedp.ByQuery, chromedp.NodeVisible),
+		chromedp.SendKeys(selector, value, chromedp.ByQuery))
+	if err != nil {
+		return err
+	}
+	// A newline in value can submit the form
+	return b.guard(ctx)
+}
+
+// Screenshot returns a PNG of the whole page
+func (b *Browser) Screenshot(ctx context.Context) ([]byte, error) {
+	var png []byte
+	if err := b.run(ctx, chromedp.FullScreenshot(&png, 100)); err != nil {
+		return nil, err
+	}
+	return png, nil
+}
+
+// saveScreenshot writes a screenshot to ScreenshotDir and returns its path
+func (b *Browser) saveScreenshot(ctx context.Context) (string, error) {
+	png, err := b.Screenshot(ctx)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(b.ScreenshotDir, fmt.Sprintf("screenshot-%d.png", time.Now().UnixNano()))
+	if err := os.WriteFile(path, png, 0o644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Saved screenshot to %s", path), nil
+}
+
+// page describes the current page, after checking it is on an allowed
+// host. Reading it is part of the step that led there.
+func (b *Browser) page(ctx context.Context) (string, error) {
+	var title, location string
+	var text interface{}
+	err := b.do(ctx, false,
+		chromedp.Location(&location),
+		chromedp.Title(&title),
+		chromedp.Evaluate(`document.body ? document.body.innerText : ""`, &text))
+	if err != nil {
+		return "", err
+	}
+	if err := b.checkLocation(ctx, location); err != nil {
+		return "", err
+	}
+	body, _ := text.(string)
+	return fmt.Sprintf("Title: %s\nURL: %s\n\n%s", title, location, b.truncate(body)), nil
+}
+
+// guard checks that the current page is on an allowed host, since
+// scripts and forms can navigate too
+func (b *Browser) guard(ctx context.Context) error {
+	var location string
+	if err := b.do(ctx, false, chromedp.Location(&location)); err != nil {
+		return err
+	}
+	return b.checkLocation(ctx, location)
+}
+
+// checkLocation leaves a page that is not on an allowed host
+func (b *Browser) checkLocation(ctx context.Context, location string) error {
+	u, err := url.Parse(location)
+	if err == nil && (u.Scheme == "http" || u.Scheme == "https") && tools.HostAllowed(b.AllowedHosts, u.Hostname()) {
+		return nil
+	}
+	if err := b.do(ctx, false, chromedp.Navigate("about:blank")); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: the page went to %s and was closed", tools.ErrHostNotAllowed, location)
+}
+
+// run takes one step of the budget and runs actions
+func (b *Browser) run(ctx context.Context, actions ...chromedp.Action) error {
+	return b.do(ctx, true, actions...)
+}
+
+// do runs actions in the browser tab, starting Chrome if needed, within
+// the action timeout and the caller's context
+func (b *Browser) do(ctx context.Context, step bool, actions ...chromedp.Action) error {
+	b.mu.Lock()
+	if step {
+		if b.MaxSteps > 0 && b.steps >= b.MaxSteps {
+			b.mu.Unlock()
+			return fmt.Errorf("%w after %d steps", ErrStepBudget, b.MaxSteps)
+		}
+		b.steps++
+	}
+	if b.ctx == nil {
+		if err := b.start(); err != nil {
+			b.mu.Unlock()
+			return err
+		}
+	}
+	tab := b.ctx
+	b.mu.Unlock()
+
+	actx, cancel := context.WithCancel(tab)
+	defer cancel()
+	if b.Timeout > 0 {
+		actx, cancel = context.WithTimeout(actx, b.Timeout)
+		defer cancel()
+	}
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	if err := chromedp.Run(actx, actions...); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("browser: %w", err)
+	}
+	return nil
+}
+
+// start launches Chrome with a fresh profile and opens its tab
+func (b *Browser) start() error {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("disable-extensions", true),
+	)
+	if b.ExecPath != "" {
+		opts = append(opts, chromedp.ExecPath(b.ExecPath))
+	}
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), opts...)
+	tabCtx, tabCancel := chromedp.NewContext(allocCtx)
+	// Chrome lives as long as the context of the first Run, so start it
+	// here rather than under an action's timeout
+	if err := chromedp.Run(tabCtx); err != nil {
+		tabCancel()
+		allocCancel()
+		return fmt.Errorf("failed to start browser: %w", err)
+	}
+	b.ctx = tabCtx
+	b.cancel = func() {
+		tabCancel()
+		allocCancel()
+	}
+	return nil
+}
+
+// truncate shortens text to MaxText characters
+func (b *Browser) truncate(text string) string {
+	runes := []rune(strings.TrimSpace(text))
+	if b.MaxText <= 0 || len(runes) <= b.MaxText {
+		return string(runes)
+	}
+	return fmt.Sprintf("%s\n[... %d more characters truncated]", string(runes[:b.MaxText]), len(runes)-b.MaxText)
+}
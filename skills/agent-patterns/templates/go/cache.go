@@ -0,0 +1,244 @@
+/*
+ * Response Cache for Go Agent Patterns
+ * Serving repeated identical requests without an API call
+ */
+
+package agentpatterns
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/limiter"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/store"
+)
+
+// Cache stores responses by key. Errors are treated as cache misses, so a
+// cache that is down only costs API calls. Implementations must be safe
+// for concurrent use.
+//
+// Example, backed by Redis (github.com/redis/go-redis/v9):
+//
+//	type redisCache struct{ rdb *redis.Client }
+//
+//	func (c redisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+//	    value, err := c.rdb.Get(ctx, key).Bytes()
+//	    if errors.Is(err, redis.Nil) {
+//	        return nil, false, nil
+//	    }
+//	    return value, err == nil, err
+//	}
+//
+//	func (c redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+//	    return c.rdb.Set(ctx, key, value, ttl).Err()
+//	}
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value for ttl, or until evicted if ttl is zero
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// cachedResponse is the cached form of a reply
+type cachedResponse struct {
+	Text string `json:"text"`
+}
+
+type cacheBypassKey struct{}
+type cacheTTLKey struct{}
+
+// WithCacheBypass makes calls made with ctx skip the client's cache, both
+// reading and writing, e.g. to force a fresh answer
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// WithCacheTTL overrides the client's CacheTTL for responses cached by
+// calls made with ctx
+func WithCacheTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, cacheTTLKey{}, ttl)
+}
+
+// cacheKey identifies a request by everything that affects its reply
+func (c *AnthropicClient) cacheKey(req MessageRequest) (string, error) {
+	provider := limiter.ProviderAnthropic
+	if c.Provider != nil {
+		provider = c.Provider.Name()
+	}
+	req.Stream = false
+	data, err := json.Marshal(struct {
+		Provider string         `json:"provider"`
+		Request  MessageRequest `json:"request"`
+	}{provider, req})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "llm/" + hex.EncodeToString(sum[:]), nil
+}
+
+// cached serves req from the client's cache, or calls send and caches its
+// reply. Cache hits report no usage, since no tokens were billed, and are
+// passed to onDelta whole. Requests with a non-zero temperature ask for
+// varied replies, as voting does, so they are never cached.
+func (c *AnthropicClient) cached(ctx context.Context, req MessageRequest, onDelta func(text string), send func() (string, Usage, error)) (string, Usage, error) {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	if c.Cache == nil || bypass || (req.Temperature != nil && *req.Temperature != 0) {
+		return send()
+	}
+	key, err := c.cacheKey(req)
+	if err != nil {
+		return send()
+	}
+
+	if data, ok, err := c.Cache.Get(ctx, key); err == nil && ok {
+		var hit cachedResponse
+		if json.Unmarshal(data, &hit) == nil {
+			publish(ctx, "client", PhaseCacheHit, map[string]interface{}{"model": req.Model})
+			if onDelta != nil {
+				onDelta(hit.Text)
+			}
+			return hit.Text, Usage{}, nil
+		}
+	}
+
+	text, usage, err := send()
+	if err != nil {
+		return text, usage, err
+	}
+	ttl := c.CacheTTL
+	if d, ok := ctx.Value(cacheTTLKey{}).(time.Duration); ok {
+		ttl = d
+	}
+	if data, err := json.Marshal(cachedResponse{Text: text}); err == nil {
+		c.Cache.Set(ctx, key, data, ttl)
+	}
+	return text, usage, nil
+}
+
+// LRUCache is an in-memory Cache holding up to a fixed number of entries,
+// evicting the least recently used
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRUCache creates a cache holding up to capacity entries
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get implements Cache
+func (c *LRUCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := el.Value.(*lruEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true, nil
+}
+
+// Set implements Cache
+func (c *LRUCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &lruEntry{key: key, value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of cached entries, including expired ones not yet
+// evicted
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// StoreCache keeps cached responses in a store.Store, e.g. a FileStore for a
+// disk cache shared by test runs. Expired entries are deleted when read.
+type StoreCache struct {
+	st     store.Store
+	prefix string
+}
+
+// storeCacheEntry is a value with its expiry, as kept in the store
+type storeCacheEntry struct {
+	Value   []byte    `json:"value"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// NewStoreCache creates a cache keeping entries under prefix in st
+func NewStoreCache(st store.Store, prefix string) *StoreCache {
+	return &StoreCache{st: st, prefix: prefix}
+}
+
+// Get implements Cache
+func (c *StoreCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := c.st.Get(ctx, c.prefix+key)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var entry storeCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false, err
+	}
+	if !entry.Expires.IsZero() && time.Now().After(entry.Expires) {
+		return nil, false, c.st.Delete(ctx, c.prefix+key)
+	}
+	return entry.Value, true, nil
+}
+
+// Set implements Cache
+func (c *StoreCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := storeCacheEntry{Value: value}
+	if ttl > 0 {
+		entry.Expires = time.Now().Add(ttl)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return c.st.Put(ctx, c.prefix+key, data)
+}
@@ -0,0 +1,221 @@
+/*
+ * Confidence Calibration for Go Agent Patterns
+ * Mapping self-reported confidence to the observed rate of correct outputs
+ */
+
+package agentpatterns
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CalibrationMethod is how a Calibration is fitted
+type CalibrationMethod string
+
+const (
+	// CalibrationIsotonic fits a non-decreasing step curve through the
+	// observed accuracy. It makes no assumption about the curve's shape but
+	// needs a few hundred samples to be smooth.
+	CalibrationIsotonic CalibrationMethod = "isotonic"
+	// CalibrationPlatt fits a logistic curve (Platt scaling). It works with
+	// fewer samples, but only corrects confidence that is uniformly too high
+	// or too low.
+	CalibrationPlatt CalibrationMethod = "platt"
+)
+
+// CalibrationSample is a self-reported confidence and whether the output it
+// came with was judged correct
+type CalibrationSample struct {
+	Confidence float64 `json:"confidence"`
+	Correct    bool    `json:"correct"`
+}
+
+// CalibrationPoint is a point of an isotonic calibration curve: outputs
+// reported around Confidence were correct at the rate Accuracy
+type CalibrationPoint struct {
+	Confidence float64 `json:"confidence"`
+	Accuracy   float64 `json:"accuracy"`
+	Samples    int     `json:"samples"`
+}
+
+// Calibration maps self-reported confidence to the probability that an
+// output is correct. It marshals to JSON, so a calibration fitted once on
+// an eval run can be saved and loaded with the pattern.
+//
+// Example:
+//
+//	cal, err := FitCalibration(samples, CalibrationIsotonic)
+//	optimizer := NewConfidenceBasedOptimizer(client, WithCalibration(cal))
+//	raw, ok := cal.Threshold(0.9) // reported confidence needed for 90% correct
+type Calibration struct {
+	Method CalibrationMethod `json:"method"`
+	// Samples is the number of samples fitted
+	Samples int `json:"samples"`
+	// Points is the curve of an isotonic calibration, by confidence
+	Points []CalibrationPoint `json:"points,omitempty"`
+	// A and B are the parameters of a Platt calibration,
+	// 1 / (1 + exp(-(A*confidence + B)))
+	A float64 `json:"a,omitempty"`
+	B float64 `json:"b,omitempty"`
+}
+
+// FitCalibration fits a calibration curve to samples
+func FitCalibration(samples []CalibrationSample, method CalibrationMethod) (*Calibration, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no calibration samples")
+	}
+	cal := &Calibration{Method: method, Samples: len(samples)}
+	switch method {
+	case CalibrationIsotonic:
+		cal.Points = fitIsotonic(samples)
+	case CalibrationPlatt:
+		cal.A, cal.B = fitPlatt(samples)
+	default:
+		return nil, fmt.Errorf("unknown calibration method: %s", method)
+	}
+	return cal, nil
+}
+
+// Apply returns the calibrated probability that an output reported with
+// confidence is correct
+func (c *Calibration) Apply(confidence float64) float64 {
+	if c.Method == CalibrationPlatt {
+		return sigmoid(c.A*confidence + c.B)
+	}
+	points := c.Points
+	if len(points) == 0 {
+		return confidence
+	}
+	if confidence <= points[0].Confidence {
+		return points[0].Accuracy
+	}
+	last := points[len(points)-1]
+	if confidence >= last.Confidence {
+		return last.Accuracy
+	}
+	i := sort.Search(len(points), func(i int) bool { return points[i].Confidence >= confidence })
+	lo, hi := points[i-1], points[i]
+	return lo.Accuracy + (confidence-lo.Confidence)*(hi.Accuracy-lo.Accuracy)/(hi.Confidence-lo.Confidence)
+}
+
+// Threshold returns the lowest self-reported confidence whose calibrated
+// probability reaches target, for use as a raw confidence threshold. It
+// returns false when no confidence reaches target.
+func (c *Calibration) Threshold(target float64) (float64, bool) {
+	if c.Apply(0) >= target {
+		return 0, true
+	}
+	if c.Method == CalibrationPlatt {
+		if c.A <= 0 || target >= 1 {
+			return 0, false
+		}
+		x := (math.Log(target/(1-target)) - c.B) / c.A
+		return x, x <= 1
+	}
+	points := c.Points
+	for i := 1; i < len(points); i++ {
+		if points[i].Accuracy >= target {
+			lo, hi := points[i-1], points[i]
+			return lo.Confidence + (target-lo.Accuracy)*(hi.Confidence-lo.Confidence)/(hi.Accuracy-lo.Accuracy), true
+		}
+	}
+	return 0, false
+}
+
+// fitIsotonic fits a non-decreasing curve with the pool adjacent violators
+// algorithm: samples are sorted by confidence, and neighbouring blocks are
+// merged while the lower one is more accurate
+func fitIsotonic(samples []CalibrationSample) []CalibrationPoint {
+	sorted := append([]CalibrationSample(nil), samples...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Confidence < sorted[j].Confidence })
+
+	type block struct {
+		confidence, correct float64
+		n                   int
+	}
+	var blocks []block
+	for _, s := range sorted {
+		b := block{confidence: s.Confidence, n: 1}
+		if s.Correct {
+			b.correct = 1
+		}
+		blocks = append(blocks, b)
+		for len(blocks) > 1 {
+			prev, cur := blocks[len(blocks)-2], blocks[len(blocks)-1]
+			if prev.correct/float64(prev.n) < cur.correct/float64(cur.n) {
+				break
+			}
+			// Equal accuracies are merged too, leaving one point per step
+			blocks = append(blocks[:len(blocks)-2], block{
+				confidence: prev.confidence + cur.confidence,
+				correct:    prev.correct + cur.correct,
+				n:          prev.n + cur.n,
+			})
+		}
+	}
+
+	points := make([]CalibrationPoint, len(blocks))
+	for i, b := range blocks {
+		points[i] = CalibrationPoint{
+			Confidence: b.confidence / float64(b.n),
+			Accuracy:   b.correct / float64(b.n),
+			Samples:    b.n,
+		}
+	}
+	return points
+}
+
+// fitPlatt fits a logistic curve by Newton's method, with Platt's smoothed
+// targets so that a small all-correct sample does not fit a certainty
+func fitPlatt(samples []CalibrationSample) (float64, float64) {
+	var positives, negatives float64
+	for _, s := range samples {
+		if s.Correct {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	hiTarget := (positives + 1) / (positives + 2)
+	loTarget := 1 / (negatives + 2)
+
+	a, b := 0.0, math.Log((positives+1)/(negatives+1))
+	for iteration := 0; iteration < 100; iteration++ {
+		var ga, gb, haa, hab, hbb float64
+		for _, s := range samples {
+			t := loTarget
+			if s.Correct {
+				t = hiTarget
+			}
+			p := sigmoid(a*s.Confidence + b)
+			d := p - t
+			w := p * (1 - p)
+			ga += d * s.Confidence
+			gb += d
+			haa += w * s.Confidence * s.Confidence
+			hab += w * s.Confidence
+			hbb += w
+		}
+		// A small ridge keeps the step defined when every sample reports
+		// the same confidence
+		haa += 1e-9
+		hbb += 1e-9
+		det := haa*hbb - hab*hab
+		if det == 0 {
+			break
+		}
+		da := (hbb*ga - hab*gb) / det
+		db := (haa*gb - hab*ga) / det
+		a, b = a-da, b-db
+		if math.Abs(da) < 1e-9 && math.Abs(db) < 1e-9 {
+			break
+		}
+	}
+	return a, b
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
@@ -0,0 +1,282 @@
+/*
+ * Text Chunking for Go Agent Patterns
+ * Token-budgeted splitting of prose and code with overlap
+ */
+
+// Package chunker splits long documents and source code into chunks that
+// fit a model's context, measured in tokens.
+//
+// Chunkers cut at the coarsest boundary that keeps chunks within budget:
+// paragraphs, then sentences, then words for prose; top-level declarations,
+// then blank lines, then lines for code. Consecutive pieces are packed into
+// each chunk, and chunks can repeat the end of the previous one as overlap.
+//
+// Example:
+//
+//	c := chunker.NewCodeChunker(2000, chunker.WithOverlap(100))
+//	for _, chunk := range c.Split(source) {
+//	    fmt.Printf("bytes %d-%d, ~%d tokens\n", chunk.Start, chunk.End, chunk.Tokens)
+//	}
+package chunker
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunk is a piece of a larger text
+type Chunk struct {
+	Text string
+	// Start and End are the byte offsets of Text in the original text
+	Start, End int
+	// Tokens is the chunk's size as measured by the chunker's TokenCounter
+	Tokens int
+}
+
+// Chunker splits text into chunks
+type Chunker interface {
+	Split(text string) []Chunk
+}
+
+// TokenCounter measures text in tokens
+type TokenCounter func(text string) int
+
+// EstimateTokens approximates a token count at four characters per token
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Option configures a chunker
+type Option func(*chunker)
+
+// WithOverlap starts each chunk with up to n tokens from the end of the
+// previous one, so context spanning a cut is not lost
+func WithOverlap(n int) Option {
+	return func(c *chunker) { c.overlap = n }
+}
+
+// WithTokenCounter measures chunks with count instead of EstimateTokens,
+// e.g. a real tokenizer
+func WithTokenCounter(count TokenCounter) Option {
+	return func(c *chunker) { c.count = count }
+}
+
+// NewTokenChunker creates a chunker that fills chunks up to maxTokens,
+// cutting only at whitespace
+func NewTokenChunker(maxTokens int, opts ...Option) Chunker {
+	return newChunker(maxTokens, []boundaries{words}, opts)
+}
+
+// NewTextChunker creates a chunker for prose that keeps paragraphs and
+// then sentences whole where they fit in maxTokens
+func NewTextChunker(maxTokens int, opts ...Option) Chunker {
+	return newChunker(maxTokens, []boundaries{paragraphs, sentences, words}, opts)
+}
+
+// NewCodeChunker creates a chunker for source code that keeps top-level
+// declarations, such as functions and types, whole where they fit in
+// maxTokens, along with the comments above them
+func NewCodeChunker(maxTokens int, opts ...Option) Chunker {
+	return newChunker(maxTokens, []boundaries{declarations, paragraphs, lines, words}, opts)
+}
+
+// Texts returns the text of each chunk
+func Texts(chunks []Chunk) []string {
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Text
+	}
+	return texts
+}
+
+// boundaries returns the offsets in text where it may be cut, in order and
+// excluding 0 and len(text)
+type boundaries func(text string) []int
+
+type chunker struct {
+	maxTokens int
+	overlap   int
+	count     TokenCounter
+	levels    []boundaries
+}
+
+func newChunker(maxTokens int, levels []boundaries, opts []Option) *chunker {
+	c := &chunker{maxTokens: maxTokens, count: EstimateTokens, levels: levels}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// piece is a span of the original text
+type piece struct {
+	start, end int
+	tokens     int
+}
+
+// Split implements Chunker. A maxTokens of zero or less returns the whole
+// text as one chunk.
+func (c *chunker) Split(text string) []Chunk {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	if c.maxTokens <= 0 {
+		return c.emit(text, nil, []piece{{0, len(text), c.count(text)}})
+	}
+
+	var chunks []Chunk
+	var current []piece
+	size := 0
+	for _, p := range c.pieces(text, 0, len(text), 0) {
+		if len(current) > 0 && size+p.tokens > c.maxTokens {
+			chunks = c.emit(text, chunks, current)
+			current, size = c.tail(current)
+			// Give up overlap rather than overflow the next chunk
+			for len(current) > 0 && size+p.tokens > c.maxTokens {
+				size -= current[0].tokens
+				current = current[1:]
+			}
+		}
+		current = append(current, p)
+		size += p.tokens
+	}
+	return c.emit(text, chunks, current)
+}
+
+// pieces cuts text[start:end] into pieces of at most maxTokens, using the
+// coarsest boundaries at or after level that make them fit
+func (c *chunker) pieces(text string, start, end, level int) []piece {
+	tokens := c.count(text[start:end])
+	if tokens <= c.maxTokens {
+		return []piece{{start, end, tokens}}
+	}
+	if level == len(c.levels) {
+		return c.hardSplit(text, start, end)
+	}
+
+	cuts := c.levels[level](text[start:end])
+	if len(cuts) == 0 {
+		return c.pieces(text, start, end, level+1)
+	}
+	var out []piece
+	prev := start
+	for _, cut := range append(cuts, end-start) {
+		out = append(out, c.pieces(text, prev, start+cut, level+1)...)
+		prev = start + cut
+	}
+	return out
+}
+
+// hardSplit cuts text with no usable boundary, such as a very long token
+// run, into pieces of at most maxTokens
+func (c *chunker) hardSplit(text string, start, end int) []piece {
+	var out []piece
+	from := start
+	for i := start; i < end; {
+		_, width := utf8.DecodeRuneInString(text[i:end])
+		if i > from && c.count(text[from:i+width]) > c.maxTokens {
+			out = append(out, piece{from, i, c.count(text[from:i])})
+			from = i
+		}
+		i += width
+	}
+	return append(out, piece{from, end, c.count(text[from:end])})
+}
+
+// tail returns the trailing pieces of a chunk that fit in the overlap
+func (c *chunker) tail(pieces []piece) ([]piece, int) {
+	size := 0
+	i := len(pieces)
+	for i > 0 && size+pieces[i-1].tokens <= c.overlap {
+		i--
+		size += pieces[i].tokens
+	}
+	return append([]piece(nil), pieces[i:]...), size
+}
+
+// emit appends the chunk spanning pieces, trimmed of surrounding
+// whitespace, unless it is blank
+func (c *chunker) emit(text string, chunks []Chunk, pieces []piece) []Chunk {
+	if len(pieces) == 0 {
+		return chunks
+	}
+	start, end := pieces[0].start, pieces[len(pieces)-1].end
+	s := text[start:end]
+	trimmed := strings.TrimLeftFunc(s, unicode.IsSpace)
+	start += len(s) - len(trimmed)
+	trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+	if trimmed == "" {
+		return chunks
+	}
+	return append(chunks, Chunk{
+		Text:   trimmed,
+		Start:  start,
+		End:    start + len(trimmed),
+		Tokens: c.count(trimmed),
+	})
+}
+
+var (
+	paragraphBreak = regexp.MustCompile(`\n[ \t]*\n\s*`)
+	sentenceEnd    = regexp.MustCompile(`[.!?]["')\]]*\s+|\n`)
+	wordBreak      = regexp.MustCompile(`\s+`)
+	// declarationStart matches an unindented line that is not the end of
+	// a block, such as a closing brace or an "end"
+	declarationStart = regexp.MustCompile(`(?m)^[^\s})\]]`)
+	blockEnd         = regexp.MustCompile(`^\s*([})\]]+[;,]?|end)\s*$`)
+)
+
+// afterMatches returns the offsets just after each match of re
+func afterMatches(re *regexp.Regexp, text string) []int {
+	var cuts []int
+	for _, m := range re.FindAllStringIndex(text, -1) {
+		if m[1] > 0 && m[1] < len(text) {
+			cuts = append(cuts, m[1])
+		}
+	}
+	return cuts
+}
+
+func paragraphs(text string) []int {
+	return afterMatches(paragraphBreak, text)
+}
+
+func sentences(text string) []int {
+	return afterMatches(sentenceEnd, text)
+}
+
+func words(text string) []int {
+	return afterMatches(wordBreak, text)
+}
+
+func lines(text string) []int {
+	var cuts []int
+	for i := 0; i < len(text)-1; i++ {
+		if text[i] == '\n' {
+			cuts = append(cuts, i+1)
+		}
+	}
+	return cuts
+}
+
+// declarations returns the starts of top-level declarations: unindented
+// lines following a blank line or the end of a block. A comment directly
+// above a declaration stays with it.
+func declarations(text string) []int {
+	var cuts []int
+	for _, m := range declarationStart.FindAllStringIndex(text, -1) {
+		if m[0] == 0 {
+			continue
+		}
+		prevLine := text[:m[0]-1]
+		if i := strings.LastIndexByte(prevLine, '\n'); i >= 0 {
+			prevLine = prevLine[i+1:]
+		}
+		if strings.TrimSpace(prevLine) == "" || blockEnd.MatchString(prevLine) {
+			cuts = append(cuts, m[0])
+		}
+	}
+	return cuts
+}
@@ -0,0 +1,422 @@
+/*
+ * Anthropic Client for Go Agent Patterns
+ * The Messages API client shared by every pattern
+ */
+
+package agentpatterns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/chunker"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/conversation"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/limiter"
+)
+
+// AnthropicClient represents a client for the Anthropic API. Every pattern
+// sends its requests through it, so one client can be shared by all of them.
+type AnthropicClient struct {
+	APIKey     string
+	BaseURL    string
+	HTTPClient *http.Client
+	// Limits, if set, throttles every request made through the client.
+	// Share one registry between clients to enforce account-wide limits.
+	Limits *limiter.Registry
+	// Retry, if set, retries requests that fail before a response arrives,
+	// including streaming requests. Patterns configured WithRetry retry
+	// whole calls on top of this, so set one or the other.
+	Retry *RetryPolicy
+	// Provider, if set, serves every request instead of the Anthropic API,
+	// e.g. an OpenAIProvider or OllamaProvider. Limits and Retry still
+	// apply, keyed by the provider's name.
+	Provider LLMProvider
+	// Cache, if set, serves repeated identical requests without an API
+	// call. Requests with a non-zero temperature are not cached.
+	Cache Cache
+	// CacheTTL is how long responses are cached; zero keeps them until
+	// the cache evicts them. WithCacheTTL overrides it per call.
+	CacheTTL time.Duration
+}
+
+// APIError is a response from the Messages API with a status other than 200
+type APIError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay asked for by the Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// MessageRequest represents a request to the Anthropic API
+type MessageRequest struct {
+	Model     string        `json:"model"`
+	MaxTokens int           `json:"max_tokens"`
+	System    string        `json:"system,omitempty"`
+	Messages  []MessageItem `json:"messages"`
+	// Temperature, TopP, and TopK are left to the API default when nil
+	Temperature   *float64         `json:"temperature,omitempty"`
+	TopP          *float64         `json:"top_p,omitempty"`
+	TopK          *int             `json:"top_k,omitempty"`
+	StopSequences []string         `json:"stop_sequences,omitempty"`
+	Metadata      *RequestMetadata `json:"metadata,omitempty"`
+	Thinking      *ThinkingConfig  `json:"thinking,omitempty"`
+	Stream        bool             `json:"stream,omitempty"`
+}
+
+// RequestMetadata describes the request to the API
+type RequestMetadata struct {
+	// UserID is an opaque identifier of the end user, for abuse detection
+	UserID string `json:"user_id,omitempty"`
+}
+
+// RequestOptions are sampling and request settings applied to every call a
+// pattern makes. Fields left zero keep the request's own setting or the API
+// default.
+//
+// Example:
+//
+//	zero := 0.0
+//	router := NewRouter[string](client, WithRequestOptions(RequestOptions{Temperature: &zero}))
+//	writer := NewLLMWorker(client, "writer", "You write copy", WithTemperature(0.8))
+type RequestOptions struct {
+	// System is placed before the system prompt of the request, if any
+	System        string
+	Temperature   *float64
+	TopP          *float64
+	TopK          *int
+	StopSequences []string
+	UserID        string
+	// ThinkingBudget, if set, enables extended thinking with this many
+	// tokens (at least MinThinkingBudget) to reason with, added to each
+	// request's MaxTokens. Thinking does not allow a chosen temperature,
+	// top P, or top K, so they are cleared.
+	ThinkingBudget int
+}
+
+// apply sets the options on req. Settings the request already has take
+// precedence, so a pattern that samples at a chosen temperature keeps it.
+func (o *RequestOptions) apply(req *MessageRequest) {
+	if o.System != "" {
+		if req.System == "" {
+			req.System = o.System
+		} else {
+			req.System = o.System + "\n\n" + req.System
+		}
+	}
+	if req.Temperature == nil {
+		req.Temperature = o.Temperature
+	}
+	if req.TopP == nil {
+		req.TopP = o.TopP
+	}
+	if req.TopK == nil {
+		req.TopK = o.TopK
+	}
+	if req.StopSequences == nil {
+		req.StopSequences = o.StopSequences
+	}
+	if req.Metadata == nil && o.UserID != "" {
+		req.Metadata = &RequestMetadata{UserID: o.UserID}
+	}
+	if req.Thinking == nil && o.ThinkingBudget > 0 {
+		req.Thinking = Thinking(o.ThinkingBudget)
+		req.MaxTokens += req.Thinking.BudgetTokens
+		req.Temperature = nil
+		req.TopP = nil
+		req.TopK = nil
+	}
+}
+
+// MessageItem represents a message in the conversation
+type MessageItem = conversation.Message
+
+// Image is an image shown to the model, such as a screenshot or diagram
+type Image = conversation.Image
+
+// MessageResponse represents a response from the Anthropic API
+type MessageResponse struct {
+	Content []ContentBlock `json:"content"`
+	Usage   Usage          `json:"usage"`
+}
+
+// ContentBlock represents a content block in the response
+type ContentBlock struct {
+	Type      string `json:"type"`
+	Text      string `json:"text,omitempty"`
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// CreateMessage sends a message to the Anthropic API
+func (c *AnthropicClient) CreateMessage(ctx context.Context, prompt, model string, maxTokens int) (string, error) {
+	text, _, err := c.CreateMessageWithUsage(ctx, prompt, model, maxTokens)
+	return text, err
+}
+
+// CreateMessageWithUsage sends a message and also returns the token usage
+// reported by the API
+func (c *AnthropicClient) CreateMessageWithUsage(ctx context.Context, prompt, model string, maxTokens int) (string, Usage, error) {
+	return c.Send(ctx, MessageRequest{
+		Model:     model,
+		MaxTokens: maxTokens,
+		Messages: []MessageItem{
+			{Role: "user", Content: prompt},
+		},
+	})
+}
+
+// CreateImageMessage sends a prompt with images, which the model sees
+// before the prompt
+//
+// Example:
+//
+//	screenshot, err := conversation.ImageFromFile("error.png")
+//	text, _, err := client.CreateImageMessage(ctx, "What went wrong?", []Image{screenshot}, DefaultModel, 1024)
+func (c *AnthropicClient) CreateImageMessage(ctx context.Context, prompt string, images []Image, model string, maxTokens int) (string, Usage, error) {
+	return c.Send(ctx, MessageRequest{
+		Model:     model,
+		MaxTokens: maxTokens,
+		Messages: []MessageItem{
+			{Role: "user", Content: prompt, Images: images},
+		},
+	})
+}
+
+// CreateConversationMessage sends a multi-turn conversation with a system
+// prompt and returns the assistant's reply
+func (c *AnthropicClient) CreateConversationMessage(ctx context.Context, system string, messages []MessageItem, model string, maxTokens int) (string, Usage, error) {
+	return c.Send(ctx, MessageRequest{
+		Model:     model,
+		MaxTokens: maxTokens,
+		System:    system,
+		Messages:  messages,
+	})
+}
+
+// Chat sends the conversation's current window and appends the reply to it
+func (c *AnthropicClient) Chat(ctx context.Context, conv *conversation.Conversation, model string, maxTokens int) (string, error) {
+	messages, err := conv.Window(ctx)
+	if err != nil {
+		return "", err
+	}
+	reply, _, err := c.CreateConversationMessage(ctx, conv.System(), messages, model, maxTokens)
+	if err != nil {
+		return "", err
+	}
+	conv.AddAssistant(reply)
+	return reply, nil
+}
+
+// Send sends a request built by the caller, for settings the other methods
+// do not take such as temperature, and returns the reply and usage
+//
+// Example:
+//
+//	temperature := 0.7
+//	text, usage, err := client.Send(ctx, MessageRequest{
+//	    Model:       DefaultModel,
+//	    MaxTokens:   1024,
+//	    System:      "You are a concise assistant.",
+//	    Messages:    []MessageItem{{Role: "user", Content: prompt}},
+//	    Temperature: &temperature,
+//	})
+func (c *AnthropicClient) Send(ctx context.Context, reqBody MessageRequest) (string, Usage, error) {
+	// Streaming requests go through stream, which parses the events
+	reqBody.Stream = false
+	return c.cached(ctx, reqBody, nil, func() (string, Usage, error) {
+		if c.Provider != nil {
+			return c.sendVia(ctx, reqBody, nil)
+		}
+		resp, err := c.post(ctx, reqBody)
+		if err != nil {
+			return "", Usage{}, err
+		}
+		defer resp.Body.Close()
+		text, usage, err := decodeMessage(ctx, resp.Body)
+		c.settleTokens(limiter.ProviderAnthropic, reqBody, usage)
+		return text, usage, err
+	})
+}
+
+// decodeMessage reads a Messages API response and returns its first text
+// block, reporting any thinking to ctx
+func decodeMessage(ctx context.Context, body io.Reader) (string, Usage, error) {
+	var msgResp MessageResponse
+	if err := json.NewDecoder(body).Decode(&msgResp); err != nil {
+		return "", Usage{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	for _, block := range msgResp.Content {
+		if block.Type == "thinking" {
+			reportThinking(ctx, block.Thinking)
+		}
+	}
+	for _, block := range msgResp.Content {
+		if block.Type == "text" {
+			return block.Text, msgResp.Usage, nil
+		}
+	}
+
+	return "", msgResp.Usage, fmt.Errorf("no text content in response")
+}
+
+// post sends a request to the Messages API, retrying it under the client's
+// policy, and returns the response once its status is 200. The caller must
+// close the response body.
+func (c *AnthropicClient) post(ctx context.Context, reqBody MessageRequest) (*http.Response, error) {
+	var resp *http.Response
+	err := c.retry(ctx, reqBody.Model, func() error {
+		var err error
+		resp, err = c.postOnce(ctx, reqBody)
+		return err
+	})
+	return resp, err
+}
+
+// retry calls send until it succeeds or fails for good under the client's
+// retry policy
+func (c *AnthropicClient) retry(ctx context.Context, model string, send func() error) error {
+	if c.Retry == nil || c.Retry.MaxAttempts <= 1 {
+		return send()
+	}
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil || attempt >= c.Retry.MaxAttempts || !isRetryableError(err) {
+			return err
+		}
+		delay := c.Retry.backoff(attempt, err)
+		publish(ctx, "client", PhaseLLMRetry, retryPayload(model, attempt, delay, err))
+		if c.Retry.OnRetry != nil {
+			c.Retry.OnRetry(ctx, attempt, delay, err)
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *AnthropicClient) postOnce(ctx context.Context, reqBody MessageRequest) (*http.Response, error) {
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.messagesURL(), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("x-api-key", c.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("content-type", "application/json")
+	if usesFiles(reqBody) {
+		req.Header.Set("anthropic-beta", filesBeta)
+	}
+
+	if c.Limits != nil {
+		if err := c.Limits.Wait(ctx, limiter.ProviderAnthropic, reqBody.Model); err != nil {
+			return nil, err
+		}
+		if err := c.reserveTokens(ctx, limiter.ProviderAnthropic, reqBody); err != nil {
+			c.Limits.Done(limiter.ProviderAnthropic, reqBody.Model, false)
+			return nil, err
+		}
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if c.Limits != nil {
+		c.Limits.Done(limiter.ProviderAnthropic, reqBody.Model, err == nil && resp.StatusCode == http.StatusTooManyRequests)
+	}
+	if err != nil {
+		c.settleTokens(limiter.ProviderAnthropic, reqBody, Usage{})
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// A failed request uses none of the tokens reserved for it
+		c.settleTokens(limiter.ProviderAnthropic, reqBody, Usage{})
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return resp, nil
+}
+
+// imageTokenEstimate is the tokens reserved for an image or PDF before the
+// API counts them; a full-size image is about this many
+const imageTokenEstimate = 1600
+
+// tokenEstimate estimates a request's tokens for metered limits: its text
+// and attachments in, and its max tokens out
+func tokenEstimate(req MessageRequest) limiter.Tokens {
+	input := chunker.EstimateTokens(req.System)
+	for _, m := range req.Messages {
+		input += chunker.EstimateTokens(m.Content) + len(m.Images)*imageTokenEstimate
+		for _, doc := range m.Documents {
+			if doc.MediaType == "text/plain" {
+				input += chunker.EstimateTokens(string(doc.Data))
+			} else {
+				input += imageTokenEstimate
+			}
+		}
+	}
+	return limiter.Tokens{Input: input, Output: req.MaxTokens}
+}
+
+// reserveTokens reserves a request's estimated tokens with the client's
+// metered limits
+func (c *AnthropicClient) reserveTokens(ctx context.Context, provider string, req MessageRequest) error {
+	if c.Limits == nil {
+		return nil
+	}
+	return c.Limits.Reserve(ctx, provider, req.Model, tokenEstimate(req))
+}
+
+// settleTokens corrects a request's reservation to the usage reported for
+// it, returning what went unused
+func (c *AnthropicClient) settleTokens(provider string, req MessageRequest, usage Usage) {
+	if c.Limits == nil {
+		return
+	}
+	c.Limits.Settle(provider, req.Model, tokenEstimate(req), limiter.Tokens{Input: usage.InputTokens, Output: usage.OutputTokens})
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an
+// HTTP date, returning zero if it is missing or invalid
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Helper function to get environment variable with default
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,97 @@
+/*
+ * Configuration Integration for Go Agent Patterns
+ * Builds clients and pattern options from a config.Config
+ */
+
+package agentpatterns
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/config"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/limiter"
+)
+
+// NewClientFromConfig creates an AnthropicClient from a loaded configuration.
+// max_concurrency caps requests in flight across all models, and
+// rate_limits throttles individual models.
+func NewClientFromConfig(cfg *config.Config) *AnthropicClient {
+	return &AnthropicClient{
+		APIKey:     cfg.APIKey,
+		BaseURL:    cfg.BaseURL,
+		HTTPClient: &http.Client{Timeout: cfg.Timeout},
+		Limits:     limitsFromConfig(cfg),
+	}
+}
+
+// limitsFromConfig builds the client's limiter registry, or nil if the
+// configuration sets no limits
+func limitsFromConfig(cfg *config.Config) *limiter.Registry {
+	if cfg.MaxConcurrency == 0 && len(cfg.RateLimits) == 0 {
+		return nil
+	}
+
+	limits := limiter.NewRegistry()
+	if cfg.MaxConcurrency > 0 {
+		limits.Set(limiter.ProviderAnthropic, limiter.NewConcurrency(cfg.MaxConcurrency))
+	}
+	for model, rl := range cfg.RateLimits {
+		var l limiter.Limiter = limiter.NewTokenBucket(rl.RequestsPerMinute, rl.Burst)
+		if rl.InputTokensPerMinute > 0 || rl.OutputTokensPerMinute > 0 {
+			l = limiter.All(l, limiter.NewTokenRate(rl.InputTokensPerMinute, rl.OutputTokensPerMinute))
+		}
+		if rl.Adaptive {
+			l = limiter.NewAdaptive(l)
+		}
+		limits.Set(limiter.ProviderAnthropic+"/"+cfg.Model(model), l)
+	}
+	return limits
+}
+
+// WithConfig applies the model, max tokens, budget, and retry settings from
+// cfg. Options listed after WithConfig override it.
+//
+// Example:
+//
+//	cfg, err := config.Load("agentpatterns.yaml")
+//	client := NewClientFromConfig(cfg)
+//	router := NewRouter[string](client, WithConfig(cfg))
+func WithConfig(cfg *config.Config) Option {
+	return func(c *patternConfig) {
+		c.model = cfg.Model("")
+		if cfg.MaxTokens > 0 {
+			c.maxTokens = cfg.MaxTokens
+		}
+		if cfg.Budget != (config.BudgetConfig{}) {
+			c.budget = &Budget{
+				MaxCalls:    cfg.Budget.MaxCalls,
+				MaxTokens:   cfg.Budget.MaxTokens,
+				MaxCost:     cfg.Budget.MaxCost,
+				MaxDuration: cfg.Budget.MaxDuration,
+			}
+		}
+		if cfg.Retry.MaxAttempts > 0 {
+			c.retry = &RetryPolicy{
+				MaxAttempts:    cfg.Retry.MaxAttempts,
+				InitialBackoff: cfg.Retry.InitialBackoff,
+				MaxBackoff:     cfg.Retry.MaxBackoff,
+				Jitter:         cfg.Retry.Jitter,
+			}
+		}
+	}
+}
+
+// messagesURL returns the Messages API endpoint for this client
+func (c *AnthropicClient) messagesURL() string {
+	return c.apiURL("/v1/messages")
+}
+
+// apiURL is the URL of an API endpoint under the client's base URL
+func (c *AnthropicClient) apiURL(path string) string {
+	base := c.BaseURL
+	if base == "" {
+		base = config.DefaultBaseURL
+	}
+	return strings.TrimRight(base, "/") + path
+}
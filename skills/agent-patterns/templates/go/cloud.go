@@ -0,0 +1,368 @@
+/*
+ * Cloud Transports for Go Agent Patterns
+ * Claude through AWS Bedrock and Google Vertex AI instead of the Anthropic API
+ */
+
+package agentpatterns
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// vendorBody converts a request to the body Bedrock and Vertex expect: the
+// Messages API body without the model, which goes in the URL, and with the
+// platform's API version
+func vendorBody(req MessageRequest, version string) ([]byte, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+	delete(body, "model")
+	body["anthropic_version"] = version
+	return json.Marshal(body)
+}
+
+// BedrockProvider sends requests to Claude on AWS Bedrock, signing them
+// with SigV4.
+//
+// Bedrock model IDs default to "anthropic.<model>-v1:0"; map models that
+// need a cross-region inference profile, such as "us.anthropic.…", with
+// Models.
+//
+// Example:
+//
+//	awsCfg, err := config.LoadDefaultConfig(ctx) // github.com/aws/aws-sdk-go-v2/config
+//	client := &AnthropicClient{Provider: &BedrockProvider{
+//	    Region:      awsCfg.Region,
+//	    Credentials: awsCfg.Credentials,
+//	}}
+type BedrockProvider struct {
+	Region      string
+	Credentials aws.CredentialsProvider
+	HTTPClient  *http.Client
+	// BaseURL overrides the regional endpoint, e.g. for a VPC endpoint
+	BaseURL string
+	// Model, if set, is used for every model not in Models
+	Model string
+	// Models maps the models named by patterns to Bedrock model IDs
+	Models map[string]string
+}
+
+// Name implements LLMProvider
+func (p *BedrockProvider) Name() string {
+	return "bedrock"
+}
+
+// modelID returns the Bedrock model ID for a model named by a pattern
+func (p *BedrockProvider) modelID(model string) string {
+	if mapped, ok := p.Models[model]; ok {
+		return mapped
+	}
+	if p.Model != "" {
+		return p.Model
+	}
+	if strings.HasPrefix(model, "claude-") {
+		return "anthropic." + model + "-v1:0"
+	}
+	return model
+}
+
+// Send implements LLMProvider
+func (p *BedrockProvider) Send(ctx context.Context, req MessageRequest) (string, Usage, error) {
+	resp, err := p.post(ctx, req, "invoke")
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
+	return decodeMessage(ctx, resp.Body)
+}
+
+// SendStream implements LLMProvider. Bedrock frames the Messages API
+// stream events in AWS event stream messages.
+func (p *BedrockProvider) SendStream(ctx context.Context, req MessageRequest, onDelta func(text string)) (string, Usage, error) {
+	resp, err := p.post(ctx, req, "invoke-with-response-stream")
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
+
+	acc := streamAccumulator{ctx: ctx, onDelta: onDelta}
+	for {
+		headers, payload, err := readEventStreamMessage(resp.Body)
+		if err == io.EOF {
+			return acc.text.String(), acc.usage, fmt.Errorf("stream ended before message_stop")
+		}
+		if err != nil {
+			return acc.text.String(), acc.usage, fmt.Errorf("failed to read stream: %w", err)
+		}
+
+		if headers[":message-type"] == "exception" {
+			status := 500
+			if headers[":exception-type"] == "throttlingException" {
+				status = http.StatusTooManyRequests
+			}
+			return acc.text.String(), acc.usage, &APIError{StatusCode: status, Body: headers[":exception-type"] + ": " + string(payload)}
+		}
+		if headers[":event-type"] != "chunk" {
+			continue
+		}
+		var chunk struct {
+			Bytes string `json:"bytes"`
+		}
+		if err := json.Unmarshal(payload, &chunk); err != nil {
+			return acc.text.String(), acc.usage, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+		event, err := base64.StdEncoding.DecodeString(chunk.Bytes)
+		if err != nil {
+			return acc.text.String(), acc.usage, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+		done, err := acc.apply(event)
+		if done || err != nil {
+			return acc.text.String(), acc.usage, err
+		}
+	}
+}
+
+func (p *BedrockProvider) post(ctx context.Context, req MessageRequest, action string) (*http.Response, error) {
+	stream := req.Stream
+	req.Stream = false
+	body, err := vendorBody(req, "bedrock-2023-05-31")
+	if err != nil {
+		return nil, err
+	}
+
+	base := p.BaseURL
+	if base == "" {
+		base = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", p.Region)
+	}
+	// Model IDs contain ':', which SigV4 expects escaped in the path
+	modelID := strings.ReplaceAll(url.PathEscape(p.modelID(req.Model)), ":", "%3A")
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(base, "/")+"/model/"+modelID+"/"+action, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	if stream {
+		httpReq.Header.Set("accept", "application/vnd.amazon.eventstream")
+	} else {
+		httpReq.Header.Set("accept", "application/json")
+	}
+
+	if p.Credentials == nil {
+		return nil, fmt.Errorf("bedrock: no credentials configured")
+	}
+	creds, err := p.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: failed to retrieve credentials: %w", err)
+	}
+	hash := sha256.Sum256(body)
+	err = v4.NewSigner().SignHTTP(ctx, creds, httpReq, hex.EncodeToString(hash[:]), "bedrock", p.Region, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: failed to sign request: %w", err)
+	}
+	return doRequest(p.HTTPClient, httpReq)
+}
+
+// readEventStreamMessage reads one AWS event stream message and returns its
+// string headers and payload
+func readEventStreamMessage(r io.Reader) (map[string]string, []byte, error) {
+	var prelude [12]byte
+	if _, err := io.ReadFull(r, prelude[:]); err != nil {
+		return nil, nil, err
+	}
+	totalLen := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return nil, nil, fmt.Errorf("event stream prelude checksum mismatch")
+	}
+	if totalLen < 16+headersLen || totalLen > 16*1024*1024 {
+		return nil, nil, fmt.Errorf("invalid event stream message length %d", totalLen)
+	}
+
+	rest := make([]byte, totalLen-12)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, nil, err
+	}
+	crc := crc32.NewIEEE()
+	crc.Write(prelude[:])
+	crc.Write(rest[:len(rest)-4])
+	if crc.Sum32() != binary.BigEndian.Uint32(rest[len(rest)-4:]) {
+		return nil, nil, fmt.Errorf("event stream message checksum mismatch")
+	}
+
+	headers, err := parseEventStreamHeaders(rest[:headersLen])
+	if err != nil {
+		return nil, nil, err
+	}
+	return headers, rest[headersLen : len(rest)-4], nil
+}
+
+// parseEventStreamHeaders decodes event stream headers, keeping the string
+// values and skipping the others
+func parseEventStreamHeaders(b []byte) (map[string]string, error) {
+	// Sizes of the fixed-length value types, by type code
+	sizes := map[byte]int{0: 0, 1: 0, 2: 1, 3: 2, 4: 4, 5: 8, 8: 8, 9: 16}
+	headers := make(map[string]string)
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 1+nameLen+1 {
+			return nil, fmt.Errorf("truncated event stream header")
+		}
+		name := string(b[1 : 1+nameLen])
+		valueType := b[1+nameLen]
+		b = b[2+nameLen:]
+
+		switch valueType {
+		case 6, 7:
+			// Byte arrays and strings have a two-byte length
+			if len(b) < 2 {
+				return nil, fmt.Errorf("truncated event stream header")
+			}
+			n := int(binary.BigEndian.Uint16(b))
+			if len(b) < 2+n {
+				return nil, fmt.Errorf("truncated event stream header")
+			}
+			if valueType == 7 {
+				headers[name] = string(b[2 : 2+n])
+			}
+			b = b[2+n:]
+		default:
+			n, ok := sizes[valueType]
+			if !ok || len(b) < n {
+				return nil, fmt.Errorf("invalid event stream header %q", name)
+			}
+			b = b[n:]
+		}
+	}
+	return headers, nil
+}
+
+// VertexProvider sends requests to Claude on Google Vertex AI.
+//
+// Vertex model IDs default to the Anthropic name with the date after an
+// "@", e.g. "claude-sonnet-4@20250514".
+//
+// Example:
+//
+//	ts, err := google.DefaultTokenSource(ctx, "https://www.googleapis.com/auth/cloud-platform") // golang.org/x/oauth2/google
+//	client := &AnthropicClient{Provider: &VertexProvider{
+//	    ProjectID: "my-project",
+//	    Region:    "us-east5",
+//	    Token: func(ctx context.Context) (string, error) {
+//	        token, err := ts.Token()
+//	        if err != nil {
+//	            return "", err
+//	        }
+//	        return token.AccessToken, nil
+//	    },
+//	}}
+type VertexProvider struct {
+	ProjectID string
+	// Region is a Vertex location such as "us-east5", or "global"
+	Region string
+	// Token returns an OAuth2 access token for the request
+	Token      func(ctx context.Context) (string, error)
+	HTTPClient *http.Client
+	// BaseURL overrides the regional endpoint
+	BaseURL string
+	// Model, if set, is used for every model not in Models
+	Model string
+	// Models maps the models named by patterns to Vertex model IDs
+	Models map[string]string
+}
+
+// vertexDate matches the date suffix of an Anthropic model name
+var vertexDate = regexp.MustCompile(`-(\d{8})$`)
+
+// Name implements LLMProvider
+func (p *VertexProvider) Name() string {
+	return "vertex"
+}
+
+// modelID returns the Vertex model ID for a model named by a pattern
+func (p *VertexProvider) modelID(model string) string {
+	if mapped, ok := p.Models[model]; ok {
+		return mapped
+	}
+	if p.Model != "" {
+		return p.Model
+	}
+	return vertexDate.ReplaceAllString(model, "@$1")
+}
+
+// Send implements LLMProvider
+func (p *VertexProvider) Send(ctx context.Context, req MessageRequest) (string, Usage, error) {
+	req.Stream = false
+	resp, err := p.post(ctx, req, "rawPredict")
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
+	return decodeMessage(ctx, resp.Body)
+}
+
+// SendStream implements LLMProvider. Vertex returns the Messages API
+// server-sent events unchanged.
+func (p *VertexProvider) SendStream(ctx context.Context, req MessageRequest, onDelta func(text string)) (string, Usage, error) {
+	req.Stream = true
+	resp, err := p.post(ctx, req, "streamRawPredict")
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
+	return readStream(ctx, resp.Body, onDelta)
+}
+
+func (p *VertexProvider) post(ctx context.Context, req MessageRequest, method string) (*http.Response, error) {
+	body, err := vendorBody(req, "vertex-2023-10-16")
+	if err != nil {
+		return nil, err
+	}
+
+	base := p.BaseURL
+	if base == "" {
+		host := p.Region + "-aiplatform.googleapis.com"
+		if p.Region == "global" {
+			host = "aiplatform.googleapis.com"
+		}
+		base = "https://" + host
+	}
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/locations/%s/publishers/anthropic/models/%s:%s",
+		strings.TrimRight(base, "/"), p.ProjectID, p.Region, p.modelID(req.Model), method)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("content-type", "application/json")
+
+	if p.Token == nil {
+		return nil, fmt.Errorf("vertex: no token source configured")
+	}
+	token, err := p.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("vertex: failed to get access token: %w", err)
+	}
+	httpReq.Header.Set("authorization", "Bearer "+token)
+	return doRequest(p.HTTPClient, httpReq)
+}
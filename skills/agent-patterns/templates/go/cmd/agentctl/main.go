@@ -0,0 +1,24 @@
+/*
+ * Command-line Runner for Go Agent Patterns
+ * Runs a chain, router, orchestrator, optimizer, agent, or graph defined in a YAML or JSON spec
+ */
+
+// Command agentctl runs a pattern described by a YAML or JSON workflow
+// file (see agentpatterns.WorkflowSpec) against stdin or a file, for
+// experimenting with patterns without writing a main().
+//
+// Usage:
+//
+//	agentctl -workflow chain.yaml -input topic.txt -stream
+//	echo "My card was charged twice" | agentctl -workflow router.yaml -format json
+//	agentctl -workflow orchestrator.yaml -model opus -max-calls 20 -max-cost 0.50 -max-duration 5m -usage < task.txt
+//	agentctl -workflow agent.yaml -input task.txt -trace trace.json
+//	agentctl -workflow agent.yaml -input task.txt -record run.json
+//	agentctl -workflow agent.yaml -input task.txt -replay run.json
+package main
+
+import "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/cmd/internal/cli"
+
+func main() {
+	cli.Main("agentctl")
+}
@@ -0,0 +1,19 @@
+/*
+ * Command-line Runner for Go Agent Patterns
+ * The original name of agentctl, kept for existing scripts
+ */
+
+// Command agentpatterns is agentctl under its original name. It takes the
+// same flags, with -spec as an alias for -workflow.
+//
+// Usage:
+//
+//	agentpatterns -spec chain.yaml -input topic.txt
+//	echo "My card was charged twice" | agentpatterns -spec router.yaml -format json
+package main
+
+import "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/cmd/internal/cli"
+
+func main() {
+	cli.Main("agentpatterns")
+}
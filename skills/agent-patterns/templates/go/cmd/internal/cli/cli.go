@@ -0,0 +1,266 @@
+/*
+ * Command-line Runner for Go Agent Patterns
+ * Runs a chain, router, orchestrator, optimizer, agent, or graph defined in a YAML or JSON spec
+ */
+
+// Package cli is the workflow runner shared by the agentctl and
+// agentpatterns commands
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/config"
+)
+
+// output is the JSON result written with -format json
+type output struct {
+	Pattern string                    `json:"pattern"`
+	RunID   string                    `json:"run_id"`
+	Output  string                    `json:"output"`
+	Details interface{}               `json:"details,omitempty"`
+	Cost    agentpatterns.CostSummary `json:"cost"`
+	Elapsed string                    `json:"elapsed"`
+}
+
+// Main runs the command named name with the process's arguments, exiting
+// with status 1 on failure
+func Main(name string) {
+	if err := run(name, os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+func run(name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	workflowPath := flags.String("workflow", "", "workflow file, YAML or JSON (required)")
+	flags.StringVar(workflowPath, "spec", "", "alias for -workflow")
+	configPath := flags.String("config", "", "settings file (API key, models, retries)")
+	inputPath := flags.String("input", "-", "input file, or - for stdin")
+	model := flags.String("model", "", "model ID or alias, overriding the settings file")
+	maxCalls := flags.Int("max-calls", 0, "maximum LLM calls for the run (0 = unlimited)")
+	maxTokens := flags.Int("max-tokens", 0, "maximum input plus output tokens for the run (0 = unlimited)")
+	maxCost := flags.Float64("max-cost", 0, "maximum spend for the run in US dollars (0 = unlimited)")
+	maxDuration := flags.Duration("max-duration", 0, "maximum wall-clock time for the run (0 = unlimited)")
+	format := flags.String("format", "text", "output format: text or json")
+	recordPath := flags.String("record", "", "write a replay bundle of the run to this file")
+	replayPath := flags.String("replay", "", "replay the run from a bundle instead of calling the API")
+	stream := flags.Bool("stream", false, "stream the text of every LLM call to stderr as it is generated")
+	usage := flags.Bool("usage", false, "print calls, tokens, and cost per model to stderr after the run")
+	tracePath := flags.String("trace", "", "write the run's spans as JSON to this file, or - to print them as a tree to stderr")
+	flags.Parse(args)
+
+	if *workflowPath == "" {
+		flags.Usage()
+		return fmt.Errorf("-workflow is required")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if *recordPath != "" && *replayPath != "" {
+		return fmt.Errorf("-record and -replay cannot be used together")
+	}
+
+	spec, err := agentpatterns.LoadWorkflow(*workflowPath)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.APIKey == "" && *replayPath == "" {
+		return fmt.Errorf("%s environment variable not set", config.EnvAPIKey)
+	}
+	// Flags override the workflow file, which overrides the settings file
+	if *model != "" {
+		spec.Model = *model
+	}
+	if *maxCalls > 0 {
+		spec.Budget.MaxCalls = *maxCalls
+	}
+	if *maxTokens > 0 {
+		spec.Budget.MaxTokens = *maxTokens
+	}
+	if *maxCost > 0 {
+		spec.Budget.MaxCost = *maxCost
+	}
+	if *maxDuration > 0 {
+		spec.Budget.MaxDuration = *maxDuration
+	}
+
+	input, err := readInput(*inputPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	runID := fmt.Sprintf("cli_%d", time.Now().UnixNano())
+	ctx = agentpatterns.WithRunID(ctx, runID)
+
+	costs := agentpatterns.NewCostTracker(agentpatterns.DefaultPricing())
+	client := agentpatterns.NewClientFromConfig(cfg)
+	opts := []agentpatterns.Option{agentpatterns.WithCostTracker(costs)}
+	if *stream {
+		opts = append(opts, agentpatterns.WithStreaming(streamTo(os.Stderr)))
+	}
+	var spans *agentpatterns.SpanRecorder
+	if *tracePath != "" {
+		spans = agentpatterns.NewSpanRecorder()
+		opts = append(opts, agentpatterns.WithTracer(spans))
+	}
+	// Summaries are written even when the run fails, as they show how far
+	// it got
+	defer func() {
+		if *usage {
+			writeUsage(os.Stderr, costs)
+		}
+		if spans != nil {
+			if err := writeTrace(*tracePath, spans); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			}
+		}
+	}()
+
+	var details interface{}
+	runPattern := func(ctx context.Context) (string, error) {
+		text, d, err := spec.Run(ctx, client, cfg, input, opts...)
+		details = d
+		return text, err
+	}
+
+	start := time.Now()
+	var text string
+	switch {
+	case *replayPath != "":
+		bundle, err := agentpatterns.LoadBundle(*replayPath)
+		if err != nil {
+			return err
+		}
+		runID = bundle.RunID
+		var report *agentpatterns.ReplayReport
+		text, report, err = agentpatterns.Replay(ctx, bundle, runPattern)
+		fmt.Fprintf(os.Stderr, "replayed %d calls, %d divergent, %d unused, output matches: %t\n",
+			report.Replayed, len(report.Divergences), report.Unused, report.OutputMatches)
+		for _, d := range report.Divergences {
+			fmt.Fprintf(os.Stderr, "divergence at recorded call %d (%s)\n", d.Seq, d.Kind)
+		}
+		if err != nil {
+			return err
+		}
+	case *recordPath != "":
+		var bundle *agentpatterns.RunBundle
+		text, bundle, err = agentpatterns.Record(ctx, runPattern)
+		if saveErr := bundle.Save(*recordPath); saveErr != nil {
+			return fmt.Errorf("failed to save replay bundle: %w", saveErr)
+		}
+		if err != nil {
+			return err
+		}
+	default:
+		if text, err = runPattern(ctx); err != nil {
+			return err
+		}
+	}
+
+	if *format == "text" {
+		fmt.Println(text)
+		return nil
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output{
+		Pattern: spec.Pattern,
+		RunID:   runID,
+		Output:  text,
+		Details: details,
+		Cost:    costs.Total(),
+		Elapsed: time.Since(start).Round(time.Millisecond).String(),
+	})
+}
+
+func readInput(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	input := strings.TrimSpace(string(data))
+	if input == "" {
+		return "", fmt.Errorf("input is empty")
+	}
+	return input, nil
+}
+
+// streamTo returns a StreamFunc writing each call's text to w. Calls made
+// at once are interleaved, so each write is headed by the pattern when it
+// changes.
+func streamTo(w io.Writer) agentpatterns.StreamFunc {
+	var mu sync.Mutex
+	last := ""
+	return func(ctx context.Context, pattern, text string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if pattern != last {
+			fmt.Fprintf(w, "\n[%s] ", pattern)
+			last = pattern
+		}
+		fmt.Fprint(w, text)
+	}
+}
+
+// writeUsage prints the calls, tokens, and cost of each model, then the
+// totals
+func writeUsage(w io.Writer, costs *agentpatterns.CostTracker) {
+	byModel := costs.ByModel()
+	models := make([]string, 0, len(byModel))
+	for model := range byModel {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "model\tcalls\tinput tokens\toutput tokens\tcost (USD)\t")
+	row := func(name string, s agentpatterns.CostSummary) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.4f\t\n", name, s.Calls, s.InputTokens, s.OutputTokens, s.Cost)
+	}
+	for _, model := range models {
+		row(model, byModel[model])
+	}
+	row("total", costs.Total())
+	tw.Flush()
+}
+
+// writeTrace writes the recorded spans as JSON to path, or as a tree to
+// stderr when path is -
+func writeTrace(path string, spans *agentpatterns.SpanRecorder) error {
+	if path == "-" {
+		return spans.WriteTree(os.Stderr)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	defer f.Close()
+	return spans.WriteJSON(f)
+}
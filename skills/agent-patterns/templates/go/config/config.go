@@ -0,0 +1,251 @@
+/*
+ * Configuration Loader for Go Agent Patterns
+ * API keys, base URLs, model aliases, concurrency caps, and budgets
+ */
+
+// Package config loads agent pattern settings from YAML with environment
+// variable overrides.
+//
+// Example config.yaml:
+//
+//	api_key: ""                # usually supplied via ANTHROPIC_API_KEY
+//	base_url: https://api.anthropic.com
+//	default_model: sonnet
+//	models:
+//	  haiku: claude-3-haiku-20240307
+//	  sonnet: claude-sonnet-4-20250514
+//	  opus: claude-opus-4-20250514
+//	max_tokens: 4096
+//	max_concurrency: 8
+//	timeout: 60s
+//	budget:
+//	  max_calls: 50
+//	  max_tokens: 200000
+//	  max_cost: 2.50             # US dollars
+//	  max_duration: 10m
+//	retry:
+//	  max_attempts: 3
+//	  initial_backoff: 500ms
+//	  max_backoff: 10s
+//	  jitter: 250ms
+//	rate_limits:                 # keyed by model alias or ID
+//	  opus:
+//	    requests_per_minute: 50
+//	    burst: 5
+//	    input_tokens_per_minute: 30000
+//	    output_tokens_per_minute: 8000
+//	    adaptive: true
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables that override file settings
+const (
+	EnvAPIKey         = "ANTHROPIC_API_KEY"
+	EnvBaseURL        = "ANTHROPIC_BASE_URL"
+	EnvModel          = "AGENTPATTERNS_MODEL"
+	EnvMaxTokens      = "AGENTPATTERNS_MAX_TOKENS"
+	EnvMaxConcurrency = "AGENTPATTERNS_MAX_CONCURRENCY"
+	EnvMaxCalls       = "AGENTPATTERNS_MAX_CALLS"
+	EnvMaxRunTokens   = "AGENTPATTERNS_MAX_RUN_TOKENS"
+	EnvMaxCost        = "AGENTPATTERNS_MAX_COST"
+	EnvMaxDuration    = "AGENTPATTERNS_MAX_DURATION"
+	EnvTimeout        = "AGENTPATTERNS_TIMEOUT"
+)
+
+// DefaultBaseURL is the Anthropic API endpoint
+const DefaultBaseURL = "https://api.anthropic.com"
+
+// Config holds settings shared by all patterns
+type Config struct {
+	APIKey         string            `yaml:"api_key"`
+	BaseURL        string            `yaml:"base_url"`
+	DefaultModel   string            `yaml:"default_model"`
+	Models         map[string]string `yaml:"models"`
+	MaxTokens      int               `yaml:"max_tokens"`
+	MaxConcurrency int               `yaml:"max_concurrency"`
+	Timeout        time.Duration     `yaml:"timeout"`
+	Budget         BudgetConfig      `yaml:"budget"`
+	Retry          RetryConfig       `yaml:"retry"`
+	// RateLimits maps a model alias or ID to its request and token rate
+	// limits
+	RateLimits map[string]RateLimit `yaml:"rate_limits"`
+}
+
+// BudgetConfig caps the resources a single run may consume
+type BudgetConfig struct {
+	MaxCalls  int `yaml:"max_calls"`
+	MaxTokens int `yaml:"max_tokens"`
+	// MaxCost is in US dollars
+	MaxCost     float64       `yaml:"max_cost"`
+	MaxDuration time.Duration `yaml:"max_duration"`
+}
+
+// RetryConfig controls retries of failed LLM calls
+type RetryConfig struct {
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	Jitter         time.Duration `yaml:"jitter"`
+}
+
+// RateLimit limits the request and token rates for one model
+type RateLimit struct {
+	RequestsPerMinute float64 `yaml:"requests_per_minute"`
+	Burst             int     `yaml:"burst"`
+	// InputTokensPerMinute and OutputTokensPerMinute mirror the API's ITPM
+	// and OTPM limits; zero leaves tokens unlimited
+	InputTokensPerMinute  int `yaml:"input_tokens_per_minute"`
+	OutputTokensPerMinute int `yaml:"output_tokens_per_minute"`
+	// Adaptive pauses all callers of the model when the API returns 429
+	Adaptive bool `yaml:"adaptive"`
+}
+
+// Default returns a configuration with built-in model aliases
+func Default() *Config {
+	return &Config{
+		BaseURL:      DefaultBaseURL,
+		DefaultModel: "sonnet",
+		Models: map[string]string{
+			"haiku":  "claude-3-haiku-20240307",
+			"sonnet": "claude-sonnet-4-20250514",
+			"opus":   "claude-opus-4-20250514",
+		},
+		Timeout: 60 * time.Second,
+	}
+}
+
+// Load reads a YAML file on top of the defaults and then applies
+// environment overrides. An empty path loads defaults and environment only.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.ApplyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// FromEnv loads defaults with environment overrides only
+func FromEnv() (*Config, error) {
+	return Load("")
+}
+
+// ApplyEnv overrides settings from environment variables
+func (c *Config) ApplyEnv() error {
+	if v := os.Getenv(EnvAPIKey); v != "" {
+		c.APIKey = v
+	}
+	if v := os.Getenv(EnvBaseURL); v != "" {
+		c.BaseURL = v
+	}
+	if v := os.Getenv(EnvModel); v != "" {
+		c.DefaultModel = v
+	}
+
+	ints := []struct {
+		env string
+		dst *int
+	}{
+		{EnvMaxTokens, &c.MaxTokens},
+		{EnvMaxConcurrency, &c.MaxConcurrency},
+		{EnvMaxCalls, &c.Budget.MaxCalls},
+		{EnvMaxRunTokens, &c.Budget.MaxTokens},
+	}
+	for _, iv := range ints {
+		if v := os.Getenv(iv.env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", iv.env, err)
+			}
+			*iv.dst = n
+		}
+	}
+
+	if v := os.Getenv(EnvMaxCost); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvMaxCost, err)
+		}
+		c.Budget.MaxCost = f
+	}
+
+	durations := []struct {
+		env string
+		dst *time.Duration
+	}{
+		{EnvMaxDuration, &c.Budget.MaxDuration},
+		{EnvTimeout, &c.Timeout},
+	}
+	for _, dv := range durations {
+		if v := os.Getenv(dv.env); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", dv.env, err)
+			}
+			*dv.dst = d
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the configuration is usable
+func (c *Config) Validate() error {
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must not be negative")
+	}
+	if c.MaxConcurrency < 0 {
+		return fmt.Errorf("max_concurrency must not be negative")
+	}
+	if c.Budget.MaxCalls < 0 {
+		return fmt.Errorf("budget.max_calls must not be negative")
+	}
+	if c.Budget.MaxTokens < 0 {
+		return fmt.Errorf("budget.max_tokens must not be negative")
+	}
+	if c.Budget.MaxCost < 0 {
+		return fmt.Errorf("budget.max_cost must not be negative")
+	}
+	if c.Retry.MaxAttempts < 0 {
+		return fmt.Errorf("retry.max_attempts must not be negative")
+	}
+	for model, rl := range c.RateLimits {
+		if rl.RequestsPerMinute < 0 || rl.Burst < 0 || rl.InputTokensPerMinute < 0 || rl.OutputTokensPerMinute < 0 {
+			return fmt.Errorf("rate_limits.%s must not be negative", model)
+		}
+	}
+	return nil
+}
+
+// Model resolves a model alias such as "haiku" to its model ID. Unknown
+// names are returned unchanged so full model IDs can be used directly.
+func (c *Config) Model(name string) string {
+	if name == "" {
+		name = c.DefaultModel
+	}
+	if id, ok := c.Models[name]; ok {
+		return id
+	}
+	return name
+}
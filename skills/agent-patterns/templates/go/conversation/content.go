@@ -0,0 +1,204 @@
+package conversation
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Image is an image attached to a message, sent inline or by URL
+type Image struct {
+	// MediaType is the MIME type of Data: image/jpeg, image/png, image/gif,
+	// or image/webp
+	MediaType string
+	// Data is the raw image, sent base64-encoded
+	Data []byte
+	// URL, if set, is fetched by the API instead of sending Data
+	URL string
+}
+
+// ImageFromFile reads an image, taking its media type from the file
+// extension or, failing that, its contents
+func ImageFromFile(path string) (Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Image{}, err
+	}
+	mediaType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if !strings.HasPrefix(mediaType, "image/") {
+		mediaType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(mediaType, "image/") {
+		return Image{}, fmt.Errorf("%s is not an image (%s)", path, mediaType)
+	}
+	return Image{MediaType: mediaType, Data: data}, nil
+}
+
+// ImageFromURL refers to an image the API fetches itself
+func ImageFromURL(url string) Image {
+	return Image{URL: url}
+}
+
+// Document is a PDF or plain-text document attached to a message, sent
+// inline, by URL, or as a file uploaded to the Files API
+type Document struct {
+	// MediaType is application/pdf or text/plain
+	MediaType string
+	// Data is the raw document; PDFs are sent base64-encoded
+	Data []byte
+	// URL, if set, is fetched by the API instead of sending Data
+	URL string
+	// FileID, if set, refers to a file uploaded to the Files API
+	FileID string
+	// Title is shown to the model with the document
+	Title string
+}
+
+// DocumentFromFile reads a PDF or text document, titled with the file name
+func DocumentFromFile(path string) (Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Document{}, err
+	}
+	doc := Document{Data: data, Title: filepath.Base(path)}
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		doc.MediaType = "application/pdf"
+	case strings.HasPrefix(http.DetectContentType(data), "text/plain"):
+		doc.MediaType = "text/plain"
+	default:
+		return Document{}, fmt.Errorf("%s is neither a PDF nor plain text", path)
+	}
+	return doc, nil
+}
+
+// DocumentFromURL refers to a PDF the API fetches itself
+func DocumentFromURL(url string) Document {
+	return Document{MediaType: "application/pdf", URL: url}
+}
+
+// AddUserImages appends a user message showing images, followed by text
+func (c *Conversation) AddUserImages(content string, images ...Image) {
+	c.Add(Message{Role: RoleUser, Content: content, Images: images})
+}
+
+// AddUserDocuments appends a user message showing documents, followed by
+// text
+func (c *Conversation) AddUserDocuments(content string, documents ...Document) {
+	c.Add(Message{Role: RoleUser, Content: content, Documents: documents})
+}
+
+// contentBlock is a Messages API content block
+type contentBlock struct {
+	Type   string         `json:"type"`
+	Text   string         `json:"text,omitempty"`
+	Source *contentSource `json:"source,omitempty"`
+	Title  string         `json:"title,omitempty"`
+}
+
+type contentSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+	FileID    string `json:"file_id,omitempty"`
+}
+
+// MarshalJSON encodes the content as a string, or as content blocks when
+// the message has attachments: documents, then images, then the text
+func (m Message) MarshalJSON() ([]byte, error) {
+	type plain struct {
+		Role    Role   `json:"role"`
+		Content string `json:"content"`
+	}
+	if len(m.Images) == 0 && len(m.Documents) == 0 {
+		return json.Marshal(plain{Role: m.Role, Content: m.Content})
+	}
+
+	blocks := make([]contentBlock, 0, len(m.Documents)+len(m.Images)+1)
+	for _, doc := range m.Documents {
+		var source *contentSource
+		switch {
+		case doc.FileID != "":
+			source = &contentSource{Type: "file", FileID: doc.FileID}
+		case doc.URL != "":
+			source = &contentSource{Type: "url", URL: doc.URL}
+		case doc.MediaType == "text/plain":
+			source = &contentSource{Type: "text", MediaType: doc.MediaType, Data: string(doc.Data)}
+		default:
+			source = &contentSource{Type: "base64", MediaType: doc.MediaType, Data: base64.StdEncoding.EncodeToString(doc.Data)}
+		}
+		blocks = append(blocks, contentBlock{Type: "document", Source: source, Title: doc.Title})
+	}
+	for _, img := range m.Images {
+		source := &contentSource{Type: "url", URL: img.URL}
+		if img.URL == "" {
+			source = &contentSource{Type: "base64", MediaType: img.MediaType, Data: base64.StdEncoding.EncodeToString(img.Data)}
+		}
+		blocks = append(blocks, contentBlock{Type: "image", Source: source})
+	}
+	if m.Content != "" {
+		blocks = append(blocks, contentBlock{Type: "text", Text: m.Content})
+	}
+	return json.Marshal(struct {
+		Role    Role           `json:"role"`
+		Content []contentBlock `json:"content"`
+	}{m.Role, blocks})
+}
+
+// UnmarshalJSON decodes content given as a string or as content blocks,
+// joining the text blocks
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Role    Role            `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Message{Role: raw.Role}
+	if len(raw.Content) == 0 || json.Unmarshal(raw.Content, &m.Content) == nil {
+		return nil
+	}
+
+	var blocks []contentBlock
+	if err := json.Unmarshal(raw.Content, &blocks); err != nil {
+		return fmt.Errorf("invalid message content: %w", err)
+	}
+	var texts []string
+	for _, b := range blocks {
+		switch {
+		case b.Type == "text":
+			texts = append(texts, b.Text)
+		case b.Type == "image" && b.Source != nil && b.Source.Type == "url":
+			m.Images = append(m.Images, Image{URL: b.Source.URL})
+		case b.Type == "image" && b.Source != nil:
+			img, err := base64.StdEncoding.DecodeString(b.Source.Data)
+			if err != nil {
+				return fmt.Errorf("invalid image data: %w", err)
+			}
+			m.Images = append(m.Images, Image{MediaType: b.Source.MediaType, Data: img})
+		case b.Type == "document" && b.Source != nil:
+			doc := Document{MediaType: b.Source.MediaType, URL: b.Source.URL, FileID: b.Source.FileID, Title: b.Title}
+			switch b.Source.Type {
+			case "text":
+				doc.Data = []byte(b.Source.Data)
+			case "base64":
+				data, err := base64.StdEncoding.DecodeString(b.Source.Data)
+				if err != nil {
+					return fmt.Errorf("invalid document data: %w", err)
+				}
+				doc.Data = data
+			}
+			m.Documents = append(m.Documents, doc)
+		}
+	}
+	m.Content = strings.Join(texts, "\n")
+	return nil
+}
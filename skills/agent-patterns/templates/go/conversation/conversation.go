@@ -0,0 +1,313 @@
+/*
+ * Conversation Memory for Go Agent Patterns
+ * Message history with roles, windowing, summarization, and serialization
+ */
+
+// Package conversation manages the message history of multi-turn patterns.
+//
+// A Conversation keeps every message, and Window returns the part that is
+// sent to the model: the most recent messages that fit the configured
+// limits, preceded by a summary of older ones when a Summarizer is set.
+//
+// Example:
+//
+//	conv := conversation.New("You are a helpful assistant.",
+//	    conversation.WithMaxMessages(20),
+//	    conversation.WithSummarizer(agentpatterns.NewSummarizer(client)),
+//	)
+//	conv.AddUser("What is the capital of France?")
+//	reply, err := client.Chat(ctx, conv, agentpatterns.DefaultModel, 1024)
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Role is the author of a message
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// Message is one turn of a conversation, in the Messages API wire format
+type Message struct {
+	Role    Role
+	Content string
+	// Documents and Images are shown to the model before Content
+	Documents []Document
+	Images    []Image
+}
+
+// Summarizer condenses messages that no longer fit the window. It receives
+// the previous summary (possibly empty) and the messages being dropped.
+type Summarizer interface {
+	Summarize(ctx context.Context, previous string, dropped []Message) (string, error)
+}
+
+// SummarizerFunc adapts a function to Summarizer
+type SummarizerFunc func(ctx context.Context, previous string, dropped []Message) (string, error)
+
+// Summarize calls f
+func (f SummarizerFunc) Summarize(ctx context.Context, previous string, dropped []Message) (string, error) {
+	return f(ctx, previous, dropped)
+}
+
+// Option configures a Conversation
+type Option func(*Conversation)
+
+// WithMaxMessages limits the window to the n most recent messages
+func WithMaxMessages(n int) Option {
+	return func(c *Conversation) { c.maxMessages = n }
+}
+
+// WithMaxChars limits the window to roughly n characters of content, a
+// cheap stand-in for a token limit (about four characters per token)
+func WithMaxChars(n int) Option {
+	return func(c *Conversation) { c.maxChars = n }
+}
+
+// WithMaxTokens limits the window, together with the system prompt and
+// summary, to roughly n tokens, estimated at four characters per token
+func WithMaxTokens(n int) Option {
+	return func(c *Conversation) { c.maxTokens = n }
+}
+
+// PreserveFunc reports whether a message that has fallen out of the window
+// should still be sent verbatim. It receives the messages after it.
+type PreserveFunc func(m Message, later []Message) bool
+
+// WithPreserve keeps messages outside the window that preserve selects,
+// e.g. tool results that later messages refer to. They are sent after the
+// summary, in addition to the window.
+func WithPreserve(preserve PreserveFunc) Option {
+	return func(c *Conversation) { c.preserve = preserve }
+}
+
+// WithSummarizer summarizes messages that fall out of the window instead of
+// dropping them silently
+func WithSummarizer(s Summarizer) Option {
+	return func(c *Conversation) { c.summarizer = s }
+}
+
+// Conversation is a message history. It is safe for concurrent use.
+type Conversation struct {
+	mu          sync.Mutex
+	system      string
+	summary     string
+	messages    []Message
+	summarized  int
+	maxMessages int
+	maxChars    int
+	maxTokens   int
+	summarizer  Summarizer
+	preserve    PreserveFunc
+}
+
+// New creates an empty conversation with a system prompt
+func New(system string, opts ...Option) *Conversation {
+	c := &Conversation{system: system}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// System returns the system prompt
+func (c *Conversation) System() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.system
+}
+
+// SetSystem replaces the system prompt
+func (c *Conversation) SetSystem(system string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.system = system
+}
+
+// Add appends messages
+func (c *Conversation) Add(messages ...Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, messages...)
+}
+
+// AddUser appends a user message
+func (c *Conversation) AddUser(content string) {
+	c.Add(Message{Role: RoleUser, Content: content})
+}
+
+// AddAssistant appends an assistant message
+func (c *Conversation) AddAssistant(content string) {
+	c.Add(Message{Role: RoleAssistant, Content: content})
+}
+
+// Messages returns a copy of the full history
+func (c *Conversation) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message(nil), c.messages...)
+}
+
+// Last returns the most recent message, or false if there is none
+func (c *Conversation) Last() (Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.messages) == 0 {
+		return Message{}, false
+	}
+	return c.messages[len(c.messages)-1], true
+}
+
+// Len returns the number of messages in the full history
+func (c *Conversation) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.messages)
+}
+
+// Summary returns the summary of messages outside the window, if any
+func (c *Conversation) Summary() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.summary
+}
+
+// Reset clears the history and summary, keeping the system prompt
+func (c *Conversation) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = nil
+	c.summary = ""
+	c.summarized = 0
+}
+
+// Window returns the messages to send to the model. It keeps the most
+// recent messages within the limits, always starting on a user message as
+// the API requires. Older messages are folded into the summary, which is
+// prepended as a user message, when a Summarizer is configured.
+func (c *Conversation) Window(ctx context.Context) ([]Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start := c.windowStart()
+	if c.summarizer != nil && start > c.summarized {
+		summary, err := c.summarizer.Summarize(ctx, c.summary, c.messages[c.summarized:start])
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize conversation: %w", err)
+		}
+		c.summary = summary
+		c.summarized = start
+	}
+
+	window := append([]Message(nil), c.messages[start:]...)
+	var preserved []string
+	if c.preserve != nil {
+		for i := 0; i < start; i++ {
+			if c.preserve(c.messages[i], c.messages[i+1:]) {
+				preserved = append(preserved, fmt.Sprintf("%s: %s", c.messages[i].Role, c.messages[i].Content))
+			}
+		}
+	}
+	if c.summary == "" && len(preserved) == 0 {
+		return window, nil
+	}
+
+	// Keep roles alternating: the summary is a user turn, so merge it into
+	// the first windowed message
+	var intro string
+	if c.summary != "" {
+		intro = "Summary of the earlier conversation:\n" + c.summary
+	}
+	if len(preserved) > 0 {
+		if intro != "" {
+			intro += "\n\n"
+		}
+		intro += "Earlier messages kept verbatim:\n" + strings.Join(preserved, "\n\n")
+	}
+	if len(window) == 0 {
+		return []Message{{Role: RoleUser, Content: intro}}, nil
+	}
+	window[0].Content = intro + "\n\n" + window[0].Content
+	return window, nil
+}
+
+// windowStart returns the index of the first message inside the limits
+func (c *Conversation) windowStart() int {
+	start := 0
+	if c.maxMessages > 0 && len(c.messages) > c.maxMessages {
+		start = len(c.messages) - c.maxMessages
+	}
+	maxChars := c.maxChars
+	if c.maxTokens > 0 {
+		budget := c.maxTokens*4 - len(c.system) - len(c.summary)
+		if budget < 0 {
+			budget = 0
+		}
+		if maxChars == 0 || budget < maxChars {
+			maxChars = budget
+		}
+	}
+	if maxChars > 0 || c.maxTokens > 0 {
+		chars := 0
+		for i := len(c.messages) - 1; i >= start; i-- {
+			chars += len(c.messages[i].Content)
+			if chars > maxChars {
+				// Always keep at least the latest message
+				start = i + 1
+				if start == len(c.messages) {
+					start--
+				}
+				break
+			}
+		}
+	}
+	for start < len(c.messages)-1 && c.messages[start].Role != RoleUser {
+		start++
+	}
+	return start
+}
+
+// state is the serialized form of a Conversation. Options are not
+// serialized; configure them on the Conversation being restored into.
+type state struct {
+	System     string    `json:"system,omitempty"`
+	Summary    string    `json:"summary,omitempty"`
+	Summarized int       `json:"summarized,omitempty"`
+	Messages   []Message `json:"messages"`
+}
+
+// MarshalJSON encodes the system prompt, summary, and full history
+func (c *Conversation) MarshalJSON() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return json.Marshal(state{
+		System:     c.system,
+		Summary:    c.summary,
+		Summarized: c.summarized,
+		Messages:   c.messages,
+	})
+}
+
+// UnmarshalJSON restores a conversation encoded with MarshalJSON, keeping
+// the receiver's window and summarizer options
+func (c *Conversation) UnmarshalJSON(data []byte) error {
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.system = s.System
+	c.summary = s.Summary
+	c.summarized = s.Summarized
+	c.messages = s.Messages
+	return nil
+}
@@ -0,0 +1,258 @@
+/*
+ * Cost Accounting for Go Agent Patterns
+ * Token usage and spend tracked per run, per pattern, and per model
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Usage is the token usage reported by the Messages API for one call
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// ModelPricing is the price of a model in US dollars per million tokens
+type ModelPricing struct {
+	InputPerMTok  float64
+	OutputPerMTok float64
+}
+
+// Cost returns the price of the given usage
+func (p ModelPricing) Cost(u Usage) float64 {
+	return (float64(u.InputTokens)*p.InputPerMTok + float64(u.OutputTokens)*p.OutputPerMTok) / 1e6
+}
+
+// DefaultPricing returns list prices for the models used in these templates
+func DefaultPricing() map[string]ModelPricing {
+	return map[string]ModelPricing{
+		"claude-3-haiku-20240307":    {InputPerMTok: 0.25, OutputPerMTok: 1.25},
+		"claude-3-5-haiku-20241022":  {InputPerMTok: 0.80, OutputPerMTok: 4},
+		"claude-3-5-sonnet-20241022": {InputPerMTok: 3, OutputPerMTok: 15},
+		"claude-sonnet-4-20250514":   {InputPerMTok: 3, OutputPerMTok: 15},
+		"claude-opus-4-20250514":     {InputPerMTok: 15, OutputPerMTok: 75},
+		"voyage-3":                   {InputPerMTok: 0.06},
+	}
+}
+
+// CostRecord is the accounting entry for a single LLM call
+type CostRecord struct {
+	RunID        string    `json:"run_id"`
+	Pattern      string    `json:"pattern"`
+	Model        string    `json:"model"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	Cost         float64   `json:"cost_usd"`
+	Time         time.Time `json:"time"`
+}
+
+// CostSummary aggregates a set of CostRecords
+type CostSummary struct {
+	Calls        int     `json:"calls"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost_usd"`
+}
+
+func (s *CostSummary) add(r CostRecord) {
+	s.Calls++
+	s.InputTokens += r.InputTokens
+	s.OutputTokens += r.OutputTokens
+	s.Cost += r.Cost
+}
+
+// CostTracker collects usage from every pattern it is attached to. A tracker
+// given to the outermost pattern travels in the context, so workers, agents,
+// and other nested patterns report into the same tracker. It is safe for
+// concurrent use.
+//
+// Example:
+//
+//	costs := NewCostTracker(DefaultPricing())
+//	orch := NewOrchestrator(client, WithCostTracker(costs))
+//	result, err := orch.Execute(ctx, task)
+//	fmt.Printf("$%.4f\n", costs.Total().Cost)
+type CostTracker struct {
+	mu      sync.Mutex
+	pricing map[string]ModelPricing
+	records []CostRecord
+}
+
+// NewCostTracker creates a tracker using the given pricing table.
+// Calls to models missing from the table are recorded at zero cost.
+func NewCostTracker(pricing map[string]ModelPricing) *CostTracker {
+	table := make(map[string]ModelPricing, len(pricing))
+	for model, p := range pricing {
+		table[model] = p
+	}
+	return &CostTracker{pricing: table}
+}
+
+// SetPricing adds or replaces the price of a model
+func (t *CostTracker) SetPricing(model string, pricing ModelPricing) *CostTracker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pricing[model] = pricing
+	return t
+}
+
+// Record accounts for one call and returns the resulting entry
+func (t *CostTracker) Record(runID, pattern, model string, usage Usage) CostRecord {
+	return t.recordAt(runID, pattern, model, usage, 1)
+}
+
+// recordAt is Record for a call billed at rate times the list price, as
+// batch requests are
+func (t *CostTracker) recordAt(runID, pattern, model string, usage Usage, rate float64) CostRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec := CostRecord{
+		RunID:        runID,
+		Pattern:      pattern,
+		Model:        model,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		Cost:         rate * t.pricing[model].Cost(usage),
+		Time:         time.Now(),
+	}
+	t.records = append(t.records, rec)
+	return rec
+}
+
+// Records returns a copy of all entries in the order they were recorded
+func (t *CostTracker) Records() []CostRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	records := make([]CostRecord, len(t.records))
+	copy(records, t.records)
+	return records
+}
+
+// Total summarizes every recorded call
+func (t *CostTracker) Total() CostSummary {
+	var total CostSummary
+	for _, r := range t.Records() {
+		total.add(r)
+	}
+	return total
+}
+
+// Run summarizes the calls made under a single run ID
+func (t *CostTracker) Run(runID string) CostSummary {
+	var total CostSummary
+	for _, r := range t.Records() {
+		if r.RunID == runID {
+			total.add(r)
+		}
+	}
+	return total
+}
+
+// ByRun summarizes calls per run ID
+func (t *CostTracker) ByRun() map[string]CostSummary {
+	return t.groupBy(func(r CostRecord) string { return r.RunID })
+}
+
+// ByPattern summarizes calls per pattern
+func (t *CostTracker) ByPattern() map[string]CostSummary {
+	return t.groupBy(func(r CostRecord) string { return r.Pattern })
+}
+
+// ByModel summarizes calls per model
+func (t *CostTracker) ByModel() map[string]CostSummary {
+	return t.groupBy(func(r CostRecord) string { return r.Model })
+}
+
+func (t *CostTracker) groupBy(key func(CostRecord) string) map[string]CostSummary {
+	groups := make(map[string]CostSummary)
+	for _, r := range t.Records() {
+		s := groups[key(r)]
+		s.add(r)
+		groups[key(r)] = s
+	}
+	return groups
+}
+
+// Reset discards all recorded calls, keeping the pricing table
+func (t *CostTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.records = nil
+}
+
+// WriteJSON exports the records and totals as a JSON document
+func (t *CostTracker) WriteJSON(w io.Writer) error {
+	report := struct {
+		Total     CostSummary            `json:"total"`
+		ByRun     map[string]CostSummary `json:"by_run"`
+		ByPattern map[string]CostSummary `json:"by_pattern"`
+		ByModel   map[string]CostSummary `json:"by_model"`
+		Records   []CostRecord           `json:"records"`
+	}{
+		Total:     t.Total(),
+		ByRun:     t.ByRun(),
+		ByPattern: t.ByPattern(),
+		ByModel:   t.ByModel(),
+		Records:   t.Records(),
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// WriteCSV exports one row per recorded call
+func (t *CostTracker) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "run_id", "pattern", "model", "input_tokens", "output_tokens", "cost_usd"})
+	for _, r := range t.Records() {
+		cw.Write([]string{
+			r.Time.Format(time.RFC3339Nano),
+			r.RunID,
+			r.Pattern,
+			r.Model,
+			strconv.Itoa(r.InputTokens),
+			strconv.Itoa(r.OutputTokens),
+			strconv.FormatFloat(r.Cost, 'f', 6, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WithCostTracker reports the usage of every LLM call to tracker
+func WithCostTracker(tracker *CostTracker) Option {
+	return func(c *patternConfig) { c.costs = tracker }
+}
+
+type costTrackerKey struct{}
+
+// CostTrackerFromContext returns the tracker attached to the current run, if any
+func CostTrackerFromContext(ctx context.Context) *CostTracker {
+	tracker, _ := ctx.Value(costTrackerKey{}).(*CostTracker)
+	return tracker
+}
+
+// ContextWithCostTracker attaches tracker to ctx so every pattern run under
+// it reports usage there, overriding trackers set with WithCostTracker. It
+// lets callers measure a single run without reconfiguring the patterns.
+func ContextWithCostTracker(ctx context.Context, tracker *CostTracker) context.Context {
+	return context.WithValue(ctx, costTrackerKey{}, tracker)
+}
+
+// recordUsage reports usage to the tracker attached to ctx. Helpers that do
+// not go through patternConfig.call (embeddings) use it directly.
+func recordUsage(ctx context.Context, pattern, model string, usage Usage) {
+	if tracker := CostTrackerFromContext(ctx); tracker != nil {
+		tracker.Record(RunIDFromContext(ctx), pattern, model, usage)
+	}
+}
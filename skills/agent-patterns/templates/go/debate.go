@@ -0,0 +1,292 @@
+/*
+ * Debate Pattern Implementation for Go
+ * Multiple personas argue a question over several rounds before a judge decides
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/jsonx"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/schema"
+)
+
+// Persona is a debater with its own perspective
+type Persona struct {
+	Name string
+	// Perspective describes the position or expertise the persona argues
+	// from, e.g. "a security engineer who prioritizes risk reduction"
+	Perspective string
+	// Model overrides the debate's model for this persona
+	Model string
+}
+
+// DebateTurn is one persona's argument in one round
+type DebateTurn struct {
+	Round    int    `json:"round"`
+	Persona  string `json:"persona"`
+	Argument string `json:"argument"`
+}
+
+// DebateVerdict is the judge's decision
+type DebateVerdict struct {
+	Answer    string `json:"answer" description:"The final answer to the question"`
+	Reasoning string `json:"reasoning" description:"Which arguments were decisive and why"`
+	Winner    string `json:"winner" description:"Name of the most persuasive persona, or \"none\""`
+}
+
+// DebateResult is the judge's answer with the full transcript
+type DebateResult struct {
+	Question   string
+	Answer     string
+	Reasoning  string
+	Winner     string
+	Transcript []DebateTurn
+	// Vote is the jury's vote when the debate is judged by vote
+	Vote *VotingResult
+}
+
+// debateCheckpoint is the state persisted after each round when a store is
+// configured
+type debateCheckpoint struct {
+	Transcript []DebateTurn `json:"transcript"`
+}
+
+// Debate has personas argue a question over several rounds, each seeing
+// the others' earlier arguments, then asks a judge for the final answer,
+// or a jury to vote for the most persuasive persona. Personas argue
+// concurrently within a round.
+//
+// Example:
+//
+//	debate := NewDebate(client).
+//	    AddPersona(Persona{Name: "Advocate", Perspective: "argues for adopting the proposal"}).
+//	    AddPersona(Persona{Name: "Skeptic", Perspective: "probes costs, risks, and alternatives"}).
+//	    SetRounds(3).
+//	    WithJudgeModel("claude-opus-4-20250514")
+//	result, err := debate.Run(ctx, "Should we migrate our monolith to microservices this year?")
+type Debate struct {
+	client     *AnthropicClient
+	cfg        patternConfig
+	personas   []Persona
+	rounds     int
+	judgeModel string
+	jury       []Juror
+	strategy   VotingStrategy
+}
+
+// NewDebate creates a new Debate with two rounds
+func NewDebate(client *AnthropicClient, opts ...Option) *Debate {
+	cfg := newPatternConfig("debate", opts)
+	return &Debate{
+		client:     client,
+		cfg:        cfg,
+		rounds:     2,
+		judgeModel: cfg.model,
+	}
+}
+
+// AddPersona adds a debater
+func (d *Debate) AddPersona(persona Persona) *Debate {
+	d.personas = append(d.personas, persona)
+	return d
+}
+
+// SetRounds sets the number of rounds of argument
+func (d *Debate) SetRounds(rounds int) *Debate {
+	d.rounds = rounds
+	return d
+}
+
+// WithJudgeModel sets a different model for the judge
+func (d *Debate) WithJudgeModel(model string) *Debate {
+	d.judgeModel = model
+	return d
+}
+
+// JudgeByVote replaces the judge with a jury voting for the most
+// persuasive persona under strategy. The answer is the winner's final
+// argument.
+//
+// Example:
+//
+//	debate.JudgeByVote(VotingBorda,
+//	    Juror{Model: "claude-sonnet-4-20250514", Count: 3},
+//	    Juror{Model: "claude-opus-4-20250514", Count: 2})
+func (d *Debate) JudgeByVote(strategy VotingStrategy, jurors ...Juror) *Debate {
+	d.strategy = strategy
+	d.jury = jurors
+	return d
+}
+
+// Run debates question and returns the judge's answer
+func (d *Debate) Run(ctx context.Context, question string) (*DebateResult, error) {
+	if len(d.personas) < 2 {
+		return nil, fmt.Errorf("debate needs at least two personas, got %d", len(d.personas))
+	}
+
+	ctx, cancel := d.cfg.startRun(ctx)
+	defer cancel()
+
+	var checkpoint debateCheckpoint
+	if _, err := d.cfg.loadCheckpoint(ctx, &checkpoint); err != nil {
+		return nil, err
+	}
+	transcript := checkpoint.Transcript
+
+	for round := len(transcript)/len(d.personas) + 1; round <= d.rounds; round++ {
+		turns, err := d.runRound(ctx, question, round, transcript)
+		if err != nil {
+			return nil, fmt.Errorf("round %d failed: %w", round, err)
+		}
+		transcript = append(transcript, turns...)
+		d.cfg.publish(ctx, PhaseIteration, map[string]interface{}{"iteration": round})
+
+		if err := d.cfg.saveCheckpoint(ctx, debateCheckpoint{Transcript: transcript}); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(d.jury) > 0 {
+		voteCtx, span := StartSpan(ctx, "debate.vote")
+		result, err := d.vote(voteCtx, question, transcript)
+		span.Finish(err)
+		if err != nil {
+			return nil, fmt.Errorf("jury vote failed: %w", err)
+		}
+		return result, nil
+	}
+
+	judgeCtx, span := StartSpan(ctx, "debate.judge")
+	verdict, err := d.judge(judgeCtx, question, transcript)
+	span.Finish(err)
+	if err != nil {
+		return nil, fmt.Errorf("judging failed: %w", err)
+	}
+
+	return &DebateResult{
+		Question:   question,
+		Answer:     verdict.Answer,
+		Reasoning:  verdict.Reasoning,
+		Winner:     verdict.Winner,
+		Transcript: transcript,
+	}, nil
+}
+
+// runRound collects one argument from every persona concurrently
+func (d *Debate) runRound(ctx context.Context, question string, round int, transcript []DebateTurn) ([]DebateTurn, error) {
+	ctx, span := StartSpan(ctx, "debate.round")
+	span.SetAttribute("round", round)
+
+	turns := make([]DebateTurn, len(d.personas))
+	errs := make([]error, len(d.personas))
+	var wg sync.WaitGroup
+	for i, persona := range d.personas {
+		wg.Add(1)
+		go func(idx int, p Persona) {
+			defer wg.Done()
+			argument, err := d.argue(ctx, question, p, round, transcript)
+			turns[idx] = DebateTurn{Round: round, Persona: p.Name, Argument: argument}
+			errs[idx] = err
+		}(i, persona)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			err = fmt.Errorf("%s: %w", d.personas[i].Name, err)
+			span.Finish(err)
+			return nil, err
+		}
+	}
+	span.Finish(nil)
+	return turns, nil
+}
+
+func (d *Debate) argue(ctx context.Context, question string, persona Persona, round int, transcript []DebateTurn) (string, error) {
+	name := "debate.rebuttal"
+	if round == 1 {
+		name = "debate.opening"
+	}
+	prompt, err := d.cfg.prompt(name, map[string]interface{}{
+		"name":        persona.Name,
+		"perspective": persona.Perspective,
+		"question":    question,
+		"transcript":  transcript,
+		"round":       round,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	model := persona.Model
+	if model == "" {
+		model = d.cfg.model
+	}
+	return d.cfg.call(ctx, d.client, prompt, model, d.cfg.tokens(1024))
+}
+
+func (d *Debate) judge(ctx context.Context, question string, transcript []DebateTurn) (*DebateVerdict, error) {
+	prompt, err := d.cfg.prompt("debate.judge", map[string]interface{}{
+		"question":   question,
+		"transcript": transcript,
+		"schema":     schema.MustFor[DebateVerdict]().String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := d.cfg.call(ctx, d.client, prompt, d.judgeModel, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	var verdict DebateVerdict
+	if err := jsonx.Unmarshal(response, &verdict); err != nil {
+		return nil, fmt.Errorf("failed to parse verdict: %w", err)
+	}
+	return &verdict, nil
+}
+
+// vote asks the jury which persona argued best, answering with the
+// winner's final argument
+func (d *Debate) vote(ctx context.Context, question string, transcript []DebateTurn) (*DebateResult, error) {
+	names := make([]string, len(d.personas))
+	for i, p := range d.personas {
+		names[i] = p.Name
+	}
+	prompt, err := d.cfg.prompt("debate.vote", map[string]interface{}{"question": question, "transcript": transcript})
+	if err != nil {
+		return nil, err
+	}
+
+	voter := NewVotingParallelizer(d.client, d.cfg.childOptions()...).WithVotingStrategy(d.strategy)
+	vote, err := voter.VoteWithJury(ctx, prompt, names, d.jury)
+	if err != nil {
+		return nil, err
+	}
+
+	var answer string
+	for _, t := range transcript {
+		if t.Persona == vote.WinningOption {
+			answer = t.Argument
+		}
+	}
+	var votes int
+	for _, c := range vote.VoteCounts {
+		if c.Option == vote.WinningOption {
+			votes = c.Votes
+		}
+	}
+	return &DebateResult{
+		Question:   question,
+		Answer:     answer,
+		Reasoning:  fmt.Sprintf("%s won the jury vote (%s) with %d of %d first-choice votes", vote.WinningOption, vote.Strategy, votes, vote.TotalVotes),
+		Winner:     vote.WinningOption,
+		Transcript: transcript,
+		Vote:       vote,
+	}, nil
+}
@@ -0,0 +1,306 @@
+/*
+ * Document Input for Go Agent Patterns
+ * PDF and text document blocks, size limits, page splitting, and file uploads
+ */
+
+package agentpatterns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/chunker"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/conversation"
+)
+
+// Document is a PDF or plain-text document shown to the model
+type Document = conversation.Document
+
+// Limits on a single document sent to the Messages API. Requests are
+// capped at 32MB, which base64 encoding brings a 24MB PDF close to.
+const (
+	MaxDocumentBytes      = 24 << 20
+	MaxDocumentPages      = 100
+	MaxTextDocumentTokens = 100000
+)
+
+// ErrDocumentTooLarge is returned for a document over the size or page
+// limits that cannot be split
+var ErrDocumentTooLarge = errors.New("document too large")
+
+// filesBeta enables the Files API and file references in messages
+const filesBeta = "files-api-2025-04-14"
+
+// PDFSplitter counts and splits the pages of PDFs
+type PDFSplitter interface {
+	PageCount(ctx context.Context, pdf []byte) (int, error)
+	// Split returns the PDF as parts of pagesPerPart pages each, in order;
+	// the last part may be shorter
+	Split(ctx context.Context, pdf []byte, pagesPerPart int) ([][]byte, error)
+}
+
+// QPDF splits PDFs with the qpdf command-line tool
+type QPDF struct {
+	// Path is the qpdf binary; empty means "qpdf" on the PATH
+	Path string
+}
+
+// PageCount implements PDFSplitter
+func (q QPDF) PageCount(ctx context.Context, pdf []byte) (int, error) {
+	dir, err := os.MkdirTemp("", "qpdf")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+	in := filepath.Join(dir, "in.pdf")
+	if err := os.WriteFile(in, pdf, 0o600); err != nil {
+		return 0, err
+	}
+
+	out, err := q.run(ctx, "--show-npages", in)
+	if err != nil {
+		return 0, err
+	}
+	pages, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, fmt.Errorf("qpdf: unexpected page count %q", strings.TrimSpace(string(out)))
+	}
+	return pages, nil
+}
+
+// Split implements PDFSplitter
+func (q QPDF) Split(ctx context.Context, pdf []byte, pagesPerPart int) ([][]byte, error) {
+	dir, err := os.MkdirTemp("", "qpdf")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	in := filepath.Join(dir, "in.pdf")
+	if err := os.WriteFile(in, pdf, 0o600); err != nil {
+		return nil, err
+	}
+
+	if _, err := q.run(ctx, fmt.Sprintf("--split-pages=%d", pagesPerPart), in, filepath.Join(dir, "part.pdf")); err != nil {
+		return nil, err
+	}
+	// Parts are named part-<first>-<last>.pdf, zero-padded so they sort
+	names, err := filepath.Glob(filepath.Join(dir, "part-*.pdf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	parts := make([][]byte, len(names))
+	for i, name := range names {
+		if parts[i], err = os.ReadFile(name); err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+func (q QPDF) run(ctx context.Context, args ...string) ([]byte, error) {
+	path := q.Path
+	if path == "" {
+		path = "qpdf"
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	// Exit status 3 means success with warnings
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 3 {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("qpdf failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// SplitDocument splits a document into parts the Messages API accepts.
+// PDFs over MaxDocumentBytes or MaxDocumentPages are split by page with
+// splitter, titled with their page ranges; without a splitter an oversized
+// PDF fails with ErrDocumentTooLarge. Text documents are split by
+// MaxTextDocumentTokens. Documents sent by URL or file ID are returned as
+// they are.
+//
+// Example:
+//
+//	doc, err := conversation.DocumentFromFile("annual-report.pdf")
+//	parts, err := SplitDocument(ctx, doc, QPDF{})
+func SplitDocument(ctx context.Context, doc Document, splitter PDFSplitter) ([]Document, error) {
+	if doc.URL != "" || doc.FileID != "" {
+		return []Document{doc}, nil
+	}
+	switch doc.MediaType {
+	case "application/pdf":
+		return splitPDF(ctx, doc, splitter)
+	case "text/plain":
+		return splitText(doc), nil
+	}
+	return nil, fmt.Errorf("unsupported document type %q", doc.MediaType)
+}
+
+func splitPDF(ctx context.Context, doc Document, splitter PDFSplitter) ([]Document, error) {
+	if splitter == nil {
+		if len(doc.Data) > MaxDocumentBytes {
+			return nil, fmt.Errorf("%w: %s is %d bytes, over the %d byte limit", ErrDocumentTooLarge, documentName(doc), len(doc.Data), MaxDocumentBytes)
+		}
+		return []Document{doc}, nil
+	}
+
+	pages, err := splitter.PageCount(ctx, doc.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pages of %s: %w", documentName(doc), err)
+	}
+	if pages <= MaxDocumentPages && len(doc.Data) <= MaxDocumentBytes {
+		return []Document{doc}, nil
+	}
+
+	// Halve the pages per part until every part is small enough
+	perPart := MaxDocumentPages
+	if pages < perPart {
+		perPart = pages
+	}
+	for {
+		parts, err := splitter.Split(ctx, doc.Data, perPart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split %s: %w", documentName(doc), err)
+		}
+		fits := true
+		for _, part := range parts {
+			if len(part) > MaxDocumentBytes {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			docs := make([]Document, len(parts))
+			for i, part := range parts {
+				first, last := i*perPart+1, (i+1)*perPart
+				if last > pages {
+					last = pages
+				}
+				docs[i] = Document{MediaType: doc.MediaType, Data: part, Title: fmt.Sprintf("%s (pages %d-%d)", documentName(doc), first, last)}
+			}
+			return docs, nil
+		}
+		if perPart == 1 {
+			return nil, fmt.Errorf("%w: %s has a page over the %d byte limit", ErrDocumentTooLarge, documentName(doc), MaxDocumentBytes)
+		}
+		perPart = (perPart + 1) / 2
+	}
+}
+
+func splitText(doc Document) []Document {
+	text := string(doc.Data)
+	if chunker.EstimateTokens(text) <= MaxTextDocumentTokens {
+		return []Document{doc}
+	}
+	chunks := chunker.NewTextChunker(MaxTextDocumentTokens).Split(text)
+	docs := make([]Document, len(chunks))
+	for i, chunk := range chunks {
+		docs[i] = Document{
+			MediaType: doc.MediaType,
+			Data:      []byte(chunk.Text),
+			Title:     fmt.Sprintf("%s (part %d of %d)", documentName(doc), i+1, len(chunks)),
+		}
+	}
+	return docs
+}
+
+func documentName(doc Document) string {
+	if doc.Title != "" {
+		return doc.Title
+	}
+	return "document"
+}
+
+// UploadDocument uploads a document's data to the Files API and returns
+// the document referring to the uploaded file, which later requests can
+// send without re-uploading it
+//
+// Example:
+//
+//	doc, err := conversation.DocumentFromFile("contract.pdf")
+//	doc, err = client.UploadDocument(ctx, doc)
+func (c *AnthropicClient) UploadDocument(ctx context.Context, doc Document) (Document, error) {
+	if len(doc.Data) == 0 {
+		return Document{}, fmt.Errorf("document has no data to upload")
+	}
+	filename := documentName(doc)
+	if doc.MediaType == "application/pdf" && !strings.HasSuffix(strings.ToLower(filename), ".pdf") {
+		filename += ".pdf"
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {fmt.Sprintf(`form-data; name="file"; filename=%q`, filename)},
+		"Content-Type":        {doc.MediaType},
+	})
+	if err != nil {
+		return Document{}, err
+	}
+	part.Write(doc.Data)
+	if err := form.Close(); err != nil {
+		return Document{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL("/v1/files"), &body)
+	if err != nil {
+		return Document{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("x-api-key", c.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("anthropic-beta", filesBeta)
+	req.Header.Set("content-type", form.FormDataContentType())
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return Document{}, fmt.Errorf("failed to upload %s: %w", filename, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return Document{}, &APIError{
+			StatusCode: resp.StatusCode,
+			Body:       string(respBody),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return Document{}, fmt.Errorf("failed to decode upload response: %w", err)
+	}
+	return Document{MediaType: doc.MediaType, FileID: file.ID, Title: doc.Title}, nil
+}
+
+// usesFiles reports whether a request refers to uploaded files
+func usesFiles(req MessageRequest) bool {
+	for _, m := range req.Messages {
+		for _, doc := range m.Documents {
+			if doc.FileID != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,311 @@
+/*
+ * Durable Execution for Go Agent Patterns
+ * Journaled workflow steps that replay and resume after a crash
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/store"
+)
+
+// Journal entry statuses
+const (
+	StepStarted   = "started"
+	StepCompleted = "completed"
+	StepFailed    = "failed"
+)
+
+// JournalEntry records one execution of a workflow step
+type JournalEntry struct {
+	Key      string          `json:"key"`
+	Kind     string          `json:"kind"`
+	Status   string          `json:"status"`
+	Attempts int             `json:"attempts"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Started  time.Time       `json:"started"`
+	Finished time.Time       `json:"finished,omitempty"`
+}
+
+// workflowResult is stored when a workflow finishes successfully
+type workflowResult struct {
+	Output   string    `json:"output"`
+	Finished time.Time `json:"finished"`
+}
+
+// DurableExecutor runs workflows whose steps are journaled to a store, in
+// the style of Temporal. Every LLM call and agent tool call made inside a
+// workflow, and every explicit Step, is recorded when it completes. Running
+// the workflow again with the same ID after a crash replays completed steps
+// from the journal instead of repeating them, and resumes at the first
+// step that did not complete.
+//
+// Steps have at-least-once semantics: a step interrupted by a crash runs
+// again on resume, so tools with side effects should be idempotent. Steps
+// are identified by their kind and input, so workflows may fan out in
+// parallel, but should otherwise be deterministic given the same step
+// results.
+//
+// Example:
+//
+//	durable := NewDurableExecutor(st)
+//	output, err := durable.Run(ctx, "report-2024-q3", func(ctx context.Context) (string, error) {
+//	    result, err := orchestrator.Execute(ctx, task)
+//	    if err != nil {
+//	        return "", err
+//	    }
+//	    return Step(ctx, "publish", func(ctx context.Context) (string, error) {
+//	        return publish(ctx, result.FinalResult)
+//	    })
+//	})
+type DurableExecutor struct {
+	st  store.Store
+	cfg patternConfig
+}
+
+// NewDurableExecutor creates an executor journaling to st
+func NewDurableExecutor(st store.Store, opts ...Option) *DurableExecutor {
+	return &DurableExecutor{st: st, cfg: newPatternConfig("durable", opts)}
+}
+
+// Run executes wf as workflowID, which is also used as the run ID. If the
+// workflow already finished, its stored output is returned without running
+// it again.
+func (e *DurableExecutor) Run(ctx context.Context, workflowID string, wf func(ctx context.Context) (string, error)) (string, error) {
+	prefix := "durable/" + workflowID + "/"
+
+	data, err := e.st.Get(ctx, prefix+"result")
+	if err == nil {
+		var result workflowResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return "", fmt.Errorf("failed to decode workflow result: %w", err)
+		}
+		e.cfg.logger.Info("workflow already finished", "workflow", workflowID)
+		return result.Output, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return "", fmt.Errorf("failed to load workflow result: %w", err)
+	}
+
+	j, err := loadJournal(ctx, e.st, prefix+"steps/")
+	if err != nil {
+		return "", err
+	}
+	if len(j.entries) > 0 {
+		e.cfg.logger.Info("resuming workflow", "workflow", workflowID, "journaled_steps", len(j.entries))
+	}
+
+	if RunIDFromContext(ctx) == "" {
+		ctx = WithRunID(ctx, workflowID)
+	}
+	ctx, cancel := e.cfg.startRun(ctx)
+	defer cancel()
+	ctx = context.WithValue(ctx, journalKey{}, j)
+
+	output, err := wf(ctx)
+	e.cfg.logger.Info("workflow finished", "workflow", workflowID, "replayed", j.replayed, "executed", j.executed, "error", err)
+	if err != nil {
+		return "", err
+	}
+
+	data, err = json.Marshal(workflowResult{Output: output, Finished: time.Now().UTC()})
+	if err != nil {
+		return "", err
+	}
+	if err := e.st.Put(ctx, prefix+"result", data); err != nil {
+		return "", fmt.Errorf("failed to save workflow result: %w", err)
+	}
+	return output, nil
+}
+
+// History returns the journal of a workflow in the order steps started
+func (e *DurableExecutor) History(ctx context.Context, workflowID string) ([]JournalEntry, error) {
+	j, err := loadJournal(ctx, e.st, "durable/"+workflowID+"/steps/")
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]JournalEntry, 0, len(j.entries))
+	for _, entry := range j.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].Started.Before(entries[b].Started) })
+	return entries, nil
+}
+
+// Reset deletes the journal and result of a workflow so it runs from the
+// start next time
+func (e *DurableExecutor) Reset(ctx context.Context, workflowID string) error {
+	keys, err := e.st.List(ctx, "durable/"+workflowID+"/")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := e.st.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Step runs fn as a journaled step of the enclosing durable workflow. On
+// replay it returns the journaled result without calling fn. Outside a
+// workflow it simply calls fn. T must round-trip through encoding/json.
+// Repeated steps with the same name are told apart by the order in which
+// they run.
+func Step[T any](ctx context.Context, name string, fn func(ctx context.Context) (T, error)) (T, error) {
+	return journaled(ctx, "step:"+name, "", fn)
+}
+
+type journalKey struct{}
+
+// journal is the step journal of one workflow execution
+type journal struct {
+	st     store.Store
+	prefix string
+
+	mu       sync.Mutex
+	entries  map[string]JournalEntry
+	seen     map[string]int
+	replayed int
+	executed int
+}
+
+func loadJournal(ctx context.Context, st store.Store, prefix string) (*journal, error) {
+	j := &journal{
+		st:      st,
+		prefix:  prefix,
+		entries: make(map[string]JournalEntry),
+		seen:    make(map[string]int),
+	}
+	keys, err := st.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list journal: %w", err)
+	}
+	for _, key := range keys {
+		data, err := st.Get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load journal entry %s: %w", key, err)
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode journal entry %s: %w", key, err)
+		}
+		j.entries[entry.Key] = entry
+	}
+	return j, nil
+}
+
+// next returns the key of the next step with this kind and input, and the
+// entry journaled under it, if any
+func (j *journal) next(kind, input string) (string, JournalEntry, bool) {
+	sum := sha256.Sum256([]byte(kind + "\x00" + input))
+	base := sanitizeKey(kind) + "-" + hex.EncodeToString(sum[:8])
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	key := fmt.Sprintf("%s-%d", base, j.seen[base])
+	j.seen[base]++
+	entry, ok := j.entries[key]
+	return key, entry, ok
+}
+
+func (j *journal) save(ctx context.Context, entry JournalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.entries[entry.Key] = entry
+	j.mu.Unlock()
+	if err := j.st.Put(ctx, j.prefix+entry.Key, data); err != nil {
+		return fmt.Errorf("failed to journal step %s: %w", entry.Key, err)
+	}
+	return nil
+}
+
+// journaled runs fn as a step of the workflow in ctx, if any, and records
+// or replays it when the run is being recorded or replayed. kind and input
+// identify the step across executions.
+func journaled[T any](ctx context.Context, kind, input string, fn func(ctx context.Context) (T, error)) (T, error) {
+	return recorded(ctx, kind, input, func(ctx context.Context) (T, error) {
+		return journalStep(ctx, kind, input, fn)
+	})
+}
+
+func journalStep[T any](ctx context.Context, kind, input string, fn func(ctx context.Context) (T, error)) (T, error) {
+	j, _ := ctx.Value(journalKey{}).(*journal)
+	if j == nil {
+		return fn(ctx)
+	}
+
+	var zero T
+	key, entry, found := j.next(kind, input)
+	if found && entry.Status == StepCompleted {
+		var result T
+		if err := json.Unmarshal(entry.Result, &result); err != nil {
+			return zero, fmt.Errorf("failed to decode journaled step %s: %w", key, err)
+		}
+		j.mu.Lock()
+		j.replayed++
+		j.mu.Unlock()
+		publish(ctx, "durable", PhaseStepReplayed, map[string]interface{}{"step": kind, "key": key})
+		return result, nil
+	}
+
+	entry.Key = key
+	entry.Kind = kind
+	entry.Status = StepStarted
+	entry.Attempts++
+	entry.Started = time.Now().UTC()
+	entry.Error = ""
+	if err := j.save(ctx, entry); err != nil {
+		return zero, err
+	}
+	j.mu.Lock()
+	j.executed++
+	j.mu.Unlock()
+
+	result, err := fn(ctx)
+	entry.Finished = time.Now().UTC()
+	if err != nil {
+		entry.Status = StepFailed
+		entry.Error = err.Error()
+		if saveErr := j.save(ctx, entry); saveErr != nil {
+			return zero, fmt.Errorf("%w (also failed to journal it: %v)", err, saveErr)
+		}
+		return zero, err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return zero, fmt.Errorf("failed to encode step result: %w", err)
+	}
+	entry.Status = StepCompleted
+	entry.Result = data
+	if err := j.save(ctx, entry); err != nil {
+		return zero, err
+	}
+	return result, nil
+}
+
+// sanitizeKey makes s safe for use in store keys on every backend
+func sanitizeKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
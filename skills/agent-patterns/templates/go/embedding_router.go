@@ -0,0 +1,209 @@
+/*
+ * Embedding Router for Go Agent Patterns
+ * Classifying inputs by similarity to example embeddings instead of an LLM call
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/vectorstore"
+)
+
+// EmbeddingRoute defines a route by example inputs instead of a description
+type EmbeddingRoute[T any] struct {
+	Category string
+	// Examples are typical inputs for the category; a few varied ones work
+	// better than many similar ones
+	Examples []string
+	Handler  func(ctx context.Context, input string) (T, error)
+}
+
+// EmbeddingRouter classifies inputs by cosine similarity to the embedded
+// examples of each route. A classification costs one embedding request
+// rather than an LLM call, so routing takes milliseconds. The confidence
+// is the similarity of the closest example.
+//
+// Example embeddings are computed on first use, or up front with Index.
+// Similarity scores depend on the embedding model, so tune the confidence
+// threshold against real inputs. A Router can serve as the fallback for
+// inputs that match no route closely enough.
+//
+// Example:
+//
+//	router := NewEmbeddingRouter[string](NewVoyageEmbedder(os.Getenv("VOYAGE_API_KEY")))
+//	router.AddRoute(EmbeddingRoute[string]{
+//	    Category: "billing",
+//	    Examples: []string{"I was charged twice", "How do I update my card?"},
+//	    Handler:  handleBilling,
+//	})
+//	router.SetFallback(func(ctx context.Context, input string) (string, error) {
+//	    result, _, err := llmRouter.Route(ctx, input, 0.7)
+//	    return result, err
+//	})
+//	result, classification, err := router.Route(ctx, "My card was charged twice", 0.6)
+type EmbeddingRouter[T any] struct {
+	embedder Embedder
+	cfg      patternConfig
+	routes   map[string]EmbeddingRoute[T]
+	fallback func(ctx context.Context, input string) (T, error)
+
+	mu      sync.Mutex
+	vectors map[string][][]float32
+}
+
+// NewEmbeddingRouter creates a new EmbeddingRouter
+func NewEmbeddingRouter[T any](embedder Embedder, opts ...Option) *EmbeddingRouter[T] {
+	return &EmbeddingRouter[T]{
+		embedder: embedder,
+		cfg:      newPatternConfig("embedding_router", opts),
+		routes:   make(map[string]EmbeddingRoute[T]),
+	}
+}
+
+// AddRoute adds a route with its handler
+func (r *EmbeddingRouter[T]) AddRoute(route EmbeddingRoute[T]) *EmbeddingRouter[T] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[route.Category] = route
+	r.vectors = nil
+	return r
+}
+
+// SetFallback sets the handler for inputs below the confidence threshold
+func (r *EmbeddingRouter[T]) SetFallback(handler func(ctx context.Context, input string) (T, error)) *EmbeddingRouter[T] {
+	r.fallback = handler
+	return r
+}
+
+// Index embeds the examples of every route. Classify calls it when the
+// routes have changed since the last call.
+func (r *EmbeddingRouter[T]) Index(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.index(ctx)
+}
+
+func (r *EmbeddingRouter[T]) index(ctx context.Context) error {
+	var texts []string
+	var owners []string
+	for _, category := range sortedKeys(r.routes) {
+		for _, example := range r.routes[category].Examples {
+			texts = append(texts, example)
+			owners = append(owners, category)
+		}
+	}
+	if len(texts) == 0 {
+		return fmt.Errorf("no route examples to index")
+	}
+
+	embedded, err := r.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed route examples: %w", err)
+	}
+	if len(embedded) != len(texts) {
+		return fmt.Errorf("embedder returned %d vectors for %d examples", len(embedded), len(texts))
+	}
+	vectors := make(map[string][][]float32, len(r.routes))
+	for i, category := range owners {
+		vectors[category] = append(vectors[category], embedded[i])
+	}
+	r.vectors = vectors
+	r.cfg.logger.Debug("indexed route examples", "routes", len(vectors), "examples", len(texts))
+	return nil
+}
+
+// Route classifies input and routes to the appropriate handler
+func (r *EmbeddingRouter[T]) Route(ctx context.Context, input string, confidenceThreshold float64) (T, *ClassificationResult, error) {
+	var zero T
+
+	ctx, cancel := r.cfg.startRun(ctx)
+	defer cancel()
+
+	classification, err := r.Classify(ctx, input)
+	if err != nil {
+		return zero, nil, fmt.Errorf("classification failed: %w", err)
+	}
+	r.cfg.publish(ctx, PhaseClassified, map[string]interface{}{
+		"category":   classification.Category,
+		"confidence": classification.Confidence,
+	})
+
+	if classification.Confidence < confidenceThreshold {
+		if r.fallback != nil {
+			result, err := r.fallback(ctx, input)
+			return result, classification, err
+		}
+		return zero, classification, fmt.Errorf("low confidence (%.2f) and no fallback handler set", classification.Confidence)
+	}
+
+	r.mu.Lock()
+	route := r.routes[classification.Category]
+	r.mu.Unlock()
+	if route.Handler == nil {
+		if r.fallback != nil {
+			result, err := r.fallback(ctx, input)
+			return result, classification, err
+		}
+		return zero, classification, fmt.Errorf("no handler for category: %s", classification.Category)
+	}
+
+	handlerCtx, span := StartSpan(ctx, "router.handler")
+	span.SetAttribute("category", classification.Category)
+	result, err := route.Handler(handlerCtx, input)
+	span.Finish(err)
+	return result, classification, err
+}
+
+// Classify returns the category whose closest example is most similar to
+// input
+func (r *EmbeddingRouter[T]) Classify(ctx context.Context, input string) (*ClassificationResult, error) {
+	ctx, span := StartSpan(ctx, "embedding_router.classify")
+	r.mu.Lock()
+	if r.vectors == nil {
+		if err := r.index(ctx); err != nil {
+			r.mu.Unlock()
+			span.Finish(err)
+			return nil, err
+		}
+	}
+	vectors := r.vectors
+	r.mu.Unlock()
+
+	query, err := r.embedder.EmbedQuery(ctx, input)
+	if err != nil {
+		span.Finish(err)
+		return nil, fmt.Errorf("failed to embed input: %w", err)
+	}
+
+	result := &ClassificationResult{Confidence: -1}
+	runnerUp := -1.0
+	for _, category := range sortedKeys(vectors) {
+		best := -1.0
+		for _, v := range vectors[category] {
+			if sim := vectorstore.Cosine(query, v); sim > best {
+				best = sim
+			}
+		}
+		if best > result.Confidence {
+			runnerUp = result.Confidence
+			result.Category = category
+			result.Confidence = best
+		} else if best > runnerUp {
+			runnerUp = best
+		}
+	}
+	if result.Confidence < 0 {
+		result.Confidence = 0
+	}
+	result.Reasoning = fmt.Sprintf("closest example similarity %.3f", result.Confidence)
+	if runnerUp >= 0 {
+		result.Reasoning += fmt.Sprintf(", next category %.3f", runnerUp)
+	}
+	span.SetAttribute("category", result.Category)
+	span.Finish(nil)
+	return result, nil
+}
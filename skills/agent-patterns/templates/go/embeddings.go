@@ -0,0 +1,146 @@
+/*
+ * Embeddings Client for Go Agent Patterns
+ * Text embeddings for retrieval, backed by the Voyage AI API
+ */
+
+package agentpatterns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultEmbeddingModel is the embedding model used when none is configured
+const DefaultEmbeddingModel = "voyage-3"
+
+// Embedder turns text into vectors. Documents and queries are embedded
+// separately because retrieval models encode them differently.
+type Embedder interface {
+	EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error)
+	EmbedQuery(ctx context.Context, text string) ([]float32, error)
+}
+
+// VoyageEmbedder calls the Voyage AI embeddings API, which Anthropic
+// recommends for use with Claude
+type VoyageEmbedder struct {
+	APIKey     string
+	Model      string
+	BaseURL    string
+	HTTPClient *http.Client
+	// BatchSize caps the number of texts sent per request
+	BatchSize int
+}
+
+// NewVoyageEmbedder creates an embedder using DefaultEmbeddingModel
+func NewVoyageEmbedder(apiKey string) *VoyageEmbedder {
+	return &VoyageEmbedder{
+		APIKey:     apiKey,
+		Model:      DefaultEmbeddingModel,
+		HTTPClient: &http.Client{},
+		BatchSize:  128,
+	}
+}
+
+type embeddingRequest struct {
+	Input     []string `json:"input"`
+	Model     string   `json:"model"`
+	InputType string   `json:"input_type"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// EmbedDocuments embeds texts for storage, in batches
+func (e *VoyageEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	batch := e.BatchSize
+	if batch <= 0 {
+		batch = len(texts)
+	}
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batch {
+		end := start + batch
+		if end > len(texts) {
+			end = len(texts)
+		}
+		embedded, err := e.embed(ctx, texts[start:end], "document")
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, embedded...)
+	}
+	return vectors, nil
+}
+
+// EmbedQuery embeds a search query
+func (e *VoyageEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := e.embed(ctx, []string{text}, "query")
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (e *VoyageEmbedder) embed(ctx context.Context, texts []string, inputType string) ([][]float32, error) {
+	jsonData, err := json.Marshal(embeddingRequest{Input: texts, Model: e.Model, InputType: inputType})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.embeddingsURL(), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := e.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("embeddings API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var embResp embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(embResp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embResp.Data))
+	}
+
+	recordUsage(ctx, "embeddings", e.Model, Usage{InputTokens: embResp.Usage.TotalTokens})
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range embResp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// embeddingsURL returns the embeddings endpoint for this embedder
+func (e *VoyageEmbedder) embeddingsURL() string {
+	base := e.BaseURL
+	if base == "" {
+		base = "https://api.voyageai.com"
+	}
+	return strings.TrimRight(base, "/") + "/v1/embeddings"
+}
@@ -0,0 +1,76 @@
+/*
+ * Confidence Calibration for the Evaluation Harness
+ * Recording reported confidence against judged correctness
+ */
+
+package evals
+
+import (
+	"context"
+	"sync"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+)
+
+// reportedConfidence holds the confidence a candidate reported for a case
+type reportedConfidence struct {
+	mu    sync.Mutex
+	value *float64
+}
+
+type reportedConfidenceKey struct{}
+
+func (r *reportedConfidence) get() *float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.value
+}
+
+// RecordConfidence reports the confidence of the output a candidate is
+// about to return, for calibration. Outside a harness run it does nothing.
+func RecordConfidence(ctx context.Context, confidence float64) {
+	if r, ok := ctx.Value(reportedConfidenceKey{}).(*reportedConfidence); ok {
+		r.mu.Lock()
+		r.value = &confidence
+		r.mu.Unlock()
+	}
+}
+
+// FromConfidenceOptimizer generates with a ConfidenceBasedOptimizer and
+// records the confidence the model reported for the output returned, before
+// any calibration
+func FromConfidenceOptimizer(name string, optimizer *agentpatterns.ConfidenceBasedOptimizer, threshold float64, maxAttempts int) Candidate {
+	return Candidate{Name: name, Run: func(ctx context.Context, input string) (string, error) {
+		result, err := optimizer.GenerateWithConfidence(ctx, input, threshold, maxAttempts)
+		if err != nil {
+			return "", err
+		}
+		RecordConfidence(ctx, result.RawConfidence)
+		return result.Output, nil
+	}}
+}
+
+// CalibrationSamples pairs each result's reported confidence with whether
+// its score reached passScore. Results that failed or reported no
+// confidence are skipped.
+//
+// Example:
+//
+//	report, err := evals.NewHarness(evals.ExactMatch()).
+//	    AddCandidate(evals.FromConfidenceOptimizer("confidence", optimizer, 1, 1)).
+//	    Run(ctx, dataset)
+//	cal, err := agentpatterns.FitCalibration(evals.CalibrationSamples(report, 1), agentpatterns.CalibrationIsotonic)
+//	optimizer = agentpatterns.NewConfidenceBasedOptimizer(client, agentpatterns.WithCalibration(cal))
+func CalibrationSamples(report *Report, passScore float64) []agentpatterns.CalibrationSample {
+	var samples []agentpatterns.CalibrationSample
+	for _, r := range report.Results {
+		if r.Error != "" || r.Confidence == nil {
+			continue
+		}
+		samples = append(samples, agentpatterns.CalibrationSample{
+			Confidence: *r.Confidence,
+			Correct:    r.Score >= passScore,
+		})
+	}
+	return samples
+}
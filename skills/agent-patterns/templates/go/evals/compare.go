@@ -0,0 +1,323 @@
+/*
+ * Pairwise Comparison for the Evaluation Harness
+ * Blinded A/B judging of prompt variants, with swapped orders against position bias
+ */
+
+package evals
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/jsonx"
+)
+
+// Prompt is a prompt variant under comparison
+type Prompt struct {
+	Name string
+	// Template is a text/template rendered with the task as {{.input}};
+	// empty sends the task as it is
+	Template string
+	// System is the system prompt, if any
+	System string
+}
+
+// VariantResult is one variant's output and record against the others.
+// Each judgment counts once, after both orders have been judged.
+type VariantResult struct {
+	Name   string `json:"name"`
+	Output string `json:"output"`
+	Wins   int    `json:"wins"`
+	Losses int    `json:"losses"`
+	Ties   int    `json:"ties"`
+	// WinRate is the share of judgments won, counting ties as half
+	WinRate float64 `json:"win_rate"`
+}
+
+// PairResult is the record of one pair of variants
+type PairResult struct {
+	A     string `json:"a"`
+	B     string `json:"b"`
+	AWins int    `json:"a_wins"`
+	BWins int    `json:"b_wins"`
+	Ties  int    `json:"ties"`
+	// PositionFlips counts judgments whose verdict changed with the order
+	// the responses were shown in, which are scored as ties
+	PositionFlips int `json:"position_flips"`
+}
+
+// Comparison is the outcome of CompareVariants, with variants ranked by
+// win rate
+type Comparison struct {
+	Task     string          `json:"task"`
+	Variants []VariantResult `json:"variants"`
+	Pairs    []PairResult    `json:"pairs"`
+}
+
+// Comparer judges prompt variants against each other pairwise
+type Comparer struct {
+	client      *agentpatterns.AnthropicClient
+	model       string
+	judgeModel  string
+	rubric      string
+	maxTokens   int
+	concurrency int
+}
+
+// NewComparer creates a comparer generating and judging with the default
+// model, four judge calls at a time
+func NewComparer(client *agentpatterns.AnthropicClient) *Comparer {
+	return &Comparer{
+		client:      client,
+		model:       agentpatterns.DefaultModel,
+		judgeModel:  agentpatterns.DefaultModel,
+		rubric:      "Which response completes the task better: more accurate, more helpful, and clearer?",
+		maxTokens:   2048,
+		concurrency: 4,
+	}
+}
+
+// SetModel sets the model generating the variants' outputs
+func (c *Comparer) SetModel(model string) *Comparer {
+	c.model = model
+	return c
+}
+
+// SetJudgeModel sets the model judging the outputs
+func (c *Comparer) SetJudgeModel(model string) *Comparer {
+	c.judgeModel = model
+	return c
+}
+
+// SetRubric sets the question the judge answers about each pair
+func (c *Comparer) SetRubric(rubric string) *Comparer {
+	c.rubric = rubric
+	return c
+}
+
+// SetConcurrency sets how many judge calls run at once (default 4)
+func (c *Comparer) SetConcurrency(n int) *Comparer {
+	if n > 0 {
+		c.concurrency = n
+	}
+	return c
+}
+
+// CompareVariants generates an output for task with each variant, then
+// judges every pair of outputs judges times. The judge sees the outputs
+// as Response A and Response B, never the variants' names, and each
+// judgment is made in both orders: a verdict that follows the position
+// rather than the response is scored as a tie. With more than one judge,
+// judge calls are sampled at temperature 1 so that they differ.
+func (c *Comparer) CompareVariants(ctx context.Context, task string, variants []Prompt, judges int) (*Comparison, error) {
+	if len(variants) < 2 {
+		return nil, fmt.Errorf("at least two variants are needed, got %d", len(variants))
+	}
+	if judges < 1 {
+		judges = 1
+	}
+
+	outputs := make([]string, len(variants))
+	for i, v := range variants {
+		output, err := c.generate(ctx, task, v)
+		if err != nil {
+			return nil, fmt.Errorf("variant '%s' failed: %w", v.Name, err)
+		}
+		outputs[i] = output
+	}
+
+	// Each judgment of a pair is two calls, one per order
+	type call struct {
+		pair, judgment int
+		swapped        bool
+	}
+	var pairs [][2]int
+	for i := range variants {
+		for j := i + 1; j < len(variants); j++ {
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+	var calls []call
+	for p := range pairs {
+		for k := 0; k < judges; k++ {
+			calls = append(calls, call{p, k, false}, call{p, k, true})
+		}
+	}
+
+	// verdicts[pair][judgment][order] is the index of the winning variant,
+	// or -1 for a tie
+	verdicts := make([][][2]int, len(pairs))
+	for p := range verdicts {
+		verdicts[p] = make([][2]int, judges)
+	}
+	sem := make(chan struct{}, c.concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for _, cl := range calls {
+		wg.Add(1)
+		go func(cl call) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			a, b := pairs[cl.pair][0], pairs[cl.pair][1]
+			if cl.swapped {
+				a, b = b, a
+			}
+			winner, err := c.judge(ctx, task, outputs[a], outputs[b], judges > 1)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			index := -1
+			switch winner {
+			case "A":
+				index = a
+			case "B":
+				index = b
+			}
+			order := 0
+			if cl.swapped {
+				order = 1
+			}
+			verdicts[cl.pair][cl.judgment][order] = index
+		}(cl)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	comparison := &Comparison{Task: task}
+	results := make([]VariantResult, len(variants))
+	for i, v := range variants {
+		results[i] = VariantResult{Name: v.Name, Output: outputs[i]}
+	}
+	for p, pair := range pairs {
+		a, b := pair[0], pair[1]
+		pr := PairResult{A: variants[a].Name, B: variants[b].Name}
+		for _, v := range verdicts[p] {
+			switch {
+			case v[0] == v[1] && v[0] == a:
+				pr.AWins++
+				results[a].Wins++
+				results[b].Losses++
+			case v[0] == v[1] && v[0] == b:
+				pr.BWins++
+				results[b].Wins++
+				results[a].Losses++
+			default:
+				if v[0] != -1 && v[1] != -1 {
+					pr.PositionFlips++
+				}
+				pr.Ties++
+				results[a].Ties++
+				results[b].Ties++
+			}
+		}
+		comparison.Pairs = append(comparison.Pairs, pr)
+	}
+	for i := range results {
+		r := &results[i]
+		if n := r.Wins + r.Losses + r.Ties; n > 0 {
+			r.WinRate = (float64(r.Wins) + float64(r.Ties)/2) / float64(n)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].WinRate > results[j].WinRate })
+	comparison.Variants = results
+	return comparison, nil
+}
+
+// generate produces a variant's output for task
+func (c *Comparer) generate(ctx context.Context, task string, v Prompt) (string, error) {
+	prompt := task
+	if v.Template != "" {
+		tmpl, err := agentpatterns.ParsePromptTemplate(v.Name, v.Template)
+		if err != nil {
+			return "", err
+		}
+		if prompt, err = tmpl.Render(map[string]interface{}{"input": task}); err != nil {
+			return "", err
+		}
+	}
+	output, _, err := c.client.Send(ctx, agentpatterns.MessageRequest{
+		Model:     c.model,
+		MaxTokens: c.maxTokens,
+		System:    v.System,
+		Messages:  []agentpatterns.MessageItem{{Role: "user", Content: prompt}},
+	})
+	return output, err
+}
+
+// judge asks which of two responses is better and returns "A", "B", or
+// "tie"
+func (c *Comparer) judge(ctx context.Context, task, a, b string, sample bool) (string, error) {
+	prompt := fmt.Sprintf(`Compare two responses to the task below.
+
+Question: %s
+
+Task:
+%s
+
+Response A:
+%s
+
+Response B:
+%s
+
+Judge the content, not the order the responses are shown in or their length. Respond with JSON in this exact format:
+{"reasoning": "brief justification", "winner": "A", "B", or "tie"}`, c.rubric, task, a, b)
+
+	req := agentpatterns.MessageRequest{
+		Model:     c.judgeModel,
+		MaxTokens: 512,
+		Messages:  []agentpatterns.MessageItem{{Role: "user", Content: prompt}},
+	}
+	if sample {
+		temperature := 1.0
+		req.Temperature = &temperature
+	}
+	response, _, err := c.client.Send(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	var verdict struct {
+		Reasoning string `json:"reasoning"`
+		Winner    string `json:"winner"`
+	}
+	if err := jsonx.Unmarshal(response, &verdict); err != nil {
+		return "", fmt.Errorf("failed to parse judge response: %w", err)
+	}
+	switch winner := strings.ToUpper(strings.TrimSpace(verdict.Winner)); winner {
+	case "A", "B":
+		return winner, nil
+	case "TIE":
+		return "tie", nil
+	default:
+		return "", fmt.Errorf("judge named no winner: %q", verdict.Winner)
+	}
+}
+
+// WriteTable writes the variants' records as an aligned text table
+func (c *Comparison) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VARIANT\tWIN RATE\tWINS\tLOSSES\tTIES")
+	for _, v := range c.Variants {
+		fmt.Fprintf(tw, "%s\t%.3f\t%d\t%d\t%d\n", v.Name, v.WinRate, v.Wins, v.Losses, v.Ties)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,265 @@
+/*
+ * Evaluation Harness for Go Agent Patterns
+ * Runs a dataset through competing patterns and compares quality, cost, and latency
+ */
+
+// Package evals compares pattern configurations on a dataset of tasks.
+//
+// Each Candidate wraps a pattern (a single call, a chain, an orchestrator,
+// an agent, or a pipeline). The Harness runs every case through every
+// candidate, scores the outputs with the configured judges, and measures the
+// cost and latency of each run. A Comparer ranks prompt variants by
+// pairwise judging instead, and a PromptOptimizer uses the harness to
+// improve a prompt template.
+//
+// Example:
+//
+//	dataset, err := evals.LoadDataset("support_tickets.jsonl")
+//	harness := evals.NewHarness(evals.Contains(), evals.LLMJudge(client, "Is the reply accurate and polite?")).
+//	    AddCandidate(evals.Single("single", client)).
+//	    AddCandidate(evals.FromChain("chain", chain)).
+//	    AddCandidate(evals.FromOrchestrator("orchestrator", orch))
+//	report, err := harness.Run(ctx, dataset)
+//	report.WriteTable(os.Stdout)
+package evals
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+)
+
+// Case is one task of a dataset
+type Case struct {
+	ID    string `json:"id"`
+	Input string `json:"input"`
+	// Expected is the reference answer used by judges such as ExactMatch
+	Expected string            `json:"expected,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+// LoadDataset reads cases from a JSON Lines file, one Case per line. Cases
+// without an ID are numbered by line.
+func LoadDataset(path string) ([]Case, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset: %w", err)
+	}
+	defer f.Close()
+
+	var cases []Case
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var c Case
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("dataset line %d: %w", line, err)
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("case-%d", line)
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+	return cases, nil
+}
+
+// RunFunc produces an output for one input
+type RunFunc func(ctx context.Context, input string) (string, error)
+
+// Candidate is a named pattern configuration under evaluation
+type Candidate struct {
+	Name string
+	Run  RunFunc
+}
+
+// Single sends the input as-is in a single LLM call
+func Single(name string, client *agentpatterns.AnthropicClient, opts ...agentpatterns.Option) Candidate {
+	chain := agentpatterns.NewPromptChain(client, opts...).AddStep(agentpatterns.ChainStep{
+		Name: "answer",
+		PromptTemplate: func(context map[string]interface{}) string {
+			return fmt.Sprint(context["input"])
+		},
+	})
+	return FromChain(name, chain)
+}
+
+// FromChain runs a PromptChain with the input under the "input" key
+func FromChain(name string, chain *agentpatterns.PromptChain) Candidate {
+	return Candidate{Name: name, Run: func(ctx context.Context, input string) (string, error) {
+		return chain.Execute(ctx, map[string]interface{}{"input": input})
+	}}
+}
+
+// FromOrchestrator treats the input as the task to decompose
+func FromOrchestrator(name string, orchestrator *agentpatterns.Orchestrator) Candidate {
+	return Candidate{Name: name, Run: func(ctx context.Context, input string) (string, error) {
+		result, err := orchestrator.Execute(ctx, input)
+		if err != nil {
+			return "", err
+		}
+		return result.FinalResult, nil
+	}}
+}
+
+// FromAgent gives the input to an agent as its task
+func FromAgent(name string, agent *agentpatterns.AutonomousAgent, maxSteps int) Candidate {
+	return Candidate{Name: name, Run: func(ctx context.Context, input string) (string, error) {
+		result, err := agent.Run(ctx, input, maxSteps)
+		if err != nil {
+			return "", err
+		}
+		if !result.Success {
+			return result.FinalResult, fmt.Errorf("agent did not complete within %d steps", maxSteps)
+		}
+		return result.FinalResult, nil
+	}}
+}
+
+// FromPipeline runs a Pipeline on the input
+func FromPipeline(name string, pipeline *agentpatterns.Pipeline) Candidate {
+	return Candidate{Name: name, Run: func(ctx context.Context, input string) (string, error) {
+		result, err := pipeline.Execute(ctx, input)
+		if err != nil {
+			return "", err
+		}
+		return result.Output, nil
+	}}
+}
+
+// Result is the outcome of one candidate on one case
+type Result struct {
+	Candidate string             `json:"candidate"`
+	CaseID    string             `json:"case_id"`
+	Output    string             `json:"output"`
+	Error     string             `json:"error,omitempty"`
+	Scores    map[string]float64 `json:"scores"`
+	// Score is the mean of the judge scores (0 when the run failed)
+	Score   float64                   `json:"score"`
+	Latency time.Duration             `json:"latency"`
+	Cost    agentpatterns.CostSummary `json:"cost"`
+	// Confidence is the confidence the candidate reported for its output
+	// with RecordConfidence, if any
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// Harness runs candidates over a dataset and scores them
+type Harness struct {
+	candidates  []Candidate
+	judges      []Judge
+	pricing     map[string]agentpatterns.ModelPricing
+	concurrency int
+}
+
+// NewHarness creates a harness that scores outputs with judges
+func NewHarness(judges ...Judge) *Harness {
+	return &Harness{
+		judges:      judges,
+		pricing:     agentpatterns.DefaultPricing(),
+		concurrency: 4,
+	}
+}
+
+// AddCandidate adds a pattern configuration to compare (builder pattern)
+func (h *Harness) AddCandidate(c Candidate) *Harness {
+	h.candidates = append(h.candidates, c)
+	return h
+}
+
+// SetConcurrency sets how many cases run at once per candidate (default 4)
+func (h *Harness) SetConcurrency(n int) *Harness {
+	if n > 0 {
+		h.concurrency = n
+	}
+	return h
+}
+
+// SetPricing sets the model prices used to compute costs
+func (h *Harness) SetPricing(pricing map[string]agentpatterns.ModelPricing) *Harness {
+	h.pricing = pricing
+	return h
+}
+
+// Run evaluates every candidate on every case. Failed runs are recorded in
+// the report rather than aborting the evaluation; Run only returns an error
+// when ctx is cancelled.
+func (h *Harness) Run(ctx context.Context, dataset []Case) (*Report, error) {
+	if len(h.candidates) == 0 {
+		return nil, fmt.Errorf("no candidates to evaluate")
+	}
+
+	report := &Report{}
+	for _, candidate := range h.candidates {
+		results := make([]Result, len(dataset))
+		sem := make(chan struct{}, h.concurrency)
+		var wg sync.WaitGroup
+		for i := range dataset {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				results[i] = h.runCase(ctx, candidate, dataset[i])
+			}(i)
+		}
+		wg.Wait()
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		report.Results = append(report.Results, results...)
+		report.Summaries = append(report.Summaries, summarize(candidate.Name, results))
+	}
+	return report, nil
+}
+
+// runCase runs one case under its own run ID and cost tracker, so the cost
+// reflects only this candidate's calls, then scores the output
+func (h *Harness) runCase(ctx context.Context, candidate Candidate, c Case) Result {
+	result := Result{Candidate: candidate.Name, CaseID: c.ID, Scores: make(map[string]float64)}
+
+	costs := agentpatterns.NewCostTracker(h.pricing)
+	runCtx := agentpatterns.WithRunID(agentpatterns.ContextWithCostTracker(ctx, costs),
+		fmt.Sprintf("eval_%s_%s", candidate.Name, c.ID))
+	confidence := &reportedConfidence{}
+	runCtx = context.WithValue(runCtx, reportedConfidenceKey{}, confidence)
+
+	start := time.Now()
+	output, err := candidate.Run(runCtx, c.Input)
+	result.Latency = time.Since(start)
+	result.Cost = costs.Total()
+	result.Output = output
+	result.Confidence = confidence.get()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var total float64
+	for _, judge := range h.judges {
+		score, err := judge.Score(ctx, c, output)
+		if err != nil {
+			result.Error = fmt.Sprintf("judge '%s' failed: %v", judge.Name(), err)
+			return result
+		}
+		result.Scores[judge.Name()] = score
+		total += score
+	}
+	if len(h.judges) > 0 {
+		result.Score = total / float64(len(h.judges))
+	}
+	return result
+}
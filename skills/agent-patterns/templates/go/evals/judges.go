@@ -0,0 +1,194 @@
+/*
+ * Judges for the Evaluation Harness
+ * Exact-match, containment, custom, and LLM-as-judge scoring
+ */
+
+package evals
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/jsonx"
+)
+
+// Judge scores an output between 0.0 and 1.0
+type Judge interface {
+	Name() string
+	Score(ctx context.Context, c Case, output string) (float64, error)
+}
+
+// funcJudge adapts a function to Judge
+type funcJudge struct {
+	name string
+	fn   func(ctx context.Context, c Case, output string) (float64, error)
+}
+
+func (j funcJudge) Name() string { return j.name }
+
+func (j funcJudge) Score(ctx context.Context, c Case, output string) (float64, error) {
+	return j.fn(ctx, c, output)
+}
+
+// JudgeFunc creates a judge from a function, e.g. to parse and check
+// structured output
+func JudgeFunc(name string, fn func(ctx context.Context, c Case, output string) (float64, error)) Judge {
+	return funcJudge{name: name, fn: fn}
+}
+
+// ExactMatch scores 1.0 when the output equals the expected answer,
+// ignoring case and surrounding whitespace
+func ExactMatch() Judge {
+	return JudgeFunc("exact_match", func(ctx context.Context, c Case, output string) (float64, error) {
+		if strings.EqualFold(strings.TrimSpace(output), strings.TrimSpace(c.Expected)) {
+			return 1, nil
+		}
+		return 0, nil
+	})
+}
+
+// Contains scores 1.0 when the output contains the expected answer,
+// ignoring case
+func Contains() Judge {
+	return JudgeFunc("contains", func(ctx context.Context, c Case, output string) (float64, error) {
+		if strings.Contains(strings.ToLower(output), strings.ToLower(strings.TrimSpace(c.Expected))) {
+			return 1, nil
+		}
+		return 0, nil
+	})
+}
+
+// llmJudge asks a model to grade the output against a rubric
+type llmJudge struct {
+	client *agentpatterns.AnthropicClient
+	rubric string
+	model  string
+}
+
+// LLMJudge grades outputs with a model against rubric. The expected answer,
+// when the case has one, is included as a reference. Judge calls are not
+// counted in the candidate's cost.
+func LLMJudge(client *agentpatterns.AnthropicClient, rubric string) Judge {
+	return &llmJudge{client: client, rubric: rubric, model: agentpatterns.DefaultModel}
+}
+
+// LLMJudgeWithModel is LLMJudge using a specific model
+func LLMJudgeWithModel(client *agentpatterns.AnthropicClient, rubric, model string) Judge {
+	return &llmJudge{client: client, rubric: rubric, model: model}
+}
+
+func (j *llmJudge) Name() string { return "llm_judge" }
+
+func (j *llmJudge) Score(ctx context.Context, c Case, output string) (float64, error) {
+	reference := ""
+	if c.Expected != "" {
+		reference = fmt.Sprintf("\nReference answer:\n%s\n", c.Expected)
+	}
+	prompt := fmt.Sprintf(`Grade the response to the task below.
+
+Rubric: %s
+
+Task:
+%s
+%s
+Response:
+%s
+
+Respond with JSON in this exact format:
+{"score": 0.0-1.0, "reasoning": "brief justification"}`, j.rubric, c.Input, reference, output)
+
+	response, err := j.client.CreateMessage(ctx, prompt, j.model, 512)
+	if err != nil {
+		return 0, err
+	}
+
+	var grade struct {
+		Score     float64 `json:"score"`
+		Reasoning string  `json:"reasoning"`
+	}
+	if err := jsonx.Unmarshal(response, &grade); err != nil {
+		return 0, fmt.Errorf("failed to parse judge response: %w", err)
+	}
+	if grade.Score < 0 || grade.Score > 1 {
+		return 0, fmt.Errorf("judge score %.2f out of range", grade.Score)
+	}
+	return grade.Score, nil
+}
+
+// rubricJudge scores each criterion of a rubric and weights the scores
+type rubricJudge struct {
+	client *agentpatterns.AnthropicClient
+	rubric *agentpatterns.Rubric
+	model  string
+}
+
+// RubricJudge grades outputs with a model on each criterion of rubric,
+// guided by its anchors, and returns the weighted mean of the criterion
+// scores. It is named after the rubric, or "rubric" if it has no name.
+//
+// Example:
+//
+//	rubric, err := agentpatterns.LoadRubric("rubrics/support_reply.md")
+//	harness := evals.NewHarness(evals.RubricJudge(client, rubric))
+func RubricJudge(client *agentpatterns.AnthropicClient, rubric *agentpatterns.Rubric) Judge {
+	return &rubricJudge{client: client, rubric: rubric, model: agentpatterns.DefaultModel}
+}
+
+func (j *rubricJudge) Name() string {
+	if j.rubric.Name != "" {
+		return j.rubric.Name
+	}
+	return "rubric"
+}
+
+func (j *rubricJudge) Score(ctx context.Context, c Case, output string) (float64, error) {
+	reference := ""
+	if c.Expected != "" {
+		reference = fmt.Sprintf("\nReference answer:\n%s\n", c.Expected)
+	}
+	prompt := fmt.Sprintf(`Grade the response to the task below on each criterion, from 0.0 to 1.0, using the anchors as a guide.
+
+Criteria:
+%s
+
+Task:
+%s
+%s
+Response:
+%s
+
+Respond with JSON in this exact format, with a score for every criterion by name:
+{"scores": {"criterion": 0.0-1.0}, "reasoning": "brief justification"}`, j.rubric, c.Input, reference, output)
+
+	response, err := j.client.CreateMessage(ctx, prompt, j.model, 1024)
+	if err != nil {
+		return 0, err
+	}
+
+	var grade struct {
+		Scores    map[string]float64 `json:"scores"`
+		Reasoning string             `json:"reasoning"`
+	}
+	if err := jsonx.Unmarshal(response, &grade); err != nil {
+		return 0, fmt.Errorf("failed to parse judge response: %w", err)
+	}
+	var total, weights float64
+	for _, criterion := range j.rubric.Criteria {
+		score, ok := grade.Scores[criterion.Name]
+		if !ok {
+			return 0, fmt.Errorf("judge did not score %s", criterion.Name)
+		}
+		if score < 0 || score > 1 {
+			return 0, fmt.Errorf("judge score %.2f for %s out of range", score, criterion.Name)
+		}
+		weight := criterion.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		total += score * weight
+		weights += weight
+	}
+	return total / weights, nil
+}
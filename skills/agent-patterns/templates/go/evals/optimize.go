@@ -0,0 +1,323 @@
+/*
+ * Prompt Optimization for the Evaluation Harness
+ * Mutating a prompt template with an optimizer model, scored by the harness
+ */
+
+package evals
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/jsonx"
+)
+
+// Reasons a prompt optimization stopped
+const (
+	StopIterations = "iterations"
+	StopBudget     = "budget"
+	StopPerfect    = "perfect"
+)
+
+// PromptCandidate is a prompt template the optimizer evaluated
+type PromptCandidate struct {
+	Iteration int    `json:"iteration"`
+	Template  string `json:"template"`
+	// Rationale is the optimizer model's reason for the change; empty for
+	// the starting template
+	Rationale string  `json:"rationale,omitempty"`
+	Score     float64 `json:"score"`
+	// Cost is what running the template over the dataset cost, in US
+	// dollars
+	Cost  float64 `json:"cost"`
+	Error string  `json:"error,omitempty"`
+}
+
+// PromptOptimization is the outcome of PromptOptimizer.Optimize
+type PromptOptimization struct {
+	Best PromptCandidate `json:"best"`
+	// BestHistory is the best template after each iteration, starting with
+	// the template given
+	BestHistory []PromptCandidate `json:"best_history"`
+	// Candidates lists every template evaluated, in order
+	Candidates []PromptCandidate `json:"candidates"`
+	// Cost is the total spent on running templates and on the optimizer
+	// model, in US dollars. Judge calls are not included.
+	Cost       float64 `json:"cost"`
+	StopReason string  `json:"stop_reason"`
+}
+
+// PromptOptimizer improves a prompt template over a dataset. Each
+// iteration, an optimizer model is shown the best template so far, its
+// weakest cases, and the templates already tried, and proposes variations;
+// the harness scores each over the dataset with the judges, and the best
+// one is kept.
+//
+// Example:
+//
+//	optimizer := evals.NewPromptOptimizer(client, evals.LLMJudge(client, "Is the reply accurate and polite?")).
+//	    SetMaxIterations(5).
+//	    SetMaxCost(2.00)
+//	result, err := optimizer.Optimize(ctx, "Answer this support ticket: {{.input}}", dataset)
+//	fmt.Println(result.Best.Template, result.Best.Score)
+type PromptOptimizer struct {
+	client         *agentpatterns.AnthropicClient
+	opts           []agentpatterns.Option
+	judges         []Judge
+	optimizerModel string
+	maxIterations  int
+	mutations      int
+	maxCost        float64
+	pricing        map[string]agentpatterns.ModelPricing
+	concurrency    int
+}
+
+// NewPromptOptimizer creates an optimizer that scores templates with
+// judges, running them as single calls configured by opts. It defaults to
+// five iterations of three variations each, with no cost cap.
+func NewPromptOptimizer(client *agentpatterns.AnthropicClient, judges ...Judge) *PromptOptimizer {
+	return &PromptOptimizer{
+		client:         client,
+		judges:         judges,
+		optimizerModel: agentpatterns.DefaultModel,
+		maxIterations:  5,
+		mutations:      3,
+		pricing:        agentpatterns.DefaultPricing(),
+		concurrency:    4,
+	}
+}
+
+// SetOptions sets the options of the chain that runs each template, e.g.
+// its model
+func (o *PromptOptimizer) SetOptions(opts ...agentpatterns.Option) *PromptOptimizer {
+	o.opts = opts
+	return o
+}
+
+// SetOptimizerModel sets the model proposing new templates
+func (o *PromptOptimizer) SetOptimizerModel(model string) *PromptOptimizer {
+	o.optimizerModel = model
+	return o
+}
+
+// SetMaxIterations sets how many rounds of variations are tried
+func (o *PromptOptimizer) SetMaxIterations(n int) *PromptOptimizer {
+	o.maxIterations = n
+	return o
+}
+
+// SetMutations sets how many variations are proposed per iteration
+func (o *PromptOptimizer) SetMutations(n int) *PromptOptimizer {
+	if n > 0 {
+		o.mutations = n
+	}
+	return o
+}
+
+// SetMaxCost stops the optimization once it has spent maxCost US dollars
+// on running templates and on the optimizer model. Zero sets no cap.
+func (o *PromptOptimizer) SetMaxCost(maxCost float64) *PromptOptimizer {
+	o.maxCost = maxCost
+	return o
+}
+
+// SetPricing sets the model prices used to compute costs
+func (o *PromptOptimizer) SetPricing(pricing map[string]agentpatterns.ModelPricing) *PromptOptimizer {
+	o.pricing = pricing
+	return o
+}
+
+// SetConcurrency sets how many cases run at once per template (default 4)
+func (o *PromptOptimizer) SetConcurrency(n int) *PromptOptimizer {
+	if n > 0 {
+		o.concurrency = n
+	}
+	return o
+}
+
+// Optimize improves template, a text/template given each case's input as
+// {{.input}}, over dataset. It returns an error only if the starting
+// template cannot be evaluated or ctx is cancelled; failed variations are
+// recorded and skipped.
+func (o *PromptOptimizer) Optimize(ctx context.Context, template string, dataset []Case) (*PromptOptimization, error) {
+	if len(dataset) == 0 {
+		return nil, fmt.Errorf("empty dataset")
+	}
+	result := &PromptOptimization{StopReason: StopIterations}
+
+	best, report, err := o.evaluate(ctx, 0, template, "", dataset)
+	result.Cost += best.Cost
+	result.Candidates = append(result.Candidates, best)
+	if err != nil {
+		return result, err
+	}
+	result.Best = best
+	result.BestHistory = append(result.BestHistory, best)
+	bestReport := report
+
+	for iteration := 1; iteration <= o.maxIterations; iteration++ {
+		if best.Score >= 1 {
+			result.StopReason = StopPerfect
+			break
+		}
+		if o.overBudget(result.Cost) {
+			result.StopReason = StopBudget
+			break
+		}
+
+		proposals, cost, err := o.propose(ctx, best, bestReport, result.Candidates, dataset)
+		result.Cost += cost
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			// The optimizer model's reply could not be used; try again
+			continue
+		}
+		for _, p := range proposals {
+			if o.overBudget(result.Cost) {
+				break
+			}
+			candidate, report, err := o.evaluate(ctx, iteration, p.Template, p.Rationale, dataset)
+			result.Cost += candidate.Cost
+			result.Candidates = append(result.Candidates, candidate)
+			if err != nil {
+				if ctx.Err() != nil {
+					return result, ctx.Err()
+				}
+				continue
+			}
+			if candidate.Score > best.Score {
+				best, bestReport = candidate, report
+			}
+		}
+		result.Best = best
+		result.BestHistory = append(result.BestHistory, best)
+	}
+	if result.StopReason == StopIterations && o.overBudget(result.Cost) {
+		result.StopReason = StopBudget
+	}
+	return result, nil
+}
+
+// overBudget reports whether cost has reached the cap
+func (o *PromptOptimizer) overBudget(cost float64) bool {
+	return o.maxCost > 0 && cost >= o.maxCost
+}
+
+// evaluate scores a template over the dataset with the harness
+func (o *PromptOptimizer) evaluate(ctx context.Context, iteration int, template, rationale string, dataset []Case) (PromptCandidate, *Report, error) {
+	candidate := PromptCandidate{Iteration: iteration, Template: template, Rationale: rationale}
+	tmpl, err := agentpatterns.ParsePromptTemplate(fmt.Sprintf("candidate_%d", iteration), template)
+	if err == nil && !referencesInput(tmpl) {
+		err = fmt.Errorf("template does not use {{.input}}")
+	}
+	if err != nil {
+		candidate.Error = err.Error()
+		return candidate, nil, err
+	}
+
+	chain := agentpatterns.NewPromptChain(o.client, o.opts...).
+		AddStep(agentpatterns.ChainStep{Name: "answer", Template: tmpl})
+	report, err := NewHarness(o.judges...).
+		AddCandidate(FromChain("candidate", chain)).
+		SetPricing(o.pricing).
+		SetConcurrency(o.concurrency).
+		Run(ctx, dataset)
+	if err != nil {
+		candidate.Error = err.Error()
+		return candidate, nil, err
+	}
+	summary := report.Summaries[0]
+	candidate.Score = summary.MeanScore
+	candidate.Cost = summary.TotalCost
+	return candidate, report, nil
+}
+
+// referencesInput reports whether a template reads the case's input
+func referencesInput(tmpl *agentpatterns.PromptTemplate) bool {
+	for _, v := range tmpl.Variables() {
+		if v == "input" {
+			return true
+		}
+	}
+	return false
+}
+
+// proposal is a variation proposed by the optimizer model
+type proposal struct {
+	Template  string `json:"template"`
+	Rationale string `json:"rationale"`
+}
+
+// propose asks the optimizer model for variations of the best template,
+// showing it the template's weakest cases and the templates already tried.
+// It returns the cost of the call.
+func (o *PromptOptimizer) propose(ctx context.Context, best PromptCandidate, report *Report, tried []PromptCandidate, dataset []Case) ([]proposal, float64, error) {
+	inputs := make(map[string]string, len(dataset))
+	for _, c := range dataset {
+		inputs[c.ID] = c.Input
+	}
+	var weakest strings.Builder
+	results := append([]Result(nil), report.Results...)
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score < results[j].Score })
+	for i, r := range results {
+		if i == 3 || r.Score >= 1 {
+			break
+		}
+		fmt.Fprintf(&weakest, "\nCase %s (score %.2f)\nInput: %s\n", r.CaseID, r.Score, truncate(inputs[r.CaseID], 1000))
+		if r.Error != "" {
+			fmt.Fprintf(&weakest, "Error: %s\n", r.Error)
+		} else {
+			fmt.Fprintf(&weakest, "Output: %s\n", truncate(r.Output, 1000))
+		}
+	}
+
+	var history strings.Builder
+	for _, c := range tried {
+		if c.Error != "" {
+			fmt.Fprintf(&history, "\n---\nFailed (%s):\n%s\n", c.Error, c.Template)
+			continue
+		}
+		fmt.Fprintf(&history, "\n---\nScore %.3f:\n%s\n", c.Score, c.Template)
+	}
+
+	prompt := fmt.Sprintf(`You are improving a prompt template. It is a Go text/template; {{.input}} is replaced with each task's input and must appear in every template.
+
+Best template so far (mean score %.3f out of 1):
+%s
+
+Its weakest cases:
+%s
+Templates already tried:
+%s
+Propose %d different improved templates that address the weaknesses above. Do not repeat a template already tried.
+
+Respond with a JSON array in this exact format:
+[{"template": "...", "rationale": "what changed and why"}]`, best.Score, best.Template, weakest.String(), history.String(), o.mutations)
+
+	response, usage, err := o.client.CreateMessageWithUsage(ctx, prompt, o.optimizerModel, 4096)
+	cost := o.pricing[o.optimizerModel].Cost(usage)
+	if err != nil {
+		return nil, cost, err
+	}
+	var proposals []proposal
+	if err := jsonx.Unmarshal(response, &proposals); err != nil {
+		return nil, cost, fmt.Errorf("failed to parse optimizer response: %w", err)
+	}
+	if len(proposals) > o.mutations {
+		proposals = proposals[:o.mutations]
+	}
+	return proposals, cost, nil
+}
+
+// truncate shortens s to n bytes for a prompt
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
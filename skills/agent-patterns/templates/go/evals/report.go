@@ -0,0 +1,87 @@
+/*
+ * Reports for the Evaluation Harness
+ * Per-candidate quality, cost, and latency summaries
+ */
+
+package evals
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Summary aggregates one candidate's results
+type Summary struct {
+	Candidate string  `json:"candidate"`
+	Cases     int     `json:"cases"`
+	Errors    int     `json:"errors"`
+	MeanScore float64 `json:"mean_score"`
+	// TotalCost and CostPerCase are in US dollars
+	TotalCost    float64       `json:"total_cost"`
+	CostPerCase  float64       `json:"cost_per_case"`
+	InputTokens  int           `json:"input_tokens"`
+	OutputTokens int           `json:"output_tokens"`
+	MeanLatency  time.Duration `json:"mean_latency"`
+	P95Latency   time.Duration `json:"p95_latency"`
+}
+
+// Report holds every result and a summary per candidate, in the order the
+// candidates were added
+type Report struct {
+	Results   []Result  `json:"results"`
+	Summaries []Summary `json:"summaries"`
+}
+
+func summarize(candidate string, results []Result) Summary {
+	s := Summary{Candidate: candidate, Cases: len(results)}
+	if len(results) == 0 {
+		return s
+	}
+
+	latencies := make([]time.Duration, 0, len(results))
+	var scoreSum float64
+	var latencySum time.Duration
+	for _, r := range results {
+		if r.Error != "" {
+			s.Errors++
+		}
+		scoreSum += r.Score
+		s.TotalCost += r.Cost.Cost
+		s.InputTokens += r.Cost.InputTokens
+		s.OutputTokens += r.Cost.OutputTokens
+		latencySum += r.Latency
+		latencies = append(latencies, r.Latency)
+	}
+
+	n := len(results)
+	s.MeanScore = scoreSum / float64(n)
+	s.CostPerCase = s.TotalCost / float64(n)
+	s.MeanLatency = latencySum / time.Duration(n)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	s.P95Latency = latencies[(n*95+99)/100-1]
+	return s
+}
+
+// WriteTable writes the summaries as an aligned text table
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CANDIDATE\tCASES\tERRORS\tSCORE\tCOST\tCOST/CASE\tTOKENS\tMEAN LATENCY\tP95 LATENCY")
+	for _, s := range r.Summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.3f\t$%.4f\t$%.4f\t%d\t%v\t%v\n",
+			s.Candidate, s.Cases, s.Errors, s.MeanScore, s.TotalCost, s.CostPerCase,
+			s.InputTokens+s.OutputTokens,
+			s.MeanLatency.Round(time.Millisecond), s.P95Latency.Round(time.Millisecond))
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the full report, including every result, as JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -7,10 +7,15 @@ package agentpatterns
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/schema"
 )
 
 // EvaluationCriterion represents an evaluation criterion with weight
@@ -18,14 +23,60 @@ type EvaluationCriterion struct {
 	Name        string
 	Description string
 	Weight      float64
+	// Anchors describe outputs at given scores, e.g. from a Rubric
+	Anchors []ScoreAnchor
 }
 
 // EvaluationResult represents the result of an evaluation
 type EvaluationResult struct {
-	OverallScore   float64
-	CriteriaScores map[string]float64
-	Feedback       string
-	Suggestions    []string
+	OverallScore   float64            `json:"overall_score" description:"Weighted score across all criteria" jsonschema:"minimum=0,maximum=1"`
+	CriteriaScores map[string]float64 `json:"criteria_scores" description:"Score from 0.0 to 1.0 for each criterion, by name"`
+	Feedback       string             `json:"feedback" description:"Overall assessment"`
+	Suggestions    []string           `json:"suggestions" description:"Specific improvements"`
+	// Judges holds each evaluator's score when several are added
+	Judges []JudgeScore `json:"judges,omitempty"`
+}
+
+// JudgeScore is one evaluator model's score in an ensemble evaluation
+type JudgeScore struct {
+	Model    string  `json:"model"`
+	Weight   float64 `json:"weight"`
+	Score    float64 `json:"score"`
+	Feedback string  `json:"feedback"`
+}
+
+// Aggregation sets how the scores of several evaluators are combined
+type Aggregation string
+
+// Aggregations
+const (
+	// AggregateMean takes the weighted mean (the default)
+	AggregateMean Aggregation = "mean"
+	// AggregateMedian takes the weighted median, so one outlier has no effect
+	AggregateMedian Aggregation = "median"
+	// AggregateMin takes the lowest score, so every judge must be satisfied
+	AggregateMin Aggregation = "min"
+)
+
+// OptimizationStop is why Optimize stopped iterating
+type OptimizationStop string
+
+const (
+	// StopThreshold means an iteration reached the score threshold
+	StopThreshold OptimizationStop = "threshold"
+	// StopMaxIterations means every iteration allowed was used
+	StopMaxIterations OptimizationStop = "max_iterations"
+	// StopPlateau means the best score stopped improving, with early stopping
+	StopPlateau OptimizationStop = "plateau"
+	// StopRepeatedSuggestions means the evaluator made the same suggestions
+	// as for the previous iteration, with early stopping
+	StopRepeatedSuggestions OptimizationStop = "repeated_suggestions"
+)
+
+// evaluatorJudge is an evaluator model added with AddEvaluator
+type evaluatorJudge struct {
+	model  string
+	weight float64
 }
 
 // IterationRecord represents a record of an iteration
@@ -33,30 +84,47 @@ type IterationRecord struct {
 	Iteration  int
 	Output     string
 	Evaluation *EvaluationResult
+	// Thinking is the generator's extended thinking, if enabled
+	Thinking string
+	// Edits is the number of edits applied to the previous output with
+	// RefinePatch; zero when the output was written in full
+	Edits int
+}
+
+// optimizerCheckpoint is the state persisted after each iteration when a
+// store is configured
+type optimizerCheckpoint struct {
+	History []IterationRecord `json:"history"`
 }
 
 // EvaluatorOptimizer iteratively refines output.
 //
 // Example:
 //
-//	optimizer := NewEvaluatorOptimizer(client, "claude-sonnet-4-20250514")
+//	optimizer := NewEvaluatorOptimizer(client, WithModel("claude-sonnet-4-20250514"))
 //	optimizer.AddCriterion(EvaluationCriterion{Name: "clarity", Description: "Clear writing", Weight: 1.5})
 //	result, err := optimizer.Optimize(ctx, "Write a blog post about AI", 3, 0.85)
 type EvaluatorOptimizer struct {
-	client         *AnthropicClient
-	generatorModel string
-	evaluatorModel string
-	criteria       []EvaluationCriterion
-	history        []IterationRecord
+	client           *AnthropicClient
+	cfg              patternConfig
+	generatorModel   string
+	evaluatorModel   string
+	evaluatorRequest *RequestOptions
+	judges           []evaluatorJudge
+	aggregation      Aggregation
+	criteria         []EvaluationCriterion
+	history          []IterationRecord
 }
 
 // NewEvaluatorOptimizer creates a new EvaluatorOptimizer
-func NewEvaluatorOptimizer(client *AnthropicClient, model string) *EvaluatorOptimizer {
+func NewEvaluatorOptimizer(client *AnthropicClient, opts ...Option) *EvaluatorOptimizer {
+	cfg := newPatternConfig("evaluator_optimizer", opts)
 	return &EvaluatorOptimizer{
 		client:         client,
-		generatorModel: model,
-		evaluatorModel: model,
-		criteria:       []EvaluationCriterion{},
+		cfg:            cfg,
+		generatorModel: cfg.model,
+		evaluatorModel: cfg.model,
+		criteria:       append([]EvaluationCriterion{}, cfg.criteria...),
 		history:        []IterationRecord{},
 	}
 }
@@ -67,6 +135,57 @@ func (e *EvaluatorOptimizer) WithEvaluatorModel(model string) *EvaluatorOptimize
 	return e
 }
 
+// WithEvaluatorOptions sets different request options for evaluation, e.g.
+// temperature 0 for consistent scores while the generator samples higher
+func (e *EvaluatorOptimizer) WithEvaluatorOptions(opts RequestOptions) *EvaluatorOptimizer {
+	e.evaluatorRequest = &opts
+	return e
+}
+
+// WithThinkingBudget lets the model think for up to budget tokens before
+// each generation and evaluation; the generator's thinking is kept in the
+// history. Options set with WithEvaluatorOptions replace it for evaluation.
+func (e *EvaluatorOptimizer) WithThinkingBudget(budget int) *EvaluatorOptimizer {
+	e.cfg.request.ThinkingBudget = budget
+	return e
+}
+
+// AddEvaluator adds a judge model. Once any are added, each iteration is
+// scored by all of them in parallel instead of by the evaluator model, and
+// their scores are combined with the configured Aggregation. A judge that
+// fails is left out as long as another succeeds.
+//
+// Example:
+//
+//	optimizer := NewEvaluatorOptimizer(client).
+//	    AddEvaluator("claude-sonnet-4-20250514", 1).
+//	    AddEvaluator("claude-opus-4-20250514", 2).
+//	    WithAggregation(AggregateMedian)
+func (e *EvaluatorOptimizer) AddEvaluator(model string, weight float64) *EvaluatorOptimizer {
+	e.judges = append(e.judges, evaluatorJudge{model: model, weight: weight})
+	return e
+}
+
+// WithAggregation sets how the scores of several evaluators are combined
+func (e *EvaluatorOptimizer) WithAggregation(aggregation Aggregation) *EvaluatorOptimizer {
+	e.aggregation = aggregation
+	return e
+}
+
+// WithEarlyStopping stops an EvaluatorOptimizer's Optimize before
+// maxIterations once refining has stopped paying off: when the best score
+// has improved by less than epsilon over the last patience iterations, or
+// when the evaluator repeats the suggestions it made for the previous
+// iteration, so the generator is not acting on them. The best output so
+// far is returned, with the reason in StopReason.
+//
+// Example:
+//
+//	optimizer := NewEvaluatorOptimizer(client, WithEarlyStopping(2, 0.02))
+func WithEarlyStopping(patience int, epsilon float64) Option {
+	return func(c *patternConfig) { c.patience, c.epsilon = patience, epsilon }
+}
+
 // AddCriterion adds an evaluation criterion
 func (e *EvaluatorOptimizer) AddCriterion(criterion EvaluationCriterion) *EvaluatorOptimizer {
 	e.criteria = append(e.criteria, criterion)
@@ -85,34 +204,92 @@ type OptimizationResult struct {
 	Iterations   int
 	MetThreshold bool
 	History      []IterationRecord
+	// StopReason is why the iterations stopped; it is empty on the partial
+	// result of a run stopped by its budget
+	StopReason OptimizationStop
+	// ParetoFront holds the iterations no other beats on every criterion,
+	// with WithParetoFront
+	ParetoFront []IterationRecord
 }
 
-// Optimize optimizes output through iterative refinement
-func (e *EvaluatorOptimizer) Optimize(ctx context.Context, task string, maxIterations int, scoreThreshold float64) (*OptimizationResult, error) {
+// Optimize optimizes output through iterative refinement. A run that exceeds
+// its budget returns a *BudgetExceededError whose Partial is an
+// *OptimizationResult with the best output evaluated so far.
+func (e *EvaluatorOptimizer) Optimize(ctx context.Context, task string, maxIterations int, scoreThreshold float64) (result *OptimizationResult, err error) {
+	ctx, cancel := e.cfg.startRun(ctx)
+	defer cancel()
+	defer func() {
+		if err != nil {
+			err = budgetStop(ctx, err, e.bestResult(len(e.history)))
+			return
+		}
+		if e.cfg.paretoFront {
+			result.ParetoFront = ParetoFront(result.History)
+		}
+	}()
+
 	e.history = []IterationRecord{}
 	currentOutput := ""
 	var lastEvaluation *EvaluationResult
 
-	for i := 0; i < maxIterations; i++ {
+	var checkpoint optimizerCheckpoint
+	if found, err := e.cfg.loadCheckpoint(ctx, &checkpoint); err != nil {
+		return nil, err
+	} else if found && len(checkpoint.History) > 0 {
+		e.history = checkpoint.History
+		last := e.history[len(e.history)-1]
+		currentOutput = last.Output
+		lastEvaluation = last.Evaluation
+		if lastEvaluation != nil && lastEvaluation.OverallScore >= scoreThreshold {
+			return &OptimizationResult{
+				FinalOutput:  currentOutput,
+				FinalScore:   lastEvaluation.OverallScore,
+				Iterations:   len(e.history),
+				MetThreshold: true,
+				History:      e.history,
+				StopReason:   StopThreshold,
+			}, nil
+		}
+	}
+
+	for i := len(e.history); i < maxIterations; i++ {
+		iterCtx, span := StartSpan(ctx, "optimizer.iteration")
+		span.SetAttribute("iteration", i+1)
+
 		// Generate (or refine) output
-		output, err := e.generate(ctx, task, currentOutput, lastEvaluation)
+		var thinking string
+		genCtx := onThinking(iterCtx, func(t string) { thinking += t })
+		output, edits, err := e.generate(genCtx, task, currentOutput, lastEvaluation)
 		if err != nil {
+			span.Finish(err)
 			return nil, fmt.Errorf("generation failed: %w", err)
 		}
 		currentOutput = output
 
 		// Evaluate output
-		evaluation, err := e.evaluate(ctx, currentOutput)
+		evaluation, err := e.evaluate(iterCtx, currentOutput)
 		if err != nil {
+			span.Finish(err)
 			return nil, fmt.Errorf("evaluation failed: %w", err)
 		}
+		span.SetAttribute("score", evaluation.OverallScore)
+		span.Finish(nil)
+		e.cfg.publish(iterCtx, PhaseIteration, map[string]interface{}{
+			"iteration": i + 1,
+			"score":     evaluation.OverallScore,
+		})
 
 		// Record iteration
 		e.history = append(e.history, IterationRecord{
 			Iteration:  i + 1,
 			Output:     currentOutput,
 			Evaluation: evaluation,
+			Thinking:   thinking,
+			Edits:      edits,
 		})
+		if err := e.cfg.saveCheckpoint(ctx, optimizerCheckpoint{History: e.history}); err != nil {
+			return nil, err
+		}
 
 		// Check if we've met the threshold
 		if evaluation.OverallScore >= scoreThreshold {
@@ -122,224 +299,373 @@ func (e *EvaluatorOptimizer) Optimize(ctx context.Context, task string, maxItera
 				Iterations:   i + 1,
 				MetThreshold: true,
 				History:      e.history,
+				StopReason:   StopThreshold,
 			}, nil
 		}
 
+		if reason := e.earlyStop(); reason != "" {
+			e.cfg.logger.Info("optimizer stopped early", "iteration", i+1, "reason", reason)
+			result := e.bestResult(i + 1)
+			result.StopReason = reason
+			return result, nil
+		}
+
 		lastEvaluation = evaluation
 	}
 
 	// Return best result after max iterations
-	var bestIteration *IterationRecord
-	var bestScore float64
-	for i := range e.history {
-		if e.history[i].Evaluation.OverallScore > bestScore {
-			bestScore = e.history[i].Evaluation.OverallScore
-			bestIteration = &e.history[i]
+	result = e.bestResult(maxIterations)
+	result.StopReason = StopMaxIterations
+	return result, nil
+}
+
+// earlyStop returns why iterating should stop early, if it should
+func (e *EvaluatorOptimizer) earlyStop() OptimizationStop {
+	n := len(e.history)
+	if e.cfg.patience <= 0 || n < 2 {
+		return ""
+	}
+	if sameSuggestions(e.history[n-2].Evaluation.Suggestions, e.history[n-1].Evaluation.Suggestions) {
+		return StopRepeatedSuggestions
+	}
+	if n > e.cfg.patience {
+		before, after := bestScore(e.history[:n-e.cfg.patience]), bestScore(e.history)
+		if after-before < e.cfg.epsilon {
+			return StopPlateau
 		}
 	}
-
-	return &OptimizationResult{
-		FinalOutput:  bestIteration.Output,
-		FinalScore:   bestIteration.Evaluation.OverallScore,
-		Iterations:   maxIterations,
-		MetThreshold: false,
-		History:      e.history,
-	}, nil
+	return ""
 }
 
-func (e *EvaluatorOptimizer) generate(ctx context.Context, task, previousOutput string, previousEvaluation *EvaluationResult) (string, error) {
-	var prompt string
-
-	if previousOutput == "" {
-		prompt = fmt.Sprintf(`Complete this task:
-
-%s
-
-Provide your best output:`, task)
-	} else {
-		var feedbackText string
-		if previousEvaluation != nil {
-			var suggestions []string
-			for _, s := range previousEvaluation.Suggestions {
-				suggestions = append(suggestions, "- "+s)
-			}
-			feedbackText = fmt.Sprintf(`Previous evaluation feedback:
-%s
-
-Specific suggestions:
-%s`, previousEvaluation.Feedback, strings.Join(suggestions, "\n"))
+// bestScore returns the highest score in history
+func bestScore(history []IterationRecord) float64 {
+	best := history[0].Evaluation.OverallScore
+	for _, record := range history[1:] {
+		if record.Evaluation.OverallScore > best {
+			best = record.Evaluation.OverallScore
 		}
-
-		prompt = fmt.Sprintf(`Improve this output based on the feedback:
-
-Original task: %s
-
-Previous output:
-%s
-
-%s
-
-Provide an improved version:`, task, previousOutput, feedbackText)
 	}
-
-	return e.client.CreateMessage(ctx, prompt, e.generatorModel, 4096)
+	return best
 }
 
-func (e *EvaluatorOptimizer) evaluate(ctx context.Context, output string) (*EvaluationResult, error) {
-	var criteriaList string
-	if len(e.criteria) > 0 {
-		var parts []string
-		for _, c := range e.criteria {
-			parts = append(parts, fmt.Sprintf("- %s (weight: %.1f): %s", c.Name, c.Weight, c.Description))
+// sameSuggestions reports whether two non-empty lists hold the same
+// suggestions, ignoring order, case, and whitespace
+func sameSuggestions(a, b []string) bool {
+	if len(a) == 0 || len(a) != len(b) {
+		return false
+	}
+	normalize := func(suggestions []string) []string {
+		out := make([]string, len(suggestions))
+		for i, s := range suggestions {
+			out[i] = strings.Join(strings.Fields(strings.ToLower(s)), " ")
+		}
+		sort.Strings(out)
+		return out
+	}
+	na, nb := normalize(a), normalize(b)
+	for i := range na {
+		if na[i] != nb[i] {
+			return false
 		}
-		criteriaList = strings.Join(parts, "\n")
-	} else {
-		criteriaList = `- quality: Overall quality and correctness
-- clarity: Clear and understandable
-- completeness: Addresses all aspects`
 	}
+	return true
+}
 
-	prompt := fmt.Sprintf(`Evaluate this output against the following criteria:
+// bestResult returns the highest-scoring iteration so far, the earliest on
+// ties
+func (e *EvaluatorOptimizer) bestResult(iterations int) *OptimizationResult {
+	result := &OptimizationResult{Iterations: iterations, History: e.history}
+	for i, record := range e.history {
+		if i == 0 || record.Evaluation.OverallScore > result.FinalScore {
+			result.FinalOutput = record.Output
+			result.FinalScore = record.Evaluation.OverallScore
+		}
+	}
+	return result
+}
 
-%s
+// generate writes the first output or revises the previous one, returning
+// the number of edits when it was patched
+func (e *EvaluatorOptimizer) generate(ctx context.Context, task, previousOutput string, previousEvaluation *EvaluationResult) (string, int, error) {
+	if e.cfg.refinement == RefinePatch && previousOutput != "" {
+		output, edits, err := e.patch(ctx, task, previousOutput, previousEvaluation)
+		if !errors.Is(err, errPatch) {
+			return output, edits, err
+		}
+		e.cfg.logger.Warn("regenerating in full", "error", err)
+	}
 
-Output to evaluate:
-%s
+	var prompt string
+	var err error
+	if previousOutput == "" {
+		prompt, err = e.cfg.prompt("optimizer.generate", map[string]interface{}{"task": task})
+	} else {
+		prompt, err = e.cfg.prompt("optimizer.improve", map[string]interface{}{
+			"task":            task,
+			"previous_output": previousOutput,
+			"evaluation":      previousEvaluation,
+		})
+	}
+	if err != nil {
+		return "", 0, err
+	}
 
-Respond with JSON in this exact format:
-{
-    "overall_score": 0.0-1.0,
-    "criteria_scores": {
-        "criterion_name": 0.0-1.0
-    },
-    "feedback": "Overall assessment",
-    "suggestions": ["specific improvement 1", "specific improvement 2"]
-}`, criteriaList, output)
+	output, err := e.cfg.call(ctx, e.client, prompt, e.generatorModel, e.cfg.tokens(4096))
+	return output, 0, err
+}
 
-	response, err := e.client.CreateMessage(ctx, prompt, e.evaluatorModel, 1024)
-	if err != nil {
-		return nil, err
+func (e *EvaluatorOptimizer) evaluate(ctx context.Context, output string) (*EvaluationResult, error) {
+	if len(e.judges) == 0 {
+		return e.evaluateWith(ctx, e.evaluatorModel, output)
 	}
 
-	return parseEvaluationJSON(response)
+	results := make([]*EvaluationResult, len(e.judges))
+	errs := make([]error, len(e.judges))
+	var wg sync.WaitGroup
+	for i, judge := range e.judges {
+		wg.Add(1)
+		go func(i int, model string) {
+			defer wg.Done()
+			judgeCtx, span := StartSpan(ctx, "optimizer.judge")
+			span.SetAttribute("model", model)
+			results[i], errs[i] = e.evaluateWith(judgeCtx, model, output)
+			span.Finish(errs[i])
+		}(i, judge.model)
+	}
+	wg.Wait()
+
+	var scores []JudgeScore
+	var evaluations []*EvaluationResult
+	for i, judge := range e.judges {
+		if errs[i] != nil {
+			e.cfg.logger.Warn("evaluator failed", "model", judge.model, "error", errs[i])
+			continue
+		}
+		scores = append(scores, JudgeScore{Model: judge.model, Weight: judge.weight, Score: results[i].OverallScore, Feedback: results[i].Feedback})
+		evaluations = append(evaluations, results[i])
+	}
+	if len(evaluations) == 0 {
+		return nil, errs[0]
+	}
+	return e.aggregate(scores, evaluations), nil
 }
 
-func parseEvaluationJSON(jsonStr string) (*EvaluationResult, error) {
+// aggregate combines the evaluations of several judges into one
+func (e *EvaluatorOptimizer) aggregate(scores []JudgeScore, evaluations []*EvaluationResult) *EvaluationResult {
+	weights := make([]float64, len(scores))
+	overall := make([]float64, len(scores))
+	for i, s := range scores {
+		weights[i] = s.Weight
+		overall[i] = s.Score
+	}
 	result := &EvaluationResult{
-		OverallScore:   0.5,
+		OverallScore:   aggregateScores(e.aggregation, overall, weights),
 		CriteriaScores: make(map[string]float64),
-		Suggestions:    []string{},
+		Judges:         scores,
+	}
+
+	// Each criterion is combined over the judges that scored it
+	criteria := make(map[string]bool)
+	for _, ev := range evaluations {
+		for name := range ev.CriteriaScores {
+			criteria[name] = true
+		}
+	}
+	for _, name := range sortedKeys(criteria) {
+		var values, valueWeights []float64
+		for i, ev := range evaluations {
+			if v, ok := ev.CriteriaScores[name]; ok {
+				values = append(values, v)
+				valueWeights = append(valueWeights, weights[i])
+			}
+		}
+		result.CriteriaScores[name] = aggregateScores(e.aggregation, values, valueWeights)
 	}
 
-	// Extract overall score
-	scoreRe := regexp.MustCompile(`"overall_score"\s*:\s*([0-9.]+)`)
-	if match := scoreRe.FindStringSubmatch(jsonStr); len(match) > 1 {
-		if score, err := strconv.ParseFloat(match[1], 64); err == nil {
-			result.OverallScore = score
+	var feedback []string
+	seen := make(map[string]bool)
+	for i, ev := range evaluations {
+		feedback = append(feedback, fmt.Sprintf("[%s] %s", scores[i].Model, ev.Feedback))
+		for _, s := range ev.Suggestions {
+			if !seen[s] {
+				seen[s] = true
+				result.Suggestions = append(result.Suggestions, s)
+			}
 		}
 	}
+	result.Feedback = strings.Join(feedback, "\n")
+	return result
+}
 
-	// Extract feedback
-	feedbackRe := regexp.MustCompile(`"feedback"\s*:\s*"([^"]*)"`)
-	if match := feedbackRe.FindStringSubmatch(jsonStr); len(match) > 1 {
-		result.Feedback = match[1]
+// aggregateScores combines values by weight; a weight of zero or less
+// counts as one
+func aggregateScores(aggregation Aggregation, values, weights []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	w := make([]float64, len(weights))
+	var total float64
+	for i, weight := range weights {
+		w[i] = weight
+		if w[i] <= 0 {
+			w[i] = 1
+		}
+		total += w[i]
 	}
 
-	// Extract suggestions
-	suggestionsRe := regexp.MustCompile(`"suggestions"\s*:\s*\[(.*?)\]`)
-	if match := suggestionsRe.FindStringSubmatch(jsonStr); len(match) > 1 {
-		suggestionItemRe := regexp.MustCompile(`"([^"]+)"`)
-		items := suggestionItemRe.FindAllStringSubmatch(match[1], -1)
-		for _, item := range items {
-			if len(item) > 1 {
-				result.Suggestions = append(result.Suggestions, item[1])
+	switch aggregation {
+	case AggregateMin:
+		lowest := values[0]
+		for _, v := range values[1:] {
+			if v < lowest {
+				lowest = v
+			}
+		}
+		return lowest
+	case AggregateMedian:
+		order := make([]int, len(values))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+		var cumulative float64
+		for _, i := range order {
+			cumulative += w[i]
+			if cumulative >= total/2 {
+				return values[i]
 			}
 		}
+		return values[order[len(order)-1]]
+	default:
+		var sum float64
+		for i, v := range values {
+			sum += v * w[i]
+		}
+		return sum / total
 	}
+}
 
-	return result, nil
+// evaluateWith scores output with one evaluator model
+func (e *EvaluatorOptimizer) evaluateWith(ctx context.Context, model, output string) (*EvaluationResult, error) {
+	prompt, err := e.cfg.prompt("optimizer.evaluate", map[string]interface{}{"criteria": e.criteria, "output": output})
+	if err != nil {
+		return nil, err
+	}
+
+	// Judges are filled in by aggregate, not by the model
+	outputSchema := schema.MustFor[EvaluationResult]()
+	delete(outputSchema.Properties, "judges")
+	cfg := e.cfg
+	if e.evaluatorRequest != nil {
+		cfg.request = *e.evaluatorRequest
+	}
+	result, err := structured[EvaluationResult](ctx, &cfg, e.client, prompt, outputSchema, model, 1024)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse evaluation: %w", err)
+	}
+	if result.CriteriaScores == nil {
+		result.CriteriaScores = make(map[string]float64)
+	}
+	return &result, nil
 }
 
 // ConfidenceBasedOptimizer generates with confidence self-assessment
 type ConfidenceBasedOptimizer struct {
 	client *AnthropicClient
-	model  string
+	cfg    patternConfig
 }
 
 // NewConfidenceBasedOptimizer creates a new ConfidenceBasedOptimizer
-func NewConfidenceBasedOptimizer(client *AnthropicClient, model string) *ConfidenceBasedOptimizer {
+func NewConfidenceBasedOptimizer(client *AnthropicClient, opts ...Option) *ConfidenceBasedOptimizer {
 	return &ConfidenceBasedOptimizer{
 		client: client,
-		model:  model,
+		cfg:    newPatternConfig("confidence_optimizer", opts),
 	}
 }
 
+// WithCalibration has a ConfidenceBasedOptimizer calibrate the model's
+// self-reported confidence before it is compared with the threshold, so
+// that a threshold of 0.9 asks for outputs that are correct 90% of the
+// time rather than ones the model reports 0.9 for. Fit cal with FitCalibration on samples from an eval
+// run, e.g. with evals.CalibrationSamples.
+func WithCalibration(cal *Calibration) Option {
+	return func(c *patternConfig) { c.calibration = cal }
+}
+
 // AttemptRecord represents a record of an attempt
 type AttemptRecord struct {
 	Attempt    int
 	Output     string
 	Confidence float64
+	// RawConfidence is the confidence the model reported, which differs
+	// from Confidence with a calibration
+	RawConfidence float64
 }
 
 // ConfidenceResult represents the result of confidence-based generation
 type ConfidenceResult struct {
-	Output       string
-	Confidence   float64
-	Attempts     []AttemptRecord
-	MetThreshold bool
+	Output        string
+	Confidence    float64
+	RawConfidence float64
+	Attempts      []AttemptRecord
+	MetThreshold  bool
 }
 
 // GenerateWithConfidence generates with confidence self-assessment
 func (c *ConfidenceBasedOptimizer) GenerateWithConfidence(ctx context.Context, task string, confidenceThreshold float64, maxAttempts int) (*ConfidenceResult, error) {
+	ctx, cancel := c.cfg.startRun(ctx)
+	defer cancel()
+
 	var attempts []AttemptRecord
 	bestOutput := ""
-	bestConfidence := 0.0
+	bestConfidence, bestRaw := 0.0, 0.0
 
 	for i := 0; i < maxAttempts; i++ {
-		prompt := fmt.Sprintf(`Complete this task and assess your confidence:
-
-%s
-
-After your response, on a new line, provide your confidence level (0.0-1.0) that your answer is correct and complete.
-
-Format:
-[Your response here]
-
-CONFIDENCE: [0.0-1.0]`, task)
+		prompt, err := c.cfg.prompt("optimizer.confidence", map[string]interface{}{"task": task})
+		if err != nil {
+			return nil, err
+		}
 
-		response, err := c.client.CreateMessage(ctx, prompt, c.model, 4096)
+		response, err := c.cfg.call(ctx, c.client, prompt, c.cfg.model, c.cfg.tokens(4096))
 		if err != nil {
 			return nil, err
 		}
 
-		output, confidence := parseConfidenceResponse(response)
+		output, raw := parseConfidenceResponse(response)
+		confidence := raw
+		if c.cfg.calibration != nil {
+			confidence = c.cfg.calibration.Apply(raw)
+		}
 
 		attempts = append(attempts, AttemptRecord{
-			Attempt:    i + 1,
-			Output:     output,
-			Confidence: confidence,
+			Attempt:       i + 1,
+			Output:        output,
+			Confidence:    confidence,
+			RawConfidence: raw,
 		})
 
 		if confidence > bestConfidence {
-			bestConfidence = confidence
+			bestConfidence, bestRaw = confidence, raw
 			bestOutput = output
 		}
 
 		if confidence >= confidenceThreshold {
 			return &ConfidenceResult{
-				Output:       output,
-				Confidence:   confidence,
-				Attempts:     attempts,
-				MetThreshold: true,
+				Output:        output,
+				Confidence:    confidence,
+				RawConfidence: raw,
+				Attempts:      attempts,
+				MetThreshold:  true,
 			}, nil
 		}
 	}
 
 	return &ConfidenceResult{
-		Output:       bestOutput,
-		Confidence:   bestConfidence,
-		Attempts:     attempts,
-		MetThreshold: false,
+		Output:        bestOutput,
+		Confidence:    bestConfidence,
+		RawConfidence: bestRaw,
+		Attempts:      attempts,
+		MetThreshold:  false,
 	}, nil
 }
 
@@ -377,7 +703,7 @@ func ExampleWritingOptimization() error {
 		HTTPClient: nil, // Would use http.Client in production
 	}
 
-	optimizer := NewEvaluatorOptimizer(client, "claude-sonnet-4-20250514")
+	optimizer := NewEvaluatorOptimizer(client, WithModel("claude-sonnet-4-20250514"))
 
 	// Add evaluation criteria
 	optimizer.
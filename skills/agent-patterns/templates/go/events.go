@@ -0,0 +1,204 @@
+/*
+ * Event Bus for Go Agent Patterns
+ * Lifecycle events published by every pattern to shared sinks
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event phases published by the patterns
+const (
+	PhaseRunStarted      = "run_started"
+	PhaseRunFinished     = "run_finished"
+	PhaseLLMCall         = "llm_call"
+	PhaseLLMRetry        = "llm_retry"
+	PhaseCacheHit        = "cache_hit"
+	PhaseStepStarted     = "step_started"
+	PhaseStepFinished    = "step_finished"
+	PhaseStepFailed      = "step_failed"
+	PhaseStepRetried     = "step_retried"
+	PhaseClassified      = "classified"
+	PhaseDecomposed      = "decomposed"
+	PhaseSubtaskFinished = "subtask_finished"
+	PhaseReplanned       = "replanned"
+	PhaseToolCalled      = "tool_called"
+	PhaseVoteCast        = "vote_cast"
+	PhaseVoteTallied     = "vote_tallied"
+	PhaseGuardrail       = "guardrail_verdict"
+	PhaseIteration       = "iteration_scored"
+	PhaseRetrieved       = "retrieved"
+	PhaseApproval        = "approval_decided"
+	PhaseStepReplayed    = "step_replayed"
+	PhaseThinking        = "thinking"
+	PhaseBatchPolled     = "batch_polled"
+	PhaseFailover        = "failover"
+	PhaseCircuitChanged  = "circuit_changed"
+)
+
+// Event is a single lifecycle event. Payload keys depend on the phase, e.g.
+// "step" for chain steps, "tool" for tool calls, and "score" for iterations.
+type Event struct {
+	Pattern string
+	RunID   string
+	SpanID  string
+	Phase   string
+	Time    time.Time
+	Payload map[string]interface{}
+}
+
+// EventSink receives events, e.g. to log them or keep an audit trail.
+// Parallel patterns publish from several goroutines, so implementations
+// must be safe for concurrent use.
+type EventSink interface {
+	OnEvent(event Event)
+}
+
+// EventSinkFunc adapts a function to EventSink
+type EventSinkFunc func(event Event)
+
+// OnEvent implements EventSink
+func (f EventSinkFunc) OnEvent(event Event) { f(event) }
+
+// SlogSink logs each event to logger, with the pattern, run, span, and
+// phase followed by the payload as attributes. Failures and retries are
+// logged as warnings, everything else at info. Give it a JSON handler for a
+// machine-readable audit log.
+//
+// Example:
+//
+//	audit, err := os.OpenFile("audit.jsonl", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+//	bus.Subscribe(SlogSink(slog.New(slog.NewJSONHandler(audit, nil))))
+func SlogSink(logger *slog.Logger) EventSink {
+	return EventSinkFunc(func(e Event) {
+		level := slog.LevelInfo
+		switch e.Phase {
+		case PhaseStepFailed, PhaseStepRetried, PhaseLLMRetry, PhaseFailover, PhaseCircuitChanged:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{slog.String("pattern", e.Pattern), slog.String("phase", e.Phase)}
+		if e.RunID != "" {
+			attrs = append(attrs, slog.String("run_id", e.RunID))
+		}
+		if e.SpanID != "" {
+			attrs = append(attrs, slog.String("span_id", e.SpanID))
+		}
+		keys := make([]string, 0, len(e.Payload))
+		for k := range e.Payload {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			attrs = append(attrs, slog.Any(k, e.Payload[k]))
+		}
+		logger.LogAttrs(context.Background(), level, "agent event", attrs...)
+	})
+}
+
+// EventBus fans events out to its sinks. A bus given to the outermost
+// pattern travels in the context, so nested patterns publish to it too.
+//
+// Example:
+//
+//	bus := NewEventBus()
+//	bus.Subscribe(EventSinkFunc(func(e Event) {
+//	    log.Printf("[%s] %s %s %v", e.RunID, e.Pattern, e.Phase, e.Payload)
+//	}))
+//	chain := NewPromptChain(client, WithEventBus(bus))
+type EventBus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]EventSink
+}
+
+// NewEventBus creates a bus with no sinks
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[int]EventSink)}
+}
+
+// Subscribe registers sink s and returns a function that removes it
+func (b *EventBus) Subscribe(s EventSink) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = s
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// Publish delivers event to every sink synchronously
+func (b *EventBus) Publish(event Event) {
+	b.mu.RLock()
+	subs := make([]EventSink, 0, len(b.subs))
+	for _, s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		s.OnEvent(event)
+	}
+}
+
+// WithEventBus publishes the pattern's lifecycle events to bus
+func WithEventBus(bus *EventBus) Option {
+	return func(c *patternConfig) { c.bus = bus }
+}
+
+type eventBusKey struct{}
+
+// EventBusFromContext returns the bus attached to the current run, if any
+func EventBusFromContext(ctx context.Context) *EventBus {
+	bus, _ := ctx.Value(eventBusKey{}).(*EventBus)
+	return bus
+}
+
+// ContextWithEventBus attaches bus to ctx so every pattern run under it
+// publishes there, overriding buses set with WithEventBus. It lets callers
+// follow a single run, e.g. to stream its events to an HTTP client.
+func ContextWithEventBus(ctx context.Context, bus *EventBus) context.Context {
+	return context.WithValue(ctx, eventBusKey{}, bus)
+}
+
+// publish sends an event to the bus attached to ctx
+func publish(ctx context.Context, pattern, phase string, payload map[string]interface{}) {
+	publishTo(ctx, EventBusFromContext(ctx), pattern, phase, payload)
+}
+
+// publish sends an event to the run's bus, falling back to the pattern's
+// own bus for calls made outside of a run
+func (c *patternConfig) publish(ctx context.Context, phase string, payload map[string]interface{}) {
+	bus := EventBusFromContext(ctx)
+	if bus == nil {
+		bus = c.bus
+	}
+	publishTo(ctx, bus, c.pattern, phase, payload)
+}
+
+func publishTo(ctx context.Context, bus *EventBus, pattern, phase string, payload map[string]interface{}) {
+	if bus == nil {
+		return
+	}
+	event := Event{
+		Pattern: pattern,
+		RunID:   RunIDFromContext(ctx),
+		Phase:   phase,
+		Time:    time.Now(),
+		Payload: payload,
+	}
+	if span := SpanFromContext(ctx); span != nil {
+		event.SpanID = span.SpanID
+	}
+	bus.Publish(event)
+}
@@ -0,0 +1,259 @@
+/*
+ * Failover for Go Agent Patterns
+ * Circuit breakers around providers, and ordered fallbacks across providers and models
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling a provider whose circuit
+// breaker is open
+var ErrCircuitOpen = errors.New("circuit open")
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState string
+
+// Circuit breaker states
+const (
+	// BreakerClosed passes requests through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen fails requests at once until the cooldown has passed
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single trial request through
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// CircuitBreaker stops sending requests to a provider that keeps failing.
+// After Threshold consecutive failures it opens, failing requests with
+// ErrCircuitOpen for Cooldown, then lets one trial request through: the
+// breaker closes if it succeeds and opens again if it fails. Failures are
+// errors worth retrying (overloaded, server errors, and network failures);
+// a rejected request shows the provider is up.
+//
+// Example:
+//
+//	client := &AnthropicClient{Provider: NewCircuitBreaker(&OpenAIProvider{APIKey: key})}
+type CircuitBreaker struct {
+	Provider  LLMProvider
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// NewCircuitBreaker wraps provider in a breaker that opens after five
+// consecutive failures and tries again after thirty seconds
+func NewCircuitBreaker(provider LLMProvider) *CircuitBreaker {
+	return &CircuitBreaker{
+		Provider:  provider,
+		Threshold: 5,
+		Cooldown:  30 * time.Second,
+		state:     BreakerClosed,
+	}
+}
+
+// State returns the breaker's state
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.Cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Name implements LLMProvider
+func (b *CircuitBreaker) Name() string {
+	return b.Provider.Name()
+}
+
+// Send implements LLMProvider
+func (b *CircuitBreaker) Send(ctx context.Context, req MessageRequest) (string, Usage, error) {
+	if err := b.allow(ctx); err != nil {
+		return "", Usage{}, err
+	}
+	text, usage, err := b.Provider.Send(ctx, req)
+	b.record(ctx, err)
+	return text, usage, err
+}
+
+// SendStream implements LLMProvider
+func (b *CircuitBreaker) SendStream(ctx context.Context, req MessageRequest, onDelta func(text string)) (string, Usage, error) {
+	if err := b.allow(ctx); err != nil {
+		return "", Usage{}, err
+	}
+	text, usage, err := b.Provider.SendStream(ctx, req, onDelta)
+	b.record(ctx, err)
+	return text, usage, err
+}
+
+// allow admits a request, or returns ErrCircuitOpen
+func (b *CircuitBreaker) allow(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.Cooldown {
+		b.transition(ctx, BreakerHalfOpen)
+	}
+	switch {
+	case b.state == BreakerOpen, b.state == BreakerHalfOpen && b.trial:
+		return fmt.Errorf("%w: %s", ErrCircuitOpen, b.Provider.Name())
+	case b.state == BreakerHalfOpen:
+		b.trial = true
+	}
+	return nil
+}
+
+// record counts a finished request towards opening or closing the breaker
+func (b *CircuitBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen {
+		b.trial = false
+	}
+	// A cancelled request says nothing about the provider
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	if !isRetryableError(err) {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.transition(ctx, BreakerClosed)
+		}
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.Threshold {
+		b.openedAt = time.Now()
+		if b.state != BreakerOpen {
+			b.transition(ctx, BreakerOpen)
+		}
+	}
+}
+
+func (b *CircuitBreaker) transition(ctx context.Context, to BreakerState) {
+	publish(ctx, "client", PhaseCircuitChanged, map[string]interface{}{
+		"provider": b.Provider.Name(),
+		"from":     string(b.state),
+		"to":       string(to),
+		"failures": b.failures,
+	})
+	b.state = to
+}
+
+// FailoverTarget is a provider to try, optionally with its own model
+type FailoverTarget struct {
+	Provider LLMProvider
+	// Model, if set, replaces the requested model, e.g. a smaller model
+	// on the same provider
+	Model string
+}
+
+// Failover serves each request from the first of its targets that
+// succeeds, moving on when a target fails with an error worth retrying or
+// its circuit breaker is open. Other errors, such as an invalid request,
+// are returned at once. A stream that has already delivered text is not
+// restarted elsewhere.
+//
+// Patterns record usage at the prices of the model they requested, so
+// costs are approximate while a fallback model serves them.
+//
+// Example:
+//
+//	anthropic := NewClientFromConfig(cfg)
+//	openai := &OpenAIProvider{APIKey: os.Getenv("OPENAI_API_KEY"), Model: "gpt-4o"}
+//	client := &AnthropicClient{Provider: NewFailover(
+//	    FailoverTarget{Provider: NewCircuitBreaker(anthropic)},
+//	    FailoverTarget{Provider: NewCircuitBreaker(anthropic), Model: "claude-3-5-haiku-20241022"},
+//	    FailoverTarget{Provider: NewCircuitBreaker(openai)},
+//	)}
+type Failover struct {
+	targets []FailoverTarget
+}
+
+// NewFailover creates a provider trying targets in order
+func NewFailover(targets ...FailoverTarget) *Failover {
+	return &Failover{targets: targets}
+}
+
+// Name implements LLMProvider
+func (f *Failover) Name() string {
+	return "failover"
+}
+
+// Send implements LLMProvider
+func (f *Failover) Send(ctx context.Context, req MessageRequest) (string, Usage, error) {
+	return f.try(ctx, req, func(target FailoverTarget, req MessageRequest) (string, Usage, error) {
+		return target.Provider.Send(ctx, req)
+	})
+}
+
+// SendStream implements LLMProvider
+func (f *Failover) SendStream(ctx context.Context, req MessageRequest, onDelta func(text string)) (string, Usage, error) {
+	return f.try(ctx, req, func(target FailoverTarget, req MessageRequest) (string, Usage, error) {
+		streamed := false
+		text, usage, err := target.Provider.SendStream(ctx, req, func(text string) {
+			streamed = true
+			if onDelta != nil {
+				onDelta(text)
+			}
+		})
+		if err != nil && streamed {
+			err = &streamInterrupted{err}
+		}
+		return text, usage, err
+	})
+}
+
+func (f *Failover) try(ctx context.Context, req MessageRequest, send func(target FailoverTarget, req MessageRequest) (string, Usage, error)) (string, Usage, error) {
+	if len(f.targets) == 0 {
+		return "", Usage{}, fmt.Errorf("failover has no providers")
+	}
+	var lastErr error
+	for i, target := range f.targets {
+		targetReq := req
+		if target.Model != "" {
+			targetReq.Model = target.Model
+		}
+		text, usage, err := send(target, targetReq)
+		var interrupted *streamInterrupted
+		if errors.As(err, &interrupted) {
+			return text, usage, interrupted.err
+		}
+		if err == nil || !(errors.Is(err, ErrCircuitOpen) || isRetryableError(err)) {
+			return text, usage, err
+		}
+		lastErr = err
+		if i+1 < len(f.targets) {
+			next, nextModel := f.targets[i+1], req.Model
+			if next.Model != "" {
+				nextModel = next.Model
+			}
+			publish(ctx, "client", PhaseFailover, map[string]interface{}{
+				"from":  target.Provider.Name() + "/" + targetReq.Model,
+				"to":    next.Provider.Name() + "/" + nextModel,
+				"error": err,
+			})
+		}
+	}
+	return "", Usage{}, fmt.Errorf("all %d providers failed: %w", len(f.targets), lastErr)
+}
+
+// streamInterrupted marks a stream that failed after delivering text
+type streamInterrupted struct {
+	err error
+}
+
+func (e *streamInterrupted) Error() string {
+	return e.err.Error()
+}
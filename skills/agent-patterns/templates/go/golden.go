@@ -0,0 +1,198 @@
+/*
+ * Golden Files for Go Agent Patterns
+ * Record real API exchanges once and replay them in CI without an API key
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// EnvRecordGolden, when set to any value, makes NewGoldenProvider record
+// instead of replay
+const EnvRecordGolden = "AGENTPATTERNS_RECORD"
+
+// goldenVersion is the golden file format written by this package
+const goldenVersion = 1
+
+// GoldenExchange is one request and the reply it received
+type GoldenExchange struct {
+	Key     string         `json:"key"`
+	Request MessageRequest `json:"request"`
+	Text    string         `json:"text"`
+	Usage   Usage          `json:"usage"`
+}
+
+// goldenFile is the on-disk form of a recording
+type goldenFile struct {
+	Version   int              `json:"version"`
+	Exchanges []GoldenExchange `json:"exchanges"`
+}
+
+// GoldenProvider is an LLMProvider that records exchanges with a live
+// provider to a golden file, or replays them from it. Requests are matched
+// by content, so concurrent patterns replay correctly whatever order their
+// calls arrive in; identical requests are served their recordings in turn,
+// the last one repeating. A request with no recording fails with
+// ErrNotRecorded. Streaming replays deliver the whole text as one delta.
+//
+// Recording replaces the file, which is rewritten after every exchange, so
+// a failing run still leaves what it received. Errors are not recorded.
+//
+// Example:
+//
+//	// AGENTPATTERNS_RECORD=1 go test ./... refreshes testdata/chain.json
+//	golden := NewGoldenProvider("testdata/chain.json", NewClientFromConfig(cfg))
+//	chain := NewPromptChain(&AnthropicClient{Provider: golden})
+type GoldenProvider struct {
+	// Path is the golden file
+	Path string
+	// Live serves requests while recording
+	Live LLMProvider
+	// Record sends requests to Live and saves the exchanges; otherwise
+	// they are served from Path
+	Record bool
+
+	mu        sync.Mutex
+	loaded    bool
+	exchanges []GoldenExchange
+	byKey     map[string][]int
+	served    map[string]int
+}
+
+// NewGoldenProvider creates a provider for the golden file at path,
+// recording through live when the AGENTPATTERNS_RECORD environment
+// variable is set
+func NewGoldenProvider(path string, live LLMProvider) *GoldenProvider {
+	return &GoldenProvider{Path: path, Live: live, Record: os.Getenv(EnvRecordGolden) != ""}
+}
+
+// Name implements LLMProvider. It is the live provider's name, so limits
+// apply the same way whether recording or replaying.
+func (g *GoldenProvider) Name() string {
+	if g.Live != nil {
+		return g.Live.Name()
+	}
+	return "golden"
+}
+
+// Send implements LLMProvider
+func (g *GoldenProvider) Send(ctx context.Context, req MessageRequest) (string, Usage, error) {
+	return g.exchange(req, nil, func() (string, Usage, error) {
+		return g.Live.Send(ctx, req)
+	})
+}
+
+// SendStream implements LLMProvider
+func (g *GoldenProvider) SendStream(ctx context.Context, req MessageRequest, onDelta func(text string)) (string, Usage, error) {
+	return g.exchange(req, onDelta, func() (string, Usage, error) {
+		return g.Live.SendStream(ctx, req, onDelta)
+	})
+}
+
+// Exchanges returns a copy of the exchanges recorded or loaded so far
+func (g *GoldenProvider) Exchanges() []GoldenExchange {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]GoldenExchange(nil), g.exchanges...)
+}
+
+// exchange records the reply from live, or replays the recorded one
+func (g *GoldenProvider) exchange(req MessageRequest, onDelta func(text string), live func() (string, Usage, error)) (string, Usage, error) {
+	key, err := goldenKey(req)
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	if g.Record {
+		if g.Live == nil {
+			return "", Usage{}, errors.New("golden provider has no live provider to record from")
+		}
+		text, usage, err := live()
+		if err != nil {
+			return text, usage, err
+		}
+		req.Stream = false
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.exchanges = append(g.exchanges, GoldenExchange{Key: key, Request: req, Text: text, Usage: usage})
+		return text, usage, g.save()
+	}
+
+	g.mu.Lock()
+	if err := g.load(); err != nil {
+		g.mu.Unlock()
+		return "", Usage{}, err
+	}
+	recorded := g.byKey[key]
+	if len(recorded) == 0 {
+		g.mu.Unlock()
+		return "", Usage{}, fmt.Errorf("%w: request %s in %s (set %s to record it)", ErrNotRecorded, key[:12], g.Path, EnvRecordGolden)
+	}
+	n := g.served[key]
+	if n >= len(recorded) {
+		n = len(recorded) - 1
+	}
+	g.served[key]++
+	hit := g.exchanges[recorded[n]]
+	g.mu.Unlock()
+
+	if onDelta != nil {
+		onDelta(hit.Text)
+	}
+	return hit.Text, hit.Usage, nil
+}
+
+// load reads the golden file on first use. The caller must hold g.mu.
+func (g *GoldenProvider) load() error {
+	if g.loaded {
+		return nil
+	}
+	data, err := os.ReadFile(g.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read golden file (set %s to record it): %w", EnvRecordGolden, err)
+	}
+	var file goldenFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to decode golden file: %w", err)
+	}
+	if file.Version != goldenVersion {
+		return fmt.Errorf("unsupported golden file version %d", file.Version)
+	}
+	g.exchanges = file.Exchanges
+	g.byKey = make(map[string][]int)
+	g.served = make(map[string]int)
+	for i, ex := range g.exchanges {
+		g.byKey[ex.Key] = append(g.byKey[ex.Key], i)
+	}
+	g.loaded = true
+	return nil
+}
+
+// save writes the exchanges recorded so far. The caller must hold g.mu.
+func (g *GoldenProvider) save() error {
+	data, err := json.MarshalIndent(goldenFile{Version: goldenVersion, Exchanges: g.exchanges}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(g.Path, append(data, '\n'), 0o644)
+}
+
+// goldenKey identifies a request by its content, streamed or not
+func goldenKey(req MessageRequest) (string, error) {
+	req.Stream = false
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
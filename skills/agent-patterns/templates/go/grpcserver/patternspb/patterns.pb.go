@@ -0,0 +1,1113 @@
+// Pattern execution service for the Go agent pattern templates.
+//
+// Each RPC runs one pattern and streams its lifecycle events as Progress
+// messages, followed by exactly one Result. Failures end the stream with a
+// gRPC status: INVALID_ARGUMENT for bad requests, RESOURCE_EXHAUSTED when
+// the run budget is exceeded, and INTERNAL for pattern errors.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: agentpatterns/v1/patterns.proto
+
+package patternspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// RunOptions override the server's settings for one run
+type RunOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Model ID or alias from the server's settings file
+	Model     string `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	MaxTokens int32  `protobuf:"varint,2,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
+	// Maximum LLM calls for the run (0 = server default)
+	MaxCalls int32 `protobuf:"varint,3,opt,name=max_calls,json=maxCalls,proto3" json:"max_calls,omitempty"`
+	// Maximum wall-clock time for the run in milliseconds (0 = server default)
+	MaxDurationMs int64 `protobuf:"varint,4,opt,name=max_duration_ms,json=maxDurationMs,proto3" json:"max_duration_ms,omitempty"`
+	// Run ID for tracing and resuming; generated if empty
+	RunId         string `protobuf:"bytes,5,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunOptions) Reset() {
+	*x = RunOptions{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunOptions) ProtoMessage() {}
+
+func (x *RunOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunOptions.ProtoReflect.Descriptor instead.
+func (*RunOptions) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RunOptions) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *RunOptions) GetMaxTokens() int32 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *RunOptions) GetMaxCalls() int32 {
+	if x != nil {
+		return x.MaxCalls
+	}
+	return 0
+}
+
+func (x *RunOptions) GetMaxDurationMs() int64 {
+	if x != nil {
+		return x.MaxDurationMs
+	}
+	return 0
+}
+
+func (x *RunOptions) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+// ChainStep is one step of a prompt chain. The prompt is a Go template over
+// the chain context: {{.input}} plus the output of every earlier step by name.
+type ChainStep struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Name   string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Prompt string                 `protobuf:"bytes,2,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	// Substrings the output must contain to pass validation
+	Require []string `protobuf:"bytes,3,rep,name=require,proto3" json:"require,omitempty"`
+	// Minimum word count for the output to pass validation
+	MinWords      int32 `protobuf:"varint,4,opt,name=min_words,json=minWords,proto3" json:"min_words,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChainStep) Reset() {
+	*x = ChainStep{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChainStep) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChainStep) ProtoMessage() {}
+
+func (x *ChainStep) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChainStep.ProtoReflect.Descriptor instead.
+func (*ChainStep) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ChainStep) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ChainStep) GetPrompt() string {
+	if x != nil {
+		return x.Prompt
+	}
+	return ""
+}
+
+func (x *ChainStep) GetRequire() []string {
+	if x != nil {
+		return x.Require
+	}
+	return nil
+}
+
+func (x *ChainStep) GetMinWords() int32 {
+	if x != nil {
+		return x.MinWords
+	}
+	return 0
+}
+
+type RunChainRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Options       *RunOptions            `protobuf:"bytes,1,opt,name=options,proto3" json:"options,omitempty"`
+	Steps         []*ChainStep           `protobuf:"bytes,2,rep,name=steps,proto3" json:"steps,omitempty"`
+	Input         string                 `protobuf:"bytes,3,opt,name=input,proto3" json:"input,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunChainRequest) Reset() {
+	*x = RunChainRequest{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunChainRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunChainRequest) ProtoMessage() {}
+
+func (x *RunChainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunChainRequest.ProtoReflect.Descriptor instead.
+func (*RunChainRequest) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RunChainRequest) GetOptions() *RunOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *RunChainRequest) GetSteps() []*ChainStep {
+	if x != nil {
+		return x.Steps
+	}
+	return nil
+}
+
+func (x *RunChainRequest) GetInput() string {
+	if x != nil {
+		return x.Input
+	}
+	return ""
+}
+
+// Route is a category the router can choose. The prompt is a Go template
+// over {{.input}}.
+type Route struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Category    string                 `protobuf:"bytes,1,opt,name=category,proto3" json:"category,omitempty"`
+	Description string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Prompt      string                 `protobuf:"bytes,3,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	// Model ID or alias; defaults to the run's model
+	Model         string `protobuf:"bytes,4,opt,name=model,proto3" json:"model,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Route) Reset() {
+	*x = Route{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Route) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Route) ProtoMessage() {}
+
+func (x *Route) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Route.ProtoReflect.Descriptor instead.
+func (*Route) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Route) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *Route) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Route) GetPrompt() string {
+	if x != nil {
+		return x.Prompt
+	}
+	return ""
+}
+
+func (x *Route) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+type RouteRequestRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Options *RunOptions            `protobuf:"bytes,1,opt,name=options,proto3" json:"options,omitempty"`
+	Routes  []*Route               `protobuf:"bytes,2,rep,name=routes,proto3" json:"routes,omitempty"`
+	Input   string                 `protobuf:"bytes,3,opt,name=input,proto3" json:"input,omitempty"`
+	// Classifications below this confidence go to the fallback
+	ConfidenceThreshold float64 `protobuf:"fixed64,4,opt,name=confidence_threshold,json=confidenceThreshold,proto3" json:"confidence_threshold,omitempty"`
+	// Prompt for low-confidence or unknown categories; without one they fail
+	FallbackPrompt string `protobuf:"bytes,5,opt,name=fallback_prompt,json=fallbackPrompt,proto3" json:"fallback_prompt,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RouteRequestRequest) Reset() {
+	*x = RouteRequestRequest{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RouteRequestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RouteRequestRequest) ProtoMessage() {}
+
+func (x *RouteRequestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RouteRequestRequest.ProtoReflect.Descriptor instead.
+func (*RouteRequestRequest) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RouteRequestRequest) GetOptions() *RunOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *RouteRequestRequest) GetRoutes() []*Route {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+func (x *RouteRequestRequest) GetInput() string {
+	if x != nil {
+		return x.Input
+	}
+	return ""
+}
+
+func (x *RouteRequestRequest) GetConfidenceThreshold() float64 {
+	if x != nil {
+		return x.ConfidenceThreshold
+	}
+	return 0
+}
+
+func (x *RouteRequestRequest) GetFallbackPrompt() string {
+	if x != nil {
+		return x.FallbackPrompt
+	}
+	return ""
+}
+
+// Worker is an LLM worker available to the orchestrator
+type Worker struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Type         string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	SystemPrompt string                 `protobuf:"bytes,2,opt,name=system_prompt,json=systemPrompt,proto3" json:"system_prompt,omitempty"`
+	// Model ID or alias; defaults to the run's model
+	Model         string `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Worker) Reset() {
+	*x = Worker{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Worker) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Worker) ProtoMessage() {}
+
+func (x *Worker) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Worker.ProtoReflect.Descriptor instead.
+func (*Worker) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Worker) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Worker) GetSystemPrompt() string {
+	if x != nil {
+		return x.SystemPrompt
+	}
+	return ""
+}
+
+func (x *Worker) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+type RunOrchestratorRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Options       *RunOptions            `protobuf:"bytes,1,opt,name=options,proto3" json:"options,omitempty"`
+	Workers       []*Worker              `protobuf:"bytes,2,rep,name=workers,proto3" json:"workers,omitempty"`
+	Task          string                 `protobuf:"bytes,3,opt,name=task,proto3" json:"task,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunOrchestratorRequest) Reset() {
+	*x = RunOrchestratorRequest{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunOrchestratorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunOrchestratorRequest) ProtoMessage() {}
+
+func (x *RunOrchestratorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunOrchestratorRequest.ProtoReflect.Descriptor instead.
+func (*RunOrchestratorRequest) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RunOrchestratorRequest) GetOptions() *RunOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *RunOrchestratorRequest) GetWorkers() []*Worker {
+	if x != nil {
+		return x.Workers
+	}
+	return nil
+}
+
+func (x *RunOrchestratorRequest) GetTask() string {
+	if x != nil {
+		return x.Task
+	}
+	return ""
+}
+
+type RunAgentRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Options *RunOptions            `protobuf:"bytes,1,opt,name=options,proto3" json:"options,omitempty"`
+	Task    string                 `protobuf:"bytes,2,opt,name=task,proto3" json:"task,omitempty"`
+	// Maximum agent steps (default 10)
+	MaxSteps      int32 `protobuf:"varint,3,opt,name=max_steps,json=maxSteps,proto3" json:"max_steps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunAgentRequest) Reset() {
+	*x = RunAgentRequest{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunAgentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunAgentRequest) ProtoMessage() {}
+
+func (x *RunAgentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunAgentRequest.ProtoReflect.Descriptor instead.
+func (*RunAgentRequest) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RunAgentRequest) GetOptions() *RunOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *RunAgentRequest) GetTask() string {
+	if x != nil {
+		return x.Task
+	}
+	return ""
+}
+
+func (x *RunAgentRequest) GetMaxSteps() int32 {
+	if x != nil {
+		return x.MaxSteps
+	}
+	return 0
+}
+
+// RunEvent is one message of a run's stream
+type RunEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	RunId string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*RunEvent_Progress
+	//	*RunEvent_Result
+	Event         isRunEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunEvent) Reset() {
+	*x = RunEvent{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunEvent) ProtoMessage() {}
+
+func (x *RunEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunEvent.ProtoReflect.Descriptor instead.
+func (*RunEvent) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RunEvent) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *RunEvent) GetEvent() isRunEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *RunEvent) GetProgress() *Progress {
+	if x != nil {
+		if x, ok := x.Event.(*RunEvent_Progress); ok {
+			return x.Progress
+		}
+	}
+	return nil
+}
+
+func (x *RunEvent) GetResult() *Result {
+	if x != nil {
+		if x, ok := x.Event.(*RunEvent_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isRunEvent_Event interface {
+	isRunEvent_Event()
+}
+
+type RunEvent_Progress struct {
+	Progress *Progress `protobuf:"bytes,2,opt,name=progress,proto3,oneof"`
+}
+
+type RunEvent_Result struct {
+	Result *Result `protobuf:"bytes,3,opt,name=result,proto3,oneof"`
+}
+
+func (*RunEvent_Progress) isRunEvent_Event() {}
+
+func (*RunEvent_Result) isRunEvent_Event() {}
+
+// Progress is a pattern lifecycle event, such as a finished chain step or
+// tool call. Payload values are rendered as strings.
+type Progress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pattern       string                 `protobuf:"bytes,1,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	Phase         string                 `protobuf:"bytes,2,opt,name=phase,proto3" json:"phase,omitempty"`
+	SpanId        string                 `protobuf:"bytes,3,opt,name=span_id,json=spanId,proto3" json:"span_id,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=time,proto3" json:"time,omitempty"`
+	Payload       map[string]string      `protobuf:"bytes,5,rep,name=payload,proto3" json:"payload,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Progress) Reset() {
+	*x = Progress{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Progress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Progress) ProtoMessage() {}
+
+func (x *Progress) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Progress.ProtoReflect.Descriptor instead.
+func (*Progress) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Progress) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *Progress) GetPhase() string {
+	if x != nil {
+		return x.Phase
+	}
+	return ""
+}
+
+func (x *Progress) GetSpanId() string {
+	if x != nil {
+		return x.SpanId
+	}
+	return ""
+}
+
+func (x *Progress) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Progress) GetPayload() map[string]string {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+// Result is the final output of a run
+type Result struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Output    string                 `protobuf:"bytes,1,opt,name=output,proto3" json:"output,omitempty"`
+	Cost      *Cost                  `protobuf:"bytes,2,opt,name=cost,proto3" json:"cost,omitempty"`
+	ElapsedMs int64                  `protobuf:"varint,3,opt,name=elapsed_ms,json=elapsedMs,proto3" json:"elapsed_ms,omitempty"`
+	// Set by RouteRequest
+	Classification *Classification `protobuf:"bytes,4,opt,name=classification,proto3" json:"classification,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Result) Reset() {
+	*x = Result{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Result) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Result) ProtoMessage() {}
+
+func (x *Result) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Result.ProtoReflect.Descriptor instead.
+func (*Result) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Result) GetOutput() string {
+	if x != nil {
+		return x.Output
+	}
+	return ""
+}
+
+func (x *Result) GetCost() *Cost {
+	if x != nil {
+		return x.Cost
+	}
+	return nil
+}
+
+func (x *Result) GetElapsedMs() int64 {
+	if x != nil {
+		return x.ElapsedMs
+	}
+	return 0
+}
+
+func (x *Result) GetClassification() *Classification {
+	if x != nil {
+		return x.Classification
+	}
+	return nil
+}
+
+// Classification is the router's choice of route
+type Classification struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Category      string                 `protobuf:"bytes,1,opt,name=category,proto3" json:"category,omitempty"`
+	Confidence    float64                `protobuf:"fixed64,2,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	Reasoning     string                 `protobuf:"bytes,3,opt,name=reasoning,proto3" json:"reasoning,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Classification) Reset() {
+	*x = Classification{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Classification) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Classification) ProtoMessage() {}
+
+func (x *Classification) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Classification.ProtoReflect.Descriptor instead.
+func (*Classification) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Classification) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *Classification) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *Classification) GetReasoning() string {
+	if x != nil {
+		return x.Reasoning
+	}
+	return ""
+}
+
+// Cost is the token usage and price of a run
+type Cost struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Calls         int32                  `protobuf:"varint,1,opt,name=calls,proto3" json:"calls,omitempty"`
+	InputTokens   int64                  `protobuf:"varint,2,opt,name=input_tokens,json=inputTokens,proto3" json:"input_tokens,omitempty"`
+	OutputTokens  int64                  `protobuf:"varint,3,opt,name=output_tokens,json=outputTokens,proto3" json:"output_tokens,omitempty"`
+	CostUsd       float64                `protobuf:"fixed64,4,opt,name=cost_usd,json=costUsd,proto3" json:"cost_usd,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Cost) Reset() {
+	*x = Cost{}
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cost) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cost) ProtoMessage() {}
+
+func (x *Cost) ProtoReflect() protoreflect.Message {
+	mi := &file_agentpatterns_v1_patterns_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cost.ProtoReflect.Descriptor instead.
+func (*Cost) Descriptor() ([]byte, []int) {
+	return file_agentpatterns_v1_patterns_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Cost) GetCalls() int32 {
+	if x != nil {
+		return x.Calls
+	}
+	return 0
+}
+
+func (x *Cost) GetInputTokens() int64 {
+	if x != nil {
+		return x.InputTokens
+	}
+	return 0
+}
+
+func (x *Cost) GetOutputTokens() int64 {
+	if x != nil {
+		return x.OutputTokens
+	}
+	return 0
+}
+
+func (x *Cost) GetCostUsd() float64 {
+	if x != nil {
+		return x.CostUsd
+	}
+	return 0
+}
+
+var File_agentpatterns_v1_patterns_proto protoreflect.FileDescriptor
+
+const file_agentpatterns_v1_patterns_proto_rawDesc = "" +
+	"\n" +
+	"\x1fagentpatterns/v1/patterns.proto\x12\x10agentpatterns.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9d\x01\n" +
+	"\n" +
+	"RunOptions\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x02 \x01(\x05R\tmaxTokens\x12\x1b\n" +
+	"\tmax_calls\x18\x03 \x01(\x05R\bmaxCalls\x12&\n" +
+	"\x0fmax_duration_ms\x18\x04 \x01(\x03R\rmaxDurationMs\x12\x15\n" +
+	"\x06run_id\x18\x05 \x01(\tR\x05runId\"n\n" +
+	"\tChainStep\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06prompt\x18\x02 \x01(\tR\x06prompt\x12\x18\n" +
+	"\arequire\x18\x03 \x03(\tR\arequire\x12\x1b\n" +
+	"\tmin_words\x18\x04 \x01(\x05R\bminWords\"\x92\x01\n" +
+	"\x0fRunChainRequest\x126\n" +
+	"\aoptions\x18\x01 \x01(\v2\x1c.agentpatterns.v1.RunOptionsR\aoptions\x121\n" +
+	"\x05steps\x18\x02 \x03(\v2\x1b.agentpatterns.v1.ChainStepR\x05steps\x12\x14\n" +
+	"\x05input\x18\x03 \x01(\tR\x05input\"s\n" +
+	"\x05Route\x12\x1a\n" +
+	"\bcategory\x18\x01 \x01(\tR\bcategory\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x16\n" +
+	"\x06prompt\x18\x03 \x01(\tR\x06prompt\x12\x14\n" +
+	"\x05model\x18\x04 \x01(\tR\x05model\"\xf0\x01\n" +
+	"\x13RouteRequestRequest\x126\n" +
+	"\aoptions\x18\x01 \x01(\v2\x1c.agentpatterns.v1.RunOptionsR\aoptions\x12/\n" +
+	"\x06routes\x18\x02 \x03(\v2\x17.agentpatterns.v1.RouteR\x06routes\x12\x14\n" +
+	"\x05input\x18\x03 \x01(\tR\x05input\x121\n" +
+	"\x14confidence_threshold\x18\x04 \x01(\x01R\x13confidenceThreshold\x12'\n" +
+	"\x0ffallback_prompt\x18\x05 \x01(\tR\x0efallbackPrompt\"W\n" +
+	"\x06Worker\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12#\n" +
+	"\rsystem_prompt\x18\x02 \x01(\tR\fsystemPrompt\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\"\x98\x01\n" +
+	"\x16RunOrchestratorRequest\x126\n" +
+	"\aoptions\x18\x01 \x01(\v2\x1c.agentpatterns.v1.RunOptionsR\aoptions\x122\n" +
+	"\aworkers\x18\x02 \x03(\v2\x18.agentpatterns.v1.WorkerR\aworkers\x12\x12\n" +
+	"\x04task\x18\x03 \x01(\tR\x04task\"z\n" +
+	"\x0fRunAgentRequest\x126\n" +
+	"\aoptions\x18\x01 \x01(\v2\x1c.agentpatterns.v1.RunOptionsR\aoptions\x12\x12\n" +
+	"\x04task\x18\x02 \x01(\tR\x04task\x12\x1b\n" +
+	"\tmax_steps\x18\x03 \x01(\x05R\bmaxSteps\"\x98\x01\n" +
+	"\bRunEvent\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\x128\n" +
+	"\bprogress\x18\x02 \x01(\v2\x1a.agentpatterns.v1.ProgressH\x00R\bprogress\x122\n" +
+	"\x06result\x18\x03 \x01(\v2\x18.agentpatterns.v1.ResultH\x00R\x06resultB\a\n" +
+	"\x05event\"\x82\x02\n" +
+	"\bProgress\x12\x18\n" +
+	"\apattern\x18\x01 \x01(\tR\apattern\x12\x14\n" +
+	"\x05phase\x18\x02 \x01(\tR\x05phase\x12\x17\n" +
+	"\aspan_id\x18\x03 \x01(\tR\x06spanId\x12.\n" +
+	"\x04time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12A\n" +
+	"\apayload\x18\x05 \x03(\v2'.agentpatterns.v1.Progress.PayloadEntryR\apayload\x1a:\n" +
+	"\fPayloadEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xb5\x01\n" +
+	"\x06Result\x12\x16\n" +
+	"\x06output\x18\x01 \x01(\tR\x06output\x12*\n" +
+	"\x04cost\x18\x02 \x01(\v2\x16.agentpatterns.v1.CostR\x04cost\x12\x1d\n" +
+	"\n" +
+	"elapsed_ms\x18\x03 \x01(\x03R\telapsedMs\x12H\n" +
+	"\x0eclassification\x18\x04 \x01(\v2 .agentpatterns.v1.ClassificationR\x0eclassification\"j\n" +
+	"\x0eClassification\x12\x1a\n" +
+	"\bcategory\x18\x01 \x01(\tR\bcategory\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\x02 \x01(\x01R\n" +
+	"confidence\x12\x1c\n" +
+	"\treasoning\x18\x03 \x01(\tR\treasoning\"\x7f\n" +
+	"\x04Cost\x12\x14\n" +
+	"\x05calls\x18\x01 \x01(\x05R\x05calls\x12!\n" +
+	"\finput_tokens\x18\x02 \x01(\x03R\vinputTokens\x12#\n" +
+	"\routput_tokens\x18\x03 \x01(\x03R\foutputTokens\x12\x19\n" +
+	"\bcost_usd\x18\x04 \x01(\x01R\acostUsd2\xda\x02\n" +
+	"\x0ePatternService\x12K\n" +
+	"\bRunChain\x12!.agentpatterns.v1.RunChainRequest\x1a\x1a.agentpatterns.v1.RunEvent0\x01\x12S\n" +
+	"\fRouteRequest\x12%.agentpatterns.v1.RouteRequestRequest\x1a\x1a.agentpatterns.v1.RunEvent0\x01\x12Y\n" +
+	"\x0fRunOrchestrator\x12(.agentpatterns.v1.RunOrchestratorRequest\x1a\x1a.agentpatterns.v1.RunEvent0\x01\x12K\n" +
+	"\bRunAgent\x12!.agentpatterns.v1.RunAgentRequest\x1a\x1a.agentpatterns.v1.RunEvent0\x01B\\ZZgithub.com/markpitt/claude-skills/skills/agent-patterns/templates/go/grpcserver/patternspbb\x06proto3"
+
+var (
+	file_agentpatterns_v1_patterns_proto_rawDescOnce sync.Once
+	file_agentpatterns_v1_patterns_proto_rawDescData []byte
+)
+
+func file_agentpatterns_v1_patterns_proto_rawDescGZIP() []byte {
+	file_agentpatterns_v1_patterns_proto_rawDescOnce.Do(func() {
+		file_agentpatterns_v1_patterns_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_agentpatterns_v1_patterns_proto_rawDesc), len(file_agentpatterns_v1_patterns_proto_rawDesc)))
+	})
+	return file_agentpatterns_v1_patterns_proto_rawDescData
+}
+
+var file_agentpatterns_v1_patterns_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_agentpatterns_v1_patterns_proto_goTypes = []any{
+	(*RunOptions)(nil),             // 0: agentpatterns.v1.RunOptions
+	(*ChainStep)(nil),              // 1: agentpatterns.v1.ChainStep
+	(*RunChainRequest)(nil),        // 2: agentpatterns.v1.RunChainRequest
+	(*Route)(nil),                  // 3: agentpatterns.v1.Route
+	(*RouteRequestRequest)(nil),    // 4: agentpatterns.v1.RouteRequestRequest
+	(*Worker)(nil),                 // 5: agentpatterns.v1.Worker
+	(*RunOrchestratorRequest)(nil), // 6: agentpatterns.v1.RunOrchestratorRequest
+	(*RunAgentRequest)(nil),        // 7: agentpatterns.v1.RunAgentRequest
+	(*RunEvent)(nil),               // 8: agentpatterns.v1.RunEvent
+	(*Progress)(nil),               // 9: agentpatterns.v1.Progress
+	(*Result)(nil),                 // 10: agentpatterns.v1.Result
+	(*Classification)(nil),         // 11: agentpatterns.v1.Classification
+	(*Cost)(nil),                   // 12: agentpatterns.v1.Cost
+	nil,                            // 13: agentpatterns.v1.Progress.PayloadEntry
+	(*timestamppb.Timestamp)(nil),  // 14: google.protobuf.Timestamp
+}
+var file_agentpatterns_v1_patterns_proto_depIdxs = []int32{
+	0,  // 0: agentpatterns.v1.RunChainRequest.options:type_name -> agentpatterns.v1.RunOptions
+	1,  // 1: agentpatterns.v1.RunChainRequest.steps:type_name -> agentpatterns.v1.ChainStep
+	0,  // 2: agentpatterns.v1.RouteRequestRequest.options:type_name -> agentpatterns.v1.RunOptions
+	3,  // 3: agentpatterns.v1.RouteRequestRequest.routes:type_name -> agentpatterns.v1.Route
+	0,  // 4: agentpatterns.v1.RunOrchestratorRequest.options:type_name -> agentpatterns.v1.RunOptions
+	5,  // 5: agentpatterns.v1.RunOrchestratorRequest.workers:type_name -> agentpatterns.v1.Worker
+	0,  // 6: agentpatterns.v1.RunAgentRequest.options:type_name -> agentpatterns.v1.RunOptions
+	9,  // 7: agentpatterns.v1.RunEvent.progress:type_name -> agentpatterns.v1.Progress
+	10, // 8: agentpatterns.v1.RunEvent.result:type_name -> agentpatterns.v1.Result
+	14, // 9: agentpatterns.v1.Progress.time:type_name -> google.protobuf.Timestamp
+	13, // 10: agentpatterns.v1.Progress.payload:type_name -> agentpatterns.v1.Progress.PayloadEntry
+	12, // 11: agentpatterns.v1.Result.cost:type_name -> agentpatterns.v1.Cost
+	11, // 12: agentpatterns.v1.Result.classification:type_name -> agentpatterns.v1.Classification
+	2,  // 13: agentpatterns.v1.PatternService.RunChain:input_type -> agentpatterns.v1.RunChainRequest
+	4,  // 14: agentpatterns.v1.PatternService.RouteRequest:input_type -> agentpatterns.v1.RouteRequestRequest
+	6,  // 15: agentpatterns.v1.PatternService.RunOrchestrator:input_type -> agentpatterns.v1.RunOrchestratorRequest
+	7,  // 16: agentpatterns.v1.PatternService.RunAgent:input_type -> agentpatterns.v1.RunAgentRequest
+	8,  // 17: agentpatterns.v1.PatternService.RunChain:output_type -> agentpatterns.v1.RunEvent
+	8,  // 18: agentpatterns.v1.PatternService.RouteRequest:output_type -> agentpatterns.v1.RunEvent
+	8,  // 19: agentpatterns.v1.PatternService.RunOrchestrator:output_type -> agentpatterns.v1.RunEvent
+	8,  // 20: agentpatterns.v1.PatternService.RunAgent:output_type -> agentpatterns.v1.RunEvent
+	17, // [17:21] is the sub-list for method output_type
+	13, // [13:17] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_agentpatterns_v1_patterns_proto_init() }
+func file_agentpatterns_v1_patterns_proto_init() {
+	if File_agentpatterns_v1_patterns_proto != nil {
+		return
+	}
+	file_agentpatterns_v1_patterns_proto_msgTypes[8].OneofWrappers = []any{
+		(*RunEvent_Progress)(nil),
+		(*RunEvent_Result)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_agentpatterns_v1_patterns_proto_rawDesc), len(file_agentpatterns_v1_patterns_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_agentpatterns_v1_patterns_proto_goTypes,
+		DependencyIndexes: file_agentpatterns_v1_patterns_proto_depIdxs,
+		MessageInfos:      file_agentpatterns_v1_patterns_proto_msgTypes,
+	}.Build()
+	File_agentpatterns_v1_patterns_proto = out.File
+	file_agentpatterns_v1_patterns_proto_goTypes = nil
+	file_agentpatterns_v1_patterns_proto_depIdxs = nil
+}
@@ -0,0 +1,266 @@
+// Pattern execution service for the Go agent pattern templates.
+//
+// Each RPC runs one pattern and streams its lifecycle events as Progress
+// messages, followed by exactly one Result. Failures end the stream with a
+// gRPC status: INVALID_ARGUMENT for bad requests, RESOURCE_EXHAUSTED when
+// the run budget is exceeded, and INTERNAL for pattern errors.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: agentpatterns/v1/patterns.proto
+
+package patternspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PatternService_RunChain_FullMethodName        = "/agentpatterns.v1.PatternService/RunChain"
+	PatternService_RouteRequest_FullMethodName    = "/agentpatterns.v1.PatternService/RouteRequest"
+	PatternService_RunOrchestrator_FullMethodName = "/agentpatterns.v1.PatternService/RunOrchestrator"
+	PatternService_RunAgent_FullMethodName        = "/agentpatterns.v1.PatternService/RunAgent"
+)
+
+// PatternServiceClient is the client API for PatternService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PatternService runs agent patterns with server-streaming progress
+type PatternServiceClient interface {
+	// RunChain runs a prompt chain on an input
+	RunChain(ctx context.Context, in *RunChainRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RunEvent], error)
+	// RouteRequest classifies an input and answers it with the matching route
+	RouteRequest(ctx context.Context, in *RouteRequestRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RunEvent], error)
+	// RunOrchestrator decomposes a task and delegates it to workers
+	RunOrchestrator(ctx context.Context, in *RunOrchestratorRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RunEvent], error)
+	// RunAgent runs an autonomous agent on a task
+	RunAgent(ctx context.Context, in *RunAgentRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RunEvent], error)
+}
+
+type patternServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPatternServiceClient(cc grpc.ClientConnInterface) PatternServiceClient {
+	return &patternServiceClient{cc}
+}
+
+func (c *patternServiceClient) RunChain(ctx context.Context, in *RunChainRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RunEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PatternService_ServiceDesc.Streams[0], PatternService_RunChain_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RunChainRequest, RunEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PatternService_RunChainClient = grpc.ServerStreamingClient[RunEvent]
+
+func (c *patternServiceClient) RouteRequest(ctx context.Context, in *RouteRequestRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RunEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PatternService_ServiceDesc.Streams[1], PatternService_RouteRequest_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RouteRequestRequest, RunEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PatternService_RouteRequestClient = grpc.ServerStreamingClient[RunEvent]
+
+func (c *patternServiceClient) RunOrchestrator(ctx context.Context, in *RunOrchestratorRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RunEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PatternService_ServiceDesc.Streams[2], PatternService_RunOrchestrator_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RunOrchestratorRequest, RunEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PatternService_RunOrchestratorClient = grpc.ServerStreamingClient[RunEvent]
+
+func (c *patternServiceClient) RunAgent(ctx context.Context, in *RunAgentRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RunEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PatternService_ServiceDesc.Streams[3], PatternService_RunAgent_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RunAgentRequest, RunEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PatternService_RunAgentClient = grpc.ServerStreamingClient[RunEvent]
+
+// PatternServiceServer is the server API for PatternService service.
+// All implementations must embed UnimplementedPatternServiceServer
+// for forward compatibility.
+//
+// PatternService runs agent patterns with server-streaming progress
+type PatternServiceServer interface {
+	// RunChain runs a prompt chain on an input
+	RunChain(*RunChainRequest, grpc.ServerStreamingServer[RunEvent]) error
+	// RouteRequest classifies an input and answers it with the matching route
+	RouteRequest(*RouteRequestRequest, grpc.ServerStreamingServer[RunEvent]) error
+	// RunOrchestrator decomposes a task and delegates it to workers
+	RunOrchestrator(*RunOrchestratorRequest, grpc.ServerStreamingServer[RunEvent]) error
+	// RunAgent runs an autonomous agent on a task
+	RunAgent(*RunAgentRequest, grpc.ServerStreamingServer[RunEvent]) error
+	mustEmbedUnimplementedPatternServiceServer()
+}
+
+// UnimplementedPatternServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPatternServiceServer struct{}
+
+func (UnimplementedPatternServiceServer) RunChain(*RunChainRequest, grpc.ServerStreamingServer[RunEvent]) error {
+	return status.Error(codes.Unimplemented, "method RunChain not implemented")
+}
+func (UnimplementedPatternServiceServer) RouteRequest(*RouteRequestRequest, grpc.ServerStreamingServer[RunEvent]) error {
+	return status.Error(codes.Unimplemented, "method RouteRequest not implemented")
+}
+func (UnimplementedPatternServiceServer) RunOrchestrator(*RunOrchestratorRequest, grpc.ServerStreamingServer[RunEvent]) error {
+	return status.Error(codes.Unimplemented, "method RunOrchestrator not implemented")
+}
+func (UnimplementedPatternServiceServer) RunAgent(*RunAgentRequest, grpc.ServerStreamingServer[RunEvent]) error {
+	return status.Error(codes.Unimplemented, "method RunAgent not implemented")
+}
+func (UnimplementedPatternServiceServer) mustEmbedUnimplementedPatternServiceServer() {}
+func (UnimplementedPatternServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafePatternServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PatternServiceServer will
+// result in compilation errors.
+type UnsafePatternServiceServer interface {
+	mustEmbedUnimplementedPatternServiceServer()
+}
+
+func RegisterPatternServiceServer(s grpc.ServiceRegistrar, srv PatternServiceServer) {
+	// If the following call panics, it indicates UnimplementedPatternServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PatternService_ServiceDesc, srv)
+}
+
+func _PatternService_RunChain_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunChainRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PatternServiceServer).RunChain(m, &grpc.GenericServerStream[RunChainRequest, RunEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PatternService_RunChainServer = grpc.ServerStreamingServer[RunEvent]
+
+func _PatternService_RouteRequest_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RouteRequestRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PatternServiceServer).RouteRequest(m, &grpc.GenericServerStream[RouteRequestRequest, RunEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PatternService_RouteRequestServer = grpc.ServerStreamingServer[RunEvent]
+
+func _PatternService_RunOrchestrator_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunOrchestratorRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PatternServiceServer).RunOrchestrator(m, &grpc.GenericServerStream[RunOrchestratorRequest, RunEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PatternService_RunOrchestratorServer = grpc.ServerStreamingServer[RunEvent]
+
+func _PatternService_RunAgent_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunAgentRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PatternServiceServer).RunAgent(m, &grpc.GenericServerStream[RunAgentRequest, RunEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PatternService_RunAgentServer = grpc.ServerStreamingServer[RunEvent]
+
+// PatternService_ServiceDesc is the grpc.ServiceDesc for PatternService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PatternService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "agentpatterns.v1.PatternService",
+	HandlerType: (*PatternServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RunChain",
+			Handler:       _PatternService_RunChain_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RouteRequest",
+			Handler:       _PatternService_RouteRequest_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RunOrchestrator",
+			Handler:       _PatternService_RunOrchestrator_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RunAgent",
+			Handler:       _PatternService_RunAgent_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "agentpatterns/v1/patterns.proto",
+}
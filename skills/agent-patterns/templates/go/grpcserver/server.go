@@ -0,0 +1,316 @@
+/*
+ * gRPC Service for Go Agent Patterns
+ * Runs chains, routers, orchestrations, and agents for non-Go callers with streamed progress
+ */
+
+// Package grpcserver serves the PatternService defined in
+// proto/agentpatterns/v1/patterns.proto, so services in any language can run
+// the patterns and follow their progress. The generated patternspb package
+// is committed; after editing the proto, regenerate it:
+//
+//	go generate ./grpcserver
+//
+// Example:
+//
+//	cfg, err := config.Load("agentpatterns.yaml")
+//	srv := grpc.NewServer()
+//	grpcserver.New(agentpatterns.NewClientFromConfig(cfg), cfg).Register(srv)
+//	lis, err := net.Listen("tcp", ":50051")
+//	err = srv.Serve(lis)
+package grpcserver
+
+//go:generate protoc -I ../proto --go_out=. --go_opt=module=github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/grpcserver --go-grpc_out=. --go-grpc_opt=module=github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/grpcserver agentpatterns/v1/patterns.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/config"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/grpcserver/patternspb"
+)
+
+// Server implements patternspb.PatternServiceServer
+type Server struct {
+	patternspb.UnimplementedPatternServiceServer
+
+	client *agentpatterns.AnthropicClient
+	cfg    *config.Config
+	opts   []agentpatterns.Option
+}
+
+// Server implements every RPC of the generated service
+var _ patternspb.PatternServiceServer = (*Server)(nil)
+
+// New creates a server running patterns with client. cfg supplies model
+// aliases and default budgets; opts are applied to every pattern, e.g.
+// WithTracer or WithStore.
+func New(client *agentpatterns.AnthropicClient, cfg *config.Config, opts ...agentpatterns.Option) *Server {
+	return &Server{client: client, cfg: cfg, opts: opts}
+}
+
+// Register adds the service to a gRPC server
+func (s *Server) Register(g *grpc.Server) {
+	patternspb.RegisterPatternServiceServer(g, s)
+}
+
+// RunChain implements patternspb.PatternServiceServer
+func (s *Server) RunChain(req *patternspb.RunChainRequest, stream patternspb.PatternService_RunChainServer) error {
+	if len(req.GetSteps()) == 0 {
+		return status.Error(codes.InvalidArgument, "chain needs at least one step")
+	}
+
+	steps := make([]agentpatterns.ChainStep, 0, len(req.GetSteps()))
+	for _, step := range req.GetSteps() {
+		tmpl, err := agentpatterns.ParsePromptTemplate(step.GetName(), step.GetPrompt())
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		steps = append(steps, agentpatterns.ChainStep{
+			Name:      step.GetName(),
+			Template:  tmpl,
+			Validator: validator(step.GetRequire(), int(step.GetMinWords())),
+		})
+	}
+
+	return s.run(stream.Context(), req.GetOptions(), stream, func(ctx context.Context, opts []agentpatterns.Option) (*patternspb.Result, error) {
+		chain := agentpatterns.NewPromptChain(s.client, opts...)
+		for _, step := range steps {
+			chain.AddStep(step)
+		}
+		output, err := chain.Execute(ctx, map[string]interface{}{"input": req.GetInput()})
+		return &patternspb.Result{Output: output}, err
+	})
+}
+
+// RouteRequest implements patternspb.PatternServiceServer
+func (s *Server) RouteRequest(req *patternspb.RouteRequestRequest, stream patternspb.PatternService_RouteRequestServer) error {
+	if len(req.GetRoutes()) == 0 {
+		return status.Error(codes.InvalidArgument, "router needs at least one route")
+	}
+
+	spec := &agentpatterns.WorkflowSpec{
+		Pattern: "router",
+		Router: &agentpatterns.RouterSpec{
+			Threshold: req.GetConfidenceThreshold(),
+			Fallback:  req.GetFallbackPrompt(),
+		},
+	}
+	for _, route := range req.GetRoutes() {
+		if _, err := agentpatterns.ParsePromptTemplate(route.GetCategory(), route.GetPrompt()); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		spec.Router.Routes = append(spec.Router.Routes, agentpatterns.RouteSpec{
+			Category:    route.GetCategory(),
+			Description: route.GetDescription(),
+			Prompt:      route.GetPrompt(),
+			Model:       route.GetModel(),
+		})
+	}
+
+	return s.run(stream.Context(), req.GetOptions(), stream, func(ctx context.Context, opts []agentpatterns.Option) (*patternspb.Result, error) {
+		output, details, err := spec.Run(ctx, s.client, s.cfg, req.GetInput(), opts...)
+		result := &patternspb.Result{Output: output}
+		if c, ok := details.(*agentpatterns.ClassificationResult); ok && c != nil {
+			result.Classification = &patternspb.Classification{
+				Category:   c.Category,
+				Confidence: c.Confidence,
+				Reasoning:  c.Reasoning,
+			}
+		}
+		return result, err
+	})
+}
+
+// RunOrchestrator implements patternspb.PatternServiceServer
+func (s *Server) RunOrchestrator(req *patternspb.RunOrchestratorRequest, stream patternspb.PatternService_RunOrchestratorServer) error {
+	if strings.TrimSpace(req.GetTask()) == "" {
+		return status.Error(codes.InvalidArgument, "task is empty")
+	}
+
+	return s.run(stream.Context(), req.GetOptions(), stream, func(ctx context.Context, opts []agentpatterns.Option) (*patternspb.Result, error) {
+		orch := agentpatterns.NewOrchestrator(s.client, opts...)
+		for _, w := range req.GetWorkers() {
+			workerOpts := opts
+			if w.GetModel() != "" {
+				workerOpts = append(append([]agentpatterns.Option{}, opts...), agentpatterns.WithModel(s.cfg.Model(w.GetModel())))
+			}
+			orch.RegisterWorker(agentpatterns.NewLLMWorker(s.client, w.GetType(), w.GetSystemPrompt(), workerOpts...))
+		}
+		result, err := orch.Execute(ctx, req.GetTask())
+		if err != nil {
+			return nil, err
+		}
+		return &patternspb.Result{Output: result.FinalResult}, nil
+	})
+}
+
+// RunAgent implements patternspb.PatternServiceServer
+func (s *Server) RunAgent(req *patternspb.RunAgentRequest, stream patternspb.PatternService_RunAgentServer) error {
+	if strings.TrimSpace(req.GetTask()) == "" {
+		return status.Error(codes.InvalidArgument, "task is empty")
+	}
+	maxSteps := int(req.GetMaxSteps())
+	if maxSteps <= 0 {
+		maxSteps = 10
+	}
+
+	return s.run(stream.Context(), req.GetOptions(), stream, func(ctx context.Context, opts []agentpatterns.Option) (*patternspb.Result, error) {
+		agent := agentpatterns.NewAutonomousAgent(s.client, opts...)
+		result, err := agent.Run(ctx, req.GetTask(), maxSteps)
+		if err != nil {
+			return nil, err
+		}
+		if !result.Success {
+			return nil, fmt.Errorf("agent did not complete within %d steps", maxSteps)
+		}
+		return &patternspb.Result{Output: result.FinalResult}, nil
+	})
+}
+
+// eventSender is the Send method shared by every server stream
+type eventSender interface {
+	Send(*patternspb.RunEvent) error
+}
+
+// run executes fn with the run's options, forwarding its events to stream
+// and finishing with its result, to which run adds the cost. Events are published from pattern
+// goroutines, so they are funneled through a channel to the one goroutine
+// allowed to send on the stream.
+func (s *Server) run(ctx context.Context, options *patternspb.RunOptions, stream eventSender, fn func(ctx context.Context, opts []agentpatterns.Option) (*patternspb.Result, error)) error {
+	runID := options.GetRunId()
+	if runID == "" {
+		runID = fmt.Sprintf("grpc_%d", time.Now().UnixNano())
+	}
+	ctx = agentpatterns.WithRunID(ctx, runID)
+
+	events := make(chan agentpatterns.Event, 64)
+	bus := agentpatterns.NewEventBus()
+	bus.Subscribe(agentpatterns.EventSinkFunc(func(e agentpatterns.Event) {
+		select {
+		case events <- e:
+		case <-ctx.Done():
+		}
+	}))
+	costs := agentpatterns.NewCostTracker(agentpatterns.DefaultPricing())
+	opts := append(s.runOptions(options), agentpatterns.WithEventBus(bus), agentpatterns.WithCostTracker(costs))
+
+	type outcome struct {
+		result *patternspb.Result
+		err    error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		result, err := fn(ctx, opts)
+		done <- outcome{result, err}
+	}()
+
+	for {
+		select {
+		case e := <-events:
+			if err := stream.Send(progressEvent(runID, e)); err != nil {
+				return err
+			}
+		case o := <-done:
+			// Flush events published before the pattern returned
+			for drained := false; !drained; {
+				select {
+				case e := <-events:
+					if err := stream.Send(progressEvent(runID, e)); err != nil {
+						return err
+					}
+				default:
+					drained = true
+				}
+			}
+			if o.err != nil {
+				return statusError(o.err)
+			}
+			total := costs.Total()
+			o.result.Cost = &patternspb.Cost{
+				Calls:        int32(total.Calls),
+				InputTokens:  int64(total.InputTokens),
+				OutputTokens: int64(total.OutputTokens),
+				CostUsd:      total.Cost,
+			}
+			o.result.ElapsedMs = time.Since(start).Milliseconds()
+			return stream.Send(&patternspb.RunEvent{
+				RunId: runID,
+				Event: &patternspb.RunEvent_Result{Result: o.result},
+			})
+		}
+	}
+}
+
+// runOptions applies the server settings and the request's overrides
+func (s *Server) runOptions(options *patternspb.RunOptions) []agentpatterns.Option {
+	cfg := *s.cfg
+	if options.GetModel() != "" {
+		cfg.DefaultModel = options.GetModel()
+	}
+	if options.GetMaxTokens() > 0 {
+		cfg.MaxTokens = int(options.GetMaxTokens())
+	}
+	if options.GetMaxCalls() > 0 {
+		cfg.Budget.MaxCalls = int(options.GetMaxCalls())
+	}
+	if options.GetMaxDurationMs() > 0 {
+		cfg.Budget.MaxDuration = time.Duration(options.GetMaxDurationMs()) * time.Millisecond
+	}
+	return append([]agentpatterns.Option{agentpatterns.WithConfig(&cfg)}, s.opts...)
+}
+
+func progressEvent(runID string, e agentpatterns.Event) *patternspb.RunEvent {
+	payload := make(map[string]string, len(e.Payload))
+	for k, v := range e.Payload {
+		if v != nil {
+			payload[k] = fmt.Sprint(v)
+		}
+	}
+	return &patternspb.RunEvent{
+		RunId: runID,
+		Event: &patternspb.RunEvent_Progress{Progress: &patternspb.Progress{
+			Pattern: e.Pattern,
+			Phase:   e.Phase,
+			SpanId:  e.SpanID,
+			Time:    timestamppb.New(e.Time),
+			Payload: payload,
+		}},
+	}
+}
+
+// statusError maps a pattern error to a gRPC status
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, agentpatterns.ErrBudgetExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// validator builds a chain validator from a step's requirements
+func validator(require []string, minWords int) agentpatterns.ValidatorFunc {
+	if len(require) == 0 && minWords == 0 {
+		return nil
+	}
+	return func(output string) bool {
+		for _, want := range require {
+			if !strings.Contains(output, want) {
+				return false
+			}
+		}
+		return len(strings.Fields(output)) >= minWords
+	}
+}
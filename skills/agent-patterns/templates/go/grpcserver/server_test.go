@@ -0,0 +1,154 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/config"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/grpcserver/patternspb"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/mockllm"
+)
+
+// dial serves a Server backed by mock over an in-memory listener and
+// returns a client for it
+func dial(t *testing.T, mock *mockllm.Mock) patternspb.PatternServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	New(agentpatterns.NewMockClient(mock).Client(), config.Default()).Register(srv)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return patternspb.NewPatternServiceClient(conn)
+}
+
+// eventStream is the client side of any of the service's streams
+type eventStream interface {
+	Recv() (*patternspb.RunEvent, error)
+}
+
+// result reads a stream to its end and returns its Result
+func result(t *testing.T, stream eventStream) (*patternspb.Result, error) {
+	t.Helper()
+	var res *patternspb.Result
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if r := event.GetResult(); r != nil {
+			if res != nil {
+				t.Fatal("stream sent more than one Result")
+			}
+			res = r
+		}
+	}
+}
+
+func TestRouteRequest(t *testing.T) {
+	mock := mockllm.New().
+		When("Classify", `{"category": "billing", "confidence": 0.9, "reasoning": "mentions a charge"}`).
+		Default("Refund issued.")
+	client := dial(t, mock)
+
+	stream, err := client.RouteRequest(context.Background(), &patternspb.RouteRequestRequest{
+		Routes: []*patternspb.Route{
+			{Category: "billing", Description: "Payments and refunds", Prompt: "Handle this billing request: {{.input}}"},
+			{Category: "technical", Description: "Bugs and errors", Prompt: "Fix: {{.input}}"},
+		},
+		Input: "I was charged twice",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := result(t, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetOutput() != "Refund issued." {
+		t.Errorf("Output = %q, want %q", res.GetOutput(), "Refund issued.")
+	}
+	if c := res.GetClassification(); c.GetCategory() != "billing" || c.GetConfidence() != 0.9 {
+		t.Errorf("Classification = %v, want billing at 0.9", c)
+	}
+	mock.AssertCalled(t, "Handle this billing request: I was charged twice")
+}
+
+func TestRunChain(t *testing.T) {
+	mock := mockllm.New().Reply("an outline", "a draft")
+	client := dial(t, mock)
+
+	stream, err := client.RunChain(context.Background(), &patternspb.RunChainRequest{
+		Steps: []*patternspb.ChainStep{
+			{Name: "outline", Prompt: "Outline {{.input}}"},
+			{Name: "draft", Prompt: "Draft from {{.outline}}"},
+		},
+		Input: "gRPC",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := result(t, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetOutput() != "a draft" {
+		t.Errorf("Output = %q, want %q", res.GetOutput(), "a draft")
+	}
+	mock.AssertCalled(t, "Draft from an outline")
+}
+
+func TestInvalidArgument(t *testing.T) {
+	client := dial(t, mockllm.New())
+	tests := []struct {
+		name string
+		call func() (eventStream, error)
+	}{
+		{"chain without steps", func() (eventStream, error) {
+			return client.RunChain(context.Background(), &patternspb.RunChainRequest{Input: "x"})
+		}},
+		{"router without routes", func() (eventStream, error) {
+			return client.RouteRequest(context.Background(), &patternspb.RouteRequestRequest{Input: "x"})
+		}},
+		{"bad route template", func() (eventStream, error) {
+			return client.RouteRequest(context.Background(), &patternspb.RouteRequestRequest{
+				Routes: []*patternspb.Route{{Category: "a", Prompt: "{{.input"}},
+				Input:  "x",
+			})
+		}},
+		{"orchestrator without task", func() (eventStream, error) {
+			return client.RunOrchestrator(context.Background(), &patternspb.RunOrchestratorRequest{})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := tt.call()
+			if err == nil {
+				_, err = result(t, stream)
+			}
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("error = %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
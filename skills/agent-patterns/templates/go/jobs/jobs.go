@@ -0,0 +1,307 @@
+/*
+ * Job Queue for Go Agent Patterns
+ * Persistent jobs, worker pools, and retries for batch and offline workloads
+ */
+
+// Package jobs runs large offline workloads, such as classifying 50,000
+// tickets or reviewing 500 files, as individual persisted jobs instead of
+// one long in-process loop. Jobs survive restarts, failed jobs are retried
+// with back-off, and every result is kept in the store.
+//
+// A Queue holds jobs in a store.Store. A Pool pulls jobs from a queue and
+// runs the handler registered for each job's kind.
+//
+// Example:
+//
+//	q := jobs.NewQueue(st, "tickets", jobs.WithMaxAttempts(5))
+//	_, err := q.EnqueueBatch(ctx, "classify", tickets)
+//
+//	pool := jobs.NewPool(q, jobs.WithWorkers(16)).
+//	    Handle("classify", func(ctx context.Context, job jobs.Job) (string, error) {
+//	        result, err := router.Classify(ctx, job.Input)
+//	        if err != nil {
+//	            return "", err
+//	        }
+//	        return result.Category, nil
+//	    })
+//	err = pool.Drain(ctx)
+//	failed, err := q.List(ctx, jobs.Failed)
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/store"
+)
+
+// ErrPermanent marks a handler error that retrying cannot fix. Wrap it to
+// fail a job without using its remaining attempts.
+var ErrPermanent = errors.New("permanent failure")
+
+// Status is the state of a job
+type Status string
+
+// Job statuses
+const (
+	Pending   Status = "pending"
+	Running   Status = "running"
+	Succeeded Status = "succeeded"
+	Failed    Status = "failed"
+)
+
+// Job is one unit of work and, once it has run, its result
+type Job struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"`
+	Input       string    `json:"input"`
+	Status      Status    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"max_attempts"`
+	Output      string    `json:"output,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	NotBefore   time.Time `json:"not_before,omitempty"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+// Stats counts the jobs in a queue by status
+type Stats struct {
+	Pending   int `json:"pending"`
+	Running   int `json:"running"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// Total returns the number of jobs in the queue
+func (s Stats) Total() int {
+	return s.Pending + s.Running + s.Succeeded + s.Failed
+}
+
+// Option configures a Queue
+type Option func(*Queue)
+
+// WithMaxAttempts sets how many times a job runs before it fails. The
+// default is 3.
+func WithMaxAttempts(n int) Option {
+	return func(q *Queue) { q.maxAttempts = n }
+}
+
+// WithBackoff sets the delay before the first retry; it doubles with each
+// further attempt up to maxBackoff. The defaults are 30 seconds and 10
+// minutes.
+func WithBackoff(initial, maxBackoff time.Duration) Option {
+	return func(q *Queue) {
+		q.backoff = initial
+		q.maxBackoff = maxBackoff
+	}
+}
+
+// Queue stores jobs under "jobs/<name>/" in a store. Each job is kept at
+// data/<id>, with an empty marker at <status>/<id> so jobs can be listed by
+// status without loading every record.
+//
+// The store has no atomic claim, so each queue should be worked by one
+// process at a time; a Pool runs its workers concurrently within that
+// process.
+type Queue struct {
+	st          store.Store
+	prefix      string
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+
+	mu  sync.Mutex
+	seq uint32
+}
+
+// NewQueue creates a queue named name in st
+func NewQueue(st store.Store, name string, opts ...Option) *Queue {
+	q := &Queue{
+		st:          st,
+		prefix:      "jobs/" + name + "/",
+		maxAttempts: 3,
+		backoff:     30 * time.Second,
+		maxBackoff:  10 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// Enqueue adds a job and returns its ID
+func (q *Queue) Enqueue(ctx context.Context, kind, input string) (string, error) {
+	now := time.Now().UTC()
+	job := Job{
+		ID:          q.newID(now),
+		Kind:        kind,
+		Input:       input,
+		Status:      Pending,
+		MaxAttempts: q.maxAttempts,
+		Created:     now,
+		Updated:     now,
+	}
+	if err := q.save(ctx, job, ""); err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// EnqueueBatch adds one job per input and returns their IDs in order. If it
+// fails part way, the IDs of the jobs already added are returned with the
+// error.
+func (q *Queue) EnqueueBatch(ctx context.Context, kind string, inputs []string) ([]string, error) {
+	ids := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		id, err := q.Enqueue(ctx, kind, input)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Get loads a job, returning store.ErrNotFound if it does not exist
+func (q *Queue) Get(ctx context.Context, id string) (Job, error) {
+	data, err := q.st.Get(ctx, q.prefix+"data/"+id)
+	if err != nil {
+		return Job{}, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return Job{}, fmt.Errorf("failed to decode job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+// List loads every job with the given status, oldest first
+func (q *Queue) List(ctx context.Context, status Status) ([]Job, error) {
+	ids, err := q.ids(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]Job, 0, len(ids))
+	for _, id := range ids {
+		job, err := q.Get(ctx, id)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// Stats counts the jobs by status
+func (q *Queue) Stats(ctx context.Context) (Stats, error) {
+	var stats Stats
+	for status, count := range map[Status]*int{
+		Pending:   &stats.Pending,
+		Running:   &stats.Running,
+		Succeeded: &stats.Succeeded,
+		Failed:    &stats.Failed,
+	} {
+		ids, err := q.ids(ctx, status)
+		if err != nil {
+			return Stats{}, err
+		}
+		*count = len(ids)
+	}
+	return stats, nil
+}
+
+// Retry returns a failed job to the queue with a fresh set of attempts
+func (q *Queue) Retry(ctx context.Context, id string) error {
+	job, err := q.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if job.Status != Failed {
+		return fmt.Errorf("job %s is %s, not failed", id, job.Status)
+	}
+	job.Status = Pending
+	job.Attempts = 0
+	job.MaxAttempts = q.maxAttempts
+	job.Error = ""
+	job.NotBefore = time.Time{}
+	job.Updated = time.Now().UTC()
+	return q.save(ctx, job, Failed)
+}
+
+// Delete removes a job and its result
+func (q *Queue) Delete(ctx context.Context, id string) error {
+	job, err := q.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := q.st.Delete(ctx, q.prefix+string(job.Status)+"/"+id); err != nil {
+		return err
+	}
+	return q.st.Delete(ctx, q.prefix+"data/"+id)
+}
+
+// save writes job and moves its status marker from previous, if set.
+// The record is written first, so a crash between the two writes leaves a
+// marker pointing at a record whose status tells the truth.
+func (q *Queue) save(ctx context.Context, job Job, previous Status) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err := q.st.Put(ctx, q.prefix+"data/"+job.ID, data); err != nil {
+		return fmt.Errorf("failed to save job %s: %w", job.ID, err)
+	}
+	if err := q.st.Put(ctx, q.prefix+string(job.Status)+"/"+job.ID, []byte{}); err != nil {
+		return fmt.Errorf("failed to index job %s: %w", job.ID, err)
+	}
+	if previous != "" && previous != job.Status {
+		if err := q.st.Delete(ctx, q.prefix+string(previous)+"/"+job.ID); err != nil {
+			return fmt.Errorf("failed to index job %s: %w", job.ID, err)
+		}
+	}
+	return nil
+}
+
+// ids lists the IDs of jobs with a status marker, oldest first
+func (q *Queue) ids(ctx context.Context, status Status) ([]string, error) {
+	prefix := q.prefix + string(status) + "/"
+	keys, err := q.st.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s jobs: %w", status, err)
+	}
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = key[len(prefix):]
+	}
+	return ids, nil
+}
+
+// newID returns an ID that sorts in enqueue order
+func (q *Queue) newID(now time.Time) string {
+	q.mu.Lock()
+	q.seq++
+	seq := q.seq
+	q.mu.Unlock()
+	return fmt.Sprintf("job_%019d_%06d", now.UnixNano(), seq%1000000)
+}
+
+// retryDelay returns the back-off before the next attempt of a job that has
+// run attempts times
+func (q *Queue) retryDelay(attempts int) time.Duration {
+	delay := q.backoff
+	for i := 1; i < attempts && delay < q.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > q.maxBackoff {
+		delay = q.maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,259 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+)
+
+// Handler runs a job and returns its output. The job ID is used as the run
+// ID, so patterns given a store resume from their checkpoints when a job is
+// retried.
+type Handler func(ctx context.Context, job Job) (string, error)
+
+// PoolOption configures a Pool
+type PoolOption func(*Pool)
+
+// WithWorkers sets how many jobs run at once. The default is 4.
+func WithWorkers(n int) PoolOption {
+	return func(p *Pool) { p.workers = n }
+}
+
+// WithJobTimeout bounds each attempt of a job; zero means no limit
+func WithJobTimeout(d time.Duration) PoolOption {
+	return func(p *Pool) { p.timeout = d }
+}
+
+// WithPollInterval sets how often an idle pool checks for new jobs. The
+// default is one second.
+func WithPollInterval(d time.Duration) PoolOption {
+	return func(p *Pool) { p.poll = d }
+}
+
+// WithLogger sets the logger used to report job progress
+func WithLogger(logger *slog.Logger) PoolOption {
+	return func(p *Pool) { p.logger = logger }
+}
+
+// Pool runs the jobs of a queue with a fixed number of workers
+type Pool struct {
+	q        *Queue
+	handlers map[string]Handler
+	workers  int
+	timeout  time.Duration
+	poll     time.Duration
+	logger   *slog.Logger
+}
+
+// NewPool creates a pool working q
+func NewPool(q *Queue, opts ...PoolOption) *Pool {
+	p := &Pool{
+		q:        q,
+		handlers: make(map[string]Handler),
+		workers:  4,
+		poll:     time.Second,
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.workers < 1 {
+		p.workers = 1
+	}
+	return p
+}
+
+// Handle registers the handler for jobs of kind
+func (p *Pool) Handle(kind string, h Handler) *Pool {
+	p.handlers[kind] = h
+	return p
+}
+
+// Run works the queue until ctx is done, waiting for new jobs when it is
+// empty. Jobs in flight when ctx is done are cancelled and run again the
+// next time the queue is worked.
+func (p *Pool) Run(ctx context.Context) error {
+	return p.work(ctx, false)
+}
+
+// Drain works the queue until no pending jobs remain, including jobs
+// waiting to be retried, and returns once the last one has finished
+func (p *Pool) Drain(ctx context.Context) error {
+	return p.work(ctx, true)
+}
+
+func (p *Pool) work(ctx context.Context, drain bool) error {
+	if err := p.recover(ctx); err != nil {
+		return err
+	}
+
+	slots := make(chan struct{}, p.workers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	var mu sync.Mutex
+	inFlight := make(map[string]bool)
+
+	for {
+		ids, err := p.q.ids(ctx, Pending)
+		if err != nil {
+			return err
+		}
+
+		started, waiting := 0, 0
+		for _, id := range ids {
+			mu.Lock()
+			busy := inFlight[id]
+			mu.Unlock()
+			if busy {
+				continue
+			}
+			job, err := p.q.Get(ctx, id)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				p.logger.Warn("failed to load job", "job", id, "error", err)
+				continue
+			}
+			if job.Status != Pending {
+				// Left by a crash between writing the record and its marker
+				p.q.st.Delete(ctx, p.q.prefix+string(Pending)+"/"+id)
+				continue
+			}
+			if time.Now().Before(job.NotBefore) {
+				waiting++
+				continue
+			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			mu.Lock()
+			inFlight[id] = true
+			mu.Unlock()
+			started++
+
+			wg.Add(1)
+			go func(job Job) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				p.execute(ctx, job)
+				mu.Lock()
+				delete(inFlight, job.ID)
+				mu.Unlock()
+			}(job)
+		}
+
+		if drain && started == 0 && waiting == 0 {
+			mu.Lock()
+			idle := len(inFlight) == 0
+			mu.Unlock()
+			if idle {
+				// A job that just failed may have been requeued; look once more
+				if ids, err := p.q.ids(ctx, Pending); err != nil || len(ids) == 0 {
+					return err
+				}
+				continue
+			}
+		}
+		if started == 0 {
+			select {
+			case <-time.After(p.poll):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// recover requeues jobs left running by a worker that crashed
+func (p *Pool) recover(ctx context.Context) error {
+	stale, err := p.q.List(ctx, Running)
+	if err != nil {
+		return err
+	}
+	for _, job := range stale {
+		p.logger.Warn("requeuing interrupted job", "job", job.ID, "attempt", job.Attempts)
+		job.Status = Pending
+		job.Updated = time.Now().UTC()
+		if err := p.q.save(ctx, job, Running); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execute runs one attempt of job and records the outcome
+func (p *Pool) execute(ctx context.Context, job Job) {
+	job.Status = Running
+	job.Attempts++
+	job.Updated = time.Now().UTC()
+	if err := p.q.save(ctx, job, Pending); err != nil {
+		p.logger.Error("failed to start job", "job", job.ID, "error", err)
+		return
+	}
+
+	output, err := p.runHandler(ctx, job)
+	if err != nil && ctx.Err() != nil {
+		// Shutting down: requeue the job without counting this attempt
+		job.Status = Pending
+		job.Attempts--
+		job.Updated = time.Now().UTC()
+		if err := p.q.save(context.Background(), job, Running); err != nil {
+			p.logger.Error("failed to requeue job", "job", job.ID, "error", err)
+		}
+		return
+	}
+
+	job.Updated = time.Now().UTC()
+	switch {
+	case err == nil:
+		job.Status = Succeeded
+		job.Output = output
+		job.Error = ""
+		p.logger.Info("job succeeded", "job", job.ID, "kind", job.Kind, "attempt", job.Attempts)
+	case job.Attempts >= job.MaxAttempts || errors.Is(err, ErrPermanent):
+		job.Status = Failed
+		job.Error = err.Error()
+		p.logger.Warn("job failed", "job", job.ID, "kind", job.Kind, "attempt", job.Attempts, "error", err)
+	default:
+		delay := p.q.retryDelay(job.Attempts)
+		job.Status = Pending
+		job.Error = err.Error()
+		job.NotBefore = job.Updated.Add(delay)
+		p.logger.Info("job will be retried", "job", job.ID, "kind", job.Kind, "attempt", job.Attempts, "backoff", delay, "error", err)
+	}
+	// Record the result even if the pool is stopping
+	if err := p.q.save(context.Background(), job, Running); err != nil {
+		p.logger.Error("failed to record job result", "job", job.ID, "error", err)
+	}
+}
+
+func (p *Pool) runHandler(ctx context.Context, job Job) (output string, err error) {
+	h, ok := p.handlers[job.Kind]
+	if !ok {
+		return "", fmt.Errorf("%w: no handler for job kind %q", ErrPermanent, job.Kind)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+
+	ctx = agentpatterns.WithRunID(ctx, job.ID)
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	return h(ctx, job)
+}
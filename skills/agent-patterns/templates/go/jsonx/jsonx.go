@@ -0,0 +1,202 @@
+/*
+ * JSON Extraction and Repair for Go Agent Patterns
+ * Pulls JSON out of model responses and fixes the mistakes models commonly make
+ */
+
+// Package jsonx extracts JSON from LLM responses and repairs it before
+// decoding.
+//
+// Models wrap JSON in Markdown fences, surround it with prose, leave trailing
+// commas, use single quotes or Python literals, and get cut off by the token
+// limit. Unmarshal handles all of these:
+//
+//	var result ClassificationResult
+//	if err := jsonx.Unmarshal(response, &result); err != nil {
+//	    if errors.Is(err, jsonx.ErrNotFound) {
+//	        // the model answered in prose
+//	    }
+//	    return nil, err
+//	}
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrNotFound is returned when the text contains no JSON object or array
+var ErrNotFound = errors.New("jsonx: no JSON found")
+
+// DecodeError is returned when JSON was found but could not be decoded,
+// even after repair
+type DecodeError struct {
+	// JSON is the extracted and repaired text that failed to decode
+	JSON string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("jsonx: %v", e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Unmarshal decodes the first JSON value in text into v, repairing it if it
+// does not decode as-is. Bracketed prose such as "[1]" before the JSON is
+// skipped. When v points to a slice only arrays are considered; when it
+// points to a struct or map only objects are.
+func Unmarshal(text string, v interface{}) error {
+	raws := candidates(text, openerFor(v))
+	if len(raws) == 0 {
+		return ErrNotFound
+	}
+
+	var firstErr error
+	for _, raw := range raws {
+		if err := json.Unmarshal([]byte(raw), v); err == nil {
+			return nil
+		}
+		repaired := Repair(raw)
+		err := json.Unmarshal([]byte(repaired), v)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = &DecodeError{JSON: repaired, Err: err}
+		}
+	}
+	return firstErr
+}
+
+// Extract returns the first JSON object or array in text, preferring the
+// contents of a Markdown code fence. If the value is cut off, Extract
+// returns everything up to the end of the text; Repair can close it.
+func Extract(text string) (string, error) {
+	raws := candidates(text, 0)
+	if len(raws) == 0 {
+		return "", ErrNotFound
+	}
+	return raws[0], nil
+}
+
+// openerFor returns the bracket that starts the JSON value v decodes, or 0
+// if either is acceptable
+func openerFor(v interface{}) byte {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return 0
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return '['
+	case reflect.Struct, reflect.Map:
+		return '{'
+	}
+	return 0
+}
+
+// candidates returns the bracketed values in text that may be JSON, those
+// inside a code fence first
+func candidates(text string, opener byte) []string {
+	var raws []string
+	if fenced, ok := stripFence(text); ok {
+		raws = append(raws, candidates(fenced, opener)...)
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if (opener == 0 && (c == '{' || c == '[')) || (opener != 0 && c == opener) {
+			end := matchClose(text, i)
+			if end < 0 {
+				// Unbalanced: the response was probably truncated
+				return append(raws, strings.TrimSpace(text[i:]))
+			}
+			raws = append(raws, text[i:end+1])
+			i = end
+		}
+	}
+	return raws
+}
+
+// stripFence returns the contents of the first ``` code fence in text
+func stripFence(text string) (string, bool) {
+	start := strings.Index(text, "```")
+	if start < 0 {
+		return "", false
+	}
+	body := text[start+3:]
+	// Skip the language tag, e.g. ```json
+	if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+		body = body[nl+1:]
+	}
+	if end := strings.Index(body, "```"); end >= 0 {
+		body = body[:end]
+	}
+	return body, true
+}
+
+// matchClose returns the index of the bracket closing the one at start, or
+// -1 if the text ends first. Brackets inside strings are ignored.
+func matchClose(text string, start int) int {
+	var stack []byte
+	inString := false
+	var quote byte
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case quote:
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			// Single quotes only delimit strings where a value or key can
+			// start, so apostrophes in prose do not confuse the scan
+			if c == '\'' && !valueStart(text, i) {
+				continue
+			}
+			inString, quote = true, c
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				return -1
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// valueStart reports whether the previous non-space character before i
+// allows a JSON key or value to begin
+func valueStart(text string, i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		switch text[j] {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case '{', '[', ',', ':':
+			return true
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,246 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeAny decodes s into a generic value, failing the test if it is not
+// valid JSON
+func decodeAny(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", s, err)
+	}
+	return v
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"bare object", `{"a": 1}`, `{"a": 1}`},
+		{"bare array", `[1, 2]`, `[1, 2]`},
+		{"fenced with language", "Here you go:\n```json\n{\"a\": 1}\n```\nDone.", `{"a": 1}`},
+		{"fenced without language", "```\n[1, 2]\n```", `[1, 2]`},
+		{"fence preferred over prose", "Not this {\"b\": 2}\n```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"prose before and after", `The answer is {"a": "b"} as requested.`, `{"a": "b"}`},
+		{"nested arrays", `Result: [[1, 2], [3, [4, 5]]] end`, `[[1, 2], [3, [4, 5]]]`},
+		{"brackets inside strings", `{"a": "x}]y"} trailing`, `{"a": "x}]y"}`},
+		{"apostrophe in prose", `It's here: {"a": 1}`, `{"a": 1}`},
+		{"truncated keeps the rest", `Sure: {"a": [1, 2`, `{"a": [1, 2`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(tt.text)
+			if err != nil {
+				t.Fatalf("Extract(%q) error: %v", tt.text, err)
+			}
+			if strings.TrimSpace(got) != tt.want {
+				t.Errorf("Extract(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractNotFound(t *testing.T) {
+	for _, text := range []string{"", "no json here", "just prose, it's fine", "```\nplain text\n```"} {
+		if _, err := Extract(text); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Extract(%q) error = %v, want ErrNotFound", text, err)
+		}
+	}
+}
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"valid input unchanged", `{"a": [1, 2], "b": null}`, `{"a": [1, 2], "b": null}`},
+		{"trailing comma in object", `{"a": 1, "b": 2,}`, `{"a": 1, "b": 2}`},
+		{"trailing comma in array", `[1, 2, 3, ]`, `[1, 2, 3]`},
+		{"trailing commas nested", `{"a": [1, 2,], "b": {"c": 3,},}`, `{"a": [1, 2], "b": {"c": 3}}`},
+		{"single-quoted strings", `{'a': 'b'}`, `{"a": "b"}`},
+		{"single quotes with double quote inside", `{'a': 'say "hi"'}`, `{"a": "say \"hi\""}`},
+		{"escaped single quote", `{'a': 'it\'s'}`, `{"a": "it's"}`},
+		{"apostrophe inside double quotes", `{"a": "it's"}`, `{"a": "it's"}`},
+		{"Python literals", `{"a": True, "b": False, "c": None}`, `{"a": true, "b": false, "c": null}`},
+		{"Python literals in array", `[True, None, False]`, `[true, null, false]`},
+		{"unquoted keys", `{a: 1, b_2: "x"}`, `{"a": 1, "b_2": "x"}`},
+		{"line comments", "{\n  \"a\": 1, // the first\n  \"b\": 2\n}", `{"a": 1, "b": 2}`},
+		{"raw newline in string", "{\"a\": \"line1\nline2\"}", `{"a": "line1\nline2"}`},
+		{"raw tab in string", "{\"a\": \"x\ty\"}", `{"a": "x\ty"}`},
+		{"nested arrays", `[[1, [2, 3,],], [4]]`, `[[1, [2, 3]], [4]]`},
+		{"mismatched close", `{"a": [1}`, `{"a": [1]}`},
+		{"text after value dropped", `{"a": 1} and more {"b": 2}`, `{"a": 1}`},
+		{"truncated after value", `{"a": 1, "b": 2`, `{"a": 1, "b": 2}`},
+		{"truncated after comma", `{"a": 1,`, `{"a": 1}`},
+		{"truncated in string value", `{"a": "hel`, `{"a": "hel"}`},
+		{"truncated in key", `{"a": 1, "be`, `{"a": 1}`},
+		{"truncated after colon", `{"a": 1, "b":`, `{"a": 1}`},
+		{"truncated after key", `{"a": 1, "b"`, `{"a": 1}`},
+		{"truncated nested", `{"a": {"b": [1, 2, {"c": "d`, `{"a": {"b": [1, 2, {"c": "d"}]}}`},
+		{"truncated array", `[1, 2, [3, 4`, `[1, 2, [3, 4]]`},
+		{"truncated after escape", `{"a": "x\`, `{"a": "x"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Repair(tt.in)
+			if !reflect.DeepEqual(decodeAny(t, got), decodeAny(t, tt.want)) {
+				t.Errorf("Repair(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	type result struct {
+		Category   string   `json:"category"`
+		Confidence float64  `json:"confidence"`
+		Tags       []string `json:"tags"`
+	}
+	tests := []struct {
+		name string
+		text string
+		want result
+	}{
+		{
+			"plain",
+			`{"category": "billing", "confidence": 0.9}`,
+			result{Category: "billing", Confidence: 0.9},
+		},
+		{
+			"fenced",
+			"```json\n{\"category\": \"billing\", \"confidence\": 0.9}\n```",
+			result{Category: "billing", Confidence: 0.9},
+		},
+		{
+			"prose wrapped",
+			`I classified it as follows: {"category": "billing", "confidence": 0.9}. Let me know!`,
+			result{Category: "billing", Confidence: 0.9},
+		},
+		{
+			"bracketed prose before object",
+			`See note [1]. {"category": "billing", "tags": ["a", "b"]}`,
+			result{Category: "billing", Tags: []string{"a", "b"}},
+		},
+		{
+			"repaired",
+			`{'category': 'billing', 'confidence': 0.9, 'tags': ['a',],}`,
+			result{Category: "billing", Confidence: 0.9, Tags: []string{"a"}},
+		},
+		{
+			"truncated object recovered",
+			`{"category": "billing", "confidence": 0.9, "tags": ["a", "b`,
+			result{Category: "billing", Confidence: 0.9, Tags: []string{"a", "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got result
+			if err := Unmarshal(tt.text, &got); err != nil {
+				t.Fatalf("Unmarshal(%q) error: %v", tt.text, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unmarshal(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		into interface{}
+		want interface{}
+	}{
+		{"slice skips object", `{"note": 1} then [1, 2]`, &[]int{}, &[]int{1, 2}},
+		{"map skips array", `[1, 2] then {"a": 1}`, &map[string]int{}, &map[string]int{"a": 1}},
+		{"nested arrays", "```\n[[1, 2], [3]]\n```", &[][]int{}, &[][]int{{1, 2}, {3}}},
+		{"Python literals", `{"a": True, "b": None}`, &map[string]interface{}{}, &map[string]interface{}{"a": true, "b": nil}},
+		{"generic value", `prose {"a": [1]}`, new(interface{}), func() interface{} {
+			var v interface{} = map[string]interface{}{"a": []interface{}{1.0}}
+			return &v
+		}()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Unmarshal(tt.text, tt.into); err != nil {
+				t.Fatalf("Unmarshal(%q) error: %v", tt.text, err)
+			}
+			if !reflect.DeepEqual(tt.into, tt.want) {
+				t.Errorf("Unmarshal(%q) = %v, want %v", tt.text, tt.into, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		into interface{}
+	}{
+		{"empty", "", &map[string]interface{}{}},
+		{"prose", "I could not classify this request.", &map[string]interface{}{}},
+		{"array when object wanted", `[1, 2, 3]`, &struct{ A int }{}},
+		{"object when array wanted", `{"a": 1}`, &[]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Unmarshal(tt.text, tt.into)
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("Unmarshal(%q) error = %v, want ErrNotFound", tt.text, err)
+			}
+		})
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		into     interface{}
+		wantJSON string
+	}{
+		{"type mismatch", `{"a": "not a number"}`, &struct {
+			A int `json:"a"`
+		}{}, `{"a": "not a number"}`},
+		{"repaired but still invalid", `{'a': 1,}`, &struct {
+			A string `json:"a"`
+		}{}, `{"a": 1}`},
+		{"unrepairable", `{"a": 1 2}`, &map[string]int{}, `{"a": 1 2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Unmarshal(tt.text, tt.into)
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("Unmarshal(%q) error = %v, want *DecodeError", tt.text, err)
+			}
+			if decodeErr.JSON != tt.wantJSON {
+				t.Errorf("DecodeError.JSON = %q, want %q", decodeErr.JSON, tt.wantJSON)
+			}
+			if decodeErr.Err == nil {
+				t.Fatal("DecodeError.Err is nil")
+			}
+			if errors.Unwrap(err) != decodeErr.Err {
+				t.Error("DecodeError does not unwrap to Err")
+			}
+			if !strings.HasPrefix(err.Error(), "jsonx: ") || !strings.Contains(err.Error(), decodeErr.Err.Error()) {
+				t.Errorf("Error() = %q, want the jsonx prefix and the cause", err.Error())
+			}
+			if errors.Is(err, ErrNotFound) {
+				t.Error("DecodeError matches ErrNotFound")
+			}
+		})
+	}
+}
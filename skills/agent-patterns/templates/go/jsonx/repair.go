@@ -0,0 +1,231 @@
+/*
+ * JSON Repair for Go Agent Patterns
+ * Fixes trailing commas, quoting, Python literals, and truncated output
+ */
+
+package jsonx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// frame is an open object or array during repair
+type frame struct {
+	close byte
+	// expectKey is true in an object where the next string is a key
+	expectKey bool
+	// keyStart is the output offset of the current key, so a key left
+	// without a value by truncation can be dropped
+	keyStart int
+	// pendingKey is true between a key and its colon or value
+	pendingKey bool
+}
+
+// Repair fixes the defects models commonly introduce into JSON:
+//
+//   - trailing commas before } or ]
+//   - single-quoted strings and unquoted object keys
+//   - Python literals True, False, and None
+//   - raw newlines, tabs, and other control characters inside strings
+//   - // line comments
+//   - truncation: unterminated strings, keys without values, and unclosed
+//     brackets
+//
+// Text after the outermost value is closed is discarded. Repair does not
+// validate its output; decode it to find out whether the repair succeeded.
+func Repair(s string) string {
+	out := make([]byte, 0, len(s)+16)
+	var stack []frame
+
+	// beginKey records where a key starts if one is expected here
+	beginKey := func() {
+		if f := topFrame(stack); f != nil && f.close == '}' && f.expectKey {
+			f.keyStart = len(out)
+			f.pendingKey = true
+			f.expectKey = false
+		}
+	}
+
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '"' || (c == '\'' && valueStart(string(out), len(out))):
+			beginKey()
+			var terminated bool
+			out, i, terminated = appendString(out, s, i)
+			if !terminated {
+				return finish(out, stack, true)
+			}
+			continue
+
+		case c == '/' && i+1 < len(s) && s[i+1] == '/':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			continue
+
+		case c == '{' || c == '[':
+			if c == '{' {
+				stack = append(stack, frame{close: '}', expectKey: true})
+			} else {
+				stack = append(stack, frame{close: ']'})
+			}
+			out = append(out, c)
+
+		case c == '}' || c == ']':
+			if len(stack) == 0 {
+				return string(out)
+			}
+			// Close anything left open inside, e.g. the ] in {"a": [1}
+			for len(stack) > 0 && stack[len(stack)-1].close != c {
+				out = append(trimComma(out), stack[len(stack)-1].close)
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) > 0 {
+				out = append(trimComma(out), c)
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				return string(out)
+			}
+
+		case c == ':':
+			if f := topFrame(stack); f != nil && f.close == '}' {
+				f.pendingKey = false
+			}
+			out = append(out, c)
+
+		case c == ',':
+			if f := topFrame(stack); f != nil && f.close == '}' {
+				f.expectKey = true
+				f.pendingKey = false
+			}
+			out = append(out, c)
+
+		case isWordStart(c):
+			j := i
+			for j < len(s) && isWordPart(s[j]) {
+				j++
+			}
+			word := s[i:j]
+			i = j
+			switch word {
+			case "true", "false", "null":
+				out = append(out, word...)
+			case "True", "False":
+				out = append(out, strings.ToLower(word)...)
+			case "None", "NaN", "Infinity", "undefined":
+				out = append(out, "null"...)
+			default:
+				if f := topFrame(stack); f != nil && f.close == '}' && f.expectKey {
+					beginKey()
+					out = append(out, '"')
+					out = append(out, word...)
+					out = append(out, '"')
+				} else {
+					out = append(out, word...)
+				}
+			}
+			continue
+
+		default:
+			out = append(out, c)
+		}
+		i++
+	}
+	return finish(out, stack, false)
+}
+
+// finish completes truncated output: it drops a dangling key, a trailing
+// comma, or a trailing colon, then closes every open bracket
+func finish(out []byte, stack []frame, truncatedString bool) string {
+	if f := topFrame(stack); f != nil && f.close == '}' {
+		trimmed := trimSpace(out)
+		if f.pendingKey || (len(trimmed) > 0 && trimmed[len(trimmed)-1] == ':') {
+			out = out[:f.keyStart]
+		} else if truncatedString {
+			out = append(out, '"')
+		}
+	} else if truncatedString {
+		out = append(out, '"')
+	}
+
+	out = trimComma(out)
+	for k := len(stack) - 1; k >= 0; k-- {
+		out = append(trimComma(out), stack[k].close)
+	}
+	return string(out)
+}
+
+func topFrame(stack []frame) *frame {
+	if len(stack) == 0 {
+		return nil
+	}
+	return &stack[len(stack)-1]
+}
+
+// appendString copies the string starting at s[i] to out as a valid
+// double-quoted JSON string. It returns the index after the closing quote
+// and false if the input ended first (the closing quote is then missing).
+func appendString(out []byte, s string, i int) ([]byte, int, bool) {
+	quote := s[i]
+	out = append(out, '"')
+	for i++; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return append(out, '"'), i + 1, true
+		case c == '\\':
+			if i+1 >= len(s) {
+				return out, len(s), false
+			}
+			i++
+			if s[i] == '\'' {
+				// \' is not a valid JSON escape
+				out = append(out, '\'')
+			} else {
+				out = append(out, '\\', s[i])
+			}
+		case c == '"':
+			// Only reachable inside single-quoted strings
+			out = append(out, '\\', '"')
+		case c == '\n':
+			out = append(out, '\\', 'n')
+		case c == '\r':
+			out = append(out, '\\', 'r')
+		case c == '\t':
+			out = append(out, '\\', 't')
+		case c < 0x20:
+			out = append(out, fmt.Sprintf(`\u%04x`, c)...)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, len(s), false
+}
+
+// trimComma removes trailing whitespace and a trailing comma
+func trimComma(out []byte) []byte {
+	out = trimSpace(out)
+	if n := len(out); n > 0 && out[n-1] == ',' {
+		out = trimSpace(out[:n-1])
+	}
+	return out
+}
+
+func trimSpace(out []byte) []byte {
+	for len(out) > 0 && strings.IndexByte(" \t\r\n", out[len(out)-1]) >= 0 {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,325 @@
+/*
+ * Rate Limiting for Go Agent Patterns
+ * Token bucket, sliding window, concurrency, and adaptive limiters keyed by provider and model
+ */
+
+// Package limiter throttles LLM requests so throughput limits hold across
+// every pattern sharing a client.
+//
+// A Registry holds limiters for a provider as a whole and for individual
+// models. The client waits on both before each request and reports whether
+// the API throttled it, which adaptive limiters use to back off. Metered
+// limiters such as TokenRate also hold each request to a tokens-per-minute
+// budget, reserved before it is sent and settled against its usage.
+//
+// Example:
+//
+//	limits := limiter.NewRegistry().
+//	    Set("anthropic", limiter.NewConcurrency(8)).
+//	    Set("anthropic/claude-opus-4-20250514", limiter.NewAdaptive(limiter.All(
+//	        limiter.NewTokenBucket(50, 5),
+//	        limiter.NewTokenRate(30000, 8000),
+//	    )))
+//	client.Limits = limits
+package limiter
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Limiter gates requests. Every successful Wait must be followed by exactly
+// one Done once the request has finished.
+type Limiter interface {
+	// Wait blocks until a request may be sent or ctx is done
+	Wait(ctx context.Context) error
+	// Done reports that the request finished, and whether the API
+	// rejected it for exceeding a rate limit (HTTP 429)
+	Done(throttled bool)
+}
+
+// TokenBucket allows bursts of up to burst requests and a sustained rate of
+// perMinute requests per minute
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full token bucket. A perMinute of zero or less
+// disables the limit.
+func NewTokenBucket(perMinute float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   perMinute / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes a token, sleeping until one is available
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if b.rate <= 0 {
+		return nil
+	}
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// Done is a no-op; tokens are not returned
+func (b *TokenBucket) Done(throttled bool) {}
+
+// SlidingWindow allows at most limit requests in any window
+type SlidingWindow struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	sent   []time.Time
+}
+
+// NewSlidingWindow creates a sliding window limiter
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{limit: limit, window: window}
+}
+
+// Wait records a request, sleeping until the window has room
+func (w *SlidingWindow) Wait(ctx context.Context) error {
+	for {
+		w.mu.Lock()
+		now := time.Now()
+		cutoff := now.Add(-w.window)
+		for len(w.sent) > 0 && !w.sent[0].After(cutoff) {
+			w.sent = w.sent[1:]
+		}
+		if len(w.sent) < w.limit {
+			w.sent = append(w.sent, now)
+			w.mu.Unlock()
+			return nil
+		}
+		delay := w.sent[0].Sub(cutoff)
+		w.mu.Unlock()
+
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// Done is a no-op; requests stay in the window until it slides past them
+func (w *SlidingWindow) Done(throttled bool) {}
+
+// Concurrency caps the number of requests in flight
+type Concurrency struct {
+	slots chan struct{}
+}
+
+// NewConcurrency allows up to n requests in flight
+func NewConcurrency(n int) *Concurrency {
+	if n < 1 {
+		n = 1
+	}
+	return &Concurrency{slots: make(chan struct{}, n)}
+}
+
+// Wait takes a slot
+func (c *Concurrency) Wait(ctx context.Context) error {
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done releases the slot
+func (c *Concurrency) Done(throttled bool) {
+	select {
+	case <-c.slots:
+	default:
+	}
+}
+
+// Adaptive pauses every caller after a throttled request, doubling the
+// pause on each further 429 and halving it after each success, so
+// concurrent callers back off together instead of retry-storming. It wraps
+// an optional inner limiter.
+type Adaptive struct {
+	inner          Limiter
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Jitter         time.Duration
+
+	mu         sync.Mutex
+	backoff    time.Duration
+	pauseUntil time.Time
+}
+
+// NewAdaptive wraps inner (which may be nil) with 429 back-off starting at
+// one second and capped at thirty
+func NewAdaptive(inner Limiter) *Adaptive {
+	return &Adaptive{
+		inner:          inner,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		Jitter:         250 * time.Millisecond,
+	}
+}
+
+// Wait waits out any shared pause, then the inner limiter
+func (a *Adaptive) Wait(ctx context.Context) error {
+	for {
+		a.mu.Lock()
+		delay := time.Until(a.pauseUntil)
+		a.mu.Unlock()
+		if delay <= 0 {
+			break
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+	if a.inner != nil {
+		return a.inner.Wait(ctx)
+	}
+	return nil
+}
+
+// Reserve reserves tokens with the inner limiter, if it is Metered
+func (a *Adaptive) Reserve(ctx context.Context, estimate Tokens) error {
+	return reserve(ctx, a.inner, estimate)
+}
+
+// Settle settles a reservation with the inner limiter, if it is Metered
+func (a *Adaptive) Settle(estimate, used Tokens) {
+	settle(a.inner, estimate, used)
+}
+
+// Done adjusts the back-off and reports to the inner limiter
+func (a *Adaptive) Done(throttled bool) {
+	if a.inner != nil {
+		a.inner.Done(throttled)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !throttled {
+		a.backoff /= 2
+		return
+	}
+	if a.backoff == 0 {
+		a.backoff = a.InitialBackoff
+	} else {
+		a.backoff *= 2
+	}
+	if a.MaxBackoff > 0 && a.backoff > a.MaxBackoff {
+		a.backoff = a.MaxBackoff
+	}
+	until := time.Now().Add(a.backoff + Jitter(a.Jitter))
+	if until.After(a.pauseUntil) {
+		a.pauseUntil = until
+	}
+}
+
+// multi applies several limiters in order
+type multi []Limiter
+
+// All combines limiters; a request must pass every one of them. The
+// combination is Metered, reserving tokens with those that are.
+func All(limiters ...Limiter) Metered {
+	return multi(limiters)
+}
+
+func (m multi) Wait(ctx context.Context) error {
+	for i, l := range m {
+		if err := l.Wait(ctx); err != nil {
+			for _, acquired := range m[:i] {
+				acquired.Done(false)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multi) Done(throttled bool) {
+	for _, l := range m {
+		l.Done(throttled)
+	}
+}
+
+func (m multi) Reserve(ctx context.Context, estimate Tokens) error {
+	for i, l := range m {
+		if err := reserve(ctx, l, estimate); err != nil {
+			for _, reserved := range m[:i] {
+				settle(reserved, estimate, Tokens{})
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multi) Settle(estimate, used Tokens) {
+	for _, l := range m {
+		settle(l, estimate, used)
+	}
+}
+
+// reserve reserves tokens with l if it is Metered
+func reserve(ctx context.Context, l Limiter, estimate Tokens) error {
+	if m, ok := l.(Metered); ok {
+		return m.Reserve(ctx, estimate)
+	}
+	return nil
+}
+
+// settle settles a reservation with l if it is Metered
+func settle(l Limiter, estimate, used Tokens) {
+	if m, ok := l.(Metered); ok {
+		m.Settle(estimate, used)
+	}
+}
+
+// Jitter returns a random duration in [0, max)
+func Jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
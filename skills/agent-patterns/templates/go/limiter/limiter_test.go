@@ -0,0 +1,164 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// timed returns how long fn took
+func timed(fn func()) time.Duration {
+	start := time.Now()
+	fn()
+	return time.Since(start)
+}
+
+// waitN calls Wait n times, failing the test on an error
+func waitN(t *testing.T, l Limiter, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	tests := []struct {
+		name string
+		l    Limiter
+		// free requests pass at once, and the next waits at least minWait
+		free    int
+		minWait time.Duration
+	}{
+		{"token bucket burst", NewTokenBucket(600, 3), 3, 80 * time.Millisecond},
+		{"token bucket disabled", NewTokenBucket(0, 1), 10, 0},
+		{"sliding window", NewSlidingWindow(2, 100*time.Millisecond), 2, 80 * time.Millisecond},
+		{"concurrency frees on done", &doneAfterWait{NewConcurrency(1)}, 5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if d := timed(func() { waitN(t, tt.l, tt.free) }); d > 40*time.Millisecond {
+				t.Fatalf("%d free requests took %v", tt.free, d)
+			}
+			d := timed(func() { waitN(t, tt.l, 1) })
+			if d < tt.minWait {
+				t.Errorf("next request waited %v, want at least %v", d, tt.minWait)
+			}
+			if tt.minWait == 0 && d > 40*time.Millisecond {
+				t.Errorf("next request waited %v, want no wait", d)
+			}
+		})
+	}
+}
+
+// doneAfterWait finishes every request as soon as it is let through
+type doneAfterWait struct{ Limiter }
+
+func (d *doneAfterWait) Wait(ctx context.Context) error {
+	if err := d.Limiter.Wait(ctx); err != nil {
+		return err
+	}
+	d.Limiter.Done(false)
+	return nil
+}
+
+func TestLimiterWaitCanceled(t *testing.T) {
+	limiters := map[string]Limiter{
+		"token bucket":   NewTokenBucket(1, 1),
+		"sliding window": NewSlidingWindow(1, time.Minute),
+		"concurrency":    NewConcurrency(1),
+	}
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			waitN(t, l, 1)
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Wait = %v, want DeadlineExceeded", err)
+			}
+		})
+	}
+}
+
+func TestAdaptiveBackoff(t *testing.T) {
+	a := NewAdaptive(nil)
+	a.InitialBackoff = 10 * time.Millisecond
+	a.MaxBackoff = 40 * time.Millisecond
+	a.Jitter = 0
+
+	steps := []struct {
+		throttled bool
+		want      time.Duration
+	}{
+		{true, 10 * time.Millisecond},
+		{true, 20 * time.Millisecond},
+		{true, 40 * time.Millisecond},
+		{true, 40 * time.Millisecond}, // capped
+		{false, 20 * time.Millisecond},
+		{false, 10 * time.Millisecond},
+		{true, 20 * time.Millisecond},
+	}
+	for i, step := range steps {
+		a.Done(step.throttled)
+		if a.backoff != step.want {
+			t.Errorf("step %d: backoff %v, want %v", i, a.backoff, step.want)
+		}
+	}
+}
+
+func TestAdaptivePausesEveryCaller(t *testing.T) {
+	a := NewAdaptive(nil)
+	a.InitialBackoff = 60 * time.Millisecond
+	a.Jitter = 0
+
+	a.Done(true)
+	if d := timed(func() { waitN(t, a, 1) }); d < 50*time.Millisecond {
+		t.Errorf("Wait after a 429 took %v, want the 60ms pause", d)
+	}
+	// The pause has passed; a success does not start another
+	a.Done(false)
+	if d := timed(func() { waitN(t, a, 1) }); d > 20*time.Millisecond {
+		t.Errorf("Wait after the pause took %v, want no wait", d)
+	}
+}
+
+func TestAdaptiveSettlesInnerTokenRate(t *testing.T) {
+	// 600 tokens a minute refill at 10 a second
+	a := NewAdaptive(NewTokenRate(600, 600))
+	ctx := context.Background()
+	estimate := Tokens{Input: 600, Output: 100}
+
+	if err := a.Reserve(ctx, estimate); err != nil {
+		t.Fatal(err)
+	}
+	// The bucket is empty, so a reservation waits until a settlement
+	// returns the unused tokens
+	done := make(chan time.Duration)
+	go func() {
+		done <- timed(func() {
+			if err := a.Reserve(ctx, Tokens{Input: 200}); err != nil {
+				t.Error(err)
+			}
+		})
+	}()
+	time.Sleep(30 * time.Millisecond)
+	a.Settle(estimate, Tokens{Input: 100, Output: 20})
+	select {
+	case d := <-done:
+		if d > 500*time.Millisecond {
+			t.Errorf("reservation took %v after the settlement", d)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("settlement did not wake the reservation")
+	}
+
+	// Usage beyond the estimate is taken from the bucket
+	a.Settle(Tokens{Input: 10}, Tokens{Input: 400})
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	if err := a.Reserve(ctx, Tokens{Input: 100}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Reserve after overuse = %v, want DeadlineExceeded", err)
+	}
+}
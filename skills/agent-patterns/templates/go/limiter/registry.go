@@ -0,0 +1,70 @@
+/*
+ * Limiter Registry for Go Agent Patterns
+ * Provider-wide and per-model limiters shared by every client and pattern
+ */
+
+package limiter
+
+import (
+	"context"
+	"sync"
+)
+
+// ProviderAnthropic is the provider key used by AnthropicClient
+const ProviderAnthropic = "anthropic"
+
+// Registry maps provider and model keys to limiters. Keys are a provider
+// name such as "anthropic", applying to every model, or "provider/model"
+// for a single model. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	limiters map[string]Limiter
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{limiters: make(map[string]Limiter)}
+}
+
+// Set installs l under key, replacing any previous limiter (builder pattern)
+func (r *Registry) Set(key string, l Limiter) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limiters[key] = l
+	return r
+}
+
+// lookup returns the provider-wide and model limiters that apply
+func (r *Registry) lookup(provider, model string) []Limiter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var ls []Limiter
+	if l, ok := r.limiters[provider]; ok {
+		ls = append(ls, l)
+	}
+	if l, ok := r.limiters[provider+"/"+model]; ok {
+		ls = append(ls, l)
+	}
+	return ls
+}
+
+// Wait blocks until both the provider and the model limiters admit a request
+func (r *Registry) Wait(ctx context.Context, provider, model string) error {
+	return multi(r.lookup(provider, model)).Wait(ctx)
+}
+
+// Done reports a finished request to the provider and model limiters
+func (r *Registry) Done(provider, model string, throttled bool) {
+	multi(r.lookup(provider, model)).Done(throttled)
+}
+
+// Reserve reserves a request's estimated tokens with the provider and model
+// limiters that are Metered
+func (r *Registry) Reserve(ctx context.Context, provider, model string, estimate Tokens) error {
+	return multi(r.lookup(provider, model)).Reserve(ctx, estimate)
+}
+
+// Settle corrects a reservation to the tokens the request used
+func (r *Registry) Settle(provider, model string, estimate, used Tokens) {
+	multi(r.lookup(provider, model)).Settle(estimate, used)
+}
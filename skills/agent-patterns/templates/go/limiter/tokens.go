@@ -0,0 +1,156 @@
+/*
+ * Token Limits for Go Agent Patterns
+ * Input and output tokens per minute, reserved before a request and settled after it
+ */
+
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Tokens counts the input and output tokens of a request
+type Tokens struct {
+	Input  int
+	Output int
+}
+
+// Metered is a Limiter that also limits tokens. Before each request the
+// client reserves an estimate of its tokens: its prompt, roughly, and its
+// max tokens of output, as the API itself estimates output. Once the
+// request has finished, the client settles the reservation against the
+// usage the API reported, returning what went unused.
+type Metered interface {
+	Limiter
+	// Reserve blocks until estimate fits within the limits or ctx is done
+	Reserve(ctx context.Context, estimate Tokens) error
+	// Settle corrects a reservation of estimate to the tokens used
+	Settle(estimate, used Tokens)
+}
+
+// TokenRate limits input and output tokens per minute, as the API's ITPM
+// and OTPM limits do, allowing bursts of up to a minute's worth. A request
+// estimated at more than a minute's worth waits for a full minute's worth.
+type TokenRate struct {
+	mu     sync.Mutex
+	input  tokenLevel
+	output tokenLevel
+	last   time.Time
+	// settled is closed, and replaced, when a settlement returns tokens,
+	// waking reservations to try again
+	settled chan struct{}
+}
+
+// tokenLevel is one bucket of a TokenRate. Its level goes negative when
+// requests take more than it holds, and refills at rate.
+type tokenLevel struct {
+	rate     float64 // tokens per second
+	capacity float64
+	level    float64
+}
+
+// NewTokenRate creates full token buckets. A limit of zero or less
+// disables it.
+func NewTokenRate(inputPerMinute, outputPerMinute int) *TokenRate {
+	return &TokenRate{
+		input:   newTokenLevel(inputPerMinute),
+		output:  newTokenLevel(outputPerMinute),
+		last:    time.Now(),
+		settled: make(chan struct{}),
+	}
+}
+
+func newTokenLevel(perMinute int) tokenLevel {
+	if perMinute <= 0 {
+		return tokenLevel{}
+	}
+	return tokenLevel{rate: float64(perMinute) / 60, capacity: float64(perMinute), level: float64(perMinute)}
+}
+
+// Wait is a no-op; tokens are taken by Reserve
+func (t *TokenRate) Wait(ctx context.Context) error { return nil }
+
+// Done is a no-op; tokens are returned by Settle
+func (t *TokenRate) Done(throttled bool) {}
+
+// Reserve takes the estimated tokens, sleeping until both buckets hold them.
+// Tokens returned by Settle wake it early.
+func (t *TokenRate) Reserve(ctx context.Context, estimate Tokens) error {
+	for {
+		t.mu.Lock()
+		t.refill()
+		delay := t.input.delay(estimate.Input)
+		if d := t.output.delay(estimate.Output); d > delay {
+			delay = d
+		}
+		if delay == 0 {
+			t.input.add(float64(-estimate.Input))
+			t.output.add(float64(-estimate.Output))
+			t.mu.Unlock()
+			return nil
+		}
+		settled := t.settled
+		t.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		case <-settled:
+			timer.Stop()
+		}
+	}
+}
+
+// Settle returns the reserved tokens that went unused, or takes those
+// used beyond the estimate
+func (t *TokenRate) Settle(estimate, used Tokens) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.refill()
+	t.input.add(float64(estimate.Input - used.Input))
+	t.output.add(float64(estimate.Output - used.Output))
+	if used.Input < estimate.Input || used.Output < estimate.Output {
+		close(t.settled)
+		t.settled = make(chan struct{})
+	}
+}
+
+func (t *TokenRate) refill() {
+	now := time.Now()
+	elapsed := now.Sub(t.last).Seconds()
+	t.last = now
+	t.input.add(elapsed * t.input.rate)
+	t.output.add(elapsed * t.output.rate)
+}
+
+// delay returns how long until the bucket holds n tokens, capped at its
+// capacity
+func (l *tokenLevel) delay(n int) time.Duration {
+	need := float64(n)
+	if need > l.capacity {
+		need = l.capacity
+	}
+	if l.rate <= 0 || l.level >= need {
+		return 0
+	}
+	d := time.Duration((need - l.level) / l.rate * float64(time.Second))
+	if d < time.Millisecond {
+		d = time.Millisecond
+	}
+	return d
+}
+
+func (l *tokenLevel) add(n float64) {
+	if l.rate <= 0 {
+		return
+	}
+	l.level += n
+	if l.level > l.capacity {
+		l.level = l.capacity
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * MCP Tool Integration for Go Agent Patterns
+ * Use the tools of Model Context Protocol servers as agent tools and workers
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/jsonx"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/mcp"
+)
+
+// MCPTools lists the tools of an MCP server as agent tools whose handlers
+// call the server. A result the tool marks as an error fails the call.
+//
+// Example:
+//
+//	transport := mcp.NewHTTPTransport("https://tools.example.com/mcp")
+//	server, err := mcp.Connect(ctx, transport)
+//	defer server.Close()
+//	tools, err := MCPTools(ctx, server)
+//	for _, tool := range tools {
+//	    tool.Timeout = 30 * time.Second
+//	    agent.RegisterTool(tool)
+//	}
+func MCPTools(ctx context.Context, server *mcp.Client) ([]AgentTool, error) {
+	tools, err := server.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	agentTools := make([]AgentTool, 0, len(tools))
+	for _, tool := range tools {
+		params, err := mcpParameters(tool.InputSchema)
+		if err != nil {
+			return nil, fmt.Errorf("mcp tool %s: %w", tool.Name, err)
+		}
+		name := tool.Name
+		agentTools = append(agentTools, AgentTool{
+			Name:        name,
+			Description: tool.Description,
+			Parameters:  params,
+			Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+				return callMCPTool(ctx, server, name, args)
+			},
+		})
+	}
+	return agentTools, nil
+}
+
+// RegisterMCPTools registers every tool of an MCP server
+func (a *AutonomousAgent) RegisterMCPTools(ctx context.Context, server *mcp.Client) error {
+	tools, err := MCPTools(ctx, server)
+	if err != nil {
+		return err
+	}
+	for _, tool := range tools {
+		a.RegisterTool(tool)
+	}
+	return nil
+}
+
+// RegisterMCPWorkers registers a worker for every tool of an MCP server,
+// using the orchestrator's client to fill in tool arguments
+func (o *Orchestrator) RegisterMCPWorkers(ctx context.Context, server *mcp.Client, opts ...Option) error {
+	tools, err := server.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tool := range tools {
+		o.RegisterWorker(NewMCPWorker(o.client, server, tool, opts...))
+	}
+	return nil
+}
+
+// MCPWorker runs an MCP tool for each subtask. The model turns the subtask
+// description and dependency results into the tool's arguments. The worker
+// type is the tool name.
+type MCPWorker struct {
+	client *AnthropicClient
+	server *mcp.Client
+	tool   mcp.Tool
+	cfg    patternConfig
+}
+
+// NewMCPWorker creates a worker backed by tool on server
+func NewMCPWorker(client *AnthropicClient, server *mcp.Client, tool mcp.Tool, opts ...Option) *MCPWorker {
+	return &MCPWorker{
+		client: client,
+		server: server,
+		tool:   tool,
+		cfg:    newPatternConfig("mcp_worker", opts),
+	}
+}
+
+// WorkerType returns the tool name
+func (w *MCPWorker) WorkerType() string {
+	return w.tool.Name
+}
+
+// Execute fills in the tool's arguments and calls it
+func (w *MCPWorker) Execute(ctx context.Context, subtask *OrchestratorSubtask, depResults map[string]string) (string, error) {
+	var deps []map[string]interface{}
+	for _, k := range sortedKeys(depResults) {
+		quoted, err := w.cfg.quote(ctx, "subtask:"+k, depResults[k])
+		if err != nil {
+			return "", err
+		}
+		deps = append(deps, map[string]interface{}{"subtask": k, "output": quoted})
+	}
+
+	inputSchema := string(w.tool.InputSchema)
+	if inputSchema == "" {
+		inputSchema = `{"type": "object"}`
+	}
+	prompt, err := w.cfg.prompt("mcp.arguments", map[string]interface{}{
+		"tool":        w.tool.Name,
+		"description": w.tool.Description,
+		"task":        subtask.Description,
+		"context":     deps,
+		"schema":      inputSchema,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	response, err := w.cfg.call(ctx, w.client, prompt, w.cfg.model, w.cfg.tokens(1024))
+	if err != nil {
+		return "", err
+	}
+	var args map[string]interface{}
+	if err := jsonx.Unmarshal(response, &args); err != nil {
+		return "", fmt.Errorf("failed to parse arguments for %s: %w", w.tool.Name, err)
+	}
+	return callMCPTool(ctx, w.server, w.tool.Name, args)
+}
+
+func callMCPTool(ctx context.Context, server *mcp.Client, name string, args map[string]interface{}) (string, error) {
+	result, err := server.CallTool(ctx, name, args)
+	if err != nil {
+		return "", err
+	}
+	if result.IsError {
+		return "", fmt.Errorf("%s", result.Text())
+	}
+	return result.Text(), nil
+}
+
+// mcpParameters converts a tool's JSON input schema to parameter
+// definitions. Types may be a name or a list of names, as in
+// ["string", "null"]; the first non-null name is used.
+func mcpParameters(inputSchema json.RawMessage) (map[string]ParameterDef, error) {
+	params := map[string]ParameterDef{}
+	if len(inputSchema) == 0 {
+		return params, nil
+	}
+	var s struct {
+		Properties map[string]struct {
+			Type        json.RawMessage `json:"type"`
+			Description string          `json:"description"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(inputSchema, &s); err != nil {
+		return nil, fmt.Errorf("invalid input schema: %w", err)
+	}
+
+	for name, prop := range s.Properties {
+		var typ string
+		var types []string
+		if json.Unmarshal(prop.Type, &typ) != nil && json.Unmarshal(prop.Type, &types) == nil {
+			for _, t := range types {
+				if t != "null" {
+					typ = t
+					break
+				}
+			}
+		}
+		params[name] = ParameterDef{Type: typ, Description: prop.Description}
+	}
+	for _, name := range s.Required {
+		if param, ok := params[name]; ok {
+			param.Required = true
+			params[name] = param
+		}
+	}
+	return params, nil
+}
@@ -0,0 +1,181 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// HTTPTransport speaks the streamable HTTP transport: each message is
+// POSTed to a single endpoint, which answers with JSON or with a stream of
+// server-sent events ending in the response. The session ID the server
+// assigns is sent with every later request.
+type HTTPTransport struct {
+	url        string
+	httpClient *http.Client
+	headers    http.Header
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+// NewHTTPTransport creates a transport for the endpoint at url
+func NewHTTPTransport(url string) *HTTPTransport {
+	return &HTTPTransport{url: url, httpClient: http.DefaultClient, headers: http.Header{}}
+}
+
+// WithHTTPClient sets the HTTP client used for requests
+func (t *HTTPTransport) WithHTTPClient(c *http.Client) *HTTPTransport {
+	t.httpClient = c
+	return t
+}
+
+// SetHeader adds a header to every request, e.g. for authorization
+func (t *HTTPTransport) SetHeader(key, value string) *HTTPTransport {
+	t.headers.Set(key, value)
+	return t
+}
+
+// Call implements Transport
+func (t *HTTPTransport) Call(ctx context.Context, id int64, method string, params interface{}) (json.RawMessage, error) {
+	msg := newRequest(id, method, params)
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	var reply message
+	if mediaType == "text/event-stream" {
+		reply, err = readEvents(resp.Body, string(msg.ID))
+	} else {
+		err = json.NewDecoder(resp.Body).Decode(&reply)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid mcp response: %w", err)
+	}
+	if reply.Error != nil {
+		return nil, reply.Error
+	}
+	return reply.Result, nil
+}
+
+// Notify implements Transport
+func (t *HTTPTransport) Notify(ctx context.Context, method string, params interface{}) error {
+	resp, err := t.post(ctx, newRequest(-1, method, params))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	return nil
+}
+
+// Close ends the session on the server, if it assigned one
+func (t *HTTPTransport) Close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	t.setHeaders(req)
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	// Servers may not allow clients to end sessions
+	return nil
+}
+
+func (t *HTTPTransport) post(ctx context.Context, msg message) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("mcp request failed: %w", err)
+	}
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+func (t *HTTPTransport) setHeaders(req *http.Request) {
+	for key, values := range t.headers {
+		req.Header[key] = values
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	t.mu.Unlock()
+}
+
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("mcp server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
+
+// readEvents reads server-sent events until the response to the request
+// with id, skipping the server's own requests and notifications
+func readEvents(r io.Reader, id string) (message, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		// A blank line ends the event
+		var msg message
+		err := json.Unmarshal([]byte(data.String()), &msg)
+		data.Reset()
+		if err == nil && msg.isResponse() && string(msg.ID) == id {
+			return msg, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return message{}, err
+	}
+	return message{}, fmt.Errorf("event stream ended without a response")
+}
@@ -0,0 +1,221 @@
+/*
+ * MCP Client for Go Agent Patterns
+ * Model Context Protocol tools over stdio and streamable HTTP
+ */
+
+// Package mcp is a Model Context Protocol client for using the tools of
+// existing MCP servers. It speaks JSON-RPC 2.0 over a Transport: a
+// subprocess's stdio, or an HTTP endpoint that may stream its responses as
+// server-sent events.
+//
+// Example:
+//
+//	transport, err := mcp.NewStdioTransport(exec.Command("npx", "-y", "@modelcontextprotocol/server-filesystem", "/data"))
+//	server, err := mcp.Connect(ctx, transport)
+//	defer server.Close()
+//	err = agent.RegisterMCPTools(ctx, server)
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ProtocolVersion is the MCP revision this client requests
+const ProtocolVersion = "2025-03-26"
+
+// Transport carries JSON-RPC messages to and from a server. Implementations
+// must be safe for concurrent use.
+type Transport interface {
+	// Call sends a request and returns the result of the response with the
+	// same ID
+	Call(ctx context.Context, id int64, method string, params interface{}) (json.RawMessage, error)
+	// Notify sends a notification, which has no response
+	Notify(ctx context.Context, method string, params interface{}) error
+	Close() error
+}
+
+// RPCError is a JSON-RPC error returned by the server
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// message is any JSON-RPC 2.0 message
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// newRequest builds a request, or a notification if id is negative
+func newRequest(id int64, method string, params interface{}) message {
+	msg := message{JSONRPC: "2.0", Method: method, Params: params}
+	if id >= 0 {
+		msg.ID = json.RawMessage(fmt.Sprint(id))
+	}
+	return msg
+}
+
+// isResponse reports whether msg answers a request rather than being a
+// request or notification from the server
+func (m *message) isResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+// ServerInfo identifies a connected server
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Tool is a tool offered by a server. InputSchema is the JSON schema of
+// its arguments.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+// Content is one item of a tool result
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	// Data is base64-encoded image or audio content
+	Data string `json:"data,omitempty"`
+}
+
+// ToolResult is the outcome of a tool call. IsError marks a failure the
+// tool reported, as opposed to a protocol error.
+type ToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Text returns the result's text content, one item per line, with other
+// content types noted by type
+func (r *ToolResult) Text() string {
+	parts := make([]string, 0, len(r.Content))
+	for _, c := range r.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+			continue
+		}
+		label := c.Type
+		if c.MimeType != "" {
+			label += " " + c.MimeType
+		}
+		parts = append(parts, "["+label+" content]")
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Client is a connection to one MCP server
+type Client struct {
+	transport Transport
+	nextID    int64
+	// Server identifies the server, as reported during initialization
+	Server ServerInfo
+	// Instructions are the server's optional usage hints for the model
+	Instructions string
+}
+
+// Connect initializes a session with the server on transport
+func Connect(ctx context.Context, transport Transport) (*Client, error) {
+	c := &Client{transport: transport}
+	var init struct {
+		ProtocolVersion string     `json:"protocolVersion"`
+		ServerInfo      ServerInfo `json:"serverInfo"`
+		Instructions    string     `json:"instructions"`
+	}
+	err := c.call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      ServerInfo{Name: "agentpatterns", Version: "1.0.0"},
+	}, &init)
+	if err != nil {
+		return nil, fmt.Errorf("mcp initialize failed: %w", err)
+	}
+	c.Server = init.ServerInfo
+	c.Instructions = init.Instructions
+
+	if err := transport.Notify(ctx, "notifications/initialized", nil); err != nil {
+		return nil, fmt.Errorf("mcp initialize failed: %w", err)
+	}
+	return c, nil
+}
+
+// ListTools returns every tool the server offers, following pagination
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var params map[string]interface{}
+		if cursor != "" {
+			params = map[string]interface{}{"cursor": cursor}
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("mcp tools/list failed: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool calls a tool with args
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*ToolResult, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	var result ToolResult
+	if err := c.call(ctx, "tools/call", map[string]interface{}{"name": name, "arguments": args}, &result); err != nil {
+		return nil, fmt.Errorf("mcp tool %s failed: %w", name, err)
+	}
+	return &result, nil
+}
+
+// Close ends the session and releases the transport
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+func (c *Client) call(ctx context.Context, method string, params, out interface{}) error {
+	id := atomic.AddInt64(&c.nextID, 1)
+	result, err := c.transport.Call(ctx, id, method, params)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Tell the server to stop work no one is waiting for
+			notifyCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			c.transport.Notify(notifyCtx, "notifications/cancelled", map[string]interface{}{"requestId": id, "reason": ctx.Err().Error()})
+		}
+		return err
+	}
+	if out == nil || len(result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(result, out); err != nil {
+		return fmt.Errorf("invalid %s result: %w", method, err)
+	}
+	return nil
+}
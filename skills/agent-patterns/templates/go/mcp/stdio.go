@@ -0,0 +1,162 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// StdioTransport runs a server as a subprocess and exchanges
+// newline-delimited JSON-RPC messages over its stdin and stdout. The
+// server's stderr is passed through to this process's stderr.
+type StdioTransport struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[string]chan message
+	err     error
+	done    chan struct{}
+}
+
+// NewStdioTransport starts cmd and reads its responses in the background
+func NewStdioTransport(cmd *exec.Cmd) (*StdioTransport, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start mcp server: %w", err)
+	}
+
+	t := &StdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[string]chan message),
+		done:    make(chan struct{}),
+	}
+	go t.read(stdout)
+	return t, nil
+}
+
+// Call implements Transport
+func (t *StdioTransport) Call(ctx context.Context, id int64, method string, params interface{}) (json.RawMessage, error) {
+	msg := newRequest(id, method, params)
+	key := string(msg.ID)
+	ch := make(chan message, 1)
+
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, t.err
+	}
+	t.pending[key] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(msg); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-t.done:
+		return nil, t.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Notify implements Transport
+func (t *StdioTransport) Notify(ctx context.Context, method string, params interface{}) error {
+	return t.write(newRequest(-1, method, params))
+}
+
+// Close closes the server's stdin, which asks it to exit, and waits for it
+func (t *StdioTransport) Close() error {
+	t.stdin.Close()
+	err := t.cmd.Wait()
+	<-t.done
+	return err
+}
+
+func (t *StdioTransport) write(msg message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to mcp server: %w", err)
+	}
+	return nil
+}
+
+// read dispatches responses to waiting calls until the server's stdout
+// closes, then fails any calls still waiting
+func (t *StdioTransport) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue // servers may log stray lines to stdout
+		}
+		if !msg.isResponse() {
+			t.answer(msg)
+			continue
+		}
+		t.mu.Lock()
+		ch := t.pending[string(msg.ID)]
+		t.mu.Unlock()
+		if ch != nil {
+			ch <- msg
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	t.mu.Lock()
+	t.err = fmt.Errorf("mcp server closed: %w", err)
+	t.mu.Unlock()
+	close(t.done)
+}
+
+// answer replies to a request from the server: pings succeed and anything
+// else is unsupported. Notifications are ignored.
+func (t *StdioTransport) answer(msg message) {
+	if len(msg.ID) == 0 {
+		return
+	}
+	reply := message{JSONRPC: "2.0", ID: msg.ID}
+	if msg.Method == "ping" {
+		reply.Result = json.RawMessage("{}")
+	} else {
+		reply.Error = &RPCError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	t.write(reply)
+}
@@ -0,0 +1,316 @@
+/*
+ * MCP Server for Go Agent Patterns
+ * Publishes chains, routers, orchestrators, agents, and RAG as MCP tools
+ */
+
+// Package mcpserver publishes configured patterns as Model Context Protocol
+// tools, so MCP hosts such as Claude Desktop and Claude Code can run a
+// Go-hosted chain, router, orchestrator, agent, or RAG pipeline directly.
+// Serve it over stdio for a host that launches the server, or mount it as
+// an http.Handler for the streamable HTTP transport.
+//
+// Example:
+//
+//	srv := mcpserver.New("support-tools", "1.0.0")
+//	srv.AddRouter("triage", "Route a customer message to the right team", router, 0.7)
+//	srv.AddRAG("search_docs", "Answer questions from the product documentation", rag)
+//	srv.AddAgent("investigate", "Investigate an incident using the ops tools", agent, 15)
+//	err := srv.ServeStdio(ctx, os.Stdin, os.Stdout)
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/mcp"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/schema"
+)
+
+// Handler runs a tool with its arguments
+type Handler func(ctx context.Context, args map[string]interface{}) (string, error)
+
+// Tool is a tool the server publishes. Arguments are validated against
+// InputSchema before Handler runs.
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema *schema.Schema
+	Handler     Handler
+}
+
+// StringRouter routes text to a handler producing text, as Router[string]
+// and EmbeddingRouter[string] do
+type StringRouter interface {
+	Route(ctx context.Context, input string, confidenceThreshold float64) (string, *agentpatterns.ClassificationResult, error)
+}
+
+// Server publishes tools to MCP clients
+type Server struct {
+	info         mcp.ServerInfo
+	instructions string
+
+	mu    sync.RWMutex
+	tools map[string]Tool
+	order []string
+}
+
+// New creates a server that identifies itself to clients by name and
+// version
+func New(name, version string) *Server {
+	return &Server{
+		info:  mcp.ServerInfo{Name: name, Version: version},
+		tools: make(map[string]Tool),
+	}
+}
+
+// SetInstructions tells clients how to use the server's tools; hosts may
+// add them to the model's system prompt
+func (s *Server) SetInstructions(instructions string) *Server {
+	s.instructions = instructions
+	return s
+}
+
+// AddTool publishes a tool, replacing any with the same name
+func (s *Server) AddTool(tool Tool) *Server {
+	if tool.InputSchema == nil {
+		tool.InputSchema = &schema.Schema{Type: "object"}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.tools[tool.Name]; !exists {
+		s.order = append(s.order, tool.Name)
+	}
+	s.tools[tool.Name] = tool
+	return s
+}
+
+// AddAgentTool publishes a tool written for AutonomousAgent
+func (s *Server) AddAgentTool(tool agentpatterns.AgentTool) *Server {
+	inputSchema := &schema.Schema{Type: "object", Properties: map[string]*schema.Schema{}}
+	for name, param := range tool.Parameters {
+		inputSchema.Properties[name] = &schema.Schema{Type: param.Type, Description: param.Description}
+		if param.Required {
+			inputSchema.Required = append(inputSchema.Required, name)
+		}
+	}
+	sort.Strings(inputSchema.Required)
+	return s.AddTool(Tool{
+		Name:        tool.Name,
+		Description: tool.Description,
+		InputSchema: inputSchema,
+		Handler:     tool.Handler,
+	})
+}
+
+// AddChain publishes a chain that runs with its "input" context variable
+// set to the tool's input. Calls run one at a time, since a chain keeps
+// per-run history.
+func (s *Server) AddChain(name, description string, chain *agentpatterns.PromptChain) *Server {
+	return s.addTextTool(name, description, "Input to the chain", serialized(func(ctx context.Context, input string) (string, error) {
+		return chain.Execute(ctx, map[string]interface{}{"input": input})
+	}))
+}
+
+// AddRouter publishes a router. Inputs classified below
+// confidenceThreshold go to the router's fallback.
+func (s *Server) AddRouter(name, description string, router StringRouter, confidenceThreshold float64) *Server {
+	return s.addTextTool(name, description, "Input to classify and handle", func(ctx context.Context, input string) (string, error) {
+		result, _, err := router.Route(ctx, input, confidenceThreshold)
+		return result, err
+	})
+}
+
+// AddOrchestrator publishes an orchestrator that decomposes the tool's
+// input as its task. Calls run one at a time.
+func (s *Server) AddOrchestrator(name, description string, orch *agentpatterns.Orchestrator) *Server {
+	return s.addTextTool(name, description, "Task to decompose and carry out", serialized(func(ctx context.Context, task string) (string, error) {
+		result, err := orch.Execute(ctx, task)
+		if err != nil {
+			return "", err
+		}
+		return result.FinalResult, nil
+	}))
+}
+
+// AddAgent publishes an agent that works on the tool's input for at most
+// maxSteps. Calls run one at a time, since an agent keeps per-run state.
+func (s *Server) AddAgent(name, description string, agent *agentpatterns.AutonomousAgent, maxSteps int) *Server {
+	return s.addTextTool(name, description, "Task for the agent", serialized(func(ctx context.Context, task string) (string, error) {
+		result, err := agent.Run(ctx, task, maxSteps)
+		if err != nil {
+			return "", err
+		}
+		if !result.Success {
+			return "", fmt.Errorf("agent did not complete within %d steps", maxSteps)
+		}
+		return result.FinalResult, nil
+	}))
+}
+
+// AddRAG publishes a RAG pipeline that answers the tool's input, listing
+// the IDs of the sources it retrieved
+func (s *Server) AddRAG(name, description string, rag *agentpatterns.RAG) *Server {
+	return s.addTextTool(name, description, "Question to answer from the knowledge base", func(ctx context.Context, question string) (string, error) {
+		result, err := rag.RetrieveAndAnswer(ctx, question)
+		if err != nil {
+			return "", err
+		}
+		if len(result.Sources) == 0 {
+			return result.Answer, nil
+		}
+		ids := make([]string, len(result.Sources))
+		for i, source := range result.Sources {
+			ids[i] = source.ID
+		}
+		return fmt.Sprintf("%s\n\nSources: %s", result.Answer, strings.Join(ids, ", ")), nil
+	})
+}
+
+// addTextTool publishes a tool taking a single "input" string
+func (s *Server) addTextTool(name, description, inputDescription string, fn func(ctx context.Context, input string) (string, error)) *Server {
+	return s.AddTool(Tool{
+		Name:        name,
+		Description: description,
+		InputSchema: &schema.Schema{
+			Type: "object",
+			Properties: map[string]*schema.Schema{
+				"input": {Type: "string", Description: inputDescription},
+			},
+			Required: []string{"input"},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			input, _ := args["input"].(string)
+			return fn(ctx, input)
+		},
+	})
+}
+
+// serialized runs fn for one call at a time
+func serialized(fn func(ctx context.Context, input string) (string, error)) func(ctx context.Context, input string) (string, error) {
+	var mu sync.Mutex
+	return func(ctx context.Context, input string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return fn(ctx, input)
+	}
+}
+
+// request is a JSON-RPC request or notification from a client
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response to a client
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *mcp.RPCError   `json:"error,omitempty"`
+}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// handle answers a request. It returns nil for a notification, which has
+// no response.
+func (s *Server) handle(ctx context.Context, req *request) *response {
+	result, rpcErr := s.dispatch(ctx, req)
+	if len(req.ID) == 0 {
+		return nil
+	}
+	resp := &response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}
+	if rpcErr == nil && result == nil {
+		resp.Result = struct{}{}
+	}
+	return resp
+}
+
+func (s *Server) dispatch(ctx context.Context, req *request) (interface{}, *mcp.RPCError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		// Answer with the client's version when it is one this server
+		// speaks, otherwise the server's own for the client to decide
+		version := mcp.ProtocolVersion
+		if params.ProtocolVersion == "2024-11-05" {
+			version = params.ProtocolVersion
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      s.info,
+			"instructions":    s.instructions,
+		}, nil
+	case "ping":
+		return nil, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": s.listTools()}, nil
+	case "tools/call":
+		return s.callTool(ctx, req.Params)
+	}
+	if strings.HasPrefix(req.Method, "notifications/") {
+		return nil, nil
+	}
+	return nil, &mcp.RPCError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+func (s *Server) listTools() []mcp.Tool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tools := make([]mcp.Tool, 0, len(s.order))
+	for _, name := range s.order {
+		tool := s.tools[name]
+		inputSchema, _ := json.Marshal(tool.InputSchema)
+		tools = append(tools, mcp.Tool{Name: tool.Name, Description: tool.Description, InputSchema: inputSchema})
+	}
+	return tools
+}
+
+// callTool runs a tool. Failures of the tool itself are returned as error
+// results, so the calling model can see them and adapt.
+func (s *Server) callTool(ctx context.Context, raw json.RawMessage) (interface{}, *mcp.RPCError) {
+	var params struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &mcp.RPCError{Code: codeInvalidParams, Message: "invalid tools/call params: " + err.Error()}
+	}
+	s.mu.RLock()
+	tool, ok := s.tools[params.Name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, &mcp.RPCError{Code: codeInvalidParams, Message: "unknown tool: " + params.Name}
+	}
+	if params.Arguments == nil {
+		params.Arguments = map[string]interface{}{}
+	}
+
+	if err := tool.InputSchema.Validate(params.Arguments); err != nil {
+		return errorResult(err), nil
+	}
+	output, err := tool.Handler(ctx, params.Arguments)
+	if err != nil {
+		return errorResult(err), nil
+	}
+	return mcp.ToolResult{Content: []mcp.Content{{Type: "text", Text: output}}}, nil
+}
+
+func errorResult(err error) mcp.ToolResult {
+	return mcp.ToolResult{Content: []mcp.Content{{Type: "text", Text: err.Error()}}, IsError: true}
+}
@@ -0,0 +1,143 @@
+package mcpserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/mcp"
+)
+
+// ServeStdio serves newline-delimited JSON-RPC messages from in, writing
+// responses to out, until in is closed or ctx is done. Requests run
+// concurrently, and a client's notifications/cancelled cancels the request
+// it names. Log to stderr, not out, when serving over stdout.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var writeMu sync.Mutex
+	write := func(resp *response) {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		out.Write(append(data, '\n'))
+	}
+
+	var mu sync.Mutex
+	inFlight := make(map[string]context.CancelFunc)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			select {
+			case lines <- append([]byte(nil), scanner.Bytes()...):
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		var line []byte
+		select {
+		case line = <-lines:
+		case err := <-readErr:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			write(&response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &mcp.RPCError{Code: codeParseError, Message: err.Error()}})
+			continue
+		}
+		if req.Method == "" {
+			continue // a response to a request this server never sends
+		}
+		if req.Method == "notifications/cancelled" {
+			var params struct {
+				RequestID json.RawMessage `json:"requestId"`
+			}
+			json.Unmarshal(req.Params, &params)
+			mu.Lock()
+			if cancelRequest, ok := inFlight[string(params.RequestID)]; ok {
+				cancelRequest()
+			}
+			mu.Unlock()
+			continue
+		}
+
+		reqCtx, cancelRequest := context.WithCancel(ctx)
+		key := string(req.ID)
+		if key != "" {
+			mu.Lock()
+			inFlight[key] = cancelRequest
+			mu.Unlock()
+		}
+		wg.Add(1)
+		go func(req request) {
+			defer wg.Done()
+			defer cancelRequest()
+			resp := s.handle(reqCtx, &req)
+			if key != "" {
+				mu.Lock()
+				delete(inFlight, key)
+				mu.Unlock()
+			}
+			// A cancelled request gets no response
+			if resp != nil && reqCtx.Err() == nil {
+				write(resp)
+			}
+		}(req)
+	}
+}
+
+// ServeHTTP implements http.Handler for the streamable HTTP transport.
+// Each POSTed request is answered with a JSON response; notifications are
+// accepted with 202. The server keeps no sessions, so it does not stream
+// or offer a GET event stream.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(io.LimitReader(r.Body, 16*1024*1024)).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &mcp.RPCError{Code: codeParseError, Message: err.Error()}})
+		return
+	}
+
+	resp := s.handle(r.Context(), &req)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,96 @@
+/*
+ * Agent Memory for Go Agent Patterns
+ * Findings that an agent carries from one run to the next
+ */
+
+// Package memory defines the Memory interface used by AutonomousAgent to
+// remember actions and learned facts across runs, with in-memory, JSON
+// Lines file, and SQLite implementations.
+//
+// Example:
+//
+//	mem, err := memory.NewFileMemory("./agent-memory.jsonl")
+//	agent := agentpatterns.NewAutonomousAgent(client).UseMemory(mem, 50)
+//	_, err = agent.Run(ctx, "Find the cause of last night's outage", 10)
+//	// A later run starts with what the first one found
+//	_, err = agent.Run(ctx, "Write the outage postmortem", 10)
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Entry kinds
+const (
+	// KindAction is a tool call made by the agent
+	KindAction = "action"
+	// KindFact is something the agent chose to remember, or the result of
+	// a completed task
+	KindFact = "fact"
+)
+
+// Entry is a single remembered item
+type Entry struct {
+	Kind string `json:"kind"`
+	// Content is the text shown to the agent in later runs
+	Content string `json:"content"`
+	// Data holds the full record, e.g. the JSON of an ActionRecord
+	Data  json.RawMessage `json:"data,omitempty"`
+	RunID string          `json:"run_id,omitempty"`
+	Time  time.Time       `json:"time"`
+}
+
+// Memory stores entries across runs. Implementations must be safe for
+// concurrent use.
+type Memory interface {
+	Add(ctx context.Context, entries ...Entry) error
+	// Recent returns up to limit of the newest entries, oldest first
+	Recent(ctx context.Context, limit int) ([]Entry, error)
+	Clear(ctx context.Context) error
+}
+
+// InMemory keeps entries for the life of the process, useful for tests and
+// for agents that run several tasks in one process
+type InMemory struct {
+	mu      sync.RWMutex
+	entries []Entry
+}
+
+// NewInMemory creates an empty in-memory Memory
+func NewInMemory() *InMemory {
+	return &InMemory{}
+}
+
+// Add implements Memory
+func (m *InMemory) Add(ctx context.Context, entries ...Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, entries...)
+	return nil
+}
+
+// Recent implements Memory
+func (m *InMemory) Recent(ctx context.Context, limit int) ([]Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return tail(m.entries, limit), nil
+}
+
+// Clear implements Memory
+func (m *InMemory) Clear(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = nil
+	return nil
+}
+
+// tail copies the last limit entries, or all of them if limit is zero
+func tail(entries []Entry, limit int) []Entry {
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return append([]Entry(nil), entries...)
+}
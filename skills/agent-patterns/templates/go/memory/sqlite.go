@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLiteMemory keeps entries in a table of a SQLite database. It uses
+// database/sql, so open the database with any SQLite driver, e.g.
+//
+//	db, err := sql.Open("sqlite", "memory.db") // modernc.org/sqlite
+//	mem, err := memory.NewSQLiteMemory(ctx, db)
+type SQLiteMemory struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLiteMemory creates the memory table if needed
+func NewSQLiteMemory(ctx context.Context, db *sql.DB) (*SQLiteMemory, error) {
+	m := &SQLiteMemory{db: db, table: "agentpatterns_memory"}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	kind TEXT NOT NULL,
+	content TEXT NOT NULL,
+	data BLOB,
+	run_id TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+)`, m.table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create memory table: %w", err)
+	}
+	return m, nil
+}
+
+// Add implements Memory
+func (m *SQLiteMemory) Add(ctx context.Context, entries ...Entry) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (kind, content, data, run_id, created_at) VALUES (?, ?, ?, ?, ?)`, m.table),
+			entry.Kind, entry.Content, []byte(entry.Data), entry.RunID, entry.Time.UTC())
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Recent implements Memory
+func (m *SQLiteMemory) Recent(ctx context.Context, limit int) ([]Entry, error) {
+	query := fmt.Sprintf(`SELECT kind, content, data, run_id, created_at FROM %s ORDER BY id DESC`, m.table)
+	var args []interface{}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var entry Entry
+		var data []byte
+		var created time.Time
+		if err := rows.Scan(&entry.Kind, &entry.Content, &data, &entry.RunID, &created); err != nil {
+			return nil, err
+		}
+		entry.Data = data
+		entry.Time = created
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Rows come newest first
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// Clear implements Memory
+func (m *SQLiteMemory) Clear(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s`, m.table))
+	return err
+}
@@ -0,0 +1,70 @@
+/*
+ * Mock Provider for Go Agent Patterns
+ * Scripted responses behind the LLMProvider interface, for tests without an API key
+ */
+
+package agentpatterns
+
+import (
+	"context"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/mockllm"
+)
+
+// mockURL is where MockClient addresses its requests; they never leave the
+// process
+const mockURL = "http://mockllm.invalid/v1/messages"
+
+// MockClient is an LLMProvider answering from a mockllm.Mock, so tests can
+// script responses and assert on calls through the provider interface. The
+// mock's rules, queue, latency, and error injection all apply, and its
+// assertion helpers are available on the MockClient.
+//
+// Example:
+//
+//	mock := NewMockClient(mockllm.New().
+//	    When("Classify", `{"category": "billing", "confidence": 0.9}`).
+//	    Default("Thanks for reaching out!"))
+//	router := NewRouter[string](mock.Client())
+//	// ... exercise the router ...
+//	mock.AssertCallCount(t, 2)
+type MockClient struct {
+	*mockllm.Mock
+}
+
+// NewMockClient creates a provider serving responses scripted on mock
+func NewMockClient(mock *mockllm.Mock) *MockClient {
+	return &MockClient{Mock: mock}
+}
+
+// Client returns an AnthropicClient whose requests are served by the mock
+func (m *MockClient) Client() *AnthropicClient {
+	return &AnthropicClient{Provider: m}
+}
+
+// Name implements LLMProvider
+func (m *MockClient) Name() string {
+	return "mock"
+}
+
+// Send implements LLMProvider
+func (m *MockClient) Send(ctx context.Context, req MessageRequest) (string, Usage, error) {
+	req.Stream = false
+	resp, err := postJSON(ctx, m.HTTPClient(), mockURL, nil, req)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
+	return decodeMessage(ctx, resp.Body)
+}
+
+// SendStream implements LLMProvider
+func (m *MockClient) SendStream(ctx context.Context, req MessageRequest, onDelta func(text string)) (string, Usage, error) {
+	req.Stream = true
+	resp, err := postJSON(ctx, m.HTTPClient(), mockURL, nil, req)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
+	return readStream(ctx, resp.Body, onDelta)
+}
@@ -0,0 +1,510 @@
+/*
+ * Mock LLM for testing agent patterns in Go
+ * Scripted Messages API responses served from an http.RoundTripper
+ */
+
+// Package mockllm provides a scripted stand-in for the Anthropic Messages API.
+//
+// The mock is an http.RoundTripper, so it works with every pattern without
+// changing their constructors: plug its HTTPClient into an AnthropicClient.
+// Streaming requests are answered with server-sent events, one text delta
+// per word.
+//
+// Example:
+//
+//	mock := mockllm.New().
+//	    When("Classify", `{"category": "billing", "confidence": 0.9}`).
+//	    Default("Thanks for reaching out!")
+//	client := &agentpatterns.AnthropicClient{APIKey: "test", HTTPClient: mock.HTTPClient()}
+//	router := agentpatterns.NewRouter[string](client)
+//	// ... exercise the router ...
+//	mock.AssertCallCount(t, 2)
+package mockllm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Response is a scripted reply
+type Response struct {
+	// Text is returned as a single text content block
+	Text string
+	// Status overrides the HTTP status (defaults to 200)
+	Status int
+	// Body overrides the whole response body when set
+	Body string
+	// Err is returned as a transport error instead of an HTTP response
+	Err error
+	// Latency delays this response
+	Latency time.Duration
+}
+
+// Call records a single request received by the mock
+type Call struct {
+	Model       string
+	System      string
+	Prompt      string
+	Messages    []Message
+	MaxTokens   int
+	Temperature *float64
+	Stream      bool
+	Body        []byte
+	Time        time.Time
+}
+
+// Message is a simplified view of a request message
+type Message struct {
+	Role string
+	Text string
+	// Documents and Images are the numbers of document and image blocks
+	// in the message
+	Documents int
+	Images    int
+}
+
+// Matcher decides whether a rule applies to a call
+type Matcher func(call Call) bool
+
+type rule struct {
+	match    Matcher
+	response Response
+}
+
+// Mock serves scripted responses and records every call. It is safe for
+// concurrent use by parallel patterns.
+type Mock struct {
+	mu        sync.Mutex
+	queue     []Response
+	rules     []rule
+	fallback  *Response
+	calls     []Call
+	latency   time.Duration
+	errorRate float64
+	errorResp Response
+	rng       *rand.Rand
+}
+
+// New creates an empty mock. Without any script it answers every call with
+// an error so unexpected calls are noticed.
+func New() *Mock {
+	return &Mock{rng: rand.New(rand.NewSource(1))}
+}
+
+// Reply queues a text response. Queued responses are consumed in order
+// before rules and the default are consulted.
+func (m *Mock) Reply(texts ...string) *Mock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, text := range texts {
+		m.queue = append(m.queue, Response{Text: text})
+	}
+	return m
+}
+
+// ReplyWith queues an arbitrary response
+func (m *Mock) ReplyWith(resp Response) *Mock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queue = append(m.queue, resp)
+	return m
+}
+
+// ReplyError queues an API error response with the given status
+func (m *Mock) ReplyError(status int, message string) *Mock {
+	return m.ReplyWith(Response{Status: status, Body: errorBody(status, message)})
+}
+
+// When adds a reusable rule answering text whenever the prompt contains substr.
+// Rules are matched in the order they were added, which keeps concurrent
+// callers deterministic where a queue would not.
+func (m *Mock) When(substr, text string) *Mock {
+	return m.WhenFunc(func(c Call) bool { return strings.Contains(c.Prompt, substr) }, Response{Text: text})
+}
+
+// WhenFunc adds a rule with a custom matcher
+func (m *Mock) WhenFunc(match Matcher, resp Response) *Mock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = append(m.rules, rule{match: match, response: resp})
+	return m
+}
+
+// Default sets the response used when nothing else matches
+func (m *Mock) Default(text string) *Mock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fallback = &Response{Text: text}
+	return m
+}
+
+// WithLatency delays every response, honoring request cancellation
+func (m *Mock) WithLatency(d time.Duration) *Mock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latency = d
+	return m
+}
+
+// WithErrorRate makes a random fraction of calls fail with the given status.
+// The random source is seeded, so runs are repeatable.
+func (m *Mock) WithErrorRate(rate float64, status int) *Mock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errorRate = rate
+	m.errorResp = Response{Status: status, Body: errorBody(status, "injected error")}
+	return m
+}
+
+// WithSeed reseeds the random source used for error injection
+func (m *Mock) WithSeed(seed int64) *Mock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rng = rand.New(rand.NewSource(seed))
+	return m
+}
+
+// HTTPClient returns an http.Client that routes all requests to the mock
+func (m *Mock) HTTPClient() *http.Client {
+	return &http.Client{Transport: m}
+}
+
+// RoundTrip implements http.RoundTripper
+func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	call := parseCall(body)
+	resp, latency := m.next(call)
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	payload, contentType := resp.Body, "application/json"
+	if payload == "" {
+		if call.Stream {
+			payload, contentType = streamBody(call.Model, resp.Text, call.Prompt), "text/event-stream"
+		} else {
+			payload = messageBody(call.Model, resp.Text, call.Prompt)
+		}
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       io.NopCloser(strings.NewReader(payload)),
+		Request:    req,
+	}, nil
+}
+
+// next records the call and picks the response to serve
+func (m *Mock) next(call Call) (Response, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = append(m.calls, call)
+
+	resp := m.pick(call)
+	latency := m.latency + resp.Latency
+	return resp, latency
+}
+
+func (m *Mock) pick(call Call) Response {
+	if m.errorRate > 0 && m.rng.Float64() < m.errorRate {
+		return m.errorResp
+	}
+	if len(m.queue) > 0 {
+		resp := m.queue[0]
+		m.queue = m.queue[1:]
+		return resp
+	}
+	for _, r := range m.rules {
+		if r.match(call) {
+			return r.response
+		}
+	}
+	if m.fallback != nil {
+		return *m.fallback
+	}
+	return Response{Status: http.StatusInternalServerError, Body: errorBody(http.StatusInternalServerError, "mockllm: no scripted response for prompt: "+truncate(call.Prompt, 80))}
+}
+
+// Calls returns a copy of all recorded calls in arrival order
+func (m *Mock) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	calls := make([]Call, len(m.calls))
+	copy(calls, m.calls)
+	return calls
+}
+
+// CallCount returns the number of calls received
+func (m *Mock) CallCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.calls)
+}
+
+// Reset clears recorded calls and any unconsumed queued responses
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = nil
+	m.queue = nil
+}
+
+// TB is the subset of testing.TB used by the assertion helpers
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertCallCount fails the test unless exactly n calls were received
+func (m *Mock) AssertCallCount(t TB, n int) {
+	t.Helper()
+	if got := m.CallCount(); got != n {
+		t.Errorf("mockllm: expected %d calls, got %d", n, got)
+	}
+}
+
+// AssertCalled fails the test unless some prompt contains substr
+func (m *Mock) AssertCalled(t TB, substr string) {
+	t.Helper()
+	for _, c := range m.Calls() {
+		if strings.Contains(c.Prompt, substr) {
+			return
+		}
+	}
+	t.Errorf("mockllm: no call with prompt containing %q", substr)
+}
+
+// AssertModel fails the test unless every call used the given model
+func (m *Mock) AssertModel(t TB, model string) {
+	t.Helper()
+	for i, c := range m.Calls() {
+		if c.Model != model {
+			t.Errorf("mockllm: call %d used model %q, expected %q", i, c.Model, model)
+		}
+	}
+}
+
+// AssertDrained fails the test if queued responses were never consumed
+func (m *Mock) AssertDrained(t TB) {
+	t.Helper()
+	m.mu.Lock()
+	remaining := len(m.queue)
+	m.mu.Unlock()
+	if remaining > 0 {
+		t.Errorf("mockllm: %d queued responses were not used", remaining)
+	}
+}
+
+// ErrTransport is a convenience error for simulating network failures
+var ErrTransport = errors.New("mockllm: simulated transport failure")
+
+func parseCall(body []byte) Call {
+	call := Call{Body: body, Time: time.Now()}
+
+	var req struct {
+		Model       string          `json:"model"`
+		MaxTokens   int             `json:"max_tokens"`
+		System      json.RawMessage `json:"system"`
+		Temperature *float64        `json:"temperature"`
+		Stream      bool            `json:"stream"`
+		Messages    []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return call
+	}
+
+	call.Model = req.Model
+	call.MaxTokens = req.MaxTokens
+	call.Temperature = req.Temperature
+	call.Stream = req.Stream
+	call.System = contentText(req.System)
+	for _, msg := range req.Messages {
+		call.Messages = append(call.Messages, Message{Role: msg.Role, Text: contentText(msg.Content), Documents: countBlocks(msg.Content, "document"), Images: countBlocks(msg.Content, "image")})
+	}
+
+	// Prompt is the latest user message, which is what rules match on
+	for i := len(call.Messages) - 1; i >= 0; i-- {
+		if call.Messages[i].Role == "user" {
+			call.Prompt = call.Messages[i].Text
+			break
+		}
+	}
+	return call
+}
+
+// contentText extracts text from a string or an array of content blocks
+func contentText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var blocks []struct {
+		Type    string          `json:"type"`
+		Text    string          `json:"text"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, b := range blocks {
+		switch {
+		case b.Text != "":
+			parts = append(parts, b.Text)
+		case len(b.Content) > 0:
+			parts = append(parts, contentText(b.Content))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// countBlocks counts the blocks of a type in an array of content blocks
+func countBlocks(raw json.RawMessage, typ string) int {
+	var blocks []struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(raw, &blocks) != nil {
+		return 0
+	}
+	n := 0
+	for _, b := range blocks {
+		if b.Type == typ {
+			n++
+		}
+	}
+	return n
+}
+
+func messageBody(model, text, prompt string) string {
+	body := map[string]interface{}{
+		"id":          "msg_mock",
+		"type":        "message",
+		"role":        "assistant",
+		"model":       model,
+		"stop_reason": "end_turn",
+		"content": []map[string]string{
+			{"type": "text", "text": text},
+		},
+		"usage": map[string]int{
+			"input_tokens":  estimateTokens(prompt),
+			"output_tokens": estimateTokens(text),
+		},
+	}
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(body)
+	return buf.String()
+}
+
+// streamBody renders a reply as server-sent events, with one text delta
+// per word so callers see the text arrive in pieces
+func streamBody(model, text, prompt string) string {
+	var buf bytes.Buffer
+	event := func(name string, data interface{}) {
+		payload, _ := json.Marshal(data)
+		fmt.Fprintf(&buf, "event: %s\ndata: %s\n\n", name, payload)
+	}
+	event("message_start", map[string]interface{}{
+		"type": "message_start",
+		"message": map[string]interface{}{
+			"id":      "msg_mock",
+			"type":    "message",
+			"role":    "assistant",
+			"model":   model,
+			"content": []interface{}{},
+			"usage":   map[string]int{"input_tokens": estimateTokens(prompt), "output_tokens": 1},
+		},
+	})
+	event("content_block_start", map[string]interface{}{
+		"type":          "content_block_start",
+		"index":         0,
+		"content_block": map[string]string{"type": "text", "text": ""},
+	})
+	for _, chunk := range strings.SplitAfter(text, " ") {
+		if chunk == "" {
+			continue
+		}
+		event("content_block_delta", map[string]interface{}{
+			"type":  "content_block_delta",
+			"index": 0,
+			"delta": map[string]string{"type": "text_delta", "text": chunk},
+		})
+	}
+	event("content_block_stop", map[string]interface{}{"type": "content_block_stop", "index": 0})
+	event("message_delta", map[string]interface{}{
+		"type":  "message_delta",
+		"delta": map[string]string{"stop_reason": "end_turn"},
+		"usage": map[string]int{"output_tokens": estimateTokens(text)},
+	})
+	event("message_stop", map[string]string{"type": "message_stop"})
+	return buf.String()
+}
+
+func errorBody(status int, message string) string {
+	errType := "api_error"
+	switch status {
+	case http.StatusTooManyRequests:
+		errType = "rate_limit_error"
+	case http.StatusBadRequest:
+		errType = "invalid_request_error"
+	case 529:
+		errType = "overloaded_error"
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":  "error",
+		"error": map[string]string{"type": errType, "message": message},
+	})
+	return string(data)
+}
+
+// estimateTokens approximates token usage at four characters per token
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,87 @@
+/*
+ * Multi-Objective Optimization for the Evaluator-Optimizer
+ * Pareto fronts of outputs over per-criterion scores
+ */
+
+package agentpatterns
+
+// WithParetoFront has an EvaluatorOptimizer's Optimize also report the
+// Pareto front of its iterations in OptimizationResult.ParetoFront: every
+// output no other output beats on one criterion without losing on another.
+// When criteria conflict, such as brevity and completeness, the front shows
+// the tradeoffs a single weighted score hides, and the caller picks among
+// them. FinalOutput is still the best by weighted score.
+//
+// Example:
+//
+//	optimizer := NewEvaluatorOptimizer(client, WithParetoFront()).
+//	    AddCriterion(EvaluationCriterion{Name: "brevity", Description: "As short as possible", Weight: 1}).
+//	    AddCriterion(EvaluationCriterion{Name: "completeness", Description: "Covers every requirement", Weight: 1})
+//	result, err := optimizer.Optimize(ctx, task, 5, 0.95)
+//	for _, record := range result.ParetoFront {
+//	    fmt.Println(record.Iteration, record.Evaluation.CriteriaScores)
+//	}
+func WithParetoFront() Option {
+	return func(c *patternConfig) { c.paretoFront = true }
+}
+
+// ParetoFront returns the iterations of history whose criterion scores are
+// not dominated, in order. An iteration dominates another when it scores at
+// least as well on every criterion and better on one; a criterion an
+// evaluation did not score counts as zero. Of iterations with the same
+// output, only the first is kept.
+func ParetoFront(history []IterationRecord) []IterationRecord {
+	criteria := make(map[string]bool)
+	for _, record := range history {
+		if record.Evaluation == nil {
+			continue
+		}
+		for name := range record.Evaluation.CriteriaScores {
+			criteria[name] = true
+		}
+	}
+	names := sortedKeys(criteria)
+	scores := func(record IterationRecord) []float64 {
+		values := make([]float64, len(names))
+		for i, name := range names {
+			values[i] = record.Evaluation.CriteriaScores[name]
+		}
+		return values
+	}
+
+	var front []IterationRecord
+	seen := make(map[string]bool)
+	for i, record := range history {
+		if record.Evaluation == nil || seen[record.Output] {
+			continue
+		}
+		seen[record.Output] = true
+		values := scores(record)
+		dominated := false
+		for j, other := range history {
+			if i != j && other.Evaluation != nil && dominates(scores(other), values) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			front = append(front, record)
+		}
+	}
+	return front
+}
+
+// dominates reports whether a is at least b everywhere and above it
+// somewhere
+func dominates(a, b []float64) bool {
+	better := false
+	for i := range a {
+		if a[i] < b[i] {
+			return false
+		}
+		if a[i] > b[i] {
+			better = true
+		}
+	}
+	return better
+}
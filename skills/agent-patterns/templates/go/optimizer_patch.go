@@ -0,0 +1,134 @@
+/*
+ * Patch Refinement for the Evaluator-Optimizer
+ * Targeted search/replace edits instead of regenerating long outputs
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Refinement sets how the generator revises its previous output
+type Refinement string
+
+const (
+	// RefineRewrite has the generator write the whole output again (the
+	// default)
+	RefineRewrite Refinement = "rewrite"
+	// RefinePatch has the generator reply with search/replace edits, which
+	// are applied to the previous output. The reply is a fraction of a long
+	// document, so far fewer output tokens are spent. When the edits cannot
+	// be applied, the output is regenerated in full.
+	RefinePatch Refinement = "patch"
+)
+
+// TextEdit replaces the single occurrence of Search with Replace
+type TextEdit struct {
+	Search  string
+	Replace string
+}
+
+// errPatch reports a reply whose edits could not be applied, which is
+// answered by regenerating the output in full
+var errPatch = errors.New("patch failed")
+
+// Edit block markers of the optimizer.patch prompt
+const (
+	editSearchMarker  = "<<<<<<< SEARCH"
+	editDivider       = "======="
+	editReplaceMarker = ">>>>>>> REPLACE"
+)
+
+// WithRefinement sets how each iteration of an EvaluatorOptimizer after the
+// first revises the previous output. The optimizer.patch prompt asks for
+// RefinePatch edits and can be overridden with WithPrompts.
+//
+// Example:
+//
+//	optimizer := NewEvaluatorOptimizer(client, WithRefinement(RefinePatch))
+//	result, err := optimizer.Optimize(ctx, "Write a 5,000-word design document for ...", 5, 0.9)
+func WithRefinement(refinement Refinement) Option {
+	return func(c *patternConfig) { c.refinement = refinement }
+}
+
+// patch asks the generator for edits to previousOutput and applies them.
+// It returns the patched output and the number of edits, or an error
+// wrapping errPatch when the reply's edits cannot be applied.
+func (e *EvaluatorOptimizer) patch(ctx context.Context, task, previousOutput string, previousEvaluation *EvaluationResult) (string, int, error) {
+	prompt, err := e.cfg.prompt("optimizer.patch", map[string]interface{}{
+		"task":            task,
+		"previous_output": previousOutput,
+		"evaluation":      previousEvaluation,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	response, err := e.cfg.call(ctx, e.client, prompt, e.generatorModel, e.cfg.tokens(4096))
+	if err != nil {
+		return "", 0, err
+	}
+	edits, err := ParseTextEdits(response)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: %v", errPatch, err)
+	}
+	output, err := ApplyTextEdits(previousOutput, edits)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: %v", errPatch, err)
+	}
+	return output, len(edits), nil
+}
+
+// ParseTextEdits reads the search/replace blocks of a reply, ignoring any
+// text around them, such as code fences
+func ParseTextEdits(text string) ([]TextEdit, error) {
+	var edits []TextEdit
+	var search, replace []string
+	// 0: outside a block, 1: in its search text, 2: in its replacement
+	state := 0
+	for _, line := range strings.Split(text, "\n") {
+		marker := strings.TrimSpace(line)
+		switch {
+		case state == 0 && marker == editSearchMarker:
+			state, search, replace = 1, nil, nil
+		case state == 1 && marker == editDivider:
+			state = 2
+		case state == 2 && marker == editReplaceMarker:
+			edits = append(edits, TextEdit{Search: strings.Join(search, "\n"), Replace: strings.Join(replace, "\n")})
+			state = 0
+		case state == 1:
+			search = append(search, line)
+		case state == 2:
+			replace = append(replace, line)
+		}
+	}
+	if state != 0 {
+		return nil, fmt.Errorf("unterminated edit block")
+	}
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("no edit blocks in response")
+	}
+	return edits, nil
+}
+
+// ApplyTextEdits applies edits to text in order. Each edit's search text
+// must occur exactly once in the text as edited so far.
+func ApplyTextEdits(text string, edits []TextEdit) (string, error) {
+	for i, edit := range edits {
+		if edit.Search == "" {
+			return "", fmt.Errorf("edit %d has no search text", i+1)
+		}
+		switch n := strings.Count(text, edit.Search); n {
+		case 1:
+			text = strings.Replace(text, edit.Search, edit.Replace, 1)
+		case 0:
+			return "", fmt.Errorf("edit %d: search text not found", i+1)
+		default:
+			return "", fmt.Errorf("edit %d: search text found %d times", i+1, n)
+		}
+	}
+	return text, nil
+}
@@ -0,0 +1,732 @@
+/*
+ * Functional Options for Go Agent Patterns
+ * Shared constructor options: model, max tokens, logging, budgets, and retries
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/conversation"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/limiter"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/store"
+)
+
+// DefaultModel is used when no WithModel option is given
+const DefaultModel = "claude-sonnet-4-20250514"
+
+// Option configures a pattern constructor.
+//
+// Example:
+//
+//	router := NewRouter[string](client,
+//	    WithModel("claude-3-haiku-20240307"),
+//	    WithRetry(DefaultRetryPolicy()),
+//	    WithLogger(slog.Default()),
+//	)
+type Option func(*patternConfig)
+
+// Budget caps the resources a single run may consume. Token and cost caps
+// are checked before each call, so the call that crosses one completes and
+// the run stops at the next.
+type Budget struct {
+	// MaxCalls is the maximum number of LLM calls per run (0 = unlimited)
+	MaxCalls int
+	// MaxTokens is the maximum input plus output tokens per run (0 = unlimited)
+	MaxTokens int
+	// MaxCost is the maximum spend per run in US dollars (0 = unlimited)
+	MaxCost float64
+	// Pricing prices calls for MaxCost; DefaultPricing is used when nil.
+	// Models missing from it are charged at the highest listed price.
+	Pricing map[string]ModelPricing
+	// MaxDuration is the wall-clock limit per run (0 = unlimited)
+	MaxDuration time.Duration
+}
+
+// RetryPolicy controls retries of failed LLM calls. Calls are retried after
+// 429, 500, 502, 503, and 529 responses and network errors, waiting at least
+// as long as a Retry-After header asks.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter adds a random delay of up to this duration to each back-off,
+	// so concurrent callers do not retry in lockstep
+	Jitter time.Duration
+	// OnRetry, if set, is called before each retry with the attempt that
+	// failed and the delay before the next one
+	OnRetry func(ctx context.Context, attempt int, delay time.Duration, err error)
+}
+
+// DefaultRetryPolicy returns a policy with three attempts and exponential back-off
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Jitter:         250 * time.Millisecond,
+	}
+}
+
+// backoff returns the delay after the given failed attempt: exponential
+// back-off with jitter, or the Retry-After of the response if longer
+func (p *RetryPolicy) backoff(attempt int, err error) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	delay += limiter.Jitter(p.Jitter)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+		delay = apiErr.RetryAfter
+	}
+	return delay
+}
+
+// ErrBudgetExceeded is returned when a run exceeds its Budget. The error
+// is a *BudgetExceededError, so the run's partial results can be recovered.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetExceededError reports the Budget limit a run reached and what it
+// had produced when it stopped. It matches ErrBudgetExceeded.
+//
+// Example:
+//
+//	result, err := agent.Run(ctx, task, 20)
+//	var budgetErr *BudgetExceededError
+//	if errors.As(err, &budgetErr) {
+//	    partial := budgetErr.Partial.(*AgentResult)
+//	    log.Printf("stopped at the %s limit after %d actions", budgetErr.Limit, len(partial.Actions))
+//	}
+type BudgetExceededError struct {
+	// Limit is the limit reached: "calls", "tokens", "cost", or "duration"
+	Limit string
+	// Calls, Tokens, Cost, and Elapsed are the run's consumption so far
+	Calls   int
+	Tokens  int
+	Cost    float64
+	Elapsed time.Duration
+	// Partial is the result of the outermost pattern so far: the
+	// []ChainHistory of a PromptChain, or the *OrchestratorResult,
+	// *OptimizationResult, or *AgentResult of the other patterns
+	Partial interface{}
+
+	budget Budget
+}
+
+// Error implements error
+func (e *BudgetExceededError) Error() string {
+	switch e.Limit {
+	case "calls":
+		return fmt.Sprintf("%v: %d calls", ErrBudgetExceeded, e.budget.MaxCalls)
+	case "tokens":
+		return fmt.Sprintf("%v: %d of %d tokens", ErrBudgetExceeded, e.Tokens, e.budget.MaxTokens)
+	case "cost":
+		return fmt.Sprintf("%v: $%.4f of $%.4f", ErrBudgetExceeded, e.Cost, e.budget.MaxCost)
+	default:
+		return fmt.Sprintf("%v: %v elapsed", ErrBudgetExceeded, e.budget.MaxDuration)
+	}
+}
+
+// Unwrap makes the error match ErrBudgetExceeded
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// WithModel sets the model used by the pattern
+func WithModel(model string) Option {
+	return func(c *patternConfig) { c.model = model }
+}
+
+// WithMaxTokens overrides the per-call max_tokens used by the pattern
+func WithMaxTokens(maxTokens int) Option {
+	return func(c *patternConfig) { c.maxTokens = maxTokens }
+}
+
+// WithLogger sets the logger used to report LLM calls
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *patternConfig) { c.logger = logger }
+}
+
+// WithBudget caps the resources used by each run
+func WithBudget(budget Budget) Option {
+	return func(c *patternConfig) { c.budget = &budget }
+}
+
+// WithRetry retries failed LLM calls according to policy
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *patternConfig) { c.retry = &policy }
+}
+
+// WithRequestOptions applies sampling and request settings, such as a
+// system prompt or stop sequences, to every call the pattern makes
+func WithRequestOptions(opts RequestOptions) Option {
+	return func(c *patternConfig) { c.request = opts }
+}
+
+// WithTemperature sets the sampling temperature of every call the pattern
+// makes
+func WithTemperature(temperature float64) Option {
+	return func(c *patternConfig) { c.request.Temperature = &temperature }
+}
+
+// WithSystemPrompt sets a system prompt for every call the pattern makes
+func WithSystemPrompt(system string) Option {
+	return func(c *patternConfig) { c.request.System = system }
+}
+
+// WithMaxConcurrency caps the work in flight: the subtasks a
+// SectioningParallelizer runs, taken in order by n workers instead of one
+// goroutine per subtask, the nodes of a Workflow, the steps of a Planner's
+// plan, or the independent subtasks an Orchestrator runs at once, where one
+// runs them sequentially. Zero, the default, sets no cap.
+//
+// Example:
+//
+//	parallelizer := NewSectioningParallelizer(client,
+//	    WithMaxConcurrency(8),
+//	    WithPacing(100*time.Millisecond),
+//	)
+//	results := parallelizer.ExecuteParallel(ctx, subtasks) // 500 subtasks
+func WithMaxConcurrency(n int) Option {
+	return func(c *patternConfig) { c.maxConcurrency = n }
+}
+
+// WithPacing starts a SectioningParallelizer's requests at least interval
+// apart across all workers, including retries after a 429 when WithRateLimit
+// is set
+func WithPacing(interval time.Duration) Option {
+	return func(c *patternConfig) { c.pacing = interval }
+}
+
+// patternConfig holds the options shared by every pattern
+type patternConfig struct {
+	// pattern names the owning pattern in cost records and logs
+	pattern   string
+	model     string
+	maxTokens int
+	logger    *slog.Logger
+	budget    *Budget
+	retry     *RetryPolicy
+	costs     *CostTracker
+	tracer    SpanExporter
+	bus       *EventBus
+	store     store.Store
+	injection *InjectionDefense
+	stream    StreamFunc
+	request   RequestOptions
+	prompts   *PromptRegistry
+
+	// Settings of the patterns that support them; the others ignore them
+	maxConcurrency int
+	pacing         time.Duration
+	patience       int
+	epsilon        float64
+	refinement     Refinement
+	paretoFront    bool
+	criteria       []EvaluationCriterion
+	calibration    *Calibration
+}
+
+func newPatternConfig(pattern string, opts []Option) patternConfig {
+	cfg := patternConfig{
+		pattern: pattern,
+		model:   DefaultModel,
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// tokens returns the configured max tokens, or def if none was set
+func (c *patternConfig) tokens(def int) int {
+	if c.maxTokens > 0 {
+		return c.maxTokens
+	}
+	return def
+}
+
+// childOptions returns options that give a helper created by this pattern
+// the same model, logger, retry policy, injection defense, streaming,
+// prompts, and request options. Budgets, cost trackers, tracers, and event buses flow
+// through the context.
+func (c *patternConfig) childOptions() []Option {
+	opts := []Option{WithModel(c.model), WithLogger(c.logger)}
+	if c.retry != nil {
+		opts = append(opts, WithRetry(*c.retry))
+	}
+	if c.injection != nil {
+		opts = append(opts, WithInjectionDefense(*c.injection))
+	}
+	if c.stream != nil {
+		opts = append(opts, WithStreaming(c.stream))
+	}
+	if c.prompts != nil {
+		opts = append(opts, WithPrompts(c.prompts))
+	}
+	opts = append(opts, WithRequestOptions(c.request))
+	return opts
+}
+
+// runBudget tracks budget consumption for one run. It travels in the
+// context so nested patterns draw from the same budget. A sub-agent's own
+// budget is nested in its caller's: calls count against both.
+type runBudget struct {
+	mu       sync.Mutex
+	parent   *runBudget
+	budget   Budget
+	calls    int
+	tokens   int
+	cost     float64
+	start    time.Time
+	deadline time.Time
+	// unpriced holds the models charged without a price, which are warned
+	// about once
+	unpriced map[string]bool
+}
+
+// exceeded returns the error for reaching limit. The caller must hold rb.mu.
+func (rb *runBudget) exceeded(limit string) *BudgetExceededError {
+	return &BudgetExceededError{
+		Limit:   limit,
+		Calls:   rb.calls,
+		Tokens:  rb.tokens,
+		Cost:    rb.cost,
+		Elapsed: time.Since(rb.start),
+		budget:  rb.budget,
+	}
+}
+
+type runBudgetKey struct{}
+
+// nestBudgetKey marks a context whose next run starts its own budget
+// within the enclosing one instead of sharing it
+type nestBudgetKey struct{}
+
+type runIDKey struct{}
+
+// WithRunID sets the run ID used to label everything recorded for a run.
+// Patterns generate one when the context does not carry it already.
+func WithRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// RunIDFromContext returns the run ID of the current run, or ""
+func RunIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+func newRunID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return "run_" + hex.EncodeToString(b[:])
+}
+
+// startRun labels ctx with a run ID, attaches the configured cost tracker,
+// event bus, tracer, and budget unless an enclosing run already did, and
+// opens a span for the pattern. The returned cancel func must always be called.
+func (c *patternConfig) startRun(ctx context.Context) (context.Context, context.CancelFunc) {
+	if RunIDFromContext(ctx) == "" {
+		ctx = WithRunID(ctx, newRunID())
+	}
+	if c.costs != nil && CostTrackerFromContext(ctx) == nil {
+		ctx = context.WithValue(ctx, costTrackerKey{}, c.costs)
+	}
+	if c.bus != nil && EventBusFromContext(ctx) == nil {
+		ctx = context.WithValue(ctx, eventBusKey{}, c.bus)
+	}
+	ctx = withTokenMeter(ctx)
+
+	ctx, span := c.startSpan(ctx, c.pattern)
+	ctx, cancel := c.startBudget(ctx)
+	start := time.Now()
+	c.publish(ctx, PhaseRunStarted, nil)
+	return ctx, func() {
+		c.publish(ctx, PhaseRunFinished, map[string]interface{}{"duration": time.Since(start)})
+		cancel()
+		span.Finish(nil)
+	}
+}
+
+// startBudget attaches the configured budget to ctx unless an enclosing run
+// already did
+func (c *patternConfig) startBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	nest, _ := ctx.Value(nestBudgetKey{}).(bool)
+	if nest {
+		ctx = context.WithValue(ctx, nestBudgetKey{}, false)
+	}
+	parent, _ := ctx.Value(runBudgetKey{}).(*runBudget)
+	if c.budget == nil || parent != nil && !nest {
+		return ctx, func() {}
+	}
+
+	rb := &runBudget{parent: parent, budget: *c.budget, start: time.Now()}
+	if rb.budget.Pricing == nil {
+		rb.budget.Pricing = DefaultPricing()
+	}
+	ctx = context.WithValue(ctx, runBudgetKey{}, rb)
+	if c.budget.MaxDuration > 0 {
+		rb.deadline = time.Now().Add(c.budget.MaxDuration)
+		return context.WithDeadline(ctx, rb.deadline)
+	}
+	return ctx, func() {}
+}
+
+// reserve accounts for one LLM call against the run budget and any budgets
+// enclosing it
+func reserveCall(ctx context.Context) error {
+	rb, _ := ctx.Value(runBudgetKey{}).(*runBudget)
+	for b := rb; b != nil; b = b.parent {
+		if err := b.check(); err != nil {
+			return err
+		}
+	}
+	for b := rb; b != nil; b = b.parent {
+		b.mu.Lock()
+		b.calls++
+		b.mu.Unlock()
+	}
+	return nil
+}
+
+// check returns the error for a limit the budget has reached, if any
+func (rb *runBudget) check() error {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	switch {
+	case rb.budget.MaxCalls > 0 && rb.calls >= rb.budget.MaxCalls:
+		return rb.exceeded("calls")
+	case rb.budget.MaxTokens > 0 && rb.tokens >= rb.budget.MaxTokens:
+		return rb.exceeded("tokens")
+	case rb.budget.MaxCost > 0 && rb.cost >= rb.budget.MaxCost:
+		return rb.exceeded("cost")
+	case !rb.deadline.IsZero() && time.Now().After(rb.deadline):
+		return rb.exceeded("duration")
+	}
+	return nil
+}
+
+// chargeUsageAt accounts for the tokens and cost of a call billed at rate
+// times the list price against the run budget, if any. A model missing from
+// the pricing of a budget with a MaxCost is charged at the highest listed
+// price, so that it cannot run past the cap unnoticed. It reports whether
+// model was charged without a price for the first time in the run.
+func chargeUsageAt(ctx context.Context, model string, usage Usage, rate float64) bool {
+	meterUsage(ctx, usage)
+	unpriced := false
+	rb, _ := ctx.Value(runBudgetKey{}).(*runBudget)
+	for b := rb; b != nil; b = b.parent {
+		b.mu.Lock()
+		b.tokens += usage.InputTokens + usage.OutputTokens
+		pricing, ok := b.budget.Pricing[model]
+		if !ok && b.budget.MaxCost > 0 {
+			pricing = highestPricing(b.budget.Pricing)
+			if !b.unpriced[model] {
+				if b.unpriced == nil {
+					b.unpriced = make(map[string]bool)
+				}
+				b.unpriced[model] = true
+				unpriced = true
+			}
+		}
+		b.cost += rate * pricing.Cost(usage)
+		b.mu.Unlock()
+	}
+	return unpriced
+}
+
+// highestPricing returns the highest input and output prices in pricing
+// and DefaultPricing
+func highestPricing(pricing map[string]ModelPricing) ModelPricing {
+	var highest ModelPricing
+	for _, table := range []map[string]ModelPricing{pricing, DefaultPricing()} {
+		for _, p := range table {
+			highest.InputPerMTok = math.Max(highest.InputPerMTok, p.InputPerMTok)
+			highest.OutputPerMTok = math.Max(highest.OutputPerMTok, p.OutputPerMTok)
+		}
+	}
+	return highest
+}
+
+// budgetStop attaches partial to err if the run stopped because it ran out
+// of budget, converting the deadline of a MaxDuration into a
+// *BudgetExceededError. Patterns call it on the way out, so the outermost
+// pattern's result is the one kept. A canceled run's error becomes a
+// *CanceledError carrying partial. Other errors are returned unchanged.
+func budgetStop(ctx context.Context, err error, partial interface{}) error {
+	if errors.Is(err, context.Canceled) {
+		return cancelStop(err, partial)
+	}
+	var budgetErr *BudgetExceededError
+	if errors.As(err, &budgetErr) {
+		budgetErr.Partial = partial
+		return err
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	rb, _ := ctx.Value(runBudgetKey{}).(*runBudget)
+	for b := rb; b != nil; b = b.parent {
+		if b.deadline.IsZero() || time.Now().Before(b.deadline) {
+			continue
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		budgetErr = b.exceeded("duration")
+		budgetErr.Partial = partial
+		return budgetErr
+	}
+	return err
+}
+
+// sendFunc sends one request to the API
+type sendFunc func(ctx context.Context) (string, Usage, error)
+
+// call sends a prompt through the client, applying budget, retry, and logging
+func (c *patternConfig) call(ctx context.Context, client *AnthropicClient, prompt, model string, maxTokens int) (string, error) {
+	return c.callMessage(ctx, client, MessageItem{Role: "user", Content: prompt}, model, maxTokens)
+}
+
+// callMessage is call for a user message that may carry documents and
+// images
+func (c *patternConfig) callMessage(ctx context.Context, client *AnthropicClient, msg MessageItem, model string, maxTokens int) (string, error) {
+	return c.callWith(ctx, model, messageInput(msg), func(ctx context.Context) (string, Usage, error) {
+		return c.send(ctx, client, messageRequest(msg, model, maxTokens))
+	})
+}
+
+// send sends req with the pattern's request options, streaming it when the
+// run has a streamer
+func (c *patternConfig) send(ctx context.Context, client *AnthropicClient, req MessageRequest) (string, Usage, error) {
+	c.request.apply(&req)
+	ctx = c.publishThinking(ctx, req)
+	if onDelta := c.streamer(ctx); onDelta != nil {
+		return client.SendStream(ctx, req, onDelta)
+	}
+	return client.Send(ctx, req)
+}
+
+// publishThinking returns a context whose responses to req publish their
+// thinking, if req enables it
+func (c *patternConfig) publishThinking(ctx context.Context, req MessageRequest) context.Context {
+	if req.Thinking == nil {
+		return ctx
+	}
+	parent := ctx
+	return onThinking(ctx, func(thinking string) {
+		c.publish(parent, PhaseThinking, map[string]interface{}{"model": req.Model, "thinking": thinking})
+	})
+}
+
+// promptRequest is a request for a reply to a single user prompt
+func promptRequest(prompt, model string, maxTokens int) MessageRequest {
+	return messageRequest(MessageItem{Role: "user", Content: prompt}, model, maxTokens)
+}
+
+// messageRequest is a request for a reply to a single message
+func messageRequest(msg MessageItem, model string, maxTokens int) MessageRequest {
+	return MessageRequest{
+		Model:     model,
+		MaxTokens: maxTokens,
+		Messages:  []MessageItem{msg},
+	}
+}
+
+// messageInput identifies a message for journaling: its text, plus a hash
+// of its attachments if it has any
+func messageInput(msg MessageItem) string {
+	if len(msg.Images) == 0 && len(msg.Documents) == 0 {
+		return msg.Content
+	}
+	h := sha256.New()
+	for _, doc := range msg.Documents {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", doc.URL, doc.FileID, doc.Title)
+		h.Write(doc.Data)
+	}
+	for _, img := range msg.Images {
+		h.Write([]byte(img.URL))
+		h.Write(img.Data)
+	}
+	return fmt.Sprintf("%s\x00attachments:%x", msg.Content, h.Sum(nil))
+}
+
+// callConversation sends the window of conv with its system prompt, applying
+// budget, retry, and logging. The reply is not added to conv.
+func (c *patternConfig) callConversation(ctx context.Context, client *AnthropicClient, conv *conversation.Conversation, model string, maxTokens int) (string, error) {
+	messages, err := conv.Window(ctx)
+	if err != nil {
+		return "", err
+	}
+	input, err := json.Marshal(messages)
+	if err != nil {
+		return "", err
+	}
+	return c.callWith(ctx, model, conv.System()+"\x00"+string(input), func(ctx context.Context) (string, Usage, error) {
+		return c.send(ctx, client, MessageRequest{
+			Model:     model,
+			MaxTokens: maxTokens,
+			System:    conv.System(),
+			Messages:  messages,
+		})
+	})
+}
+
+// callWith sends a request with retries. Inside a durable workflow the
+// response is journaled under model and input, which identify the request.
+func (c *patternConfig) callWith(ctx context.Context, model, input string, send sendFunc) (string, error) {
+	return journaled(ctx, "llm:"+c.pattern, model+"\x00"+input, func(ctx context.Context) (string, error) {
+		return c.callWithRetry(ctx, model, send)
+	})
+}
+
+func (c *patternConfig) callWithRetry(ctx context.Context, model string, send sendFunc) (string, error) {
+	attempts := 1
+	if c.retry != nil && c.retry.MaxAttempts > 1 {
+		attempts = c.retry.MaxAttempts
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		response, err := c.sendOnce(ctx, model, send)
+		if err == nil {
+			return response, nil
+		}
+		lastErr = err
+
+		if attempt == attempts || !isRetryableError(err) {
+			break
+		}
+		delay := c.retry.backoff(attempt, err)
+		c.logger.Warn("retrying LLM call", "model", model, "attempt", attempt, "backoff", delay, "error", err)
+		c.publish(ctx, PhaseLLMRetry, retryPayload(model, attempt, delay, err))
+		if c.retry.OnRetry != nil {
+			c.retry.OnRetry(ctx, attempt, delay, err)
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return "", err
+		}
+	}
+	return "", lastErr
+}
+
+// retryPayload is the payload of a PhaseLLMRetry event
+func retryPayload(model string, attempt int, delay time.Duration, err error) map[string]interface{} {
+	return map[string]interface{}{
+		"model":   model,
+		"attempt": attempt,
+		"backoff": delay,
+		"error":   err,
+	}
+}
+
+// callOnce sends a single request without retries, applying budget,
+// logging, and journaling
+func (c *patternConfig) callOnce(ctx context.Context, client *AnthropicClient, msg MessageItem, model string, maxTokens int) (string, error) {
+	return journaled(ctx, "llm:"+c.pattern, model+"\x00"+messageInput(msg), func(ctx context.Context) (string, error) {
+		return c.sendOnce(ctx, model, func(ctx context.Context) (string, Usage, error) {
+			return c.send(ctx, client, messageRequest(msg, model, maxTokens))
+		})
+	})
+}
+
+func (c *patternConfig) sendOnce(ctx context.Context, model string, send sendFunc) (string, error) {
+	if err := reserveCall(ctx); err != nil {
+		return "", err
+	}
+
+	ctx, span := StartSpan(ctx, "llm.call")
+	span.SetAttribute("model", model)
+
+	start := time.Now()
+	response, usage, err := send(ctx)
+	c.recordUsage(ctx, model, usage)
+	c.chargeUsageAt(ctx, model, usage, 1)
+	span.SetAttribute("input_tokens", usage.InputTokens)
+	span.SetAttribute("output_tokens", usage.OutputTokens)
+	span.Finish(err)
+	c.publish(ctx, PhaseLLMCall, map[string]interface{}{
+		"model":         model,
+		"duration":      time.Since(start),
+		"input_tokens":  usage.InputTokens,
+		"output_tokens": usage.OutputTokens,
+		"error":         err,
+	})
+	if err != nil {
+		c.logger.Debug("LLM call failed", "model", model, "duration", time.Since(start), "error", err)
+		return "", err
+	}
+	c.logger.Debug("LLM call finished", "model", model, "duration", time.Since(start), "response_chars", len(response),
+		"input_tokens", usage.InputTokens, "output_tokens", usage.OutputTokens)
+	return response, nil
+}
+
+// chargeUsageAt charges usage to the run budget, warning the first time a
+// model without a price is charged
+func (c *patternConfig) chargeUsageAt(ctx context.Context, model string, usage Usage, rate float64) {
+	if chargeUsageAt(ctx, model, usage, rate) {
+		c.logger.Warn("no price for model, charging the budget at the highest listed price", "model", model)
+	}
+}
+
+// recordUsage reports usage to the run's cost tracker, falling back to the
+// pattern's own tracker for calls made outside of a run
+func (c *patternConfig) recordUsage(ctx context.Context, model string, usage Usage) {
+	c.recordUsageAt(ctx, model, usage, 1)
+}
+
+// recordUsageAt is recordUsage for a call billed at rate times the list
+// price
+func (c *patternConfig) recordUsageAt(ctx context.Context, model string, usage Usage, rate float64) {
+	if usage == (Usage{}) {
+		return
+	}
+	tracker := CostTrackerFromContext(ctx)
+	if tracker == nil {
+		tracker = c.costs
+	}
+	if tracker != nil {
+		tracker.recordAt(RunIDFromContext(ctx), c.pattern, model, usage, rate)
+	}
+}
+
+// isRetryableError reports whether err is a transient API or network failure
+func isRetryableError(err error) bool {
+	if err == nil || errors.Is(err, ErrBudgetExceeded) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case 429, 500, 502, 503, 529:
+			return true
+		}
+		return false
+	}
+	msg := err.Error()
+	for _, status := range []string{"status 429", "status 500", "status 502", "status 503", "status 529"} {
+		if strings.Contains(msg, status) {
+			return true
+		}
+	}
+	return strings.Contains(msg, "failed to send request")
+}
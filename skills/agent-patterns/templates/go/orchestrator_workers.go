@@ -7,17 +7,65 @@ package agentpatterns
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/approvals"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/jsonx"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/schema"
 )
 
 // Subtask represents a subtask created by the orchestrator
 type OrchestratorSubtask struct {
-	ID           string   `json:"id"`
-	Description  string   `json:"description"`
-	WorkerType   string   `json:"worker_type"`
-	Dependencies []string `json:"dependencies"`
+	ID           string   `json:"id" description:"Unique ID such as subtask_1"`
+	Description  string   `json:"description" description:"What needs to be done"`
+	WorkerType   string   `json:"worker_type" description:"Worker that should handle the subtask"`
+	Dependencies []string `json:"dependencies" description:"IDs of subtasks whose results this one needs"`
+	// Decompose marks a subtask too large for one worker, which is broken
+	// down in turn when WithMaxDepth allows
+	Decompose bool `json:"decompose,omitempty" description:"True if the subtask is too large for one worker and should itself be broken down"`
+}
+
+// ErrTaskUnrecoverable is returned when re-planning after a failed subtask
+// concludes that the task cannot be completed
+var ErrTaskUnrecoverable = errors.New("task unrecoverable")
+
+// ErrSubtaskFailed is returned by an orchestrator with the SynthesisFailFast
+// policy when a subtask fails
+var ErrSubtaskFailed = errors.New("subtask failed")
+
+// SynthesisPolicy decides what the orchestrator does with subtasks that
+// failed, once re-planning, if any, has given up on them
+type SynthesisPolicy string
+
+const (
+	// SynthesisWithGaps synthesizes the results there are, telling the
+	// model which subtasks failed and why so that it does not paper over
+	// them. It is the default.
+	SynthesisWithGaps SynthesisPolicy = "with_gaps"
+	// SynthesisFailFast stops at the first failure without synthesizing
+	SynthesisFailFast SynthesisPolicy = "fail_fast"
+	// SynthesisRetryFailed runs each failed subtask once more, then
+	// synthesizes with the gaps left
+	SynthesisRetryFailed SynthesisPolicy = "retry_failed"
+)
+
+// Re-planning actions
+const (
+	ReplanRetry   = "retry"
+	ReplanReplace = "replace"
+	ReplanAbandon = "abandon"
+)
+
+// replanDecision is the orchestrator's response to a failed subtask
+type replanDecision struct {
+	Action     string                `json:"action" description:"retry, replace, or abandon" jsonschema:"enum=retry|replace|abandon"`
+	WorkerType string                `json:"worker_type,omitempty" description:"Worker to retry the failed subtask with"`
+	Subtasks   []OrchestratorSubtask `json:"subtasks,omitempty" description:"Subtasks replacing the failed one"`
+	Reason     string                `json:"reason" description:"Why this action was chosen"`
 }
 
 // WorkerResult represents the result from a worker
@@ -39,16 +87,16 @@ type LLMWorker struct {
 	client       *AnthropicClient
 	workerType   string
 	systemPrompt string
-	model        string
+	cfg          patternConfig
 }
 
 // NewLLMWorker creates a new LLM worker
-func NewLLMWorker(client *AnthropicClient, workerType, systemPrompt, model string) *LLMWorker {
+func NewLLMWorker(client *AnthropicClient, workerType, systemPrompt string, opts ...Option) *LLMWorker {
 	return &LLMWorker{
 		client:       client,
 		workerType:   workerType,
 		systemPrompt: systemPrompt,
-		model:        model,
+		cfg:          newPatternConfig("llm_worker", opts),
 	}
 }
 
@@ -59,39 +107,143 @@ func (w *LLMWorker) WorkerType() string {
 
 // Execute executes the subtask
 func (w *LLMWorker) Execute(ctx context.Context, subtask *OrchestratorSubtask, depResults map[string]string) (string, error) {
-	var contextInfo string
+	var contextInfo []map[string]string
+	for _, k := range sortedKeys(depResults) {
+		quoted, err := w.cfg.quote(ctx, "subtask:"+k, depResults[k])
+		if err != nil {
+			return "", err
+		}
+		contextInfo = append(contextInfo, map[string]string{"subtask": k, "output": quoted})
+	}
+
+	prompt, err := w.cfg.prompt("orchestrator.worker", map[string]interface{}{
+		"role":    w.systemPrompt,
+		"task":    subtask.Description,
+		"context": contextInfo,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return w.cfg.call(ctx, w.client, prompt, w.cfg.model, w.cfg.tokens(4096))
+}
+
+// AgentWorker runs an AutonomousAgent for each subtask, so decomposed work
+// can use tools. The agent shares the orchestrator's run budget and events.
+// The agent keeps per-run state, so its subtasks run one at a time even
+// when the orchestrator runs others in parallel.
+type AgentWorker struct {
+	workerType string
+	agent      *AutonomousAgent
+	maxSteps   int
+	mu         sync.Mutex
+}
+
+// NewAgentWorker creates a worker backed by agent
+func NewAgentWorker(workerType string, agent *AutonomousAgent, maxSteps int) *AgentWorker {
+	return &AgentWorker{
+		workerType: workerType,
+		agent:      agent,
+		maxSteps:   maxSteps,
+	}
+}
+
+// WorkerType returns the worker type
+func (w *AgentWorker) WorkerType() string {
+	return w.workerType
+}
+
+// Execute runs the agent on the subtask
+func (w *AgentWorker) Execute(ctx context.Context, subtask *OrchestratorSubtask, depResults map[string]string) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	task := subtask.Description
 	if len(depResults) > 0 {
 		var parts []string
-		for k, v := range depResults {
-			parts = append(parts, fmt.Sprintf("[%s]: %s", k, v))
+		for _, k := range sortedKeys(depResults) {
+			v := depResults[k]
+			quoted, err := w.agent.cfg.quote(ctx, "subtask:"+k, v)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, fmt.Sprintf("[%s]: %s", k, quoted))
 		}
-		contextInfo = "\n\nContext from previous tasks:\n" + strings.Join(parts, "\n")
+		task += "\n\nContext from previous tasks:\n" + strings.Join(parts, "\n")
 	}
 
-	prompt := fmt.Sprintf("%s\n\nTask: %s%s\n\nProvide your result:", w.systemPrompt, subtask.Description, contextInfo)
+	result, err := w.agent.Run(withCheckpointScope(ctx, subtask.ID), task, w.maxSteps)
+	if err != nil {
+		return "", err
+	}
+	if !result.Success {
+		return "", fmt.Errorf("agent did not complete subtask %s within %d steps", subtask.ID, w.maxSteps)
+	}
+	return result.FinalResult, nil
+}
+
+// WorkerFunc handles a subtask in Go code
+type WorkerFunc func(ctx context.Context, subtask *OrchestratorSubtask, depResults map[string]string) (string, error)
+
+// FuncWorker runs a Go function for each subtask, so a plan can mix
+// deterministic steps, such as a SQL query or an internal API call, with
+// LLM workers. Name the worker type after what the function does, since
+// the type names are all the orchestrator sees when decomposing.
+//
+// Example:
+//
+//	orch.RegisterWorker(NewFuncWorker("sales_query", func(ctx context.Context, subtask *OrchestratorSubtask, _ map[string]string) (string, error) {
+//	    return querySales(ctx, db, subtask.Description)
+//	}))
+type FuncWorker struct {
+	workerType string
+	fn         WorkerFunc
+}
+
+// NewFuncWorker creates a worker backed by fn
+func NewFuncWorker(workerType string, fn WorkerFunc) *FuncWorker {
+	return &FuncWorker{
+		workerType: workerType,
+		fn:         fn,
+	}
+}
+
+// WorkerType returns the worker type
+func (w *FuncWorker) WorkerType() string {
+	return w.workerType
+}
 
-	return w.client.CreateMessage(ctx, prompt, w.model, 4096)
+// Execute calls the function
+func (w *FuncWorker) Execute(ctx context.Context, subtask *OrchestratorSubtask, depResults map[string]string) (string, error) {
+	return w.fn(ctx, subtask, depResults)
 }
 
 // Orchestrator decomposes tasks and coordinates workers.
 //
 // Example:
 //
-//	orch := NewOrchestrator(client, "claude-sonnet-4-20250514")
-//	orch.RegisterWorker(NewLLMWorker(client, "researcher", "You research topics", model))
+//	orch := NewOrchestrator(client, WithModel("claude-sonnet-4-20250514"))
+//	orch.RegisterWorker(NewLLMWorker(client, "researcher", "You research topics"))
 //	result, err := orch.Execute(ctx, "Write an article about AI")
 type Orchestrator struct {
-	client  *AnthropicClient
-	model   string
-	workers map[string]Worker
+	client     *AnthropicClient
+	cfg        patternConfig
+	workers    map[string]Worker
+	approver   approvals.Approver
+	maxReplans int
+	cache      *subtaskCache
+	onProgress func(Progress)
+	synthesis  SynthesisPolicy
+	maxDepth   int
 }
 
 // NewOrchestrator creates a new Orchestrator
-func NewOrchestrator(client *AnthropicClient, model string) *Orchestrator {
+func NewOrchestrator(client *AnthropicClient, opts ...Option) *Orchestrator {
 	return &Orchestrator{
-		client:  client,
-		model:   model,
-		workers: make(map[string]Worker),
+		client:    client,
+		cfg:       newPatternConfig("orchestrator", opts),
+		workers:   make(map[string]Worker),
+		synthesis: SynthesisWithGaps,
 	}
 }
 
@@ -101,139 +253,382 @@ func (o *Orchestrator) RegisterWorker(worker Worker) *Orchestrator {
 	return o
 }
 
+// RequirePlanApproval asks approver to approve the decomposed subtasks
+// before any of them run
+func (o *Orchestrator) RequirePlanApproval(approver approvals.Approver) *Orchestrator {
+	o.approver = approver
+	return o
+}
+
+// WithMaxReplans lets the orchestrator re-plan up to n times per run when a
+// subtask fails. Once the subtasks already running have finished, it is
+// shown the failure and either retries the subtask with another worker
+// type, replaces it with new subtasks that its dependents then wait for,
+// or abandons the task, in which case Execute returns
+// ErrTaskUnrecoverable. Zero, the default, records the failure and
+// carries on.
+func (o *Orchestrator) WithMaxReplans(n int) *Orchestrator {
+	o.maxReplans = n
+	return o
+}
+
+// WithSynthesisPolicy sets what happens to subtasks that failed:
+// SynthesisWithGaps, the default, SynthesisFailFast, or
+// SynthesisRetryFailed
+func (o *Orchestrator) WithSynthesisPolicy(policy SynthesisPolicy) *Orchestrator {
+	o.synthesis = policy
+	return o
+}
+
+// OnProgress calls fn as each subtask starts and finishes, with the share
+// of subtasks finished and the tokens spent so far. Calls are serialized.
+func (o *Orchestrator) OnProgress(fn func(Progress)) *Orchestrator {
+	o.onProgress = fn
+	return o
+}
+
 // OrchestratorResult represents the result of orchestration
 type OrchestratorResult struct {
 	FinalResult   string
 	Subtasks      []OrchestratorSubtask
 	WorkerResults []WorkerResult
+	// Synthesis is the policy applied to failed subtasks
+	Synthesis SynthesisPolicy
+	// Gaps lists the subtasks without a result, which the final result
+	// was synthesized without
+	Gaps []string
+	// Trace lists the subtasks run at every depth, in the order they
+	// finished
+	Trace []SubtaskTrace
+}
+
+// orchestratorCheckpoint is the state persisted after each subtask when a
+// store is configured
+type orchestratorCheckpoint struct {
+	Subtasks      []OrchestratorSubtask `json:"subtasks"`
+	WorkerResults []WorkerResult        `json:"worker_results"`
 }
 
-// Execute executes a complex task by decomposing and delegating
+// Execute executes a complex task by decomposing and delegating. A run that
+// exceeds its budget stops launching subtasks and returns a
+// *BudgetExceededError whose Partial is an *OrchestratorResult without a
+// final result. A canceled run stops the same way, once the subtasks in
+// flight have seen the cancellation, and returns a *CanceledError. With
+// SynthesisFailFast, a failed subtask stops the run likewise, and Execute
+// returns the partial result with an error wrapping ErrSubtaskFailed.
 func (o *Orchestrator) Execute(ctx context.Context, task string) (*OrchestratorResult, error) {
-	// Step 1: Decompose the task
-	subtasks, err := o.decomposeTask(ctx, task)
+	ctx, cancel := o.cfg.startRun(ctx)
+	defer cancel()
+
+	// Resume from a checkpoint of the same run, if any
+	var checkpoint orchestratorCheckpoint
+	found, err := o.cfg.loadCheckpoint(ctx, &checkpoint)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decompose task: %w", err)
+		return nil, err
 	}
 
-	// Step 2: Execute subtasks respecting dependencies
+	// Step 1: Decompose the task
+	subtasks := checkpoint.Subtasks
+	level := topLevel(task)
+	if !found {
+		decomposeCtx, span := StartSpan(ctx, "orchestrator.decompose")
+		subtasks, err = o.decomposeTask(decomposeCtx, task, nil, o.maxDepth > 0)
+		span.SetAttribute("subtasks", len(subtasks))
+		span.Finish(err)
+		if err != nil {
+			return nil, budgetStop(ctx, fmt.Errorf("failed to decompose task: %w", err), &OrchestratorResult{})
+		}
+		o.cfg.publish(ctx, PhaseDecomposed, map[string]interface{}{"subtasks": len(subtasks)})
+
+		if o.approver != nil {
+			plan := make([]string, len(subtasks))
+			for i, st := range subtasks {
+				plan[i] = fmt.Sprintf("%s [%s]: %s", st.ID, st.WorkerType, st.Description)
+			}
+			err := o.cfg.requestApproval(ctx, o.approver, "orchestrator_plan",
+				fmt.Sprintf("Approve a plan of %d subtasks for: %s", len(subtasks), task),
+				map[string]interface{}{"subtasks": plan})
+			if err != nil {
+				return nil, fmt.Errorf("plan not approved: %w", err)
+			}
+		}
+	}
+
+	// Step 2: Execute subtasks respecting dependencies. Subtasks that
+	// succeeded before a resume are not run again.
 	results := make(map[string]string)
 	var workerResults []WorkerResult
-
-	sortedSubtasks, err := o.topologicalSort(subtasks)
-	if err != nil {
-		return nil, err
+	var done []NodeResult
+	var doneIDs []string
+	for _, wr := range checkpoint.WorkerResults {
+		if wr.Success {
+			results[wr.SubtaskID] = wr.Result
+			workerResults = append(workerResults, wr)
+			done = append(done, NodeResult{ID: wr.SubtaskID, Output: wr.Result})
+			doneIDs = append(doneIDs, wr.SubtaskID)
+		}
+	}
+	progress := newProgressTracker(o.onProgress, subtaskIDs(subtasks), doneIDs)
+	record := func(r NodeResult) {
+		if r.Err != nil {
+			workerResults = append(workerResults, WorkerResult{
+				SubtaskID: r.ID,
+				Success:   false,
+				Error:     r.Err.Error(),
+			})
+			return
+		}
+		result, _ := r.Output.(string)
+		results[r.ID] = result
+		workerResults = append(workerResults, WorkerResult{
+			SubtaskID: r.ID,
+			Result:    result,
+			Success:   true,
+		})
 	}
 
-	for _, subtask := range sortedSubtasks {
-		// Gather dependency results
-		depResults := make(map[string]string)
-		for _, dep := range subtask.Dependencies {
-			if result, exists := results[dep]; exists {
-				depResults[dep] = result
+	// The plan runs as a workflow. A failure that can be replanned stops
+	// it; once the running subtasks finish, the orchestrator replans and
+	// runs the revised plan, keeping the subtasks already finished.
+	replans := 0
+	var stopErr error
+	for {
+		sortedSubtasks, err := o.topologicalSort(subtasks)
+		if err != nil {
+			return nil, err
+		}
+		var failures []NodeResult
+		wf := o.workflow(sortedSubtasks, progress, level).OnNodeFinished(func(ctx context.Context, r NodeResult) error {
+			progress.finished(ctx, r.ID, r.ID, r.Err)
+			record(r)
+			if r.Err != nil {
+				// Replanning cannot help a run that is out of budget or
+				// canceled
+				if ctx.Err() != nil || errors.Is(budgetStop(ctx, r.Err, nil), ErrBudgetExceeded) {
+					return r.Err
+				}
+				if replans+len(failures) < o.maxReplans {
+					failures = append(failures, r)
+					return errReplan
+				}
+				if o.synthesis == SynthesisFailFast {
+					return fmt.Errorf("%w: %s: %v", ErrSubtaskFailed, r.ID, r.Err)
+				}
 			}
+			done = append(done, r)
+			return o.cfg.saveCheckpoint(ctx, orchestratorCheckpoint{Subtasks: subtasks, WorkerResults: workerResults})
+		})
+		if _, err := wf.run(ctx, nil, done); !errors.Is(err, errReplan) {
+			stopErr = err
+			break
 		}
 
-		// Find appropriate worker
-		worker, exists := o.workers[subtask.WorkerType]
-		if !exists {
-			// Use default LLM worker
-			worker = NewLLMWorker(
-				o.client,
-				subtask.WorkerType,
-				fmt.Sprintf("You are a %s specialist.", subtask.WorkerType),
-				o.model,
-			)
+		for _, failure := range failures {
+			var failed OrchestratorSubtask
+			for _, st := range subtasks {
+				if st.ID == failure.ID {
+					failed = st
+				}
+			}
+			replans++
+			replanCtx, span := StartSpan(ctx, "orchestrator.replan")
+			span.SetAttribute("subtask_id", failure.ID)
+			// A retried subtask keeps its ID and runs again
+			revised, err := o.replan(replanCtx, task, subtasks, results, failed, failure.Err)
+			span.Finish(err)
+			if err != nil {
+				stopErr = err
+				break
+			}
+			subtasks = revised
+			progress.setSteps(subtaskIDs(subtasks))
 		}
+		if stopErr == nil {
+			stopErr = o.cfg.saveCheckpoint(ctx, orchestratorCheckpoint{Subtasks: subtasks, WorkerResults: workerResults})
+		}
+		if stopErr != nil {
+			break
+		}
+	}
+	gaps := subtaskGaps(subtasks, results, workerResults)
+	partial := &OrchestratorResult{Subtasks: subtasks, WorkerResults: workerResults, Synthesis: o.synthesis, Gaps: gapIDs(gaps), Trace: level.trace.list()}
+	if errors.Is(stopErr, ErrSubtaskFailed) {
+		return partial, stopErr
+	}
+	if stopErr != nil {
+		return nil, budgetStop(ctx, stopErr, partial)
+	}
 
-		result, err := worker.Execute(ctx, &subtask, depResults)
+	// Retry the failed subtasks once, in dependency order, keeping the
+	// results of the rest
+	if o.synthesis == SynthesisRetryFailed && len(gaps) > 0 {
+		var succeeded []NodeResult
+		for _, r := range done {
+			if r.Err == nil {
+				succeeded = append(succeeded, r)
+			}
+		}
+		sortedSubtasks, err := o.topologicalSort(subtasks)
 		if err != nil {
-			workerResults = append(workerResults, WorkerResult{
-				SubtaskID: subtask.ID,
-				Success:   false,
-				Error:     err.Error(),
-			})
-		} else {
-			results[subtask.ID] = result
-			workerResults = append(workerResults, WorkerResult{
-				SubtaskID: subtask.ID,
-				Result:    result,
-				Success:   true,
-			})
+			return nil, err
+		}
+		wf := o.workflow(sortedSubtasks, progress, level).OnNodeFinished(func(ctx context.Context, r NodeResult) error {
+			progress.finished(ctx, r.ID, r.ID, r.Err)
+			record(r)
+			return o.cfg.saveCheckpoint(ctx, orchestratorCheckpoint{Subtasks: subtasks, WorkerResults: workerResults})
+		})
+		_, err = wf.run(ctx, nil, succeeded)
+		gaps = subtaskGaps(subtasks, results, workerResults)
+		partial = &OrchestratorResult{Subtasks: subtasks, WorkerResults: workerResults, Synthesis: o.synthesis, Gaps: gapIDs(gaps), Trace: level.trace.list()}
+		if err != nil {
+			return nil, budgetStop(ctx, err, partial)
 		}
 	}
 
 	// Step 3: Synthesize final result
-	finalResult, err := o.synthesizeResults(ctx, task, results)
+	synthCtx, span := StartSpan(ctx, "orchestrator.synthesize")
+	finalResult, err := o.synthesizeResults(synthCtx, task, results, gaps)
+	span.Finish(err)
 	if err != nil {
-		return nil, err
+		return nil, budgetStop(ctx, err, partial)
 	}
 
-	return &OrchestratorResult{
-		FinalResult:   finalResult,
-		Subtasks:      subtasks,
-		WorkerResults: workerResults,
-	}, nil
+	partial.FinalResult = finalResult
+	partial.Trace = level.trace.list()
+	return partial, nil
 }
 
-func (o *Orchestrator) decomposeTask(ctx context.Context, task string) ([]OrchestratorSubtask, error) {
-	var workerTypes []string
-	for wt := range o.workers {
-		workerTypes = append(workerTypes, wt)
+// subtaskGaps describes the subtasks without a result for the synthesis
+// prompt, with the last error of each
+func subtaskGaps(subtasks []OrchestratorSubtask, results map[string]string, workerResults []WorkerResult) []map[string]string {
+	var gaps []map[string]string
+	for _, st := range subtasks {
+		if _, ok := results[st.ID]; ok {
+			continue
+		}
+		reason := "did not run"
+		for _, wr := range workerResults {
+			if wr.SubtaskID == st.ID && !wr.Success {
+				reason = wr.Error
+			}
+		}
+		gaps = append(gaps, map[string]string{"subtask": st.ID, "description": st.Description, "error": reason})
 	}
+	return gaps
+}
 
-	prompt := fmt.Sprintf(`Break down this task into subtasks that can be delegated to specialized workers.
+// gapIDs returns the subtask IDs of gaps
+func gapIDs(gaps []map[string]string) []string {
+	var ids []string
+	for _, gap := range gaps {
+		ids = append(ids, gap["subtask"])
+	}
+	return ids
+}
 
-Task: %s
+// errReplan stops a plan's workflow so that the orchestrator can replan
+var errReplan = errors.New("replan")
 
-Available worker types: %s
+// workflow builds the workflow running subtasks of a level, given in
+// dependency order. Subtasks start as soon as every dependency has
+// finished, successfully or not. Dependencies on unknown IDs are ignored.
+func (o *Orchestrator) workflow(subtasks []OrchestratorSubtask, progress *progressTracker, level subtaskLevel) *Workflow {
+	wf := newWorkflow(o.client, o.cfg)
+	for _, subtask := range subtasks {
+		subtask := subtask
+		wf.AddNode(Node{ID: subtask.ID, ContinueOnError: true, Run: func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
+			depResults := make(map[string]string)
+			for _, dep := range subtask.Dependencies {
+				if result, exists := inputs[dep].(string); exists {
+					depResults[dep] = result
+				}
+			}
+			progress.started(ctx, subtask.ID, subtask.ID)
+			return o.runSubtask(ctx, &subtask, depResults, level)
+		}})
+	}
+	for _, subtask := range subtasks {
+		for _, dep := range subtask.Dependencies {
+			if _, known := wf.nodes[dep]; known {
+				wf.AddEdge(Edge{From: dep, To: subtask.ID, Type: OnComplete})
+			}
+		}
+	}
+	return wf
+}
+
+// runSubtask executes one subtask with its registered worker, or a default
+// LLM worker when none is registered for its type, or decomposes it when
+// it is marked for decomposition and the maximum depth allows
+func (o *Orchestrator) runSubtask(ctx context.Context, subtask *OrchestratorSubtask, depResults map[string]string, level subtaskLevel) (string, error) {
+	decompose := subtask.Decompose && level.depth < o.maxDepth
+	if decompose && level.repeats(subtask.Description) {
+		o.cfg.logger.Warn("subtask repeats a task it was decomposed from, not decomposing it", "subtask", level.id(subtask.ID))
+		decompose = false
+	}
+	worker, exists := o.workers[subtask.WorkerType]
+	if !exists {
+		worker = NewLLMWorker(
+			o.client,
+			subtask.WorkerType,
+			fmt.Sprintf("You are a %s specialist.", subtask.WorkerType),
+			o.cfg.childOptions()...,
+		)
+	}
 
-Respond with JSON array of subtasks:
-[
-  {
-    "id": "subtask_1",
-    "description": "What needs to be done",
-    "worker_type": "worker_type",
-    "dependencies": []
-  },
-  {
-    "id": "subtask_2",
-    "description": "Another task",
-    "worker_type": "worker_type",
-    "dependencies": ["subtask_1"]
-  }
-]
+	workerCtx, span := StartSpan(ctx, "orchestrator.subtask")
+	span.SetAttribute("subtask_id", level.id(subtask.ID))
+	span.SetAttribute("worker_type", subtask.WorkerType)
+	span.SetAttribute("depth", level.depth)
+	start := time.Now()
+	result, err := o.cachedSubtask(workerCtx, subtask, depResults, func() (string, error) {
+		if decompose {
+			return o.runDecomposed(workerCtx, subtask, depResults, level)
+		}
+		return worker.Execute(workerCtx, subtask, depResults)
+	})
+	span.Finish(err)
+	level.record(subtask, decompose, err, time.Since(start))
+	o.cfg.publish(workerCtx, PhaseSubtaskFinished, map[string]interface{}{
+		"subtask":     level.id(subtask.ID),
+		"worker_type": subtask.WorkerType,
+		"depth":       level.depth,
+		"success":     err == nil,
+	})
+	return result, err
+}
 
-Only include the JSON array, no other text.`, task, strings.Join(workerTypes, ", "))
+// decomposeTask breaks a task down into subtasks, given the results of the
+// subtasks it depends on, if any. Only a recursive decomposition may mark
+// subtasks for decomposition in turn.
+func (o *Orchestrator) decomposeTask(ctx context.Context, task string, contextInfo []map[string]string, recursive bool) ([]OrchestratorSubtask, error) {
+	workerTypes := sortedKeys(o.workers)
+	planSchema := schema.MustFor[[]OrchestratorSubtask]()
+	planSchema.Items.Properties["worker_type"].Enum = workerTypes
+	if !recursive {
+		delete(planSchema.Items.Properties, "decompose")
+	}
 
-	response, err := o.client.CreateMessage(ctx, prompt, o.model, 2048)
+	prompt, err := o.cfg.prompt("orchestrator.decompose", map[string]interface{}{
+		"task":         task,
+		"context":      contextInfo,
+		"worker_types": workerTypes,
+		"schema":       planSchema.String(),
+		"recursive":    recursive,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Clean up JSON
-	jsonStr := response
-	if strings.Contains(response, "```") {
-		lines := strings.Split(response, "\n")
-		var jsonLines []string
-		inJSON := false
-		for _, line := range lines {
-			if strings.HasPrefix(line, "[") {
-				inJSON = true
-			}
-			if inJSON {
-				if strings.HasPrefix(line, "```") {
-					break
-				}
-				jsonLines = append(jsonLines, line)
-			}
-		}
-		jsonStr = strings.Join(jsonLines, "\n")
+	response, err := o.cfg.call(ctx, o.client, prompt, o.cfg.model, o.cfg.tokens(2048))
+	if err != nil {
+		return nil, err
 	}
 
 	var subtasks []OrchestratorSubtask
-	if err := json.Unmarshal([]byte(jsonStr), &subtasks); err != nil {
+	if err := jsonx.Unmarshal(response, &subtasks); err != nil {
 		// Fallback: create a single subtask
+		o.cfg.logger.Warn("could not parse task plan, using a single subtask", "error", err)
 		workerType := "general"
 		if len(workerTypes) > 0 {
 			workerType = workerTypes[0]
@@ -246,25 +641,148 @@ Only include the JSON array, no other text.`, task, strings.Join(workerTypes, ",
 		}}, nil
 	}
 
+	if !recursive {
+		for i := range subtasks {
+			subtasks[i].Decompose = false
+		}
+	}
 	return subtasks, nil
 }
 
-func (o *Orchestrator) synthesizeResults(ctx context.Context, originalTask string, results map[string]string) (string, error) {
-	var resultParts []string
-	for k, v := range results {
-		resultParts = append(resultParts, fmt.Sprintf("### %s\n%s", k, v))
+// quoteDependencies quotes the results of a subtask's dependencies for a
+// prompt, as untrusted content
+func (o *Orchestrator) quoteDependencies(ctx context.Context, depResults map[string]string) ([]map[string]string, error) {
+	var contextInfo []map[string]string
+	for _, k := range sortedKeys(depResults) {
+		quoted, err := o.cfg.quote(ctx, "subtask:"+k, depResults[k])
+		if err != nil {
+			return nil, err
+		}
+		contextInfo = append(contextInfo, map[string]string{"subtask": k, "output": quoted})
+	}
+	return contextInfo, nil
+}
+
+// replan asks the orchestrator LLM how to recover from a failed subtask and
+// returns the revised plan
+func (o *Orchestrator) replan(ctx context.Context, task string, subtasks []OrchestratorSubtask, results map[string]string, failed OrchestratorSubtask, failure error) ([]OrchestratorSubtask, error) {
+	workerTypes := sortedKeys(o.workers)
+	decisionSchema := schema.MustFor[replanDecision]()
+	decisionSchema.Properties["worker_type"].Enum = workerTypes
+	decisionSchema.Properties["subtasks"].Items.Properties["worker_type"].Enum = workerTypes
+	if o.maxDepth == 0 {
+		delete(decisionSchema.Properties["subtasks"].Items.Properties, "decompose")
+	}
+
+	plan := make([]map[string]string, len(subtasks))
+	for i, st := range subtasks {
+		status := "pending"
+		if _, done := results[st.ID]; done {
+			status = "done"
+		} else if st.ID == failed.ID {
+			status = "failed"
+		}
+		plan[i] = map[string]string{"id": st.ID, "worker_type": st.WorkerType, "status": status, "description": st.Description}
+	}
+
+	prompt, err := o.cfg.prompt("orchestrator.replan", map[string]interface{}{
+		"task":         task,
+		"plan":         plan,
+		"failed":       failed,
+		"error":        failure,
+		"worker_types": workerTypes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	decision, err := structured[replanDecision](ctx, &o.cfg, o.client, prompt, decisionSchema, o.cfg.model, o.cfg.tokens(2048))
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-plan: %w", err)
+	}
+	o.cfg.publish(ctx, PhaseReplanned, map[string]interface{}{
+		"subtask": failed.ID,
+		"action":  decision.Action,
+		"reason":  decision.Reason,
+	})
+
+	var revised []OrchestratorSubtask
+	switch decision.Action {
+	case ReplanRetry:
+		for _, st := range subtasks {
+			if st.ID == failed.ID && decision.WorkerType != "" {
+				st.WorkerType = decision.WorkerType
+			}
+			revised = append(revised, st)
+		}
+	case ReplanReplace:
+		if len(decision.Subtasks) == 0 {
+			return nil, fmt.Errorf("re-plan replaced subtask %s with no subtasks", failed.ID)
+		}
+		ids := map[string]bool{failed.ID: true}
+		for _, st := range subtasks {
+			ids[st.ID] = true
+		}
+		var replacementIDs []string
+		for _, st := range decision.Subtasks {
+			if ids[st.ID] {
+				return nil, fmt.Errorf("re-plan reused subtask ID %s", st.ID)
+			}
+			ids[st.ID] = true
+			replacementIDs = append(replacementIDs, st.ID)
+		}
+		for _, st := range subtasks {
+			if st.ID == failed.ID {
+				continue
+			}
+			var deps []string
+			for _, dep := range st.Dependencies {
+				if dep == failed.ID {
+					deps = append(deps, replacementIDs...)
+				} else {
+					deps = append(deps, dep)
+				}
+			}
+			st.Dependencies = deps
+			revised = append(revised, st)
+		}
+		revised = append(revised, decision.Subtasks...)
+	default:
+		return nil, fmt.Errorf("%w: subtask %s failed: %s", ErrTaskUnrecoverable, failed.ID, decision.Reason)
 	}
 
-	prompt := fmt.Sprintf(`Synthesize these subtask results into a cohesive final result.
+	if _, err := o.topologicalSort(revised); err != nil {
+		return nil, fmt.Errorf("invalid re-plan: %w", err)
+	}
+	return revised, nil
+}
 
-Original Task: %s
+func (o *Orchestrator) synthesizeResults(ctx context.Context, originalTask string, results map[string]string, gaps []map[string]string) (string, error) {
+	var resultParts []map[string]string
+	for _, k := range sortedKeys(results) {
+		v := results[k]
+		quoted, err := o.cfg.quote(ctx, "worker:"+k, v)
+		if err != nil {
+			return "", err
+		}
+		resultParts = append(resultParts, map[string]string{"subtask": k, "output": quoted})
+	}
 
-Subtask Results:
-%s
+	prompt, err := o.cfg.prompt("orchestrator.synthesize", map[string]interface{}{"task": originalTask, "results": resultParts, "gaps": gaps})
+	if err != nil {
+		return "", err
+	}
 
-Provide a well-organized final result that addresses the original task:`, originalTask, strings.Join(resultParts, "\n\n"))
+	return o.cfg.call(ctx, o.client, prompt, o.cfg.model, o.cfg.tokens(4096))
+}
 
-	return o.client.CreateMessage(ctx, prompt, o.model, 4096)
+// subtaskIDs returns the IDs of subtasks
+func subtaskIDs(subtasks []OrchestratorSubtask) []string {
+	ids := make([]string, len(subtasks))
+	for i, st := range subtasks {
+		ids[i] = st.ID
+	}
+	return ids
 }
 
 func (o *Orchestrator) topologicalSort(subtasks []OrchestratorSubtask) ([]OrchestratorSubtask, error) {
@@ -323,7 +841,7 @@ func ExampleResearchArticle() error {
 		HTTPClient: nil, // Would use http.Client in production
 	}
 
-	orchestrator := NewOrchestrator(client, "claude-sonnet-4-20250514")
+	orchestrator := NewOrchestrator(client, WithModel("claude-sonnet-4-20250514"))
 
 	// Register specialized workers
 	orchestrator.
@@ -331,19 +849,19 @@ func ExampleResearchArticle() error {
 			client,
 			"researcher",
 			"You are a research specialist. Gather facts, statistics, and key information.",
-			"claude-sonnet-4-20250514",
+			WithModel("claude-sonnet-4-20250514"),
 		)).
 		RegisterWorker(NewLLMWorker(
 			client,
 			"writer",
 			"You are a skilled writer. Create engaging, well-structured content.",
-			"claude-sonnet-4-20250514",
+			WithModel("claude-sonnet-4-20250514"),
 		)).
 		RegisterWorker(NewLLMWorker(
 			client,
 			"editor",
 			"You are an editor. Review and improve content for clarity and accuracy.",
-			"claude-sonnet-4-20250514",
+			WithModel("claude-sonnet-4-20250514"),
 		))
 
 	ctx := context.Background()
@@ -6,15 +6,19 @@
 package agentpatterns
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"math"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/chunker"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/limiter"
 )
 
 // SubtaskResult represents the result of a parallel subtask
@@ -30,111 +34,408 @@ type SubtaskResult struct {
 type Subtask struct {
 	Name   string
 	Prompt string
+	// Documents and Images are shown to the model with the prompt, e.g.
+	// a contract or a screenshot
+	Documents []Document
+	Images    []Image
+}
+
+// message is the user message sent for the subtask
+func (st *Subtask) message() MessageItem {
+	return MessageItem{Role: "user", Content: st.Prompt, Documents: st.Documents, Images: st.Images}
 }
 
 // SectioningParallelizer divides tasks into independent subtasks for parallel execution.
 //
 // Example:
 //
-//	parallelizer := NewSectioningParallelizer(client, "claude-sonnet-4-20250514")
+//	parallelizer := NewSectioningParallelizer(client, WithModel("claude-sonnet-4-20250514"))
 //	result, err := parallelizer.ProcessCodeReview(ctx, code)
 type SectioningParallelizer struct {
-	client *AnthropicClient
-	model  string
+	client     *AnthropicClient
+	cfg        patternConfig
+	onProgress ProgressFunc
+	rateLimit  *RateLimitConfig
+	chunker    chunker.Chunker
+	splitter   PDFSplitter
 }
 
+// ProgressFunc is called each time a subtask finishes.
+// Calls are serialized, so implementations do not need their own locking.
+type ProgressFunc func(completed, total int, latest SubtaskResult)
+
 // NewSectioningParallelizer creates a new SectioningParallelizer
-func NewSectioningParallelizer(client *AnthropicClient, model string) *SectioningParallelizer {
+func NewSectioningParallelizer(client *AnthropicClient, opts ...Option) *SectioningParallelizer {
 	return &SectioningParallelizer{
 		client: client,
-		model:  model,
+		cfg:    newPatternConfig("sectioning", opts),
+	}
+}
+
+// OnProgress sets a callback that reports progress as subtasks complete
+func (p *SectioningParallelizer) OnProgress(fn ProgressFunc) *SectioningParallelizer {
+	p.onProgress = fn
+	return p
+}
+
+// RateLimitConfig controls how subtask launches are spread out and how the
+// parallelizer slows down when the API starts returning 429 responses
+type RateLimitConfig struct {
+	// RampUp spreads launches evenly over this duration
+	RampUp time.Duration
+	// Jitter adds a random delay of up to this duration to each launch
+	Jitter time.Duration
+	// InitialBackoff is the shared pause applied after the first 429
+	InitialBackoff time.Duration
+	// MaxBackoff caps the shared pause after repeated 429s
+	MaxBackoff time.Duration
+	// MaxRetries is the number of times a rate-limited subtask is retried
+	MaxRetries int
+}
+
+// DefaultRateLimitConfig returns conservative scheduling defaults
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		RampUp:         2 * time.Second,
+		Jitter:         250 * time.Millisecond,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		MaxRetries:     3,
+	}
+}
+
+// WithRateLimit enables staggered launches and shared 429 back-off
+func (p *SectioningParallelizer) WithRateLimit(cfg RateLimitConfig) *SectioningParallelizer {
+	p.rateLimit = &cfg
+	return p
+}
+
+// WithChunker splits long inputs, such as the code given to
+// ProcessCodeReview, so each subtask sees a chunk that fits the model
+func (p *SectioningParallelizer) WithChunker(c chunker.Chunker) *SectioningParallelizer {
+	p.chunker = c
+	return p
+}
+
+// WithPDFSplitter splits PDFs over the document limits given to
+// MapReduceDocuments, e.g. QPDF{}
+func (p *SectioningParallelizer) WithPDFSplitter(s PDFSplitter) *SectioningParallelizer {
+	p.splitter = s
+	return p
+}
+
+// rateGate is shared by all goroutines of one ExecuteParallel call so that a
+// 429 seen by any of them pauses the others instead of letting them retry-storm
+type rateGate struct {
+	cfg      RateLimitConfig
+	adaptive *limiter.Adaptive
+}
+
+func newRateGate(cfg RateLimitConfig) *rateGate {
+	adaptive := limiter.NewAdaptive(nil)
+	adaptive.InitialBackoff = cfg.InitialBackoff
+	adaptive.MaxBackoff = cfg.MaxBackoff
+	adaptive.Jitter = cfg.Jitter
+	return &rateGate{cfg: cfg, adaptive: adaptive}
+}
+
+// launchDelay returns the staggered start delay for subtask idx of total
+func (g *rateGate) launchDelay(idx, total int) time.Duration {
+	var delay time.Duration
+	if total > 1 && g.cfg.RampUp > 0 {
+		delay = g.cfg.RampUp * time.Duration(idx) / time.Duration(total)
+	}
+	return delay + limiter.Jitter(g.cfg.Jitter)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isRateLimitError reports whether err is a 429 response from the API
+func isRateLimitError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == 429
+}
+
+// callWithRateLimit runs a single subtask call through the shared gate
+func (p *SectioningParallelizer) callWithRateLimit(ctx context.Context, gate *rateGate, pacer limiter.Limiter, st Subtask) (string, error) {
+	for attempt := 0; ; attempt++ {
+		if err := pacer.Wait(ctx); err != nil {
+			return "", err
+		}
+		if err := gate.adaptive.Wait(ctx); err != nil {
+			return "", err
+		}
+		response, err := p.cfg.callOnce(ctx, p.client, st.message(), p.cfg.model, p.cfg.tokens(2048))
+		gate.adaptive.Done(isRateLimitError(err))
+		if err == nil {
+			return response, nil
+		}
+		if !isRateLimitError(err) || attempt >= gate.cfg.MaxRetries {
+			return "", err
+		}
 	}
 }
 
 // ExecuteParallel executes multiple subtasks in parallel
 func (p *SectioningParallelizer) ExecuteParallel(ctx context.Context, subtasks []Subtask) []SubtaskResult {
+	ctx, cancel := p.cfg.startRun(ctx)
+	defer cancel()
+
 	results := make([]SubtaskResult, len(subtasks))
-	var wg sync.WaitGroup
+	var mu sync.Mutex
+	completed := 0
 
-	for i, subtask := range subtasks {
-		wg.Add(1)
-		go func(idx int, st Subtask) {
-			defer wg.Done()
-			start := time.Now()
+	var gate *rateGate
+	if p.rateLimit != nil {
+		gate = newRateGate(*p.rateLimit)
+	}
+	// Without pacing the pacer never waits
+	var pacer limiter.Limiter = limiter.All()
+	if p.cfg.pacing > 0 {
+		pacer = limiter.NewTokenBucket(float64(time.Minute)/float64(p.cfg.pacing), 1)
+	}
+	begin := time.Now()
+
+	run := func(idx int, st Subtask) {
+		ctx, span := StartSpan(ctx, "sectioning.subtask")
+		span.SetAttribute("subtask", st.Name)
+
+		var response string
+		var err error
+		start := time.Now()
+		if gate != nil {
+			// Launch times are measured from the start of the batch, so
+			// workers picking up later subtasks keep to the ramp-up
+			if err = sleepContext(ctx, time.Until(begin.Add(gate.launchDelay(idx, len(subtasks))))); err == nil {
+				start = time.Now()
+				response, err = p.callWithRateLimit(ctx, gate, pacer, st)
+			}
+		} else if err = pacer.Wait(ctx); err == nil {
+			start = time.Now()
+			response, err = p.cfg.callMessage(ctx, p.client, st.message(), p.cfg.model, p.cfg.tokens(2048))
+		}
+		duration := time.Since(start)
+		span.Finish(err)
+		p.cfg.publish(ctx, PhaseSubtaskFinished, map[string]interface{}{
+			"subtask":  st.Name,
+			"success":  err == nil,
+			"duration": duration,
+		})
+
+		if err != nil {
+			results[idx] = SubtaskResult{
+				Name:     st.Name,
+				Success:  false,
+				Error:    err.Error(),
+				Duration: duration,
+			}
+		} else {
+			results[idx] = SubtaskResult{
+				Name:     st.Name,
+				Result:   response,
+				Success:  true,
+				Duration: duration,
+			}
+		}
 
-			response, err := p.client.CreateMessage(ctx, st.Prompt, p.model, 2048)
-			duration := time.Since(start)
+		if p.onProgress != nil {
+			mu.Lock()
+			completed++
+			p.onProgress(completed, len(subtasks), results[idx])
+			mu.Unlock()
+		}
+	}
 
-			if err != nil {
-				results[idx] = SubtaskResult{
-					Name:     st.Name,
-					Success:  false,
-					Error:    err.Error(),
-					Duration: duration,
-				}
-			} else {
-				results[idx] = SubtaskResult{
-					Name:     st.Name,
-					Result:   response,
-					Success:  true,
-					Duration: duration,
-				}
+	workers := len(subtasks)
+	if p.cfg.maxConcurrency > 0 && p.cfg.maxConcurrency < workers {
+		workers = p.cfg.maxConcurrency
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range next {
+				run(idx, subtasks[idx])
 			}
-		}(i, subtask)
+		}()
 	}
+	for i := range subtasks {
+		next <- i
+	}
+	close(next)
 
 	wg.Wait()
 	return results
 }
 
+// TypedSubtask is a subtask whose response is parsed into a value of type T
+type TypedSubtask[T any] struct {
+	Name   string
+	Prompt string
+	Parse  func(response string) (T, error)
+}
+
+// TypedSubtaskResult is a SubtaskResult carrying the parsed value
+type TypedSubtaskResult[T any] struct {
+	SubtaskResult
+	Value T
+}
+
+// ExecuteParallelTyped executes subtasks in parallel and post-processes each
+// response with its Parse function, so callers receive structured results.
+// A parse failure marks that subtask as unsuccessful.
+//
+// Example:
+//
+//	results := ExecuteParallelTyped(ctx, parallelizer, []TypedSubtask[[]string]{
+//	    {Name: "security", Prompt: prompt, Parse: parseFindings},
+//	})
+func ExecuteParallelTyped[T any](ctx context.Context, p *SectioningParallelizer, subtasks []TypedSubtask[T]) []TypedSubtaskResult[T] {
+	plain := make([]Subtask, len(subtasks))
+	for i, st := range subtasks {
+		plain[i] = Subtask{Name: st.Name, Prompt: st.Prompt}
+	}
+
+	raw := p.ExecuteParallel(ctx, plain)
+
+	results := make([]TypedSubtaskResult[T], len(raw))
+	for i, r := range raw {
+		results[i] = TypedSubtaskResult[T]{SubtaskResult: r}
+		if !r.Success || subtasks[i].Parse == nil {
+			continue
+		}
+
+		value, err := subtasks[i].Parse(r.Result)
+		if err != nil {
+			results[i].Success = false
+			results[i].Error = fmt.Sprintf("parse failed: %s", err.Error())
+			continue
+		}
+		results[i].Value = value
+	}
+
+	return results
+}
+
 // CodeReviewResult represents the result of a code review
 type CodeReviewResult struct {
 	SecurityAnalysis        string
 	PerformanceAnalysis     string
 	MaintainabilityAnalysis string
 	BugAnalysis             string
-	TotalDuration           time.Duration
+	// ArchitectureAnalysis compares the code with the diagrams given to
+	// ProcessCodeReviewWithDiagrams
+	ArchitectureAnalysis string
+	TotalDuration        time.Duration
 }
 
-// ProcessCodeReview performs parallel code review analysis
-func (p *SectioningParallelizer) ProcessCodeReview(ctx context.Context, code string) (*CodeReviewResult, error) {
-	subtasks := []Subtask{
-		{
-			Name: "security",
-			Prompt: fmt.Sprintf(`Analyze this code for security vulnerabilities:
+// codeReviewAspect is one analysis of a code review
+type codeReviewAspect struct {
+	name   string
+	prompt string
+}
+
+// codeReviewAspects are the analyses run by ProcessCodeReview
+var codeReviewAspects = []codeReviewAspect{
+	{"security", `Analyze this code for security vulnerabilities:
+%s
+List any security issues found with severity and recommendations.`},
+	{"performance", `Analyze this code for performance issues:
 %s
-List any security issues found with severity and recommendations.`, code),
-		},
-		{
-			Name: "performance",
-			Prompt: fmt.Sprintf(`Analyze this code for performance issues:
+Identify inefficiencies and suggest optimizations.`},
+	{"maintainability", `Analyze this code for maintainability:
 %s
-Identify inefficiencies and suggest optimizations.`, code),
-		},
-		{
-			Name: "maintainability",
-			Prompt: fmt.Sprintf(`Analyze this code for maintainability:
+Check code structure, naming, and suggest improvements.`},
+	{"bugs", `Analyze this code for potential bugs:
 %s
-Check code structure, naming, and suggest improvements.`, code),
-		},
-		{
-			Name: "bugs",
-			Prompt: fmt.Sprintf(`Analyze this code for potential bugs:
+Identify logic errors, edge cases, and potential runtime issues.`},
+}
+
+// architectureAspect is the analysis added by ProcessCodeReviewWithDiagrams
+var architectureAspect = codeReviewAspect{"architecture", `Compare this code with the architecture shown in the attached diagrams:
 %s
-Identify logic errors, edge cases, and potential runtime issues.`, code),
-		},
+Identify where the code departs from the diagrams: components, dependencies, and data flows that are missing, extra, or connected differently.`}
+
+// diagramContext introduces the diagrams attached to every analysis
+const diagramContext = "The attached diagrams show the architecture this code belongs to. Use them to understand the code's role and boundaries.\n\n"
+
+// ProcessCodeReview performs parallel code review analysis. With a chunker
+// set, long code is reviewed chunk by chunk and each analysis joins the
+// findings for every part.
+func (p *SectioningParallelizer) ProcessCodeReview(ctx context.Context, code string) (*CodeReviewResult, error) {
+	return p.codeReview(ctx, code, nil)
+}
+
+// ProcessCodeReviewWithDiagrams reviews code alongside architecture
+// diagrams, such as component or sequence diagrams. Every analysis sees the
+// diagrams, and an additional architecture analysis checks the code against
+// them.
+//
+// Example:
+//
+//	diagram, err := conversation.ImageFromFile("docs/payments-architecture.png")
+//	review, err := parallelizer.ProcessCodeReviewWithDiagrams(ctx, code, diagram)
+//	fmt.Println(review.ArchitectureAnalysis)
+func (p *SectioningParallelizer) ProcessCodeReviewWithDiagrams(ctx context.Context, code string, diagrams ...Image) (*CodeReviewResult, error) {
+	return p.codeReview(ctx, code, diagrams)
+}
+
+func (p *SectioningParallelizer) codeReview(ctx context.Context, code string, diagrams []Image) (*CodeReviewResult, error) {
+	aspects := codeReviewAspects
+	intro := ""
+	if len(diagrams) > 0 {
+		aspects = append(append([]codeReviewAspect(nil), codeReviewAspects...), architectureAspect)
+		intro = diagramContext
+	}
+
+	parts := []string{code}
+	if p.chunker != nil {
+		if chunks := p.chunker.Split(code); len(chunks) > 1 {
+			parts = chunker.Texts(chunks)
+		}
+	}
+
+	var subtasks []Subtask
+	for _, aspect := range aspects {
+		for i, part := range parts {
+			name := aspect.name
+			prompt := fmt.Sprintf(aspect.prompt, part)
+			if len(parts) > 1 {
+				name = fmt.Sprintf("%s#%d", aspect.name, i+1)
+				prompt = fmt.Sprintf("This is part %d of %d of a larger file.\n\n%s", i+1, len(parts), prompt)
+			}
+			subtasks = append(subtasks, Subtask{Name: name, Prompt: intro + prompt, Images: diagrams})
+		}
 	}
 
 	results := p.ExecuteParallel(ctx, subtasks)
 
-	// Find results by name
-	getResult := func(name string) string {
-		for _, r := range results {
-			if r.Name == name && r.Success {
+	// Join the successful results for an aspect in part order
+	getResult := func(aspect int) string {
+		if len(parts) == 1 {
+			if r := results[aspect]; r.Success {
 				return r.Result
 			}
+			return ""
+		}
+		var b strings.Builder
+		for i := range parts {
+			if r := results[aspect*len(parts)+i]; r.Success {
+				fmt.Fprintf(&b, "Part %d of %d:\n%s\n\n", i+1, len(parts), r.Result)
+			}
 		}
-		return ""
+		return strings.TrimSpace(b.String())
 	}
 
 	// Find max duration
@@ -145,26 +446,217 @@ Identify logic errors, edge cases, and potential runtime issues.`, code),
 		}
 	}
 
-	return &CodeReviewResult{
-		SecurityAnalysis:        getResult("security"),
-		PerformanceAnalysis:     getResult("performance"),
-		MaintainabilityAnalysis: getResult("maintainability"),
-		BugAnalysis:             getResult("bugs"),
+	review := &CodeReviewResult{
+		SecurityAnalysis:        getResult(0),
+		PerformanceAnalysis:     getResult(1),
+		MaintainabilityAnalysis: getResult(2),
+		BugAnalysis:             getResult(3),
 		TotalDuration:           maxDuration,
-	}, nil
+	}
+	if len(aspects) > len(codeReviewAspects) {
+		review.ArchitectureAnalysis = getResult(len(codeReviewAspects))
+	}
+	return review, nil
+}
+
+// MapReduceResult is the outcome of MapReduce or MapReduceDocuments
+type MapReduceResult struct {
+	Chunks []chunker.Chunk
+	// Documents holds the document parts mapped by MapReduceDocuments
+	Documents []Document
+	// Mapped holds the map step's result for each chunk or document part
+	Mapped []SubtaskResult
+	Result string
+}
+
+// MapReduce splits text with c, runs mapPrompt over every chunk in
+// parallel, and combines the successful results with reducePrompt.
+// {chunk} in mapPrompt is replaced by the chunk, and {results} in
+// reducePrompt by the map results in order.
+//
+// Example:
+//
+//	result, err := parallelizer.MapReduce(ctx, transcript, chunker.NewTextChunker(3000),
+//	    "List the decisions made in this part of a meeting transcript:\n\n{chunk}",
+//	    "Merge these lists of decisions, removing duplicates:\n\n{results}")
+func (p *SectioningParallelizer) MapReduce(ctx context.Context, text string, c chunker.Chunker, mapPrompt, reducePrompt string) (*MapReduceResult, error) {
+	chunks := c.Split(text)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("nothing to map: text is empty")
+	}
+
+	ctx, cancel := p.cfg.startRun(ctx)
+	defer cancel()
+
+	subtasks := make([]Subtask, len(chunks))
+	for i, chunk := range chunks {
+		subtasks[i] = Subtask{
+			Name:   fmt.Sprintf("chunk#%d", i+1),
+			Prompt: strings.ReplaceAll(mapPrompt, "{chunk}", chunk.Text),
+		}
+	}
+	mapped := p.ExecuteParallel(ctx, subtasks)
+	result, err := p.reduce(ctx, mapped, reducePrompt)
+	if err != nil {
+		return nil, err
+	}
+	return &MapReduceResult{Chunks: chunks, Mapped: mapped, Result: result}, nil
+}
+
+// MapReduceDocuments is MapReduce over documents: each document is split
+// into parts within the document limits (see SplitDocument), mapPrompt is
+// run with every part attached, and the results are combined with
+// reducePrompt. {title} in mapPrompt is replaced by the part's title.
+//
+// Example:
+//
+//	report, err := conversation.DocumentFromFile("annual-report.pdf")
+//	result, err := parallelizer.WithPDFSplitter(QPDF{}).MapReduceDocuments(ctx, []Document{report},
+//	    "List the risks disclosed in {title}.",
+//	    "Merge these lists of risks, removing duplicates:\n\n{results}")
+func (p *SectioningParallelizer) MapReduceDocuments(ctx context.Context, docs []Document, mapPrompt, reducePrompt string) (*MapReduceResult, error) {
+	ctx, cancel := p.cfg.startRun(ctx)
+	defer cancel()
+
+	var parts []Document
+	for _, doc := range docs {
+		split, err := SplitDocument(ctx, doc, p.splitter)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, split...)
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("nothing to map: no documents")
+	}
+
+	subtasks := make([]Subtask, len(parts))
+	for i, part := range parts {
+		subtasks[i] = Subtask{
+			Name:      fmt.Sprintf("document#%d", i+1),
+			Prompt:    strings.ReplaceAll(mapPrompt, "{title}", documentName(part)),
+			Documents: []Document{part},
+		}
+	}
+	mapped := p.ExecuteParallel(ctx, subtasks)
+	result, err := p.reduce(ctx, mapped, reducePrompt)
+	if err != nil {
+		return nil, err
+	}
+	return &MapReduceResult{Documents: parts, Mapped: mapped, Result: result}, nil
+}
+
+// reduce combines the successful map results with reducePrompt
+func (p *SectioningParallelizer) reduce(ctx context.Context, mapped []SubtaskResult, reducePrompt string) (string, error) {
+	var combined strings.Builder
+	succeeded := 0
+	for i, r := range mapped {
+		if !r.Success {
+			p.cfg.logger.Warn("map step failed", "part", i+1, "error", r.Error)
+			continue
+		}
+		succeeded++
+		fmt.Fprintf(&combined, "--- Part %d of %d ---\n%s\n\n", i+1, len(mapped), r.Result)
+	}
+	if succeeded == 0 {
+		return "", fmt.Errorf("all %d map steps failed: %s", len(mapped), mapped[0].Error)
+	}
+
+	prompt := strings.ReplaceAll(reducePrompt, "{results}", strings.TrimSpace(combined.String()))
+	result, err := p.cfg.call(ctx, p.client, prompt, p.cfg.model, p.cfg.tokens(4096))
+	if err != nil {
+		return "", fmt.Errorf("reduce step failed: %w", err)
+	}
+	return result, nil
 }
 
 // VotingParallelizer gets multiple votes for consensus
 type VotingParallelizer struct {
-	client *AnthropicClient
-	model  string
+	client    *AnthropicClient
+	cfg       patternConfig
+	tieBreak  TieBreakStrategy
+	tallyMode TallyMode
+	strategy  VotingStrategy
+}
+
+// VotingStrategy determines how ballots decide the winner. Votes weighted
+// by confidence are a tally mode, TallyConfidenceWeighted.
+type VotingStrategy int
+
+const (
+	// VotingMajority elects the option chosen by the most voters
+	VotingMajority VotingStrategy = iota
+	// VotingBorda asks voters to rank every option; an option scores
+	// n-1 points for each first place among n options, n-2 for each
+	// second, and so on
+	VotingBorda
+	// VotingRankedChoice asks voters to rank every option and runs an
+	// instant runoff: the option with the fewest first preferences is
+	// eliminated, its ballots move to their next preference, and so on
+	// until one option holds a majority
+	VotingRankedChoice
+)
+
+func (s VotingStrategy) String() string {
+	switch s {
+	case VotingMajority:
+		return "Majority"
+	case VotingBorda:
+		return "Borda"
+	case VotingRankedChoice:
+		return "RankedChoice"
+	default:
+		return "Unknown"
+	}
 }
 
+// TallyMode determines how ballots are counted
+type TallyMode int
+
+const (
+	// TallyMajority counts one vote per voter
+	TallyMajority TallyMode = iota
+	// TallyConfidenceWeighted also asks voters for a confidence value and
+	// additionally reports a winner with votes weighted by confidence, as
+	// WeightedWinningOption. Ranked strategies, whose ballots carry no
+	// confidence, reject it.
+	TallyConfidenceWeighted
+)
+
+// TieBreakStrategy determines how a tie between the top options is
+// resolved. Revotes and runoffs are tallied with the voting strategy.
+type TieBreakStrategy int
+
+const (
+	// TieBreakLowestIndex picks the tied option that was listed first
+	TieBreakLowestIndex TieBreakStrategy = iota
+	// TieBreakRevote runs another full round of voting on all options
+	TieBreakRevote
+	// TieBreakRunoff runs another round restricted to the tied options
+	TieBreakRunoff
+)
+
+func (t TieBreakStrategy) String() string {
+	switch t {
+	case TieBreakLowestIndex:
+		return "LowestIndex"
+	case TieBreakRevote:
+		return "Revote"
+	case TieBreakRunoff:
+		return "Runoff"
+	default:
+		return "Unknown"
+	}
+}
+
+// maxTieBreakRounds bounds revote/runoff rounds before falling back to lowest index
+const maxTieBreakRounds = 2
+
 // NewVotingParallelizer creates a new VotingParallelizer
-func NewVotingParallelizer(client *AnthropicClient, model string) *VotingParallelizer {
+func NewVotingParallelizer(client *AnthropicClient, opts ...Option) *VotingParallelizer {
 	return &VotingParallelizer{
 		client: client,
-		model:  model,
+		cfg:    newPatternConfig("voting", opts),
 	}
 }
 
@@ -172,6 +664,26 @@ func NewVotingParallelizer(client *AnthropicClient, model string) *VotingParalle
 type VoteCount struct {
 	Option string
 	Votes  int
+	// Weight is the sum of voter confidence when using TallyConfidenceWeighted
+	Weight float64
+	// Score is the option's standing under the voting strategy: first
+	// choices, Borda points, or votes in the final instant-runoff round
+	Score float64
+}
+
+// Juror defines a group of voters that share the same model
+type Juror struct {
+	Model string
+	Count int
+}
+
+// ModelTally represents the votes cast by a single model
+type ModelTally struct {
+	Model         string
+	VoteCounts    []VoteCount
+	TotalVotes    int
+	WinningOption string
+	WinningIndex  int
 }
 
 // VotingResult represents the result of a vote
@@ -180,91 +692,464 @@ type VotingResult struct {
 	WinningIndex  int
 	VoteCounts    []VoteCount
 	TotalVotes    int
-	Consensus     bool
+	// Consensus reports whether the winner was most voters' first choice
+	Consensus    bool
+	ModelTallies []ModelTally
+	// Tied reports whether the first round ended with several top options
+	Tied bool
+	// TiedIndices lists the options that tied in the first round
+	TiedIndices []int
+	// TieBreak is the strategy that resolved the tie, if there was one
+	TieBreak TieBreakStrategy
+	// TieBreakRounds is the number of extra voting rounds that were run
+	TieBreakRounds int
+	// WeightedWinningOption and WeightedWinningIndex are the winner when
+	// votes are weighted by confidence (TallyConfidenceWeighted only)
+	WeightedWinningOption string
+	WeightedWinningIndex  int
+	// Strategy is the voting strategy that chose the winner
+	Strategy VotingStrategy
+	// Eliminated lists options in the order instant runoff eliminated them
+	// (VotingRankedChoice only)
+	Eliminated []int
+}
+
+// WithTieBreak sets the strategy used when the top options tie
+func (v *VotingParallelizer) WithTieBreak(strategy TieBreakStrategy) *VotingParallelizer {
+	v.tieBreak = strategy
+	return v
+}
+
+// WithTallyMode sets how ballots are counted
+func (v *VotingParallelizer) WithTallyMode(mode TallyMode) *VotingParallelizer {
+	v.tallyMode = mode
+	return v
+}
+
+// WithVotingStrategy sets how ballots decide the winner
+//
+// Example:
+//
+//	voter := NewVotingParallelizer(client).
+//	    WithVotingStrategy(VotingRankedChoice).
+//	    WithTieBreak(TieBreakRunoff)
+func (v *VotingParallelizer) WithVotingStrategy(strategy VotingStrategy) *VotingParallelizer {
+	v.strategy = strategy
+	return v
 }
 
 // Vote gets multiple votes on a decision
 func (v *VotingParallelizer) Vote(ctx context.Context, question string, options []string, voterCount int) (*VotingResult, error) {
-	var optionsList strings.Builder
-	for i, opt := range options {
-		optionsList.WriteString(fmt.Sprintf("%d. %s\n", i+1, opt))
+	return v.VoteWithJury(ctx, question, options, []Juror{{Model: v.cfg.model, Count: voterCount}})
+}
+
+// VoteWithJury gets votes from a heterogeneous jury of models.
+// Per-model tallies are reported in ModelTallies so systematic
+// disagreements between models are visible.
+//
+// Example:
+//
+//	result, err := voter.VoteWithJury(ctx, question, options, []Juror{
+//	    {Model: "claude-3-haiku-20240307", Count: 3},
+//	    {Model: "claude-sonnet-4-20250514", Count: 3},
+//	    {Model: "claude-opus-4-20250514", Count: 1},
+//	})
+func (v *VotingParallelizer) VoteWithJury(ctx context.Context, question string, options []string, jurors []Juror) (*VotingResult, error) {
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no options to vote on")
+	}
+	if v.ranked() && v.tallyMode == TallyConfidenceWeighted {
+		return nil, fmt.Errorf("%s ballots are rankings without a confidence, so they cannot be tallied by confidence", v.strategy)
 	}
 
-	prompt := fmt.Sprintf(`Consider this question:
-%s
+	ctx, cancel := v.cfg.startRun(ctx)
+	defer cancel()
 
-Options:
-%s
+	// Expand jurors into one model per voter
+	var voterModels []string
+	for _, juror := range jurors {
+		for i := 0; i < juror.Count; i++ {
+			voterModels = append(voterModels, juror.Model)
+		}
+	}
+	if len(voterModels) == 0 {
+		return nil, fmt.Errorf("jury has no voters")
+	}
+
+	allIndices := make([]int, len(options))
+	for i := range options {
+		allIndices[i] = i
+	}
+
+	ballots, err := v.runVotingRound(ctx, question, options, allIndices, voterModels)
+	if err != nil {
+		return nil, err
+	}
+	votes := firstChoices(ballots)
+	voteCounts, validVotes := countVotes(votes)
+	scores, eliminated := v.tally(ballots, allIndices)
+	winners := topScores(scores, allIndices)
+	winningIndex := winners[0]
+
+	result := &VotingResult{Strategy: v.strategy, Eliminated: eliminated}
+	if len(winners) > 1 {
+		result.Tied = true
+		result.TiedIndices = winners
+		result.TieBreak = v.tieBreak
+		winningIndex, result.TieBreakRounds, err = v.breakTie(ctx, question, options, allIndices, winners, voterModels)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Build per-model tallies in jury order
+	var modelTallies []ModelTally
+	seen := make(map[string]int)
+	modelVotes := make(map[string][]int)
+	for i, model := range voterModels {
+		if _, ok := seen[model]; !ok {
+			seen[model] = len(modelTallies)
+			modelTallies = append(modelTallies, ModelTally{Model: model})
+		}
+		modelVotes[model] = append(modelVotes[model], votes[i])
+	}
+	for i := range modelTallies {
+		counts, valid := countVotes(modelVotes[modelTallies[i].Model])
+		winners, _ := findWinners(counts, len(options))
+		idx := winners[0]
+		modelTallies[i].VoteCounts = buildVoteCounts(options, counts)
+		modelTallies[i].TotalVotes = valid
+		modelTallies[i].WinningIndex = idx
+		modelTallies[i].WinningOption = options[idx]
+	}
+
+	result.WinningOption = options[winningIndex]
+	result.WinningIndex = winningIndex
+	result.VoteCounts = buildVoteCounts(options, voteCounts)
+	result.TotalVotes = validVotes
+	for i := range options {
+		result.VoteCounts[i].Score = scores[i]
+	}
 
-Analyze carefully and respond with only the number of your chosen option.`, question, optionsList.String())
+	if v.confidenceBallots() {
+		weights := weighVotes(ballots)
+		weightedIndex := 0
+		for i := range options {
+			result.VoteCounts[i].Weight = weights[i]
+			if weights[i] > weights[weightedIndex] {
+				weightedIndex = i
+			}
+		}
+		result.WeightedWinningIndex = weightedIndex
+		result.WeightedWinningOption = options[weightedIndex]
+	}
+	result.Consensus = validVotes > 0 && voteCounts[winningIndex] > validVotes/2
+	result.ModelTallies = modelTallies
+
+	v.cfg.publish(ctx, PhaseVoteTallied, map[string]interface{}{
+		"strategy":  v.strategy.String(),
+		"winner":    result.WinningOption,
+		"votes":     validVotes,
+		"consensus": result.Consensus,
+		"tied":      result.Tied,
+	})
+
+	return result, nil
+}
+
+// breakTie resolves a tie between the given option indices using the
+// configured strategy, returning the winner and the number of extra rounds
+func (v *VotingParallelizer) breakTie(ctx context.Context, question string, options []string, allIndices, tied []int, voterModels []string) (int, int, error) {
+	rounds := 0
+	for v.tieBreak != TieBreakLowestIndex && rounds < maxTieBreakRounds {
+		candidates := allIndices
+		if v.tieBreak == TieBreakRunoff {
+			candidates = tied
+		}
+
+		ballots, err := v.runVotingRound(ctx, question, options, candidates, voterModels)
+		if err != nil {
+			return 0, rounds, err
+		}
+		rounds++
+
+		scores, _ := v.tally(ballots, candidates)
+		winners := topScores(scores, candidates)
+		top := scores[winners[0]]
+		if top > 0 && len(winners) == 1 {
+			return winners[0], rounds, nil
+		}
+		if top > 0 && v.tieBreak == TieBreakRunoff {
+			tied = winners
+		}
+	}
 
-	votes := make([]int, voterCount)
+	// Fall back to the first-listed tied option
+	return tied[0], rounds, nil
+}
+
+// ballot is one voter's response: the options it chose, as indices into
+// the full option list, best first, and its confidence. A failed vote has
+// no ranking.
+type ballot struct {
+	ranking    []int
+	confidence float64
+}
+
+// ranked reports whether the strategy asks voters to rank every option
+func (v *VotingParallelizer) ranked() bool {
+	return v.strategy == VotingBorda || v.strategy == VotingRankedChoice
+}
+
+// confidenceBallots reports whether voters are asked for a confidence
+func (v *VotingParallelizer) confidenceBallots() bool {
+	return v.tallyMode == TallyConfidenceWeighted
+}
+
+// runVotingRound asks every voter to choose among the candidate options and
+// returns their ballots
+func (v *VotingParallelizer) runVotingRound(ctx context.Context, question string, options []string, candidates []int, voterModels []string) ([]ballot, error) {
+	listed := make([]map[string]interface{}, len(candidates))
+	for i, idx := range candidates {
+		listed[i] = map[string]interface{}{"number": i + 1, "text": options[idx]}
+	}
+	prompt, err := v.cfg.prompt("voting.ballot", map[string]interface{}{
+		"question":   question,
+		"options":    listed,
+		"ranked":     v.ranked(),
+		"confidence": v.confidenceBallots(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ballots := make([]ballot, len(voterModels))
 	var wg sync.WaitGroup
-	var mu sync.Mutex
 
-	for i := 0; i < voterCount; i++ {
+	for i, model := range voterModels {
 		wg.Add(1)
-		go func(idx int) {
+		go func(idx int, m string) {
 			defer wg.Done()
+			b := v.castVote(ctx, prompt, m, len(candidates))
+			for j, choice := range b.ranking {
+				b.ranking[j] = candidates[choice]
+			}
+			ballots[idx] = b
 
-			// Create request with temperature for variance
-			reqBody := struct {
-				Model       string        `json:"model"`
-				MaxTokens   int           `json:"max_tokens"`
-				Messages    []MessageItem `json:"messages"`
-				Temperature float64       `json:"temperature"`
-			}{
-				Model:       v.model,
-				MaxTokens:   10,
-				Messages:    []MessageItem{{Role: "user", Content: prompt}},
-				Temperature: 0.7,
+			payload := map[string]interface{}{"voter": idx, "model": m, "valid": len(b.ranking) > 0}
+			if len(b.ranking) > 0 {
+				payload["choice"] = options[b.ranking[0]]
+			}
+			if v.confidenceBallots() {
+				payload["confidence"] = b.confidence
 			}
+			v.cfg.publish(ctx, PhaseVoteCast, payload)
+		}(i, model)
+	}
 
-			jsonData, _ := json.Marshal(reqBody)
-			req, _ := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
-			req.Header.Set("x-api-key", v.client.APIKey)
-			req.Header.Set("anthropic-version", "2023-06-01")
-			req.Header.Set("content-type", "application/json")
+	wg.Wait()
+	return ballots, nil
+}
 
-			resp, err := v.client.HTTPClient.Do(req)
-			if err != nil {
-				mu.Lock()
-				votes[idx] = -1
-				mu.Unlock()
-				return
-			}
-			defer resp.Body.Close()
+// firstChoices returns each ballot's first choice, or -1 for a failed vote
+func firstChoices(ballots []ballot) []int {
+	votes := make([]int, len(ballots))
+	for i, b := range ballots {
+		votes[i] = -1
+		if len(b.ranking) > 0 {
+			votes[i] = b.ranking[0]
+		}
+	}
+	return votes
+}
 
-			var msgResp MessageResponse
-			if err := json.NewDecoder(resp.Body).Decode(&msgResp); err != nil {
-				mu.Lock()
-				votes[idx] = -1
-				mu.Unlock()
-				return
+// weighVotes sums voter confidence per first-choice option index
+func weighVotes(ballots []ballot) map[int]float64 {
+	weights := make(map[int]float64)
+	for _, b := range ballots {
+		if len(b.ranking) > 0 {
+			weights[b.ranking[0]] += b.confidence
+		}
+	}
+	return weights
+}
+
+// tally scores the candidates from ballots with the voting strategy, also
+// returning the instant-runoff elimination order
+func (v *VotingParallelizer) tally(ballots []ballot, candidates []int) (map[int]float64, []int) {
+	switch v.strategy {
+	case VotingBorda:
+		return bordaCount(ballots, candidates), nil
+	case VotingRankedChoice:
+		return instantRunoff(ballots, candidates)
+	default:
+		scores := make(map[int]float64)
+		for _, vote := range firstChoices(ballots) {
+			if vote >= 0 {
+				scores[vote]++
 			}
+		}
+		return scores, nil
+	}
+}
 
-			for _, block := range msgResp.Content {
-				if block.Type == "text" {
-					var vote int
-					fmt.Sscanf(strings.TrimSpace(block.Text), "%d", &vote)
-					if vote >= 1 && vote <= len(options) {
-						mu.Lock()
-						votes[idx] = vote - 1 // 0-indexed
-						mu.Unlock()
-						return
-					}
+// bordaCount gives each candidate n-1 points per first place among n
+// candidates, n-2 per second place, and so on. Unranked candidates score
+// nothing.
+func bordaCount(ballots []ballot, candidates []int) map[int]float64 {
+	points := make(map[int]float64)
+	for _, b := range ballots {
+		for place, idx := range b.ranking {
+			points[idx] += float64(len(candidates) - 1 - place)
+		}
+	}
+	return points
+}
+
+// instantRunoff eliminates the candidate with the fewest first preferences,
+// moving its ballots to their next preference, until one candidate holds a
+// majority of the ballots still in play or the rest are level. It returns
+// the final round's counts and the elimination order; among equally weak
+// candidates the one listed last goes first.
+func instantRunoff(ballots []ballot, candidates []int) (map[int]float64, []int) {
+	remaining := make(map[int]bool, len(candidates))
+	for _, idx := range candidates {
+		remaining[idx] = true
+	}
+
+	var eliminated []int
+	for {
+		counts := make(map[int]float64)
+		active := 0
+		for _, b := range ballots {
+			for _, idx := range b.ranking {
+				if remaining[idx] {
+					counts[idx]++
+					active++
+					break
 				}
 			}
+		}
 
-			mu.Lock()
-			votes[idx] = -1
-			mu.Unlock()
-		}(i)
+		lowest, highest := -1, -1
+		for _, idx := range candidates {
+			if !remaining[idx] {
+				continue
+			}
+			if lowest < 0 || counts[idx] <= counts[lowest] {
+				lowest = idx
+			}
+			if highest < 0 || counts[idx] > counts[highest] {
+				highest = idx
+			}
+		}
+		if active == 0 || counts[highest]*2 > float64(active) || counts[lowest] == counts[highest] {
+			return counts, eliminated
+		}
+		remaining[lowest] = false
+		eliminated = append(eliminated, lowest)
 	}
+}
 
-	wg.Wait()
+// topScores returns every candidate sharing the highest score, in option
+// order. No positive score is not a tie; the first candidate is returned.
+func topScores(scores map[int]float64, candidates []int) []int {
+	best := 0.0
+	for _, idx := range candidates {
+		if scores[idx] > best {
+			best = scores[idx]
+		}
+	}
+	if best == 0 {
+		return []int{candidates[0]}
+	}
+
+	var winners []int
+	for _, idx := range candidates {
+		if scores[idx] == best {
+			winners = append(winners, idx)
+		}
+	}
+	return winners
+}
+
+// castVote asks a single voter for its ballot, with the ranking as 0-indexed
+// positions among the numOptions presented. Confidence is 1.0 unless
+// confidence ballots are enabled.
+func (v *VotingParallelizer) castVote(ctx context.Context, prompt, model string, numOptions int) ballot {
+	maxTokens := 10
+	switch {
+	case v.ranked():
+		maxTokens = 10 + 4*numOptions
+	case v.confidenceBallots():
+		maxTokens = 20
+	}
+
+	// Sample with temperature so voters vary
+	temperature := 0.7
+	req := promptRequest(prompt, model, maxTokens)
+	req.Temperature = &temperature
+	text, err := v.cfg.callWith(ctx, model, prompt, func(ctx context.Context) (string, Usage, error) {
+		return v.cfg.send(ctx, v.client, req)
+	})
+	if err != nil {
+		return ballot{}
+	}
+
+	if v.ranked() {
+		return ballot{ranking: parseRanking(text, numOptions), confidence: 1.0}
+	}
+
+	vote, confidence := parseBallot(text)
+	if vote >= 1 && vote <= numOptions {
+		if !v.confidenceBallots() {
+			confidence = 1.0
+		}
+		return ballot{ranking: []int{vote - 1}, confidence: confidence} // 0-indexed
+	}
+
+	return ballot{}
+}
+
+// rankingNumber matches the option numbers in a ranked ballot
+var rankingNumber = regexp.MustCompile(`\d+`)
+
+// parseRanking parses option numbers from a ranked ballot into 0-indexed
+// positions, best first, skipping repeats and numbers out of range
+func parseRanking(text string, numOptions int) []int {
+	var ranking []int
+	seen := make(map[int]bool)
+	for _, field := range rankingNumber.FindAllString(text, -1) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > numOptions || seen[n] {
+			continue
+		}
+		seen[n] = true
+		ranking = append(ranking, n-1)
+	}
+	return ranking
+}
+
+// parseBallot parses "<option> [confidence]" from a voter response.
+// A missing or invalid confidence defaults to 0.5.
+func parseBallot(text string) (int, float64) {
+	var vote int
+	confidence := 0.5
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return 0, confidence
+	}
+	fmt.Sscanf(strings.TrimRight(fields[0], ".,:"), "%d", &vote)
+	if len(fields) > 1 {
+		if conf, err := strconv.ParseFloat(strings.Trim(fields[1], "()[],"), 64); err == nil {
+			confidence = math.Max(0, math.Min(1, conf))
+		}
+	}
+	return vote, confidence
+}
 
-	// Count valid votes
+// countVotes counts valid (non-negative) votes per option index
+func countVotes(votes []int) (map[int]int, int) {
 	voteCounts := make(map[int]int)
 	validVotes := 0
 	for _, vote := range votes {
@@ -273,18 +1158,35 @@ Analyze carefully and respond with only the number of your chosen option.`, ques
 			validVotes++
 		}
 	}
+	return voteCounts, validVotes
+}
 
-	// Find winner
-	winningIndex := 0
+// findWinners returns every option index sharing the highest vote count,
+// in option order, so the result never depends on map iteration order
+func findWinners(voteCounts map[int]int, numOptions int) ([]int, int) {
 	maxVotes := 0
-	for idx, count := range voteCounts {
-		if count > maxVotes {
-			maxVotes = count
-			winningIndex = idx
+	for idx := 0; idx < numOptions; idx++ {
+		if voteCounts[idx] > maxVotes {
+			maxVotes = voteCounts[idx]
+		}
+	}
+
+	// No valid votes is not a tie; default to the first option
+	if maxVotes == 0 {
+		return []int{0}, 0
+	}
+
+	var winners []int
+	for idx := 0; idx < numOptions; idx++ {
+		if voteCounts[idx] == maxVotes {
+			winners = append(winners, idx)
 		}
 	}
+	return winners, maxVotes
+}
 
-	// Build vote counts
+// buildVoteCounts converts vote counts into an ordered list per option
+func buildVoteCounts(options []string, voteCounts map[int]int) []VoteCount {
 	voteCountsList := make([]VoteCount, len(options))
 	for i, opt := range options {
 		voteCountsList[i] = VoteCount{
@@ -292,16 +1194,7 @@ Analyze carefully and respond with only the number of your chosen option.`, ques
 			Votes:  voteCounts[i],
 		}
 	}
-
-	consensus := validVotes > 0 && maxVotes > validVotes/2
-
-	return &VotingResult{
-		WinningOption: options[winningIndex],
-		WinningIndex:  winningIndex,
-		VoteCounts:    voteCountsList,
-		TotalVotes:    validVotes,
-		Consensus:     consensus,
-	}, nil
+	return voteCountsList
 }
 
 // SafetyVotingResult represents the result of a safety vote
@@ -314,11 +1207,13 @@ type SafetyVotingResult struct {
 
 // SafetyVote performs a safety vote requiring unanimous agreement
 func (v *VotingParallelizer) SafetyVote(ctx context.Context, content string, voterCount int) (*SafetyVotingResult, error) {
-	prompt := fmt.Sprintf(`Evaluate if this content is safe and appropriate:
-
-%s
+	prompt, err := v.cfg.prompt("voting.safety", map[string]interface{}{"content": content})
+	if err != nil {
+		return nil, err
+	}
 
-Respond with only 'SAFE' or 'UNSAFE'.`, content)
+	ctx, cancel := v.cfg.startRun(ctx)
+	defer cancel()
 
 	votes := make([]bool, voterCount)
 	var wg sync.WaitGroup
@@ -328,7 +1223,7 @@ Respond with only 'SAFE' or 'UNSAFE'.`, content)
 		go func(idx int) {
 			defer wg.Done()
 
-			response, err := v.client.CreateMessage(ctx, prompt, v.model, 10)
+			response, err := v.cfg.call(ctx, v.client, prompt, v.cfg.model, 10)
 			if err != nil {
 				votes[idx] = false
 				return
@@ -359,24 +1254,111 @@ Respond with only 'SAFE' or 'UNSAFE'.`, content)
 	}, nil
 }
 
-// GuardrailsParallelizer runs guardrails in parallel with main task
+// Actions taken when an output guardrail fails
+const (
+	// OutputBlock withholds the result
+	OutputBlock = "block"
+	// OutputRedact removes the offending content, for guardrails that are
+	// a Redactor; others block
+	OutputRedact = "redact"
+	// OutputRegenerate asks for a new result, telling the model why the
+	// last was rejected, and blocks once regenerations run out
+	OutputRegenerate = "regenerate"
+)
+
+// defaultMaxRegenerations bounds OutputRegenerate retries per execution
+const defaultMaxRegenerations = 2
+
+// GuardrailsParallelizer runs guardrails in parallel with main task, then
+// screens its result with output guardrails
 type GuardrailsParallelizer struct {
-	client *AnthropicClient
-	model  string
+	client           *AnthropicClient
+	cfg              patternConfig
+	guardrails       []Guardrail
+	outputs          []outputGuardrail
+	maxRegenerations int
+	earlyAbort       bool
+}
+
+// outputGuardrail is a guardrail screening the main result
+type outputGuardrail struct {
+	guardrail Guardrail
+	action    string
 }
 
 // NewGuardrailsParallelizer creates a new GuardrailsParallelizer
-func NewGuardrailsParallelizer(client *AnthropicClient, model string) *GuardrailsParallelizer {
+func NewGuardrailsParallelizer(client *AnthropicClient, opts ...Option) *GuardrailsParallelizer {
 	return &GuardrailsParallelizer{
-		client: client,
-		model:  model,
+		client:           client,
+		cfg:              newPatternConfig("guardrails", opts),
+		maxRegenerations: defaultMaxRegenerations,
 	}
 }
 
+// AddGuardrail adds guardrails that run on every execution
+func (g *GuardrailsParallelizer) AddGuardrail(guardrails ...Guardrail) *GuardrailsParallelizer {
+	g.guardrails = append(g.guardrails, guardrails...)
+	return g
+}
+
+// AddOutputGuardrail adds guardrails that screen the main task's result,
+// taking action (OutputBlock, OutputRedact, or OutputRegenerate) when one
+// fails. Streamed deltas are sent before screening, so stream only to
+// trusted consumers.
+//
+// Example:
+//
+//	g.AddOutputGuardrail(OutputRedact, NewPIIGuardrail())
+//	g.AddOutputGuardrail(OutputRegenerate, NewLLMJudgeGuardrail(client, "on_topic", prompt))
+func (g *GuardrailsParallelizer) AddOutputGuardrail(action string, guardrails ...Guardrail) *GuardrailsParallelizer {
+	for _, gr := range guardrails {
+		g.outputs = append(g.outputs, outputGuardrail{guardrail: gr, action: action})
+	}
+	return g
+}
+
+// SetEarlyAbort makes the main task stream while the input guardrails run,
+// and cancels it as soon as one fails rather than paying for a response
+// that will be blocked. Deltas go to the WithStreaming handler, if any.
+func (g *GuardrailsParallelizer) SetEarlyAbort(enabled bool) *GuardrailsParallelizer {
+	g.earlyAbort = enabled
+	return g
+}
+
+// SetMaxRegenerations sets how many times OutputRegenerate may ask for a
+// new result in one execution
+func (g *GuardrailsParallelizer) SetMaxRegenerations(n int) *GuardrailsParallelizer {
+	g.maxRegenerations = n
+	return g
+}
+
+// RegisterGuardrail adds a guardrail definition that runs on every
+// execution, with LLM checks sent through the parallelizer's client
+func (g *GuardrailsParallelizer) RegisterGuardrail(def GuardrailDef) *GuardrailsParallelizer {
+	return g.AddGuardrail(def.Bind(g.client))
+}
+
+// UseGuardrails registers built-in guardrails by name, e.g. GuardrailPII
+func (g *GuardrailsParallelizer) UseGuardrails(names ...string) error {
+	defs, err := resolveGuardrails(names)
+	if err != nil {
+		return err
+	}
+	for _, def := range defs {
+		g.RegisterGuardrail(def)
+	}
+	return nil
+}
+
 // GuardrailResult represents the result of a guardrail check
 type GuardrailResult struct {
 	Name   string
 	Passed bool
+	// Reason explains a failure, or holds the error of a check that failed
+	// to run
+	Reason string
+	// Action is what a failed output guardrail did to the result
+	Action string
 }
 
 // GuardrailedResult represents the result of a guardrailed execution
@@ -385,51 +1367,89 @@ type GuardrailedResult struct {
 	Blocked            bool
 	GuardrailResults   []GuardrailResult
 	BlockingGuardrails []string
+	// OutputResults holds the output guardrail checks of every result
+	// screened, including those regenerated
+	OutputResults []GuardrailResult
+	Regenerations int
+	Redacted      bool
+	// Aborted is set when early abort cancelled the main task
+	Aborted bool
 }
 
-// ExecuteWithGuardrails executes task with parallel guardrails
+// ExecuteWithGuardrails executes task with parallel guardrails: those
+// given here and those registered. A guardrail that fails, or fails to
+// run, blocks the result. If the input passes, the result is then screened
+// by the output guardrails.
 func (g *GuardrailsParallelizer) ExecuteWithGuardrails(
 	ctx context.Context,
 	input string,
 	taskPrompt string,
-	guardrailPrompts []string,
+	guardrails ...Guardrail,
 ) (*GuardrailedResult, error) {
+	ctx, cancel := g.cfg.startRun(ctx)
+	defer cancel()
+
 	var wg sync.WaitGroup
 	var mainResult string
 	var mainErr error
-	guardrailResults := make([]GuardrailResult, len(guardrailPrompts))
+
+	all := append(append([]Guardrail(nil), guardrails...), g.guardrails...)
+	guardrailResults := make([]GuardrailResult, len(all))
 
 	// Run main task
+	mainCtx, abort := context.WithCancel(ctx)
+	defer abort()
+	var abortOnce sync.Once
+	aborted := false
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		mainResult, mainErr = g.client.CreateMessage(ctx, taskPrompt, g.model, 4096)
+		if !g.earlyAbort {
+			mainResult, mainErr = g.cfg.call(mainCtx, g.client, taskPrompt, g.cfg.model, g.cfg.tokens(4096))
+			return
+		}
+		mainResult, mainErr = g.cfg.callWith(mainCtx, g.cfg.model, taskPrompt, func(ctx context.Context) (string, Usage, error) {
+			onDelta := g.cfg.streamer(ctx)
+			if onDelta == nil {
+				onDelta = func(string) {}
+			}
+			req := promptRequest(taskPrompt, g.cfg.model, g.cfg.tokens(4096))
+			g.cfg.request.apply(&req)
+			return g.client.SendStream(ctx, req, onDelta)
+		})
 	}()
 
 	// Run guardrails
-	for i, prompt := range guardrailPrompts {
+	for i, guardrail := range all {
 		wg.Add(1)
-		go func(idx int, p string) {
+		go func(idx int, gr Guardrail) {
 			defer wg.Done()
 
-			checkPrompt := strings.ReplaceAll(p, "{input}", input) + "\n\nRespond with only 'PASS' or 'FAIL'."
-			response, err := g.client.CreateMessage(ctx, checkPrompt, "claude-3-haiku-20240307", 10)
-
-			passed := false
-			if err == nil {
-				passed = strings.Contains(strings.ToUpper(response), "PASS")
+			verdict, err := checkGuardrail(ctx, gr, input)
+			if err != nil {
+				verdict = GuardrailVerdict{Reason: err.Error()}
 			}
-
 			guardrailResults[idx] = GuardrailResult{
-				Name:   fmt.Sprintf("guardrail_%d", idx),
-				Passed: passed,
+				Name:   gr.Name(),
+				Passed: verdict.Passed,
+				Reason: verdict.Reason,
+			}
+			if !verdict.Passed && g.earlyAbort {
+				abortOnce.Do(func() {
+					aborted = true
+					abort()
+					publish(ctx, "guardrails", PhaseGuardrail, map[string]interface{}{
+						"guardrail": gr.Name(),
+						"action":    "abort",
+					})
+				})
 			}
-		}(i, prompt)
+		}(i, guardrail)
 	}
 
 	wg.Wait()
 
-	if mainErr != nil {
+	if mainErr != nil && !aborted {
 		return nil, mainErr
 	}
 
@@ -443,17 +1463,112 @@ func (g *GuardrailsParallelizer) ExecuteWithGuardrails(
 		}
 	}
 
-	var result *string
-	if allPassed {
-		result = &mainResult
-	}
-
-	return &GuardrailedResult{
-		Result:             result,
+	result := &GuardrailedResult{
+		Aborted:            aborted && mainErr != nil,
 		Blocked:            !allPassed,
 		GuardrailResults:   guardrailResults,
 		BlockingGuardrails: blocking,
-	}, nil
+	}
+	if !allPassed {
+		return result, nil
+	}
+	if err := g.screenOutput(ctx, taskPrompt, mainResult, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// screenOutput runs the output guardrails over text, regenerating and
+// redacting as their actions direct, and records the outcome in result
+func (g *GuardrailsParallelizer) screenOutput(ctx context.Context, taskPrompt, text string, result *GuardrailedResult) error {
+	for {
+		checks := g.checkAll(ctx, g.outputs, text)
+
+		var blocking, regenerate []GuardrailResult
+		var redactors []Redactor
+		for i := range checks {
+			if checks[i].Passed {
+				continue
+			}
+			action := g.outputs[i].action
+			redactor, canRedact := g.outputs[i].guardrail.(Redactor)
+			if (action == OutputRegenerate && result.Regenerations >= g.maxRegenerations) ||
+				(action == OutputRedact && !canRedact) || (action != OutputRegenerate && action != OutputRedact) {
+				action = OutputBlock
+			}
+			checks[i].Action = action
+			switch action {
+			case OutputBlock:
+				blocking = append(blocking, checks[i])
+			case OutputRegenerate:
+				regenerate = append(regenerate, checks[i])
+			case OutputRedact:
+				redactors = append(redactors, redactor)
+			}
+		}
+		result.OutputResults = append(result.OutputResults, checks...)
+
+		if len(blocking) > 0 {
+			result.Blocked = true
+			for _, check := range blocking {
+				result.BlockingGuardrails = append(result.BlockingGuardrails, check.Name)
+			}
+			return nil
+		}
+		if len(regenerate) > 0 {
+			result.Regenerations++
+			publish(ctx, "guardrails", PhaseGuardrail, map[string]interface{}{
+				"action":       OutputRegenerate,
+				"regeneration": result.Regenerations,
+			})
+			var err error
+			text, err = g.cfg.call(ctx, g.client, regeneratePrompt(taskPrompt, regenerate), g.cfg.model, g.cfg.tokens(4096))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, r := range redactors {
+			text = r.Redact(text)
+		}
+		result.Redacted = len(redactors) > 0
+		result.Result = &text
+		return nil
+	}
+}
+
+// checkAll runs guardrails over text in parallel. A guardrail that fails
+// to run fails its check.
+func (g *GuardrailsParallelizer) checkAll(ctx context.Context, guardrails []outputGuardrail, text string) []GuardrailResult {
+	results := make([]GuardrailResult, len(guardrails))
+	var wg sync.WaitGroup
+	for i, og := range guardrails {
+		wg.Add(1)
+		go func(idx int, gr Guardrail) {
+			defer wg.Done()
+			verdict, err := checkGuardrail(ctx, gr, text)
+			if err != nil {
+				verdict = GuardrailVerdict{Reason: err.Error()}
+			}
+			results[idx] = GuardrailResult{Name: gr.Name(), Passed: verdict.Passed, Reason: verdict.Reason}
+		}(i, og.guardrail)
+	}
+	wg.Wait()
+	return results
+}
+
+// regeneratePrompt asks again for taskPrompt, explaining why the last
+// result was rejected
+func regeneratePrompt(taskPrompt string, rejected []GuardrailResult) string {
+	var b strings.Builder
+	b.WriteString(taskPrompt)
+	b.WriteString("\n\nA previous response was rejected by these checks:\n")
+	for _, r := range rejected {
+		fmt.Fprintf(&b, "- %s: %s\n", r.Name, r.Reason)
+	}
+	b.WriteString("Write a new response that passes them.")
+	return b.String()
 }
 
 // ExampleCodeReview demonstrates the parallelization pattern
@@ -468,7 +1583,7 @@ func ExampleCodeReview() error {
 		HTTPClient: &http.Client{},
 	}
 
-	parallelizer := NewSectioningParallelizer(client, "claude-sonnet-4-20250514")
+	parallelizer := NewSectioningParallelizer(client, WithModel("claude-sonnet-4-20250514"))
 
 	code := `
 func getUser(id int) *User {
@@ -0,0 +1,179 @@
+package agentpatterns
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/mockllm"
+)
+
+// byModel answers each voter by its model
+func byModel(mock *mockllm.Mock, answers map[string]string) *mockllm.Mock {
+	for model, text := range answers {
+		model := model
+		mock.WhenFunc(func(c mockllm.Call) bool { return c.Model == model }, mockllm.Response{Text: text})
+	}
+	return mock
+}
+
+func TestVoteTieBreak(t *testing.T) {
+	options := []string{"Apple", "Banana", "Carrot"}
+	jury := []Juror{{Model: "a", Count: 1}, {Model: "b", Count: 1}}
+	tests := []struct {
+		name       string
+		tieBreak   TieBreakStrategy
+		wantWinner string
+		wantRounds int
+	}{
+		// Voters split between Apple and Banana in every full round
+		{"lowest index", TieBreakLowestIndex, "Apple", 0},
+		{"revote falls back to lowest index", TieBreakRevote, "Apple", maxTieBreakRounds},
+		// The runoff leaves Carrot out, and both voters pick Banana
+		{"runoff", TieBreakRunoff, "Banana", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Repeated runs must agree despite voters answering concurrently
+			for run := 0; run < 5; run++ {
+				mock := mockllm.New().WhenFunc(func(c mockllm.Call) bool { return !strings.Contains(c.Prompt, "Carrot") },
+					mockllm.Response{Text: "2"})
+				byModel(mock, map[string]string{"a": "1", "b": "2"})
+				voter := NewVotingParallelizer(NewMockClient(mock).Client()).WithTieBreak(tt.tieBreak)
+
+				result, err := voter.VoteWithJury(context.Background(), "Which is a fruit?", options, jury)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !result.Tied || !reflect.DeepEqual(result.TiedIndices, []int{0, 1}) {
+					t.Fatalf("Tied = %v %v, want a tie between 0 and 1", result.Tied, result.TiedIndices)
+				}
+				if result.WinningOption != tt.wantWinner || result.TieBreakRounds != tt.wantRounds {
+					t.Errorf("run %d: winner %s after %d rounds, want %s after %d", run, result.WinningOption, result.TieBreakRounds, tt.wantWinner, tt.wantRounds)
+				}
+			}
+		})
+	}
+}
+
+func TestVoteConfidenceWeighted(t *testing.T) {
+	mock := byModel(mockllm.New(), map[string]string{"a": "1 0.9", "b": "2 0.3", "c": "2 0.3"})
+	voter := NewVotingParallelizer(NewMockClient(mock).Client()).WithTallyMode(TallyConfidenceWeighted)
+
+	result, err := voter.VoteWithJury(context.Background(), "Pick one", []string{"A", "B"},
+		[]Juror{{Model: "a", Count: 1}, {Model: "b", Count: 1}, {Model: "c", Count: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.WinningOption != "B" {
+		t.Errorf("WinningOption = %s, want B by count", result.WinningOption)
+	}
+	if result.WeightedWinningOption != "A" {
+		t.Errorf("WeightedWinningOption = %s, want A by confidence", result.WeightedWinningOption)
+	}
+}
+
+func TestVoteRejectsConfidenceForRankedBallots(t *testing.T) {
+	for _, strategy := range []VotingStrategy{VotingBorda, VotingRankedChoice} {
+		mock := mockllm.New().Default("1, 2")
+		voter := NewVotingParallelizer(NewMockClient(mock).Client()).
+			WithVotingStrategy(strategy).
+			WithTallyMode(TallyConfidenceWeighted)
+		if _, err := voter.Vote(context.Background(), "Pick one", []string{"A", "B"}, 3); err == nil {
+			t.Errorf("%s with TallyConfidenceWeighted: want an error", strategy)
+		}
+		mock.AssertCallCount(t, 0)
+	}
+}
+
+func TestVoteRankedChoice(t *testing.T) {
+	// First preferences A 2, B 2, C 1: C is eliminated and its ballot
+	// moves to B, which then holds a majority
+	mock := byModel(mockllm.New(), map[string]string{"a": "1, 2, 3", "b": "2, 3, 1", "c": "3, 2, 1"})
+	voter := NewVotingParallelizer(NewMockClient(mock).Client()).WithVotingStrategy(VotingRankedChoice)
+
+	result, err := voter.VoteWithJury(context.Background(), "Pick one", []string{"A", "B", "C"},
+		[]Juror{{Model: "a", Count: 2}, {Model: "b", Count: 2}, {Model: "c", Count: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.WinningOption != "B" || !reflect.DeepEqual(result.Eliminated, []int{2}) {
+		t.Errorf("winner %s, eliminated %v; want B, [2]", result.WinningOption, result.Eliminated)
+	}
+}
+
+func TestBordaCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		ballots []ballot
+		want    map[int]float64
+	}{
+		{
+			"full rankings",
+			[]ballot{{ranking: []int{0, 1, 2}}, {ranking: []int{1, 0, 2}}, {ranking: []int{1, 2, 0}}},
+			map[int]float64{0: 3, 1: 5, 2: 1},
+		},
+		{
+			"partial ranking scores only the ranked",
+			[]ballot{{ranking: []int{2}}, {ranking: []int{0, 2}}},
+			map[int]float64{0: 2, 2: 3},
+		},
+		{
+			"failed ballots are ignored",
+			[]ballot{{}, {ranking: []int{1, 0, 2}}},
+			map[int]float64{0: 1, 1: 2, 2: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bordaCount(tt.ballots, []int{0, 1, 2})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bordaCount = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstantRunoff(t *testing.T) {
+	rank := func(r ...int) ballot { return ballot{ranking: r} }
+	tests := []struct {
+		name           string
+		ballots        []ballot
+		wantCounts     map[int]float64
+		wantEliminated []int
+	}{
+		{
+			"first-round majority",
+			[]ballot{rank(0, 1), rank(0, 2), rank(1, 0)},
+			map[int]float64{0: 2, 1: 1},
+			nil,
+		},
+		{
+			"transfer decides",
+			[]ballot{rank(0, 1, 2), rank(0, 1, 2), rank(1, 2, 0), rank(1, 2, 0), rank(2, 1, 0)},
+			map[int]float64{0: 2, 1: 3},
+			[]int{2},
+		},
+		{
+			"weakest tie eliminates the last listed",
+			[]ballot{rank(0), rank(0), rank(1), rank(2)},
+			map[int]float64{0: 2, 1: 1},
+			[]int{2},
+		},
+		{
+			"exhausted ballots leave play",
+			[]ballot{rank(0), rank(0), rank(1), rank(1), rank(2)},
+			map[int]float64{0: 2, 1: 2},
+			[]int{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts, eliminated := instantRunoff(tt.ballots, []int{0, 1, 2})
+			if !reflect.DeepEqual(counts, tt.wantCounts) || !reflect.DeepEqual(eliminated, tt.wantEliminated) {
+				t.Errorf("instantRunoff = %v, %v; want %v, %v", counts, eliminated, tt.wantCounts, tt.wantEliminated)
+			}
+		})
+	}
+}
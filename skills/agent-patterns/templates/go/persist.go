@@ -0,0 +1,84 @@
+/*
+ * Run State Persistence for Go Agent Patterns
+ * Checkpoints written to a store.Store so runs can resume
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/store"
+)
+
+// WithStore checkpoints run state to st after every step: chain steps,
+// orchestrator subtasks, agent steps, and optimizer iterations. Running the
+// pattern again with the same run ID (see WithRunID) resumes from the last
+// checkpoint instead of starting over.
+//
+// Example:
+//
+//	st, err := store.NewFileStore("./state")
+//	orch := NewOrchestrator(client, WithStore(st))
+//	result, err := orch.Execute(WithRunID(ctx, "quarterly-report"), task)
+func WithStore(st store.Store) Option {
+	return func(c *patternConfig) { c.store = st }
+}
+
+type checkpointScopeKey struct{}
+
+// withCheckpointScope distinguishes the checkpoints of nested patterns that
+// share a run ID, such as one agent per orchestrator subtask
+func withCheckpointScope(ctx context.Context, name string) context.Context {
+	if scope, _ := ctx.Value(checkpointScopeKey{}).(string); scope != "" {
+		name = scope + "/" + name
+	}
+	return context.WithValue(ctx, checkpointScopeKey{}, name)
+}
+
+// checkpointKey is the store key for the current run of this pattern
+func (c *patternConfig) checkpointKey(ctx context.Context) string {
+	key := c.pattern + "/" + RunIDFromContext(ctx)
+	if scope, _ := ctx.Value(checkpointScopeKey{}).(string); scope != "" {
+		key += "/" + scope
+	}
+	return key
+}
+
+// saveCheckpoint stores v as JSON if a store is configured
+func (c *patternConfig) saveCheckpoint(ctx context.Context, v interface{}) error {
+	if c.store == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	if err := c.store.Put(ctx, c.checkpointKey(ctx), data); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// loadCheckpoint decodes the run's checkpoint into v and reports whether
+// one existed
+func (c *patternConfig) loadCheckpoint(ctx context.Context, v interface{}) (bool, error) {
+	if c.store == nil {
+		return false, nil
+	}
+	data, err := c.store.Get(ctx, c.checkpointKey(ctx))
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	c.logger.Info("resuming from checkpoint", "key", c.checkpointKey(ctx))
+	return true, nil
+}
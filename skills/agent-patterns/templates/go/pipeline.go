@@ -0,0 +1,238 @@
+/*
+ * Pattern Composition for Go
+ * Pipelines of routers, chains, orchestrators, agents, and optimizers
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/approvals"
+)
+
+// PipelineState is the shared context passed from stage to stage. Each stage
+// reads Output from the previous stage and replaces it with its own.
+type PipelineState struct {
+	Input  string
+	Output string
+	// Values holds named results, e.g. a router stage stores the category
+	// it picked under "<stage>.category"
+	Values map[string]interface{}
+}
+
+// Stage is one step of a Pipeline
+type Stage interface {
+	Name() string
+	Run(ctx context.Context, state *PipelineState) error
+}
+
+// StageRecord records the execution of one stage
+type StageRecord struct {
+	Stage    string
+	Output   string
+	Duration time.Duration
+	Error    string
+}
+
+// PipelineResult is the result of running a Pipeline
+type PipelineResult struct {
+	RunID  string
+	Output string
+	Values map[string]interface{}
+	Stages []StageRecord
+}
+
+// Pipeline composes patterns declaratively. Stages run in order within a
+// single run, so the run ID, budget, cost tracker, event bus, and tracer
+// configured on the pipeline are shared by every pattern inside it.
+//
+// Example:
+//
+//	pipeline := NewPipeline(WithBudget(Budget{MaxCalls: 20}), WithCostTracker(costs)).
+//	    Then(RouterStage("triage", router, 0.7)).
+//	    Then(ChainStage("draft", chain)).
+//	    Then(OptimizerStage("polish", optimizer, 3, 0.85))
+//	result, err := pipeline.Execute(ctx, "My card was charged twice")
+type Pipeline struct {
+	cfg    patternConfig
+	stages []Stage
+}
+
+// NewPipeline creates an empty pipeline. Model options are ignored; each
+// stage uses the pattern it wraps.
+func NewPipeline(opts ...Option) *Pipeline {
+	return &Pipeline{cfg: newPatternConfig("pipeline", opts)}
+}
+
+// Then appends a stage (builder pattern)
+func (p *Pipeline) Then(stage Stage) *Pipeline {
+	p.stages = append(p.stages, stage)
+	return p
+}
+
+// Execute runs every stage in order, stopping at the first error. The
+// result holds the stages completed so far even when an error is returned.
+func (p *Pipeline) Execute(ctx context.Context, input string) (*PipelineResult, error) {
+	ctx, cancel := p.cfg.startRun(ctx)
+	defer cancel()
+
+	state := &PipelineState{
+		Input:  input,
+		Output: input,
+		Values: make(map[string]interface{}),
+	}
+	result := &PipelineResult{RunID: RunIDFromContext(ctx), Values: state.Values}
+
+	for _, stage := range p.stages {
+		stageCtx, span := StartSpan(ctx, "pipeline.stage")
+		span.SetAttribute("stage", stage.Name())
+		p.cfg.publish(stageCtx, PhaseStepStarted, map[string]interface{}{"step": stage.Name()})
+
+		start := time.Now()
+		err := stage.Run(withCheckpointScope(stageCtx, stage.Name()), state)
+		record := StageRecord{Stage: stage.Name(), Output: state.Output, Duration: time.Since(start)}
+		span.Finish(err)
+
+		if err != nil {
+			record.Error = err.Error()
+			result.Stages = append(result.Stages, record)
+			result.Output = state.Output
+			p.cfg.publish(stageCtx, PhaseStepFailed, map[string]interface{}{"step": stage.Name(), "error": err})
+			return result, fmt.Errorf("stage '%s' failed: %w", stage.Name(), err)
+		}
+		result.Stages = append(result.Stages, record)
+		p.cfg.publish(stageCtx, PhaseStepFinished, map[string]interface{}{"step": stage.Name(), "output_chars": len(state.Output)})
+	}
+
+	result.Output = state.Output
+	return result, nil
+}
+
+// funcStage adapts a function to Stage
+type funcStage struct {
+	name string
+	fn   func(ctx context.Context, state *PipelineState) error
+}
+
+func (s funcStage) Name() string { return s.name }
+
+func (s funcStage) Run(ctx context.Context, state *PipelineState) error { return s.fn(ctx, state) }
+
+// StageFunc creates a stage from a function, e.g. for parsing or formatting
+// between patterns
+func StageFunc(name string, fn func(ctx context.Context, state *PipelineState) error) Stage {
+	return funcStage{name: name, fn: fn}
+}
+
+// ChainStage runs a PromptChain. The chain context contains "input" (the
+// previous stage's output) plus every entry of state.Values.
+func ChainStage(name string, chain *PromptChain) Stage {
+	return StageFunc(name, func(ctx context.Context, state *PipelineState) error {
+		chainContext := make(map[string]interface{}, len(state.Values)+1)
+		for k, v := range state.Values {
+			chainContext[k] = v
+		}
+		chainContext["input"] = state.Output
+
+		output, err := chain.Execute(ctx, chainContext)
+		if err != nil {
+			return err
+		}
+		state.Output = output
+		return nil
+	})
+}
+
+// RouterStage routes the previous output and continues with the handler's
+// result. The classification is stored under "<name>.category" and
+// "<name>.confidence".
+func RouterStage(name string, router *Router[string], confidenceThreshold float64) Stage {
+	return StageFunc(name, func(ctx context.Context, state *PipelineState) error {
+		output, classification, err := router.Route(ctx, state.Output, confidenceThreshold)
+		if classification != nil {
+			state.Values[name+".category"] = classification.Category
+			state.Values[name+".confidence"] = classification.Confidence
+		}
+		if err != nil {
+			return err
+		}
+		state.Output = output
+		return nil
+	})
+}
+
+// OrchestratorStage treats the previous output as the task to decompose
+func OrchestratorStage(name string, orchestrator *Orchestrator) Stage {
+	return StageFunc(name, func(ctx context.Context, state *PipelineState) error {
+		result, err := orchestrator.Execute(ctx, state.Output)
+		if err != nil {
+			return err
+		}
+		state.Values[name+".subtasks"] = len(result.Subtasks)
+		state.Output = result.FinalResult
+		return nil
+	})
+}
+
+// AgentStage gives the previous output to an agent as its task
+func AgentStage(name string, agent *AutonomousAgent, maxSteps int) Stage {
+	return StageFunc(name, func(ctx context.Context, state *PipelineState) error {
+		result, err := agent.Run(ctx, state.Output, maxSteps)
+		if err != nil {
+			return err
+		}
+		if !result.Success {
+			return fmt.Errorf("agent did not complete within %d steps", maxSteps)
+		}
+		state.Output = result.FinalResult
+		return nil
+	})
+}
+
+// OptimizerStage refines the previous output with an EvaluatorOptimizer.
+// The score is stored under "<name>.score".
+func OptimizerStage(name string, optimizer *EvaluatorOptimizer, maxIterations int, scoreThreshold float64) Stage {
+	return StageFunc(name, func(ctx context.Context, state *PipelineState) error {
+		task := fmt.Sprintf("Improve the following text while preserving its meaning:\n\n%s", state.Output)
+		result, err := optimizer.Optimize(ctx, task, maxIterations, scoreThreshold)
+		if err != nil {
+			return err
+		}
+		state.Values[name+".score"] = result.FinalScore
+		state.Output = result.FinalOutput
+		return nil
+	})
+}
+
+// RAGStage answers the previous output as a question from the RAG corpus.
+// The cited chunk IDs are stored under "<name>.citations".
+func RAGStage(name string, rag *RAG) Stage {
+	return StageFunc(name, func(ctx context.Context, state *PipelineState) error {
+		result, err := rag.RetrieveAndAnswer(ctx, state.Output)
+		if err != nil {
+			return err
+		}
+		cited := make([]string, len(result.Citations))
+		for i, c := range result.Citations {
+			cited[i] = c.ChunkID
+		}
+		state.Values[name+".citations"] = cited
+		state.Output = result.Answer
+		return nil
+	})
+}
+
+// ApprovalStage pauses the pipeline until approver accepts the previous
+// output. A rejection fails the pipeline with an error wrapping
+// approvals.ErrRejected.
+func ApprovalStage(name string, approver approvals.Approver) Stage {
+	cfg := newPatternConfig("pipeline", nil)
+	return StageFunc(name, func(ctx context.Context, state *PipelineState) error {
+		return cfg.requestApproval(ctx, approver, "pipeline_stage:"+name,
+			fmt.Sprintf("Review the pipeline output before stage '%s'", name),
+			map[string]interface{}{"output": state.Output})
+	})
+}
@@ -0,0 +1,503 @@
+/*
+ * Plan-and-Execute Pattern Implementation for Go
+ * A typed, editable plan carried out step by step by workers and tools, revised when steps fail
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/schema"
+)
+
+// PlanStepStatus is the state of a plan step
+type PlanStepStatus string
+
+// Plan step states
+const (
+	PlanStepPending PlanStepStatus = "pending"
+	PlanStepDone    PlanStepStatus = "done"
+	PlanStepFailed  PlanStepStatus = "failed"
+)
+
+// PlanStep is one step of a Plan, carried out by a worker or a tool
+type PlanStep struct {
+	ID          string `json:"id" description:"Unique ID such as step_1"`
+	Description string `json:"description" description:"What the step must achieve"`
+	// Action names the worker type or tool that carries out the step
+	Action string `json:"action" description:"Worker type or tool that carries out the step"`
+	// Args are a tool step's arguments. String arguments are templates
+	// over the outputs of the step's dependencies, e.g. "{{.step_1}}".
+	Args      map[string]interface{} `json:"args,omitempty" description:"Arguments for a tool step"`
+	DependsOn []string               `json:"depends_on" description:"IDs of steps whose output this step needs"`
+
+	// Status, Output, and Error are filled in as the plan runs
+	Status PlanStepStatus `json:"status,omitempty"`
+	Output string         `json:"output,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// Plan is a goal and the steps to achieve it. Edit it freely between
+// Planner.Plan and Planner.Execute: the steps are plain data.
+type Plan struct {
+	Goal  string     `json:"goal"`
+	Steps []PlanStep `json:"steps"`
+}
+
+// Step returns the step with an ID, or nil
+func (p *Plan) Step(id string) *PlanStep {
+	for i := range p.Steps {
+		if p.Steps[i].ID == id {
+			return &p.Steps[i]
+		}
+	}
+	return nil
+}
+
+// AddStep appends a step (builder pattern)
+func (p *Plan) AddStep(step PlanStep) *Plan {
+	p.Steps = append(p.Steps, step)
+	return p
+}
+
+// RemoveStep removes a step, and the dependencies of other steps on it.
+// It reports whether the step existed.
+func (p *Plan) RemoveStep(id string) bool {
+	removed := false
+	steps := p.Steps[:0]
+	for _, st := range p.Steps {
+		if st.ID == id {
+			removed = true
+			continue
+		}
+		deps := st.DependsOn[:0:0]
+		for _, dep := range st.DependsOn {
+			if dep != id {
+				deps = append(deps, dep)
+			}
+		}
+		st.DependsOn = deps
+		steps = append(steps, st)
+	}
+	p.Steps = steps
+	return removed
+}
+
+// Validate checks that step IDs are unique and that every dependency names
+// a step, without cycles
+func (p *Plan) Validate() error {
+	ids := make(map[string]bool, len(p.Steps))
+	for _, st := range p.Steps {
+		if st.ID == "" {
+			return fmt.Errorf("plan has a step without an ID")
+		}
+		if ids[st.ID] {
+			return fmt.Errorf("plan has more than one step %s", st.ID)
+		}
+		ids[st.ID] = true
+	}
+	wf := newWorkflow(nil, patternConfig{})
+	for _, st := range p.Steps {
+		wf.AddNode(Node{ID: st.ID, Run: func(context.Context, map[string]interface{}) (interface{}, error) { return nil, nil }})
+	}
+	for _, st := range p.Steps {
+		for _, dep := range st.DependsOn {
+			if !ids[dep] {
+				return fmt.Errorf("step %s depends on unknown step %s", st.ID, dep)
+			}
+			wf.Connect(dep, st.ID)
+		}
+	}
+	return wf.validate()
+}
+
+// PlanProgress reports a finished step
+type PlanProgress struct {
+	Step PlanStep
+	// Done and Total count the plan's finished steps and all its steps
+	Done  int
+	Total int
+}
+
+// PlanResult is the outcome of executing a plan
+type PlanResult struct {
+	// Output is the output of the plan's last step
+	Output string
+	Plan   *Plan
+	// Revisions is the number of times the plan was revised after a
+	// failed step
+	Revisions int
+}
+
+// planCheckpoint is the state persisted after each step when a store is
+// configured
+type planCheckpoint struct {
+	Plan      *Plan `json:"plan"`
+	Revisions int   `json:"revisions"`
+}
+
+// Planner makes a typed plan for a goal, which the caller can inspect and
+// edit, then carries it out with workers and tools. Steps start as soon as
+// the steps they depend on are done. When a step fails, the planner
+// revises the steps not yet done, up to a limit.
+//
+// Example:
+//
+//	planner := NewPlanner(client).
+//	    RegisterWorker(NewLLMWorker(client, "writer", "You write clear technical prose")).
+//	    RegisterTool(searchTool).
+//	    OnProgress(func(p PlanProgress) { log.Printf("%d/%d %s", p.Done, p.Total, p.Step.ID) })
+//	plan, err := planner.Plan(ctx, "Write a market overview of solid-state batteries")
+//	plan.RemoveStep("step_4") // edit before running
+//	result, err := planner.Execute(ctx, plan)
+type Planner struct {
+	client       *AnthropicClient
+	cfg          patternConfig
+	workers      map[string]Worker
+	tools        map[string]*AgentTool
+	onProgress   func(PlanProgress)
+	maxRevisions int
+}
+
+// NewPlanner creates a Planner that revises a plan up to twice per run
+func NewPlanner(client *AnthropicClient, opts ...Option) *Planner {
+	return &Planner{
+		client:       client,
+		cfg:          newPatternConfig("planner", opts),
+		workers:      make(map[string]Worker),
+		tools:        make(map[string]*AgentTool),
+		maxRevisions: 2,
+	}
+}
+
+// RegisterWorker makes a worker available to plan steps (builder pattern)
+func (p *Planner) RegisterWorker(worker Worker) *Planner {
+	p.workers[worker.WorkerType()] = worker
+	return p
+}
+
+// RegisterTool makes a tool available to plan steps (builder pattern)
+func (p *Planner) RegisterTool(tool AgentTool) *Planner {
+	p.tools[tool.Name] = &tool
+	return p
+}
+
+// OnProgress calls fn as each step finishes, successfully or not
+func (p *Planner) OnProgress(fn func(PlanProgress)) *Planner {
+	p.onProgress = fn
+	return p
+}
+
+// WithMaxRevisions sets how many times a run may revise its plan after a
+// failed step; zero makes a failed step fail the run
+func (p *Planner) WithMaxRevisions(n int) *Planner {
+	p.maxRevisions = n
+	return p
+}
+
+// Run plans goal and executes the plan
+func (p *Planner) Run(ctx context.Context, goal string) (*PlanResult, error) {
+	ctx, cancel := p.cfg.startRun(ctx)
+	defer cancel()
+
+	plan, err := p.Plan(ctx, goal)
+	if err != nil {
+		return nil, err
+	}
+	return p.Execute(ctx, plan)
+}
+
+// Plan asks the model for a plan to achieve goal using the registered
+// workers and tools
+func (p *Planner) Plan(ctx context.Context, goal string) (*Plan, error) {
+	if len(p.workers) == 0 && len(p.tools) == 0 {
+		return nil, fmt.Errorf("planner has no workers or tools")
+	}
+	ctx, cancel := p.cfg.startRun(ctx)
+	defer cancel()
+
+	prompt, err := p.cfg.prompt("planner.plan", map[string]interface{}{"goal": goal, "actions": p.actions()})
+	if err != nil {
+		return nil, err
+	}
+	planCtx, span := StartSpan(ctx, "planner.plan")
+	steps, err := structured[[]PlanStep](planCtx, &p.cfg, p.client, prompt, p.stepsSchema(), p.cfg.model, p.cfg.tokens(2048))
+	span.SetAttribute("steps", len(steps))
+	span.Finish(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan: %w", err)
+	}
+
+	plan := &Plan{Goal: goal, Steps: steps}
+	if err := p.validate(plan); err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+	p.cfg.publish(ctx, PhaseDecomposed, map[string]interface{}{"subtasks": len(steps)})
+	return plan, nil
+}
+
+// Execute carries out plan, recording each step's status and output in it.
+// Steps already done are not run again. A step that fails once the plan
+// may no longer be revised fails the run.
+func (p *Planner) Execute(ctx context.Context, plan *Plan) (*PlanResult, error) {
+	ctx, cancel := p.cfg.startRun(ctx)
+	defer cancel()
+
+	// Resume from a checkpoint of the same run, if any
+	checkpoint := planCheckpoint{Plan: plan}
+	if _, err := p.cfg.loadCheckpoint(ctx, &checkpoint); err != nil {
+		return nil, err
+	}
+	plan, revisions := checkpoint.Plan, checkpoint.Revisions
+	if err := p.validate(plan); err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+
+	for {
+		var done []NodeResult
+		for _, st := range plan.Steps {
+			if st.Status == PlanStepDone {
+				done = append(done, NodeResult{ID: st.ID, Output: st.Output})
+			}
+		}
+
+		var failed *NodeResult
+		wf := p.workflow(plan).OnNodeFinished(func(ctx context.Context, r NodeResult) error {
+			step := plan.Step(r.ID)
+			if r.Err != nil {
+				step.Status, step.Error = PlanStepFailed, r.Err.Error()
+			} else {
+				step.Status, step.Output, step.Error = PlanStepDone, r.Output.(string), ""
+			}
+			p.progress(plan, *step)
+			if err := p.cfg.saveCheckpoint(ctx, planCheckpoint{Plan: plan, Revisions: revisions}); err != nil {
+				return err
+			}
+			switch {
+			case r.Err == nil:
+				return nil
+			// Revising cannot help a run that is out of budget
+			case revisions >= p.maxRevisions || errors.Is(budgetStop(ctx, r.Err, nil), ErrBudgetExceeded):
+				return r.Err
+			case failed == nil:
+				failed = &r
+			}
+			return errReplan
+		})
+		_, err := wf.run(ctx, nil, done)
+		if !errors.Is(err, errReplan) {
+			if err != nil {
+				return nil, budgetStop(ctx, err, &PlanResult{Plan: plan, Revisions: revisions})
+			}
+			break
+		}
+
+		revisions++
+		reviseCtx, span := StartSpan(ctx, "planner.revise")
+		span.SetAttribute("step_id", failed.ID)
+		err = p.revise(reviseCtx, plan, *plan.Step(failed.ID), failed.Err)
+		span.Finish(err)
+		if err != nil {
+			return nil, budgetStop(ctx, err, &PlanResult{Plan: plan, Revisions: revisions})
+		}
+		if err := p.cfg.saveCheckpoint(ctx, planCheckpoint{Plan: plan, Revisions: revisions}); err != nil {
+			return nil, err
+		}
+	}
+
+	var output string
+	if len(plan.Steps) > 0 {
+		output = plan.Steps[len(plan.Steps)-1].Output
+	}
+	return &PlanResult{Output: output, Plan: plan, Revisions: revisions}, nil
+}
+
+// workflow builds the workflow running the plan's steps
+func (p *Planner) workflow(plan *Plan) *Workflow {
+	wf := newWorkflow(p.client, p.cfg)
+	for _, step := range plan.Steps {
+		step := step
+		wf.AddNode(Node{ID: step.ID, ContinueOnError: true, Run: func(ctx context.Context, inputs map[string]interface{}) (interface{}, error) {
+			depResults := make(map[string]string)
+			for _, dep := range step.DependsOn {
+				if result, ok := inputs[dep].(string); ok {
+					depResults[dep] = result
+				}
+			}
+			return p.runStep(ctx, step, depResults)
+		}})
+	}
+	for _, step := range plan.Steps {
+		for _, dep := range step.DependsOn {
+			wf.Connect(dep, step.ID)
+		}
+	}
+	return wf
+}
+
+// runStep carries out one step with its tool or worker
+func (p *Planner) runStep(ctx context.Context, step PlanStep, depResults map[string]string) (string, error) {
+	ctx, span := StartSpan(ctx, "planner.step")
+	span.SetAttribute("step_id", step.ID)
+	span.SetAttribute("action", step.Action)
+	p.cfg.publish(ctx, PhaseStepStarted, map[string]interface{}{"step": step.ID, "action": step.Action})
+
+	var output string
+	var err error
+	if tool, ok := p.tools[step.Action]; ok {
+		var args map[string]interface{}
+		if args, err = stepArgs(step, depResults); err == nil {
+			output, err = p.cfg.runTool(ctx, tool, args)
+		}
+	} else {
+		subtask := &OrchestratorSubtask{ID: step.ID, Description: step.Description, WorkerType: step.Action, Dependencies: step.DependsOn}
+		output, err = p.workers[step.Action].Execute(ctx, subtask, depResults)
+	}
+	span.Finish(err)
+	if err != nil {
+		p.cfg.publish(ctx, PhaseStepFailed, map[string]interface{}{"step": step.ID, "error": err})
+		return "", err
+	}
+	p.cfg.publish(ctx, PhaseStepFinished, map[string]interface{}{"step": step.ID})
+	return output, nil
+}
+
+// stepArgs renders the string arguments of a tool step over the outputs of
+// its dependencies
+func stepArgs(step PlanStep, depResults map[string]string) (map[string]interface{}, error) {
+	data := make(map[string]interface{}, len(depResults))
+	for k, v := range depResults {
+		data[k] = v
+	}
+	args := make(map[string]interface{}, len(step.Args))
+	for name, value := range step.Args {
+		text, ok := value.(string)
+		if !ok || !strings.Contains(text, "{{") {
+			args[name] = value
+			continue
+		}
+		tmpl, err := ParsePromptTemplate(step.ID+"."+name, text)
+		if err != nil {
+			return nil, err
+		}
+		if args[name], err = tmpl.Render(data); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// revise replaces the steps of plan that are not done with revised steps
+func (p *Planner) revise(ctx context.Context, plan *Plan, failed PlanStep, failure error) error {
+	steps := make([]map[string]string, len(plan.Steps))
+	for i, st := range plan.Steps {
+		status := st.Status
+		if status == "" {
+			status = PlanStepPending
+		}
+		steps[i] = map[string]string{"id": st.ID, "action": st.Action, "status": string(status), "description": st.Description}
+	}
+	prompt, err := p.cfg.prompt("planner.revise", map[string]interface{}{
+		"goal":    plan.Goal,
+		"plan":    steps,
+		"failed":  failed,
+		"error":   failure,
+		"actions": p.actions(),
+	})
+	if err != nil {
+		return err
+	}
+	revised, err := structured[[]PlanStep](ctx, &p.cfg, p.client, prompt, p.stepsSchema(), p.cfg.model, p.cfg.tokens(2048))
+	if err != nil {
+		return fmt.Errorf("failed to revise plan: %w", err)
+	}
+
+	next := &Plan{Goal: plan.Goal}
+	for _, st := range plan.Steps {
+		if st.Status == PlanStepDone {
+			next.Steps = append(next.Steps, st)
+		}
+	}
+	for _, st := range revised {
+		if prev := next.Step(st.ID); prev != nil {
+			return fmt.Errorf("revised plan replaces done step %s", st.ID)
+		}
+		st.Status, st.Output, st.Error = PlanStepPending, "", ""
+		next.Steps = append(next.Steps, st)
+	}
+	if err := p.validate(next); err != nil {
+		return fmt.Errorf("invalid revised plan: %w", err)
+	}
+	p.cfg.publish(ctx, PhaseReplanned, map[string]interface{}{
+		"subtask": failed.ID,
+		"steps":   len(revised),
+	})
+	*plan = *next
+	return nil
+}
+
+// validate checks a plan's structure and that each step's action is a
+// registered worker or tool
+func (p *Planner) validate(plan *Plan) error {
+	if len(plan.Steps) == 0 {
+		return fmt.Errorf("plan has no steps")
+	}
+	for _, st := range plan.Steps {
+		_, isWorker := p.workers[st.Action]
+		_, isTool := p.tools[st.Action]
+		if !isWorker && !isTool {
+			return fmt.Errorf("step %s uses unknown action %q", st.ID, st.Action)
+		}
+	}
+	return plan.Validate()
+}
+
+// progress reports a finished step to the progress callback
+func (p *Planner) progress(plan *Plan, step PlanStep) {
+	if p.onProgress == nil {
+		return
+	}
+	finished := 0
+	for _, st := range plan.Steps {
+		if st.Status == PlanStepDone {
+			finished++
+		}
+	}
+	p.onProgress(PlanProgress{Step: step, Done: finished, Total: len(plan.Steps)})
+}
+
+// actions describes the workers and tools for prompts
+func (p *Planner) actions() []map[string]string {
+	var actions []map[string]string
+	for _, name := range sortedKeys(p.workers) {
+		actions = append(actions, map[string]string{"name": name, "description": "worker; describe the work in the step's description"})
+	}
+	for _, name := range sortedKeys(p.tools) {
+		tool := p.tools[name]
+		var params []string
+		for _, param := range sortedKeys(tool.Parameters) {
+			def := tool.Parameters[param]
+			params = append(params, fmt.Sprintf("%s: %s (%s)", param, def.Type, def.Description))
+		}
+		actions = append(actions, map[string]string{
+			"name":        name,
+			"description": fmt.Sprintf("tool taking args {%s}; %s", strings.Join(params, ", "), tool.Description),
+		})
+	}
+	return actions
+}
+
+// stepsSchema is the schema of the steps the model plans, limited to the
+// registered actions
+func (p *Planner) stepsSchema() *schema.Schema {
+	s := schema.MustFor[[]PlanStep]()
+	for _, runtime := range []string{"status", "output", "error"} {
+		delete(s.Items.Properties, runtime)
+	}
+	s.Items.Properties["action"].Enum = append(sortedKeys(p.workers), sortedKeys(p.tools)...)
+	return s
+}
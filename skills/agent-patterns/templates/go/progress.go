@@ -0,0 +1,178 @@
+/*
+ * Progress Reporting for Go Agent Patterns
+ * Live progress of long orchestrations and chains, and partial results on cancellation
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ProgressStatus is what happened to the step a Progress reports on
+type ProgressStatus string
+
+const (
+	ProgressStarted  ProgressStatus = "started"
+	ProgressFinished ProgressStatus = "finished"
+	ProgressFailed   ProgressStatus = "failed"
+)
+
+// Progress reports a subtask or chain step starting or finishing, for UIs
+// showing a run's progress live
+type Progress struct {
+	// Step is the subtask ID or the chain step's name
+	Step   string
+	Status ProgressStatus
+	// Done and Total count the finished and planned steps. Total changes
+	// when an orchestrator replans.
+	Done  int
+	Total int
+	// Percent is Done as a percentage of Total
+	Percent float64
+	// Tokens is the number of input and output tokens the run has spent
+	// so far, across nested patterns
+	Tokens int
+	// Err is set when Status is ProgressFailed
+	Err error
+}
+
+// CanceledError reports that a run's context was canceled and what the run
+// had produced when it stopped. It matches context.Canceled.
+//
+// Example:
+//
+//	result, err := orch.Execute(ctx, task)
+//	var canceled *CanceledError
+//	if errors.As(err, &canceled) {
+//	    partial := canceled.Partial.(*OrchestratorResult)
+//	    log.Printf("canceled after %d subtasks", len(partial.WorkerResults))
+//	}
+type CanceledError struct {
+	Err error
+	// Partial is the result of the outermost pattern so far, as for
+	// BudgetExceededError
+	Partial interface{}
+}
+
+// Error implements error
+func (e *CanceledError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the context's error
+func (e *CanceledError) Unwrap() error { return e.Err }
+
+// tokenMeter counts the tokens spent by a run and the runs nested in it
+type tokenMeter struct {
+	tokens int64
+}
+
+type tokenMeterKey struct{}
+
+// withTokenMeter attaches a token meter to ctx unless an enclosing run
+// already did
+func withTokenMeter(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(tokenMeterKey{}).(*tokenMeter); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, tokenMeterKey{}, &tokenMeter{})
+}
+
+// meterUsage adds usage to the run's token meter, if any
+func meterUsage(ctx context.Context, usage Usage) {
+	if m, ok := ctx.Value(tokenMeterKey{}).(*tokenMeter); ok {
+		atomic.AddInt64(&m.tokens, int64(usage.InputTokens+usage.OutputTokens))
+	}
+}
+
+// runTokens returns the tokens spent by the run so far
+func runTokens(ctx context.Context) int {
+	if m, ok := ctx.Value(tokenMeterKey{}).(*tokenMeter); ok {
+		return int(atomic.LoadInt64(&m.tokens))
+	}
+	return 0
+}
+
+// progressTracker counts a run's finished steps and reports them to an
+// OnProgress callback. Steps are tracked by key, so a retried step is
+// counted once. Callbacks are serialized.
+type progressTracker struct {
+	fn    func(Progress)
+	mu    sync.Mutex
+	steps map[string]bool
+	done  map[string]bool
+}
+
+// newProgressTracker tracks the steps with the given keys, some of which
+// may be done already, e.g. after a resume
+func newProgressTracker(fn func(Progress), keys, done []string) *progressTracker {
+	t := &progressTracker{fn: fn, done: make(map[string]bool)}
+	t.setSteps(keys)
+	for _, key := range done {
+		t.done[key] = true
+	}
+	return t
+}
+
+// setSteps replaces the planned steps
+func (t *progressTracker) setSteps(keys []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		t.steps[key] = true
+	}
+}
+
+// started reports that a step started
+func (t *progressTracker) started(ctx context.Context, key, step string) {
+	t.report(ctx, key, Progress{Step: step, Status: ProgressStarted})
+}
+
+// finished reports that a step finished, failing if err is set
+func (t *progressTracker) finished(ctx context.Context, key, step string, err error) {
+	p := Progress{Step: step, Status: ProgressFinished}
+	if err != nil {
+		p.Status, p.Err = ProgressFailed, err
+	}
+	t.report(ctx, key, p)
+}
+
+func (t *progressTracker) report(ctx context.Context, key string, p Progress) {
+	if t == nil || t.fn == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if p.Status != ProgressStarted {
+		t.done[key] = true
+	}
+	for key := range t.steps {
+		if t.done[key] {
+			p.Done++
+		}
+	}
+	p.Total = len(t.steps)
+	if p.Total > 0 {
+		p.Percent = 100 * float64(p.Done) / float64(p.Total)
+	}
+	p.Tokens = runTokens(ctx)
+	t.fn(p)
+}
+
+// cancelStop attaches partial to err if the run stopped because its
+// context was canceled. Like budgetStop, the outermost pattern's result is
+// the one kept.
+func cancelStop(err error, partial interface{}) error {
+	var canceledErr *CanceledError
+	if errors.As(err, &canceledErr) {
+		canceledErr.Partial = partial
+		return err
+	}
+	if errors.Is(err, context.Canceled) {
+		return &CanceledError{Err: err, Partial: partial}
+	}
+	return err
+}
@@ -6,97 +6,26 @@
 package agentpatterns
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
 	"strings"
-)
-
-// AnthropicClient represents a client for the Anthropic API
-type AnthropicClient struct {
-	APIKey     string
-	HTTPClient *http.Client
-}
-
-// MessageRequest represents a request to the Anthropic API
-type MessageRequest struct {
-	Model      string          `json:"model"`
-	MaxTokens  int             `json:"max_tokens"`
-	Messages   []MessageItem   `json:"messages"`
-}
-
-// MessageItem represents a message in the conversation
-type MessageItem struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-// MessageResponse represents a response from the Anthropic API
-type MessageResponse struct {
-	Content []ContentBlock `json:"content"`
-}
-
-// ContentBlock represents a content block in the response
-type ContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
-}
-
-// CreateMessage sends a message to the Anthropic API
-func (c *AnthropicClient) CreateMessage(ctx context.Context, prompt, model string) (string, error) {
-	reqBody := MessageRequest{
-		Model:     model,
-		MaxTokens: 4096,
-		Messages: []MessageItem{
-			{Role: "user", Content: prompt},
-		},
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("x-api-key", c.APIKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-	req.Header.Set("content-type", "application/json")
-
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
-	}
-
-	var msgResp MessageResponse
-	if err := json.NewDecoder(resp.Body).Decode(&msgResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	for _, block := range msgResp.Content {
-		if block.Type == "text" {
-			return block.Text, nil
-		}
-	}
-
-	return "", fmt.Errorf("no text content in response")
-}
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/approvals"
+)
 
 // ValidatorFunc validates the output of a step
 type ValidatorFunc func(output string) bool
 
+// ValidationFunc validates the output of a step and explains a rejection
+type ValidationFunc func(output string) error
+
+// RepairTemplateFunc generates the prompt for another attempt at a step from
+// the current context, the rejected output, and the reason it was rejected
+type RepairTemplateFunc func(context map[string]interface{}, output, reason string) string
+
 // ProcessorFunc processes the output of a step
 type ProcessorFunc func(output string) interface{}
 
@@ -107,8 +36,54 @@ type PromptTemplateFunc func(context map[string]interface{}) string
 type ChainStep struct {
 	Name           string
 	PromptTemplate PromptTemplateFunc
-	Validator      ValidatorFunc
-	Processor      ProcessorFunc
+	// Template is used instead of PromptTemplate when set, rendered with
+	// the chain context; a variable missing from the context fails the
+	// step
+	Template  *PromptTemplate
+	Validator ValidatorFunc
+	Processor ProcessorFunc
+	// Validate is used instead of Validator when set; its error is the
+	// reason passed to RepairPromptTemplate
+	Validate ValidationFunc
+	// MaxRetries is the number of further attempts made after the output
+	// fails validation (0 = fail at once)
+	MaxRetries int
+	// RepairPromptTemplate builds the prompt for each further attempt. By
+	// default the original prompt is sent again with the rejected output
+	// and the reason.
+	RepairPromptTemplate RepairTemplateFunc
+	// Approver, if set, must approve the step's output before the chain
+	// continues; a rejection fails the chain
+	Approver approvals.Approver
+
+	// parallel is set for steps added with AddParallelStep
+	parallel *ParallelStep
+}
+
+// ParallelBranch is one prompt of a ParallelStep. Its output is validated
+// and processed like a ChainStep's and stored in the context under Name.
+type ParallelBranch struct {
+	Name           string
+	PromptTemplate PromptTemplateFunc
+	// Template is used instead of PromptTemplate when set
+	Template  *PromptTemplate
+	Validator ValidatorFunc
+	Processor ProcessorFunc
+}
+
+// ParallelStep runs several prompts concurrently against the same context
+// and merges their outputs back into it, so chains can fan out and in
+// between sequential steps. The merged output is stored under Name.
+type ParallelStep struct {
+	Name     string
+	Branches []ParallelBranch
+	// MaxConcurrency caps the branches in flight (0 = all at once)
+	MaxConcurrency int
+	// Merge combines the branch outputs into the step's output. By default
+	// they are joined in order under "## <branch name>" headings.
+	Merge func(outputs map[string]string) string
+	// Approver, if set, must approve the merged output
+	Approver approvals.Approver
 }
 
 // ChainHistory represents the execution history of a step
@@ -119,11 +94,20 @@ type ChainHistory struct {
 	Context map[string]interface{}
 }
 
+// chainCheckpoint is the state persisted after each step when a store is
+// configured
+type chainCheckpoint struct {
+	NextStep int                    `json:"next_step"`
+	Context  map[string]interface{} `json:"context"`
+	Output   string                 `json:"output"`
+	History  []ChainHistory         `json:"history"`
+}
+
 // PromptChain executes a sequence of LLM calls with validation and processing between steps.
 //
 // Example:
 //
-//	chain := NewPromptChain(client, "claude-3-5-sonnet-20241022")
+//	chain := NewPromptChain(client, WithModel("claude-3-5-sonnet-20241022"))
 //	chain.AddStep(ChainStep{
 //	    Name: "outline",
 //	    PromptTemplate: func(ctx map[string]interface{}) string {
@@ -136,16 +120,18 @@ type ChainHistory struct {
 //	result, err := chain.Execute(ctx, map[string]interface{}{"topic": "AI Safety"})
 type PromptChain struct {
 	client  *AnthropicClient
-	model   string
+	cfg     patternConfig
 	steps   []ChainStep
 	history []ChainHistory
+
+	onProgress func(Progress)
 }
 
 // NewPromptChain creates a new prompt chain
-func NewPromptChain(client *AnthropicClient, model string) *PromptChain {
+func NewPromptChain(client *AnthropicClient, opts ...Option) *PromptChain {
 	return &PromptChain{
 		client:  client,
-		model:   model,
+		cfg:     newPatternConfig("prompt_chain", opts),
 		steps:   make([]ChainStep, 0),
 		history: make([]ChainHistory, 0),
 	}
@@ -157,58 +143,308 @@ func (pc *PromptChain) AddStep(step ChainStep) *PromptChain {
 	return pc
 }
 
-// Execute runs the chain with the initial context
-func (pc *PromptChain) Execute(ctx context.Context, initialContext map[string]interface{}) (string, error) {
-	// Copy initial context
+// AddParallelStep adds a fan-out/fan-in step to the chain (builder pattern).
+// A branch that fails or does not validate fails the step.
+//
+// Example:
+//
+//	chain.AddParallelStep(ParallelStep{
+//	    Name: "research",
+//	    Branches: []ParallelBranch{
+//	        {Name: "pros", PromptTemplate: func(ctx map[string]interface{}) string {
+//	            return fmt.Sprintf("List the advantages of %v", ctx["topic"])
+//	        }},
+//	        {Name: "cons", PromptTemplate: func(ctx map[string]interface{}) string {
+//	            return fmt.Sprintf("List the drawbacks of %v", ctx["topic"])
+//	        }},
+//	    },
+//	})
+//	// Later steps read ctx["pros"], ctx["cons"], or the merged ctx["research"]
+func (pc *PromptChain) AddParallelStep(step ParallelStep) *PromptChain {
+	pc.steps = append(pc.steps, ChainStep{Name: step.Name, Approver: step.Approver, parallel: &step})
+	return pc
+}
+
+// OnProgress calls fn as each step starts and finishes, with the share of
+// steps finished and the tokens spent so far
+func (pc *PromptChain) OnProgress(fn func(Progress)) *PromptChain {
+	pc.onProgress = fn
+	return pc
+}
+
+// Execute runs the chain with the initial context. A run that exceeds its
+// budget returns a *BudgetExceededError whose Partial is the history of the
+// steps completed, and a canceled run a *CanceledError with the same.
+func (pc *PromptChain) Execute(ctx context.Context, initialContext map[string]interface{}) (result string, err error) {
+	ctx, cancel := pc.cfg.startRun(ctx)
+	defer cancel()
+	defer func() {
+		if err != nil {
+			err = budgetStop(ctx, err, append([]ChainHistory(nil), pc.history...))
+		}
+	}()
+
+	// Copy initial context, quoting string inputs as untrusted
 	context := make(map[string]interface{})
 	for k, v := range initialContext {
+		if s, ok := v.(string); ok {
+			quoted, err := pc.cfg.quote(ctx, "input:"+k, s)
+			if err != nil {
+				return "", err
+			}
+			v = quoted
+		}
 		context[k] = v
 	}
 
 	var currentOutput string
+	startStep := 0
+
+	// Resume from a checkpoint of the same run, if any
+	var checkpoint chainCheckpoint
+	if found, err := pc.cfg.loadCheckpoint(ctx, &checkpoint); err != nil {
+		return "", err
+	} else if found {
+		context = checkpoint.Context
+		currentOutput = checkpoint.Output
+		pc.history = checkpoint.History
+		startStep = checkpoint.NextStep
+	}
 
-	for _, step := range pc.steps {
-		// Format prompt with current context
-		prompt := step.PromptTemplate(context)
+	keys, done := make([]string, len(pc.steps)), make([]string, startStep)
+	for i := range pc.steps {
+		keys[i] = strconv.Itoa(i)
+	}
+	copy(done, keys)
+	progress := newProgressTracker(pc.onProgress, keys, done)
 
-		// Call LLM
-		output, err := pc.client.CreateMessage(ctx, prompt, pc.model)
-		if err != nil {
-			return "", fmt.Errorf("step '%s' failed: %w", step.Name, err)
+	wf := pc.workflow(startStep, context, &currentOutput, progress)
+	if _, err := wf.run(ctx, nil, nil); err != nil {
+		return "", err
+	}
+
+	return currentOutput, nil
+}
+
+// workflow builds the workflow running the steps from start on as a line
+// of nodes, checkpointing after each. Each step updates chainContext and
+// output.
+func (pc *PromptChain) workflow(start int, chainContext map[string]interface{}, output *string, progress *progressTracker) *Workflow {
+	wf := newWorkflow(pc.client, pc.cfg)
+	for i := start; i < len(pc.steps); i++ {
+		step, id := pc.steps[i], strconv.Itoa(i)
+		wf.AddNode(Node{ID: id, Run: func(ctx context.Context, _ map[string]interface{}) (interface{}, error) {
+			progress.started(ctx, id, step.Name)
+			stepOutput, err := pc.runStep(ctx, step, chainContext)
+			progress.finished(ctx, id, step.Name, err)
+			if err != nil {
+				return nil, err
+			}
+			*output = stepOutput
+			return stepOutput, nil
+		}})
+		if i > start {
+			wf.Connect(strconv.Itoa(i-1), id)
+		}
+	}
+	return wf.OnNodeFinished(func(ctx context.Context, r NodeResult) error {
+		if r.Err != nil {
+			return nil
 		}
-		currentOutput = output
+		i, _ := strconv.Atoi(r.ID)
+		return pc.cfg.saveCheckpoint(ctx, chainCheckpoint{
+			NextStep: i + 1,
+			Context:  chainContext,
+			Output:   *output,
+			History:  pc.history,
+		})
+	})
+}
+
+// runStep runs one step against the chain context, validating, repairing,
+// and approving its output, then stores the output in the context and
+// records it in the history
+func (pc *PromptChain) runStep(ctx context.Context, step ChainStep, context map[string]interface{}) (string, error) {
+	stepCtx, span := StartSpan(ctx, "chain.step")
+	span.SetAttribute("step", step.Name)
+	pc.cfg.publish(stepCtx, PhaseStepStarted, map[string]interface{}{"step": step.Name})
+
+	var prompt, output string
+	var branches []ChainHistory
+	var err error
+	if step.parallel != nil {
+		output, branches, err = pc.executeParallel(stepCtx, step.parallel, context)
+	} else {
+		// Format prompt with current context and call LLM
+		prompt, err = renderStep(step.Template, step.PromptTemplate, context)
+		if err == nil {
+			output, err = pc.cfg.call(stepCtx, pc.client, prompt, pc.cfg.model, pc.cfg.tokens(4096))
+		}
+	}
+	if err != nil {
+		span.Finish(err)
+		pc.cfg.publish(stepCtx, PhaseStepFailed, map[string]interface{}{"step": step.Name, "error": err})
+		return "", fmt.Errorf("step '%s' failed: %w", step.Name, err)
+	}
 
-		// Validate if validator provided
-		if step.Validator != nil && !step.Validator(currentOutput) {
-			preview := currentOutput
+	// Validate, feeding rejected output back to the model while retries remain
+	for attempt := 1; ; attempt++ {
+		reason := step.validate(output)
+		if reason == nil {
+			break
+		}
+		if attempt > step.MaxRetries {
+			preview := output
 			if len(preview) > 100 {
 				preview = preview[:100]
 			}
-			return "", fmt.Errorf("step '%s' validation failed. Output: %s", step.Name, preview)
+			err := fmt.Errorf("step '%s' validation failed: %v. Output: %s", step.Name, reason, preview)
+			span.Finish(err)
+			pc.cfg.publish(stepCtx, PhaseStepFailed, map[string]interface{}{"step": step.Name, "error": err})
+			return "", err
 		}
 
-		// Process if processor provided
-		if step.Processor != nil {
-			processed := step.Processor(currentOutput)
-			context[step.Name] = processed
+		pc.cfg.logger.Warn("retrying chain step", "step", step.Name, "attempt", attempt, "reason", reason)
+		pc.cfg.publish(stepCtx, PhaseStepRetried, map[string]interface{}{"step": step.Name, "attempt": attempt, "reason": reason.Error()})
+		if step.RepairPromptTemplate != nil {
+			prompt = step.RepairPromptTemplate(context, output, reason.Error())
 		} else {
-			context[step.Name] = currentOutput
+			prompt, err = pc.repairPrompt(step, context, output, reason.Error())
+		}
+		if err == nil {
+			output, err = pc.cfg.call(stepCtx, pc.client, prompt, pc.cfg.model, pc.cfg.tokens(4096))
+		}
+		if err != nil {
+			span.Finish(err)
+			pc.cfg.publish(stepCtx, PhaseStepFailed, map[string]interface{}{"step": step.Name, "error": err})
+			return "", fmt.Errorf("step '%s' failed: %w", step.Name, err)
+		}
+	}
+	if step.Approver != nil {
+		err := pc.cfg.requestApproval(stepCtx, step.Approver, "chain_step:"+step.Name,
+			fmt.Sprintf("Review the output of step '%s'", step.Name),
+			map[string]interface{}{"output": output})
+		if err != nil {
+			span.Finish(err)
+			pc.cfg.publish(stepCtx, PhaseStepFailed, map[string]interface{}{"step": step.Name, "error": err})
+			return "", fmt.Errorf("step '%s' not approved: %w", step.Name, err)
 		}
+	}
+	span.Finish(nil)
+	pc.cfg.publish(stepCtx, PhaseStepFinished, map[string]interface{}{"step": step.Name, "output_chars": len(output)})
+
+	// Process if processor provided
+	if step.Processor != nil {
+		processed := step.Processor(output)
+		context[step.Name] = processed
+	} else {
+		context[step.Name] = output
+	}
+
+	// Track history
+	contextCopy := make(map[string]interface{})
+	for k, v := range context {
+		contextCopy[k] = v
+	}
+	pc.history = append(pc.history, branches...)
+	pc.history = append(pc.history, ChainHistory{
+		Step:    step.Name,
+		Prompt:  prompt,
+		Output:  output,
+		Context: contextCopy,
+	})
+
+	return output, nil
+}
 
-		// Track history
-		contextCopy := make(map[string]interface{})
-		for k, v := range context {
-			contextCopy[k] = v
+// validate runs the step's validation and returns why the output was
+// rejected, or nil
+func (s ChainStep) validate(output string) error {
+	if s.Validate != nil {
+		return s.Validate(output)
+	}
+	if s.Validator != nil && !s.Validator(output) {
+		return errors.New("the output did not pass validation")
+	}
+	return nil
+}
+
+// renderStep renders a step's prompt from its template, or else its
+// template function
+func renderStep(tmpl *PromptTemplate, fn PromptTemplateFunc, context map[string]interface{}) (string, error) {
+	if tmpl != nil {
+		return tmpl.Render(context)
+	}
+	return fn(context), nil
+}
+
+// repairPrompt is the default prompt for another attempt at a step: the
+// original prompt followed by the rejected output and the reason
+func (pc *PromptChain) repairPrompt(step ChainStep, context map[string]interface{}, output, reason string) (string, error) {
+	prompt, err := renderStep(step.Template, step.PromptTemplate, context)
+	if err != nil {
+		return "", err
+	}
+	return pc.cfg.prompt("chain.repair", map[string]interface{}{"prompt": prompt, "output": output, "reason": reason})
+}
+
+// executeParallel runs the branches of ps through a SectioningParallelizer
+// and stores their outputs in context. It returns the merged output and a
+// history entry for each branch.
+func (pc *PromptChain) executeParallel(ctx context.Context, ps *ParallelStep, context map[string]interface{}) (string, []ChainHistory, error) {
+	subtasks := make([]Subtask, len(ps.Branches))
+	for i, branch := range ps.Branches {
+		prompt, err := renderStep(branch.Template, branch.PromptTemplate, context)
+		if err != nil {
+			return "", nil, fmt.Errorf("branch '%s' failed: %w", branch.Name, err)
+		}
+		subtasks[i] = Subtask{Name: branch.Name, Prompt: prompt}
+	}
+
+	opts := append(pc.cfg.childOptions(), WithMaxTokens(pc.cfg.tokens(4096)), WithMaxConcurrency(ps.MaxConcurrency))
+	results := NewSectioningParallelizer(pc.client, opts...).ExecuteParallel(ctx, subtasks)
+
+	outputs := make(map[string]string, len(results))
+	for i, result := range results {
+		branch := ps.Branches[i]
+		if !result.Success {
+			return "", nil, fmt.Errorf("branch '%s' failed: %s", branch.Name, result.Error)
 		}
-		pc.history = append(pc.history, ChainHistory{
-			Step:    step.Name,
-			Prompt:  prompt,
-			Output:  currentOutput,
+		if branch.Validator != nil && !branch.Validator(result.Result) {
+			return "", nil, fmt.Errorf("branch '%s' validation failed", branch.Name)
+		}
+		outputs[branch.Name] = result.Result
+	}
+
+	for _, branch := range ps.Branches {
+		if branch.Processor != nil {
+			context[branch.Name] = branch.Processor(outputs[branch.Name])
+		} else {
+			context[branch.Name] = outputs[branch.Name]
+		}
+	}
+	contextCopy := make(map[string]interface{})
+	for k, v := range context {
+		contextCopy[k] = v
+	}
+	history := make([]ChainHistory, len(ps.Branches))
+	for i, branch := range ps.Branches {
+		history[i] = ChainHistory{
+			Step:    ps.Name + "/" + branch.Name,
+			Prompt:  subtasks[i].Prompt,
+			Output:  outputs[branch.Name],
 			Context: contextCopy,
-		})
+		}
 	}
 
-	return currentOutput, nil
+	if ps.Merge != nil {
+		return ps.Merge(outputs), history, nil
+	}
+	sections := make([]string, len(ps.Branches))
+	for i, branch := range ps.Branches {
+		sections[i] = fmt.Sprintf("## %s\n\n%s", branch.Name, outputs[branch.Name])
+	}
+	return strings.Join(sections, "\n\n"), history, nil
 }
 
 // History returns the execution history
@@ -228,7 +464,7 @@ func ExampleDocumentGeneration() error {
 		HTTPClient: &http.Client{},
 	}
 
-	chain := NewPromptChain(client, "claude-3-5-sonnet-20241022")
+	chain := NewPromptChain(client, WithModel("claude-3-5-sonnet-20241022"))
 
 	// Step 1: Generate outline
 	chain.AddStep(ChainStep{
@@ -284,12 +520,3 @@ Fix any grammar, improve clarity, and ensure consistent tone.`, ctx["draft"])
 
 	return nil
 }
-
-// Helper function to get environment variable with default
-func getEnv(key, defaultValue string) string {
-	value := /* os.Getenv(key) */ ""
-	if value == "" {
-		return defaultValue
-	}
-	return value
-}
@@ -0,0 +1,336 @@
+/*
+ * Prompt Templates for Go Agent Patterns
+ * text/template prompts with variable checks and versions, and the registry patterns draw them from
+ */
+
+package agentpatterns
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+// PromptTemplate is a named, versioned text/template prompt. Templates
+// read their variables from a map, e.g. {{.input}}, and can use the join
+// function: {{join .worker_types ", "}}.
+type PromptTemplate struct {
+	Name    string
+	Version string
+	tmpl    *template.Template
+	vars    []string
+}
+
+// promptFuncs are the functions available to prompt templates
+var promptFuncs = template.FuncMap{"join": strings.Join}
+
+// ParsePromptTemplate parses a prompt template. A name of the form
+// "name@version" sets the version.
+//
+// Example:
+//
+//	tmpl, err := ParsePromptTemplate("summarize@v2", "Summarize in {{.words}} words:\n\n{{.input}}")
+//	prompt, err := tmpl.Render(map[string]interface{}{"words": 50, "input": text})
+func ParsePromptTemplate(name, text string) (*PromptTemplate, error) {
+	name, version, _ := strings.Cut(name, "@")
+	tmpl, err := template.New(name).Funcs(promptFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s: %w", name, err)
+	}
+	seen := make(map[string]bool)
+	var vars []string
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			collectVariables(t.Tree.Root, true, seen, &vars)
+		}
+	}
+	sort.Strings(vars)
+	return &PromptTemplate{Name: name, Version: version, tmpl: tmpl, vars: vars}, nil
+}
+
+// MustParsePromptTemplate is ParsePromptTemplate for templates known to be
+// valid; it panics on a parse error
+func MustParsePromptTemplate(name, text string) *PromptTemplate {
+	t, err := ParsePromptTemplate(name, text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// collectVariables adds the top-level fields a template reads, such as
+// "input" for {{.input}} or {{$.input}}. root reports whether the dot is
+// still the template's data, which it is not inside range and with blocks.
+func collectVariables(node parse.Node, root bool, seen map[string]bool, vars *[]string) {
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			*vars = append(*vars, name)
+		}
+	}
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectVariables(child, root, seen, vars)
+		}
+	case *parse.ActionNode:
+		collectVariables(n.Pipe, root, seen, vars)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				collectVariables(arg, root, seen, vars)
+			}
+		}
+	case *parse.ChainNode:
+		collectVariables(n.Node, root, seen, vars)
+	case *parse.FieldNode:
+		if root {
+			add(n.Ident[0])
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			add(n.Ident[1])
+		}
+	case *parse.IfNode:
+		collectVariables(n.Pipe, root, seen, vars)
+		collectVariables(n.List, root, seen, vars)
+		collectVariables(n.ElseList, root, seen, vars)
+	case *parse.RangeNode:
+		collectVariables(n.Pipe, root, seen, vars)
+		collectVariables(n.List, false, seen, vars)
+		collectVariables(n.ElseList, root, seen, vars)
+	case *parse.WithNode:
+		collectVariables(n.Pipe, root, seen, vars)
+		collectVariables(n.List, false, seen, vars)
+		collectVariables(n.ElseList, root, seen, vars)
+	case *parse.TemplateNode:
+		collectVariables(n.Pipe, root, seen, vars)
+	}
+}
+
+// Variables returns the top-level variables the template reads, sorted
+func (t *PromptTemplate) Variables() []string {
+	return append([]string(nil), t.vars...)
+}
+
+// Render executes the template with data. It fails, naming them, if data
+// lacks any of the template's variables.
+func (t *PromptTemplate) Render(data map[string]interface{}) (string, error) {
+	var missing []string
+	for _, v := range t.vars {
+		if _, ok := data[v]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("prompt %s is missing variables: %s", t.ref(), strings.Join(missing, ", "))
+	}
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", t.ref(), err)
+	}
+	return buf.String(), nil
+}
+
+// ref names the template and its version
+func (t *PromptTemplate) ref() string {
+	if t.Version == "" {
+		return t.Name
+	}
+	return t.Name + "@" + t.Version
+}
+
+// PromptRegistry holds prompt templates by name and version. Get returns
+// a name's pinned version, or else its highest. It is safe for concurrent
+// use.
+//
+// Example:
+//
+//	prompts, err := LoadPromptDir("./prompts") // router.classify@v2.tmpl, ...
+//	router := NewRouter[string](client, WithPrompts(prompts))
+type PromptRegistry struct {
+	mu        sync.RWMutex
+	templates map[string]map[string]*PromptTemplate
+	pinned    map[string]string
+}
+
+// NewPromptRegistry creates an empty registry
+func NewPromptRegistry() *PromptRegistry {
+	return &PromptRegistry{
+		templates: make(map[string]map[string]*PromptTemplate),
+		pinned:    make(map[string]string),
+	}
+}
+
+// LoadPrompts loads every <name>.tmpl and <name>@<version>.tmpl file in
+// dir of fsys, such as an embed.FS, into a new registry. A single trailing
+// newline is dropped from each file.
+func LoadPrompts(fsys fs.FS, dir string) (*PromptRegistry, error) {
+	r := NewPromptRegistry()
+	if err := r.Load(fsys, dir); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// LoadPromptDir loads the templates in a directory on disk; see LoadPrompts
+func LoadPromptDir(dir string) (*PromptRegistry, error) {
+	return LoadPrompts(os.DirFS(dir), ".")
+}
+
+// Load adds the templates in dir of fsys to the registry; see LoadPrompts
+func (r *PromptRegistry) Load(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.tmpl"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		text := strings.TrimSuffix(string(data), "\n")
+		t, err := ParsePromptTemplate(strings.TrimSuffix(path.Base(file), ".tmpl"), text)
+		if err != nil {
+			return err
+		}
+		r.Register(t)
+	}
+	return nil
+}
+
+// Register adds a template, replacing any with the same name and version
+// (builder pattern)
+func (r *PromptRegistry) Register(t *PromptTemplate) *PromptRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.templates[t.Name] == nil {
+		r.templates[t.Name] = make(map[string]*PromptTemplate)
+	}
+	r.templates[t.Name][t.Version] = t
+	return r
+}
+
+// Pin makes Get return a version of name other than the highest (builder
+// pattern)
+func (r *PromptRegistry) Pin(name, version string) *PromptRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pinned[name] = version
+	return r
+}
+
+// Get returns the pinned or highest version of a template. A nil registry
+// has no templates.
+func (r *PromptRegistry) Get(name string) (*PromptTemplate, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := r.templates[name]
+	if version, ok := r.pinned[name]; ok {
+		t, ok := versions[version]
+		return t, ok
+	}
+	var best *PromptTemplate
+	for _, t := range versions {
+		if best == nil || compareVersions(t.Version, best.Version) > 0 {
+			best = t
+		}
+	}
+	return best, best != nil
+}
+
+// Version returns a specific version of a template
+func (r *PromptRegistry) Version(name, version string) (*PromptTemplate, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.templates[name][version]
+	return t, ok
+}
+
+// Names returns the names of the registered templates, sorted
+func (r *PromptRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return sortedKeys(r.templates)
+}
+
+// compareVersions orders versions such as "v2" and "1.10" by their
+// numeric parts, comparing other parts as text. No version sorts first.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	return len(as) - len(bs)
+}
+
+//go:embed prompts/*.tmpl
+var promptFiles embed.FS
+
+// builtinPrompts are the prompts the patterns use unless WithPrompts
+// overrides them
+var builtinPrompts = mustLoadPrompts(promptFiles, "prompts")
+
+func mustLoadPrompts(fsys fs.FS, dir string) *PromptRegistry {
+	r, err := LoadPrompts(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// DefaultPrompts returns a registry of the built-in prompts, to copy from
+// or extend. Each is named for the pattern and step using it:
+// "router.classify", "orchestrator.decompose", "optimizer.evaluate",
+// "chain.repair", and so on.
+func DefaultPrompts() *PromptRegistry {
+	return mustLoadPrompts(promptFiles, "prompts")
+}
+
+// WithPrompts overrides the pattern's built-in prompts with templates of
+// the same name from r; prompts r lacks stay built in
+func WithPrompts(r *PromptRegistry) Option {
+	return func(c *patternConfig) { c.prompts = r }
+}
+
+// prompt renders a template from the pattern's registry, or the built-in
+// one
+func (c *patternConfig) prompt(name string, data map[string]interface{}) (string, error) {
+	t, ok := c.prompts.Get(name)
+	if !ok {
+		t, ok = builtinPrompts.Get(name)
+	}
+	if !ok {
+		return "", fmt.Errorf("no prompt template named %s", name)
+	}
+	return t.Render(data)
+}
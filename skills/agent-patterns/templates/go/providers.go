@@ -0,0 +1,508 @@
+/*
+ * LLM Providers for Go Agent Patterns
+ * Running the patterns against OpenAI-compatible endpoints and local Ollama models
+ */
+
+package agentpatterns
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/limiter"
+)
+
+// LLMProvider sends Messages API requests to a model backend. Set one as
+// an AnthropicClient's Provider to run every pattern against it; requests
+// keep the Anthropic shape and each provider translates them.
+//
+// Patterns name Claude models, so providers map model names with their
+// Model and Models fields.
+//
+// Example:
+//
+//	client := &AnthropicClient{Provider: &OllamaProvider{Model: "llama3.1"}}
+//	router := NewRouter[string](client) // classifies with llama3.1
+type LLMProvider interface {
+	// Name identifies the provider in limiter keys, e.g. "openai"
+	Name() string
+	Send(ctx context.Context, req MessageRequest) (string, Usage, error)
+	// SendStream passes each text delta to onDelta as it arrives and
+	// returns the full text once the response is complete
+	SendStream(ctx context.Context, req MessageRequest, onDelta func(text string)) (string, Usage, error)
+}
+
+// Name implements LLMProvider
+func (c *AnthropicClient) Name() string {
+	return limiter.ProviderAnthropic
+}
+
+// SendStream implements LLMProvider
+func (c *AnthropicClient) SendStream(ctx context.Context, req MessageRequest, onDelta func(text string)) (string, Usage, error) {
+	req.Stream = true
+	return c.stream(ctx, req, onDelta)
+}
+
+// sendVia serves a request with the client's Provider, under the client's
+// limits and retry policy
+func (c *AnthropicClient) sendVia(ctx context.Context, req MessageRequest, onDelta func(text string)) (string, Usage, error) {
+	name := c.Provider.Name()
+	var text string
+	var usage Usage
+	err := c.retry(ctx, req.Model, func() error {
+		if c.Limits != nil {
+			if err := c.Limits.Wait(ctx, name, req.Model); err != nil {
+				return err
+			}
+			if err := c.reserveTokens(ctx, name, req); err != nil {
+				c.Limits.Done(name, req.Model, false)
+				return err
+			}
+		}
+		var err error
+		if req.Stream {
+			text, usage, err = c.Provider.SendStream(ctx, req, onDelta)
+		} else {
+			text, usage, err = c.Provider.Send(ctx, req)
+		}
+		if c.Limits != nil {
+			var apiErr *APIError
+			c.Limits.Done(name, req.Model, errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests)
+			c.settleTokens(name, req, usage)
+		}
+		return err
+	})
+	return text, usage, err
+}
+
+// resolveModel returns the backend's name for a model requested by a
+// pattern
+func resolveModel(model, fallback string, models map[string]string) string {
+	if mapped, ok := models[model]; ok {
+		return mapped
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return model
+}
+
+// chatMessage is a message in the OpenAI and Ollama chat formats, which
+// carry the system prompt as the first message. Content is a string, or
+// for OpenAI messages with images a list of content parts; Ollama takes
+// images separately, base64-encoded.
+type chatMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+	Images  []string    `json:"images,omitempty"`
+}
+
+// chatMessages converts a request's system prompt and messages to the
+// OpenAI format. Documents must be inline: text is sent as text and PDFs
+// as file data.
+func chatMessages(req MessageRequest) ([]chatMessage, error) {
+	var messages []chatMessage
+	if req.System != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		if len(m.Images) == 0 && len(m.Documents) == 0 {
+			messages = append(messages, chatMessage{Role: string(m.Role), Content: m.Content})
+			continue
+		}
+		parts := make([]map[string]interface{}, 0, len(m.Documents)+len(m.Images)+1)
+		for _, doc := range m.Documents {
+			if doc.URL != "" || doc.FileID != "" {
+				return nil, fmt.Errorf("openai documents must be sent inline: %s", documentName(doc))
+			}
+			if doc.MediaType == "text/plain" {
+				parts = append(parts, map[string]interface{}{"type": "text", "text": documentText(doc)})
+				continue
+			}
+			parts = append(parts, map[string]interface{}{"type": "file", "file": map[string]string{
+				"filename":  documentName(doc),
+				"file_data": "data:" + doc.MediaType + ";base64," + base64.StdEncoding.EncodeToString(doc.Data),
+			}})
+		}
+		for _, img := range m.Images {
+			url := img.URL
+			if url == "" {
+				url = "data:" + img.MediaType + ";base64," + base64.StdEncoding.EncodeToString(img.Data)
+			}
+			parts = append(parts, map[string]interface{}{"type": "image_url", "image_url": map[string]string{"url": url}})
+		}
+		if m.Content != "" {
+			parts = append(parts, map[string]interface{}{"type": "text", "text": m.Content})
+		}
+		messages = append(messages, chatMessage{Role: string(m.Role), Content: parts})
+	}
+	return messages, nil
+}
+
+// ollamaMessages converts a request's system prompt and messages to the
+// Ollama format, which only takes inline images and plain-text documents
+func ollamaMessages(req MessageRequest) ([]chatMessage, error) {
+	var messages []chatMessage
+	if req.System != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		var texts []string
+		for _, doc := range m.Documents {
+			if doc.MediaType != "text/plain" || doc.URL != "" || doc.FileID != "" {
+				return nil, fmt.Errorf("ollama only reads inline text documents: %s", documentName(doc))
+			}
+			texts = append(texts, documentText(doc))
+		}
+		msg := chatMessage{Role: string(m.Role), Content: strings.Join(append(texts, m.Content), "\n\n")}
+		for _, img := range m.Images {
+			if img.URL != "" {
+				return nil, fmt.Errorf("ollama does not fetch image URLs: %s", img.URL)
+			}
+			msg.Images = append(msg.Images, base64.StdEncoding.EncodeToString(img.Data))
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// documentText is a text document as a titled message part
+func documentText(doc Document) string {
+	if doc.Title == "" {
+		return string(doc.Data)
+	}
+	return fmt.Sprintf("Document: %s\n\n%s", doc.Title, doc.Data)
+}
+
+// postJSON sends body to url and returns the response once its status is
+// 200, or an *APIError. The caller must close the response body.
+func postJSON(ctx context.Context, httpClient *http.Client, url string, headers map[string]string, body interface{}) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("content-type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return doRequest(httpClient, req)
+}
+
+// doRequest sends a prepared request and returns the response once its
+// status is 200, or an *APIError. The caller must close the response body.
+func doRequest(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return resp, nil
+}
+
+// OpenAIProvider sends requests to an OpenAI-compatible Chat Completions
+// endpoint: OpenAI itself, or servers such as vLLM, LM Studio, and
+// llama.cpp that implement the same API.
+//
+// Example:
+//
+//	client := &AnthropicClient{Provider: &OpenAIProvider{
+//	    APIKey: os.Getenv("OPENAI_API_KEY"),
+//	    Models: map[string]string{
+//	        DefaultModel:              "gpt-4o",
+//	        "claude-3-haiku-20240307": "gpt-4o-mini",
+//	    },
+//	}}
+type OpenAIProvider struct {
+	APIKey string
+	// BaseURL defaults to https://api.openai.com/v1
+	BaseURL    string
+	HTTPClient *http.Client
+	// Model, if set, is used for every model not in Models
+	Model string
+	// Models maps the models named by patterns to the endpoint's models.
+	// Unmapped names are sent as-is when Model is empty.
+	Models map[string]string
+}
+
+// openAIRequest is a Chat Completions request
+type openAIRequest struct {
+	Model         string         `json:"model"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Messages      []chatMessage  `json:"messages"`
+	Temperature   *float64       `json:"temperature,omitempty"`
+	TopP          *float64       `json:"top_p,omitempty"`
+	Stop          []string       `json:"stop,omitempty"`
+	User          string         `json:"user,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *openAIOptions `json:"stream_options,omitempty"`
+}
+
+type openAIOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIResponse covers both complete responses and stream chunks
+type openAIResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// usage converts the response's token counts
+func (r *openAIResponse) usage() Usage {
+	if r.Usage == nil {
+		return Usage{}
+	}
+	return Usage{InputTokens: r.Usage.PromptTokens, OutputTokens: r.Usage.CompletionTokens}
+}
+
+// Name implements LLMProvider
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+// Send implements LLMProvider
+func (p *OpenAIProvider) Send(ctx context.Context, req MessageRequest) (string, Usage, error) {
+	resp, err := p.post(ctx, req, false)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
+
+	var chatResp openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", Usage{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(chatResp.Choices) == 0 {
+		return "", chatResp.usage(), fmt.Errorf("no choices in response")
+	}
+	return chatResp.Choices[0].Message.Content, chatResp.usage(), nil
+}
+
+// SendStream implements LLMProvider
+func (p *OpenAIProvider) SendStream(ctx context.Context, req MessageRequest, onDelta func(text string)) (string, Usage, error) {
+	resp, err := p.post(ctx, req, true)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
+
+	var text strings.Builder
+	var usage Usage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(line[len("data:"):])
+		if data == "[DONE]" {
+			return text.String(), usage, nil
+		}
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return text.String(), usage, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+		if chunk.Error != nil {
+			return text.String(), usage, &APIError{StatusCode: 500, Body: chunk.Error.Message}
+		}
+		if chunk.Usage != nil {
+			usage = chunk.usage()
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				text.WriteString(choice.Delta.Content)
+				if onDelta != nil {
+					onDelta(choice.Delta.Content)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return text.String(), usage, fmt.Errorf("failed to read stream: %w", err)
+	}
+	return text.String(), usage, fmt.Errorf("stream ended before [DONE]")
+}
+
+func (p *OpenAIProvider) post(ctx context.Context, req MessageRequest, stream bool) (*http.Response, error) {
+	messages, err := chatMessages(req)
+	if err != nil {
+		return nil, err
+	}
+	body := openAIRequest{
+		Model:       resolveModel(req.Model, p.Model, p.Models),
+		MaxTokens:   req.MaxTokens,
+		Messages:    messages,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stop:        req.StopSequences,
+		Stream:      stream,
+	}
+	if req.Metadata != nil {
+		body.User = req.Metadata.UserID
+	}
+	if stream {
+		body.StreamOptions = &openAIOptions{IncludeUsage: true}
+	}
+
+	base := p.BaseURL
+	if base == "" {
+		base = "https://api.openai.com/v1"
+	}
+	headers := map[string]string{}
+	if p.APIKey != "" {
+		headers["authorization"] = "Bearer " + p.APIKey
+	}
+	return postJSON(ctx, p.HTTPClient, strings.TrimRight(base, "/")+"/chat/completions", headers, body)
+}
+
+// OllamaProvider sends requests to a local Ollama server's chat API, for
+// air-gapped deployments and development without API costs.
+//
+// Example:
+//
+//	client := &AnthropicClient{Provider: &OllamaProvider{Model: "llama3.1"}}
+type OllamaProvider struct {
+	// BaseURL defaults to http://localhost:11434
+	BaseURL    string
+	HTTPClient *http.Client
+	// Model, if set, is used for every model not in Models
+	Model string
+	// Models maps the models named by patterns to local models. Unmapped
+	// names are sent as-is when Model is empty.
+	Models map[string]string
+}
+
+// ollamaRequest is a request to /api/chat
+type ollamaRequest struct {
+	Model    string                 `json:"model"`
+	Messages []chatMessage          `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// ollamaResponse is a complete response or one line of a streamed one
+type ollamaResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+// Name implements LLMProvider
+func (p *OllamaProvider) Name() string {
+	return "ollama"
+}
+
+// Send implements LLMProvider
+func (p *OllamaProvider) Send(ctx context.Context, req MessageRequest) (string, Usage, error) {
+	return p.SendStream(ctx, req, nil)
+}
+
+// SendStream implements LLMProvider. Ollama streams newline-delimited JSON
+// objects; Send requests the same format and reads it in one go.
+func (p *OllamaProvider) SendStream(ctx context.Context, req MessageRequest, onDelta func(text string)) (string, Usage, error) {
+	messages, err := ollamaMessages(req)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	body := ollamaRequest{
+		Model:    resolveModel(req.Model, p.Model, p.Models),
+		Messages: messages,
+		Stream:   true,
+		Options:  map[string]interface{}{},
+	}
+	if req.MaxTokens > 0 {
+		body.Options["num_predict"] = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		body.Options["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		body.Options["top_p"] = *req.TopP
+	}
+	if req.TopK != nil {
+		body.Options["top_k"] = *req.TopK
+	}
+	if len(req.StopSequences) > 0 {
+		body.Options["stop"] = req.StopSequences
+	}
+
+	base := p.BaseURL
+	if base == "" {
+		base = "http://localhost:11434"
+	}
+	resp, err := postJSON(ctx, p.HTTPClient, strings.TrimRight(base, "/")+"/api/chat", nil, body)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
+
+	var text strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var chunk ollamaResponse
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			return text.String(), Usage{}, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+		if chunk.Error != "" {
+			return text.String(), Usage{}, &APIError{StatusCode: 500, Body: chunk.Error}
+		}
+		if chunk.Message.Content != "" {
+			text.WriteString(chunk.Message.Content)
+			if onDelta != nil {
+				onDelta(chunk.Message.Content)
+			}
+		}
+		if chunk.Done {
+			return text.String(), Usage{InputTokens: chunk.PromptEvalCount, OutputTokens: chunk.EvalCount}, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return text.String(), Usage{}, fmt.Errorf("failed to read stream: %w", err)
+	}
+	return text.String(), Usage{}, fmt.Errorf("stream ended before done")
+}
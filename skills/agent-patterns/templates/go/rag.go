@@ -0,0 +1,443 @@
+/*
+ * Retrieval-Augmented Generation Pattern Implementation for Go
+ * Chunking, embedding, vector retrieval, and cited answer generation
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/chunker"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/vectorstore"
+)
+
+// Metadata keys set on every indexed chunk
+const (
+	MetadataDocument = "document"
+	MetadataChunk    = "chunk"
+)
+
+// Citation links a numbered reference in an answer to the chunk it cites
+type Citation struct {
+	Index      int     `json:"index"`
+	ChunkID    string  `json:"chunk_id"`
+	DocumentID string  `json:"document_id"`
+	Text       string  `json:"text"`
+	Score      float64 `json:"score"`
+}
+
+// RAGResult is a generated answer with the sources it was given and the
+// ones it cited
+type RAGResult struct {
+	Answer    string
+	Sources   []vectorstore.Match
+	Citations []Citation
+}
+
+// RAG answers questions from an indexed corpus.
+//
+// Example:
+//
+//	embedder := NewVoyageEmbedder(os.Getenv("VOYAGE_API_KEY"))
+//	rag := NewRAG(client, embedder, vectorstore.NewMemory()).SetTopK(4)
+//	_, err := rag.Index(ctx, "handbook", handbookText, map[string]string{"source": "handbook.md"})
+//	result, err := rag.RetrieveAndAnswer(ctx, "How many vacation days do I get?")
+//	for _, c := range result.Citations {
+//	    fmt.Printf("[%d] %s\n", c.Index, c.ChunkID)
+//	}
+type RAG struct {
+	client       *AnthropicClient
+	embedder     Embedder
+	store        vectorstore.VectorStore
+	cfg          patternConfig
+	chunkSize    int
+	chunkOverlap int
+	chunker      chunker.Chunker
+	topK         int
+	minScore     float64
+	filter       vectorstore.Filter
+	splitter     PDFSplitter
+	search       SearchProvider
+	searchLimit  int
+}
+
+// NewRAG creates a RAG pattern over the given embedder and vector store
+func NewRAG(client *AnthropicClient, embedder Embedder, store vectorstore.VectorStore, opts ...Option) *RAG {
+	return &RAG{
+		client:       client,
+		embedder:     embedder,
+		store:        store,
+		cfg:          newPatternConfig("rag", opts),
+		chunkSize:    1000,
+		chunkOverlap: 200,
+		topK:         5,
+	}
+}
+
+// SetChunking sets the chunk size and overlap, in characters, used by Index
+func (r *RAG) SetChunking(size, overlap int) *RAG {
+	r.chunkSize = size
+	r.chunkOverlap = overlap
+	return r
+}
+
+// SetChunker splits documents with c instead of by characters, e.g. a
+// chunker.NewTextChunker sized in tokens or a chunker.NewCodeChunker for
+// source files
+func (r *RAG) SetChunker(c chunker.Chunker) *RAG {
+	r.chunker = c
+	return r
+}
+
+// SetPDFSplitter splits PDFs over the document limits given to
+// IndexDocument, e.g. QPDF{}
+func (r *RAG) SetPDFSplitter(s PDFSplitter) *RAG {
+	r.splitter = s
+	return r
+}
+
+// SetTopK sets the number of chunks retrieved per question
+func (r *RAG) SetTopK(k int) *RAG {
+	r.topK = k
+	return r
+}
+
+// SetMinScore drops retrieved chunks less similar than score
+func (r *RAG) SetMinScore(score float64) *RAG {
+	r.minScore = score
+	return r
+}
+
+// SetFilter restricts retrieval to chunks whose metadata matches filter,
+// e.g. the documents one tenant may see
+func (r *RAG) SetFilter(filter vectorstore.Filter) *RAG {
+	r.filter = filter
+	return r
+}
+
+// SetWebSearch adds up to maxResults web search results to the chunks
+// retrieved for each question. A web source has its URL as its ID and
+// document, and "web" as its "source" metadata. Web sources are quoted with
+// the injection defense set WithInjectionDefense before they reach a prompt.
+func (r *RAG) SetWebSearch(provider SearchProvider, maxResults int) *RAG {
+	r.search = provider
+	r.searchLimit = maxResults
+	return r
+}
+
+// Index chunks and embeds a document and stores its chunks as
+// "<docID>#<n>". Re-indexing a document replaces chunks with the same IDs;
+// delete the old ones first if the new version may be shorter. It returns
+// the number of chunks stored.
+func (r *RAG) Index(ctx context.Context, docID, text string, metadata map[string]string) (int, error) {
+	ctx, cancel := r.cfg.startRun(ctx)
+	defer cancel()
+
+	var chunks []string
+	if r.chunker != nil {
+		chunks = chunker.Texts(r.chunker.Split(text))
+	} else {
+		chunks = ChunkText(text, r.chunkSize, r.chunkOverlap)
+	}
+	if len(chunks) == 0 {
+		return 0, nil
+	}
+
+	vectors, err := r.embedder.EmbedDocuments(ctx, chunks)
+	if err != nil {
+		return 0, fmt.Errorf("failed to embed %s: %w", docID, err)
+	}
+
+	records := make([]vectorstore.Record, len(chunks))
+	for i, chunk := range chunks {
+		meta := make(map[string]string, len(metadata)+2)
+		for k, v := range metadata {
+			meta[k] = v
+		}
+		meta[MetadataDocument] = docID
+		meta[MetadataChunk] = strconv.Itoa(i)
+		records[i] = vectorstore.Record{
+			ID:       fmt.Sprintf("%s#%d", docID, i),
+			Vector:   vectors[i],
+			Text:     chunk,
+			Metadata: meta,
+		}
+	}
+	if err := r.store.Upsert(ctx, records); err != nil {
+		return 0, fmt.Errorf("failed to store %s: %w", docID, err)
+	}
+	r.cfg.logger.Info("indexed document", "document", docID, "chunks", len(chunks))
+	return len(chunks), nil
+}
+
+// IndexDocument indexes a document as Index does. Plain text is indexed as
+// it is; PDFs are split into parts within the document limits and
+// transcribed to Markdown by the model, tables and figures included, so
+// scanned and laid-out pages can be retrieved as text.
+//
+// Example:
+//
+//	doc, err := conversation.DocumentFromFile("benefits-guide.pdf")
+//	n, err := rag.SetPDFSplitter(QPDF{}).IndexDocument(ctx, "benefits", doc, nil)
+func (r *RAG) IndexDocument(ctx context.Context, docID string, doc Document, metadata map[string]string) (int, error) {
+	if doc.MediaType == "text/plain" && doc.URL == "" && doc.FileID == "" {
+		return r.Index(ctx, docID, string(doc.Data), metadata)
+	}
+
+	ctx, cancel := r.cfg.startRun(ctx)
+	defer cancel()
+
+	parts, err := SplitDocument(ctx, doc, r.splitter)
+	if err != nil {
+		return 0, err
+	}
+	instruction, err := r.cfg.prompt("rag.transcribe", map[string]interface{}{})
+	if err != nil {
+		return 0, err
+	}
+	texts := make([]string, len(parts))
+	for i, part := range parts {
+		msg := MessageItem{
+			Role:      "user",
+			Content:   instruction,
+			Documents: []Document{part},
+		}
+		texts[i], err = r.cfg.callMessage(ctx, r.client, msg, r.cfg.model, r.cfg.tokens(8192))
+		if err != nil {
+			return 0, fmt.Errorf("failed to transcribe %s: %w", documentName(part), err)
+		}
+	}
+	return r.Index(ctx, docID, strings.Join(texts, "\n\n"), metadata)
+}
+
+// Retrieve returns the chunks most similar to question
+func (r *RAG) Retrieve(ctx context.Context, question string) ([]vectorstore.Match, error) {
+	ctx, span := StartSpan(ctx, "rag.retrieve")
+	vector, err := r.embedder.EmbedQuery(ctx, question)
+	if err != nil {
+		span.Finish(err)
+		return nil, fmt.Errorf("failed to embed question: %w", err)
+	}
+	matches, err := r.store.QueryFiltered(ctx, vector, r.topK, r.filter)
+	if err != nil {
+		span.Finish(err)
+		return nil, fmt.Errorf("retrieval failed: %w", err)
+	}
+
+	kept := matches[:0]
+	for _, m := range matches {
+		if m.Score >= r.minScore {
+			kept = append(kept, m)
+		}
+	}
+	if r.search != nil {
+		results, err := webSearch(ctx, r.search, question, r.searchLimit)
+		if err != nil {
+			span.Finish(err)
+			return nil, err
+		}
+		for _, res := range results {
+			kept = append(kept, vectorstore.Match{Record: vectorstore.Record{
+				ID:       res.URL,
+				Text:     res.Title + "\n" + res.Snippet,
+				Metadata: map[string]string{MetadataDocument: res.URL, "source": "web"},
+			}})
+		}
+	}
+	span.SetAttribute("matches", len(kept))
+	span.Finish(nil)
+	r.cfg.publish(ctx, PhaseRetrieved, map[string]interface{}{"matches": len(kept)})
+	return kept, nil
+}
+
+// RetrieveAndAnswer retrieves the top chunks for question and generates an
+// answer that cites them
+func (r *RAG) RetrieveAndAnswer(ctx context.Context, question string) (*RAGResult, error) {
+	ctx, cancel := r.cfg.startRun(ctx)
+	defer cancel()
+
+	sources, err := r.Retrieve(ctx, question)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted, err := r.formatSources(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := r.cfg.prompt("rag.answer", map[string]interface{}{"sources": formatted, "question": question})
+	if err != nil {
+		return nil, err
+	}
+
+	genCtx, span := StartSpan(ctx, "rag.generate")
+	answer, err := r.cfg.call(genCtx, r.client, prompt, r.cfg.model, r.cfg.tokens(1024))
+	span.Finish(err)
+	if err != nil {
+		return nil, fmt.Errorf("generation failed: %w", err)
+	}
+
+	return &RAGResult{
+		Answer:    answer,
+		Sources:   sources,
+		Citations: extractCitations(answer, sources),
+	}, nil
+}
+
+// Augment retrieves chunks relevant to prompt and returns prompt preceded
+// by them, grounding patterns that do not retrieve for themselves, such as
+// a Router or PromptChain
+//
+// Example:
+//
+//	grounded, _, err := rag.Augment(ctx, ticket)
+//	result, _, err := router.Route(ctx, grounded, 0.7)
+func (r *RAG) Augment(ctx context.Context, prompt string) (string, []vectorstore.Match, error) {
+	sources, err := r.Retrieve(ctx, prompt)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(sources) == 0 {
+		return prompt, nil, nil
+	}
+	formatted, err := r.formatSources(ctx, sources)
+	if err != nil {
+		return "", nil, err
+	}
+	augmented, err := r.cfg.prompt("rag.augment", map[string]interface{}{"sources": formatted, "prompt": prompt})
+	if err != nil {
+		return "", nil, err
+	}
+	return augmented, sources, nil
+}
+
+// knowledgeSearchArgs are the arguments of the knowledge base tool
+type knowledgeSearchArgs struct {
+	Query string `json:"query" description:"What to look up in the knowledge base"`
+}
+
+// Tool returns an AgentTool that searches the indexed corpus, so an
+// AutonomousAgent can ground its answers in it
+//
+// Example:
+//
+//	agent.RegisterTool(rag.Tool())
+func (r *RAG) Tool() AgentTool {
+	return NewTool("search_knowledge_base", "Search the knowledge base for passages relevant to a query",
+		func(ctx context.Context, args knowledgeSearchArgs) (string, error) {
+			sources, err := r.Retrieve(ctx, args.Query)
+			if err != nil {
+				return "", err
+			}
+			return r.formatSources(ctx, sources)
+		})
+}
+
+// formatSources numbers retrieved chunks for a prompt. Web search results
+// are quoted with the injection defense, if any, as they are untrusted.
+func (r *RAG) formatSources(ctx context.Context, sources []vectorstore.Match) (string, error) {
+	if len(sources) == 0 {
+		return "(no relevant sources found)\n\n", nil
+	}
+	var b strings.Builder
+	for i, s := range sources {
+		text := s.Text
+		if s.Metadata["source"] == "web" {
+			quoted, err := r.cfg.quote(ctx, "web:"+s.ID, text)
+			if err != nil {
+				return "", err
+			}
+			text = quoted
+		}
+		fmt.Fprintf(&b, "[%d] (%s)\n%s\n\n", i+1, s.ID, text)
+	}
+	return b.String(), nil
+}
+
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// extractCitations maps the [n] references in answer to sources, in order
+// of first appearance. References to unknown sources are ignored.
+func extractCitations(answer string, sources []vectorstore.Match) []Citation {
+	var citations []Citation
+	seen := make(map[int]bool)
+	for _, m := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		for _, part := range strings.Split(m[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n < 1 || n > len(sources) || seen[n] {
+				continue
+			}
+			seen[n] = true
+			s := sources[n-1]
+			citations = append(citations, Citation{
+				Index:      n,
+				ChunkID:    s.ID,
+				DocumentID: s.Metadata[MetadataDocument],
+				Text:       s.Text,
+				Score:      s.Score,
+			})
+		}
+	}
+	return citations
+}
+
+// ChunkText splits text into chunks of at most size characters, each
+// overlapping the previous one by about overlap characters. Chunks end at a
+// paragraph, sentence, or word boundary when one falls in their second half.
+func ChunkText(text string, size, overlap int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if size <= 0 {
+		return []string{text}
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	runes := []rune(text)
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := start + size
+		if end >= len(runes) {
+			chunks = append(chunks, strings.TrimSpace(string(runes[start:])))
+			break
+		}
+		end = start + chunkBoundary(runes[start:end])
+
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		// Start the overlap on a word boundary
+		next := end - overlap
+		for next > start && next < end && !unicode.IsSpace(runes[next-1]) {
+			next++
+		}
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
+
+// chunkBoundary returns the length of window cut at the best boundary in
+// its second half, or the full window if there is none
+func chunkBoundary(window []rune) int {
+	s := string(window)
+	half := len(s) / 2
+	for _, sep := range []string{"\n\n", ". ", "\n", " "} {
+		if i := strings.LastIndex(s, sep); i >= half {
+			return utf8.RuneCountInString(s[:i+len(sep)])
+		}
+	}
+	return len(window)
+}
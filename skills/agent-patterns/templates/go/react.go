@@ -0,0 +1,124 @@
+/*
+ * ReAct Format for Go Agent Patterns
+ * Thought / Action / Observation steps with strict parsing and bounded observations
+ */
+
+package agentpatterns
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/jsonx"
+)
+
+// UseReAct switches the agent from JSON actions to the ReAct format:
+// each response is a Thought: followed by either an Action: and its Action
+// Input:, or a Final Answer:. Tool results come back as Observation:
+// messages, truncated to maxObservation characters (4000 if zero) so one
+// large result cannot crowd the rest out of context. A response breaking
+// the format is sent back with the problem for the model to correct.
+//
+// Example:
+//
+//	agent := NewAutonomousAgent(client).UseReAct(2000).RegisterTool(searchTool)
+func (a *AutonomousAgent) UseReAct(maxObservation int) *AutonomousAgent {
+	if maxObservation <= 0 {
+		maxObservation = 4000
+	}
+	if !a.react {
+		// Stop before the model invents an observation of its own
+		a.cfg.request.StopSequences = append(a.cfg.request.StopSequences, "\nObservation:")
+	}
+	a.react = true
+	a.maxObservation = maxObservation
+	return a
+}
+
+// reactLabel matches the labels starting each part of a ReAct response
+var reactLabel = regexp.MustCompile(`(?m)^(Thought|Action Input|Action|Final Answer|Observation):[ \t]*`)
+
+// parseReAct parses a ReAct response into an action. It fails, saying
+// why, unless the response is exactly a thought followed by an action and
+// its input, or by a final answer.
+func parseReAct(response string) (*AgentAction, error) {
+	parts := make(map[string]string)
+	var order []string
+	locs := reactLabel.FindAllStringSubmatchIndex(response, -1)
+	if len(locs) == 0 {
+		return nil, fmt.Errorf("no Thought: found")
+	}
+	if prefix := strings.TrimSpace(response[:locs[0][0]]); prefix != "" {
+		return nil, fmt.Errorf("text before Thought:")
+	}
+	for i, loc := range locs {
+		label := response[loc[2]:loc[3]]
+		if _, dup := parts[label]; dup {
+			return nil, fmt.Errorf("more than one %s:", label)
+		}
+		end := len(response)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		parts[label] = strings.TrimSpace(response[loc[1]:end])
+		order = append(order, label)
+	}
+
+	_, observed := parts["Observation"]
+	switch {
+	case observed:
+		return nil, fmt.Errorf("observations come from tools; stop after Action Input:")
+	case order[0] != "Thought":
+		return nil, fmt.Errorf("the response must start with Thought:")
+	case parts["Thought"] == "":
+		return nil, fmt.Errorf("Thought: is empty")
+	}
+
+	action := &AgentAction{Thought: parts["Thought"]}
+	switch {
+	case len(order) == 2 && order[1] == "Final Answer":
+		if parts["Final Answer"] == "" {
+			return nil, fmt.Errorf("Final Answer: is empty")
+		}
+		action.Action = "complete"
+		action.Result = parts["Final Answer"]
+	case len(order) == 3 && order[1] == "Action" && order[2] == "Action Input":
+		action.Action = parts["Action"]
+		if action.Action == "" || strings.ContainsAny(action.Action, " \n") {
+			return nil, fmt.Errorf("Action: must be a single tool name")
+		}
+		if err := jsonx.Unmarshal(parts["Action Input"], &action.Args); err != nil {
+			return nil, fmt.Errorf("Action Input: is not a JSON object: %v", err)
+		}
+		if fact, ok := action.Args["fact"].(string); ok && action.Action == "remember" {
+			action.Result = fact
+		}
+	default:
+		return nil, fmt.Errorf("expected Thought: followed by either Action: and Action Input:, or Final Answer:")
+	}
+	return action, nil
+}
+
+// handleFormatError returns a response that broke the ReAct format to the
+// model with what was wrong
+func (a *AutonomousAgent) handleFormatError(response string, err error) error {
+	a.conv.AddAssistant(response)
+	a.conv.AddUser(fmt.Sprintf("Invalid format: %s. Respond with Thought: and then either Action: and Action Input: (a JSON object), or Final Answer:.", err))
+	a.state.ActionHistory = append(a.state.ActionHistory, ActionRecord{
+		Step:       a.state.TotalSteps,
+		ActionType: "format_error",
+		Thought:    err.Error(),
+	})
+	return nil
+}
+
+// truncateObservation shortens an observation to at most max characters,
+// saying how much was cut
+func truncateObservation(observation string, max int) string {
+	runes := []rune(observation)
+	if len(runes) <= max {
+		return observation
+	}
+	return fmt.Sprintf("%s\n[... %d more characters truncated]", string(runes[:max]), len(runes)-max)
+}
@@ -0,0 +1,139 @@
+/*
+ * Reflective Agent for Go Agent Patterns
+ * Critique an agent's result once it completes, and resume it to close the gaps
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/schema"
+)
+
+// AgentCritique is a review of an agent's final result
+type AgentCritique struct {
+	Complete bool     `json:"complete" description:"Whether the result fully completes the task"`
+	Gaps     []string `json:"gaps" description:"Requirements missed, claims not supported, or work left undone"`
+	Critique string   `json:"critique" description:"What the agent should do to close the gaps"`
+}
+
+// ReflectiveResult is the outcome of a ReflectiveAgent run
+type ReflectiveResult struct {
+	AgentResult
+	// Rounds is the number of times the agent was resumed with a critique
+	Rounds    int
+	Critiques []AgentCritique
+}
+
+// ReflectiveAgent runs an agent and, each time it completes, critiques its
+// result against the task. If the critique finds gaps, the agent resumes
+// its conversation with the critique and works on, up to a number of
+// reflection rounds.
+//
+// Example:
+//
+//	agent := NewAutonomousAgent(client).RegisterTool(searchTool)
+//	reflective := NewReflectiveAgent(agent, WithModel("claude-sonnet-4-20250514"))
+//	result, err := reflective.Run(ctx, "Compare the three largest EV makers' 2024 margins", 15, 2)
+type ReflectiveAgent struct {
+	agent  *AutonomousAgent
+	cfg    patternConfig
+	critic func(ctx context.Context, task, result string) (*AgentCritique, error)
+}
+
+// NewReflectiveAgent wraps agent; opts configure the critique calls
+func NewReflectiveAgent(agent *AutonomousAgent, opts ...Option) *ReflectiveAgent {
+	return &ReflectiveAgent{
+		agent: agent,
+		cfg:   newPatternConfig("reflective_agent", opts),
+	}
+}
+
+// SetCritic replaces the LLM critique with another check, such as running
+// tests against the agent's work
+func (r *ReflectiveAgent) SetCritic(critic func(ctx context.Context, task, result string) (*AgentCritique, error)) *ReflectiveAgent {
+	r.critic = critic
+	return r
+}
+
+// Agent returns the wrapped agent
+func (r *ReflectiveAgent) Agent() *AutonomousAgent {
+	return r.agent
+}
+
+// Run runs the agent on task for up to maxSteps, then resumes it for up to
+// maxSteps more in each of at most maxRounds reflection rounds
+func (r *ReflectiveAgent) Run(ctx context.Context, task string, maxSteps, maxRounds int) (*ReflectiveResult, error) {
+	ctx, cancel := r.cfg.startRun(ctx)
+	defer cancel()
+
+	result, err := r.agent.Run(ctx, task, maxSteps)
+	if err != nil {
+		return nil, err
+	}
+
+	reflective := &ReflectiveResult{}
+	for round := 0; result.Success; round++ {
+		roundCtx, span := StartSpan(ctx, "reflective_agent.critique")
+		span.SetAttribute("round", round)
+		critique, err := r.critique(roundCtx, task, result.FinalResult)
+		span.Finish(err)
+		if err != nil {
+			return nil, fmt.Errorf("critique failed: %w", err)
+		}
+		reflective.Critiques = append(reflective.Critiques, *critique)
+		r.cfg.publish(roundCtx, PhaseIteration, map[string]interface{}{
+			"iteration": round,
+			"success":   critique.Complete,
+			"gaps":      len(critique.Gaps),
+		})
+		if critique.Complete || round >= maxRounds {
+			break
+		}
+
+		r.cfg.logger.Info("resuming agent with critique", "round", round+1, "gaps", len(critique.Gaps))
+		result, err = r.agent.revise(ctx, critiqueFeedback(critique), maxSteps)
+		if err != nil {
+			return nil, err
+		}
+		reflective.Rounds++
+	}
+
+	reflective.AgentResult = *result
+	return reflective, nil
+}
+
+func (r *ReflectiveAgent) critique(ctx context.Context, task, result string) (*AgentCritique, error) {
+	if r.critic != nil {
+		return r.critic(ctx, task, result)
+	}
+	prompt, err := r.cfg.prompt("agent.critique", map[string]interface{}{"task": task, "result": result})
+	if err != nil {
+		return nil, err
+	}
+	critique, err := structured[AgentCritique](ctx, &r.cfg, r.agent.client, prompt, schema.MustFor[AgentCritique](), r.cfg.model, 1024)
+	if err != nil {
+		return nil, err
+	}
+	return &critique, nil
+}
+
+// critiqueFeedback is the message resuming the agent after a critique
+func critiqueFeedback(critique *AgentCritique) string {
+	var b strings.Builder
+	b.WriteString("A review of your final answer found it incomplete.")
+	if len(critique.Gaps) > 0 {
+		b.WriteString("\n\nGaps:")
+		for _, gap := range critique.Gaps {
+			b.WriteString("\n- " + gap)
+		}
+	}
+	if critique.Critique != "" {
+		b.WriteString("\n\n" + critique.Critique)
+	}
+	b.WriteString("\n\nKeep working on the task, then give a new final answer that addresses the review.")
+	return b.String()
+}
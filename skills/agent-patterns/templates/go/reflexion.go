@@ -0,0 +1,254 @@
+/*
+ * Reflexion Pattern Implementation for Go
+ * Attempt, self-critique, remember the lesson, and retry
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/jsonx"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/schema"
+)
+
+// ReflexionVerdict is the critique of one attempt
+type ReflexionVerdict struct {
+	Success  bool    `json:"success" description:"Whether the output fully satisfies the task"`
+	Score    float64 `json:"score" description:"How close the output is to satisfying the task" jsonschema:"minimum=0,maximum=1"`
+	Critique string  `json:"critique" description:"What is wrong or missing, citing the task requirements"`
+	Lesson   string  `json:"lesson" description:"One reusable instruction that would avoid this mistake next time"`
+}
+
+// Reflection is one failed attempt and the lesson drawn from it
+type Reflection struct {
+	Task     string  `json:"task"`
+	Attempt  int     `json:"attempt"`
+	Output   string  `json:"output"`
+	Score    float64 `json:"score"`
+	Critique string  `json:"critique"`
+	Lesson   string  `json:"lesson"`
+}
+
+// ReflexionResult is the outcome of a Reflexion run
+type ReflexionResult struct {
+	Output      string
+	Success     bool
+	Attempts    int
+	Reflections []Reflection
+}
+
+// reflexionCheckpoint is the state persisted after each attempt when a
+// store is configured
+type reflexionCheckpoint struct {
+	Reflections []Reflection `json:"reflections"`
+}
+
+// Reflexion retries a task with lessons learned from critiques of earlier
+// attempts. Unlike EvaluatorOptimizer, which refines one output, each
+// attempt starts fresh, and lessons are kept as episodic memory that later
+// tasks on the same Reflexion also draw on.
+//
+// Example:
+//
+//	reflexion := NewReflexion(client, WithModel("claude-sonnet-4-20250514")).SetMemorySize(20)
+//	result, err := reflexion.Run(ctx, "Write a SQL query listing customers with no orders in 2024", 3)
+//	// Later tasks start with the lessons from this one
+//	lessons := reflexion.Memory()
+type Reflexion struct {
+	client     *AnthropicClient
+	cfg        patternConfig
+	evaluator  func(ctx context.Context, task, output string) (*ReflexionVerdict, error)
+	memorySize int
+
+	mu     sync.Mutex
+	memory []Reflection
+}
+
+// NewReflexion creates a new Reflexion pattern
+func NewReflexion(client *AnthropicClient, opts ...Option) *Reflexion {
+	return &Reflexion{
+		client:     client,
+		cfg:        newPatternConfig("reflexion", opts),
+		memorySize: 10,
+	}
+}
+
+// SetEvaluator replaces the LLM self-critique with an external check, such
+// as running tests against generated code. When the verdict has no lesson,
+// one is derived from the critique by the model.
+func (r *Reflexion) SetEvaluator(evaluator func(ctx context.Context, task, output string) (*ReflexionVerdict, error)) *Reflexion {
+	r.evaluator = evaluator
+	return r
+}
+
+// SetMemorySize sets how many lessons from earlier tasks are kept and
+// included in prompts
+func (r *Reflexion) SetMemorySize(n int) *Reflexion {
+	r.memorySize = n
+	return r
+}
+
+// Memory returns the reflections kept from earlier tasks
+func (r *Reflexion) Memory() []Reflection {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Reflection(nil), r.memory...)
+}
+
+// Remember adds reflections to memory, e.g. ones saved from a previous
+// process (builder pattern)
+func (r *Reflexion) Remember(reflections ...Reflection) *Reflexion {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.memory = append(r.memory, reflections...)
+	if r.memorySize >= 0 && len(r.memory) > r.memorySize {
+		r.memory = r.memory[len(r.memory)-r.memorySize:]
+	}
+	return r
+}
+
+// Run attempts task up to maxAttempts times, reflecting on each failure
+func (r *Reflexion) Run(ctx context.Context, task string, maxAttempts int) (*ReflexionResult, error) {
+	ctx, cancel := r.cfg.startRun(ctx)
+	defer cancel()
+
+	var checkpoint reflexionCheckpoint
+	if _, err := r.cfg.loadCheckpoint(ctx, &checkpoint); err != nil {
+		return nil, err
+	}
+	reflections := checkpoint.Reflections
+	prior := r.Memory()
+
+	output := ""
+	for attempt := len(reflections) + 1; attempt <= maxAttempts; attempt++ {
+		attemptCtx, span := StartSpan(ctx, "reflexion.attempt")
+		span.SetAttribute("attempt", attempt)
+
+		var err error
+		output, err = r.attempt(attemptCtx, task, prior, reflections)
+		if err != nil {
+			span.Finish(err)
+			return nil, fmt.Errorf("attempt %d failed: %w", attempt, err)
+		}
+
+		verdict, err := r.evaluate(attemptCtx, task, output)
+		if err != nil {
+			span.Finish(err)
+			return nil, fmt.Errorf("evaluation failed: %w", err)
+		}
+		span.SetAttribute("success", verdict.Success)
+		span.SetAttribute("score", verdict.Score)
+		span.Finish(nil)
+		r.cfg.publish(attemptCtx, PhaseIteration, map[string]interface{}{
+			"iteration": attempt,
+			"score":     verdict.Score,
+			"success":   verdict.Success,
+		})
+
+		if verdict.Success {
+			return &ReflexionResult{
+				Output:      output,
+				Success:     true,
+				Attempts:    attempt,
+				Reflections: reflections,
+			}, nil
+		}
+
+		lesson := verdict.Lesson
+		if lesson == "" {
+			if lesson, err = r.reflect(attemptCtx, task, output, verdict.Critique); err != nil {
+				return nil, fmt.Errorf("reflection failed: %w", err)
+			}
+		}
+		reflection := Reflection{
+			Task:     task,
+			Attempt:  attempt,
+			Output:   output,
+			Score:    verdict.Score,
+			Critique: verdict.Critique,
+			Lesson:   lesson,
+		}
+		reflections = append(reflections, reflection)
+		r.Remember(reflection)
+		r.cfg.logger.Info("attempt failed", "attempt", attempt, "lesson", lesson)
+
+		if err := r.cfg.saveCheckpoint(ctx, reflexionCheckpoint{Reflections: reflections}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resumed after the last attempt, or every attempt failed
+	if output == "" && len(reflections) > 0 {
+		output = reflections[len(reflections)-1].Output
+	}
+	return &ReflexionResult{
+		Output:      output,
+		Success:     false,
+		Attempts:    len(reflections),
+		Reflections: reflections,
+	}, nil
+}
+
+func (r *Reflexion) attempt(ctx context.Context, task string, prior, current []Reflection) (string, error) {
+	var lessons []string
+	for _, m := range prior {
+		lessons = append(lessons, "- "+m.Lesson)
+	}
+	for _, m := range current {
+		lessons = append(lessons, fmt.Sprintf("- Attempt %d: %s", m.Attempt, m.Lesson))
+	}
+
+	prompt, err := r.cfg.prompt("reflexion.attempt", map[string]interface{}{"task": task, "lessons": lessons})
+	if err != nil {
+		return "", err
+	}
+	return r.cfg.call(ctx, r.client, prompt, r.cfg.model, r.cfg.tokens(4096))
+}
+
+func (r *Reflexion) evaluate(ctx context.Context, task, output string) (*ReflexionVerdict, error) {
+	if r.evaluator != nil {
+		return r.evaluator(ctx, task, output)
+	}
+
+	prompt, err := r.cfg.prompt("reflexion.evaluate", map[string]interface{}{
+		"task":   task,
+		"output": output,
+		"schema": schema.MustFor[ReflexionVerdict]().String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.cfg.call(ctx, r.client, prompt, r.cfg.model, 1024)
+	if err != nil {
+		return nil, err
+	}
+
+	var verdict ReflexionVerdict
+	if err := jsonx.Unmarshal(response, &verdict); err != nil {
+		return nil, fmt.Errorf("failed to parse critique: %w", err)
+	}
+	return &verdict, nil
+}
+
+// reflect turns an external critique into a reusable lesson
+func (r *Reflexion) reflect(ctx context.Context, task, output, critique string) (string, error) {
+	prompt, err := r.cfg.prompt("reflexion.reflect", map[string]interface{}{
+		"task":     task,
+		"output":   output,
+		"feedback": critique,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	lesson, err := r.cfg.call(ctx, r.client, prompt, r.cfg.model, 256)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(lesson), nil
+}
@@ -0,0 +1,314 @@
+/*
+ * Deterministic Replay for Go Agent Patterns
+ * Record every LLM response, tool result, and random decision of a run, and re-drive the run from the recording
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotRecorded is returned during replay when a run makes a call, or
+// asks for a decision, that the bundle has no recording left for
+var ErrNotRecorded = errors.New("not recorded in replay bundle")
+
+// bundleVersion is the RunBundle format written by this package
+const bundleVersion = 1
+
+// RecordedCall is one LLM call, tool call, or durable step of a recorded run
+type RecordedCall struct {
+	Seq      int             `json:"seq"`
+	Kind     string          `json:"kind"`
+	Input    string          `json:"input"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Duration time.Duration   `json:"duration"`
+}
+
+// RecordedDecision is one value chosen with Decide
+type RecordedDecision struct {
+	Seq   int             `json:"seq"`
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+// RunBundle holds everything a run received from outside: LLM responses,
+// tool results, and random decisions. Bundles are indented JSON ordered by
+// sequence, so two recordings of the same run can be compared with diff.
+type RunBundle struct {
+	Version   int                `json:"version"`
+	RunID     string             `json:"run_id"`
+	Recorded  time.Time          `json:"recorded"`
+	Calls     []RecordedCall     `json:"calls"`
+	Decisions []RecordedDecision `json:"decisions,omitempty"`
+	Output    string             `json:"output"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// Save writes the bundle to path
+func (b *RunBundle) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// LoadBundle reads a bundle written by Save
+func LoadBundle(path string) (*RunBundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b RunBundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to decode replay bundle: %w", err)
+	}
+	if b.Version != bundleVersion {
+		return nil, fmt.Errorf("unsupported replay bundle version %d", b.Version)
+	}
+	return &b, nil
+}
+
+// Divergence is a call made during replay whose input differs from the
+// recording it was served from, typically because a prompt or the code
+// building it changed since the run was recorded
+type Divergence struct {
+	Seq      int    `json:"seq"`
+	Kind     string `json:"kind"`
+	Recorded string `json:"recorded"`
+	Actual   string `json:"actual"`
+}
+
+// ReplayReport describes how closely a replay followed its bundle
+type ReplayReport struct {
+	Replayed    int          `json:"replayed"`
+	Divergences []Divergence `json:"divergences,omitempty"`
+	// Unused counts recorded calls the replay never asked for
+	Unused int `json:"unused"`
+	// OutputMatches reports whether the replay produced the recorded output
+	OutputMatches bool `json:"output_matches"`
+}
+
+// Record runs fn and captures everything it receives from outside in a
+// bundle. The bundle is returned even when fn fails, so failing runs can be
+// attached to bug reports.
+//
+// Example:
+//
+//	output, bundle, err := Record(ctx, func(ctx context.Context) (string, error) {
+//	    result, err := orchestrator.Execute(ctx, task)
+//	    if err != nil {
+//	        return "", err
+//	    }
+//	    return result.FinalResult, nil
+//	})
+//	bundle.Save("run.json")
+func Record(ctx context.Context, fn func(ctx context.Context) (string, error)) (string, *RunBundle, error) {
+	if RunIDFromContext(ctx) == "" {
+		ctx = WithRunID(ctx, newRunID())
+	}
+	s := &replaySession{
+		bundle: &RunBundle{Version: bundleVersion, RunID: RunIDFromContext(ctx), Recorded: time.Now().UTC()},
+	}
+	output, err := fn(context.WithValue(ctx, replaySessionKey{}, s))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bundle.Output = output
+	if err != nil {
+		s.bundle.Error = err.Error()
+	}
+	return output, s.bundle, err
+}
+
+// Replay re-drives fn from bundle: every LLM call, tool call, durable step,
+// and decision is answered from the recording, with no requests sent.
+// Calls are matched to recordings by kind and input; a call whose input
+// changed is served the next unused recording of its kind and reported as
+// a divergence. Recorded errors are replayed with their message only.
+func Replay(ctx context.Context, bundle *RunBundle, fn func(ctx context.Context) (string, error)) (string, *ReplayReport, error) {
+	s := &replaySession{
+		replay:    true,
+		byInput:   make(map[string][]int),
+		byKind:    make(map[string][]int),
+		used:      make([]bool, len(bundle.Calls)),
+		decisions: make(map[string][]json.RawMessage),
+		report:    &ReplayReport{},
+		bundle:    bundle,
+	}
+	for i, call := range bundle.Calls {
+		key := callKey(call.Kind, call.Input)
+		s.byInput[key] = append(s.byInput[key], i)
+		s.byKind[call.Kind] = append(s.byKind[call.Kind], i)
+	}
+	for _, d := range bundle.Decisions {
+		s.decisions[d.Name] = append(s.decisions[d.Name], d.Value)
+	}
+
+	ctx = WithRunID(ctx, bundle.RunID)
+	output, err := fn(context.WithValue(ctx, replaySessionKey{}, s))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, used := range s.used {
+		if !used {
+			s.report.Unused++
+		}
+	}
+	s.report.OutputMatches = err == nil && output == bundle.Output
+	return output, s.report, err
+}
+
+// Decide returns fn's result, recording it when the run is recorded and
+// returning the recorded value instead of calling fn when it is replayed.
+// Use it for anything random or time-dependent that affects the run, such
+// as sampling, shuffling, or IDs. T must round-trip through encoding/json.
+func Decide[T any](ctx context.Context, name string, fn func() T) (T, error) {
+	s, _ := ctx.Value(replaySessionKey{}).(*replaySession)
+	if s == nil {
+		return fn(), nil
+	}
+
+	var value T
+	if s.replay {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		queue := s.decisions[name]
+		if len(queue) == 0 {
+			return value, fmt.Errorf("%w: decision %q", ErrNotRecorded, name)
+		}
+		s.decisions[name] = queue[1:]
+		if err := json.Unmarshal(queue[0], &value); err != nil {
+			return value, fmt.Errorf("failed to decode recorded decision %q: %w", name, err)
+		}
+		return value, nil
+	}
+
+	value = fn()
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value, fmt.Errorf("failed to encode decision %q: %w", name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	s.bundle.Decisions = append(s.bundle.Decisions, RecordedDecision{Seq: s.seq, Name: name, Value: data})
+	return value, nil
+}
+
+type replaySessionKey struct{}
+
+// replaySession records calls into a bundle, or serves them from one
+type replaySession struct {
+	replay bool
+
+	mu        sync.Mutex
+	bundle    *RunBundle
+	seq       int
+	byInput   map[string][]int
+	byKind    map[string][]int
+	used      []bool
+	decisions map[string][]json.RawMessage
+	report    *ReplayReport
+}
+
+// next returns the recording to serve for a call, preferring one with the
+// same input
+func (s *replaySession) next(kind, input string) (RecordedCall, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	take := func(queue []int) (int, bool) {
+		for _, i := range queue {
+			if !s.used[i] {
+				s.used[i] = true
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	if i, ok := take(s.byInput[callKey(kind, input)]); ok {
+		s.report.Replayed++
+		return s.bundle.Calls[i], true
+	}
+	if i, ok := take(s.byKind[kind]); ok {
+		call := s.bundle.Calls[i]
+		s.report.Replayed++
+		s.report.Divergences = append(s.report.Divergences, Divergence{Seq: call.Seq, Kind: kind, Recorded: call.Input, Actual: input})
+		return call, true
+	}
+	return RecordedCall{}, false
+}
+
+func (s *replaySession) add(call RecordedCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	call.Seq = s.seq
+	s.bundle.Calls = append(s.bundle.Calls, call)
+}
+
+func callKey(kind, input string) string {
+	sum := sha256.Sum256([]byte(input))
+	return kind + "\x00" + hex.EncodeToString(sum[:])
+}
+
+// recorded records or replays a call when ctx carries a replay session
+func recorded[T any](ctx context.Context, kind, input string, fn func(ctx context.Context) (T, error)) (T, error) {
+	s, _ := ctx.Value(replaySessionKey{}).(*replaySession)
+	if s == nil {
+		return fn(ctx)
+	}
+
+	var result T
+	if s.replay {
+		call, ok := s.next(kind, input)
+		if !ok {
+			return result, fmt.Errorf("%w: %s call", ErrNotRecorded, kind)
+		}
+		if call.Error != "" {
+			return result, errors.New(call.Error)
+		}
+		if err := json.Unmarshal(call.Result, &result); err != nil {
+			return result, fmt.Errorf("failed to decode recorded %s call: %w", kind, err)
+		}
+		return result, nil
+	}
+
+	start := time.Now()
+	result, fnErr := fn(ctx)
+	call := RecordedCall{Kind: kind, Input: input, Duration: time.Since(start)}
+	if fnErr != nil {
+		call.Error = fnErr.Error()
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return result, fmt.Errorf("failed to encode %s result: %w", kind, err)
+		}
+		call.Result = data
+	}
+	s.add(call)
+	return result, fnErr
+}
+
+// sortedKeys returns the keys of m in order, so prompts built from maps
+// are the same on every run
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -6,101 +6,21 @@
 package agentpatterns
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"regexp"
-	"strconv"
+	"sort"
 	"strings"
-)
-
-// AnthropicClient represents a client for the Anthropic API
-type AnthropicClient struct {
-	APIKey     string
-	HTTPClient *http.Client
-}
-
-// MessageRequest represents a request to the Anthropic API
-type MessageRequest struct {
-	Model     string        `json:"model"`
-	MaxTokens int           `json:"max_tokens"`
-	Messages  []MessageItem `json:"messages"`
-}
-
-// MessageItem represents a message in the conversation
-type MessageItem struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-// MessageResponse represents a response from the Anthropic API
-type MessageResponse struct {
-	Content []ContentBlock `json:"content"`
-}
-
-// ContentBlock represents a content block in the response
-type ContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
-}
-
-// CreateMessage sends a message to the Anthropic API
-func (c *AnthropicClient) CreateMessage(ctx context.Context, prompt, model string, maxTokens int) (string, error) {
-	reqBody := MessageRequest{
-		Model:     model,
-		MaxTokens: maxTokens,
-		Messages: []MessageItem{
-			{Role: "user", Content: prompt},
-		},
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("x-api-key", c.APIKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-	req.Header.Set("content-type", "application/json")
+	"sync"
 
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
-	}
-
-	var msgResp MessageResponse
-	if err := json.NewDecoder(resp.Body).Decode(&msgResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	for _, block := range msgResp.Content {
-		if block.Type == "text" {
-			return block.Text, nil
-		}
-	}
-
-	return "", fmt.Errorf("no text content in response")
-}
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/schema"
+)
 
 // ClassificationResult represents the result of a classification
 type ClassificationResult struct {
-	Category   string  `json:"category"`
-	Confidence float64 `json:"confidence"`
-	Reasoning  string  `json:"reasoning"`
+	Category   string  `json:"category" description:"The best matching category"`
+	Confidence float64 `json:"confidence" description:"How certain the classification is" jsonschema:"minimum=0,maximum=1"`
+	Reasoning  string  `json:"reasoning" description:"Brief explanation"`
 }
 
 // Route defines a route with its handler
@@ -114,7 +34,7 @@ type Route[T any] struct {
 //
 // Example:
 //
-//	router := NewRouter[string](client, "claude-sonnet-4-20250514")
+//	router := NewRouter[string](client, WithModel("claude-sonnet-4-20250514"))
 //	router.AddRoute(Route[string]{
 //	    Category: "technical",
 //	    Description: "Technical issues",
@@ -123,16 +43,16 @@ type Route[T any] struct {
 //	result, classification, err := router.Route(ctx, "My app crashed", 0.7)
 type Router[T any] struct {
 	client   *AnthropicClient
-	model    string
+	cfg      patternConfig
 	routes   map[string]Route[T]
 	fallback func(ctx context.Context, input string) (T, error)
 }
 
 // NewRouter creates a new Router
-func NewRouter[T any](client *AnthropicClient, model string) *Router[T] {
+func NewRouter[T any](client *AnthropicClient, opts ...Option) *Router[T] {
 	return &Router[T]{
 		client: client,
-		model:  model,
+		cfg:    newPatternConfig("router", opts),
 		routes: make(map[string]Route[T]),
 	}
 }
@@ -153,10 +73,17 @@ func (r *Router[T]) SetFallback(handler func(ctx context.Context, input string)
 func (r *Router[T]) Route(ctx context.Context, input string, confidenceThreshold float64) (T, *ClassificationResult, error) {
 	var zero T
 
+	ctx, cancel := r.cfg.startRun(ctx)
+	defer cancel()
+
 	classification, err := r.Classify(ctx, input)
 	if err != nil {
 		return zero, nil, fmt.Errorf("classification failed: %w", err)
 	}
+	r.cfg.publish(ctx, PhaseClassified, map[string]interface{}{
+		"category":   classification.Category,
+		"confidence": classification.Confidence,
+	})
 
 	if classification.Confidence < confidenceThreshold {
 		if r.fallback != nil {
@@ -175,63 +102,153 @@ func (r *Router[T]) Route(ctx context.Context, input string, confidenceThreshold
 		return zero, classification, fmt.Errorf("no handler for category: %s", classification.Category)
 	}
 
-	result, err := route.Handler(ctx, input)
+	handlerCtx, span := StartSpan(ctx, "router.handler")
+	span.SetAttribute("category", classification.Category)
+	result, err := route.Handler(handlerCtx, input)
+	span.Finish(err)
 	return result, classification, err
 }
 
 // Classify classifies input into a category
 func (r *Router[T]) Classify(ctx context.Context, input string) (*ClassificationResult, error) {
-	var categories []string
-	for _, route := range r.routes {
-		categories = append(categories, fmt.Sprintf("- %s: %s", route.Category, route.Description))
+	var categories []map[string]string
+	outputSchema := schema.MustFor[ClassificationResult]()
+	for _, category := range sortedKeys(r.routes) {
+		route := r.routes[category]
+		categories = append(categories, map[string]string{"name": route.Category, "description": route.Description})
+		outputSchema.Properties["category"].Enum = append(outputSchema.Properties["category"].Enum, route.Category)
 	}
 
-	prompt := fmt.Sprintf(`Classify the following input into one of these categories:
-%s
-
-Input: %s
-
-Respond with JSON in this exact format:
-{
-    "category": "<category_name>",
-    "confidence": <0.0-1.0>,
-    "reasoning": "<brief explanation>"
-}`, strings.Join(categories, "\n"), input)
-
-	response, err := r.client.CreateMessage(ctx, prompt, r.model, 256)
+	quoted, err := r.cfg.quote(ctx, "user_input", input)
 	if err != nil {
 		return nil, err
 	}
+	prompt, err := r.cfg.prompt("router.classify", map[string]interface{}{"categories": categories, "input": quoted})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := structured[ClassificationResult](ctx, &r.cfg, r.client, prompt, outputSchema, r.cfg.model, r.cfg.tokens(256))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse classification: %w", err)
+	}
+	return &result, nil
+}
 
-	return parseClassificationJSON(response)
+// RouteResult is the outcome of one handler run by RouteMulti
+type RouteResult[T any] struct {
+	Classification ClassificationResult
+	Result         T
+	Err            error
 }
 
-func parseClassificationJSON(jsonStr string) (*ClassificationResult, error) {
-	result := &ClassificationResult{
-		Confidence: 0.5,
-	}
+// MergeFunc combines the results of the handlers run by RouteMulti, which
+// are ordered by confidence, highest first. It decides how to treat
+// handlers that failed.
+type MergeFunc[T any] func(ctx context.Context, results []RouteResult[T]) (T, error)
+
+// multiClassification is the response expected by ClassifyMulti
+type multiClassification struct {
+	Categories []ClassificationResult `json:"categories" description:"Every category that applies, with its own confidence"`
+}
+
+// RouteMulti classifies input into every category that applies, runs the
+// handlers of those at or above confidenceThreshold concurrently, and
+// merges their results. All classifications are returned, highest
+// confidence first. The fallback handles input that matches no route.
+//
+// Example:
+//
+//	reply, classifications, err := router.RouteMulti(ctx, "I was charged twice and the app crashes", 0.6,
+//	    func(ctx context.Context, results []RouteResult[string]) (string, error) {
+//	        var parts []string
+//	        for _, r := range results {
+//	            if r.Err != nil {
+//	                return "", r.Err
+//	            }
+//	            parts = append(parts, r.Result)
+//	        }
+//	        return strings.Join(parts, "\n\n"), nil
+//	    })
+func (r *Router[T]) RouteMulti(ctx context.Context, input string, confidenceThreshold float64, merge MergeFunc[T]) (T, []ClassificationResult, error) {
+	var zero T
 
-	// Extract category
-	categoryRe := regexp.MustCompile(`"category"\s*:\s*"([^"]*)"`)
-	if match := categoryRe.FindStringSubmatch(jsonStr); len(match) > 1 {
-		result.Category = match[1]
+	ctx, cancel := r.cfg.startRun(ctx)
+	defer cancel()
+
+	classifications, err := r.ClassifyMulti(ctx, input)
+	if err != nil {
+		return zero, nil, fmt.Errorf("classification failed: %w", err)
+	}
+	categories := make([]string, 0, len(classifications))
+	var matched []ClassificationResult
+	for _, c := range classifications {
+		categories = append(categories, c.Category)
+		if _, ok := r.routes[c.Category]; ok && c.Confidence >= confidenceThreshold {
+			matched = append(matched, c)
+		}
 	}
+	r.cfg.publish(ctx, PhaseClassified, map[string]interface{}{
+		"categories": categories,
+		"matched":    len(matched),
+	})
 
-	// Extract confidence
-	confidenceRe := regexp.MustCompile(`"confidence"\s*:\s*([0-9.]+)`)
-	if match := confidenceRe.FindStringSubmatch(jsonStr); len(match) > 1 {
-		if conf, err := strconv.ParseFloat(match[1], 64); err == nil {
-			result.Confidence = conf
+	if len(matched) == 0 {
+		if r.fallback != nil {
+			result, err := r.fallback(ctx, input)
+			return result, classifications, err
 		}
+		return zero, classifications, fmt.Errorf("no category above confidence %.2f and no fallback handler set", confidenceThreshold)
+	}
+
+	results := make([]RouteResult[T], len(matched))
+	var wg sync.WaitGroup
+	for i, c := range matched {
+		wg.Add(1)
+		go func(i int, c ClassificationResult) {
+			defer wg.Done()
+			handlerCtx, span := StartSpan(ctx, "router.handler")
+			span.SetAttribute("category", c.Category)
+			result, err := r.routes[c.Category].Handler(handlerCtx, input)
+			span.Finish(err)
+			results[i] = RouteResult[T]{Classification: c, Result: result, Err: err}
+		}(i, c)
 	}
+	wg.Wait()
 
-	// Extract reasoning
-	reasoningRe := regexp.MustCompile(`"reasoning"\s*:\s*"([^"]*)"`)
-	if match := reasoningRe.FindStringSubmatch(jsonStr); len(match) > 1 {
-		result.Reasoning = match[1]
+	merged, err := merge(ctx, results)
+	return merged, classifications, err
+}
+
+// ClassifyMulti classifies input into every category that applies, highest
+// confidence first
+func (r *Router[T]) ClassifyMulti(ctx context.Context, input string) ([]ClassificationResult, error) {
+	var categories []map[string]string
+	outputSchema := schema.MustFor[multiClassification]()
+	categorySchema := outputSchema.Properties["categories"].Items.Properties["category"]
+	for _, category := range sortedKeys(r.routes) {
+		route := r.routes[category]
+		categories = append(categories, map[string]string{"name": route.Category, "description": route.Description})
+		categorySchema.Enum = append(categorySchema.Enum, route.Category)
 	}
 
-	return result, nil
+	quoted, err := r.cfg.quote(ctx, "user_input", input)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := r.cfg.prompt("router.classify_multi", map[string]interface{}{"categories": categories, "input": quoted})
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := structured[multiClassification](ctx, &r.cfg, r.client, prompt, outputSchema, r.cfg.model, r.cfg.tokens(512))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse classification: %w", err)
+	}
+	sort.SliceStable(parsed.Categories, func(i, j int) bool {
+		return parsed.Categories[i].Confidence > parsed.Categories[j].Confidence
+	})
+	return parsed.Categories, nil
 }
 
 // Complexity represents task complexity levels
@@ -258,20 +275,24 @@ func (c Complexity) String() string {
 
 // ModelRouter routes to appropriate model based on task complexity
 type ModelRouter struct {
-	client              *AnthropicClient
-	classificationModel string
+	client *AnthropicClient
+	cfg    patternConfig
 }
 
-// NewModelRouter creates a new ModelRouter
-func NewModelRouter(client *AnthropicClient, classificationModel string) *ModelRouter {
+// NewModelRouter creates a new ModelRouter. WithModel sets the model used to
+// assess complexity.
+func NewModelRouter(client *AnthropicClient, opts ...Option) *ModelRouter {
 	return &ModelRouter{
-		client:              client,
-		classificationModel: classificationModel,
+		client: client,
+		cfg:    newPatternConfig("model_router", opts),
 	}
 }
 
 // RouteByComplexity routes to appropriate model based on task complexity
 func (r *ModelRouter) RouteByComplexity(ctx context.Context, input string) (string, error) {
+	ctx, cancel := r.cfg.startRun(ctx)
+	defer cancel()
+
 	complexity, err := r.AssessComplexity(ctx, input)
 	if err != nil {
 		return "", err
@@ -289,21 +310,17 @@ func (r *ModelRouter) RouteByComplexity(ctx context.Context, input string) (stri
 		model = "claude-sonnet-4-20250514"
 	}
 
-	return r.client.CreateMessage(ctx, input, model, 4096)
+	return r.cfg.call(ctx, r.client, input, model, r.cfg.tokens(4096))
 }
 
 // AssessComplexity assesses the complexity of a task
 func (r *ModelRouter) AssessComplexity(ctx context.Context, input string) (Complexity, error) {
-	prompt := fmt.Sprintf(`Assess the complexity of this task on a scale:
-- Simple: Factual lookup, simple formatting, basic questions
-- Moderate: Analysis, summarization, code review
-- Complex: Multi-step reasoning, creative writing, complex coding
-
-Task: %s
-
-Respond with just one word: Simple, Moderate, or Complex`, input)
+	prompt, err := r.cfg.prompt("router.complexity", map[string]interface{}{"task": input})
+	if err != nil {
+		return ComplexityModerate, err
+	}
 
-	response, err := r.client.CreateMessage(ctx, prompt, r.classificationModel, 10)
+	response, err := r.cfg.call(ctx, r.client, prompt, r.cfg.model, 10)
 	if err != nil {
 		return ComplexityModerate, err
 	}
@@ -330,7 +347,7 @@ func ExampleCustomerServiceRouting() error {
 		HTTPClient: &http.Client{},
 	}
 
-	router := NewRouter[string](client, "claude-sonnet-4-20250514")
+	router := NewRouter[string](client, WithModel("claude-sonnet-4-20250514"))
 
 	// Add routes
 	router.AddRoute(Route[string]{
@@ -378,9 +395,3 @@ func ExampleCustomerServiceRouting() error {
 
 	return nil
 }
-
-// Helper function to get environment variable with default
-func getEnv(key, defaultValue string) string {
-	// Implementation would use os.Getenv in production
-	return defaultValue
-}
@@ -0,0 +1,280 @@
+/*
+ * Rubrics for Go Agent Patterns
+ * Evaluation criteria with weights and scoring anchors, kept in YAML or Markdown files
+ */
+
+package agentpatterns
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScoreAnchor describes what an output earning Score looks like, to keep
+// judges consistent
+type ScoreAnchor struct {
+	Score       float64 `yaml:"score"`
+	Description string  `yaml:"description"`
+	// Example is a sample output at this score, if any
+	Example string `yaml:"example"`
+}
+
+// RubricCriterion is one criterion of a rubric. Weight defaults to 1.
+type RubricCriterion struct {
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description"`
+	Weight      float64       `yaml:"weight"`
+	Anchors     []ScoreAnchor `yaml:"anchors"`
+}
+
+// Rubric is a set of weighted evaluation criteria kept outside code, so
+// EvaluatorOptimizer and the eval harness can score against the same
+// standard. Anchors are sorted from the highest score down.
+//
+// Example rubric.yaml:
+//
+//	name: support_reply
+//	criteria:
+//	  - name: accuracy
+//	    description: The reply's facts and steps are correct
+//	    weight: 2
+//	    anchors:
+//	      - score: 1.0
+//	        description: Every fact and step is correct
+//	      - score: 0.0
+//	        description: The reply would mislead the customer
+//	        example: "Just reinstall the app; that always fixes billing errors."
+//	  - name: tone
+//	    description: Polite and empathetic
+type Rubric struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	Criteria    []RubricCriterion `yaml:"criteria"`
+}
+
+// LoadRubric reads a rubric from a Markdown file (.md or .markdown, see
+// ParseMarkdownRubric) or else from YAML or JSON
+//
+// Example:
+//
+//	rubric, err := LoadRubric("rubrics/support_reply.md")
+//	optimizer := NewEvaluatorOptimizer(client, WithRubric(rubric))
+func LoadRubric(path string) (*Rubric, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rubric: %w", err)
+	}
+	var rubric *Rubric
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		rubric, err = ParseMarkdownRubric(data)
+	default:
+		rubric, err = ParseRubric(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rubric %s: %w", path, err)
+	}
+	return rubric, nil
+}
+
+// ParseRubric parses and validates a YAML or JSON rubric
+func ParseRubric(data []byte) (*Rubric, error) {
+	var rubric Rubric
+	if err := yaml.Unmarshal(data, &rubric); err != nil {
+		return nil, fmt.Errorf("failed to parse rubric: %w", err)
+	}
+	if err := rubric.normalize(); err != nil {
+		return nil, err
+	}
+	return &rubric, nil
+}
+
+var (
+	// rubricWeightHeading matches a criterion heading such as
+	// "Accuracy (weight: 2)"
+	rubricWeightHeading = regexp.MustCompile(`(?i)^(.*?)\s*\(weight:\s*([0-9.]+)\)$`)
+	rubricWeightLine    = regexp.MustCompile(`(?i)^weight:\s*([0-9.]+)$`)
+	rubricAnchor        = regexp.MustCompile(`^[-*]\s+([0-9]*\.?[0-9]+)\s*:\s*(.*)$`)
+	rubricExample       = regexp.MustCompile(`(?i)^example:\s*(.*)$`)
+)
+
+// ParseMarkdownRubric parses a rubric written in Markdown. The level-one
+// heading names the rubric, and text under it describes it. Each
+// level-two heading starts a criterion, with an optional weight in the
+// heading or on a "Weight:" line. List items starting with a score and a
+// colon are anchors; an indented "Example:" line under an anchor gives its
+// example, and further indented lines continue it.
+//
+// Example rubric.md:
+//
+//	# Support reply
+//
+//	## Accuracy (weight: 2)
+//
+//	The reply's facts and steps are correct.
+//
+//	- 1.0: Every fact and step is correct
+//	- 0.0: The reply would mislead the customer
+//	  Example: Just reinstall the app; that always fixes billing errors.
+//
+//	## Tone
+//
+//	Polite and empathetic.
+func ParseMarkdownRubric(data []byte) (*Rubric, error) {
+	var rubric Rubric
+	var criterion *RubricCriterion
+	var anchor *ScoreAnchor
+	var description []string
+	inExample := false
+
+	// flush ends the description being collected
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(description, "\n"))
+		if criterion != nil {
+			criterion.Description = text
+		} else {
+			rubric.Description = text
+		}
+		description = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		raw := scanner.Text()
+		text := strings.TrimSpace(raw)
+		indented := raw != "" && (raw[0] == ' ' || raw[0] == '\t')
+
+		switch {
+		case strings.HasPrefix(text, "# ") && criterion == nil:
+			rubric.Name = strings.TrimSpace(text[2:])
+			anchor = nil
+		case strings.HasPrefix(text, "## "):
+			flush()
+			rubric.Criteria = append(rubric.Criteria, RubricCriterion{Name: strings.TrimSpace(text[3:])})
+			criterion = &rubric.Criteria[len(rubric.Criteria)-1]
+			if m := rubricWeightHeading.FindStringSubmatch(criterion.Name); m != nil {
+				weight, err := strconv.ParseFloat(m[2], 64)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: bad weight %q", line, m[2])
+				}
+				criterion.Name, criterion.Weight = m[1], weight
+			}
+			anchor = nil
+		case criterion != nil && rubricWeightLine.MatchString(text) && anchor == nil:
+			weight, err := strconv.ParseFloat(rubricWeightLine.FindStringSubmatch(text)[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad weight %q", line, text)
+			}
+			criterion.Weight = weight
+		case criterion != nil && !indented && rubricAnchor.MatchString(text):
+			m := rubricAnchor.FindStringSubmatch(text)
+			score, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad score %q", line, m[1])
+			}
+			criterion.Anchors = append(criterion.Anchors, ScoreAnchor{Score: score, Description: m[2]})
+			anchor = &criterion.Anchors[len(criterion.Anchors)-1]
+			inExample = false
+		case anchor != nil && indented && text != "":
+			if m := rubricExample.FindStringSubmatch(text); m != nil {
+				anchor.Example, inExample = m[1], true
+			} else if inExample {
+				anchor.Example += "\n" + text
+			} else {
+				anchor.Description += " " + text
+			}
+		default:
+			if text != "" {
+				anchor = nil
+			}
+			if anchor == nil {
+				description = append(description, raw)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rubric: %w", err)
+	}
+	flush()
+
+	if err := rubric.normalize(); err != nil {
+		return nil, err
+	}
+	return &rubric, nil
+}
+
+// normalize validates the rubric, defaults weights, and sorts anchors
+func (r *Rubric) normalize() error {
+	if len(r.Criteria) == 0 {
+		return fmt.Errorf("rubric needs at least one criterion")
+	}
+	seen := make(map[string]bool)
+	for i := range r.Criteria {
+		c := &r.Criteria[i]
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" {
+			return fmt.Errorf("criterion %d has no name", i+1)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("duplicate criterion %s", c.Name)
+		}
+		seen[c.Name] = true
+		if c.Weight < 0 {
+			return fmt.Errorf("criterion %s: negative weight", c.Name)
+		}
+		if c.Weight == 0 {
+			c.Weight = 1
+		}
+		for _, a := range c.Anchors {
+			if a.Score < 0 || a.Score > 1 {
+				return fmt.Errorf("criterion %s: anchor score %.2f out of range 0-1", c.Name, a.Score)
+			}
+		}
+		sort.SliceStable(c.Anchors, func(i, j int) bool { return c.Anchors[i].Score > c.Anchors[j].Score })
+	}
+	return nil
+}
+
+// EvaluationCriteria returns the rubric's criteria for an
+// EvaluatorOptimizer
+func (r *Rubric) EvaluationCriteria() []EvaluationCriterion {
+	criteria := make([]EvaluationCriterion, len(r.Criteria))
+	for i, c := range r.Criteria {
+		criteria[i] = EvaluationCriterion{Name: c.Name, Description: c.Description, Weight: c.Weight, Anchors: c.Anchors}
+	}
+	return criteria
+}
+
+// String renders the criteria, weights, and anchors as a list for a
+// judge's prompt
+func (r *Rubric) String() string {
+	var b strings.Builder
+	for i, c := range r.Criteria {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "- %s (weight: %.1f): %s", c.Name, c.Weight, c.Description)
+		for _, a := range c.Anchors {
+			fmt.Fprintf(&b, "\n  - %.1f: %s", a.Score, a.Description)
+			if a.Example != "" {
+				fmt.Fprintf(&b, " Example: %s", a.Example)
+			}
+		}
+	}
+	return b.String()
+}
+
+// WithRubric adds the rubric's criteria to those an EvaluatorOptimizer
+// evaluates the output against
+func WithRubric(rubric *Rubric) Option {
+	return func(c *patternConfig) { c.criteria = append(c.criteria, rubric.EvaluationCriteria()...) }
+}
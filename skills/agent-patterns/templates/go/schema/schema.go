@@ -0,0 +1,216 @@
+/*
+ * JSON Schema Generation for Go Agent Patterns
+ * Schemas for tool parameters and structured LLM outputs derived from struct tags
+ */
+
+// Package schema generates JSON Schema from Go types, so tool parameters and
+// the JSON the patterns ask models to produce are declared once as structs.
+//
+// Field names come from the json tag; fields without omitempty are required.
+// Two more tags refine the schema:
+//
+//	description:"..."                          the property description
+//	jsonschema:"enum=a|b,minimum=0,maximum=1"  constraints, plus "required"
+//	                                           or "optional" to override omitempty
+//
+// Example:
+//
+//	type SearchArgs struct {
+//	    Query string `json:"query" description:"Search query"`
+//	    Limit int    `json:"limit,omitempty" description:"Maximum results" jsonschema:"minimum=1,maximum=50"`
+//	}
+//	s := schema.MustFor[SearchArgs]()
+//	fmt.Println(s) // {"type": "object", "properties": {...}, "required": ["query"]}
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema document. Only the keywords the patterns need are
+// supported.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// String returns the schema as indented JSON, ready to embed in a prompt
+func (s *Schema) String() string {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// For generates the schema of T
+func For[T any]() (*Schema, error) {
+	return Generate(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// MustFor is like For but panics on error. Use it for types known at
+// compile time.
+func MustFor[T any]() *Schema {
+	s, err := For[T]()
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Generate returns the schema of t. Each call returns a fresh schema, so
+// callers may adjust it, e.g. to fill in an enum known only at run time.
+func Generate(t reflect.Type) (*Schema, error) {
+	return generate(t, map[reflect.Type]bool{})
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func generate(t reflect.Type, visiting map[reflect.Type]bool) (*Schema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}, nil
+		}
+		items, err := generate(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("schema: map key %s is not a string", t.Key())
+		}
+		values, err := generate(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if visiting[t] {
+			return nil, fmt.Errorf("schema: recursive type %s", t)
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		if err := addFields(s, t, visiting); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("schema: unsupported type %s", t)
+}
+
+// addFields adds the properties of struct t to s, flattening embedded
+// structs the way encoding/json does
+func addFields(s *Schema, t reflect.Type, visiting map[reflect.Type]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := addFields(s, ft, visiting); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop, err := generate(field.Type, visiting)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		prop.Description = field.Tag.Get("description")
+
+		required := !strings.Contains(opts, "omitempty")
+		if err := applyConstraints(prop, field.Tag.Get("jsonschema"), &required); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		s.Properties[name] = prop
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return nil
+}
+
+// applyConstraints parses a jsonschema tag into prop
+func applyConstraints(prop *Schema, tag string, required *bool) error {
+	if tag == "" {
+		return nil
+	}
+	for _, item := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(item), "=")
+		switch key {
+		case "required":
+			*required = true
+		case "optional":
+			*required = false
+		case "enum":
+			prop.Enum = strings.Split(value, "|")
+		case "format":
+			prop.Format = value
+		case "minimum", "maximum":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q", key, value)
+			}
+			if key == "minimum" {
+				prop.Minimum = &n
+			} else {
+				prop.Maximum = &n
+			}
+		default:
+			return fmt.Errorf("unknown jsonschema tag %q", key)
+		}
+	}
+	return nil
+}
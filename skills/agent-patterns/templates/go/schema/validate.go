@@ -0,0 +1,135 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ValidationError lists the ways a value does not match a schema
+type ValidationError struct {
+	// Problems are prefixed with the path of the offending value, e.g.
+	// "$.categories[0].confidence: 1.4 is above the maximum 1"
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "schema: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks a value decoded by encoding/json into an interface{}
+// against the schema, returning a *ValidationError listing every problem.
+// Properties that are not required may be null.
+func (s *Schema) Validate(v interface{}) error {
+	var problems []string
+	s.validate("$", v, &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+func (s *Schema) validate(path string, v interface{}, problems *[]string) {
+	if s == nil {
+		return
+	}
+	add := func(format string, args ...interface{}) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			add("expected object, got %s", kindOf(v))
+			return
+		}
+		required := make(map[string]bool, len(s.Required))
+		for _, name := range s.Required {
+			required[name] = true
+			if _, ok := obj[name]; !ok {
+				add("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := obj[name]
+			if value == nil && !required[name] {
+				continue
+			}
+			if prop, ok := s.Properties[name]; ok {
+				prop.validate(path+"."+name, value, problems)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(path+"."+name, value, problems)
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			add("expected array, got %s", kindOf(v))
+			return
+		}
+		for i, item := range arr {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, problems)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			add("expected string, got %s", kindOf(v))
+			return
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			add("expected boolean, got %s", kindOf(v))
+			return
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			add("expected %s, got %s", s.Type, kindOf(v))
+			return
+		}
+		if s.Type == "integer" && n != math.Trunc(n) {
+			add("expected integer, got %v", n)
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			add("%v is below the minimum %v", n, *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			add("%v is above the maximum %v", n, *s.Maximum)
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		str, _ := v.(string)
+		for _, allowed := range s.Enum {
+			if str == allowed {
+				return
+			}
+		}
+		add("%v is not one of %s", v, strings.Join(s.Enum, ", "))
+	}
+}
+
+// kindOf names the JSON type of a decoded value
+func kindOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type category struct {
+	Name       string  `json:"name" jsonschema:"enum=billing|technical"`
+	Confidence float64 `json:"confidence" jsonschema:"minimum=0,maximum=1"`
+}
+
+type classification struct {
+	Categories []category        `json:"categories"`
+	Priority   int               `json:"priority" jsonschema:"minimum=1,maximum=5"`
+	Urgent     bool              `json:"urgent"`
+	Note       string            `json:"note,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+}
+
+func TestValidate(t *testing.T) {
+	s := MustFor[classification]()
+	tests := []struct {
+		name string
+		json string
+		want []string
+	}{
+		{
+			name: "valid",
+			json: `{"categories": [{"name": "billing", "confidence": 0.9}], "priority": 2, "urgent": false}`,
+		},
+		{
+			name: "optional properties may be null",
+			json: `{"categories": [], "priority": 1, "urgent": true, "note": null, "tags": null}`,
+		},
+		{
+			name: "not an object",
+			json: `"billing"`,
+			want: []string{"$: expected object, got string"},
+		},
+		{
+			name: "missing required properties",
+			json: `{"categories": []}`,
+			want: []string{
+				`$: missing required property "priority"`,
+				`$: missing required property "urgent"`,
+			},
+		},
+		{
+			name: "required property is null",
+			json: `{"categories": null, "priority": 1, "urgent": true}`,
+			want: []string{"$.categories: expected array, got null"},
+		},
+		{
+			name: "nested problems carry their path",
+			json: `{"categories": [{"name": "billing", "confidence": 0.5}, {"name": "sales", "confidence": 1.4}], "priority": 1, "urgent": true}`,
+			want: []string{
+				"$.categories[1].confidence: 1.4 is above the maximum 1",
+				"$.categories[1].name: sales is not one of billing, technical",
+			},
+		},
+		{
+			name: "integer bounds",
+			json: `{"categories": [], "priority": 0.5, "urgent": true}`,
+			want: []string{
+				"$.priority: expected integer, got 0.5",
+				"$.priority: 0.5 is below the minimum 1",
+			},
+		},
+		{
+			name: "wrong scalar types",
+			json: `{"categories": [], "priority": "high", "urgent": "yes", "note": 3}`,
+			want: []string{
+				"$.note: expected string, got number",
+				"$.priority: expected integer, got string",
+				"$.urgent: expected boolean, got string",
+			},
+		},
+		{
+			name: "map values",
+			json: `{"categories": [], "priority": 1, "urgent": true, "tags": {"team": "core", "size": 3}}`,
+			want: []string{"$.tags.size: expected string, got number"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			if err := json.Unmarshal([]byte(tt.json), &v); err != nil {
+				t.Fatal(err)
+			}
+			err := s.Validate(v)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate = %v, want nil", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate = %v, want a *ValidationError", err)
+			}
+			if !reflect.DeepEqual(verr.Problems, tt.want) {
+				t.Errorf("problems\n got %q\nwant %q", verr.Problems, tt.want)
+			}
+		})
+	}
+}
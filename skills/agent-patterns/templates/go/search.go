@@ -0,0 +1,414 @@
+/*
+ * Web Search for Go Agent Patterns
+ * Search providers (Brave, SerpAPI, Tavily) for agents and RAG, with deduplication and snippet extraction
+ */
+
+package agentpatterns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// SearchResult is one web search hit
+type SearchResult struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	// Snippet is plain text from the page, the passage most relevant to
+	// the query where the provider returns more than a summary
+	Snippet string `json:"snippet"`
+}
+
+// SearchProvider searches the web
+type SearchProvider interface {
+	Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error)
+}
+
+// searchArgs are the arguments of the web search tool
+type searchArgs struct {
+	Query string `json:"query" description:"The search query"`
+}
+
+// SearchTool returns an AgentTool named "web_search" that returns up to
+// maxResults deduplicated results from provider
+//
+// Example:
+//
+//	agent.RegisterTool(SearchTool(NewBraveSearch(os.Getenv("BRAVE_API_KEY")), 5))
+func SearchTool(provider SearchProvider, maxResults int) AgentTool {
+	return NewTool("web_search", "Search the web. Returns titles, URLs, and snippets of the top results.",
+		func(ctx context.Context, args searchArgs) (string, error) {
+			results, err := webSearch(ctx, provider, args.Query, maxResults)
+			if err != nil {
+				return "", err
+			}
+			return formatSearchResults(results), nil
+		})
+}
+
+// CombineSearch returns a provider that queries every provider in
+// parallel and interleaves their results, dropping duplicates. It fails
+// only if every provider fails.
+func CombineSearch(providers ...SearchProvider) SearchProvider {
+	return combinedSearch(providers)
+}
+
+type combinedSearch []SearchProvider
+
+// Search implements SearchProvider
+func (c combinedSearch) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	lists := make([][]SearchResult, len(c))
+	errs := make([]error, len(c))
+	var wg sync.WaitGroup
+	for i, provider := range c {
+		wg.Add(1)
+		go func(i int, provider SearchProvider) {
+			defer wg.Done()
+			lists[i], errs[i] = provider.Search(ctx, query, maxResults)
+		}(i, provider)
+	}
+	wg.Wait()
+
+	var merged []SearchResult
+	var failures []string
+	for rank := 0; ; rank++ {
+		more := false
+		for i, list := range lists {
+			if errs[i] != nil && rank == 0 {
+				failures = append(failures, errs[i].Error())
+			}
+			if rank < len(list) {
+				merged = append(merged, list[rank])
+				more = true
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	if len(failures) == len(c) && len(c) > 0 {
+		return nil, fmt.Errorf("every search provider failed: %s", strings.Join(failures, "; "))
+	}
+	merged = DedupeResults(merged)
+	if maxResults > 0 && len(merged) > maxResults {
+		merged = merged[:maxResults]
+	}
+	return merged, nil
+}
+
+// webSearch runs a search in a span and dedupes the results
+func webSearch(ctx context.Context, provider SearchProvider, query string, maxResults int) ([]SearchResult, error) {
+	if maxResults <= 0 {
+		maxResults = 5
+	}
+	ctx, span := StartSpan(ctx, "search")
+	results, err := provider.Search(ctx, query, maxResults)
+	if err != nil {
+		span.Finish(err)
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	results = DedupeResults(results)
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	span.SetAttribute("results", len(results))
+	span.Finish(nil)
+	return results, nil
+}
+
+// formatSearchResults numbers search results for a prompt
+func formatSearchResults(results []SearchResult) string {
+	if len(results) == 0 {
+		return "(no results found)"
+	}
+	var b strings.Builder
+	for i, r := range results {
+		fmt.Fprintf(&b, "[%d] %s\n%s\n%s\n\n", i+1, r.Title, r.URL, r.Snippet)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// DedupeResults drops results whose URL matches an earlier one once
+// normalized: scheme, "www.", fragments, tracking parameters, and trailing
+// slashes are ignored
+func DedupeResults(results []SearchResult) []SearchResult {
+	seen := make(map[string]bool, len(results))
+	kept := make([]SearchResult, 0, len(results))
+	for _, r := range results {
+		key := normalizeURL(r.URL)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// normalizeURL reduces a URL to the parts that identify the page
+func normalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return strings.ToLower(strings.TrimSpace(raw))
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(key, "utm_") || key == "gclid" || key == "fbclid" {
+			query.Del(key)
+		}
+	}
+	normalized := host + strings.TrimRight(u.EscapedPath(), "/")
+	if encoded := query.Encode(); encoded != "" {
+		normalized += "?" + encoded
+	}
+	return normalized
+}
+
+var (
+	htmlTag     = regexp.MustCompile(`<[^>]*>`)
+	sentenceEnd = regexp.MustCompile(`[.!?]["')\]]?\s+`)
+)
+
+// ExtractSnippet returns the passage of text most relevant to query, as
+// plain text of at most maxLen characters (300 if zero): the sentence
+// sharing the most words with the query, followed by the sentences after
+// it while they fit
+func ExtractSnippet(text, query string, maxLen int) string {
+	if maxLen <= 0 {
+		maxLen = 300
+	}
+	text = strings.Join(strings.Fields(html.UnescapeString(htmlTag.ReplaceAllString(text, " "))), " ")
+	if len([]rune(text)) <= maxLen {
+		return text
+	}
+
+	var sentences []string
+	start := 0
+	for _, loc := range sentenceEnd.FindAllStringIndex(text, -1) {
+		sentences = append(sentences, strings.TrimSpace(text[start:loc[1]]))
+		start = loc[1]
+	}
+	if start < len(text) {
+		sentences = append(sentences, strings.TrimSpace(text[start:]))
+	}
+
+	terms := make(map[string]bool)
+	for _, word := range searchTerms(query) {
+		terms[word] = true
+	}
+	best, bestScore := 0, -1
+	for i, sentence := range sentences {
+		score := 0
+		for _, word := range searchTerms(sentence) {
+			if terms[word] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	snippet := sentences[best]
+	for _, next := range sentences[best+1:] {
+		if len([]rune(snippet))+1+len([]rune(next)) > maxLen {
+			break
+		}
+		snippet += " " + next
+	}
+	if runes := []rune(snippet); len(runes) > maxLen {
+		cut := strings.LastIndexFunc(string(runes[:maxLen]), unicode.IsSpace)
+		if cut <= 0 {
+			cut = len(string(runes[:maxLen]))
+		}
+		snippet = string(runes[:maxLen])[:cut] + "..."
+	}
+	return snippet
+}
+
+// searchTerms lowercases text into words of three letters or more
+func searchTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) >= 3 {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// searchRequest sends a search request and decodes the JSON response
+func searchRequest(client *http.Client, req *http.Request, provider string, out interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s API error (status %d): %s", provider, resp.StatusCode, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// BraveSearch calls the Brave Search API
+type BraveSearch struct {
+	APIKey     string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewBraveSearch creates a Brave Search provider
+func NewBraveSearch(apiKey string) *BraveSearch {
+	return &BraveSearch{APIKey: apiKey, HTTPClient: &http.Client{}}
+}
+
+// Search implements SearchProvider
+func (b *BraveSearch) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	base := b.BaseURL
+	if base == "" {
+		base = "https://api.search.brave.com"
+	}
+	params := url.Values{"q": {query}, "count": {fmt.Sprint(maxResults)}}
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(base, "/")+"/res/v1/web/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Subscription-Token", b.APIKey)
+
+	var resp struct {
+		Web struct {
+			Results []struct {
+				Title         string   `json:"title"`
+				URL           string   `json:"url"`
+				Description   string   `json:"description"`
+				ExtraSnippets []string `json:"extra_snippets"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := searchRequest(b.HTTPClient, req, "Brave", &resp); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(resp.Web.Results))
+	for _, r := range resp.Web.Results {
+		text := strings.Join(append([]string{r.Description}, r.ExtraSnippets...), " ")
+		results = append(results, SearchResult{Title: ExtractSnippet(r.Title, "", 200), URL: r.URL, Snippet: ExtractSnippet(text, query, 0)})
+	}
+	return results, nil
+}
+
+// SerpAPISearch calls SerpAPI, which returns the results of Google and
+// other engines
+type SerpAPISearch struct {
+	APIKey string
+	// Engine is the SerpAPI engine, "google" by default
+	Engine     string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewSerpAPISearch creates a SerpAPI provider searching Google
+func NewSerpAPISearch(apiKey string) *SerpAPISearch {
+	return &SerpAPISearch{APIKey: apiKey, Engine: "google", HTTPClient: &http.Client{}}
+}
+
+// Search implements SearchProvider
+func (s *SerpAPISearch) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	base := s.BaseURL
+	if base == "" {
+		base = "https://serpapi.com"
+	}
+	params := url.Values{"q": {query}, "engine": {s.Engine}, "num": {fmt.Sprint(maxResults)}, "api_key": {s.APIKey}}
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(base, "/")+"/search.json?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	var resp struct {
+		Error          string `json:"error"`
+		OrganicResults []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"organic_results"`
+	}
+	if err := searchRequest(s.HTTPClient, req, "SerpAPI", &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("SerpAPI error: %s", resp.Error)
+	}
+	results := make([]SearchResult, 0, len(resp.OrganicResults))
+	for _, r := range resp.OrganicResults {
+		results = append(results, SearchResult{Title: r.Title, URL: r.Link, Snippet: ExtractSnippet(r.Snippet, query, 0)})
+	}
+	return results, nil
+}
+
+// TavilySearch calls the Tavily search API, built for LLM agents
+type TavilySearch struct {
+	APIKey string
+	// SearchDepth is "basic" (the default) or "advanced"
+	SearchDepth string
+	BaseURL     string
+	HTTPClient  *http.Client
+}
+
+// NewTavilySearch creates a Tavily provider using basic search depth
+func NewTavilySearch(apiKey string) *TavilySearch {
+	return &TavilySearch{APIKey: apiKey, SearchDepth: "basic", HTTPClient: &http.Client{}}
+}
+
+// Search implements SearchProvider
+func (t *TavilySearch) Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error) {
+	base := t.BaseURL
+	if base == "" {
+		base = "https://api.tavily.com"
+	}
+	body, err := json.Marshal(map[string]interface{}{"query": query, "max_results": maxResults, "search_depth": t.SearchDepth})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(base, "/")+"/search", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	req.Header.Set("content-type", "application/json")
+
+	var resp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := searchRequest(t.HTTPClient, req, "Tavily", &resp); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: ExtractSnippet(r.Content, query, 0)})
+	}
+	return results, nil
+}
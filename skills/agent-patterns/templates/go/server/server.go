@@ -0,0 +1,439 @@
+/*
+ * HTTP Service for Go Agent Patterns
+ * Serves routers, chains, orchestrators, and agents as REST endpoints with SSE streaming
+ */
+
+// Package server mounts configured patterns as HTTP endpoints, so they can
+// be deployed as a microservice.
+//
+//	POST /v1/patterns/{name}  run a pattern: {"input": "...", "run_id": "..."}
+//	GET  /v1/patterns         list the mounted patterns
+//	GET  /healthz             liveness: 200 while the process serves
+//	GET  /readyz              readiness: 503 once shutdown has begun
+//
+// A run answers with JSON holding its output, details, and cost. A client
+// sending "Accept: text/event-stream" instead receives server-sent events
+// as the run progresses: "progress" for each lifecycle event, "delta" for
+// generated text, then "result" or "error".
+//
+// Example:
+//
+//	srv := server.New(server.WithLogger(logger))
+//	srv.AddRouter("triage", router, 0.7)
+//	srv.AddChain("summarize", newSummaryChain)
+//	srv.AddAgent("investigate", newOpsAgent, 15)
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//	err := srv.ListenAndServe(ctx, ":8080")
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+)
+
+// maxRequestBody limits the size of run requests
+const maxRequestBody = 10 << 20
+
+// RunFunc runs a pattern on input, returning its output and any details
+// worth returning to the client, such as a classification
+type RunFunc func(ctx context.Context, input string) (output string, details interface{}, err error)
+
+// StringRouter routes text to a handler producing text, as Router[string]
+// and EmbeddingRouter[string] do
+type StringRouter interface {
+	Route(ctx context.Context, input string, confidenceThreshold float64) (string, *agentpatterns.ClassificationResult, error)
+}
+
+// Server serves patterns over HTTP. It implements http.Handler.
+type Server struct {
+	logger          *slog.Logger
+	shutdownTimeout time.Duration
+
+	mu        sync.RWMutex
+	endpoints map[string]RunFunc
+	order     []string
+	draining  bool
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithLogger sets the logger for requests and shutdown
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) { s.logger = logger }
+}
+
+// WithShutdownTimeout sets how long ListenAndServe waits for runs in
+// flight once its context is done (default 30s)
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) { s.shutdownTimeout = d }
+}
+
+// New creates a server with no endpoints
+func New(opts ...Option) *Server {
+	s := &Server{
+		logger:          slog.Default(),
+		shutdownTimeout: 30 * time.Second,
+		endpoints:       make(map[string]RunFunc),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add mounts a pattern at /v1/patterns/{name}, replacing any with the same
+// name
+func (s *Server) Add(name string, run RunFunc) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.endpoints[name]; !exists {
+		s.order = append(s.order, name)
+	}
+	s.endpoints[name] = run
+	return s
+}
+
+// AddChain mounts a chain that runs with its "input" context variable set
+// to the request's input. A chain keeps per-run history, so newChain
+// builds one for each request.
+func (s *Server) AddChain(name string, newChain func() *agentpatterns.PromptChain) *Server {
+	return s.Add(name, func(ctx context.Context, input string) (string, interface{}, error) {
+		chain := newChain()
+		output, err := chain.Execute(ctx, map[string]interface{}{"input": input})
+		return output, chain.History(), err
+	})
+}
+
+// AddRouter mounts a router. Inputs classified below confidenceThreshold
+// go to the router's fallback.
+func (s *Server) AddRouter(name string, router StringRouter, confidenceThreshold float64) *Server {
+	return s.Add(name, func(ctx context.Context, input string) (string, interface{}, error) {
+		output, classification, err := router.Route(ctx, input, confidenceThreshold)
+		return output, classification, err
+	})
+}
+
+// AddOrchestrator mounts an orchestrator that decomposes the request's
+// input as its task
+func (s *Server) AddOrchestrator(name string, orch *agentpatterns.Orchestrator) *Server {
+	return s.Add(name, func(ctx context.Context, task string) (string, interface{}, error) {
+		result, err := orch.Execute(ctx, task)
+		if err != nil {
+			return "", nil, err
+		}
+		return result.FinalResult, result, nil
+	})
+}
+
+// AddAgent mounts an agent that works on the request's input for at most
+// maxSteps. An agent keeps per-run state, so newAgent builds one for each
+// request.
+func (s *Server) AddAgent(name string, newAgent func() *agentpatterns.AutonomousAgent, maxSteps int) *Server {
+	return s.Add(name, func(ctx context.Context, task string) (string, interface{}, error) {
+		result, err := newAgent().Run(ctx, task, maxSteps)
+		if err != nil {
+			return "", nil, err
+		}
+		if !result.Success {
+			return "", result, fmt.Errorf("agent did not complete within %d steps", maxSteps)
+		}
+		return result.FinalResult, result, nil
+	})
+}
+
+// ListenAndServe serves on addr until ctx is done, then shuts down
+// gracefully: /readyz starts failing, new connections are refused, and
+// runs in flight get the shutdown timeout to finish.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, lis)
+}
+
+// Serve is ListenAndServe on an existing listener
+func (s *Server) Serve(ctx context.Context, lis net.Listener) error {
+	srv := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	errs := make(chan error, 1)
+	go func() { errs <- srv.Serve(lis) }()
+	s.logger.Info("serving patterns", "addr", lis.Addr().String())
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+	s.logger.Info("shutting down", "timeout", s.shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/healthz":
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	case r.URL.Path == "/readyz":
+		s.mu.RLock()
+		draining := s.draining
+		s.mu.RUnlock()
+		if draining {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "shutting down"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+	case r.URL.Path == "/v1/patterns":
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.mu.RLock()
+		names := append([]string{}, s.order...)
+		s.mu.RUnlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"patterns": names})
+	case strings.HasPrefix(r.URL.Path, "/v1/patterns/"):
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleRun(w, r, strings.TrimPrefix(r.URL.Path, "/v1/patterns/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// runRequest is the body of a run request
+type runRequest struct {
+	Input string `json:"input"`
+	RunID string `json:"run_id"`
+}
+
+// runResponse is the result of a run, returned as JSON or as the final
+// server-sent event
+type runResponse struct {
+	RunID     string                    `json:"run_id"`
+	Output    string                    `json:"output"`
+	Details   interface{}               `json:"details,omitempty"`
+	Cost      agentpatterns.CostSummary `json:"cost"`
+	ElapsedMS int64                     `json:"elapsed_ms"`
+}
+
+// errorResponse reports a failed run
+type errorResponse struct {
+	RunID string `json:"run_id,omitempty"`
+	Error string `json:"error"`
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request, name string) {
+	s.mu.RLock()
+	run, exists := s.endpoints[name]
+	s.mu.RUnlock()
+	if !exists {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: fmt.Sprintf("unknown pattern %q", name)})
+		return
+	}
+
+	var req runRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Input) == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "input is empty"})
+		return
+	}
+	if req.RunID == "" {
+		req.RunID = fmt.Sprintf("http_%d", time.Now().UnixNano())
+	}
+
+	ctx := agentpatterns.WithRunID(r.Context(), req.RunID)
+	costs := agentpatterns.NewCostTracker(agentpatterns.DefaultPricing())
+	ctx = agentpatterns.ContextWithCostTracker(ctx, costs)
+	logger := s.logger.With("pattern", name, "run_id", req.RunID)
+
+	flusher, canStream := w.(http.Flusher)
+	if canStream && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.stream(ctx, w, flusher, run, req, costs, logger)
+		return
+	}
+
+	start := time.Now()
+	output, details, err := run(ctx, req.Input)
+	if err != nil {
+		logger.Warn("run failed", "error", err)
+		writeJSON(w, statusCode(err), errorResponse{RunID: req.RunID, Error: err.Error()})
+		return
+	}
+	logger.Info("run finished", "elapsed", time.Since(start))
+	writeJSON(w, http.StatusOK, runResponse{
+		RunID:     req.RunID,
+		Output:    output,
+		Details:   details,
+		Cost:      costs.Total(),
+		ElapsedMS: time.Since(start).Milliseconds(),
+	})
+}
+
+// sseMessage is one server-sent event
+type sseMessage struct {
+	event string
+	data  interface{}
+}
+
+// progressEvent is the data of a "progress" event. Payload values are
+// formatted as text, since some, such as errors, do not encode as JSON.
+type progressEvent struct {
+	Pattern string            `json:"pattern"`
+	Phase   string            `json:"phase"`
+	SpanID  string            `json:"span_id,omitempty"`
+	Time    time.Time         `json:"time"`
+	Payload map[string]string `json:"payload,omitempty"`
+}
+
+func newProgressEvent(e agentpatterns.Event) progressEvent {
+	payload := make(map[string]string, len(e.Payload))
+	for k, v := range e.Payload {
+		if v != nil {
+			payload[k] = fmt.Sprint(v)
+		}
+	}
+	return progressEvent{Pattern: e.Pattern, Phase: e.Phase, SpanID: e.SpanID, Time: e.Time, Payload: payload}
+}
+
+// deltaEvent is the data of a "delta" event
+type deltaEvent struct {
+	Pattern string `json:"pattern"`
+	Text    string `json:"text"`
+}
+
+// stream runs the pattern, sending its events and generated text as
+// server-sent events. Events are published from pattern goroutines, so they
+// are funneled through a channel to the handler, the one goroutine allowed
+// to write the response.
+func (s *Server) stream(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, run RunFunc, req runRequest, costs *agentpatterns.CostTracker, logger *slog.Logger) {
+	messages := make(chan sseMessage, 64)
+	send := func(m sseMessage) {
+		select {
+		case messages <- m:
+		case <-ctx.Done():
+		}
+	}
+	bus := agentpatterns.NewEventBus()
+	bus.Subscribe(agentpatterns.EventSinkFunc(func(e agentpatterns.Event) {
+		send(sseMessage{"progress", newProgressEvent(e)})
+	}))
+	ctx = agentpatterns.ContextWithEventBus(ctx, bus)
+	ctx = agentpatterns.ContextWithStreaming(ctx, func(ctx context.Context, pattern, text string) {
+		send(sseMessage{"delta", deltaEvent{Pattern: pattern, Text: text}})
+	})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	type outcome struct {
+		output  string
+		details interface{}
+		err     error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		output, details, err := run(ctx, req.Input)
+		done <- outcome{output, details, err}
+	}()
+
+	write := func(m sseMessage) {
+		if err := writeEvent(w, m); err != nil {
+			logger.Debug("failed to write event", "error", err)
+		}
+		flusher.Flush()
+	}
+	for {
+		select {
+		case m := <-messages:
+			write(m)
+		case o := <-done:
+			// Flush events sent before the pattern returned
+			for drained := false; !drained; {
+				select {
+				case m := <-messages:
+					write(m)
+				default:
+					drained = true
+				}
+			}
+			if o.err != nil {
+				logger.Warn("run failed", "error", o.err)
+				write(sseMessage{"error", errorResponse{RunID: req.RunID, Error: o.err.Error()}})
+				return
+			}
+			logger.Info("run finished", "elapsed", time.Since(start))
+			write(sseMessage{"result", runResponse{
+				RunID:     req.RunID,
+				Output:    o.output,
+				Details:   o.details,
+				Cost:      costs.Total(),
+				ElapsedMS: time.Since(start).Milliseconds(),
+			}})
+			return
+		}
+	}
+}
+
+// writeEvent writes one server-sent event with JSON data
+func writeEvent(w io.Writer, m sseMessage) error {
+	data, err := json.Marshal(m.data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", m.event, data)
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// statusCode maps a pattern error to an HTTP status
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, agentpatterns.ErrBudgetExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, agentpatterns.ErrTaskUnrecoverable):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Persistence Layer for Go Agent Patterns
+ * Key/value storage for chain checkpoints, agent state, and run histories
+ */
+
+// Package store defines the Store interface used to persist run state, with
+// in-memory, filesystem, SQLite, Bolt, and S3 implementations.
+//
+// Keys are slash-separated paths such as "prompt_chain/run_1a2b3c". Values
+// are opaque bytes; the patterns store JSON.
+//
+// Example:
+//
+//	st, err := store.NewFileStore("./state")
+//	chain := agentpatterns.NewPromptChain(client, agentpatterns.WithStore(st))
+//	ctx = agentpatterns.WithRunID(ctx, "nightly-report")
+//	result, err := chain.Execute(ctx, input) // resumes if a checkpoint exists
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned by Get when a key does not exist
+var ErrNotFound = errors.New("store: key not found")
+
+// Store persists values by key. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, value []byte) error
+	Delete(ctx context.Context, key string) error
+	// List returns all keys with the given prefix in sorted order
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Memory is an in-memory Store, useful for tests
+type Memory struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{data: make(map[string][]byte)}
+}
+
+// Get implements Store
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+// Put implements Store
+func (m *Memory) Put(ctx context.Context, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete implements Store
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+// List implements Store
+func (m *Memory) List(ctx context.Context, prefix string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var keys []string
+	for key := range m.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
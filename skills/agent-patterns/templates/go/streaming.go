@@ -0,0 +1,194 @@
+/*
+ * Streaming for Go Agent Patterns
+ * Server-sent event responses from the Messages API, surfaced as text deltas while a call runs
+ */
+
+package agentpatterns
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/limiter"
+)
+
+// StreamFunc receives the text of a streamed response as it is generated.
+// pattern names the pattern making the call; the run ID is in ctx.
+// Parallel patterns stream several calls at once, so implementations must
+// be safe for concurrent use.
+type StreamFunc func(ctx context.Context, pattern, text string)
+
+// WithStreaming streams every LLM call of the pattern and passes the text
+// to fn as it arrives. The pattern still works with complete responses, so
+// results are unchanged; fn only sees output earlier.
+//
+// A call that is retried streams again from the start, and calls served
+// from a durable journal or replay bundle are not streamed at all.
+//
+// Example:
+//
+//	chain := NewPromptChain(client, WithStreaming(func(ctx context.Context, pattern, text string) {
+//	    fmt.Print(text)
+//	}))
+func WithStreaming(fn StreamFunc) Option {
+	return func(c *patternConfig) { c.stream = fn }
+}
+
+// CreateMessageStream sends a message with streaming enabled, passing each
+// text delta to onDelta as it arrives, and returns the full text and usage
+// once the response is complete
+func (c *AnthropicClient) CreateMessageStream(ctx context.Context, prompt, model string, maxTokens int, onDelta func(text string)) (string, Usage, error) {
+	return c.stream(ctx, MessageRequest{
+		Model:     model,
+		MaxTokens: maxTokens,
+		Messages: []MessageItem{
+			{Role: "user", Content: prompt},
+		},
+		Stream: true,
+	}, onDelta)
+}
+
+// CreateConversationMessageStream is CreateConversationMessage with
+// streaming enabled
+func (c *AnthropicClient) CreateConversationMessageStream(ctx context.Context, system string, messages []MessageItem, model string, maxTokens int, onDelta func(text string)) (string, Usage, error) {
+	return c.stream(ctx, MessageRequest{
+		Model:     model,
+		MaxTokens: maxTokens,
+		System:    system,
+		Messages:  messages,
+		Stream:    true,
+	}, onDelta)
+}
+
+// streamEvent is the subset of the streaming event payloads used here
+type streamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage Usage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		Thinking string `json:"thinking"`
+	} `json:"delta"`
+	Usage Usage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// stream sends a streaming request and reads its server-sent events until
+// message_stop
+func (c *AnthropicClient) stream(ctx context.Context, reqBody MessageRequest, onDelta func(text string)) (string, Usage, error) {
+	return c.cached(ctx, reqBody, onDelta, func() (string, Usage, error) {
+		if c.Provider != nil {
+			return c.sendVia(ctx, reqBody, onDelta)
+		}
+		resp, err := c.post(ctx, reqBody)
+		if err != nil {
+			return "", Usage{}, err
+		}
+		defer resp.Body.Close()
+		text, usage, err := readStream(ctx, resp.Body, onDelta)
+		c.settleTokens(limiter.ProviderAnthropic, reqBody, usage)
+		return text, usage, err
+	})
+}
+
+// readStream reads Messages API server-sent events until message_stop,
+// reporting any thinking to ctx
+func readStream(ctx context.Context, body io.Reader, onDelta func(text string)) (string, Usage, error) {
+	acc := streamAccumulator{ctx: ctx, onDelta: onDelta}
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// Only data lines matter: each carries its event type in the payload
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		done, err := acc.apply([]byte(strings.TrimSpace(line[len("data:"):])))
+		if done || err != nil {
+			return acc.text.String(), acc.usage, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return acc.text.String(), acc.usage, fmt.Errorf("failed to read stream: %w", err)
+	}
+	return acc.text.String(), acc.usage, fmt.Errorf("stream ended before message_stop")
+}
+
+// streamAccumulator builds a response from streaming events, however they
+// are framed. Thinking is reported to ctx once the message is complete.
+type streamAccumulator struct {
+	ctx      context.Context
+	text     strings.Builder
+	thinking strings.Builder
+	usage    Usage
+	onDelta  func(text string)
+}
+
+// apply handles one event, reporting whether the message is complete
+func (a *streamAccumulator) apply(data []byte) (bool, error) {
+	var event streamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return false, fmt.Errorf("failed to decode stream event: %w", err)
+	}
+
+	switch event.Type {
+	case "message_start":
+		a.usage = event.Message.Usage
+	case "content_block_delta":
+		if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+			a.text.WriteString(event.Delta.Text)
+			if a.onDelta != nil {
+				a.onDelta(event.Delta.Text)
+			}
+		}
+		if event.Delta.Type == "thinking_delta" {
+			a.thinking.WriteString(event.Delta.Thinking)
+		}
+	case "message_delta":
+		a.usage.OutputTokens = event.Usage.OutputTokens
+	case "message_stop":
+		reportThinking(a.ctx, a.thinking.String())
+		return true, nil
+	case "error":
+		// Overloaded errors mid-stream are reported with the status the
+		// API would have returned, so they are retried like any other
+		status := 500
+		if event.Error.Type == "overloaded_error" {
+			status = 529
+		}
+		return false, &APIError{StatusCode: status, Body: event.Error.Type + ": " + event.Error.Message}
+	}
+	return false, nil
+}
+
+// streamKey carries the StreamFunc set by ContextWithStreaming
+type streamKey struct{}
+
+// ContextWithStreaming streams every LLM call made under ctx to fn, as
+// WithStreaming does, for patterns built without it. WithStreaming takes
+// precedence.
+func ContextWithStreaming(ctx context.Context, fn StreamFunc) context.Context {
+	return context.WithValue(ctx, streamKey{}, fn)
+}
+
+// streamer returns the delta callback for a call made by the pattern, or
+// nil when streaming is off
+func (c *patternConfig) streamer(ctx context.Context) func(text string) {
+	stream := c.stream
+	if stream == nil {
+		stream, _ = ctx.Value(streamKey{}).(StreamFunc)
+	}
+	if stream == nil {
+		return nil
+	}
+	return func(text string) { stream(ctx, c.pattern, text) }
+}
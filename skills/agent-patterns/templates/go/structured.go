@@ -0,0 +1,125 @@
+/*
+ * Structured Outputs for Go Agent Patterns
+ * Typed JSON responses: schema in the prompt, prefilled reply, validation, and repair
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/jsonx"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/schema"
+)
+
+// structuredRetries is the number of further attempts made when a
+// response does not match the schema
+const structuredRetries = 2
+
+// StructuredOutput asks the model for a value of type T. The JSON Schema of
+// T is appended to the prompt and the reply is prefilled with the opening
+// bracket, so the model answers with JSON only. A response that does not
+// decode or does not match the schema is sent back with the problems found,
+// up to two more times.
+//
+// Example:
+//
+//	type Sentiment struct {
+//	    Label string  `json:"label" jsonschema:"enum=positive|negative|neutral"`
+//	    Score float64 `json:"score" jsonschema:"minimum=0,maximum=1"`
+//	}
+//	sentiment, err := StructuredOutput[Sentiment](ctx, client, "Classify the sentiment of: "+review)
+func StructuredOutput[T any](ctx context.Context, client *AnthropicClient, prompt string, opts ...Option) (T, error) {
+	cfg := newPatternConfig("structured_output", opts)
+	ctx, cancel := cfg.startRun(ctx)
+	defer cancel()
+
+	outputSchema, err := schema.For[T]()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return structured[T](ctx, &cfg, client, prompt, outputSchema, cfg.model, cfg.tokens(1024))
+}
+
+// structured requests a value of type T matching outputSchema, which may be
+// the schema of T adjusted at run time, e.g. with an enum of categories
+func structured[T any](ctx context.Context, cfg *patternConfig, client *AnthropicClient, prompt string, outputSchema *schema.Schema, model string, maxTokens int) (T, error) {
+	var zero T
+	// Extended thinking does not allow prefilling the reply
+	var prefill string
+	if cfg.request.ThinkingBudget == 0 {
+		prefill = "{"
+		if outputSchema.Type == "array" {
+			prefill = "["
+		}
+	}
+	messages := []MessageItem{
+		{Role: "user", Content: fmt.Sprintf("%s\n\nRespond with JSON matching this schema:\n%s", prompt, outputSchema)},
+	}
+	if prefill != "" {
+		messages = append(messages, MessageItem{Role: "assistant", Content: prefill})
+	}
+
+	for attempt := 0; ; attempt++ {
+		input, err := json.Marshal(messages)
+		if err != nil {
+			return zero, err
+		}
+		// The request is sent as-is, so copy the messages it refers to
+		request := append([]MessageItem(nil), messages...)
+		response, err := cfg.callWith(ctx, model, string(input), func(ctx context.Context) (string, Usage, error) {
+			req := MessageRequest{Model: model, MaxTokens: maxTokens, Messages: request}
+			cfg.request.apply(&req)
+			return client.Send(cfg.publishThinking(ctx, req), req)
+		})
+		if err != nil {
+			return zero, err
+		}
+
+		// A reply that repeats the prefill, as scripted mocks do, is used as-is
+		text := prefill + response
+		if trimmed := strings.TrimSpace(response); strings.HasPrefix(trimmed, prefill) && !json.Valid([]byte(prefill+trimmed)) {
+			text = trimmed
+		}
+		var result T
+		problem := decodeStructured(text, outputSchema, &result)
+		if problem == nil {
+			return result, nil
+		}
+		if attempt >= structuredRetries {
+			return zero, fmt.Errorf("invalid structured output: %w", problem)
+		}
+		cfg.logger.Warn("retrying invalid structured output", "attempt", attempt+1, "error", problem)
+		if prefill != "" {
+			messages = messages[:len(messages)-1]
+		}
+		messages = append(messages,
+			MessageItem{Role: "assistant", Content: text},
+			MessageItem{Role: "user", Content: fmt.Sprintf("That response is invalid: %v\n\nRespond again with only JSON matching the schema.", problem)},
+		)
+		if prefill != "" {
+			messages = append(messages, MessageItem{Role: "assistant", Content: prefill})
+		}
+	}
+}
+
+// decodeStructured decodes response into result after checking it against
+// outputSchema
+func decodeStructured(response string, outputSchema *schema.Schema, result interface{}) error {
+	var raw interface{}
+	if err := jsonx.Unmarshal(response, &raw); err != nil {
+		return err
+	}
+	if err := outputSchema.Validate(raw); err != nil {
+		return err
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
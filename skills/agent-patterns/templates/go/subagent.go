@@ -0,0 +1,118 @@
+/*
+ * Sub-Agents for Go Agent Patterns
+ * Agents registered as tools of other agents, forming hierarchical agent trees
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// agentDepthKey carries how many sub-agents deep a run is
+type agentDepthKey struct{}
+
+// runningAgentsKey carries the agents a run is nested in, outermost first
+type runningAgentsKey struct{}
+
+// withRunningAgent records that a is running in ctx
+func withRunningAgent(ctx context.Context, a *AutonomousAgent) context.Context {
+	agents, _ := ctx.Value(runningAgentsKey{}).([]*AutonomousAgent)
+	return context.WithValue(ctx, runningAgentsKey{}, append(agents[:len(agents):len(agents)], a))
+}
+
+// agentRunning reports whether a is running in ctx, as the run itself or
+// an agent that delegated to it
+func agentRunning(ctx context.Context, a *AutonomousAgent) bool {
+	agents, _ := ctx.Value(runningAgentsKey{}).([]*AutonomousAgent)
+	for _, running := range agents {
+		if running == a {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSubAgentLimits sets how AsTool runs the agent: at most maxSteps steps
+// per delegated task (default 10), and at most maxDepth sub-agents below
+// the top-level agent (default 3) (builder pattern)
+func (a *AutonomousAgent) SetSubAgentLimits(maxSteps, maxDepth int) *AutonomousAgent {
+	a.subAgentSteps = maxSteps
+	a.subAgentDepth = maxDepth
+	return a
+}
+
+// AsTool wraps the agent as a tool another agent can delegate tasks to.
+// The sub-agent starts each task afresh and draws from its own budget, set
+// with WithBudget, within its caller's: its calls count against both, and
+// running out of its own budget fails only the tool call. A call that would
+// nest agents deeper than the sub-agent's depth limit fails without
+// running, as does a call to an agent already running in the chain of
+// delegation, such as an agent registered as its own tool. Calls to one
+// sub-agent from concurrent runs take turns, whichever of its tools they
+// go through, and a call waiting for its turn gives up when ctx is done.
+//
+// Example:
+//
+//	researcher := NewAutonomousAgent(client, WithBudget(Budget{MaxCalls: 20})).
+//	    RegisterTool(searchTool)
+//	lead := NewAutonomousAgent(client).
+//	    RegisterTool(researcher.AsTool("researcher", "Research a question and report findings"))
+//	result, err := lead.Run(ctx, "Write a briefing on solid-state batteries", 15)
+func (a *AutonomousAgent) AsTool(name, description string) AgentTool {
+	return AgentTool{
+		Name:        name,
+		Description: description,
+		Parameters: map[string]ParameterDef{
+			"task": {Type: "string", Description: "The task to delegate, with any context it needs", Required: true},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			task, _ := args["task"].(string)
+			if strings.TrimSpace(task) == "" {
+				return "", fmt.Errorf("task is required")
+			}
+
+			maxSteps, maxDepth := a.subAgentSteps, a.subAgentDepth
+			if maxSteps <= 0 {
+				maxSteps = 10
+			}
+			if maxDepth <= 0 {
+				maxDepth = 3
+			}
+			if agentRunning(ctx, a) {
+				return "", fmt.Errorf("sub-agent %s not run: it is already running in this chain of delegation", name)
+			}
+			depth, _ := ctx.Value(agentDepthKey{}).(int)
+			depth++
+			if depth > maxDepth {
+				return "", fmt.Errorf("sub-agent %s not run: agents may nest at most %d deep", name, maxDepth)
+			}
+
+			select {
+			case a.busy <- struct{}{}:
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+			defer func() { <-a.busy }()
+
+			// Checkpoints are kept per task, so a resumed run picks up a
+			// delegated task where it stopped
+			sum := sha256.Sum256([]byte(task))
+			ctx = withCheckpointScope(ctx, name+"-"+hex.EncodeToString(sum[:6]))
+			ctx = context.WithValue(ctx, agentDepthKey{}, depth)
+			ctx = context.WithValue(ctx, nestBudgetKey{}, true)
+			result, err := a.Run(ctx, task, maxSteps)
+			if err != nil {
+				return "", err
+			}
+			if !result.Success {
+				return "", fmt.Errorf("sub-agent %s did not complete the task within %d steps", name, maxSteps)
+			}
+			return result.FinalResult, nil
+		},
+	}
+}
@@ -0,0 +1,230 @@
+/*
+ * Subtask Cache for Go Agent Patterns
+ * Reusing the results of identical or near-identical orchestrator subtasks
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/vectorstore"
+)
+
+// subtaskCache reuses subtask results. Keys hash a subtask's content, not
+// its ID, so the same work decomposed under another ID in another run is
+// still a hit.
+type subtaskCache struct {
+	cache Cache
+	ttl   time.Duration
+
+	// Similarity matching, when an embedder is set
+	embedder  Embedder
+	threshold float64
+
+	mu       sync.Mutex
+	inflight map[string]*subtaskFlight
+	index    []subtaskEmbedding
+}
+
+// subtaskFlight is a subtask being computed, which identical subtasks
+// wait for instead of computing it again
+type subtaskFlight struct {
+	done   chan struct{}
+	result string
+	err    error
+}
+
+// subtaskEmbedding indexes a cached result by its description's embedding.
+// Only subtasks of the same worker type with the same dependency results
+// are compared.
+type subtaskEmbedding struct {
+	scope  string
+	vector []float32
+	key    string
+}
+
+// subtaskCacheEntry is a cached subtask result
+type subtaskCacheEntry struct {
+	Result string `json:"result"`
+}
+
+// WithSubtaskCache caches successful subtask results in cache for ttl
+// (zero keeps them until the cache evicts them). Subtasks are keyed by
+// their worker type, description, and dependency results, ignoring case,
+// whitespace, and subtask IDs, so identical subtasks are computed once
+// whether they come from the same run or another. Identical subtasks
+// running at the same time wait for the first instead of duplicating it.
+func (o *Orchestrator) WithSubtaskCache(cache Cache, ttl time.Duration) *Orchestrator {
+	if o.cache == nil {
+		o.cache = &subtaskCache{inflight: make(map[string]*subtaskFlight)}
+	}
+	o.cache.cache = cache
+	o.cache.ttl = ttl
+	return o
+}
+
+// WithSubtaskSimilarity also serves a subtask from the cache when its
+// description's embedding has a cosine similarity of at least threshold
+// with that of a cached subtask of the same worker type and dependency
+// results. Embeddings of descriptions are kept in memory, so similar
+// matches are found among the subtasks this orchestrator has run; exact
+// matches work across processes when the cache is shared. Thresholds
+// around 0.95 catch rewordings without merging different work. It takes
+// effect with WithSubtaskCache.
+func (o *Orchestrator) WithSubtaskSimilarity(embedder Embedder, threshold float64) *Orchestrator {
+	if o.cache == nil {
+		o.cache = &subtaskCache{inflight: make(map[string]*subtaskFlight)}
+	}
+	o.cache.embedder = embedder
+	o.cache.threshold = threshold
+	return o
+}
+
+// cachedSubtask serves subtask from the cache, or runs it with run and
+// caches its result if it succeeds
+func (o *Orchestrator) cachedSubtask(ctx context.Context, subtask *OrchestratorSubtask, depResults map[string]string, run func() (string, error)) (string, error) {
+	c := o.cache
+	if c == nil || c.cache == nil {
+		return run()
+	}
+	scope := subtaskScope(subtask.WorkerType, depResults)
+	key := subtaskKey(scope, subtask.Description)
+
+	if result, ok := c.get(ctx, key); ok {
+		o.publishSubtaskHit(ctx, subtask, 1)
+		return result, nil
+	}
+
+	c.mu.Lock()
+	if flight, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-flight.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if flight.err == nil {
+			o.publishSubtaskHit(ctx, subtask, 1)
+			return flight.result, nil
+		}
+		// The first attempt failed, so this one tries for itself
+		return run()
+	}
+	flight := &subtaskFlight{done: make(chan struct{})}
+	c.inflight[key] = flight
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		c.mu.Unlock()
+		close(flight.done)
+	}()
+
+	var vector []float32
+	if c.embedder != nil {
+		// An embedding failure only loses similarity matching
+		if v, err := c.embedder.EmbedQuery(ctx, normalizeSubtask(subtask.Description)); err == nil {
+			vector = v
+			if result, similarity, ok := c.similar(ctx, scope, vector); ok {
+				o.publishSubtaskHit(ctx, subtask, similarity)
+				flight.result = result
+				return result, nil
+			}
+		}
+	}
+
+	flight.result, flight.err = run()
+	if flight.err != nil {
+		return flight.result, flight.err
+	}
+	if data, err := json.Marshal(subtaskCacheEntry{Result: flight.result}); err == nil {
+		c.cache.Set(ctx, key, data, c.ttl)
+	}
+	if vector != nil {
+		c.mu.Lock()
+		c.index = append(c.index, subtaskEmbedding{scope: scope, vector: vector, key: key})
+		c.mu.Unlock()
+	}
+	return flight.result, nil
+}
+
+// get returns the cached result under key
+func (c *subtaskCache) get(ctx context.Context, key string) (string, bool) {
+	data, ok, err := c.cache.Get(ctx, key)
+	if err != nil || !ok {
+		return "", false
+	}
+	var hit subtaskCacheEntry
+	if json.Unmarshal(data, &hit) != nil {
+		return "", false
+	}
+	return hit.Result, true
+}
+
+// similar returns the cached result of the most similar subtask in scope,
+// if any reaches the threshold and is still cached
+func (c *subtaskCache) similar(ctx context.Context, scope string, vector []float32) (string, float64, bool) {
+	c.mu.Lock()
+	var best subtaskEmbedding
+	bestScore := -1.0
+	for _, entry := range c.index {
+		if entry.scope != scope {
+			continue
+		}
+		if score := vectorstore.Cosine(vector, entry.vector); score > bestScore {
+			best, bestScore = entry, score
+		}
+	}
+	c.mu.Unlock()
+	if bestScore < c.threshold {
+		return "", 0, false
+	}
+	result, ok := c.get(ctx, best.key)
+	return result, bestScore, ok
+}
+
+// publishSubtaskHit reports a subtask served from the cache
+func (o *Orchestrator) publishSubtaskHit(ctx context.Context, subtask *OrchestratorSubtask, similarity float64) {
+	o.cfg.publish(ctx, PhaseCacheHit, map[string]interface{}{
+		"subtask":     subtask.ID,
+		"worker_type": subtask.WorkerType,
+		"similarity":  similarity,
+	})
+}
+
+// subtaskScope hashes what besides its description a subtask's result
+// depends on: its worker type and its dependencies' results, in order of
+// the results rather than of the IDs, which differ between runs
+func subtaskScope(workerType string, depResults map[string]string) string {
+	results := make([]string, 0, len(depResults))
+	for _, result := range depResults {
+		results = append(results, result)
+	}
+	sort.Strings(results)
+	data, _ := json.Marshal(struct {
+		WorkerType string   `json:"worker_type"`
+		Deps       []string `json:"deps"`
+	}{workerType, results})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// subtaskKey returns the cache key of a description within a scope
+func subtaskKey(scope, description string) string {
+	sum := sha256.Sum256([]byte(scope + "\n" + normalizeSubtask(description)))
+	return "subtask/" + hex.EncodeToString(sum[:])
+}
+
+// normalizeSubtask lower-cases a description and collapses its whitespace
+func normalizeSubtask(description string) string {
+	return strings.Join(strings.Fields(strings.ToLower(description)), " ")
+}
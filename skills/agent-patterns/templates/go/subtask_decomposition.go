@@ -0,0 +1,163 @@
+/*
+ * Recursive Decomposition for Go Agent Patterns
+ * Breaking subtasks too large for one worker down further, to a maximum depth
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SubtaskTrace records one subtask of an orchestration at any depth
+type SubtaskTrace struct {
+	// ID is the subtask's path from the top level, e.g. "chapter_3/section_2"
+	ID string
+	// ParentID is the ID of the subtask this one was decomposed from, or ""
+	// at the top level
+	ParentID    string
+	Depth       int
+	WorkerType  string
+	Description string
+	// Decomposed is set when the subtask was broken down and its subtasks'
+	// results synthesized, rather than given to a worker
+	Decomposed bool
+	Success    bool
+	Error      string
+	Duration   time.Duration
+}
+
+// WithMaxDepth lets the orchestrator decompose subtasks it judges too large
+// for one worker, such as the chapters of a long report, up to depth levels
+// below the task. A decomposed subtask's subtasks run like the task's, and
+// their results are synthesized into its result. A subtask repeating a task
+// it was decomposed from is given to a worker instead, so decomposition
+// cannot go round in circles. Failed subtasks below the top level are
+// synthesized around, or stop the run with SynthesisFailFast; re-planning
+// and SynthesisRetryFailed apply to the top level only. Zero, the default,
+// does not decompose subtasks.
+func (o *Orchestrator) WithMaxDepth(depth int) *Orchestrator {
+	o.maxDepth = depth
+	return o
+}
+
+// subtaskLevel is a level of a recursive decomposition
+type subtaskLevel struct {
+	depth int
+	// parent is the ID of the subtask decomposed into this level
+	parent string
+	// tasks holds the normalized task and subtasks enclosing this level,
+	// outermost first
+	tasks []string
+	trace *subtaskTraceLog
+}
+
+// subtaskTraceLog collects the trace of a run's subtasks
+type subtaskTraceLog struct {
+	mu      sync.Mutex
+	entries []SubtaskTrace
+}
+
+// topLevel returns the level of the subtasks of task
+func topLevel(task string) subtaskLevel {
+	return subtaskLevel{tasks: []string{normalizeSubtask(task)}, trace: &subtaskTraceLog{}}
+}
+
+// id returns the path ID of a subtask at this level
+func (l subtaskLevel) id(subtaskID string) string {
+	if l.parent == "" {
+		return subtaskID
+	}
+	return l.parent + "/" + subtaskID
+}
+
+// child returns the level subtask is decomposed into
+func (l subtaskLevel) child(subtask *OrchestratorSubtask) subtaskLevel {
+	tasks := append(append([]string(nil), l.tasks...), normalizeSubtask(subtask.Description))
+	return subtaskLevel{depth: l.depth + 1, parent: l.id(subtask.ID), tasks: tasks, trace: l.trace}
+}
+
+// repeats reports whether description repeats a task enclosing this level
+func (l subtaskLevel) repeats(description string) bool {
+	normalized := normalizeSubtask(description)
+	for _, task := range l.tasks {
+		if task == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// record adds a finished subtask to the trace
+func (l subtaskLevel) record(subtask *OrchestratorSubtask, decomposed bool, err error, duration time.Duration) {
+	entry := SubtaskTrace{
+		ID:          l.id(subtask.ID),
+		ParentID:    l.parent,
+		Depth:       l.depth,
+		WorkerType:  subtask.WorkerType,
+		Description: subtask.Description,
+		Decomposed:  decomposed,
+		Success:     err == nil,
+		Duration:    duration,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	l.trace.mu.Lock()
+	l.trace.entries = append(l.trace.entries, entry)
+	l.trace.mu.Unlock()
+}
+
+// list returns a copy of the trace so far
+func (t *subtaskTraceLog) list() []SubtaskTrace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]SubtaskTrace(nil), t.entries...)
+}
+
+// runDecomposed breaks subtask down, runs its subtasks, and synthesizes
+// their results into its result
+func (o *Orchestrator) runDecomposed(ctx context.Context, subtask *OrchestratorSubtask, depResults map[string]string, level subtaskLevel) (string, error) {
+	child := level.child(subtask)
+	contextInfo, err := o.quoteDependencies(ctx, depResults)
+	if err != nil {
+		return "", err
+	}
+	subtasks, err := o.decomposeTask(ctx, subtask.Description, contextInfo, child.depth < o.maxDepth)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompose subtask: %w", err)
+	}
+	o.cfg.publish(ctx, PhaseDecomposed, map[string]interface{}{
+		"subtasks": len(subtasks),
+		"parent":   child.parent,
+		"depth":    child.depth,
+	})
+
+	sortedSubtasks, err := o.topologicalSort(subtasks)
+	if err != nil {
+		return "", err
+	}
+	results := make(map[string]string)
+	var workerResults []WorkerResult
+	wf := o.workflow(sortedSubtasks, nil, child).OnNodeFinished(func(ctx context.Context, r NodeResult) error {
+		if r.Err != nil {
+			if o.synthesis == SynthesisFailFast {
+				return fmt.Errorf("%w: %s: %v", ErrSubtaskFailed, child.id(r.ID), r.Err)
+			}
+			workerResults = append(workerResults, WorkerResult{SubtaskID: r.ID, Error: r.Err.Error()})
+			return nil
+		}
+		results[r.ID], _ = r.Output.(string)
+		return nil
+	})
+	if _, err := wf.run(ctx, nil, nil); err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "", fmt.Errorf("none of the %d subtasks of %s succeeded", len(subtasks), child.parent)
+	}
+	return o.synthesizeResults(ctx, subtask.Description, results, subtaskGaps(subtasks, results, workerResults))
+}
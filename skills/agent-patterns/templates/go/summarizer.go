@@ -0,0 +1,32 @@
+/*
+ * Conversation Summarizer for Go Agent Patterns
+ * LLM-backed summaries of messages that fall out of a conversation window
+ */
+
+package agentpatterns
+
+import (
+	"context"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/conversation"
+)
+
+// NewSummarizer returns a conversation.Summarizer that folds dropped
+// messages into a running summary with one LLM call. Use a cheap model.
+//
+// Example:
+//
+//	agent.SetHistoryWindow(
+//	    conversation.WithMaxMessages(20),
+//	    conversation.WithSummarizer(NewSummarizer(client, WithModel("claude-3-haiku-20240307"))),
+//	)
+func NewSummarizer(client *AnthropicClient, opts ...Option) conversation.Summarizer {
+	cfg := newPatternConfig("summarizer", opts)
+	return conversation.SummarizerFunc(func(ctx context.Context, previous string, dropped []conversation.Message) (string, error) {
+		prompt, err := cfg.prompt("summarizer.update", map[string]interface{}{"previous": previous, "messages": dropped})
+		if err != nil {
+			return "", err
+		}
+		return cfg.call(ctx, client, prompt, cfg.model, cfg.tokens(1024))
+	})
+}
@@ -0,0 +1,89 @@
+/*
+ * Extended Thinking for Go Agent Patterns
+ * Thinking budgets on requests, and the model's reasoning kept apart from its answer
+ */
+
+package agentpatterns
+
+import (
+	"context"
+)
+
+// MinThinkingBudget is the smallest thinking budget the API accepts
+const MinThinkingBudget = 1024
+
+// ThinkingConfig enables extended thinking on a request. BudgetTokens is
+// part of, and must be less than, the request's MaxTokens.
+type ThinkingConfig struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
+}
+
+// Thinking enables extended thinking with a budget of tokens
+func Thinking(budget int) *ThinkingConfig {
+	if budget < MinThinkingBudget {
+		budget = MinThinkingBudget
+	}
+	return &ThinkingConfig{Type: "enabled", BudgetTokens: budget}
+}
+
+// Reply is a response with the model's thinking kept apart from its text
+type Reply struct {
+	Text string
+	// Thinking is the model's reasoning before it answered, empty unless
+	// the request enabled extended thinking
+	Thinking string
+	Usage    Usage
+}
+
+// SendReply is Send for a request that may enable extended thinking. Cached
+// replies have no thinking.
+//
+// Example:
+//
+//	reply, err := client.SendReply(ctx, MessageRequest{
+//	    Model:     DefaultModel,
+//	    MaxTokens: 16000,
+//	    Thinking:  Thinking(10000),
+//	    Messages:  []MessageItem{{Role: "user", Content: "Is 2^61 - 1 prime?"}},
+//	})
+//	fmt.Println(reply.Thinking)
+//	fmt.Println(reply.Text)
+func (c *AnthropicClient) SendReply(ctx context.Context, req MessageRequest) (*Reply, error) {
+	reply := &Reply{}
+	ctx = onThinking(ctx, func(thinking string) { reply.Thinking += thinking })
+	var err error
+	reply.Text, reply.Usage, err = c.Send(ctx, req)
+	return reply, err
+}
+
+// WithThinking enables extended thinking with a budget of tokens for every
+// call the pattern makes; see RequestOptions.ThinkingBudget
+func WithThinking(budget int) Option {
+	return func(c *patternConfig) { c.request.ThinkingBudget = budget }
+}
+
+// thinkingKey carries the function receiving the thinking of responses
+type thinkingKey struct{}
+
+// onThinking returns a context whose responses pass their thinking to fn,
+// as well as to any function set by an enclosing context
+func onThinking(ctx context.Context, fn func(thinking string)) context.Context {
+	parent, _ := ctx.Value(thinkingKey{}).(func(string))
+	return context.WithValue(ctx, thinkingKey{}, func(thinking string) {
+		fn(thinking)
+		if parent != nil {
+			parent(thinking)
+		}
+	})
+}
+
+// reportThinking passes a response's thinking to the context's receiver
+func reportThinking(ctx context.Context, thinking string) {
+	if thinking == "" {
+		return
+	}
+	if fn, ok := ctx.Value(thinkingKey{}).(func(string)); ok {
+		fn(thinking)
+	}
+}
@@ -0,0 +1,253 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+)
+
+// CodeLanguage describes how a CodeRunner runs one language: the image,
+// and a shell command that reads the code from standard input and runs it
+type CodeLanguage struct {
+	Image   string
+	Command string
+	// Env is set in the container, as KEY=value pairs
+	Env []string
+}
+
+// DefaultCodeLanguages are the languages a new CodeRunner runs
+var DefaultCodeLanguages = map[string]CodeLanguage{
+	"python": {
+		Image:   "python:3.12-alpine",
+		Command: "cat > /tmp/main.py && python3 /tmp/main.py",
+	},
+	"go": {
+		Image:   "golang:1.22-alpine",
+		Command: "cat > /tmp/main.go && cd /tmp && go run main.go",
+		Env:     []string{"GOCACHE=/tmp/.cache", "GOPATH=/tmp/go", "CGO_ENABLED=0"},
+	},
+}
+
+// CodeResult is the outcome of running a snippet
+type CodeResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	// TimedOut is set when the snippet was killed at the time limit
+	TimedOut bool
+	Duration time.Duration
+}
+
+// CodeRunner runs model-written code in throwaway Docker containers: no
+// network, a read-only file system apart from a small /tmp, no
+// capabilities, an unprivileged user, and limits on memory, CPU,
+// processes, and time. Set Runtime to "runsc" to run under gVisor, which
+// isolates the container's system calls from the host kernel.
+type CodeRunner struct {
+	// Docker is the docker binary, "docker" by default
+	Docker string
+	// Runtime is the OCI runtime, e.g. "runsc"; empty uses Docker's default
+	Runtime   string
+	Languages map[string]CodeLanguage
+	// Memory is the memory limit in Docker's format, e.g. "256m"
+	Memory    string
+	CPUs      float64
+	PidsLimit int
+	Timeout   time.Duration
+	// Network allows network access; off by default
+	Network bool
+	// MaxOutput caps each of stdout and stderr; the rest is dropped
+	MaxOutput int
+}
+
+// NewCodeRunner creates a runner for DefaultCodeLanguages with 256MB of
+// memory, one CPU, 64 processes, and 60 seconds per run, without network
+func NewCodeRunner() *CodeRunner {
+	languages := make(map[string]CodeLanguage, len(DefaultCodeLanguages))
+	for name, lang := range DefaultCodeLanguages {
+		languages[name] = lang
+	}
+	return &CodeRunner{
+		Docker:    "docker",
+		Languages: languages,
+		Memory:    "256m",
+		CPUs:      1,
+		PidsLimit: 64,
+		Timeout:   60 * time.Second,
+		MaxOutput: 16 << 10,
+	}
+}
+
+// Tool returns the runner as an agent tool named "run_code"
+func (c *CodeRunner) Tool() agentpatterns.AgentTool {
+	languages := make([]string, 0, len(c.Languages))
+	for name := range c.Languages {
+		languages = append(languages, name)
+	}
+	sort.Strings(languages)
+	return agentpatterns.AgentTool{
+		Name: "run_code",
+		Description: fmt.Sprintf("Run a complete program in a sandbox without network access and return its exit code, stdout, and stderr. "+
+			"Languages: %s. Use it to check that code works before relying on it.", strings.Join(languages, ", ")),
+		Parameters: map[string]agentpatterns.ParameterDef{
+			"language": {Type: "string", Description: "One of: " + strings.Join(languages, ", "), Required: true},
+			"code":     {Type: "string", Description: "The complete program; Go code must be package main", Required: true},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			language, err := stringArg(args, "language")
+			if err != nil {
+				return "", err
+			}
+			code, err := stringArg(args, "code")
+			if err != nil {
+				return "", err
+			}
+			result, err := c.Run(ctx, language, code)
+			if err != nil {
+				return "", err
+			}
+			return result.String(), nil
+		},
+	}
+}
+
+// Run runs code in a new container. A program that fails or times out is
+// not an error; an error means the sandbox itself could not run it.
+func (c *CodeRunner) Run(ctx context.Context, language, code string) (*CodeResult, error) {
+	lang, ok := c.Languages[strings.ToLower(language)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %q", language)
+	}
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	name := "coderun-" + hex.EncodeToString(id)
+
+	runCtx := ctx
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(runCtx, c.docker(), c.args(name, lang)...)
+	cmd.Stdin = strings.NewReader(code)
+	stdout := &cappedBuffer{max: c.MaxOutput}
+	stderr := &cappedBuffer{max: c.MaxOutput}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	result := &CodeResult{Stdout: stdout.String(), Stderr: stderr.String(), Duration: time.Since(start)}
+	if runCtx.Err() != nil {
+		// Killing the docker client leaves the container running
+		killCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		_ = exec.CommandContext(killCtx, c.docker(), "rm", "-f", name).Run()
+		cancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		result.TimedOut = true
+		result.ExitCode = -1
+		return result, nil
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 125:
+		// docker run exits 125 when it cannot start the container
+		return nil, fmt.Errorf("sandbox failed: %s", strings.TrimSpace(result.Stderr))
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return nil, fmt.Errorf("sandbox failed: %w", err)
+	}
+	return result, nil
+}
+
+// args returns the docker run arguments for one snippet
+func (c *CodeRunner) args(name string, lang CodeLanguage) []string {
+	args := []string{"run", "--rm", "-i", "--name", name,
+		"--read-only", "--tmpfs", "/tmp:rw,exec,size=256m",
+		"--cap-drop", "ALL", "--security-opt", "no-new-privileges",
+		"--user", "65534:65534", "--workdir", "/tmp",
+		"--env", "HOME=/tmp",
+	}
+	if !c.Network {
+		args = append(args, "--network", "none")
+	}
+	if c.Runtime != "" {
+		args = append(args, "--runtime", c.Runtime)
+	}
+	if c.Memory != "" {
+		// Equal memory and swap limits leave no swap
+		args = append(args, "--memory", c.Memory, "--memory-swap", c.Memory)
+	}
+	if c.CPUs > 0 {
+		args = append(args, "--cpus", fmt.Sprint(c.CPUs))
+	}
+	if c.PidsLimit > 0 {
+		args = append(args, "--pids-limit", fmt.Sprint(c.PidsLimit))
+	}
+	for _, env := range lang.Env {
+		args = append(args, "--env", env)
+	}
+	return append(args, lang.Image, "sh", "-c", lang.Command)
+}
+
+// docker returns the docker binary
+func (c *CodeRunner) docker() string {
+	if c.Docker == "" {
+		return "docker"
+	}
+	return c.Docker
+}
+
+// String formats the result for the model
+func (r *CodeResult) String() string {
+	var b strings.Builder
+	if r.TimedOut {
+		fmt.Fprintf(&b, "timed out after %s\n", r.Duration.Round(time.Millisecond))
+	} else {
+		fmt.Fprintf(&b, "exit code %d\n", r.ExitCode)
+	}
+	fmt.Fprintf(&b, "stdout:\n%s\nstderr:\n%s", r.Stdout, r.Stderr)
+	return b.String()
+}
+
+// cappedBuffer keeps the first max bytes written to it and counts the rest
+type cappedBuffer struct {
+	buf     bytes.Buffer
+	max     int
+	dropped int
+}
+
+// Write implements io.Writer, never failing so the writer is not blocked
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	keep := len(p)
+	if b.max > 0 && b.buf.Len()+keep > b.max {
+		keep = b.max - b.buf.Len()
+	}
+	b.buf.Write(p[:keep])
+	b.dropped += len(p) - keep
+	return len(p), nil
+}
+
+// String returns what was kept, noting how much was dropped
+func (b *cappedBuffer) String() string {
+	if b.dropped > 0 {
+		return fmt.Sprintf("%s\n[... %d more bytes truncated]", b.buf.String(), b.dropped)
+	}
+	return b.buf.String()
+}
@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+)
+
+// ErrHostNotAllowed is returned when a fetch names a host off the allowlist
+var ErrHostNotAllowed = errors.New("host not allowed")
+
+// Fetcher fetches web pages over HTTP and HTTPS. Only hosts on the
+// allowlist can be fetched, redirects included. By default, addresses on
+// loopback, private, and link-local networks are refused even for allowed
+// hosts, so a hostname cannot be pointed at internal services.
+type Fetcher struct {
+	// AllowedHosts lists host names that may be fetched. "*.example.com"
+	// allows the subdomains of example.com.
+	AllowedHosts []string
+	// AllowPrivate permits loopback, private, and link-local addresses
+	AllowPrivate bool
+	// MaxBytes caps the body returned; the rest is cut off
+	MaxBytes int
+	Timeout  time.Duration
+	// UserAgent is sent with each request
+	UserAgent string
+}
+
+// NewFetcher creates a fetcher for the allowed hosts that returns at most
+// 100KB of a body and gives up after 30 seconds
+func NewFetcher(allowedHosts ...string) *Fetcher {
+	return &Fetcher{
+		AllowedHosts: allowedHosts,
+		MaxBytes:     100 << 10,
+		Timeout:      30 * time.Second,
+		UserAgent:    "agentpatterns-fetch/1.0",
+	}
+}
+
+// Tool returns the fetcher as an agent tool named "fetch"
+func (f *Fetcher) Tool() agentpatterns.AgentTool {
+	return agentpatterns.AgentTool{
+		Name:        "fetch",
+		Description: fmt.Sprintf("Fetch a web page by URL and return its text. Allowed hosts: %s.", strings.Join(f.AllowedHosts, ", ")),
+		Parameters: map[string]agentpatterns.ParameterDef{
+			"url": {Type: "string", Description: "The http or https URL to fetch", Required: true},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			rawURL, err := stringArg(args, "url")
+			if err != nil {
+				return "", err
+			}
+			return f.Fetch(ctx, rawURL)
+		},
+	}
+}
+
+// Fetch returns the body of a URL, failing on a status other than 2xx
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if err := f.check(u); err != nil {
+		return "", err
+	}
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+	resp, err := f.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// Read one byte more than the cap to tell whether the body was cut
+	max := f.MaxBytes
+	if max <= 0 {
+		max = 100 << 10
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(max)+1))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("fetch %s: status %d: %s", u, resp.StatusCode, truncate(string(body), 500))
+	}
+	text := string(body)
+	if len(body) > max {
+		text = text[:max] + "\n[... truncated]"
+	}
+	return text, nil
+}
+
+// check refuses URLs that are not http or https, or whose host is not
+// allowed
+func (f *Fetcher) check(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("fetch %s: only http and https URLs can be fetched", u)
+	}
+	if HostAllowed(f.AllowedHosts, u.Hostname()) {
+		return nil
+	}
+	return fmt.Errorf("fetch %s: %w: %s", u, ErrHostNotAllowed, u.Hostname())
+}
+
+// HostAllowed reports whether host matches an allowlist of host names,
+// where "*.example.com" matches the subdomains of example.com
+func HostAllowed(allowed []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if host == pattern || (strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// client returns an HTTP client that checks redirects against the
+// allowlist and, unless AllowPrivate is set, refuses to connect to
+// internal addresses
+func (f *Fetcher) client() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !f.AllowPrivate {
+		// Checked at connect time, so DNS answers cannot change in between
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+				ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+				return fmt.Errorf("fetch: refusing to connect to internal address %s", host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("fetch: too many redirects")
+			}
+			return f.check(req.URL)
+		},
+	}
+}
@@ -0,0 +1,246 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+)
+
+// ErrOutsideRoot is returned for a path that leaves the root directory,
+// directly or through a symbolic link
+var ErrOutsideRoot = errors.New("path is outside the root directory")
+
+// FileSystem gives agents access to the files under a root directory.
+// Paths are relative to the root, and no path, symbolic links included,
+// reaches outside it. Writing is off unless Writable is set.
+type FileSystem struct {
+	root string
+	// Writable adds the write_file tool
+	Writable bool
+	// MaxBytes caps the size of a file read or written
+	MaxBytes int
+}
+
+// NewFileSystem creates a read-only file system rooted at root, which
+// must be an existing directory. Files larger than 100KB are refused.
+func NewFileSystem(root string) (*FileSystem, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	abs, err = filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	return &FileSystem{root: abs, MaxBytes: 100 << 10}, nil
+}
+
+// Root returns the absolute root directory
+func (fs *FileSystem) Root() string {
+	return fs.root
+}
+
+// Tools returns the read_file and list_files tools, and write_file if the
+// file system is writable
+func (fs *FileSystem) Tools() []agentpatterns.AgentTool {
+	tools := []agentpatterns.AgentTool{fs.ReadTool(), fs.ListTool()}
+	if fs.Writable {
+		tools = append(tools, fs.WriteTool())
+	}
+	return tools
+}
+
+// ReadTool returns a tool reading a text file
+func (fs *FileSystem) ReadTool() agentpatterns.AgentTool {
+	return agentpatterns.AgentTool{
+		Name:        "read_file",
+		Description: "Read a text file. Paths are relative to the workspace root.",
+		Parameters: map[string]agentpatterns.ParameterDef{
+			"path": {Type: "string", Description: "Path of the file to read", Required: true},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			path, err := stringArg(args, "path")
+			if err != nil {
+				return "", err
+			}
+			return fs.ReadFile(path)
+		},
+	}
+}
+
+// ListTool returns a tool listing a directory
+func (fs *FileSystem) ListTool() agentpatterns.AgentTool {
+	return agentpatterns.AgentTool{
+		Name:        "list_files",
+		Description: "List the files and directories in a directory. Paths are relative to the workspace root.",
+		Parameters: map[string]agentpatterns.ParameterDef{
+			"path": {Type: "string", Description: "Directory to list; \".\" for the root"},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			path, _ := args["path"].(string)
+			if path == "" {
+				path = "."
+			}
+			return fs.List(path)
+		},
+	}
+}
+
+// WriteTool returns a tool writing a text file, creating its directories
+func (fs *FileSystem) WriteTool() agentpatterns.AgentTool {
+	return agentpatterns.AgentTool{
+		Name:        "write_file",
+		Description: "Write a text file, replacing any existing content. Paths are relative to the workspace root.",
+		Parameters: map[string]agentpatterns.ParameterDef{
+			"path":    {Type: "string", Description: "Path of the file to write", Required: true},
+			"content": {Type: "string", Description: "The complete new content of the file", Required: true},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			path, err := stringArg(args, "path")
+			if err != nil {
+				return "", err
+			}
+			content, _ := args["content"].(string)
+			if err := fs.WriteFile(path, content); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Wrote %d bytes to %s", len(content), path), nil
+		},
+	}
+}
+
+// ReadFile returns the content of a file
+func (fs *FileSystem) ReadFile(path string) (string, error) {
+	full, err := fs.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(full)
+	if err != nil {
+		return "", fs.relErr(err)
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if fs.MaxBytes > 0 {
+		reader = io.LimitReader(file, int64(fs.MaxBytes)+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fs.relErr(err)
+	}
+	if fs.MaxBytes > 0 && len(data) > fs.MaxBytes {
+		return "", fmt.Errorf("%s is larger than %d bytes", path, fs.MaxBytes)
+	}
+	return string(data), nil
+}
+
+// List returns the entries of a directory, one per line, directories
+// marked with a trailing slash
+func (fs *FileSystem) List(path string) (string, error) {
+	full, err := fs.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(full)
+	if err != nil {
+		return "", fs.relErr(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return "(empty directory)", nil
+	}
+	return truncate(strings.Join(names, "\n"), fs.MaxBytes), nil
+}
+
+// WriteFile replaces the content of a file, creating it and its
+// directories as needed
+func (fs *FileSystem) WriteFile(path, content string) error {
+	if !fs.Writable {
+		return fmt.Errorf("the file system is read-only")
+	}
+	if fs.MaxBytes > 0 && len(content) > fs.MaxBytes {
+		return fmt.Errorf("content is larger than %d bytes", fs.MaxBytes)
+	}
+	full, err := fs.resolve(path)
+	if err != nil {
+		return err
+	}
+	if full == fs.root {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return fs.relErr(err)
+	}
+	// Resolve again now the directories exist, in case one is a link
+	if full, err = fs.resolve(path); err != nil {
+		return err
+	}
+	return fs.relErr(os.WriteFile(full, []byte(content), 0o644))
+}
+
+// resolve maps a path relative to the root to an absolute path, following
+// symbolic links as far as they exist, and fails if it leaves the root
+func (fs *FileSystem) resolve(path string) (string, error) {
+	full := filepath.Join(fs.root, filepath.Clean(string(filepath.Separator)+path))
+
+	// Resolve the longest existing prefix; the rest does not exist yet
+	existing, rest := full, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			full = filepath.Join(resolved, rest)
+			break
+		}
+		if !os.IsNotExist(err) {
+			return "", fs.relErr(err)
+		}
+		// A dangling link could be followed anywhere once written through
+		if _, lerr := os.Lstat(existing); lerr == nil {
+			return "", fmt.Errorf("%s: %w", path, ErrOutsideRoot)
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = filepath.Dir(existing)
+	}
+
+	if full != fs.root && !strings.HasPrefix(full, fs.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s: %w", path, ErrOutsideRoot)
+	}
+	return full, nil
+}
+
+// relErr rewrites the root out of file errors, so the model only sees
+// paths relative to it
+func (fs *FileSystem) relErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		if rel, relErr := filepath.Rel(fs.root, pathErr.Path); relErr == nil {
+			return fmt.Errorf("%s %s: %w", pathErr.Op, rel, pathErr.Err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// jail creates a root holding docs/readme.md, with a sibling directory
+// outside it holding secret.txt, and links under the root pointing at both
+func jail(t *testing.T) *FileSystem {
+	t.Helper()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{filepath.Join(root, "docs"), outside} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(root, "docs", "readme.md"): "inside",
+		filepath.Join(outside, "secret.txt"):     "secret",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"escape":       outside,
+		"secret":       filepath.Join(outside, "secret.txt"),
+		"relative":     "../outside",
+		"docs-link":    "docs",
+		"dangling":     filepath.Join(outside, "missing.txt"),
+		"dangling-dir": filepath.Join(outside, "missing"),
+		"dangling-in":  "docs/missing.md",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("symbolic links unsupported: %v", err)
+		}
+	}
+	fs, err := NewFileSystem(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.Writable = true
+	return fs
+}
+
+func TestResolve(t *testing.T) {
+	fs := jail(t)
+	tests := []struct {
+		path string
+		want string // relative to the root; empty when outside it
+	}{
+		{"docs/readme.md", "docs/readme.md"},
+		{"", "."},
+		{".", "."},
+		{"new/file.txt", "new/file.txt"},
+		// .. cannot climb above the root
+		{"..", "."},
+		{"../outside/secret.txt", "outside/secret.txt"},
+		{"docs/../../../outside", "outside"},
+		{"docs/../docs/readme.md", "docs/readme.md"},
+		// Absolute paths are taken relative to the root
+		{"/", "."},
+		{"/etc/passwd", "etc/passwd"},
+		{filepath.Join(fs.Root(), "..", "outside"), strings.TrimPrefix(filepath.Dir(fs.Root()), "/") + "/outside"},
+		// Links are followed, and must land inside the root
+		{"docs-link/readme.md", "docs/readme.md"},
+		{"escape", ""},
+		{"escape/secret.txt", ""},
+		{"escape/new.txt", ""},
+		{"secret", ""},
+		{"relative/secret.txt", ""},
+		// Dangling links are refused wherever they point, since writing
+		// through one would create its target
+		{"dangling", ""},
+		{"dangling-dir/new.txt", ""},
+		{"dangling-in", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := fs.resolve(tt.path)
+			if tt.want == "" {
+				if !errors.Is(err, ErrOutsideRoot) {
+					t.Errorf("resolve = %q, %v; want ErrOutsideRoot", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+			if want := filepath.Join(fs.Root(), tt.want); got != want {
+				t.Errorf("resolve = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFileSystemStaysInRoot(t *testing.T) {
+	fs := jail(t)
+	outside := filepath.Join(filepath.Dir(fs.Root()), "outside")
+
+	if _, err := fs.ReadFile("escape/secret.txt"); !errors.Is(err, ErrOutsideRoot) {
+		t.Errorf("ReadFile through a link = %v, want ErrOutsideRoot", err)
+	}
+	if _, err := fs.List("relative"); !errors.Is(err, ErrOutsideRoot) {
+		t.Errorf("List through a link = %v, want ErrOutsideRoot", err)
+	}
+	for _, path := range []string{"dangling", "dangling-dir/new.txt", "escape/new.txt"} {
+		if err := fs.WriteFile(path, "x"); !errors.Is(err, ErrOutsideRoot) {
+			t.Errorf("WriteFile(%q) = %v, want ErrOutsideRoot", path, err)
+		}
+	}
+	for _, name := range []string{"missing.txt", "missing", "new.txt"} {
+		if _, err := os.Lstat(filepath.Join(outside, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was created outside the root", name)
+		}
+	}
+
+	if err := fs.WriteFile("../notes/todo.txt", "inside"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := fs.ReadFile("notes/todo.txt"); err != nil || got != "inside" {
+		t.Errorf("ReadFile = %q, %v; want the file written under the root", got, err)
+	}
+}
@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+)
+
+// maxParsedOutput caps the JSON returned by the parsing tools
+const maxParsedOutput = 100 << 10
+
+// ParseJSON returns a tool named "parse_json" that validates JSON text and
+// returns it indented, or the value at a dotted path such as
+// "items.0.name"
+func ParseJSON() agentpatterns.AgentTool {
+	return agentpatterns.AgentTool{
+		Name:        "parse_json",
+		Description: "Parse JSON text and return it formatted, or the value at a path such as items.0.name.",
+		Parameters: map[string]agentpatterns.ParameterDef{
+			"text": {Type: "string", Description: "The JSON text", Required: true},
+			"path": {Type: "string", Description: "Dotted path of the value to return; array elements by index"},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			text, err := stringArg(args, "text")
+			if err != nil {
+				return "", err
+			}
+			path, _ := args["path"].(string)
+
+			decoder := json.NewDecoder(strings.NewReader(text))
+			decoder.UseNumber()
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return "", fmt.Errorf("invalid JSON: %w", err)
+			}
+			if _, err := decoder.Token(); err != io.EOF {
+				return "", fmt.Errorf("invalid JSON: text after the value")
+			}
+			if path != "" {
+				if value, err = lookup(value, path); err != nil {
+					return "", err
+				}
+			}
+			return formatJSON(value)
+		},
+	}
+}
+
+// lookup returns the value at a dotted path
+func lookup(value interface{}, path string) (interface{}, error) {
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		at := strings.Join(keys[:i+1], ".")
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("no key at %s", at)
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, fmt.Errorf("no element at %s: the array has %d elements", at, len(v))
+			}
+			value = v[index]
+		default:
+			return nil, fmt.Errorf("no value at %s: its parent is not an object or array", at)
+		}
+	}
+	return value, nil
+}
+
+// ParseCSV returns a tool named "parse_csv" that converts CSV text to JSON:
+// an array of objects keyed by the header row, or of arrays without one
+func ParseCSV() agentpatterns.AgentTool {
+	return agentpatterns.AgentTool{
+		Name:        "parse_csv",
+		Description: "Parse CSV text into JSON: an array of objects keyed by the header row, or of arrays if there is no header.",
+		Parameters: map[string]agentpatterns.ParameterDef{
+			"text":      {Type: "string", Description: "The CSV text", Required: true},
+			"delimiter": {Type: "string", Description: "Field delimiter; a comma if omitted"},
+			"header":    {Type: "boolean", Description: "Whether the first row names the columns; true if omitted"},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			text, err := stringArg(args, "text")
+			if err != nil {
+				return "", err
+			}
+			reader := csv.NewReader(strings.NewReader(text))
+			reader.FieldsPerRecord = -1
+			if delimiter, _ := args["delimiter"].(string); delimiter != "" {
+				r, size := utf8.DecodeRuneInString(delimiter)
+				if size != len(delimiter) {
+					return "", fmt.Errorf("delimiter must be a single character")
+				}
+				reader.Comma = r
+			}
+			records, err := reader.ReadAll()
+			if err != nil {
+				return "", fmt.Errorf("invalid CSV: %w", err)
+			}
+
+			if header, ok := args["header"].(bool); (ok && !header) || len(records) == 0 {
+				return formatJSON(records)
+			}
+			rows := make([]map[string]string, 0, len(records)-1)
+			for _, record := range records[1:] {
+				row := make(map[string]string, len(records[0]))
+				for i, column := range records[0] {
+					if i < len(record) {
+						row[column] = record[i]
+					}
+				}
+				rows = append(rows, row)
+			}
+			return formatJSON(rows)
+		},
+	}
+}
+
+// formatJSON returns value as indented JSON, cut off at maxParsedOutput
+func formatJSON(value interface{}) (string, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return truncate(string(data), maxParsedOutput), nil
+}
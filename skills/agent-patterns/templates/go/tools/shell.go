@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+)
+
+// ErrCommandNotAllowed is returned for a program off the allowlist
+var ErrCommandNotAllowed = errors.New("command not allowed")
+
+// Shell runs commands for agents. Commands are split into words, with
+// single and double quotes, and run directly rather than through a shell,
+// so pipes, redirection, and substitution have no effect. Only programs on
+// the allowlist run, in the working directory, with an environment holding
+// just PATH and HOME unless Env is set, and are killed after the timeout.
+// Arguments are not checked: allow only programs that are safe whatever
+// their arguments, which may name files outside the working directory.
+type Shell struct {
+	// Dir is the working directory of each command
+	Dir string
+	// AllowedCommands lists the program names that may run, e.g. "ls";
+	// names containing a path separator are never allowed
+	AllowedCommands []string
+	// Env replaces the default environment, as KEY=value pairs
+	Env     []string
+	Timeout time.Duration
+	// MaxOutput caps the combined output returned; the rest is cut off
+	MaxOutput int
+}
+
+// NewShell creates a shell running the allowed programs in dir, killed
+// after 30 seconds, returning at most 16KB of output
+func NewShell(dir string, allowedCommands ...string) *Shell {
+	return &Shell{
+		Dir:             dir,
+		AllowedCommands: allowedCommands,
+		Timeout:         30 * time.Second,
+		MaxOutput:       16 << 10,
+	}
+}
+
+// Tool returns the shell as an agent tool named "shell"
+func (s *Shell) Tool() agentpatterns.AgentTool {
+	return agentpatterns.AgentTool{
+		Name: "shell",
+		Description: fmt.Sprintf("Run a command and return its exit code and output. Allowed programs: %s. "+
+			"There is no shell: pipes, redirection, and variables are not supported.", strings.Join(s.AllowedCommands, ", ")),
+		Parameters: map[string]agentpatterns.ParameterDef{
+			"command": {Type: "string", Description: "The command line, e.g. grep -rn \"TODO\" src", Required: true},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			command, err := stringArg(args, "command")
+			if err != nil {
+				return "", err
+			}
+			return s.Run(ctx, command)
+		},
+	}
+}
+
+// Run runs a command line. A non-zero exit is not an error: the exit code
+// is reported with the output, for the model to act on.
+func (s *Shell) Run(ctx context.Context, command string) (string, error) {
+	argv, err := splitCommand(command)
+	if err != nil {
+		return "", err
+	}
+	if !s.allowed(argv[0]) {
+		return "", fmt.Errorf("%w: %s", ErrCommandNotAllowed, argv[0])
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = s.Dir
+	cmd.Env = s.Env
+	if cmd.Env == nil {
+		cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "HOME=" + os.Getenv("HOME")}
+	}
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// Don't wait on children holding the output open after a kill
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("command timed out after %s: %s", s.Timeout, truncate(output.String(), 1000))
+	}
+	var exitErr *exec.ExitError
+	exitCode := 0
+	switch {
+	case errors.As(err, &exitErr):
+		exitCode = exitErr.ExitCode()
+	case err != nil:
+		return "", err
+	}
+	return fmt.Sprintf("exit code %d\n%s", exitCode, truncate(output.String(), s.MaxOutput)), nil
+}
+
+// allowed reports whether a program name is on the allowlist
+func (s *Shell) allowed(program string) bool {
+	if strings.ContainsRune(program, '/') || strings.ContainsRune(program, filepath.Separator) {
+		return false
+	}
+	for _, name := range s.AllowedCommands {
+		if program == name {
+			return true
+		}
+	}
+	return false
+}
+
+// splitCommand splits a command line into words. Single quotes keep text
+// as-is; double quotes allow \" and \\ escapes; outside quotes a backslash
+// escapes the next character.
+func splitCommand(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in command")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("command is empty")
+	}
+	return words, nil
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{command: "ls -la src", want: []string{"ls", "-la", "src"}},
+		{command: "  grep\t-rn\n TODO  ", want: []string{"grep", "-rn", "TODO"}},
+		{command: `grep -rn "TODO: fix" src`, want: []string{"grep", "-rn", "TODO: fix", "src"}},
+		{command: `echo 'a "b" \c'`, want: []string{"echo", `a "b" \c`}},
+		{command: `echo "a \"b\" \\ \c"`, want: []string{"echo", `a "b" \ \c`}},
+		{command: `echo a\ b \"c\"`, want: []string{"echo", "a b", `"c"`}},
+		{command: `echo '' ""`, want: []string{"echo", "", ""}},
+		{command: `echo ab'cd'"ef"`, want: []string{"echo", "abcdef"}},
+		// Shell metacharacters are ordinary text
+		{command: "ls; rm -rf /", want: []string{"ls;", "rm", "-rf", "/"}},
+		{command: "ls && rm x | cat > out", want: []string{"ls", "&&", "rm", "x", "|", "cat", ">", "out"}},
+		{command: "echo $(rm x) `id` $HOME", want: []string{"echo", "$(rm", "x)", "`id`", "$HOME"}},
+		{command: `echo "x'y"`, want: []string{"echo", "x'y"}},
+		{command: "", wantErr: true},
+		{command: "   ", wantErr: true},
+		{command: `echo 'open`, wantErr: true},
+		{command: `echo "open`, wantErr: true},
+		{command: `echo trailing\`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got, err := splitCommand(tt.command)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("splitCommand = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitCommand = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellAllowlist(t *testing.T) {
+	s := NewShell(t.TempDir(), "echo", "ls")
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{"echo hi", true},
+		{"'ec''ho' hi", true},
+		{`ec\ho hi`, true},
+		{"rm -rf /", false},
+		{"/bin/echo hi", false},
+		{"./echo hi", false},
+		{"../bin/echo hi", false},
+		{"bin/ls", false},
+		{"'echo hi'", false},
+		{"echo;rm x", false},
+		{"$(echo) hi", false},
+		{"ECHO hi", false},
+		{"sh -c 'echo hi'", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			argv, err := splitCommand(tt.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.allowed(argv[0]); got != tt.allowed {
+				t.Errorf("allowed(%q) = %v, want %v", argv[0], got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestShellRun(t *testing.T) {
+	s := NewShell(t.TempDir(), "echo")
+	ctx := context.Background()
+
+	if _, err := s.Run(ctx, "rm -rf x"); !errors.Is(err, ErrCommandNotAllowed) {
+		t.Errorf("Run(rm) = %v, want ErrCommandNotAllowed", err)
+	}
+	// Nothing is interpreted by a shell
+	out, err := s.Run(ctx, `echo 'a; rm x' $(id) "$HOME" | cat > out`)
+	if err != nil {
+		t.Skipf("echo unavailable: %v", err)
+	}
+	want := "exit code 0\na; rm x $(id) $HOME | cat > out\n"
+	if out != want {
+		t.Errorf("Run = %q, want %q", out, want)
+	}
+
+	s.MaxOutput = 8
+	out, err = s.Run(ctx, "echo 0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "abcdef") {
+		t.Errorf("Run = %q, want the output cut at 8 bytes", out)
+	}
+}
@@ -0,0 +1,602 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+)
+
+// SQLDialect names the database behind a Database, which decides how its
+// schema is read and how query parameters are written
+type SQLDialect string
+
+// Supported SQL dialects
+const (
+	Postgres SQLDialect = "postgres"
+	MySQL    SQLDialect = "mysql"
+	SQLite   SQLDialect = "sqlite"
+)
+
+// ErrReadOnly is returned for a statement that could modify a read-only
+// database
+var ErrReadOnly = errors.New("database is read-only")
+
+// Database gives agents a SQL database through database/sql: its schema,
+// parameterized queries with a row limit, and, unless ReadOnly, statements
+// that change data. Open the database with the driver for its dialect.
+//
+// A read-only database accepts a single SELECT, WITH, EXPLAIN, SHOW, or
+// DESCRIBE statement without data-changing keywords, and runs it in a
+// read-only transaction (query_only mode on SQLite) that is rolled back, so
+// a statement that slips past the check still cannot write. For the
+// strongest guarantee, also connect as a user that may only read.
+//
+// Example:
+//
+//	db, err := sql.Open("pgx", os.Getenv("DATABASE_URL"))
+//	database := tools.NewDatabase(db, tools.Postgres)
+//	for _, tool := range database.Tools() {
+//	    agent.RegisterTool(tool)
+//	}
+type Database struct {
+	db      *sql.DB
+	dialect SQLDialect
+	// ReadOnly refuses statements that change data; on by default
+	ReadOnly bool
+	// MaxRows caps the rows a query returns
+	MaxRows int
+	Timeout time.Duration
+}
+
+// NewDatabase creates a read-only database returning at most 100 rows
+// per query, with 30 seconds per statement
+func NewDatabase(db *sql.DB, dialect SQLDialect) *Database {
+	return &Database{
+		db:       db,
+		dialect:  dialect,
+		ReadOnly: true,
+		MaxRows:  100,
+		Timeout:  30 * time.Second,
+	}
+}
+
+// Tools returns the sql_schema and sql_query tools, and sql_execute if the
+// database is not read-only
+func (d *Database) Tools() []agentpatterns.AgentTool {
+	tools := []agentpatterns.AgentTool{d.SchemaTool(), d.QueryTool()}
+	if !d.ReadOnly {
+		tools = append(tools, d.ExecuteTool())
+	}
+	return tools
+}
+
+// SchemaTool returns a tool describing the database's tables
+func (d *Database) SchemaTool() agentpatterns.AgentTool {
+	return agentpatterns.AgentTool{
+		Name:        "sql_schema",
+		Description: fmt.Sprintf("Describe the tables, columns, and foreign keys of the %s database. Call it before writing queries.", d.dialect),
+		Parameters:  map[string]agentpatterns.ParameterDef{},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			return d.Schema(ctx)
+		},
+	}
+}
+
+// QueryTool returns a tool running a query
+func (d *Database) QueryTool() agentpatterns.AgentTool {
+	description := fmt.Sprintf("Run a SQL query on the %s database and return up to %d rows. Pass values as params with %s placeholders rather than in the SQL.",
+		d.dialect, d.MaxRows, d.placeholders())
+	if d.ReadOnly {
+		description += " The database is read-only."
+	}
+	return agentpatterns.AgentTool{
+		Name:        "sql_query",
+		Description: description,
+		Parameters: map[string]agentpatterns.ParameterDef{
+			"query":  {Type: "string", Description: "A single SQL statement", Required: true},
+			"params": {Type: "array", Description: "Values for the placeholders, in order"},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			query, err := stringArg(args, "query")
+			if err != nil {
+				return "", err
+			}
+			params, _ := args["params"].([]interface{})
+			return d.Query(ctx, query, params...)
+		},
+	}
+}
+
+// ExecuteTool returns a tool running a statement that changes data
+func (d *Database) ExecuteTool() agentpatterns.AgentTool {
+	return agentpatterns.AgentTool{
+		Name:        "sql_execute",
+		Description: fmt.Sprintf("Run an INSERT, UPDATE, DELETE, or DDL statement on the %s database and return the rows affected. Pass values as params with %s placeholders.", d.dialect, d.placeholders()),
+		Parameters: map[string]agentpatterns.ParameterDef{
+			"statement": {Type: "string", Description: "A single SQL statement", Required: true},
+			"params":    {Type: "array", Description: "Values for the placeholders, in order"},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			statement, err := stringArg(args, "statement")
+			if err != nil {
+				return "", err
+			}
+			params, _ := args["params"].([]interface{})
+			affected, err := d.Execute(ctx, statement, params...)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d rows affected", affected), nil
+		},
+	}
+}
+
+// Query runs a query and returns its column names and rows as JSON arrays,
+// one row per line
+func (d *Database) Query(ctx context.Context, query string, params ...interface{}) (string, error) {
+	if err := d.checkStatement(query); err != nil {
+		return "", err
+	}
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	var out string
+	err := d.readOnly(ctx, func(q queryer) error {
+		rows, err := q.QueryContext(ctx, query, params...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		out, err = d.formatRows(rows)
+		return err
+	})
+	return out, err
+}
+
+// Execute runs a statement that changes data, returning the rows affected
+func (d *Database) Execute(ctx context.Context, statement string, params ...interface{}) (int64, error) {
+	if d.ReadOnly {
+		return 0, ErrReadOnly
+	}
+	if err := d.checkStatement(statement); err != nil {
+		return 0, err
+	}
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	result, err := d.db.ExecContext(ctx, statement, params...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// queryer runs queries on a database, transaction, or connection
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// readOnly runs fn in a read-only transaction that is rolled back, or
+// directly on the database if it is writable
+func (d *Database) readOnly(ctx context.Context, fn func(queryer) error) error {
+	if !d.ReadOnly {
+		return fn(d.db)
+	}
+	if d.dialect == SQLite {
+		// SQLite drivers do not all support read-only transactions
+		conn, err := d.db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), "PRAGMA query_only = OFF")
+		return fn(conn)
+	}
+	tx, err := d.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return fn(tx)
+}
+
+var (
+	sqlFirstWord = regexp.MustCompile(`^\s*\(?\s*([A-Za-z]+)`)
+	sqlWriteWord = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|UPSERT|DROP|ALTER|CREATE|TRUNCATE|RENAME|GRANT|REVOKE|ATTACH|DETACH|VACUUM|REINDEX|COPY|CALL|EXEC|EXECUTE|LOCK|SET|PRAGMA|INTO|LOAD)\b`)
+)
+
+// checkStatement refuses more than one statement, and on a read-only
+// database anything but a read
+func (d *Database) checkStatement(statement string) error {
+	// Strings, quoted names, and comments cannot hide keywords or
+	// statement separators
+	bare := d.bare(statement)
+	if i := strings.Index(bare, ";"); i >= 0 && strings.TrimSpace(bare[i+1:]) != "" {
+		return fmt.Errorf("only one statement may be run at a time")
+	}
+	if !d.ReadOnly {
+		return nil
+	}
+	first := sqlFirstWord.FindStringSubmatch(bare)
+	if first == nil {
+		return fmt.Errorf("%w: statement must be a query", ErrReadOnly)
+	}
+	switch strings.ToUpper(first[1]) {
+	case "SELECT", "WITH", "EXPLAIN", "SHOW", "DESCRIBE", "DESC", "VALUES":
+	default:
+		return fmt.Errorf("%w: %s statements are not allowed", ErrReadOnly, strings.ToUpper(first[1]))
+	}
+	if word := sqlWriteWord.FindString(bare); word != "" {
+		return fmt.Errorf("%w: %s is not allowed in a query", ErrReadOnly, strings.ToUpper(word))
+	}
+	return nil
+}
+
+// bare blanks out the strings, quoted names, and comments of a statement,
+// reading them the way the dialect does: MySQL strings take backslash
+// escapes, and its /*! comments are run, so their text is kept; Postgres
+// block comments nest, E-prefixed strings take backslash escapes, and
+// $tag$ quotes strings. Strings become an empty string, comments a space.
+func (d *Database) bare(statement string) string {
+	mysql, postgres := d.dialect == MySQL, d.dialect == Postgres
+	var out strings.Builder
+	executable := false
+	for i := 0; i < len(statement); {
+		rest := statement[i:]
+		switch c := rest[0]; {
+		case c == '\'' || c == '"' || c == '`':
+			escapes := mysql && c != '`' ||
+				postgres && c == '\'' && i > 0 && (statement[i-1] == 'E' || statement[i-1] == 'e') &&
+					(i == 1 || !isWordByte(statement[i-2]))
+			i += quotedLen(rest, escapes)
+			out.WriteString("''")
+		case strings.HasPrefix(rest, "--") && (!mysql || len(rest) == 2 || rest[2] <= ' '),
+			c == '#' && mysql:
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			i += end
+			out.WriteByte(' ')
+		case executable && strings.HasPrefix(rest, "*/"):
+			executable = false
+			i += 2
+			out.WriteByte(' ')
+		case mysql && strings.HasPrefix(rest, "/*!"):
+			executable = true
+			i += 3
+			for i < len(statement) && statement[i] >= '0' && statement[i] <= '9' {
+				i++
+			}
+			out.WriteByte(' ')
+		case strings.HasPrefix(rest, "/*"):
+			i += commentLen(rest, postgres)
+			out.WriteByte(' ')
+		case c == '$' && postgres && (i == 0 || !isWordByte(statement[i-1])) && dollarQuotedLen(rest) > 0:
+			i += dollarQuotedLen(rest)
+			out.WriteString("''")
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// quotedLen returns the length of the quoted text at the start of s,
+// where a doubled quote, or with escapes a backslash, escapes the next
+// character; unterminated text runs to the end
+func quotedLen(s string, escapes bool) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case escapes && s[i] == '\\':
+			i++
+		case s[i] == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// commentLen returns the length of the block comment at the start of s
+func commentLen(s string, nested bool) int {
+	depth := 0
+	for i := 0; i+1 < len(s); i++ {
+		switch {
+		case s[i] == '/' && s[i+1] == '*' && (nested || depth == 0):
+			depth++
+			i++
+		case s[i] == '*' && s[i+1] == '/':
+			if depth--; depth == 0 {
+				return i + 2
+			}
+			i++
+		}
+	}
+	return len(s)
+}
+
+// dollarQuotedLen returns the length of the Postgres $tag$ string at the
+// start of s, or 0 if s does not start with one
+func dollarQuotedLen(s string) int {
+	end := strings.IndexByte(s[1:], '$') + 1
+	if end == 0 {
+		return 0
+	}
+	tag := s[:end+1]
+	for i, c := range tag[1:end] {
+		if !isWordByte(byte(c)) || c >= '0' && c <= '9' && i == 0 {
+			return 0
+		}
+	}
+	n := strings.Index(s[len(tag):], tag)
+	if n < 0 {
+		return len(s)
+	}
+	return len(tag) + n + len(tag)
+}
+
+// isWordByte reports whether c may appear in an unquoted identifier
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// formatRows returns the column names and up to MaxRows rows
+func (d *Database) formatRows(rows *sql.Rows) (string, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	header, _ := json.Marshal(columns)
+	var b strings.Builder
+	fmt.Fprintf(&b, "columns: %s\n", header)
+
+	count := 0
+	for rows.Next() {
+		if d.MaxRows > 0 && count == d.MaxRows {
+			fmt.Fprintf(&b, "(stopped at %d rows; narrow the query or aggregate)", d.MaxRows)
+			return b.String(), nil
+		}
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return "", err
+		}
+		for i, v := range values {
+			if raw, ok := v.([]byte); ok {
+				values[i] = string(raw)
+			}
+		}
+		line, err := json.Marshal(values)
+		if err != nil {
+			return "", err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&b, "(%d rows)", count)
+	return b.String(), nil
+}
+
+// schemaColumn is one column of the schema
+type schemaColumn struct {
+	table, name, dataType string
+	nullable              bool
+	references            string
+}
+
+// Schema describes the database's tables and views, one per line, with
+// their columns, types, and foreign keys
+func (d *Database) Schema(ctx context.Context) (string, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	var columns []schemaColumn
+	var err error
+	switch d.dialect {
+	case SQLite:
+		columns, err = d.sqliteSchema(ctx)
+	case Postgres, MySQL:
+		columns, err = d.informationSchema(ctx)
+	default:
+		return "", fmt.Errorf("unsupported SQL dialect %q", d.dialect)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read schema: %w", err)
+	}
+	if len(columns) == 0 {
+		return "(no tables)", nil
+	}
+
+	var b strings.Builder
+	for i, c := range columns {
+		if i == 0 || columns[i-1].table != c.table {
+			if i > 0 {
+				b.WriteString(")\n")
+			}
+			fmt.Fprintf(&b, "%s(", c.table)
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(c.name)
+		if c.dataType != "" {
+			fmt.Fprintf(&b, " %s", strings.ToLower(c.dataType))
+		}
+		if !c.nullable {
+			b.WriteString(" not null")
+		}
+		if c.references != "" {
+			fmt.Fprintf(&b, " references %s", c.references)
+		}
+	}
+	b.WriteString(")")
+	return b.String(), nil
+}
+
+// sqliteSchema reads the schema with SQLite's pragmas
+func (d *Database) sqliteSchema(ctx context.Context) ([]schemaColumn, error) {
+	tables, err := d.queryStrings(ctx, `SELECT name FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	var columns []schemaColumn
+	for _, table := range tables {
+		quoted := `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+		refs := make(map[string]string)
+		rows, err := d.db.QueryContext(ctx, "SELECT \"from\", \"table\", \"to\" FROM pragma_foreign_key_list("+quoted+")")
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var from, refTable string
+			var to sql.NullString
+			if err := rows.Scan(&from, &refTable, &to); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			refs[from] = refTable
+			if to.Valid {
+				refs[from] += "." + to.String
+			}
+		}
+		rows.Close()
+
+		rows, err = d.db.QueryContext(ctx, "SELECT name, type, \"notnull\", pk FROM pragma_table_info("+quoted+")")
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			c := schemaColumn{table: table}
+			var notNull, pk int
+			if err := rows.Scan(&c.name, &c.dataType, &notNull, &pk); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			c.nullable = notNull == 0 && pk == 0
+			c.references = refs[c.name]
+			columns = append(columns, c)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rows.Close()
+	}
+	return columns, nil
+}
+
+// informationSchema reads the schema of the current Postgres or MySQL
+// database from information_schema
+func (d *Database) informationSchema(ctx context.Context) ([]schemaColumn, error) {
+	var columnsQuery, refsQuery string
+	if d.dialect == Postgres {
+		columnsQuery = `SELECT CASE WHEN table_schema = 'public' THEN table_name ELSE table_schema || '.' || table_name END,
+	column_name, data_type, is_nullable = 'YES'
+FROM information_schema.columns
+WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+ORDER BY table_schema, table_name, ordinal_position`
+		refsQuery = `SELECT CASE WHEN kcu.table_schema = 'public' THEN kcu.table_name ELSE kcu.table_schema || '.' || kcu.table_name END,
+	kcu.column_name,
+	CASE WHEN ccu.table_schema = 'public' THEN ccu.table_name ELSE ccu.table_schema || '.' || ccu.table_name END || '.' || ccu.column_name
+FROM information_schema.table_constraints tc
+JOIN information_schema.key_column_usage kcu
+	ON tc.constraint_name = kcu.constraint_name AND tc.constraint_schema = kcu.constraint_schema
+JOIN information_schema.constraint_column_usage ccu
+	ON tc.constraint_name = ccu.constraint_name AND tc.constraint_schema = ccu.constraint_schema
+WHERE tc.constraint_type = 'FOREIGN KEY'`
+	} else {
+		columnsQuery = `SELECT table_name, column_name, column_type, is_nullable = 'YES'
+FROM information_schema.columns
+WHERE table_schema = DATABASE()
+ORDER BY table_name, ordinal_position`
+		refsQuery = `SELECT table_name, column_name, CONCAT(referenced_table_name, '.', referenced_column_name)
+FROM information_schema.key_column_usage
+WHERE table_schema = DATABASE() AND referenced_table_name IS NOT NULL`
+	}
+
+	refs := make(map[[2]string]string)
+	rows, err := d.db.QueryContext(ctx, refsQuery)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var table, column, ref string
+		if err := rows.Scan(&table, &column, &ref); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		refs[[2]string{table, column}] = ref
+	}
+	rows.Close()
+
+	rows, err = d.db.QueryContext(ctx, columnsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []schemaColumn
+	for rows.Next() {
+		var c schemaColumn
+		if err := rows.Scan(&c.table, &c.name, &c.dataType, &c.nullable); err != nil {
+			return nil, err
+		}
+		c.references = refs[[2]string{c.table, c.name}]
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
+
+// queryStrings runs a query returning one string column
+func (d *Database) queryStrings(ctx context.Context, query string) ([]string, error) {
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// placeholders describes the dialect's parameter placeholders
+func (d *Database) placeholders() string {
+	if d.dialect == Postgres {
+		return "$1, $2, ..."
+	}
+	return "?"
+}
+
+// withTimeout applies the statement timeout
+func (d *Database) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d.Timeout)
+}
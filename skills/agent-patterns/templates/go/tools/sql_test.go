@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckStatementReadOnly(t *testing.T) {
+	tests := []struct {
+		name      string
+		dialect   SQLDialect
+		statement string
+		allowed   bool
+	}{
+		{"select", SQLite, "SELECT * FROM users WHERE id = ?", true},
+		{"parenthesized select", Postgres, "(SELECT 1) UNION (SELECT 2)", true},
+		{"trailing semicolon", SQLite, "SELECT 1; ", true},
+		{"explain", Postgres, "EXPLAIN SELECT * FROM users", true},
+		{"show", MySQL, "SHOW TABLES", true},
+		{"values", SQLite, "VALUES (1), (2)", true},
+		{"lowercase", SQLite, "select 1", true},
+		{"keywords inside names", SQLite, "SELECT created_at, updated, deleted_flag FROM settings", true},
+
+		// Writes and statements that are not queries
+		{"insert", SQLite, "INSERT INTO users VALUES (1)", false},
+		{"lowercase delete", SQLite, "delete from users", false},
+		{"select into", Postgres, "SELECT * INTO backup FROM users", false},
+		{"into outfile", MySQL, "SELECT * FROM users INTO OUTFILE '/tmp/users'", false},
+		{"set", Postgres, "SET search_path TO evil", false},
+		{"empty", SQLite, "", false},
+		{"only a comment", SQLite, "-- SELECT 1", false},
+
+		// Writes after a semicolon
+		{"second statement", SQLite, "SELECT 1; DROP TABLE users", false},
+		{"second read", SQLite, "SELECT 1; SELECT 2", false},
+		{"semicolon then comment", SQLite, "SELECT 1; -- DROP TABLE users", true},
+		{"no space", MySQL, "SELECT 1;DELETE FROM users", false},
+
+		// Writes inside comments are not run, unless the dialect runs them
+		{"line comment", SQLite, "SELECT 1 -- ; DROP TABLE users", true},
+		{"block comment", Postgres, "SELECT 1 /* ; DROP TABLE users */", true},
+		{"comment hides a separator", SQLite, "SELECT 1 /* */; DROP TABLE users", false},
+		{"comment before the verb", SQLite, "/* SELECT */ DELETE FROM users", false},
+		{"comment in a keyword", SQLite, "SELECT 1; DR/**/OP TABLE users", false},
+		{"quote in a comment", SQLite, "SELECT 1 -- '\n; DROP TABLE users; -- '", false},
+		{"mysql executable comment", MySQL, "SELECT 1 /*!50000 ; DROP TABLE users */", false},
+		{"mysql executable into", MySQL, "SELECT * FROM users /*!INTO OUTFILE '/tmp/users'*/", false},
+		{"mysql hash comment", MySQL, "SELECT 1 # '\n; DROP TABLE users; -- '", false},
+		{"mysql double dash needs a space", MySQL, "SELECT 1--1; DROP TABLE users", false},
+		{"mysql double dash comment", MySQL, "SELECT 1 -- ; DROP TABLE users", true},
+		{"postgres nested comment", Postgres, "SELECT 1 /* /* */ '*/ ; DROP TABLE users; --'", false},
+		{"sqlite comments do not nest", SQLite, "SELECT 1 /* /* */ ; DROP TABLE users", false},
+
+		// Writes inside CTEs
+		{"cte select", Postgres, "WITH recent AS (SELECT * FROM orders) SELECT * FROM recent", true},
+		{"cte delete", Postgres, "WITH gone AS (DELETE FROM orders RETURNING *) SELECT * FROM gone", false},
+		{"cte insert", Postgres, "WITH x AS (SELECT 1) INSERT INTO orders SELECT * FROM x", false},
+		{"cte update", SQLite, "WITH x AS (SELECT 1) UPDATE orders SET paid = 1", false},
+		{"cte named like a write", Postgres, `WITH "delete" AS (SELECT 1) SELECT * FROM "delete"`, true},
+
+		// Writes inside string literals are data
+		{"string", SQLite, "SELECT * FROM log WHERE message = 'DROP TABLE users'", true},
+		{"separator in a string", SQLite, "SELECT 'a; DROP TABLE users'", true},
+		{"doubled quote", SQLite, "SELECT 'it''s; DROP TABLE users'", true},
+		{"quoted name", SQLite, `SELECT "update" FROM "insert"`, true},
+		{"backquoted name", MySQL, "SELECT `drop` FROM `t;x`", true},
+		{"string closed early", SQLite, "SELECT 'a'; DROP TABLE users; --'", false},
+		{"sqlite backslash ends nothing", SQLite, `SELECT 'a\'; DROP TABLE users; -- '`, false},
+		{"mysql backslash escape", MySQL, `SELECT 'a\'; DROP TABLE users; -- '`, true},
+		{"mysql backslash before a doubled quote", MySQL, `SELECT 'a\'' ; DROP TABLE users; -- '`, false},
+		{"postgres escape string", Postgres, `SELECT E'a\'' ; DROP TABLE users; -- '`, false},
+		{"postgres standard string", Postgres, `SELECT 'a\'' ; DROP TABLE users; -- '`, true},
+		{"postgres dollar quotes", Postgres, "SELECT $$; DROP TABLE users$$", true},
+		{"postgres tagged dollar quotes", Postgres, "SELECT $body$ $$; DROP $$ $body$", true},
+		{"postgres dollar quote hides a quote", Postgres, "SELECT $$'$$; DROP TABLE users; --'", false},
+		{"postgres parameters", Postgres, "SELECT * FROM users WHERE id = $1 AND name = $2", true},
+
+		// PRAGMA writes
+		{"pragma", SQLite, "PRAGMA journal_mode = DELETE", false},
+		{"pragma query_only", SQLite, "PRAGMA query_only = OFF", false},
+		{"pragma after select", SQLite, "SELECT 1; PRAGMA writable_schema = ON", false},
+		{"pragma in a cte", SQLite, "WITH x AS (SELECT 1) PRAGMA user_version = 1", false},
+		{"pragma function", SQLite, "SELECT * FROM pragma_table_info('users')", true},
+
+		// ATTACH
+		{"attach", SQLite, "ATTACH DATABASE '/tmp/evil.db' AS evil", false},
+		{"attach after select", SQLite, "SELECT 1; ATTACH '/tmp/evil.db' AS evil", false},
+		{"detach", SQLite, "DETACH DATABASE evil", false},
+		{"attach in a string", SQLite, "SELECT 'ATTACH' AS verb", true},
+		{"name starting with attach", SQLite, "SELECT * FROM attachments", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDatabase(nil, tt.dialect)
+			err := d.checkStatement(tt.statement)
+			if tt.allowed && err != nil {
+				t.Errorf("checkStatement(%q) = %v, want nil", tt.statement, err)
+			}
+			if !tt.allowed && err == nil {
+				t.Errorf("checkStatement(%q) = nil, want an error", tt.statement)
+			}
+		})
+	}
+}
+
+func TestCheckStatementWritable(t *testing.T) {
+	d := NewDatabase(nil, SQLite)
+	d.ReadOnly = false
+	if err := d.checkStatement("INSERT INTO users VALUES ('a;b')"); err != nil {
+		t.Errorf("insert = %v, want nil", err)
+	}
+	if err := d.checkStatement("UPDATE users SET n = 1; DROP TABLE users"); err == nil {
+		t.Error("two statements were accepted")
+	}
+	if err := d.checkStatement("DELETE FROM users"); errors.Is(err, ErrReadOnly) {
+		t.Errorf("delete = %v on a writable database", err)
+	}
+}
@@ -0,0 +1,51 @@
+/*
+ * Built-in Tools for Go Agent Patterns
+ * Ready-made agent tools for web fetches, files, shell commands, and JSON and CSV data
+ */
+
+// Package tools provides agent tools for common jobs, each confined by
+// safe defaults: web fetches only reach allowed hosts, file access stays
+// inside a root directory, and shell commands run only allowed programs,
+// without a shell, under a timeout. Every tool caps the size of what it
+// returns, so one large result cannot crowd the rest out of context.
+//
+// Example:
+//
+//	fs, err := tools.NewFileSystem("./workspace")
+//	agent := agentpatterns.NewAutonomousAgent(client).
+//	    RegisterTool(tools.NewFetcher("docs.python.org", "*.wikipedia.org").Tool()).
+//	    RegisterTool(tools.NewShell("./workspace", "ls", "grep", "wc").Tool()).
+//	    RegisterTool(tools.ParseJSON()).
+//	    RegisterTool(tools.ParseCSV())
+//	for _, tool := range fs.Tools() {
+//	    agent.RegisterTool(tool)
+//	}
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// stringArg returns a required string argument
+func stringArg(args map[string]interface{}, name string) (string, error) {
+	value, ok := args[name].(string)
+	if !ok || strings.TrimSpace(value) == "" {
+		return "", fmt.Errorf("%s is required", name)
+	}
+	return value, nil
+}
+
+// truncate shortens text to at most max bytes, on a character boundary,
+// saying how much was cut
+func truncate(text string, max int) string {
+	if max <= 0 || len(text) <= max {
+		return text
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n[... %d more bytes truncated]", text[:cut], len(text)-cut)
+}
@@ -0,0 +1,268 @@
+/*
+ * Distributed Tracing for Go Agent Patterns
+ * Run/span context propagated through composed patterns and tool calls
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span is one timed operation in a trace: a pattern run, an agent step, a
+// tool call, or an LLM request. Methods are safe to call on a nil Span, which
+// is what StartSpan returns when tracing is disabled.
+type Span struct {
+	TraceID    string            `json:"trace_id"`
+	SpanID     string            `json:"span_id"`
+	ParentID   string            `json:"parent_id,omitempty"`
+	Name       string            `json:"name"`
+	StartTime  time.Time         `json:"start_time"`
+	EndTime    time.Time         `json:"end_time"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Error      string            `json:"error,omitempty"`
+
+	mu       sync.Mutex
+	exporter SpanExporter
+}
+
+// SetAttribute records a key/value pair on the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Attributes[key] = fmt.Sprint(value)
+}
+
+// Finish ends the span, recording err if non-nil, and exports it
+func (s *Span) Finish(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.EndTime.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.EndTime = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	s.mu.Unlock()
+	s.exporter.ExportSpan(s)
+}
+
+// Duration returns how long the span ran
+func (s *Span) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// SpanExporter receives every finished span
+type SpanExporter interface {
+	ExportSpan(span *Span)
+}
+
+// SpanExporterFunc adapts a function to SpanExporter
+type SpanExporterFunc func(span *Span)
+
+// ExportSpan implements SpanExporter
+func (f SpanExporterFunc) ExportSpan(span *Span) { f(span) }
+
+// WithTracer exports spans for every run of the pattern. Like budgets and
+// cost trackers, the exporter travels in the context, so nested patterns,
+// workers, and tool handlers all contribute to the same trace.
+//
+// Example:
+//
+//	spans := NewSpanRecorder()
+//	orch := NewOrchestrator(client, WithTracer(spans))
+//	result, err := orch.Execute(ctx, task)
+//	spans.WriteTree(os.Stdout)
+func WithTracer(exporter SpanExporter) Option {
+	return func(c *patternConfig) { c.tracer = exporter }
+}
+
+type spanKey struct{}
+type spanExporterKey struct{}
+
+// SpanFromContext returns the active span, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// StartSpan starts a child of the active span. Tool handlers can use it to
+// trace their own work; it is a no-op when the run has no tracer.
+//
+// Example:
+//
+//	Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+//	    ctx, span := StartSpan(ctx, "inventory.lookup")
+//	    defer span.Finish(nil)
+//	    req, _ := http.NewRequestWithContext(ctx, "GET", inventoryURL, nil)
+//	    InjectTraceContext(ctx, req)
+//	    ...
+//	}
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	exporter, _ := ctx.Value(spanExporterKey{}).(SpanExporter)
+	if exporter == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		SpanID:     newTraceHex(8),
+		Name:       name,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]string),
+		exporter:   exporter,
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+	} else if tc, ok := ctx.Value(remoteTraceKey{}).(traceParent); ok {
+		span.TraceID = tc.traceID
+		span.ParentID = tc.spanID
+	} else {
+		span.TraceID = newTraceHex(16)
+	}
+	if runID := RunIDFromContext(ctx); runID != "" {
+		span.Attributes["run_id"] = runID
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// startSpan attaches the pattern's exporter to ctx, unless an enclosing run
+// already did, and starts a span
+func (c *patternConfig) startSpan(ctx context.Context, name string) (context.Context, *Span) {
+	if c.tracer != nil && ctx.Value(spanExporterKey{}) == nil {
+		ctx = context.WithValue(ctx, spanExporterKey{}, c.tracer)
+	}
+	return StartSpan(ctx, name)
+}
+
+// traceParent is a span context received from another service
+type traceParent struct {
+	traceID string
+	spanID  string
+}
+
+type remoteTraceKey struct{}
+
+// InjectTraceContext sets the W3C traceparent header on an outgoing request
+// so the receiving service can join the trace
+func InjectTraceContext(ctx context.Context, req *http.Request) {
+	if span := SpanFromContext(ctx); span != nil {
+		req.Header.Set("traceparent", fmt.Sprintf("00-%s-%s-01", span.TraceID, span.SpanID))
+	}
+}
+
+// ExtractTraceContext reads a W3C traceparent header from an incoming request
+// so spans started from the returned context continue the caller's trace
+func ExtractTraceContext(ctx context.Context, req *http.Request) context.Context {
+	parts := strings.Split(req.Header.Get("traceparent"), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteTraceKey{}, traceParent{traceID: parts[1], spanID: parts[2]})
+}
+
+func newTraceHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SpanRecorder is an in-memory SpanExporter for tests and debugging
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// NewSpanRecorder creates an empty recorder
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// ExportSpan implements SpanExporter
+func (r *SpanRecorder) ExportSpan(span *Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+// Spans returns the finished spans in the order they ended
+func (r *SpanRecorder) Spans() []*Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := make([]*Span, len(r.spans))
+	copy(spans, r.spans)
+	return spans
+}
+
+// WriteJSON writes the finished spans as a JSON array
+func (r *SpanRecorder) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.Spans())
+}
+
+// WriteTree prints each trace as an indented tree ordered by start time
+func (r *SpanRecorder) WriteTree(w io.Writer) error {
+	spans := r.Spans()
+	children := make(map[string][]*Span)
+	known := make(map[string]bool)
+	for _, s := range spans {
+		known[s.SpanID] = true
+	}
+	var roots []*Span
+	for _, s := range spans {
+		if s.ParentID == "" || !known[s.ParentID] {
+			roots = append(roots, s)
+		} else {
+			children[s.ParentID] = append(children[s.ParentID], s)
+		}
+	}
+
+	byStart := func(list []*Span) {
+		sort.Slice(list, func(i, j int) bool { return list[i].StartTime.Before(list[j].StartTime) })
+	}
+
+	var write func(s *Span, depth int) error
+	write = func(s *Span, depth int) error {
+		line := fmt.Sprintf("%s%s (%v)", strings.Repeat("  ", depth), s.Name, s.Duration().Round(time.Millisecond))
+		if s.Error != "" {
+			line += " error: " + s.Error
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		kids := children[s.SpanID]
+		byStart(kids)
+		for _, child := range kids {
+			if err := write(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	byStart(roots)
+	for _, root := range roots {
+		if err := write(root, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
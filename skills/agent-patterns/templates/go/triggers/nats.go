@@ -0,0 +1,80 @@
+package triggers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSubscription is the subset of *nats.Subscription used by NATSQueue
+type NATSSubscription interface {
+	NextMsgWithContext(ctx context.Context) (*nats.Msg, error)
+}
+
+// NATSQueue receives messages from a synchronous NATS subscription. With a
+// JetStream subscription using nats.ManualAck, failed runs are redelivered;
+// core NATS has no redelivery, so Ack and Nack only answer messages that
+// have a reply subject.
+//
+// Example:
+//
+//	js, err := nc.JetStream()
+//	sub, err := js.QueueSubscribeSync("agents.triage", "triage-workers", nats.ManualAck())
+//	go d.Consume(ctx, "triage", triggers.NewNATSQueue(sub))
+type NATSQueue struct {
+	sub NATSSubscription
+}
+
+// NewNATSQueue creates a queue reading from sub
+func NewNATSQueue(sub NATSSubscription) *NATSQueue {
+	return &NATSQueue{sub: sub}
+}
+
+// Receive implements Queue
+func (q *NATSQueue) Receive(ctx context.Context) (Message, error) {
+	msg, err := q.sub.NextMsgWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return natsMessage{msg}, nil
+}
+
+type natsMessage struct {
+	msg *nats.Msg
+}
+
+func (m natsMessage) Event() Event {
+	headers := make(map[string]string, len(m.msg.Header))
+	for name, values := range m.msg.Header {
+		if len(values) > 0 {
+			headers[name] = values[0]
+		}
+	}
+	return Event{
+		ID:       m.msg.Header.Get(nats.MsgIdHdr),
+		Source:   SourceNATS,
+		Subject:  m.msg.Subject,
+		Payload:  m.msg.Data,
+		Headers:  headers,
+		Received: time.Now(),
+	}
+}
+
+func (m natsMessage) Ack(ctx context.Context) error {
+	return ignoreNoReply(m.msg.Ack())
+}
+
+func (m natsMessage) Nack(ctx context.Context) error {
+	return ignoreNoReply(m.msg.Nak())
+}
+
+// ignoreNoReply drops the error for settling a core NATS message that
+// nobody is waiting on
+func ignoreNoReply(err error) error {
+	if errors.Is(err, nats.ErrMsgNoReply) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package triggers
+
+import (
+	"context"
+	"time"
+)
+
+// Message is a queue message that must be settled once its run finishes
+type Message interface {
+	// Event converts the message to an event; Consume fills in the trigger
+	Event() Event
+	// Ack removes the message from the queue
+	Ack(ctx context.Context) error
+	// Nack returns the message to the queue for redelivery
+	Nack(ctx context.Context) error
+}
+
+// Queue receives messages from a message broker. NATSQueue and SQSQueue
+// adapt the NATS and Amazon SQS clients.
+type Queue interface {
+	// Receive blocks until a message arrives or ctx is done
+	Receive(ctx context.Context) (Message, error)
+}
+
+// ConsumeOption configures Consume
+type ConsumeOption func(*consumeConfig)
+
+type consumeConfig struct {
+	nackFailed   bool
+	errorBackoff time.Duration
+}
+
+// AckFailed acknowledges messages whose run failed instead of returning
+// them to the queue. Use it when failures are handled by result callbacks
+// and retries would only repeat them.
+func AckFailed() ConsumeOption {
+	return func(c *consumeConfig) { c.nackFailed = false }
+}
+
+// WithErrorBackoff sets how long Consume waits after a receive error
+// before trying again. The default is five seconds.
+func WithErrorBackoff(d time.Duration) ConsumeOption {
+	return func(c *consumeConfig) { c.errorBackoff = d }
+}
+
+// Consume receives messages from q and runs each with the named trigger
+// until ctx is done. Receiving pauses while the trigger is at its
+// concurrency limit, so unstarted work stays in the broker. Messages are
+// acknowledged after their run finishes and its callbacks have been called;
+// messages whose run failed are returned to the queue for redelivery.
+func (d *Dispatcher) Consume(ctx context.Context, trigger string, q Queue, opts ...ConsumeOption) error {
+	cfg := consumeConfig{nackFailed: true, errorBackoff: 5 * time.Second}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	for {
+		msg, err := q.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			d.logger.Warn("failed to receive message", "trigger", trigger, "error", err)
+			select {
+			case <-time.After(cfg.errorBackoff):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		event := msg.Event()
+		event.Trigger = trigger
+		_, err = d.dispatch(ctx, event, true, func(result Result) {
+			// Settle with a fresh context: the run may outlive ctx
+			settleCtx, cancel := context.WithTimeout(context.Background(), d.callbackTimeout)
+			defer cancel()
+			settle := msg.Ack
+			if !result.Succeeded() && cfg.nackFailed {
+				settle = msg.Nack
+			}
+			if err := settle(settleCtx); err != nil {
+				d.logger.Error("failed to settle message", "trigger", trigger, "event", result.EventID, "error", err)
+			}
+		})
+		if err != nil {
+			nackCtx, cancel := context.WithTimeout(context.Background(), d.callbackTimeout)
+			msg.Nack(nackCtx)
+			cancel()
+			return err
+		}
+	}
+}
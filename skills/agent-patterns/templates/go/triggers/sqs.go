@@ -0,0 +1,95 @@
+package triggers
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// SQSAPI is the subset of *sqs.Client used by SQSQueue
+type SQSAPI interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+	ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+}
+
+// SQSQueue long-polls an Amazon SQS queue. Acknowledged messages are
+// deleted; rejected messages are made visible again at once, so the
+// queue's redrive policy decides when they move to a dead-letter queue.
+// The queue's visibility timeout should exceed the trigger's timeout.
+//
+// Example:
+//
+//	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+//	go d.Consume(ctx, "triage", triggers.NewSQSQueue(sqs.NewFromConfig(awsCfg), queueURL))
+type SQSQueue struct {
+	client   SQSAPI
+	queueURL string
+}
+
+// NewSQSQueue creates a queue reading from queueURL
+func NewSQSQueue(client SQSAPI, queueURL string) *SQSQueue {
+	return &SQSQueue{client: client, queueURL: queueURL}
+}
+
+// Receive implements Queue. It takes one message at a time, so at most one
+// message at a time is held back from other consumers while it waits for
+// a free run slot.
+func (q *SQSQueue) Receive(ctx context.Context) (Message, error) {
+	for {
+		out, err := q.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(q.queueURL),
+			MaxNumberOfMessages:   1,
+			WaitTimeSeconds:       20,
+			MessageAttributeNames: []string{"All"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(out.Messages) > 0 {
+			return &sqsMessage{queue: q, msg: out.Messages[0]}, nil
+		}
+	}
+}
+
+type sqsMessage struct {
+	queue *SQSQueue
+	msg   types.Message
+}
+
+func (m *sqsMessage) Event() Event {
+	headers := make(map[string]string, len(m.msg.MessageAttributes))
+	for name, attr := range m.msg.MessageAttributes {
+		if attr.StringValue != nil {
+			headers[name] = *attr.StringValue
+		}
+	}
+	return Event{
+		ID:       aws.ToString(m.msg.MessageId),
+		Source:   SourceSQS,
+		Subject:  m.queue.queueURL,
+		Payload:  []byte(aws.ToString(m.msg.Body)),
+		Headers:  headers,
+		Received: time.Now(),
+	}
+}
+
+func (m *sqsMessage) Ack(ctx context.Context) error {
+	_, err := m.queue.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(m.queue.queueURL),
+		ReceiptHandle: m.msg.ReceiptHandle,
+	})
+	return err
+}
+
+func (m *sqsMessage) Nack(ctx context.Context) error {
+	_, err := m.queue.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(m.queue.queueURL),
+		ReceiptHandle:     m.msg.ReceiptHandle,
+		VisibilityTimeout: 0,
+	})
+	return err
+}
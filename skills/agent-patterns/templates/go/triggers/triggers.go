@@ -0,0 +1,377 @@
+/*
+ * Event-Driven Triggers for Go Agent Patterns
+ * Start agent and orchestrator runs from webhooks and message queues
+ */
+
+// Package triggers starts pattern runs in response to outside events: an
+// HTTP webhook, or a message on a NATS subject or SQS queue.
+//
+// Each Trigger names a RunFunc, how many of its runs may be in flight at
+// once, how long a run may take, and the callbacks that receive its result.
+// A Dispatcher holds the triggers and runs events against them.
+//
+// Example:
+//
+//	d := triggers.NewDispatcher(triggers.WithLogger(logger))
+//	d.Register(triggers.Trigger{
+//	    Name:          "triage",
+//	    Run:           triggers.RunAgent(newTriageAgent, 10, triggers.Prompt("Triage this issue: {{.JSON.title}}\n\n{{.JSON.body}}")),
+//	    MaxConcurrent: 4,
+//	    Timeout:       5 * time.Minute,
+//	    Callbacks:     []triggers.Callback{triggers.NewWebhookCallback("https://example.com/results")},
+//	})
+//	http.Handle("/hooks/triage", d.WebhookHandler("triage", secret))
+//	go d.Consume(ctx, "triage", triggers.NewSQSQueue(sqs.NewFromConfig(awsCfg), queueURL))
+package triggers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"text/template"
+	"time"
+
+	agentpatterns "github.com/markpitt/claude-skills/skills/agent-patterns/templates/go"
+)
+
+var (
+	// ErrUnknownTrigger is returned for events naming an unregistered trigger
+	ErrUnknownTrigger = errors.New("unknown trigger")
+	// ErrBusy is returned by TryDispatch when a trigger is at its
+	// concurrency limit
+	ErrBusy = errors.New("trigger at concurrency limit")
+	// ErrClosed is returned once the dispatcher is shutting down
+	ErrClosed = errors.New("dispatcher closed")
+)
+
+// Event sources
+const (
+	SourceWebhook = "webhook"
+	SourceNATS    = "nats"
+	SourceSQS     = "sqs"
+)
+
+// Event is an incoming webhook request or queue message
+type Event struct {
+	ID       string            `json:"id"`
+	Trigger  string            `json:"trigger"`
+	Source   string            `json:"source"`
+	Subject  string            `json:"subject,omitempty"`
+	Payload  []byte            `json:"payload"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Received time.Time         `json:"received"`
+}
+
+// RunFunc runs a pattern for an event and returns its output
+type RunFunc func(ctx context.Context, event Event) (string, error)
+
+// Result is delivered to a trigger's callbacks when a run finishes
+type Result struct {
+	Trigger  string    `json:"trigger"`
+	EventID  string    `json:"event_id"`
+	RunID    string    `json:"run_id"`
+	Output   string    `json:"output,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+}
+
+// Succeeded reports whether the run finished without error
+func (r Result) Succeeded() bool {
+	return r.Error == ""
+}
+
+// Callback receives the result of every run of a trigger
+type Callback interface {
+	Deliver(ctx context.Context, result Result) error
+}
+
+// CallbackFunc adapts a function to the Callback interface
+type CallbackFunc func(ctx context.Context, result Result) error
+
+// Deliver implements Callback
+func (f CallbackFunc) Deliver(ctx context.Context, result Result) error {
+	return f(ctx, result)
+}
+
+// Trigger configures how a kind of event starts runs
+type Trigger struct {
+	Name string
+	Run  RunFunc
+	// MaxConcurrent caps the runs in flight; zero means no limit
+	MaxConcurrent int
+	// Timeout bounds each run; zero means no limit
+	Timeout time.Duration
+	// Callbacks receive every result, in order
+	Callbacks []Callback
+}
+
+// Option configures a Dispatcher
+type Option func(*Dispatcher)
+
+// WithLogger sets the logger used to report runs and callback failures
+func WithLogger(logger *slog.Logger) Option {
+	return func(d *Dispatcher) { d.logger = logger }
+}
+
+// WithCallbackTimeout limits how long each callback may take. The default
+// is 30 seconds.
+func WithCallbackTimeout(timeout time.Duration) Option {
+	return func(d *Dispatcher) { d.callbackTimeout = timeout }
+}
+
+// Dispatcher runs events against registered triggers. Runs outlive the
+// request or message that started them, and are cancelled only by their
+// timeout or by Shutdown.
+type Dispatcher struct {
+	logger          *slog.Logger
+	callbackTimeout time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	triggers map[string]*registered
+	closed   bool
+}
+
+type registered struct {
+	Trigger
+	slots chan struct{}
+}
+
+// NewDispatcher creates an empty dispatcher
+func NewDispatcher(opts ...Option) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		callbackTimeout: 30 * time.Second,
+		ctx:             ctx,
+		cancel:          cancel,
+		triggers:        make(map[string]*registered),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Register adds a trigger, replacing any trigger with the same name
+func (d *Dispatcher) Register(t Trigger) *Dispatcher {
+	r := &registered{Trigger: t}
+	if t.MaxConcurrent > 0 {
+		r.slots = make(chan struct{}, t.MaxConcurrent)
+	}
+	d.mu.Lock()
+	d.triggers[t.Name] = r
+	d.mu.Unlock()
+	return d
+}
+
+// Dispatch starts a run of event.Trigger, waiting for a free slot if the
+// trigger is at its concurrency limit. It returns the run ID once the run
+// has started.
+func (d *Dispatcher) Dispatch(ctx context.Context, event Event) (string, error) {
+	return d.dispatch(ctx, event, true, nil)
+}
+
+// TryDispatch is like Dispatch but returns ErrBusy instead of waiting
+func (d *Dispatcher) TryDispatch(ctx context.Context, event Event) (string, error) {
+	return d.dispatch(ctx, event, false, nil)
+}
+
+// Shutdown stops accepting events and waits for runs in flight to finish.
+// If ctx is done first, the remaining runs are cancelled.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// dispatch starts a run and calls done with its result after the callbacks
+func (d *Dispatcher) dispatch(ctx context.Context, event Event, wait bool, done func(Result)) (string, error) {
+	// Count the event before releasing the lock, so Shutdown waits for it
+	d.mu.Lock()
+	t, ok := d.triggers[event.Trigger]
+	if d.closed {
+		d.mu.Unlock()
+		return "", ErrClosed
+	}
+	if !ok {
+		d.mu.Unlock()
+		return "", fmt.Errorf("%w: %q", ErrUnknownTrigger, event.Trigger)
+	}
+	d.wg.Add(1)
+	d.mu.Unlock()
+
+	if t.slots != nil {
+		var err error
+		if wait {
+			select {
+			case t.slots <- struct{}{}:
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-d.ctx.Done():
+				err = ErrClosed
+			}
+		} else {
+			select {
+			case t.slots <- struct{}{}:
+			default:
+				err = ErrBusy
+			}
+		}
+		if err != nil {
+			d.wg.Done()
+			return "", err
+		}
+	}
+
+	if event.ID == "" {
+		event.ID = newID("evt_")
+	}
+	if event.Received.IsZero() {
+		event.Received = time.Now()
+	}
+	runID := "trigger_" + event.Trigger + "_" + event.ID
+
+	go func() {
+		defer d.wg.Done()
+		if t.slots != nil {
+			defer func() { <-t.slots }()
+		}
+		result := d.run(t, event, runID)
+		d.deliver(t, result)
+		if done != nil {
+			done(result)
+		}
+	}()
+	return runID, nil
+}
+
+func (d *Dispatcher) run(t *registered, event Event, runID string) Result {
+	ctx := agentpatterns.WithRunID(d.ctx, runID)
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	result := Result{Trigger: t.Name, EventID: event.ID, RunID: runID, Started: time.Now()}
+	d.logger.Info("trigger run started", "trigger", t.Name, "event", event.ID, "source", event.Source, "run_id", runID)
+	output, err := t.Run(ctx, event)
+	result.Finished = time.Now()
+	if err != nil {
+		result.Error = err.Error()
+		d.logger.Warn("trigger run failed", "trigger", t.Name, "run_id", runID, "duration", result.Finished.Sub(result.Started), "error", err)
+	} else {
+		result.Output = output
+		d.logger.Info("trigger run finished", "trigger", t.Name, "run_id", runID, "duration", result.Finished.Sub(result.Started))
+	}
+	return result
+}
+
+// deliver hands the result to every callback. Callbacks are not cancelled
+// by Shutdown, so results of runs that finished are not lost.
+func (d *Dispatcher) deliver(t *registered, result Result) {
+	for _, cb := range t.Callbacks {
+		ctx, cancel := context.WithTimeout(context.Background(), d.callbackTimeout)
+		if err := cb.Deliver(ctx, result); err != nil {
+			d.logger.Error("trigger callback failed", "trigger", t.Name, "run_id", result.RunID, "error", err)
+		}
+		cancel()
+	}
+}
+
+func newID(prefix string) string {
+	var b [8]byte
+	rand.Read(b[:])
+	return prefix + hex.EncodeToString(b[:])
+}
+
+// PromptFunc builds a pattern's input from an event
+type PromptFunc func(event Event) (string, error)
+
+// Prompt compiles a text/template into a PromptFunc. The template sees
+// .Event, .Payload as a string, .JSON as the decoded payload when it is
+// JSON, and .Headers.
+func Prompt(text string) PromptFunc {
+	tmpl, err := template.New("prompt").Option("missingkey=zero").Parse(text)
+	return func(event Event) (string, error) {
+		if err != nil {
+			return "", fmt.Errorf("invalid prompt template: %w", err)
+		}
+		var decoded interface{}
+		if json.Unmarshal(event.Payload, &decoded) != nil {
+			decoded = nil
+		}
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, map[string]interface{}{
+			"Event":   event,
+			"Payload": string(event.Payload),
+			"JSON":    decoded,
+			"Headers": event.Headers,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to render prompt: %w", err)
+		}
+		return buf.String(), nil
+	}
+}
+
+// RunAgent runs a fresh agent from newAgent for each event, since an
+// AutonomousAgent holds the state of one run at a time
+func RunAgent(newAgent func() *agentpatterns.AutonomousAgent, maxSteps int, prompt PromptFunc) RunFunc {
+	return func(ctx context.Context, event Event) (string, error) {
+		task, err := prompt(event)
+		if err != nil {
+			return "", err
+		}
+		result, err := newAgent().Run(ctx, task, maxSteps)
+		if err != nil {
+			return "", err
+		}
+		if !result.Success {
+			return "", fmt.Errorf("agent did not complete within %d steps", maxSteps)
+		}
+		return result.FinalResult, nil
+	}
+}
+
+// RunOrchestrator runs orch for each event
+func RunOrchestrator(orch *agentpatterns.Orchestrator, prompt PromptFunc) RunFunc {
+	return func(ctx context.Context, event Event) (string, error) {
+		task, err := prompt(event)
+		if err != nil {
+			return "", err
+		}
+		result, err := orch.Execute(ctx, task)
+		if err != nil {
+			return "", err
+		}
+		return result.FinalResult, nil
+	}
+}
@@ -0,0 +1,143 @@
+package triggers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxWebhookBody limits the size of webhook payloads
+const maxWebhookBody = 10 << 20
+
+// WebhookHandler serves POST requests that start runs of the named
+// trigger. If secret is set, requests must carry an X-Signature-256 header
+// with the hex HMAC-SHA256 of the body, as sent by GitHub and similar
+// services. An X-Event-ID header, if present, becomes the event ID.
+//
+// The handler answers 202 Accepted with the run ID once the run has
+// started, and 429 Too Many Requests if the trigger is at its concurrency
+// limit, so senders retry later instead of queueing in memory.
+func (d *Dispatcher) WebhookHandler(trigger, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		if secret != "" && !validSignature(secret, body, r.Header.Get("X-Signature-256")) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		headers := make(map[string]string, len(r.Header))
+		for name, values := range r.Header {
+			if name == "Authorization" || name == "Cookie" || len(values) == 0 {
+				continue
+			}
+			headers[name] = values[0]
+		}
+		event := Event{
+			ID:       r.Header.Get("X-Event-ID"),
+			Trigger:  trigger,
+			Source:   SourceWebhook,
+			Subject:  r.URL.Path,
+			Payload:  body,
+			Headers:  headers,
+			Received: time.Now(),
+		}
+
+		runID, err := d.TryDispatch(r.Context(), event)
+		switch {
+		case errors.Is(err, ErrBusy):
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		case errors.Is(err, ErrClosed):
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		case errors.Is(err, ErrUnknownTrigger):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"run_id": runID})
+	})
+}
+
+// validSignature checks a "sha256=<hex>" HMAC header against body
+func validSignature(secret string, body []byte, header string) bool {
+	const prefix = "sha256="
+	if len(header) <= len(prefix) || header[:len(prefix)] != prefix {
+		return false
+	}
+	got, err := hex.DecodeString(header[len(prefix):])
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(got, sign(secret, body))
+}
+
+func sign(secret string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// WebhookCallback posts each result as JSON to a URL. If Secret is set,
+// each POST carries an X-Signature-256 header with the hex HMAC-SHA256 of
+// the body, so the receiver can verify the sender.
+type WebhookCallback struct {
+	URL        string
+	Secret     string
+	HTTPClient *http.Client
+}
+
+// NewWebhookCallback creates a callback posting to url
+func NewWebhookCallback(url string) *WebhookCallback {
+	return &WebhookCallback{URL: url, HTTPClient: &http.Client{}}
+}
+
+// Deliver implements Callback
+func (c *WebhookCallback) Deliver(ctx context.Context, result Result) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("content-type", "application/json")
+	if c.Secret != "" {
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(sign(c.Secret, body)))
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("result webhook error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
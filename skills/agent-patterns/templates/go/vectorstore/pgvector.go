@@ -0,0 +1,148 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PGVector stores records in PostgreSQL with the pgvector extension. It uses
+// database/sql, so open the database with any PostgreSQL driver, e.g.
+//
+//	db, err := sql.Open("pgx", os.Getenv("DATABASE_URL")) // github.com/jackc/pgx/v5/stdlib
+//	vs, err := vectorstore.NewPGVector(ctx, db, "docs", 1024)
+type PGVector struct {
+	db    *sql.DB
+	table string
+}
+
+// NewPGVector creates the extension and table if needed. dims must match
+// the embedding model, e.g. 1024 for voyage-3.
+func NewPGVector(ctx context.Context, db *sql.DB, table string, dims int) (*PGVector, error) {
+	if !isIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	v := &PGVector{db: db, table: table}
+	stmts := []string{
+		`CREATE EXTENSION IF NOT EXISTS vector`,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	embedding vector(%d) NOT NULL,
+	text TEXT NOT NULL,
+	metadata JSONB NOT NULL DEFAULT '{}'
+)`, table, dims),
+	}
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("failed to create vector table: %w", err)
+		}
+	}
+	return v, nil
+}
+
+// Upsert implements VectorStore
+func (v *PGVector) Upsert(ctx context.Context, records []Record) error {
+	tx, err := v.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (id, embedding, text, metadata) VALUES ($1, $2::vector, $3, $4)
+ON CONFLICT (id) DO UPDATE SET embedding = excluded.embedding, text = excluded.text, metadata = excluded.metadata`, v.table))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range records {
+		metadata, err := json.Marshal(r.Metadata)
+		if err != nil {
+			return err
+		}
+		if r.Metadata == nil {
+			metadata = []byte("{}")
+		}
+		if _, err := stmt.ExecContext(ctx, r.ID, vectorLiteral(r.Vector), r.Text, metadata); err != nil {
+			return fmt.Errorf("failed to upsert %s: %w", r.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Query implements VectorStore using cosine distance
+func (v *PGVector) Query(ctx context.Context, vector []float32, k int) ([]Match, error) {
+	return v.QueryFiltered(ctx, vector, k, nil)
+}
+
+// QueryFiltered implements VectorStore, filtering with JSONB containment
+// so a GIN index on metadata can serve it
+func (v *PGVector) QueryFiltered(ctx context.Context, vector []float32, k int, filter Filter) ([]Match, error) {
+	if filter == nil {
+		filter = Filter{}
+	}
+	contains, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := v.db.QueryContext(ctx, fmt.Sprintf(`SELECT id, text, metadata, 1 - (embedding <=> $1::vector) AS score
+FROM %s WHERE metadata @> $3::jsonb ORDER BY embedding <=> $1::vector LIMIT $2`, v.table), vectorLiteral(vector), k, contains)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var m Match
+		var metadata []byte
+		if err := rows.Scan(&m.ID, &m.Text, &metadata, &m.Score); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(metadata, &m.Metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata for %s: %w", m.ID, err)
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// Delete implements VectorStore
+func (v *PGVector) Delete(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		if _, err := v.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, v.table), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vectorLiteral formats a vector in pgvector's text format, e.g. [0.1,0.2]
+func vectorLiteral(vector []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range vector {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,207 @@
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Qdrant stores records in a Qdrant collection over its REST API. Record
+// IDs are mapped to deterministic UUIDs, since Qdrant only accepts integer
+// or UUID point IDs; the original ID is kept in the payload.
+type Qdrant struct {
+	BaseURL    string
+	Collection string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// NewQdrant creates a store for collection on the server at baseURL, e.g.
+// "http://localhost:6333"
+func NewQdrant(baseURL, collection string) *Qdrant {
+	return &Qdrant{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Collection: collection,
+		HTTPClient: &http.Client{},
+	}
+}
+
+// EnsureCollection creates the collection with cosine distance if it does
+// not exist. dims must match the embedding model.
+func (q *Qdrant) EnsureCollection(ctx context.Context, dims int) error {
+	err := q.do(ctx, http.MethodGet, q.collectionPath(""), nil, nil)
+	if err == nil {
+		return nil
+	}
+	body := map[string]interface{}{
+		"vectors": map[string]interface{}{"size": dims, "distance": "Cosine"},
+	}
+	if err := q.do(ctx, http.MethodPut, q.collectionPath(""), body, nil); err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+	return nil
+}
+
+type qdrantPoint struct {
+	ID      string                 `json:"id"`
+	Vector  []float32              `json:"vector,omitempty"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+// Upsert implements VectorStore
+func (q *Qdrant) Upsert(ctx context.Context, records []Record) error {
+	points := make([]qdrantPoint, len(records))
+	for i, r := range records {
+		points[i] = qdrantPoint{
+			ID:     pointID(r.ID),
+			Vector: r.Vector,
+			Payload: map[string]interface{}{
+				"id":       r.ID,
+				"text":     r.Text,
+				"metadata": r.Metadata,
+			},
+		}
+	}
+	return q.do(ctx, http.MethodPut, q.collectionPath("/points?wait=true"), map[string]interface{}{"points": points}, nil)
+}
+
+// qdrantScoredPoint is a search hit
+type qdrantScoredPoint struct {
+	Score   float64 `json:"score"`
+	Payload struct {
+		ID       string            `json:"id"`
+		Text     string            `json:"text"`
+		Metadata map[string]string `json:"metadata"`
+	} `json:"payload"`
+}
+
+// Query implements VectorStore
+func (q *Qdrant) Query(ctx context.Context, vector []float32, k int) ([]Match, error) {
+	return q.QueryFiltered(ctx, vector, k, nil)
+}
+
+// QueryFiltered implements VectorStore
+func (q *Qdrant) QueryFiltered(ctx context.Context, vector []float32, k int, filter Filter) ([]Match, error) {
+	var resp struct {
+		Result []qdrantScoredPoint `json:"result"`
+	}
+	if err := q.do(ctx, http.MethodPost, q.collectionPath("/points/search"), qdrantSearch(vector, k, filter), &resp); err != nil {
+		return nil, err
+	}
+	return qdrantMatches(resp.Result), nil
+}
+
+// QueryBatch implements BatchQuerier with a single batch search request
+func (q *Qdrant) QueryBatch(ctx context.Context, vectors [][]float32, k int, filter Filter) ([][]Match, error) {
+	searches := make([]map[string]interface{}, len(vectors))
+	for i, vector := range vectors {
+		searches[i] = qdrantSearch(vector, k, filter)
+	}
+	var resp struct {
+		Result [][]qdrantScoredPoint `json:"result"`
+	}
+	if err := q.do(ctx, http.MethodPost, q.collectionPath("/points/search/batch"), map[string]interface{}{"searches": searches}, &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([][]Match, len(resp.Result))
+	for i, points := range resp.Result {
+		results[i] = qdrantMatches(points)
+	}
+	return results, nil
+}
+
+// qdrantSearch builds a search request, matching filter against the
+// metadata payload
+func qdrantSearch(vector []float32, k int, filter Filter) map[string]interface{} {
+	search := map[string]interface{}{
+		"vector":       vector,
+		"limit":        k,
+		"with_payload": true,
+	}
+	if len(filter) > 0 {
+		must := make([]map[string]interface{}, 0, len(filter))
+		for key, value := range filter {
+			must = append(must, map[string]interface{}{
+				"key":   "metadata." + key,
+				"match": map[string]interface{}{"value": value},
+			})
+		}
+		search["filter"] = map[string]interface{}{"must": must}
+	}
+	return search
+}
+
+func qdrantMatches(points []qdrantScoredPoint) []Match {
+	matches := make([]Match, len(points))
+	for i, p := range points {
+		matches[i] = Match{
+			Record: Record{ID: p.Payload.ID, Text: p.Payload.Text, Metadata: p.Payload.Metadata},
+			Score:  p.Score,
+		}
+	}
+	return matches
+}
+
+// Delete implements VectorStore
+func (q *Qdrant) Delete(ctx context.Context, ids ...string) error {
+	points := make([]string, len(ids))
+	for i, id := range ids {
+		points[i] = pointID(id)
+	}
+	return q.do(ctx, http.MethodPost, q.collectionPath("/points/delete?wait=true"), map[string]interface{}{"points": points}, nil)
+}
+
+func (q *Qdrant) collectionPath(suffix string) string {
+	return "/collections/" + url.PathEscape(q.Collection) + suffix
+}
+
+// do sends a JSON request and decodes the response into out, if non-nil
+func (q *Qdrant) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, q.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if q.APIKey != "" {
+		req.Header.Set("api-key", q.APIKey)
+	}
+
+	resp, err := q.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("qdrant error (status %d): %s", resp.StatusCode, string(data))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// pointID derives a UUID from a record ID
+func pointID(id string) string {
+	h := sha1.Sum([]byte(id))
+	h[6] = (h[6] & 0x0f) | 0x50 // version 5
+	h[8] = (h[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
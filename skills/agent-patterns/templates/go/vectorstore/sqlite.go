@@ -0,0 +1,136 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// SQLite stores records in a table of a SQLite database, for retrieval
+// without a vector database server. It uses database/sql, so open the
+// database with any SQLite driver, e.g.
+//
+//	db, err := sql.Open("sqlite", "vectors.db") // modernc.org/sqlite
+//	vs, err := vectorstore.NewSQLite(ctx, db, "docs")
+//
+// Queries scan the table and compute cosine similarity in Go, which suits
+// corpora of up to about a hundred thousand chunks. Embeddings are stored
+// as little-endian float32 blobs, the format sqlite-vec reads, so a vec0
+// index can be built over the same table as the corpus grows.
+type SQLite struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLite creates the table if needed
+func NewSQLite(ctx context.Context, db *sql.DB, table string) (*SQLite, error) {
+	if !isIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	embedding BLOB NOT NULL,
+	text TEXT NOT NULL,
+	metadata TEXT NOT NULL DEFAULT '{}'
+)`, table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vector table: %w", err)
+	}
+	return &SQLite{db: db, table: table}, nil
+}
+
+// Upsert implements VectorStore
+func (s *SQLite) Upsert(ctx context.Context, records []Record) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (id, embedding, text, metadata) VALUES (?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET embedding = excluded.embedding, text = excluded.text, metadata = excluded.metadata`, s.table))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range records {
+		metadata := []byte("{}")
+		if r.Metadata != nil {
+			if metadata, err = json.Marshal(r.Metadata); err != nil {
+				return err
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, r.ID, encodeVector(r.Vector), r.Text, string(metadata)); err != nil {
+			return fmt.Errorf("failed to upsert %s: %w", r.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Query implements VectorStore
+func (s *SQLite) Query(ctx context.Context, vector []float32, k int) ([]Match, error) {
+	return s.QueryFiltered(ctx, vector, k, nil)
+}
+
+// QueryFiltered implements VectorStore
+func (s *SQLite) QueryFiltered(ctx context.Context, vector []float32, k int, filter Filter) ([]Match, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT id, embedding, text, metadata FROM %s`, s.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var r Record
+		var embedding []byte
+		var metadata string
+		if err := rows.Scan(&r.ID, &embedding, &r.Text, &metadata); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(metadata), &r.Metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata for %s: %w", r.ID, err)
+		}
+		if !filter.Matches(r.Metadata) {
+			continue
+		}
+		r.Vector = decodeVector(embedding)
+		matches = append(matches, Match{Record: r, Score: Cosine(vector, r.Vector)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return topK(matches, k), nil
+}
+
+// Delete implements VectorStore
+func (s *SQLite) Delete(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, s.table), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeVector packs a vector as little-endian float32s
+func encodeVector(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, x := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+// decodeVector unpacks a vector written by encodeVector
+func decodeVector(buf []byte) []float32 {
+	vector := make([]float32, len(buf)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vector
+}
@@ -0,0 +1,174 @@
+/*
+ * Vector Stores for Go Agent Patterns
+ * Embedding storage and similarity search for retrieval-augmented generation
+ */
+
+// Package vectorstore defines the VectorStore interface used by the RAG
+// pattern, with in-memory, pgvector, Qdrant, and SQLite implementations.
+//
+// Example:
+//
+//	vs := vectorstore.NewMemory()
+//	rag := agentpatterns.NewRAG(client, embedder, vs)
+//	_, err := rag.Index(ctx, "handbook", handbookText, nil)
+//	result, err := rag.RetrieveAndAnswer(ctx, "How many vacation days do I get?")
+package vectorstore
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Record is a stored chunk of text and its embedding
+type Record struct {
+	ID       string            `json:"id"`
+	Vector   []float32         `json:"vector"`
+	Text     string            `json:"text"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Match is a query result. Score is the cosine similarity, higher is closer.
+type Match struct {
+	Record
+	Score float64 `json:"score"`
+}
+
+// VectorStore stores records and finds those nearest to a query vector.
+// Implementations must be safe for concurrent use.
+type VectorStore interface {
+	// Upsert inserts records, replacing any with the same ID
+	Upsert(ctx context.Context, records []Record) error
+	// Query returns up to k records ordered by decreasing similarity
+	Query(ctx context.Context, vector []float32, k int) ([]Match, error)
+	// QueryFiltered is Query over only the records matching filter
+	QueryFiltered(ctx context.Context, vector []float32, k int, filter Filter) ([]Match, error)
+	Delete(ctx context.Context, ids ...string) error
+}
+
+// Filter restricts a query to records whose metadata has each key set to
+// the given value. An empty Filter matches every record.
+type Filter map[string]string
+
+// Matches reports whether metadata satisfies f
+func (f Filter) Matches(metadata map[string]string) bool {
+	for k, v := range f {
+		if got, ok := metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// BatchQuerier is implemented by stores that can answer several queries
+// in one round trip
+type BatchQuerier interface {
+	QueryBatch(ctx context.Context, vectors [][]float32, k int, filter Filter) ([][]Match, error)
+}
+
+// QueryBatch returns the matches for each of vectors, in order. It uses a
+// single request when vs is a BatchQuerier and concurrent queries
+// otherwise.
+func QueryBatch(ctx context.Context, vs VectorStore, vectors [][]float32, k int, filter Filter) ([][]Match, error) {
+	if bq, ok := vs.(BatchQuerier); ok {
+		return bq.QueryBatch(ctx, vectors, k, filter)
+	}
+
+	results := make([][]Match, len(vectors))
+	errs := make([]error, len(vectors))
+	var wg sync.WaitGroup
+	for i, vector := range vectors {
+		wg.Add(1)
+		go func(idx int, v []float32) {
+			defer wg.Done()
+			results[idx], errs[idx] = vs.QueryFiltered(ctx, v, k, filter)
+		}(i, vector)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// Memory is an in-memory VectorStore using brute-force cosine similarity.
+// It suits tests and corpora of up to a few thousand chunks.
+type Memory struct {
+	mu      sync.RWMutex
+	records map[string]Record
+}
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{records: make(map[string]Record)}
+}
+
+// Upsert implements VectorStore
+func (m *Memory) Upsert(ctx context.Context, records []Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range records {
+		m.records[r.ID] = r
+	}
+	return nil
+}
+
+// Query implements VectorStore
+func (m *Memory) Query(ctx context.Context, vector []float32, k int) ([]Match, error) {
+	return m.QueryFiltered(ctx, vector, k, nil)
+}
+
+// QueryFiltered implements VectorStore
+func (m *Memory) QueryFiltered(ctx context.Context, vector []float32, k int, filter Filter) ([]Match, error) {
+	m.mu.RLock()
+	matches := make([]Match, 0, len(m.records))
+	for _, r := range m.records {
+		if filter.Matches(r.Metadata) {
+			matches = append(matches, Match{Record: r, Score: Cosine(vector, r.Vector)})
+		}
+	}
+	m.mu.RUnlock()
+
+	return topK(matches, k), nil
+}
+
+// Delete implements VectorStore
+func (m *Memory) Delete(ctx context.Context, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.records, id)
+	}
+	return nil
+}
+
+// topK sorts matches by decreasing score and keeps the first k, or all of
+// them if k is not positive
+func topK(matches []Match, k int) []Match {
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 if their lengths
+// differ or either is zero
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,179 @@
+/*
+ * Chain-of-Verification Pattern Implementation for Go
+ * Draft, plan verification questions, answer them independently, and revise
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/jsonx"
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/schema"
+)
+
+// VerificationQA is a verification question and its independent answer
+type VerificationQA struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	Error    string `json:"error,omitempty"`
+}
+
+// verificationPlan is the structured output of the planning step
+type verificationPlan struct {
+	Questions []string `json:"questions" description:"Short factual questions, each checking one claim in the draft and answerable on its own"`
+}
+
+// VerificationResult is the outcome of a Chain-of-Verification run
+type VerificationResult struct {
+	Draft         string
+	Verifications []VerificationQA
+	Final         string
+}
+
+// ChainOfVerification reduces hallucinations by checking a draft answer
+// against independently answered verification questions. The questions are
+// answered without seeing the draft so its mistakes are not repeated.
+//
+// Example:
+//
+//	cove := NewChainOfVerification(client).SetMaxQuestions(5)
+//	result, err := cove.Run(ctx, "Name five politicians born in New York City")
+//	fmt.Println(result.Final)
+type ChainOfVerification struct {
+	client        *AnthropicClient
+	cfg           patternConfig
+	maxQuestions  int
+	verifierModel string
+}
+
+// NewChainOfVerification creates a new ChainOfVerification
+func NewChainOfVerification(client *AnthropicClient, opts ...Option) *ChainOfVerification {
+	cfg := newPatternConfig("chain_of_verification", opts)
+	return &ChainOfVerification{
+		client:        client,
+		cfg:           cfg,
+		maxQuestions:  5,
+		verifierModel: cfg.model,
+	}
+}
+
+// SetMaxQuestions caps the number of verification questions
+func (v *ChainOfVerification) SetMaxQuestions(n int) *ChainOfVerification {
+	v.maxQuestions = n
+	return v
+}
+
+// WithVerifierModel sets a different model for answering verification
+// questions, e.g. a cheaper one
+func (v *ChainOfVerification) WithVerifierModel(model string) *ChainOfVerification {
+	v.verifierModel = model
+	return v
+}
+
+// Run drafts an answer to query, verifies it, and returns the revision
+func (v *ChainOfVerification) Run(ctx context.Context, query string) (*VerificationResult, error) {
+	ctx, cancel := v.cfg.startRun(ctx)
+	defer cancel()
+
+	draftCtx, span := StartSpan(ctx, "cove.draft")
+	draft, err := v.cfg.call(draftCtx, v.client, query, v.cfg.model, v.cfg.tokens(2048))
+	span.Finish(err)
+	if err != nil {
+		return nil, fmt.Errorf("draft failed: %w", err)
+	}
+
+	planCtx, span := StartSpan(ctx, "cove.plan")
+	questions, err := v.plan(planCtx, query, draft)
+	span.SetAttribute("questions", len(questions))
+	span.Finish(err)
+	if err != nil {
+		return nil, fmt.Errorf("planning verification failed: %w", err)
+	}
+	if len(questions) == 0 {
+		return &VerificationResult{Draft: draft, Final: draft}, nil
+	}
+
+	verifyCtx, span := StartSpan(ctx, "cove.verify")
+	verifications := v.verify(verifyCtx, questions)
+	span.Finish(nil)
+
+	reviseCtx, span := StartSpan(ctx, "cove.revise")
+	final, err := v.revise(reviseCtx, query, draft, verifications)
+	span.Finish(err)
+	if err != nil {
+		return nil, fmt.Errorf("revision failed: %w", err)
+	}
+
+	return &VerificationResult{
+		Draft:         draft,
+		Verifications: verifications,
+		Final:         final,
+	}, nil
+}
+
+func (v *ChainOfVerification) plan(ctx context.Context, query, draft string) ([]string, error) {
+	prompt, err := v.cfg.prompt("verification.plan", map[string]interface{}{
+		"max_questions": v.maxQuestions,
+		"query":         query,
+		"draft":         draft,
+		"schema":        schema.MustFor[verificationPlan]().String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := v.cfg.call(ctx, v.client, prompt, v.cfg.model, 1024)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan verificationPlan
+	if err := jsonx.Unmarshal(response, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse verification questions: %w", err)
+	}
+	if v.maxQuestions > 0 && len(plan.Questions) > v.maxQuestions {
+		plan.Questions = plan.Questions[:v.maxQuestions]
+	}
+	return plan.Questions, nil
+}
+
+// verify answers each question concurrently. A failed question is recorded
+// and left out of the revision rather than failing the run.
+func (v *ChainOfVerification) verify(ctx context.Context, questions []string) []VerificationQA {
+	results := make([]VerificationQA, len(questions))
+	var wg sync.WaitGroup
+	for i, question := range questions {
+		wg.Add(1)
+		go func(idx int, q string) {
+			defer wg.Done()
+			prompt, err := v.cfg.prompt("verification.answer", map[string]interface{}{"question": q})
+			var answer string
+			if err == nil {
+				answer, err = v.cfg.call(ctx, v.client, prompt, v.verifierModel, 512)
+			}
+			results[idx] = VerificationQA{Question: q, Answer: strings.TrimSpace(answer)}
+			if err != nil {
+				results[idx].Error = err.Error()
+				v.cfg.logger.Warn("verification question failed", "question", q, "error", err)
+			}
+		}(i, question)
+	}
+	wg.Wait()
+	return results
+}
+
+func (v *ChainOfVerification) revise(ctx context.Context, query, draft string, verifications []VerificationQA) (string, error) {
+	prompt, err := v.cfg.prompt("verification.revise", map[string]interface{}{
+		"query":         query,
+		"draft":         draft,
+		"verifications": verifications,
+	})
+	if err != nil {
+		return "", err
+	}
+	return v.cfg.call(ctx, v.client, prompt, v.cfg.model, v.cfg.tokens(2048))
+}
@@ -0,0 +1,429 @@
+/*
+ * Workflow Engine for Go Agent Patterns
+ * DAGs of LLM calls, tools, guardrails, and approvals, run concurrently where the edges allow
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/approvals"
+)
+
+// NodeFunc runs a workflow node. inputs holds the run's inputs and the
+// outputs of the nodes that succeeded before it started, by node ID.
+type NodeFunc func(ctx context.Context, inputs map[string]interface{}) (interface{}, error)
+
+// Node is a step of a Workflow. Exactly one of Prompt, Tool, Guardrail,
+// Approver, and Run says what the node does.
+type Node struct {
+	ID string
+	// Prompt calls the model with the template rendered from the inputs;
+	// the output is the reply
+	Prompt *PromptTemplate
+	// Tool calls a tool with Args built from the inputs, or with the
+	// inputs themselves; the output is the tool's result
+	Tool *AgentTool
+	Args func(inputs map[string]interface{}) map[string]interface{}
+	// Guardrail checks the input named Input, which becomes the output if
+	// it passes; a failed check fails the node
+	Guardrail Guardrail
+	// Approver must approve the input named Input, which becomes the
+	// output if it is approved; a rejection fails the node
+	Approver approvals.Approver
+	// Input names the input, or node, a Guardrail or Approver node reads
+	Input string
+	// Run is any other step
+	Run NodeFunc
+	// ContinueOnError keeps the run going when the node fails, as does an
+	// OnFailure or OnComplete edge out of it
+	ContinueOnError bool
+	// JoinAny runs the node once any incoming edge is taken rather than
+	// all of them, so both arms of a branch can join at it
+	JoinAny bool
+}
+
+// EdgeType says which outcomes of its source an edge follows
+type EdgeType int
+
+// Edge types
+const (
+	// OnSuccess edges are taken when their source succeeds
+	OnSuccess EdgeType = iota
+	// OnFailure edges are taken when their source fails, e.g. to a
+	// fallback node
+	OnFailure
+	// OnComplete edges are taken whether their source succeeds or fails
+	OnComplete
+)
+
+// Edge makes To wait for From. A node whose incoming edges are not taken
+// is skipped, and so are the nodes waiting on it.
+type Edge struct {
+	From string
+	To   string
+	Type EdgeType
+	// When, if set, must also hold for the edge to be taken
+	When func(result NodeResult) bool
+}
+
+// NodeResult is the outcome of a node
+type NodeResult struct {
+	ID     string
+	Output interface{}
+	Err    error
+}
+
+// WorkflowResult is the result of running a Workflow
+type WorkflowResult struct {
+	RunID string
+	// Outputs holds the output of each node that succeeded, by ID
+	Outputs map[string]interface{}
+	// Results lists the nodes that ran, in the order they finished
+	Results []NodeResult
+	// Skipped lists the nodes none of whose edges were taken
+	Skipped []string
+}
+
+// nodeStatus is the state of a node within a run
+type nodeStatus int
+
+const (
+	nodePending nodeStatus = iota
+	nodeRunning
+	nodeSucceeded
+	nodeFailed
+	nodeSkipped
+)
+
+// Workflow runs a graph of nodes. Each node starts once every node it has
+// an edge from has finished, so nodes without a path between them run
+// concurrently. PromptChain and Orchestrator run on it: a chain is a line
+// of nodes, and an orchestrator's plan a graph of subtasks.
+//
+// Example:
+//
+//	wf := NewWorkflow(client).
+//	    AddNode(Node{ID: "draft", Prompt: MustParsePromptTemplate("draft", "Write a reply to: {{.ticket}}")}).
+//	    AddNode(Node{ID: "pii", Guardrail: NewPIIGuardrail(), Input: "draft"}).
+//	    AddNode(Node{ID: "review", Approver: approver, Input: "pii"}).
+//	    AddNode(Node{ID: "send", Tool: &sendTool, Args: func(in map[string]interface{}) map[string]interface{} {
+//	        return map[string]interface{}{"body": in["review"]}
+//	    }}).
+//	    Connect("draft", "pii").Connect("pii", "review").Connect("review", "send")
+//	result, err := wf.Run(ctx, map[string]interface{}{"ticket": ticket})
+type Workflow struct {
+	client     *AnthropicClient
+	cfg        patternConfig
+	nodes      map[string]Node
+	order      []string
+	edges      []Edge
+	onFinished func(ctx context.Context, result NodeResult) error
+	// quiet leaves spans and events to the pattern running the workflow
+	quiet bool
+}
+
+// NewWorkflow creates an empty workflow
+func NewWorkflow(client *AnthropicClient, opts ...Option) *Workflow {
+	return &Workflow{
+		client: client,
+		cfg:    newPatternConfig("workflow", opts),
+		nodes:  make(map[string]Node),
+	}
+}
+
+// newWorkflow creates a workflow for a pattern that publishes its own
+// events
+func newWorkflow(client *AnthropicClient, cfg patternConfig) *Workflow {
+	return &Workflow{client: client, cfg: cfg, nodes: make(map[string]Node), quiet: true}
+}
+
+// AddNode adds a node, replacing any with the same ID (builder pattern).
+// Nodes ready at the same time start in the order they were added.
+func (w *Workflow) AddNode(node Node) *Workflow {
+	if _, exists := w.nodes[node.ID]; !exists {
+		w.order = append(w.order, node.ID)
+	}
+	w.nodes[node.ID] = node
+	return w
+}
+
+// AddEdge adds an edge (builder pattern)
+func (w *Workflow) AddEdge(edge Edge) *Workflow {
+	w.edges = append(w.edges, edge)
+	return w
+}
+
+// Connect adds an OnSuccess edge (builder pattern)
+func (w *Workflow) Connect(from, to string) *Workflow {
+	return w.AddEdge(Edge{From: from, To: to})
+}
+
+// ConnectIf adds an OnSuccess edge taken only when cond holds for the
+// source's output, e.g. to branch on a classification (builder pattern)
+func (w *Workflow) ConnectIf(from, to string, cond func(output interface{}) bool) *Workflow {
+	return w.AddEdge(Edge{From: from, To: to, When: func(r NodeResult) bool { return cond(r.Output) }})
+}
+
+// OnNodeFinished calls fn after each node runs, one node at a time and
+// also while the run is stopping; an error stops the run (builder pattern)
+func (w *Workflow) OnNodeFinished(fn func(ctx context.Context, result NodeResult) error) *Workflow {
+	w.onFinished = fn
+	return w
+}
+
+// Run runs the workflow with inputs. A node's failure stops the run unless
+// the node continues on error or an edge handles it; nodes already running
+// finish first. The result holds the nodes finished so far even when an
+// error is returned. done lists nodes finished earlier, e.g. before a
+// restart, which are not run again. Canceling ctx stops the run in the
+// same way, returning a *CanceledError whose Partial is the result.
+func (w *Workflow) Run(ctx context.Context, inputs map[string]interface{}, done ...NodeResult) (result *WorkflowResult, err error) {
+	ctx, cancel := w.cfg.startRun(ctx)
+	defer cancel()
+	result, err = w.run(ctx, inputs, done)
+	if err != nil {
+		err = budgetStop(ctx, err, result)
+	}
+	return result, err
+}
+
+// validate checks that the edges join known nodes without a cycle
+func (w *Workflow) validate() error {
+	next := make(map[string][]string)
+	for _, e := range w.edges {
+		for _, id := range []string{e.From, e.To} {
+			if _, exists := w.nodes[id]; !exists {
+				return fmt.Errorf("workflow edge %s -> %s: unknown node '%s'", e.From, e.To, id)
+			}
+		}
+		next[e.From] = append(next[e.From], e.To)
+	}
+	for _, id := range w.order {
+		node := w.nodes[id]
+		kinds := 0
+		for _, set := range []bool{node.Prompt != nil, node.Tool != nil, node.Guardrail != nil, node.Approver != nil, node.Run != nil} {
+			if set {
+				kinds++
+			}
+		}
+		if kinds != 1 {
+			return fmt.Errorf("workflow node '%s' must have exactly one of Prompt, Tool, Guardrail, Approver, or Run", id)
+		}
+	}
+
+	visited := make(map[string]bool)
+	visiting := make(map[string]bool)
+	var visit func(id string) error
+	visit = func(id string) error {
+		if visiting[id] {
+			return fmt.Errorf("circular dependency detected: %s", id)
+		}
+		if visited[id] {
+			return nil
+		}
+		visiting[id] = true
+		for _, to := range next[id] {
+			if err := visit(to); err != nil {
+				return err
+			}
+		}
+		delete(visiting, id)
+		visited[id] = true
+		return nil
+	}
+	for _, id := range w.order {
+		if err := visit(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run schedules the nodes, starting each as soon as its incoming edges are
+// resolved
+func (w *Workflow) run(ctx context.Context, inputs map[string]interface{}, done []NodeResult) (*WorkflowResult, error) {
+	result := &WorkflowResult{RunID: RunIDFromContext(ctx), Outputs: make(map[string]interface{})}
+	if err := w.validate(); err != nil {
+		return result, err
+	}
+
+	incoming := make(map[string][]Edge)
+	handled := make(map[string]bool)
+	for _, e := range w.edges {
+		incoming[e.To] = append(incoming[e.To], e)
+		if e.Type != OnSuccess {
+			handled[e.From] = true
+		}
+	}
+
+	// values holds what nodes see as their inputs
+	values := make(map[string]interface{}, len(inputs)+len(w.nodes))
+	for k, v := range inputs {
+		values[k] = v
+	}
+	status := make(map[string]nodeStatus, len(w.nodes))
+	outcomes := make(map[string]NodeResult, len(w.nodes))
+	record := func(r NodeResult) {
+		outcomes[r.ID] = r
+		result.Results = append(result.Results, r)
+		if r.Err != nil {
+			status[r.ID] = nodeFailed
+			return
+		}
+		status[r.ID] = nodeSucceeded
+		values[r.ID] = r.Output
+		result.Outputs[r.ID] = r.Output
+	}
+	for _, r := range done {
+		if _, exists := w.nodes[r.ID]; exists {
+			record(r)
+		}
+	}
+
+	// resolve reports whether every edge into id is resolved and, if so,
+	// whether the node should run
+	resolve := func(id string) (resolved, runs bool) {
+		edges := incoming[id]
+		if len(edges) == 0 {
+			return true, true
+		}
+		taken := 0
+		for _, e := range edges {
+			switch status[e.From] {
+			case nodePending, nodeRunning:
+				return false, false
+			case nodeSkipped:
+				continue
+			}
+			r := outcomes[e.From]
+			follows := e.Type == OnComplete || (e.Type == OnSuccess) == (r.Err == nil)
+			if follows && (e.When == nil || e.When(r)) {
+				taken++
+			}
+		}
+		if w.nodes[id].JoinAny {
+			return true, taken > 0
+		}
+		return true, taken == len(edges)
+	}
+
+	finished := make(chan NodeResult)
+	running := 0
+	var stopErr error
+	for {
+		// Skipping a node can resolve the nodes after it, so sweep until
+		// nothing changes
+		for changed := true; changed; {
+			changed = false
+			for _, id := range w.order {
+				if status[id] != nodePending {
+					continue
+				}
+				resolved, runs := resolve(id)
+				if !resolved {
+					continue
+				}
+				if !runs {
+					status[id] = nodeSkipped
+					result.Skipped = append(result.Skipped, id)
+					changed = true
+					continue
+				}
+				// Stop launching after a failure, a hook error, or
+				// cancellation, but let running nodes finish; they see the
+				// canceled context too
+				if stopErr == nil && ctx.Err() != nil {
+					stopErr = ctx.Err()
+				}
+				if stopErr != nil || (w.cfg.maxConcurrency > 0 && running >= w.cfg.maxConcurrency) {
+					continue
+				}
+
+				nodeInputs := make(map[string]interface{}, len(values))
+				for k, v := range values {
+					nodeInputs[k] = v
+				}
+				status[id] = nodeRunning
+				running++
+				go func(node Node) {
+					output, err := w.execute(ctx, node, nodeInputs)
+					finished <- NodeResult{ID: node.ID, Output: output, Err: err}
+				}(w.nodes[id])
+			}
+		}
+		if running == 0 {
+			break
+		}
+
+		r := <-finished
+		running--
+		record(r)
+		if r.Err != nil && stopErr == nil && !w.nodes[r.ID].ContinueOnError && !handled[r.ID] {
+			stopErr = r.Err
+		}
+		if w.onFinished != nil {
+			if err := w.onFinished(ctx, r); err != nil && stopErr == nil {
+				stopErr = err
+			}
+		}
+	}
+	return result, stopErr
+}
+
+// execute runs one node, with a span and events unless the workflow is
+// quiet
+func (w *Workflow) execute(ctx context.Context, node Node, inputs map[string]interface{}) (output interface{}, err error) {
+	if !w.quiet {
+		var span *Span
+		ctx, span = StartSpan(ctx, "workflow.node")
+		span.SetAttribute("node", node.ID)
+		w.cfg.publish(ctx, PhaseStepStarted, map[string]interface{}{"step": node.ID})
+		defer func() {
+			span.Finish(err)
+			if err != nil {
+				err = fmt.Errorf("node '%s' failed: %w", node.ID, err)
+				w.cfg.publish(ctx, PhaseStepFailed, map[string]interface{}{"step": node.ID, "error": err})
+				return
+			}
+			w.cfg.publish(ctx, PhaseStepFinished, map[string]interface{}{"step": node.ID})
+		}()
+	}
+
+	switch {
+	case node.Prompt != nil:
+		prompt, err := node.Prompt.Render(inputs)
+		if err != nil {
+			return nil, err
+		}
+		return w.cfg.call(ctx, w.client, prompt, w.cfg.model, w.cfg.tokens(4096))
+	case node.Tool != nil:
+		args := inputs
+		if node.Args != nil {
+			args = node.Args(inputs)
+		}
+		return w.cfg.runTool(ctx, node.Tool, args)
+	case node.Guardrail != nil:
+		input := fmt.Sprint(inputs[node.Input])
+		verdict, err := checkGuardrail(ctx, node.Guardrail, input)
+		if err != nil {
+			return nil, err
+		}
+		if !verdict.Passed {
+			return nil, fmt.Errorf("guardrail %s failed: %s", node.Guardrail.Name(), verdict.Reason)
+		}
+		return input, nil
+	case node.Approver != nil:
+		err := w.cfg.requestApproval(ctx, node.Approver, "workflow_node:"+node.ID,
+			fmt.Sprintf("Review the output of '%s'", node.Input),
+			map[string]interface{}{"output": inputs[node.Input]})
+		if err != nil {
+			return nil, err
+		}
+		return inputs[node.Input], nil
+	default:
+		return node.Run(ctx, inputs)
+	}
+}
@@ -0,0 +1,404 @@
+/*
+ * Workflow Definitions for Go Agent Patterns
+ * Chains, routers, orchestrators, optimizers, agents, and graphs declared in YAML or JSON
+ */
+
+package agentpatterns
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/markpitt/claude-skills/skills/agent-patterns/templates/go/config"
+)
+
+// WorkflowSpec declares a pattern to run, so pipelines can be changed
+// without recompiling. Exactly one of the pattern sections must match
+// Pattern. Prompts are templates over {{.input}} and, in chains and
+// graphs, the output of every earlier step by name. Models may be aliases
+// from the settings file.
+//
+// Example chain.yaml:
+//
+//	pattern: chain
+//	model: haiku
+//	budget:
+//	  max_calls: 10
+//	  max_cost: 0.25
+//	chain:
+//	  steps:
+//	    - name: outline
+//	      prompt: "Create an outline for: {{.input}}"
+//	      require: ["1.", "2."]
+//	      max_retries: 1
+//	    - name: draft
+//	      prompt: "Expand this outline into an article:\n{{.outline}}"
+type WorkflowSpec struct {
+	Pattern string `yaml:"pattern"`
+	// Model overrides the settings file's default model
+	Model string `yaml:"model"`
+	// Budget overrides the settings file's budget, field by field
+	Budget       config.BudgetConfig `yaml:"budget"`
+	Chain        *ChainSpec          `yaml:"chain"`
+	Router       *RouterSpec         `yaml:"router"`
+	Orchestrator *OrchestratorSpec   `yaml:"orchestrator"`
+	Optimizer    *OptimizerSpec      `yaml:"optimizer"`
+	Agent        *AgentSpec          `yaml:"agent"`
+	Graph        *GraphSpec          `yaml:"graph"`
+}
+
+// ChainSpec defines a prompt chain
+type ChainSpec struct {
+	Steps []StepSpec `yaml:"steps"`
+}
+
+// StepSpec defines one chain step
+type StepSpec struct {
+	Name   string `yaml:"name"`
+	Prompt string `yaml:"prompt"`
+	// Require lists substrings the output must contain to pass validation
+	Require []string `yaml:"require"`
+	// MinWords is the minimum word count for the output to pass validation
+	MinWords int `yaml:"min_words"`
+	// MaxRetries is the number of repair attempts after failed validation
+	MaxRetries int `yaml:"max_retries"`
+}
+
+// RouterSpec defines a router whose handlers are single prompts
+type RouterSpec struct {
+	Threshold float64     `yaml:"threshold"`
+	Routes    []RouteSpec `yaml:"routes"`
+	// Fallback is the prompt used for low-confidence or unknown categories
+	Fallback string `yaml:"fallback"`
+}
+
+// RouteSpec defines one route
+type RouteSpec struct {
+	Category    string `yaml:"category"`
+	Description string `yaml:"description"`
+	Prompt      string `yaml:"prompt"`
+	Model       string `yaml:"model"`
+}
+
+// OrchestratorSpec defines the workers available to the orchestrator
+type OrchestratorSpec struct {
+	Workers        []WorkerSpec `yaml:"workers"`
+	MaxConcurrency int          `yaml:"max_concurrency"`
+	MaxReplans     int          `yaml:"max_replans"`
+	MaxDepth       int          `yaml:"max_depth"`
+}
+
+// WorkerSpec defines an LLM worker
+type WorkerSpec struct {
+	Type         string `yaml:"type"`
+	SystemPrompt string `yaml:"system_prompt"`
+	Model        string `yaml:"model"`
+}
+
+// OptimizerSpec defines an evaluator-optimizer and the criteria it scores
+// against
+type OptimizerSpec struct {
+	Criteria       []CriterionSpec `yaml:"criteria"`
+	MaxIterations  int             `yaml:"max_iterations"`
+	Threshold      float64         `yaml:"threshold"`
+	EvaluatorModel string          `yaml:"evaluator_model"`
+	// Patience and Epsilon enable early stopping when Patience is set
+	Patience int     `yaml:"patience"`
+	Epsilon  float64 `yaml:"epsilon"`
+	// ParetoFront reports the Pareto front of the iterations
+	ParetoFront bool `yaml:"pareto_front"`
+}
+
+// CriterionSpec defines one evaluation criterion. Weight defaults to 1.
+type CriterionSpec struct {
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description"`
+	Weight      float64 `yaml:"weight"`
+}
+
+// AgentSpec defines an autonomous agent. No tools can be declared, so the
+// agent reasons over the input alone.
+type AgentSpec struct {
+	MaxSteps int `yaml:"max_steps"`
+}
+
+// GraphSpec defines a Workflow of prompt nodes
+type GraphSpec struct {
+	Nodes          []NodeSpec `yaml:"nodes"`
+	MaxConcurrency int        `yaml:"max_concurrency"`
+	// Output names the node whose output is the result; by default the
+	// last node
+	Output string `yaml:"output"`
+}
+
+// NodeSpec defines one prompt node and the nodes it waits for
+type NodeSpec struct {
+	ID     string `yaml:"id"`
+	Prompt string `yaml:"prompt"`
+	// After lists the nodes whose output this one needs
+	After []string `yaml:"after"`
+	// On is when the edges from After are taken: success (the default),
+	// failure, or complete
+	On string `yaml:"on"`
+}
+
+// LoadWorkflow reads and validates a workflow definition. JSON files are
+// read as YAML, of which JSON is a subset.
+//
+// Example:
+//
+//	spec, err := LoadWorkflow("pipelines/support.yaml")
+//	output, details, err := spec.Run(ctx, client, cfg, ticket)
+func LoadWorkflow(path string) (*WorkflowSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow: %w", err)
+	}
+	spec, err := ParseWorkflow(data)
+	if err != nil {
+		return nil, fmt.Errorf("workflow %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// ParseWorkflow parses and validates a YAML or JSON workflow definition
+func ParseWorkflow(data []byte) (*WorkflowSpec, error) {
+	var spec WorkflowSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+
+	switch spec.Pattern {
+	case "chain":
+		if spec.Chain == nil || len(spec.Chain.Steps) == 0 {
+			return nil, fmt.Errorf("chain pattern needs at least one step")
+		}
+	case "router":
+		if spec.Router == nil || len(spec.Router.Routes) == 0 {
+			return nil, fmt.Errorf("router pattern needs at least one route")
+		}
+	case "orchestrator":
+		if spec.Orchestrator == nil {
+			spec.Orchestrator = &OrchestratorSpec{}
+		}
+	case "optimizer":
+		if spec.Optimizer == nil || len(spec.Optimizer.Criteria) == 0 {
+			return nil, fmt.Errorf("optimizer pattern needs at least one criterion")
+		}
+		if spec.Optimizer.MaxIterations <= 0 {
+			spec.Optimizer.MaxIterations = 3
+		}
+		if spec.Optimizer.Threshold <= 0 {
+			spec.Optimizer.Threshold = 0.8
+		}
+	case "agent":
+		if spec.Agent == nil {
+			spec.Agent = &AgentSpec{}
+		}
+		if spec.Agent.MaxSteps <= 0 {
+			spec.Agent.MaxSteps = 10
+		}
+	case "graph":
+		if spec.Graph == nil || len(spec.Graph.Nodes) == 0 {
+			return nil, fmt.Errorf("graph pattern needs at least one node")
+		}
+		for _, node := range spec.Graph.Nodes {
+			if _, ok := edgeTypes[node.On]; !ok {
+				return nil, fmt.Errorf("node %s: unknown edge type %q (want success, failure, or complete)", node.ID, node.On)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown pattern %q (want chain, router, orchestrator, optimizer, agent, or graph)", spec.Pattern)
+	}
+	return &spec, nil
+}
+
+// edgeTypes maps the edge types of a NodeSpec to EdgeType
+var edgeTypes = map[string]EdgeType{
+	"":         OnSuccess,
+	"success":  OnSuccess,
+	"failure":  OnFailure,
+	"complete": OnComplete,
+}
+
+// Run builds the pattern and runs it on input, returning the final text
+// and the pattern's detailed result. Model aliases resolve through cfg,
+// whose default model and budget the spec overrides; opts override both.
+// cfg may be nil.
+func (s *WorkflowSpec) Run(ctx context.Context, client *AnthropicClient, cfg *config.Config, input string, opts ...Option) (string, interface{}, error) {
+	settings := config.Default()
+	if cfg != nil {
+		copied := *cfg
+		settings = &copied
+	}
+	if s.Model != "" {
+		settings.DefaultModel = s.Model
+	}
+	if s.Budget.MaxCalls > 0 {
+		settings.Budget.MaxCalls = s.Budget.MaxCalls
+	}
+	if s.Budget.MaxTokens > 0 {
+		settings.Budget.MaxTokens = s.Budget.MaxTokens
+	}
+	if s.Budget.MaxCost > 0 {
+		settings.Budget.MaxCost = s.Budget.MaxCost
+	}
+	if s.Budget.MaxDuration > 0 {
+		settings.Budget.MaxDuration = s.Budget.MaxDuration
+	}
+	opts = append([]Option{WithConfig(settings)}, opts...)
+	// withModel returns opts with a model alias applied, if one is set
+	withModel := func(model string) []Option {
+		if model == "" {
+			return opts
+		}
+		return append(append([]Option{}, opts...), WithModel(settings.Model(model)))
+	}
+
+	switch s.Pattern {
+	case "chain":
+		chain := NewPromptChain(client, opts...)
+		for _, step := range s.Chain.Steps {
+			tmpl, err := ParsePromptTemplate(step.Name, step.Prompt)
+			if err != nil {
+				return "", nil, err
+			}
+			chain.AddStep(ChainStep{
+				Name:       step.Name,
+				Template:   tmpl,
+				Validator:  step.validator(),
+				MaxRetries: step.MaxRetries,
+			})
+		}
+		result, err := chain.Execute(ctx, map[string]interface{}{"input": input})
+		return result, chain.History(), err
+
+	case "router":
+		router := NewRouter[string](client, opts...)
+		for _, route := range s.Router.Routes {
+			handler, err := promptHandler(client, route.Category, route.Prompt, withModel(route.Model))
+			if err != nil {
+				return "", nil, err
+			}
+			router.AddRoute(Route[string]{
+				Category:    route.Category,
+				Description: route.Description,
+				Handler:     handler,
+			})
+		}
+		if s.Router.Fallback != "" {
+			handler, err := promptHandler(client, "fallback", s.Router.Fallback, opts)
+			if err != nil {
+				return "", nil, err
+			}
+			router.SetFallback(handler)
+		}
+		result, classification, err := router.Route(ctx, input, s.Router.Threshold)
+		return result, classification, err
+
+	case "orchestrator":
+		orch := NewOrchestrator(client, append(opts, WithMaxConcurrency(s.Orchestrator.MaxConcurrency))...).
+			WithMaxReplans(s.Orchestrator.MaxReplans).
+			WithMaxDepth(s.Orchestrator.MaxDepth)
+		for _, w := range s.Orchestrator.Workers {
+			orch.RegisterWorker(NewLLMWorker(client, w.Type, w.SystemPrompt, withModel(w.Model)...))
+		}
+		result, err := orch.Execute(ctx, input)
+		if err != nil {
+			return "", nil, err
+		}
+		return result.FinalResult, result, nil
+
+	case "optimizer":
+		if s.Optimizer.Patience > 0 {
+			opts = append(opts, WithEarlyStopping(s.Optimizer.Patience, s.Optimizer.Epsilon))
+		}
+		if s.Optimizer.ParetoFront {
+			opts = append(opts, WithParetoFront())
+		}
+		optimizer := NewEvaluatorOptimizer(client, opts...)
+		if s.Optimizer.EvaluatorModel != "" {
+			optimizer.WithEvaluatorModel(settings.Model(s.Optimizer.EvaluatorModel))
+		}
+		for _, c := range s.Optimizer.Criteria {
+			weight := c.Weight
+			if weight == 0 {
+				weight = 1
+			}
+			optimizer.AddCriterion(EvaluationCriterion{Name: c.Name, Description: c.Description, Weight: weight})
+		}
+		result, err := optimizer.Optimize(ctx, input, s.Optimizer.MaxIterations, s.Optimizer.Threshold)
+		if err != nil {
+			return "", nil, err
+		}
+		return result.FinalOutput, result, nil
+
+	case "agent":
+		agent := NewAutonomousAgent(client, opts...)
+		result, err := agent.Run(ctx, input, s.Agent.MaxSteps)
+		if err != nil {
+			return "", nil, err
+		}
+		return result.FinalResult, result, nil
+
+	case "graph":
+		wf := NewWorkflow(client, append(opts, WithMaxConcurrency(s.Graph.MaxConcurrency))...)
+		for _, node := range s.Graph.Nodes {
+			tmpl, err := ParsePromptTemplate(node.ID, node.Prompt)
+			if err != nil {
+				return "", nil, err
+			}
+			wf.AddNode(Node{ID: node.ID, Prompt: tmpl})
+			for _, from := range node.After {
+				wf.AddEdge(Edge{From: from, To: node.ID, Type: edgeTypes[node.On]})
+			}
+		}
+		outputNode := s.Graph.Output
+		if outputNode == "" {
+			outputNode = s.Graph.Nodes[len(s.Graph.Nodes)-1].ID
+		}
+		result, err := wf.Run(ctx, map[string]interface{}{"input": input})
+		if err != nil {
+			return "", result, err
+		}
+		output, ok := result.Outputs[outputNode].(string)
+		if !ok {
+			return "", result, fmt.Errorf("output node %s did not run", outputNode)
+		}
+		return output, result, nil
+	}
+	return "", nil, fmt.Errorf("unknown pattern %q", s.Pattern)
+}
+
+// validator builds a chain validator from a step's requirements
+func (s StepSpec) validator() ValidatorFunc {
+	if len(s.Require) == 0 && s.MinWords == 0 {
+		return nil
+	}
+	return func(output string) bool {
+		for _, want := range s.Require {
+			if !strings.Contains(output, want) {
+				return false
+			}
+		}
+		return len(strings.Fields(output)) >= s.MinWords
+	}
+}
+
+// promptHandler returns a route handler that runs prompt as a one-step chain,
+// so it shares the router's run budget, costs, and events
+func promptHandler(client *AnthropicClient, name, prompt string, opts []Option) (func(context.Context, string) (string, error), error) {
+	tmpl, err := ParsePromptTemplate(name, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, input string) (string, error) {
+		chain := NewPromptChain(client, opts...)
+		chain.AddStep(ChainStep{Name: name, Template: tmpl})
+		return chain.Execute(ctx, map[string]interface{}{"input": input})
+	}, nil
+}